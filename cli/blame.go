@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/yoanbernabeu/grepai/config"
+	"github.com/yoanbernabeu/grepai/git"
+)
+
+// openBlameCache returns the blame cache of the project, or nil when the
+// project is not a git repository.
+func openBlameCache(projectRoot string, cfg *config.Config) *git.BlameCache {
+	if !git.IsGitRepo(projectRoot) {
+		return nil
+	}
+	cache := git.NewBlameCache(config.GetBlameCachePath(projectRoot), projectRoot, cfg.Search.Blame.ChurnDays)
+	cache.Load()
+	return cache
+}
+
+// formatBlame describes the last change of a result for display.
+func formatBlame(info *git.BlameInfo) string {
+	churn := fmt.Sprintf("%d changes in %d days", info.Churn, info.ChurnDays)
+	if info.SHA == "" {
+		return fmt.Sprintf("not committed yet (%s)", churn)
+	}
+	last := fmt.Sprintf("%s %s, %s", shortSHA(info.SHA), info.Author, info.Date.Format("2006-01-02"))
+	if info.Uncommitted {
+		last += ", with uncommitted changes"
+	}
+	return fmt.Sprintf("%s (%s)", last, churn)
+}
@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/yoanbernabeu/grepai/config"
+	"github.com/yoanbernabeu/grepai/store"
+)
+
+var (
+	cacheJSON      bool
+	cacheMaxSizeMB int
+	cacheAll       bool
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the shared embedding cache",
+	Long: `Manage the embedding cache shared by all projects, in ~/.grepai/cache.
+
+When embedder.cache.enabled is set, vectors are cached by provider, model,
+dimensions and content: identical code in several repositories, or an index
+rebuilt from scratch, is embedded once. The least recently used vectors are
+evicted above embedder.cache.max_size_mb.`,
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the size of the embedding cache",
+	Args:  cobra.NoArgs,
+	RunE:  runCacheStats,
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Evict the least recently used embeddings",
+	Long: `Evict the least recently used vectors of the embedding cache until it fits
+--max-size-mb, or remove all of them with --all.
+
+Examples:
+  grepai cache prune
+  grepai cache prune --max-size-mb 256
+  grepai cache prune --all`,
+	Args: cobra.NoArgs,
+	RunE: runCachePrune,
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+
+	cacheStatsCmd.Flags().BoolVarP(&cacheJSON, "json", "j", false, "Output stats in JSON format")
+	cachePruneCmd.Flags().IntVar(&cacheMaxSizeMB, "max-size-mb", 0, "Size to prune the cache to (default: embedder.cache.max_size_mb of the project, or 1024)")
+	cachePruneCmd.Flags().BoolVar(&cacheAll, "all", false, "Remove all cached embeddings")
+	cachePruneCmd.MarkFlagsMutuallyExclusive("max-size-mb", "all")
+}
+
+func openEmbeddingCache() (*store.DiskEmbeddingCache, error) {
+	dir, err := config.GetEmbeddingCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return store.NewDiskEmbeddingCache(dir), nil
+}
+
+func runCacheStats(cmd *cobra.Command, args []string) error {
+	cache, err := openEmbeddingCache()
+	if err != nil {
+		return err
+	}
+	stats, err := cache.Stats()
+	if err != nil {
+		return err
+	}
+	if cacheJSON {
+		return printJSON(stats)
+	}
+
+	fmt.Printf("Embedding cache: %s\n", stats.Dir)
+	fmt.Printf("  Entries: %d\n", stats.Entries)
+	fmt.Printf("  Size:    %s\n", formatBytes(stats.Bytes))
+	if stats.Entries > 0 {
+		fmt.Printf("  Oldest:  %s\n", stats.Oldest.Format("2006-01-02 15:04"))
+		fmt.Printf("  Newest:  %s\n", stats.Newest.Format("2006-01-02 15:04"))
+	}
+	return nil
+}
+
+func runCachePrune(cmd *cobra.Command, args []string) error {
+	cache, err := openEmbeddingCache()
+	if err != nil {
+		return err
+	}
+
+	maxSizeMB := cacheMaxSizeMB
+	if maxSizeMB <= 0 {
+		maxSizeMB = config.DefaultEmbeddingCacheMaxSizeMB
+		if projectRoot, err := config.FindProjectRoot(); err == nil {
+			if cfg, err := config.Load(projectRoot); err == nil {
+				maxSizeMB = cfg.Embedder.Cache.MaxSizeMB
+			}
+		}
+	}
+	maxBytes := int64(maxSizeMB) << 20
+	if cacheAll {
+		maxBytes = 0
+	}
+
+	removed, freed, err := cache.Prune(maxBytes)
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		fmt.Println("Nothing to prune")
+		return nil
+	}
+	fmt.Printf("Removed %d cached embeddings (%s)\n", removed, formatBytes(freed))
+	return nil
+}
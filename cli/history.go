@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/yoanbernabeu/grepai/config"
+	"github.com/yoanbernabeu/grepai/embedder"
+	"github.com/yoanbernabeu/grepai/git"
+	"github.com/yoanbernabeu/grepai/history"
+	"github.com/yoanbernabeu/grepai/store"
+)
+
+// historyUpdateInterval is how often the watcher checks for new commits.
+const historyUpdateInterval = 30 * time.Second
+
+// newHistoryIndexer creates the indexer of the commit history of a project,
+// redacting secrets from diffs as the code indexer does.
+func newHistoryIndexer(projectRoot string, cfg *config.Config, st store.VectorStore, emb embedder.Embedder, commitLog *history.Log) *history.Indexer {
+	opts := history.Options{
+		MaxCommits:        cfg.History.MaxCommits,
+		MaxFilesPerCommit: cfg.History.MaxFilesPerCommit,
+		MaxDiffLines:      cfg.History.MaxDiffLines,
+	}
+	if cfg.Redaction.Enabled(cfg.Embedder) {
+		opts.Redactor = cfg.Redaction.NewRedactor()
+		opts.MaskStored = cfg.Redaction.MaskStored
+	}
+	return history.NewIndexer(projectRoot, st, emb, commitLog, opts)
+}
+
+// startHistoryUpdates keeps the commit history index of a project up to date
+// while watching: new commits are indexed shortly after they appear.
+func startHistoryUpdates(ctx context.Context, projectRoot string, cfg *config.Config, emb embedder.Embedder) {
+	if !cfg.History.Enabled || !git.IsGitRepo(projectRoot) {
+		return
+	}
+	st, err := history.OpenStore(ctx, cfg, projectRoot)
+	if err != nil {
+		log.Printf("Warning: commit history will not be indexed: %v", err)
+		return
+	}
+	commitLog, err := history.OpenLog(projectRoot)
+	if err != nil {
+		st.Close()
+		log.Printf("Warning: commit history will not be indexed: %v", err)
+		return
+	}
+	idx := newHistoryIndexer(projectRoot, cfg, st, emb, commitLog)
+
+	go func() {
+		defer st.Close()
+		ticker := time.NewTicker(historyUpdateInterval)
+		defer ticker.Stop()
+		for {
+			indexed, err := idx.Update(ctx, nil)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Warning: failed to index commit history of %s: %v", projectRoot, err)
+			}
+			if indexed > 0 {
+				log.Printf("Indexed %d commits of %s", indexed, projectRoot)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
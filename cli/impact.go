@@ -0,0 +1,200 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yoanbernabeu/grepai/config"
+	"github.com/yoanbernabeu/grepai/git"
+	"github.com/yoanbernabeu/grepai/trace"
+)
+
+var (
+	impactStaged   bool
+	impactDepth    int
+	impactJSON     bool
+	impactMarkdown bool
+)
+
+var impactCmd = &cobra.Command{
+	Use:   "impact [<base>..<head> | <rev>]",
+	Short: "Show what a change can break",
+	Long: `Analyze the impact of a git diff before merging it.
+
+The changed lines of the diff are mapped to the symbols of the symbol index,
+whose callers are then walked transitively up to --depth levels. The report
+lists the changed symbols, the affected callers, the tests among them, the
+files involved and, with RPG enabled, the feature areas they belong to.
+
+Without arguments, uncommitted changes are compared to HEAD. A single
+revision is compared to the working tree, and --staged analyzes the changes
+staged for the next commit. Line numbers are mapped with the symbol index of
+the working tree, which 'grepai watch' keeps up to date: check out <head>
+for the most accurate results.
+
+Examples:
+  grepai impact
+  grepai impact --staged
+  grepai impact main..feature/login --depth 5
+  grepai impact main..HEAD --markdown > impact.md`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runImpact,
+}
+
+func init() {
+	impactCmd.Flags().BoolVar(&impactStaged, "staged", false, "Analyze the changes staged for the next commit")
+	impactCmd.Flags().IntVarP(&impactDepth, "depth", "d", trace.DefaultImpactDepth, "Maximum number of caller levels to walk")
+	impactCmd.Flags().BoolVarP(&impactJSON, "json", "j", false, "Output the report in JSON format (for AI agents)")
+	impactCmd.Flags().BoolVar(&impactMarkdown, "markdown", false, "Output the report in Markdown (for PR comments)")
+	impactCmd.MarkFlagsMutuallyExclusive("json", "markdown")
+}
+
+func runImpact(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	var rng string
+	if len(args) > 0 {
+		rng = args[0]
+	}
+	if impactStaged && strings.Contains(rng, "..") {
+		return fmt.Errorf("--staged compares the index to a single revision, not a range")
+	}
+
+	projectRoot, err := config.FindProjectRoot()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load(projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if !git.IsGitRepo(projectRoot) {
+		return fmt.Errorf("%s is not a git repository", projectRoot)
+	}
+
+	symbolStore := trace.NewGOBSymbolStore(config.GetSymbolIndexPath(projectRoot))
+	if err := symbolStore.Load(ctx); err != nil {
+		return fmt.Errorf("failed to load symbol index: %w", err)
+	}
+	defer symbolStore.Close()
+	stats, err := symbolStore.GetStats(ctx)
+	if err != nil || stats.TotalSymbols == 0 {
+		return fmt.Errorf("symbol index is empty. Run 'grepai watch' first to build the index")
+	}
+
+	diffs, err := git.Diff(ctx, projectRoot, rng, impactStaged)
+	if err != nil {
+		return err
+	}
+	impact, err := trace.AnalyzeImpact(ctx, symbolStore, trace.ChangesFromDiff(diffs), impactDepth)
+	if err != nil {
+		return err
+	}
+	impact.Range = git.DescribeDiff(rng, impactStaged)
+	enrichSymbolsWithRPG(projectRoot, cfg, impact.Symbols()...)
+	impact.CollectFeatures()
+
+	switch {
+	case impactJSON:
+		return printJSON(impact)
+	case impactMarkdown:
+		writeImpactMarkdown(os.Stdout, impact)
+		return nil
+	}
+	writeImpactText(os.Stdout, impact)
+	return nil
+}
+
+// writeImpactText writes an impact report for the terminal.
+func writeImpactText(w io.Writer, impact *trace.Impact) {
+	fmt.Fprintf(w, "Impact of %s\n", impact.Range)
+	fmt.Fprintln(w, strings.Repeat("=", 60))
+
+	sections := []struct {
+		title   string
+		symbols []trace.ImpactSymbol
+	}{
+		{"Changed symbols", impact.Changed},
+		{"Affected callers", impact.Affected},
+		{"Tests", impact.Tests},
+	}
+	for _, section := range sections {
+		fmt.Fprintf(w, "\n%s (%d):\n", section.title, len(section.symbols))
+		for _, sym := range section.symbols {
+			line := fmt.Sprintf("  - %s @ %s", sym.Name, formatLocation(sym.File, sym.Line, sym.Cell))
+			if sym.Via != "" {
+				line += fmt.Sprintf(" (depth %d, calls %s)", sym.Depth, sym.Via)
+			}
+			if sym.FeaturePath != "" {
+				line += fmt.Sprintf(" [%s]", sym.FeaturePath)
+			}
+			fmt.Fprintln(w, line)
+		}
+	}
+
+	fmt.Fprintf(w, "\nFiles (%d):\n", len(impact.Files))
+	for _, file := range impact.Files {
+		fmt.Fprintf(w, "  - %s\n", file)
+	}
+	if len(impact.Features) > 0 {
+		fmt.Fprintf(w, "\nFeature areas (%d):\n", len(impact.Features))
+		for _, feature := range impact.Features {
+			fmt.Fprintf(w, "  - %s\n", feature)
+		}
+	}
+}
+
+// writeImpactMarkdown writes an impact report as Markdown, e.g. for a pull
+// request comment.
+func writeImpactMarkdown(w io.Writer, impact *trace.Impact) {
+	fmt.Fprintf(w, "## Impact analysis: %s\n\n", impact.Range)
+	fmt.Fprintf(w, "%d changed symbols, %d affected callers, %d tests, %d files\n",
+		len(impact.Changed), len(impact.Affected), len(impact.Tests), len(impact.Files))
+
+	sections := []struct {
+		title   string
+		symbols []trace.ImpactSymbol
+	}{
+		{"Changed symbols", impact.Changed},
+		{"Affected callers", impact.Affected},
+		{"Tests", impact.Tests},
+	}
+	for _, section := range sections {
+		if len(section.symbols) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n### %s\n\n", section.title)
+		fmt.Fprintln(w, "| Symbol | Location | Depth | Calls | Feature |")
+		fmt.Fprintln(w, "|--------|----------|-------|-------|---------|")
+		for _, sym := range section.symbols {
+			via := ""
+			if sym.Via != "" {
+				via = "`" + sym.Via + "`"
+			}
+			fmt.Fprintf(w, "| `%s` | `%s` | %d | %s | %s |\n",
+				sym.Name, formatLocation(sym.File, sym.Line, sym.Cell), sym.Depth, via, markdownCell(sym.FeaturePath))
+		}
+	}
+
+	if len(impact.Features) > 0 {
+		fmt.Fprintln(w, "\n### Feature areas")
+		fmt.Fprintln(w)
+		for _, feature := range impact.Features {
+			fmt.Fprintf(w, "- %s\n", feature)
+		}
+	}
+
+	fmt.Fprintf(w, "\n<details>\n<summary>Files (%d)</summary>\n\n", len(impact.Files))
+	for _, file := range impact.Files {
+		fmt.Fprintf(w, "- `%s`\n", file)
+	}
+	fmt.Fprintln(w, "\n</details>")
+}
+
+// markdownCell escapes the pipes of a Markdown table cell.
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/yoanbernabeu/grepai/trace"
+)
+
+func TestWriteImpactMarkdown(t *testing.T) {
+	impact := &trace.Impact{
+		Range: "main..HEAD",
+		Changed: []trace.ImpactSymbol{
+			{Symbol: trace.Symbol{Name: "hashPassword", File: "auth/login.go", Line: 10, FeaturePath: "auth|login"}},
+		},
+		Affected: []trace.ImpactSymbol{
+			{Symbol: trace.Symbol{Name: "Login", File: "auth/login.go", Line: 3}, Depth: 1, Via: "hashPassword"},
+		},
+		Tests:    []trace.ImpactSymbol{},
+		Files:    []string{"auth/login.go"},
+		Features: []string{"auth|login"},
+	}
+
+	var buf bytes.Buffer
+	writeImpactMarkdown(&buf, impact)
+	out := buf.String()
+
+	for _, want := range []string{
+		"## Impact analysis: main..HEAD",
+		"1 changed symbols, 1 affected callers, 0 tests, 1 files",
+		"| `hashPassword` | `auth/login.go:10` | 0 |  | auth\\|login |",
+		"| `Login` | `auth/login.go:3` | 1 | `hashPassword` |  |",
+		"- `auth/login.go`",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "### Tests") {
+		t.Errorf("expected no section for empty tests:\n%s", out)
+	}
+}
@@ -0,0 +1,606 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yoanbernabeu/grepai/config"
+	"github.com/yoanbernabeu/grepai/embedder"
+	"github.com/yoanbernabeu/grepai/git"
+	"github.com/yoanbernabeu/grepai/indexer"
+	"github.com/yoanbernabeu/grepai/rpg"
+	"github.com/yoanbernabeu/grepai/store"
+	"github.com/yoanbernabeu/grepai/trace"
+)
+
+// Exit codes of `grepai index` besides 0 (success) and 1 (error).
+const (
+	exitIndexStale   = 2 // --check found files out of date
+	exitIndexPartial = 3 // Some files failed to index
+)
+
+// Summary statuses of `grepai index`.
+const (
+	indexStatusOK       = "ok"
+	indexStatusPartial  = "partial"
+	indexStatusUpToDate = "up_to_date"
+	indexStatusStale    = "stale"
+	indexStatusDryRun   = "dry_run"
+)
+
+// Throughput assumptions for --dry-run duration estimates.
+const (
+	dryRunRequestLatency       = 1500 * time.Millisecond // One embedding request to a hosted API
+	dryRunLocalTokensPerSecond = 2000                    // Local embedders (Ollama, LM Studio) on a laptop
+)
+
+var (
+	indexFull   bool
+	indexFiles  []string
+	indexCheck  bool
+	indexDryRun bool
+	indexRef    string
+)
+
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Index the project once and exit",
+	Long: `Index the project once, without starting a watcher, then exit.
+Intended for CI pipelines and scripts.
+
+Runs the same steps as the initial scan of 'grepai watch': embeds new and
+changed files, removes deleted ones, extracts symbols for 'grepai trace' and
+rebuilds the RPG graph when enabled. A JSON summary is printed to stdout;
+logs go to stderr.
+
+With --ref, the files of a branch, tag or commit are read from the git object
+database instead of the working tree and indexed into a separate index under
+.grepai/refs/, queried with 'grepai search --ref' and 'grepai trace --ref'.
+The RPG graph is not built for refs.
+
+Examples:
+  grepai index                          Index changes since the last run
+  grepai index --full                   Re-embed every file
+  grepai index --files a.go,b.go        Only (re)index the given files
+  grepai index --check                  Fail if the index is out of date
+  grepai index --full --dry-run         Estimate tokens, cost and time of a full index
+  grepai index --ref main               Index the main branch without checking it out
+
+Exit codes:
+  0  Success (or index up to date with --check)
+  1  Error (configuration, embedder or store unavailable, ...)
+  2  Index is stale (--check)
+  3  Some files failed to index`,
+	RunE: runIndex,
+}
+
+func init() {
+	indexCmd.Flags().BoolVar(&indexFull, "full", false, "Re-index all files, ignoring the last index time")
+	indexCmd.Flags().StringSliceVar(&indexFiles, "files", nil, "Only index these files (comma-separated or repeated)")
+	indexCmd.Flags().BoolVar(&indexCheck, "check", false, "Report stale files without indexing; exit 2 if the index is out of date")
+	indexCmd.Flags().BoolVar(&indexDryRun, "dry-run", false, "Estimate chunks, tokens, cost and time without embedding anything")
+	indexCmd.Flags().StringVar(&indexRef, "ref", "", "Index a git branch, tag or commit into a separate index instead of the working tree")
+	indexCmd.MarkFlagsMutuallyExclusive("full", "files")
+	indexCmd.MarkFlagsMutuallyExclusive("full", "check")
+	indexCmd.MarkFlagsMutuallyExclusive("check", "dry-run")
+}
+
+// indexSummary is the JSON summary printed by `grepai index`.
+type indexSummary struct {
+	Status        string             `json:"status"`
+	FilesAdded    int                `json:"files_added"`
+	FilesUpdated  int                `json:"files_updated"`
+	FilesRemoved  int                `json:"files_removed"`
+	FilesSkipped  int                `json:"files_skipped"`
+	FilesFailed   int                `json:"files_failed"`
+	ChunksCreated int                `json:"chunks_created"`
+	Tokens        int64              `json:"tokens"`
+	DurationMs    int64              `json:"duration_ms"`
+	Stale         *indexer.IndexDiff `json:"stale,omitempty"`
+}
+
+// indexDryRunSummary is the JSON estimate printed by `grepai index --dry-run`.
+// Price and cost are null when the model price is unknown.
+type indexDryRunSummary struct {
+	Status                string                `json:"status"`
+	Provider              string                `json:"provider"`
+	Model                 string                `json:"model"`
+	Files                 int                   `json:"files"`
+	Chunks                int                   `json:"chunks"`
+	Tokens                int                   `json:"tokens"`
+	CachedChunks          int                   `json:"cached_chunks"`
+	TokensToEmbed         int                   `json:"tokens_to_embed"`
+	Requests              int                   `json:"requests"`
+	PricePerMillionTokens *float64              `json:"price_per_million_tokens"`
+	EstimatedCostUSD      *float64              `json:"estimated_cost_usd"`
+	EstimatedDurationMs   int64                 `json:"estimated_duration_ms"`
+	Directories           []indexer.DirEstimate `json:"directories"`
+}
+
+func runIndex(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	start := time.Now()
+
+	projectRoot, err := config.FindProjectRoot()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	files, err := resolveIndexFiles(projectRoot, indexFiles)
+	if err != nil {
+		return err
+	}
+
+	// Everything written by a ref index lives in its own directory
+	dataDir := config.GetConfigDir(projectRoot)
+	symbolIndexPath := config.GetSymbolIndexPath(projectRoot)
+	lastIndexTime := cfg.Watch.LastIndexTime
+	var tree *git.Tree
+	if indexRef != "" {
+		tree, err = git.OpenTree(ctx, projectRoot, indexRef)
+		if err != nil {
+			return err
+		}
+		defer tree.Close()
+		dataDir = config.GetRefDir(projectRoot, indexRef)
+		symbolIndexPath = filepath.Join(dataDir, config.SymbolIndexFileName)
+		// Files carry the commit time, so unchanged files are told apart by
+		// their content hash only
+		lastIndexTime = time.Time{}
+	}
+
+	var st store.VectorStore
+	if tree != nil {
+		st, err = initializeRefStore(ctx, cfg, projectRoot, indexRef)
+	} else {
+		st, err = initializeIndexStore(ctx, cfg, projectRoot)
+	}
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	var scanner *indexer.Scanner
+	var dirConfigs *config.DirConfigs
+	if tree != nil {
+		scanner, err = newRefScanner(projectRoot, indexRef, tree, cfg)
+		if err != nil {
+			return err
+		}
+		dirConfigs = config.LoadDirConfigsFS(tree, cfg.Ignore)
+	} else {
+		ignoreMatcher, err := indexer.NewIgnoreMatcher(projectRoot, cfg.Ignore, cfg.ExternalGitignore)
+		if err != nil {
+			return fmt.Errorf("failed to initialize ignore matcher: %w", err)
+		}
+		scanner = newScanner(projectRoot, ignoreMatcher, cfg.Index)
+		dirConfigs = loadDirConfigs(projectRoot, cfg, ignoreMatcher)
+	}
+	chunker := newChunker(cfg.Chunking)
+
+	if indexCheck {
+		// Comparing hashes needs no embedder
+		idx := indexer.NewIndexer(projectRoot, st, nil, chunker, scanner, time.Time{})
+		diff, err := idx.Diff(ctx, files)
+		if err != nil {
+			return fmt.Errorf("failed to compare index: %w", err)
+		}
+		summary := indexSummary{
+			Status:       indexStatusUpToDate,
+			FilesAdded:   len(diff.Added),
+			FilesUpdated: len(diff.Updated),
+			FilesRemoved: len(diff.Removed),
+			DurationMs:   time.Since(start).Milliseconds(),
+		}
+		if diff.Stale() {
+			summary.Status = indexStatusStale
+			summary.Stale = diff
+		}
+		if err := printJSON(summary); err != nil {
+			return err
+		}
+		if diff.Stale() {
+			return &ExitError{
+				Code: exitIndexStale,
+				Err: fmt.Errorf("index is stale: %d added, %d updated, %d removed",
+					len(diff.Added), len(diff.Updated), len(diff.Removed)),
+			}
+		}
+		return nil
+	}
+
+	if indexDryRun {
+		est, err := estimateIndex(ctx, projectRoot, cfg, st, chunker, scanner, lastIndexTime, files, indexFull,
+			indexerOptionsIn(dataDir, dirConfigs, cfg)...)
+		if err != nil {
+			return fmt.Errorf("failed to estimate indexing: %w", err)
+		}
+		return printJSON(newDryRunSummary(est, cfg.Embedder))
+	}
+
+	emb, err := initializeEmbedder(ctx, cfg, embedder.WithUsageFile(config.GetUsagePath(projectRoot)))
+	if err != nil {
+		return err
+	}
+	defer emb.Close()
+
+	recordFingerprint := checkFingerprint(ctx, st, dataDir, cfg, indexFull)
+	rechunk := recordFingerprint && !indexFull && staleChunks(ctx, st, dataDir)
+	if rechunk && len(files) > 0 {
+		// Only a scan of the project chunks every file again
+		rechunk, recordFingerprint = false, false
+	}
+	if indexFull {
+		lastIndexTime = time.Time{}
+		// On a branch, only its overlay is rebuilt
+		cleared := st
+		if overlayStore, ok := st.(*store.OverlayStore); ok && overlayStore.Overlay() != nil {
+			cleared = overlayStore.Overlay()
+		}
+		if err := clearIndex(ctx, cleared); err != nil {
+			return err
+		}
+	}
+	idx := indexer.NewIndexer(projectRoot, st, emb, chunker, scanner, lastIndexTime,
+		append(indexerOptionsIn(dataDir, dirConfigs, cfg), indexer.WithRechunk(rechunk))...)
+
+	symbolStore := trace.NewGOBSymbolStore(symbolIndexPath)
+	if err := symbolStore.Load(ctx); err != nil {
+		log.Printf("Warning: failed to load symbol index: %v", err)
+	}
+	defer symbolStore.Close()
+
+	extractor := trace.NewRegexExtractor()
+	tracedLanguages := tracedLanguagesFor(cfg.Trace)
+
+	var stats *indexer.IndexStats
+	if len(files) > 0 {
+		stats, err = indexFileList(ctx, st, idx, scanner, extractor, symbolStore, tracedLanguages, files)
+	} else {
+		stats, err = runInitialScan(ctx, idx, scanner, extractor, symbolStore, tracedLanguages, lastIndexTime, true)
+	}
+	if err != nil {
+		return err
+	}
+
+	var rpgIndexer *rpg.RPGIndexer
+	var rpgStore rpg.RPGStore
+	if tree == nil {
+		rpgIndexer, rpgStore = newRPGIndexer(ctx, cfg, projectRoot)
+	}
+	if rpgIndexer != nil {
+		defer rpgStore.Close()
+		if err := rpgIndexer.BuildFull(ctx, symbolStore, st); err != nil {
+			log.Printf("Warning: failed to build RPG graph: %v", err)
+		} else {
+			rpgStats := rpgStore.GetGraph().Stats()
+			log.Printf("RPG graph built: %d nodes, %d edges", rpgStats.TotalNodes, rpgStats.TotalEdges)
+		}
+	}
+
+	if err := st.Persist(ctx); err != nil {
+		return fmt.Errorf("failed to persist index: %w", err)
+	}
+	if err := symbolStore.Persist(ctx); err != nil {
+		log.Printf("Warning: failed to persist symbol index: %v", err)
+	}
+	if rpgStore != nil {
+		if err := rpgStore.Persist(ctx); err != nil {
+			log.Printf("Warning: failed to persist RPG graph: %v", err)
+		}
+	}
+	if err := scanner.SaveGeneratedReport(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	if err := idx.SaveSecretsReport(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	if recordFingerprint {
+		if err := config.SaveFingerprint(dataDir, config.NewFingerprint(cfg.Embedder)); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+
+	if tree != nil {
+		if err := config.SaveRefInfo(projectRoot, config.RefInfo{Ref: indexRef, Commit: tree.Commit, IndexedAt: time.Now()}); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+
+	// Only a complete project scan may advance the last index time; failed
+	// files must be retried by the next run.
+	if tree == nil && len(files) == 0 && stats.FilesFailed == 0 && (stats.FilesIndexed > 0 || stats.ChunksCreated > 0 || indexFull) {
+		cfg.Watch.LastIndexTime = time.Now()
+		if err := cfg.Save(projectRoot); err != nil {
+			log.Printf("Warning: failed to save config: %v", err)
+		}
+	}
+
+	summary := indexSummary{
+		Status:        indexStatusOK,
+		FilesAdded:    stats.FilesAdded,
+		FilesUpdated:  stats.FilesUpdated,
+		FilesRemoved:  stats.FilesRemoved,
+		FilesSkipped:  stats.FilesSkipped,
+		FilesFailed:   stats.FilesFailed,
+		ChunksCreated: stats.ChunksCreated,
+		Tokens:        stats.TokensEmbedded,
+		DurationMs:    time.Since(start).Milliseconds(),
+	}
+	if stats.FilesFailed > 0 {
+		summary.Status = indexStatusPartial
+	}
+	if err := printJSON(summary); err != nil {
+		return err
+	}
+	if stats.FilesFailed > 0 {
+		return &ExitError{
+			Code: exitIndexPartial,
+			Err:  fmt.Errorf("%d files failed to index", stats.FilesFailed),
+		}
+	}
+	return nil
+}
+
+// resolveIndexFiles converts --files arguments (relative to the working
+// directory, or absolute) into paths relative to the project root.
+func resolveIndexFiles(projectRoot string, files []string) ([]string, error) {
+	resolved := make([]string, 0, len(files))
+	for _, file := range files {
+		file = strings.TrimSpace(file)
+		if file == "" {
+			continue
+		}
+		absPath, err := filepath.Abs(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", file, err)
+		}
+		relPath, err := filepath.Rel(projectRoot, absPath)
+		if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("%s is outside the project %s", file, projectRoot)
+		}
+		resolved = append(resolved, relPath)
+	}
+	return resolved, nil
+}
+
+// checkFingerprint compares the embedding settings of cfg with those the index
+// in dataDir was built with, warning on a mismatch. It returns whether the
+// fingerprint of cfg may be recorded once the index is up to date: an index
+// of other settings keeps its fingerprint until rebuilt with --full.
+func checkFingerprint(ctx context.Context, st store.VectorStore, dataDir string, cfg *config.Config, full bool) bool {
+	if full {
+		return true
+	}
+	current := config.NewFingerprint(cfg.Embedder)
+	stored, err := config.LoadFingerprint(dataDir)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return false
+	}
+	if stored != nil {
+		if diff := stored.Diff(current); len(diff) > 0 {
+			log.Printf("Warning: the index was built with another embedding %s; run 'grepai index --full' to rebuild it", strings.Join(diff, ", "))
+			return false
+		}
+		return true
+	}
+
+	// No fingerprint: the index is new, or predates fingerprints
+	if stats, err := st.GetStats(ctx); err == nil && stats.TotalChunks == 0 {
+		return true
+	}
+	if current.QueryPrefix != "" || current.DocumentPrefix != "" {
+		log.Printf("Warning: the index predates the query and document prefixes of %s; run 'grepai index --full' to rebuild it with them", current.Model)
+		return false
+	}
+	return true
+}
+
+// staleChunks reports whether the index in dataDir holds chunks of an older
+// config.ChunkSchema, which must be chunked again to record their metadata.
+// Indexes without a fingerprint predate the schema unless they are empty.
+func staleChunks(ctx context.Context, st store.VectorStore, dataDir string) bool {
+	stored, err := config.LoadFingerprint(dataDir)
+	if err != nil {
+		return false
+	}
+	if stored != nil {
+		if stored.ChunkSchema < config.ChunkSchema {
+			log.Printf("Chunking the index again to record chunk languages; embeddings are reused")
+			return true
+		}
+		return false
+	}
+	if stats, err := st.GetStats(ctx); err != nil || stats.TotalChunks == 0 {
+		return false
+	}
+	log.Printf("Chunking the index again to record chunk languages; embeddings are reused")
+	return true
+}
+
+// clearIndex removes every document and its chunks so that all files are
+// re-embedded.
+func clearIndex(ctx context.Context, st store.VectorStore) error {
+	docs, err := st.ListDocuments(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list documents: %w", err)
+	}
+	for _, doc := range docs {
+		if err := st.DeleteByFile(ctx, doc); err != nil {
+			return fmt.Errorf("failed to delete chunks for %s: %w", doc, err)
+		}
+		if err := st.DeleteDocument(ctx, doc); err != nil {
+			return fmt.Errorf("failed to delete document %s: %w", doc, err)
+		}
+	}
+	return nil
+}
+
+// indexFileList indexes only the given files: changed files are re-embedded,
+// missing or no longer indexable files are removed.
+func indexFileList(ctx context.Context, st store.VectorStore, idx *indexer.Indexer, scanner *indexer.Scanner, extractor *trace.RegexExtractor, symbolStore *trace.GOBSymbolStore, tracedLanguages []string, files []string) (*indexer.IndexStats, error) {
+	start := time.Now()
+	tokensBefore := idx.TokensEmbedded()
+	stats := &indexer.IndexStats{}
+
+	for _, relPath := range files {
+		var file *indexer.FileInfo
+		if scanner.Indexable(relPath) {
+			var err error
+			file, err = scanner.ScanFile(relPath)
+			if err != nil && !os.IsNotExist(err) {
+				log.Printf("Failed to scan %s: %v", relPath, err)
+				stats.FilesFailed++
+				continue
+			}
+		}
+
+		doc, err := st.GetDocument(ctx, relPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get document %s: %w", relPath, err)
+		}
+
+		if file == nil {
+			// Deleted, ignored, unsupported or skipped (large, binary, generated)
+			if doc == nil {
+				stats.FilesSkipped++
+				continue
+			}
+			if err := idx.RemoveFile(ctx, relPath); err != nil {
+				log.Printf("Failed to remove %s: %v", relPath, err)
+				stats.FilesFailed++
+				continue
+			}
+			if err := symbolStore.DeleteFile(ctx, relPath); err != nil {
+				log.Printf("Warning: failed to remove symbols for %s: %v", relPath, err)
+			}
+			stats.FilesRemoved++
+			continue
+		}
+
+		if doc == nil || doc.Hash != file.Hash {
+			chunks, err := idx.IndexFile(ctx, *file)
+			if err != nil {
+				log.Printf("Failed to index %s: %v", file.Path, err)
+				stats.FilesFailed++
+				continue
+			}
+			stats.FilesIndexed++
+			stats.ChunksCreated += chunks
+			if doc == nil {
+				stats.FilesAdded++
+			} else {
+				stats.FilesUpdated++
+			}
+		} else {
+			stats.FilesSkipped++
+		}
+
+		if !isTracedFile(idx, file.Path, tracedLanguages) {
+			continue
+		}
+		if existingHash, ok := symbolStore.GetFileContentHash(file.Path); ok && existingHash == file.Hash {
+			continue
+		}
+		symbols, refs, err := extractor.ExtractAll(ctx, file.Path, file.Content)
+		if err != nil {
+			log.Printf("Warning: failed to extract symbols from %s: %v", file.Path, err)
+			continue
+		}
+		if err := symbolStore.SaveFileWithContentHash(ctx, file.Path, file.Hash, symbols, refs); err != nil {
+			log.Printf("Warning: failed to save symbols for %s: %v", file.Path, err)
+		}
+	}
+
+	stats.TokensEmbedded = idx.TokensEmbedded() - tokensBefore
+	stats.Duration = time.Since(start)
+	return stats, nil
+}
+
+// estimateIndex estimates the work of an indexing run for --dry-run. The
+// indexer gets the configured embedder, decorators included, so that the
+// shared embedding cache is checked with the keys of a real run; estimating
+// sends no request. Without an embedder (e.g. a missing API key), the shared
+// cache is not checked.
+func estimateIndex(ctx context.Context, projectRoot string, cfg *config.Config, st store.VectorStore, chunker *indexer.Chunker, scanner *indexer.Scanner,
+	lastIndexTime time.Time, files []string, full bool, opts ...indexer.IndexerOption) (*indexer.Estimate, error) {
+	emb, err := embedder.NewFromConfig(cfg)
+	if err != nil {
+		log.Printf("Warning: the embedding cache will not be checked: %v", err)
+		emb = nil
+	} else {
+		defer emb.Close()
+	}
+	idx := indexer.NewIndexer(projectRoot, st, emb, chunker, scanner, lastIndexTime, opts...)
+	return idx.Estimate(ctx, files, full)
+}
+
+// newDryRunSummary prices an estimate with the embedder configuration.
+func newDryRunSummary(est *indexer.Estimate, cfg config.EmbedderConfig) indexDryRunSummary {
+	summary := indexDryRunSummary{
+		Status:        indexStatusDryRun,
+		Provider:      cfg.Provider,
+		Model:         cfg.Model,
+		Files:         est.Files,
+		Chunks:        est.Chunks,
+		Tokens:        est.Tokens,
+		CachedChunks:  est.CachedChunks,
+		TokensToEmbed: est.UncachedTokens,
+		Directories:   est.Dirs,
+	}
+
+	if price, ok := cfg.PricePerMillionTokens(); ok {
+		cost := float64(est.UncachedTokens) / 1_000_000 * price
+		summary.PricePerMillionTokens = &price
+		summary.EstimatedCostUSD = &cost
+	}
+
+	var duration time.Duration
+	switch cfg.Provider {
+	case "local":
+		// Embedded in-process, without requests
+	case "ollama", "lmstudio", "tei", "llamacpp":
+		duration = time.Duration(float64(est.UncachedTokens) / dryRunLocalTokensPerSecond * float64(time.Second))
+		uncached := est.Chunks - est.CachedChunks
+		summary.Requests = (uncached + embedder.LocalRequestSize - 1) / embedder.LocalRequestSize
+	default:
+		// The OpenAI embedder batches across files and sends batches in parallel
+		summary.Requests = est.FilesToEmbed
+		parallelism := 1
+		if cfg.Provider == "openai" || cfg.Provider == "azure-openai" {
+			summary.Requests = est.Batches
+			parallelism = max(cfg.Parallelism, 1)
+		}
+		duration = time.Duration((summary.Requests+parallelism-1)/parallelism) * dryRunRequestLatency
+		if cfg.TPMLimit > 0 {
+			tpmDuration := time.Duration(float64(est.UncachedTokens) / float64(cfg.TPMLimit) * float64(time.Minute))
+			duration = max(duration, tpmDuration)
+		}
+	}
+	summary.EstimatedDurationMs = duration.Milliseconds()
+
+	return summary
+}
+
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	return nil
+}
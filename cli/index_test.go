@@ -0,0 +1,238 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yoanbernabeu/grepai/config"
+	"github.com/yoanbernabeu/grepai/embedder"
+	"github.com/yoanbernabeu/grepai/indexer"
+	"github.com/yoanbernabeu/grepai/store"
+	"github.com/yoanbernabeu/grepai/trace"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, 0},
+		{"plain error", errors.New("boom"), 1},
+		{"exit error", &ExitError{Code: exitIndexStale, Err: errors.New("stale")}, exitIndexStale},
+		{"wrapped exit error", fmt.Errorf("index: %w", &ExitError{Code: exitIndexPartial, Err: errors.New("partial")}), exitIndexPartial},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveIndexFiles(t *testing.T) {
+	projectRoot := t.TempDir()
+
+	files, err := resolveIndexFiles(projectRoot, []string{filepath.Join(projectRoot, "pkg", "a.go"), " "})
+	if err != nil {
+		t.Fatalf("resolveIndexFiles failed: %v", err)
+	}
+	if len(files) != 1 || files[0] != filepath.Join("pkg", "a.go") {
+		t.Errorf("files = %v, want [%s]", files, filepath.Join("pkg", "a.go"))
+	}
+
+	if _, err := resolveIndexFiles(projectRoot, []string{filepath.Join(filepath.Dir(projectRoot), "other.go")}); err == nil {
+		t.Error("expected an error for a file outside the project")
+	}
+}
+
+func TestIndexFileList(t *testing.T) {
+	ctx := context.Background()
+	projectRoot := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(projectRoot, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatalf("failed to create source file: %v", err)
+	}
+
+	ignoreMatcher, err := indexer.NewIgnoreMatcher(projectRoot, []string{}, "")
+	if err != nil {
+		t.Fatalf("failed to create ignore matcher: %v", err)
+	}
+	scanner := indexer.NewScanner(projectRoot, ignoreMatcher)
+	vecStore := store.NewGOBStore(filepath.Join(projectRoot, "index.gob"))
+	if err := vecStore.SaveDocument(ctx, store.Document{Path: "deleted.go", Hash: "hash"}); err != nil {
+		t.Fatalf("failed to save document: %v", err)
+	}
+	idx := indexer.NewIndexer(projectRoot, vecStore, &noOpEmbedder{}, indexer.NewChunker(512, 50), scanner, time.Time{})
+
+	symbolStore := trace.NewGOBSymbolStore(filepath.Join(projectRoot, "symbols.gob"))
+	defer symbolStore.Close()
+
+	stats, err := indexFileList(ctx, vecStore, idx, scanner, trace.NewRegexExtractor(), symbolStore, []string{".go"},
+		[]string{"main.go", "deleted.go", "missing.go"})
+	if err != nil {
+		t.Fatalf("indexFileList failed: %v", err)
+	}
+
+	if stats.FilesAdded != 1 || stats.FilesRemoved != 1 || stats.FilesSkipped != 1 {
+		t.Errorf("expected 1 added, 1 removed, 1 skipped, got %+v", stats)
+	}
+	if doc, _ := vecStore.GetDocument(ctx, "deleted.go"); doc != nil {
+		t.Error("deleted.go should be removed from the index")
+	}
+	if !symbolStore.IsFileIndexed("main.go") {
+		t.Error("symbols should be extracted for main.go")
+	}
+
+	// A second run finds nothing to do
+	stats, err = indexFileList(ctx, vecStore, idx, scanner, trace.NewRegexExtractor(), symbolStore, []string{".go"}, []string{"main.go"})
+	if err != nil {
+		t.Fatalf("indexFileList failed: %v", err)
+	}
+	if stats.FilesIndexed != 0 || stats.FilesSkipped != 1 {
+		t.Errorf("expected main.go to be skipped, got %+v", stats)
+	}
+}
+
+func TestIndexFileList_LocalEmbedder(t *testing.T) {
+	ctx := context.Background()
+	projectRoot := t.TempDir()
+
+	files := map[string]string{
+		"config.go": "package app\n\n// ParseConfigFile reads the YAML configuration file.\nfunc ParseConfigFile(path string) (*Config, error) {\n\treturn nil, nil\n}\n",
+		"render.go": "package app\n\n// RenderHTMLTemplate writes a page.\nfunc RenderHTMLTemplate(w io.Writer, page *Page) error {\n\treturn nil\n}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(projectRoot, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create source file: %v", err)
+		}
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Embedder = config.EmbedderConfig{Provider: "local", Model: embedder.LocalModel}
+	emb, err := embedder.NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("failed to create local embedder: %v", err)
+	}
+
+	ignoreMatcher, err := indexer.NewIgnoreMatcher(projectRoot, []string{}, "")
+	if err != nil {
+		t.Fatalf("failed to create ignore matcher: %v", err)
+	}
+	scanner := indexer.NewScanner(projectRoot, ignoreMatcher)
+	vecStore := store.NewGOBStore(filepath.Join(projectRoot, "index.gob"))
+	idx := indexer.NewIndexer(projectRoot, vecStore, emb, indexer.NewChunker(512, 50), scanner, time.Time{})
+	symbolStore := trace.NewGOBSymbolStore(filepath.Join(projectRoot, "symbols.gob"))
+	defer symbolStore.Close()
+
+	stats, err := indexFileList(ctx, vecStore, idx, scanner, trace.NewRegexExtractor(), symbolStore, []string{".go"}, []string{"config.go", "render.go"})
+	if err != nil {
+		t.Fatalf("indexFileList failed: %v", err)
+	}
+	if stats.FilesAdded != 2 {
+		t.Fatalf("expected 2 files added, got %+v", stats)
+	}
+
+	query, err := emb.Embed(ctx, "parse configuration file")
+	if err != nil {
+		t.Fatalf("failed to embed query: %v", err)
+	}
+	results, err := vecStore.Search(ctx, query, 2, store.SearchOptions{})
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if len(results) == 0 || results[0].Chunk.FilePath != "config.go" {
+		t.Errorf("expected config.go as the best match, got %+v", results)
+	}
+}
+
+func TestNewDryRunSummary(t *testing.T) {
+	est := &indexer.Estimate{
+		Files:          10,
+		Chunks:         100,
+		Tokens:         2_000_000,
+		CachedChunks:   50,
+		UncachedTokens: 1_000_000,
+		FilesToEmbed:   5,
+		Batches:        8,
+	}
+
+	summary := newDryRunSummary(est, config.EmbedderConfig{Provider: "openai", Model: "text-embedding-3-small", Parallelism: 4, TPMLimit: 500_000})
+	if summary.EstimatedCostUSD == nil || *summary.EstimatedCostUSD < 0.0199 || *summary.EstimatedCostUSD > 0.0201 {
+		t.Errorf("expected a cost of $0.02 for 1M tokens, got %v", summary.EstimatedCostUSD)
+	}
+	if summary.Requests != 8 {
+		t.Errorf("expected 8 batch requests, got %d", summary.Requests)
+	}
+	// 1M tokens at 500k TPM dominate 2 rounds of parallel requests
+	if summary.EstimatedDurationMs != (2 * time.Minute).Milliseconds() {
+		t.Errorf("expected 2 minutes, got %dms", summary.EstimatedDurationMs)
+	}
+
+	summary = newDryRunSummary(est, config.EmbedderConfig{Provider: "openai", Model: "text-embedding-3-small", Parallelism: 4})
+	if summary.EstimatedDurationMs != (2 * dryRunRequestLatency).Milliseconds() {
+		t.Errorf("expected 2 request rounds without a TPM limit, got %dms", summary.EstimatedDurationMs)
+	}
+
+	summary = newDryRunSummary(est, config.EmbedderConfig{Provider: "ollama", Model: "nomic-embed-text"})
+	if want := (est.Chunks - est.CachedChunks + embedder.LocalRequestSize - 1) / embedder.LocalRequestSize; summary.Requests != want {
+		t.Errorf("expected %d batched local requests, got %d", want, summary.Requests)
+	}
+
+	summary = newDryRunSummary(est, config.EmbedderConfig{Provider: "synthetic", Model: "unknown"})
+	if summary.EstimatedCostUSD != nil || summary.PricePerMillionTokens != nil {
+		t.Error("expected no cost for an unknown model price")
+	}
+	if summary.Requests != 5 {
+		t.Errorf("expected one request per file for non-batch embedders, got %d", summary.Requests)
+	}
+}
+
+func TestEstimateIndex_SharedCache(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("HOME", t.TempDir()) // The shared cache lives in ~/.grepai/cache
+	projectRoot := t.TempDir()
+	content := "package aws\n\nconst keyID = \"AKIA" + "IOSFODNN7EXAMPLE\"\n\nfunc Client() {}\n"
+	if err := os.WriteFile(filepath.Join(projectRoot, "client.go"), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create source file: %v", err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Embedder = config.EmbedderConfig{Provider: "local", Model: embedder.LocalModel, Cache: config.EmbeddingCacheConfig{Enabled: true}}
+	cfg.Redaction.Mode = "always"
+	dataDir := t.TempDir()
+	ignoreMatcher, err := indexer.NewIgnoreMatcher(projectRoot, []string{}, "")
+	if err != nil {
+		t.Fatalf("failed to create ignore matcher: %v", err)
+	}
+	scanner := indexer.NewScanner(projectRoot, ignoreMatcher)
+	chunker := indexer.NewChunker(512, 50)
+
+	// A real run fills the shared cache with the masked chunks
+	emb, err := embedder.NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("failed to create embedder: %v", err)
+	}
+	idx := indexer.NewIndexer(projectRoot, store.NewGOBStore(filepath.Join(dataDir, "index.gob")), emb, chunker, scanner, time.Time{},
+		indexerOptionsIn(dataDir, nil, cfg)...)
+	if _, err := idx.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll failed: %v", err)
+	}
+	emb.Close()
+
+	// A dry run over a new index finds every chunk in the shared cache
+	empty := store.NewGOBStore(filepath.Join(t.TempDir(), "index.gob"))
+	est, err := estimateIndex(ctx, projectRoot, cfg, empty, chunker, scanner, time.Time{}, nil, false, indexerOptionsIn(dataDir, nil, cfg)...)
+	if err != nil {
+		t.Fatalf("estimateIndex failed: %v", err)
+	}
+	if est.Chunks == 0 || est.CachedChunks != est.Chunks || est.UncachedTokens != 0 {
+		t.Errorf("expected all chunks cached, got %+v", est)
+	}
+}
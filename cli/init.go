@@ -2,12 +2,15 @@ package cli
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/yoanbernabeu/grepai/config"
+	"github.com/yoanbernabeu/grepai/embedder"
 	"github.com/yoanbernabeu/grepai/git"
 	"github.com/yoanbernabeu/grepai/indexer"
 )
@@ -22,6 +25,8 @@ var (
 
 const (
 	lmStudioEmbeddingDimensions = 768
+	// selfHostedEndpoint is the default endpoint of TEI and llama.cpp servers.
+	selfHostedEndpoint = "http://localhost:8080"
 )
 
 var initCmd = &cobra.Command{
@@ -38,7 +43,7 @@ This command will:
 }
 
 func init() {
-	initCmd.Flags().StringVarP(&initProvider, "provider", "p", "", "Embedding provider (ollama, lmstudio, openai, synthetic, or openrouter)")
+	initCmd.Flags().StringVarP(&initProvider, "provider", "p", "", "Embedding provider (ollama, lmstudio, openai, azure-openai, synthetic, openrouter, tei, llamacpp, or local)")
 	initCmd.Flags().StringVarP(&initModel, "model", "m", "", "Embedding model (for openrouter: text-embedding-3-small, text-embedding-3-large, qwen3-embedding-8b)")
 	initCmd.Flags().StringVarP(&initBackend, "backend", "b", "", "Storage backend (gob, postgres, or qdrant)")
 	initCmd.Flags().BoolVar(&initNonInteractive, "yes", false, "Use defaults without prompting")
@@ -108,6 +113,9 @@ func runInit(cmd *cobra.Command, args []string) error {
 			fmt.Println("  3) openai (cloud, requires API key)")
 			fmt.Println("  4) synthetic (cloud, free embedding API)")
 			fmt.Println("  5) openrouter (cloud, multi-provider gateway)")
+			fmt.Println("  6) tei (self-hosted, Hugging Face Text Embeddings Inference)")
+			fmt.Println("  7) llamacpp (self-hosted, llama-server --embedding)")
+			fmt.Println("  8) local (built-in, offline, lower quality)")
 			fmt.Print("Choice [1]: ")
 
 			input, _ := reader.ReadString('\n')
@@ -160,6 +168,24 @@ func runInit(cmd *cobra.Command, args []string) error {
 				default:
 					cfg.Embedder.Model = "openai/text-embedding-3-small"
 				}
+			case "6", "tei", "7", "llamacpp":
+				cfg.Embedder.Provider = "tei"
+				name := "TEI"
+				if input == "7" || input == "llamacpp" {
+					cfg.Embedder.Provider = "llamacpp"
+					name = "llama.cpp"
+				}
+				fmt.Printf("%s endpoint [%s]: ", name, selfHostedEndpoint)
+				endpoint, _ := reader.ReadString('\n')
+				endpoint = strings.TrimSpace(endpoint)
+				if endpoint == "" {
+					endpoint = selfHostedEndpoint
+				}
+				cfg.Embedder.Endpoint = endpoint
+				detectServerModel(&cfg.Embedder)
+			case "8", "local":
+				cfg.Embedder.Provider = "local"
+				setLocalEmbedder(&cfg.Embedder)
 			default:
 				cfg.Embedder.Provider = "ollama"
 				fmt.Print("Ollama endpoint [http://localhost:11434]: ")
@@ -182,6 +208,10 @@ func runInit(cmd *cobra.Command, args []string) error {
 				cfg.Embedder.Model = "text-embedding-3-small"
 				cfg.Embedder.Endpoint = "https://api.openai.com/v1"
 				// OpenAI: leave Dimensions nil to use model's native dimensions
+			case "azure-openai":
+				cfg.Embedder.Model = "text-embedding-3-small"
+				cfg.Embedder.Endpoint = ""
+				cfg.Embedder.Dimensions = nil
 			case "synthetic":
 				cfg.Embedder.Model = "hf:nomic-ai/nomic-embed-text-v1.5"
 				cfg.Embedder.Endpoint = "https://api.synthetic.new/openai/v1"
@@ -191,6 +221,11 @@ func runInit(cmd *cobra.Command, args []string) error {
 				cfg.Embedder.Model = "openai/text-embedding-3-small"
 				cfg.Embedder.Endpoint = "https://openrouter.ai/api/v1"
 				// OpenRouter: leave Dimensions nil to use model's native dimensions
+			case "tei", "llamacpp":
+				cfg.Embedder.Endpoint = selfHostedEndpoint
+				detectServerModel(&cfg.Embedder)
+			case "local":
+				setLocalEmbedder(&cfg.Embedder)
 			}
 		}
 
@@ -268,6 +303,10 @@ func runInit(cmd *cobra.Command, args []string) error {
 				cfg.Embedder.Model = "text-embedding-3-small"
 				cfg.Embedder.Endpoint = "https://api.openai.com/v1"
 				cfg.Embedder.Dimensions = nil
+			case "azure-openai":
+				cfg.Embedder.Model = "text-embedding-3-small"
+				cfg.Embedder.Endpoint = ""
+				cfg.Embedder.Dimensions = nil
 			case "synthetic":
 				cfg.Embedder.Model = "hf:nomic-ai/nomic-embed-text-v1.5"
 				cfg.Embedder.Endpoint = "https://api.synthetic.new/openai/v1"
@@ -285,6 +324,11 @@ func runInit(cmd *cobra.Command, args []string) error {
 				default:
 					cfg.Embedder.Model = "openai/text-embedding-3-small"
 				}
+			case "tei", "llamacpp":
+				cfg.Embedder.Endpoint = selfHostedEndpoint
+				detectServerModel(&cfg.Embedder)
+			case "local":
+				setLocalEmbedder(&cfg.Embedder)
 			}
 		}
 		if initBackend != "" {
@@ -324,13 +368,73 @@ func runInit(cmd *cobra.Command, args []string) error {
 		fmt.Printf("  Endpoint: %s\n", cfg.Embedder.Endpoint)
 	case "openai":
 		fmt.Println("\nMake sure OPENAI_API_KEY is set in your environment.")
+	case "azure-openai":
+		fmt.Println("\nSet your Azure OpenAI resource and deployment in .grepai/config.yaml:")
+		fmt.Println("  embedder.endpoint: https://RESOURCE.openai.azure.com")
+		fmt.Println("  embedder.azure.deployment: your embedding deployment")
+		fmt.Println("and set AZURE_OPENAI_API_KEY, or embedder.azure.token_command for Microsoft Entra ID.")
 	case "synthetic":
 		fmt.Println("\nMake sure SYNTHETIC_API_KEY or OPENAI_API_KEY is set in your environment.")
 		fmt.Println("  Get your free API key at: https://api.synthetic.new")
 	case "openrouter":
 		fmt.Println("\nMake sure OPENROUTER_API_KEY or OPENAI_API_KEY is set in your environment.")
 		fmt.Println("  Get your API key at: https://openrouter.ai/keys")
+	case "tei":
+		fmt.Println("\nMake sure the Text Embeddings Inference server is running.")
+		fmt.Printf("  Endpoint: %s\n", cfg.Embedder.Endpoint)
+	case "llamacpp":
+		fmt.Println("\nMake sure llama-server is running with an embedding model:")
+		fmt.Println("  llama-server -m model.gguf --embedding --port 8080")
+	case "local":
+		fmt.Println("\nThe local embedder runs in-process without any service.")
+		fmt.Println("  Its results are deterministic but of lower quality than a neural model:")
+		fmt.Println("  use it for offline CI and tests, and ollama or a hosted provider for search.")
 	}
 
 	return nil
 }
+
+// detectServerModel fills the model (unless --model is set) and dimensions of
+// a self-hosted embedding server (TEI, llama.cpp) when it can be reached. The
+// dimensions size the PostgreSQL and Qdrant collections, and the model
+// selects the query and document prefixes.
+func detectServerModel(cfg *config.EmbedderConfig) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cfg.Model = initModel
+	cfg.Dimensions = nil
+	var detector interface {
+		ModelID(ctx context.Context) string
+		DetectDimensions(ctx context.Context) (int, error)
+	}
+	switch cfg.Provider {
+	case "tei":
+		detector = embedder.NewTEIEmbedder(embedder.WithTEIEndpoint(cfg.Endpoint), embedder.WithTEIKey(cfg.APIKey))
+	case "llamacpp":
+		detector = embedder.NewLlamaCppEmbedder(embedder.WithLlamaCppEndpoint(cfg.Endpoint), embedder.WithLlamaCppKey(cfg.APIKey))
+	default:
+		return
+	}
+
+	dimensions, err := detector.DetectDimensions(ctx)
+	if err != nil {
+		fmt.Printf("Warning: could not reach the embedding server at %s: %v\n", cfg.Endpoint, err)
+		fmt.Println("  Set embedder.model and embedder.dimensions in .grepai/config.yaml before indexing.")
+		return
+	}
+	if cfg.Model == "" {
+		cfg.Model = detector.ModelID(ctx)
+	}
+	cfg.Dimensions = &dimensions
+	fmt.Printf("Detected model %s (%d dimensions)\n", cfg.Model, dimensions)
+}
+
+// setLocalEmbedder configures the built-in local embedder, which needs no
+// endpoint.
+func setLocalEmbedder(cfg *config.EmbedderConfig) {
+	cfg.Model = embedder.LocalModel
+	cfg.Endpoint = ""
+	dim := embedder.NewLocalEmbedder().Dimensions()
+	cfg.Dimensions = &dim
+}
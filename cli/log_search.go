@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yoanbernabeu/grepai/config"
+	"github.com/yoanbernabeu/grepai/embedder"
+	"github.com/yoanbernabeu/grepai/git"
+	"github.com/yoanbernabeu/grepai/history"
+)
+
+var (
+	logSearchLimit    int
+	logSearchJSON     bool
+	logSearchNoUpdate bool
+	logSearchRebuild  bool
+)
+
+var logSearchCmd = &cobra.Command{
+	Use:   "log-search <query>",
+	Short: "Search the commit history with natural language",
+	Long: `Search the commits of the project by intent, e.g. "when and why did we
+change how sessions expire".
+
+Each commit message and a summarized diff of each file it touched are embedded
+into a history index of their own (.grepai/history/ with the GOB backend). The
+index is updated with the commits that appeared since the last run before
+searching, and kept up to date by 'grepai watch'.
+
+Requires history.enabled: true in .grepai/config.yaml. The history section
+also caps the number of commits (max_commits) and the size of diff summaries.
+
+Examples:
+  grepai log-search "when did session expiry change"
+  grepai log-search "switch from bcrypt" --limit 5 --json
+  grepai log-search "rate limiting" --no-update`,
+	Args: cobra.ExactArgs(1),
+	RunE: runLogSearch,
+}
+
+func init() {
+	logSearchCmd.Flags().IntVarP(&logSearchLimit, "limit", "n", 10, "Maximum number of commits to return")
+	logSearchCmd.Flags().BoolVarP(&logSearchJSON, "json", "j", false, "Output results in JSON format (for AI agents)")
+	logSearchCmd.Flags().BoolVar(&logSearchNoUpdate, "no-update", false, "Search without indexing new commits first")
+	logSearchCmd.Flags().BoolVar(&logSearchRebuild, "rebuild", false, "Re-embed the whole history before searching")
+	logSearchCmd.MarkFlagsMutuallyExclusive("no-update", "rebuild")
+}
+
+func runLogSearch(cmd *cobra.Command, args []string) error {
+	query := args[0]
+	ctx := context.Background()
+
+	projectRoot, err := config.FindProjectRoot()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load(projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if !cfg.History.Enabled {
+		return fmt.Errorf("commit history indexing is disabled. Set history.enabled: true in .grepai/config.yaml")
+	}
+	if !git.IsGitRepo(projectRoot) {
+		return fmt.Errorf("%s is not a git repository", projectRoot)
+	}
+
+	emb, err := embedder.NewFromConfig(cfg, embedder.WithUsageFile(config.GetUsagePath(projectRoot)))
+	if err != nil {
+		return fmt.Errorf("failed to initialize embedder: %w", err)
+	}
+	defer emb.Close()
+
+	st, err := history.OpenStore(ctx, cfg, projectRoot)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	commitLog, err := history.OpenLog(projectRoot)
+	if err != nil {
+		return err
+	}
+
+	if logSearchRebuild {
+		if err := clearIndex(ctx, st); err != nil {
+			return err
+		}
+		commitLog.Reset()
+	}
+	if !logSearchNoUpdate {
+		idx := newHistoryIndexer(projectRoot, cfg, st, emb, commitLog)
+		indexed, err := idx.Update(ctx, func(done, total int) {
+			fmt.Fprintf(os.Stderr, "\rIndexing commits: %d/%d", done, total)
+		})
+		if indexed > 0 {
+			fmt.Fprintln(os.Stderr)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to index commit history: %w", err)
+		}
+	}
+
+	results, err := history.Search(ctx, st, emb, commitLog, query, logSearchLimit)
+	if err != nil {
+		return err
+	}
+
+	if logSearchJSON {
+		if results == nil {
+			results = []history.Result{}
+		}
+		return printJSON(results)
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No commits found.")
+		return nil
+	}
+	fmt.Printf("Found %d commits for: %q\n\n", len(results), query)
+	for i, r := range results {
+		fmt.Printf("─── Commit %d (score: %.4f) ───\n", i+1, r.Score)
+		fmt.Printf("%s %s\n", shortSHA(r.SHA), r.Subject)
+		fmt.Printf("Author: %s <%s>\n", r.Author, r.Email)
+		fmt.Printf("Date:   %s\n", r.Date.Format("2006-01-02 15:04:05 -0700"))
+		fmt.Printf("Files:  %s\n", formatFileList(r.Files, 5))
+		if r.DiffExcerpt != "" {
+			fmt.Println()
+			if r.MatchedFile != "" {
+				fmt.Printf("  %s\n", r.MatchedFile)
+			}
+			lines := strings.Split(strings.TrimRight(r.DiffExcerpt, "\n"), "\n")
+			for j, line := range lines {
+				if j == 15 {
+					fmt.Printf("  ... (%d more lines)\n", len(lines)-15)
+					break
+				}
+				fmt.Printf("  %s\n", line)
+			}
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// shortSHA abbreviates a commit SHA for display.
+func shortSHA(sha string) string {
+	if len(sha) > 10 {
+		return sha[:10]
+	}
+	return sha
+}
+
+// formatFileList joins the first max files and counts the others.
+func formatFileList(files []string, max int) string {
+	if len(files) <= max {
+		return strings.Join(files, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(files[:max], ", "), len(files)-max)
+}
@@ -0,0 +1,220 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/yoanbernabeu/grepai/config"
+	"github.com/yoanbernabeu/grepai/git"
+	"github.com/yoanbernabeu/grepai/store"
+)
+
+// initializeOverlayStore opens the overlay index of a branch.
+func initializeOverlayStore(ctx context.Context, cfg *config.Config, projectRoot, branch string) (store.VectorStore, error) {
+	switch cfg.Store.Backend {
+	case "gob":
+		gobStore := store.NewGOBStore(config.GetOverlayIndexPath(projectRoot, branch))
+		if err := gobStore.Load(ctx); err != nil {
+			return nil, fmt.Errorf("failed to load overlay of %s: %w", branch, err)
+		}
+		return gobStore, nil
+	case "postgres":
+		return store.NewPostgresStore(ctx, cfg.Store.Postgres.DSN, config.OverlayProjectID(projectRoot, branch), cfg.Embedder.GetDimensions())
+	default:
+		return nil, fmt.Errorf("branch overlays are not supported by the %s backend", cfg.Store.Backend)
+	}
+}
+
+// openOverlay opens the overlay of a branch, creating it if needed.
+func openOverlay(ctx context.Context, cfg *config.Config, projectRoot, branch string) (store.VectorStore, error) {
+	if err := config.EnsureOverlayInfo(projectRoot, branch); err != nil {
+		return nil, err
+	}
+	return initializeOverlayStore(ctx, cfg, projectRoot, branch)
+}
+
+// initializeIndexStore opens the store indexing commands write to. With
+// branch overlays enabled, it is the main index layered with the overlay of
+// the checked out branch (none on the default branch), and overlays of
+// deleted branches are removed.
+func initializeIndexStore(ctx context.Context, cfg *config.Config, projectRoot string) (store.VectorStore, error) {
+	st, err := initializeStore(ctx, cfg, projectRoot)
+	if err != nil || !cfg.Branches.Overlay {
+		return st, err
+	}
+	if !config.SupportsOverlays(cfg.Store.Backend) {
+		log.Printf("Warning: branch overlays are not supported by the %s backend, indexing all branches into the main index", cfg.Store.Backend)
+		return st, nil
+	}
+
+	branch, err := config.OverlayBranch(projectRoot, cfg)
+	if err != nil {
+		st.Close()
+		return nil, err
+	}
+	gcOverlays(ctx, cfg, projectRoot, branch)
+
+	overlayStore := store.NewOverlayStore(st, nil)
+	if branch != "" {
+		overlay, err := openOverlay(ctx, cfg, projectRoot, branch)
+		if err != nil {
+			st.Close()
+			return nil, err
+		}
+		overlayStore.SetOverlay(overlay)
+		log.Printf("Indexing branch %s into its overlay", branch)
+	}
+	return overlayStore, nil
+}
+
+// initializeSearchOverlay opens the overlay of the checked out branch for
+// searching, or returns nil when there is none.
+func initializeSearchOverlay(ctx context.Context, cfg *config.Config, projectRoot string) store.VectorStore {
+	branch, err := config.OverlayBranch(projectRoot, cfg)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return nil
+	}
+	if branch == "" || !config.HasOverlay(projectRoot, branch) {
+		return nil
+	}
+	overlay, err := initializeOverlayStore(ctx, cfg, projectRoot, branch)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return nil
+	}
+	return overlay
+}
+
+// gcOverlays removes the overlays of branches that no longer exist, except
+// the one of keep.
+func gcOverlays(ctx context.Context, cfg *config.Config, projectRoot, keep string) {
+	overlays, err := config.ListOverlays(projectRoot)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return
+	}
+	if len(overlays) == 0 {
+		return
+	}
+	branches, err := git.Branches(projectRoot)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return
+	}
+	exists := make(map[string]bool, len(branches))
+	for _, branch := range branches {
+		exists[branch] = true
+	}
+	for _, overlay := range overlays {
+		if overlay.Branch == keep || exists[overlay.Branch] {
+			continue
+		}
+		if err := removeOverlay(ctx, cfg, projectRoot, overlay.Branch); err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+		log.Printf("Removed overlay of deleted branch %s", overlay.Branch)
+	}
+}
+
+// removeOverlay deletes the overlay of a branch and its documents.
+func removeOverlay(ctx context.Context, cfg *config.Config, projectRoot, branch string) error {
+	if cfg.Store.Backend == "postgres" {
+		overlay, err := initializeOverlayStore(ctx, cfg, projectRoot, branch)
+		if err != nil {
+			return err
+		}
+		err = clearIndex(ctx, overlay)
+		overlay.Close()
+		if err != nil {
+			return fmt.Errorf("failed to clear overlay of %s: %w", branch, err)
+		}
+	}
+	return config.RemoveOverlayDir(projectRoot, branch)
+}
+
+// branchTracker switches the overlay written by the watcher when another
+// branch is checked out. Files that differ between the branches then arrive
+// as file events and are indexed into the new overlay.
+type branchTracker struct {
+	cfg           *config.Config
+	projectRoot   string
+	store         *store.OverlayStore
+	head          *git.Head
+	defaultBranch string
+	branch        string // Branch of the current overlay, "" for the main index
+}
+
+// newBranchTracker returns nil when st has no branch overlays.
+func newBranchTracker(cfg *config.Config, projectRoot string, st store.VectorStore) *branchTracker {
+	overlayStore, ok := st.(*store.OverlayStore)
+	if !ok {
+		return nil
+	}
+	head, err := git.OpenHead(projectRoot)
+	if err != nil {
+		log.Printf("Warning: branch switches will not be detected: %v", err)
+		return nil
+	}
+	defaultBranch, err := config.DefaultBranch(projectRoot, cfg)
+	if err != nil {
+		log.Printf("Warning: branch switches will not be detected: %v", err)
+		return nil
+	}
+	t := &branchTracker{
+		cfg:           cfg,
+		projectRoot:   projectRoot,
+		store:         overlayStore,
+		head:          head,
+		defaultBranch: defaultBranch,
+	}
+	t.branch = t.overlayBranch()
+	return t
+}
+
+// overlayBranch returns the branch checked out, or "" on the default branch.
+func (t *branchTracker) overlayBranch() string {
+	branch, err := t.head.Branch()
+	if err != nil || branch == t.defaultBranch {
+		return ""
+	}
+	return branch
+}
+
+// sync switches overlays if another branch was checked out. It is called
+// before each file event.
+func (t *branchTracker) sync(ctx context.Context) {
+	if t == nil {
+		return
+	}
+	branch := t.overlayBranch()
+	if branch == t.branch {
+		return
+	}
+
+	var overlay store.VectorStore
+	if branch != "" {
+		var err error
+		overlay, err = openOverlay(ctx, t.cfg, t.projectRoot, branch)
+		if err != nil {
+			log.Printf("Warning: failed to switch to the overlay of %s: %v", branch, err)
+			return
+		}
+	}
+	if previous := t.store.SetOverlay(overlay); previous != nil {
+		if err := previous.Persist(ctx); err != nil {
+			log.Printf("Warning: failed to persist overlay of %s: %v", t.branch, err)
+		}
+		previous.Close()
+	}
+	t.branch = branch
+
+	if branch == "" {
+		log.Printf("Switched to default branch %s, indexing into the main index", t.defaultBranch)
+	} else {
+		log.Printf("Switched to branch %s, indexing into its overlay", branch)
+	}
+	gcOverlays(ctx, t.cfg, t.projectRoot, branch)
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/yoanbernabeu/grepai/config"
+	"github.com/yoanbernabeu/grepai/git"
+	"github.com/yoanbernabeu/grepai/indexer"
+	"github.com/yoanbernabeu/grepai/store"
+)
+
+// initializeRefStore opens the index of a git ref built by
+// `grepai index --ref`. It is kept apart from the working tree index: a file
+// of .grepai/refs/ for gob, its own project id for postgres and its own
+// collection for qdrant.
+func initializeRefStore(ctx context.Context, cfg *config.Config, projectRoot, ref string) (store.VectorStore, error) {
+	switch cfg.Store.Backend {
+	case "gob":
+		gobStore := store.NewGOBStore(filepath.Join(config.GetRefDir(projectRoot, ref), config.IndexFileName))
+		if err := gobStore.Load(ctx); err != nil {
+			return nil, fmt.Errorf("failed to load index of ref %s: %w", ref, err)
+		}
+		return gobStore, nil
+	case "postgres":
+		return store.NewPostgresStore(ctx, cfg.Store.Postgres.DSN, projectRoot+"@"+ref, cfg.Embedder.GetDimensions())
+	case "qdrant":
+		collectionName := cfg.Store.Qdrant.Collection
+		if collectionName == "" {
+			collectionName = store.SanitizeCollectionName(projectRoot)
+		}
+		collectionName += "_" + config.RefSlug(ref)
+		return store.NewQdrantStore(ctx, cfg.Store.Qdrant.Endpoint, cfg.Store.Qdrant.Port, cfg.Store.Qdrant.UseTLS, collectionName, cfg.Store.Qdrant.APIKey, cfg.Embedder.GetDimensions())
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", cfg.Store.Backend)
+	}
+}
+
+// newRefScanner returns a scanner reading the files of a git tree with the
+// same ignore rules and file types as the working tree. .gitignore,
+// .grepaiignore and .gitattributes files are read from the tree itself.
+func newRefScanner(projectRoot, ref string, tree *git.Tree, cfg *config.Config) (*indexer.Scanner, error) {
+	ignoreMatcher, err := indexer.NewIgnoreMatcherFS(tree, cfg.Ignore, cfg.ExternalGitignore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize ignore matcher: %w", err)
+	}
+	report := loadGeneratedReport(filepath.Join(config.GetRefDir(projectRoot, ref), config.GeneratedReportName))
+	opts := append(scannerOptions(cfg.Index, report), indexer.WithFS(tree))
+	return indexer.NewScanner(projectRoot, ignoreMatcher, opts...), nil
+}
+
+// requireRefIndex returns an error when ref was never indexed.
+func requireRefIndex(projectRoot, ref string) error {
+	_, err := config.LoadRefInfo(projectRoot, ref)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("ref %s is not indexed. Run 'grepai index --ref %s' first", ref, ref)
+	}
+	return err
+}
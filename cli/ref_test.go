@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yoanbernabeu/grepai/config"
+	"github.com/yoanbernabeu/grepai/git"
+	"github.com/yoanbernabeu/grepai/indexer"
+)
+
+func TestIndexRef(t *testing.T) {
+	ctx := context.Background()
+	projectRoot := t.TempDir()
+	runGitDiscovery(t, projectRoot, "init")
+	runGitDiscovery(t, projectRoot, "config", "user.email", "test@example.com")
+	runGitDiscovery(t, projectRoot, "config", "user.name", "Test User")
+
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(projectRoot, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	write(".gitignore", "ignored.go\n")
+	write("main.go", "package main\n\nfunc committed() {}\n")
+	write("ignored.go", "package main\n")
+	runGitDiscovery(t, projectRoot, "add", "-f", ".")
+	runGitDiscovery(t, projectRoot, "commit", "-m", "init")
+	runGitDiscovery(t, projectRoot, "tag", "v1")
+
+	// The working tree moves on; the ref index must not see it
+	write("main.go", "package main\n\nfunc uncommitted() {}\n")
+	write("new.go", "package main\n")
+
+	if err := requireRefIndex(projectRoot, "v1"); err == nil || !strings.Contains(err.Error(), "grepai index --ref v1") {
+		t.Fatalf("expected an error for an unindexed ref, got %v", err)
+	}
+
+	tree, err := git.OpenTree(ctx, projectRoot, "v1")
+	if err != nil {
+		t.Fatalf("OpenTree failed: %v", err)
+	}
+	defer tree.Close()
+
+	cfg := config.DefaultConfig()
+	st, err := initializeRefStore(ctx, cfg, projectRoot, "v1")
+	if err != nil {
+		t.Fatalf("initializeRefStore failed: %v", err)
+	}
+	defer st.Close()
+	scanner, err := newRefScanner(projectRoot, "v1", tree, cfg)
+	if err != nil {
+		t.Fatalf("newRefScanner failed: %v", err)
+	}
+	idx := indexer.NewIndexer(projectRoot, st, &noOpEmbedder{}, indexer.NewChunker(512, 50), scanner, time.Time{})
+	if _, err := idx.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll failed: %v", err)
+	}
+	if err := st.Persist(ctx); err != nil {
+		t.Fatalf("Persist failed: %v", err)
+	}
+	if err := config.SaveRefInfo(projectRoot, config.RefInfo{Ref: "v1", Commit: tree.Commit, IndexedAt: time.Now()}); err != nil {
+		t.Fatalf("SaveRefInfo failed: %v", err)
+	}
+
+	docs, err := st.ListDocuments(ctx)
+	if err != nil {
+		t.Fatalf("ListDocuments failed: %v", err)
+	}
+	if len(docs) != 1 || docs[0] != "main.go" {
+		t.Fatalf("expected only main.go in the ref index, got %v", docs)
+	}
+	chunks, err := st.GetChunksForFile(ctx, "main.go")
+	if err != nil || len(chunks) == 0 {
+		t.Fatalf("expected chunks for main.go: %v", err)
+	}
+	if !strings.Contains(chunks[0].Content, "committed()") || strings.Contains(chunks[0].Content, "uncommitted") {
+		t.Errorf("expected the committed content, got %q", chunks[0].Content)
+	}
+
+	if err := requireRefIndex(projectRoot, "v1"); err != nil {
+		t.Errorf("expected v1 to be indexed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(config.GetRefDir(projectRoot, "v1"), config.IndexFileName)); err != nil {
+		t.Errorf("expected the ref index under .grepai/refs: %v", err)
+	}
+	if _, err := os.Stat(config.GetIndexPath(projectRoot)); !os.IsNotExist(err) {
+		t.Errorf("the working tree index must not be written, got %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/alpkeskin/gotoon"
+	"github.com/spf13/cobra"
+	"github.com/yoanbernabeu/grepai/config"
+	"github.com/yoanbernabeu/grepai/embedder"
+	"github.com/yoanbernabeu/grepai/git"
+	"github.com/yoanbernabeu/grepai/review"
+	"github.com/yoanbernabeu/grepai/search"
+	"github.com/yoanbernabeu/grepai/trace"
+)
+
+var (
+	reviewBudget  int
+	reviewPerHunk int
+	reviewJSON    bool
+	reviewTOON    bool
+)
+
+var reviewContextCmd = &cobra.Command{
+	Use:   "review-context <base>..<head>",
+	Short: "Build the context to review a change",
+	Long: `Build a compact context pack to review a git diff.
+
+For each changed hunk, grepai searches the most similar code outside the
+changed files, lists the callers and callees of the changed symbols and, with
+RPG enabled, the feature areas involved. The pack fits a token budget: hunk
+locations come first, then call graph context, then related code by rank.
+
+Feed the pack to an AI reviewer alongside the diff, so that it sees the code
+the change should stay consistent with.
+
+Examples:
+  grepai review-context main..HEAD
+  grepai review-context main..feature/login --budget 8000 --toon
+  grepai review-context origin/main..HEAD --per-hunk 5 --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReviewContext,
+}
+
+func init() {
+	reviewContextCmd.Flags().IntVar(&reviewBudget, "budget", review.DefaultBudget, "Estimated token budget of the pack (0 for no limit)")
+	reviewContextCmd.Flags().IntVarP(&reviewPerHunk, "per-hunk", "n", review.DefaultPerHunk, "Related code chunks per hunk")
+	reviewContextCmd.Flags().BoolVarP(&reviewJSON, "json", "j", false, "Output the pack in JSON format")
+	reviewContextCmd.Flags().BoolVarP(&reviewTOON, "toon", "t", false, "Output the pack in TOON format (token-efficient)")
+	reviewContextCmd.MarkFlagsMutuallyExclusive("json", "toon")
+}
+
+func runReviewContext(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	rng := args[0]
+	if !strings.Contains(rng, "..") {
+		return fmt.Errorf("expected a range <base>..<head>, got %q", rng)
+	}
+	if err := git.ValidateRevision(rng); err != nil {
+		return err
+	}
+
+	projectRoot, err := config.FindProjectRoot()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load(projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if !git.IsGitRepo(projectRoot) {
+		return fmt.Errorf("%s is not a git repository", projectRoot)
+	}
+
+	diffs, err := git.Diff(ctx, projectRoot, rng, false)
+	if err != nil {
+		return err
+	}
+
+	emb, err := embedder.NewFromConfig(cfg, embedder.WithUsageFile(config.GetUsagePath(projectRoot)))
+	if err != nil {
+		return fmt.Errorf("failed to initialize embedder: %w", err)
+	}
+	defer emb.Close()
+
+	st, err := initializeStore(ctx, cfg, projectRoot)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	overlay := initializeSearchOverlay(ctx, cfg, projectRoot)
+	if overlay != nil {
+		defer overlay.Close()
+	}
+	searcher := search.NewSearcher(st, emb, cfg.Search,
+		search.WithDirConfigs(loadDirConfigs(projectRoot, cfg, nil)),
+		search.WithOverlay(overlay))
+
+	opts := review.Options{
+		PerHunk: reviewPerHunk,
+		Budget:  reviewBudget,
+		Enrich: func(symbols ...*trace.Symbol) {
+			enrichSymbolsWithRPG(projectRoot, cfg, symbols...)
+		},
+	}
+	if cfg.Redaction.Enabled(cfg.Embedder) {
+		opts.Redactor = cfg.Redaction.NewRedactor()
+	}
+	// The call graph is best-effort: the pack is still useful without it
+	symbolStore := trace.NewGOBSymbolStore(config.GetSymbolIndexPath(projectRoot))
+	if err := symbolStore.Load(ctx); err == nil {
+		defer symbolStore.Close()
+		if stats, err := symbolStore.GetStats(ctx); err == nil && stats.TotalSymbols > 0 {
+			opts.Symbols = symbolStore
+		}
+	}
+
+	pack, err := review.Build(ctx, searcher, diffs, opts)
+	if err != nil {
+		return err
+	}
+	pack.Range = rng
+
+	switch {
+	case reviewJSON:
+		return printJSON(pack)
+	case reviewTOON:
+		output, err := gotoon.Encode(pack)
+		if err != nil {
+			return fmt.Errorf("failed to encode TOON: %w", err)
+		}
+		fmt.Println(output)
+		return nil
+	}
+	writeReviewMarkdown(os.Stdout, pack)
+	return nil
+}
+
+// writeReviewMarkdown writes a review context pack as Markdown.
+func writeReviewMarkdown(w io.Writer, pack *review.Pack) {
+	fmt.Fprintf(w, "## Review context: %s\n\n", pack.Range)
+	fmt.Fprintf(w, "%d hunks, ~%d tokens", len(pack.Hunks), pack.Tokens)
+	if pack.Truncated {
+		fmt.Fprint(w, " (truncated to fit the budget)")
+	}
+	fmt.Fprintln(w)
+
+	if len(pack.Features) > 0 {
+		fmt.Fprintln(w, "\n### Feature areas")
+		fmt.Fprintln(w)
+		for _, feature := range pack.Features {
+			fmt.Fprintf(w, "- %s\n", feature)
+		}
+	}
+
+	for _, h := range pack.Hunks {
+		fmt.Fprintf(w, "\n### `%s:%d-%d` (+%d -%d)\n", h.File, h.StartLine, h.EndLine, h.Added, h.Removed)
+		if len(h.Symbols) > 0 {
+			fmt.Fprintf(w, "\nChanged: `%s`\n", strings.Join(h.Symbols, "`, `"))
+		}
+		writeSymbolRefs(w, "Called by", h.Callers)
+		writeSymbolRefs(w, "Calls", h.Callees)
+		for _, rel := range h.Related {
+			fmt.Fprintf(w, "\n`%s:%d-%d` (score %.2f)\n\n```\n%s\n```\n", rel.File, rel.StartLine, rel.EndLine, rel.Score, rel.Snippet)
+		}
+	}
+}
+
+// writeSymbolRefs writes a labeled list of symbol locations on one line.
+func writeSymbolRefs(w io.Writer, label string, refs []review.SymbolRef) {
+	if len(refs) == 0 {
+		return
+	}
+	parts := make([]string, len(refs))
+	for i, ref := range refs {
+		if ref.File == "" {
+			parts[i] = fmt.Sprintf("`%s`", ref.Name)
+			continue
+		}
+		parts[i] = fmt.Sprintf("`%s` (%s)", ref.Name, formatLocation(ref.File, ref.Line, 0))
+	}
+	fmt.Fprintf(w, "\n%s: %s\n", label, strings.Join(parts, ", "))
+}
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/yoanbernabeu/grepai/review"
+)
+
+func TestWriteReviewMarkdown(t *testing.T) {
+	pack := &review.Pack{
+		Range:     "main..HEAD",
+		Tokens:    120,
+		Truncated: true,
+		Hunks: []review.Hunk{{
+			File: "auth/login.go", StartLine: 4, EndLine: 6, Added: 3, Removed: 1,
+			Symbols: []string{"Login"},
+			Callers: []review.SymbolRef{{Name: "HandleAuth", File: "api/handler.go", Line: 3}},
+			Callees: []review.SymbolRef{{Name: "hash"}},
+			Related: []review.Related{{File: "auth/password.go", StartLine: 1, EndLine: 3, Score: 0.9, Snippet: "func hashPassword() {}"}},
+		}},
+		Features: []string{"auth/login"},
+	}
+
+	var buf bytes.Buffer
+	writeReviewMarkdown(&buf, pack)
+	out := buf.String()
+
+	for _, want := range []string{
+		"## Review context: main..HEAD",
+		"1 hunks, ~120 tokens (truncated to fit the budget)",
+		"- auth/login",
+		"### `auth/login.go:4-6` (+3 -1)",
+		"Changed: `Login`",
+		"Called by: `HandleAuth` (api/handler.go:3)",
+		"Calls: `hash`",
+		"`auth/password.go:1-3` (score 0.90)\n\n```\nfunc hashPassword() {}\n```",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+}
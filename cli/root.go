@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -31,6 +32,33 @@ func Execute() error {
 	return rootCmd.Execute()
 }
 
+// ExitError is returned by commands that exit with a specific status code.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the process exit status for an error returned by Execute:
+// the code of an ExitError, 1 for any other error and 0 for nil.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return 1
+}
+
 // GetRootCmd returns the root command for documentation generation
 func GetRootCmd() *cobra.Command {
 	return rootCmd
@@ -40,9 +68,15 @@ func init() {
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(watchCmd)
+	rootCmd.AddCommand(indexCmd)
+	rootCmd.AddCommand(secretsCmd)
 	rootCmd.AddCommand(searchCmd)
+	rootCmd.AddCommand(logSearchCmd)
+	rootCmd.AddCommand(impactCmd)
+	rootCmd.AddCommand(reviewContextCmd)
 	rootCmd.AddCommand(agentSetupCmd)
 	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(cacheCmd)
 	rootCmd.AddCommand(workspaceCmd)
 }
 
@@ -11,6 +11,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/yoanbernabeu/grepai/config"
 	"github.com/yoanbernabeu/grepai/embedder"
+	"github.com/yoanbernabeu/grepai/git"
 	"github.com/yoanbernabeu/grepai/rpg"
 	"github.com/yoanbernabeu/grepai/search"
 	"github.com/yoanbernabeu/grepai/store"
@@ -24,6 +25,9 @@ var (
 	searchWorkspace string
 	searchProjects  []string
 	searchPath      string
+	searchLanguage  string
+	searchRef       string
+	searchBlame     bool
 )
 
 // SearchResultJSON is a lightweight struct for JSON output (excludes vector, hash, updated_at)
@@ -31,22 +35,38 @@ type SearchResultJSON struct {
 	FilePath    string  `json:"file_path"`
 	StartLine   int     `json:"start_line"`
 	EndLine     int     `json:"end_line"`
+	Cell        int     `json:"cell,omitempty"`
+	Language    string  `json:"language,omitempty"`
 	Score       float32 `json:"score"`
 	Content     string  `json:"content"`
 	FeaturePath string  `json:"feature_path,omitempty"`
 	SymbolName  string  `json:"symbol_name,omitempty"`
 }
 
+// SearchResultBlameJSON is a SearchResultJSON with git blame metadata (--blame)
+type SearchResultBlameJSON struct {
+	SearchResultJSON
+	Blame *git.BlameInfo `json:"blame,omitempty"`
+}
+
 // SearchResultCompactJSON is a minimal struct for compact JSON output (no content field)
 type SearchResultCompactJSON struct {
 	FilePath    string  `json:"file_path"`
 	StartLine   int     `json:"start_line"`
 	EndLine     int     `json:"end_line"`
+	Cell        int     `json:"cell,omitempty"`
+	Language    string  `json:"language,omitempty"`
 	Score       float32 `json:"score"`
 	FeaturePath string  `json:"feature_path,omitempty"`
 	SymbolName  string  `json:"symbol_name,omitempty"`
 }
 
+// SearchResultCompactBlameJSON is a SearchResultCompactJSON with git blame metadata (--blame)
+type SearchResultCompactBlameJSON struct {
+	SearchResultCompactJSON
+	Blame *git.BlameInfo `json:"blame,omitempty"`
+}
+
 var searchCmd = &cobra.Command{
 	Use:   "search <query>",
 	Short: "Search codebase with natural language",
@@ -68,13 +88,19 @@ func init() {
 	searchCmd.Flags().StringVar(&searchWorkspace, "workspace", "", "Workspace name for cross-project search")
 	searchCmd.Flags().StringArrayVar(&searchProjects, "project", nil, "Project name(s) to search (requires --workspace, can be repeated)")
 	searchCmd.Flags().StringVar(&searchPath, "path", "", "Path prefix to filter search results")
+	searchCmd.Flags().StringVar(&searchLanguage, "lang", "", "Only return results of this language (e.g. go, python, dockerfile)")
+	searchCmd.Flags().StringVar(&searchRef, "ref", "", "Search the index of a git ref built with 'grepai index --ref'")
+	searchCmd.Flags().BoolVar(&searchBlame, "blame", false, "Add the last commit and change count of each result (git blame)")
+	searchCmd.MarkFlagsMutuallyExclusive("ref", "workspace")
+	searchCmd.MarkFlagsMutuallyExclusive("ref", "blame")
 	searchCmd.MarkFlagsMutuallyExclusive("json", "toon")
 }
 
-// rpgEnrichment holds RPG context for a search result
+// rpgEnrichment holds RPG context and git blame metadata for a search result
 type rpgEnrichment struct {
 	FeaturePath string
 	SymbolName  string
+	Blame       *git.BlameInfo
 }
 
 // enrichWithRPG enriches search results with RPG feature paths and symbol names
@@ -130,7 +156,7 @@ func runSearch(cmd *cobra.Command, args []string) error {
 
 	// Workspace mode
 	if searchWorkspace != "" {
-		return runWorkspaceSearch(ctx, query, searchProjects, searchPath)
+		return runWorkspaceSearch(ctx, query, searchProjects, searchPath, searchLanguage)
 	}
 
 	// Find project root
@@ -144,9 +170,14 @@ func runSearch(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
+	if searchRef != "" {
+		if err := requireRefIndex(projectRoot, searchRef); err != nil {
+			return err
+		}
+	}
 
 	// Initialize embedder
-	emb, err := embedder.NewFromConfig(cfg)
+	emb, err := embedder.NewFromConfig(cfg, embedder.WithUsageFile(config.GetUsagePath(projectRoot)))
 	if err != nil {
 		return fmt.Errorf("failed to initialize embedder: %w", err)
 	}
@@ -154,21 +185,27 @@ func runSearch(cmd *cobra.Command, args []string) error {
 
 	// Initialize store
 	var st store.VectorStore
-	switch cfg.Store.Backend {
-	case "gob":
+	switch {
+	case searchRef != "":
+		var err error
+		st, err = initializeRefStore(ctx, cfg, projectRoot, searchRef)
+		if err != nil {
+			return err
+		}
+	case cfg.Store.Backend == "gob":
 		indexPath := config.GetIndexPath(projectRoot)
 		gobStore := store.NewGOBStore(indexPath)
 		if err := gobStore.Load(ctx); err != nil {
 			return fmt.Errorf("failed to load index: %w", err)
 		}
 		st = gobStore
-	case "postgres":
+	case cfg.Store.Backend == "postgres":
 		var err error
 		st, err = store.NewPostgresStore(ctx, cfg.Store.Postgres.DSN, projectRoot, cfg.Embedder.GetDimensions())
 		if err != nil {
 			return fmt.Errorf("failed to connect to postgres: %w", err)
 		}
-	case "qdrant":
+	case cfg.Store.Backend == "qdrant":
 		collectionName := cfg.Store.Qdrant.Collection
 		if collectionName == "" {
 			collectionName = store.SanitizeCollectionName(projectRoot)
@@ -183,11 +220,40 @@ func runSearch(cmd *cobra.Command, args []string) error {
 	}
 	defer st.Close()
 
+	// Merge the overlay of the checked out branch
+	var overlay store.VectorStore
+	if searchRef == "" {
+		overlay = initializeSearchOverlay(ctx, cfg, projectRoot)
+	}
+	if overlay != nil {
+		defer overlay.Close()
+	}
+
+	// Blame the working tree for metadata and recency boosts
+	var blameCache *git.BlameCache
+	if searchRef == "" && (searchBlame || cfg.Search.Blame.Enabled || len(cfg.Search.Boost.Recency) > 0) {
+		blameCache = openBlameCache(projectRoot, cfg)
+	}
+	if blameCache != nil {
+		defer func() {
+			if err := blameCache.Persist(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to save blame cache: %v\n", err)
+			}
+		}()
+	}
+
 	// Create searcher with boost config
-	searcher := search.NewSearcher(st, emb, cfg.Search)
+	opts := []search.SearcherOption{
+		search.WithDirConfigs(loadDirConfigs(projectRoot, cfg, nil)),
+		search.WithOverlay(overlay),
+	}
+	if blameCache != nil && len(cfg.Search.Boost.Recency) > 0 {
+		opts = append(opts, search.WithRecency(search.BlameRecency(ctx, blameCache)))
+	}
+	searcher := search.NewSearcher(st, emb, cfg.Search, opts...)
 
 	// Search with boosting
-	results, err := searcher.Search(ctx, query, searchLimit, searchPath)
+	results, err := searcher.SearchWithOptions(ctx, query, searchLimit, store.SearchOptions{PathPrefix: searchPath, Language: searchLanguage})
 	if err != nil {
 		if searchJSON {
 			return outputSearchErrorJSON(err)
@@ -198,8 +264,16 @@ func runSearch(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("search failed: %w", err)
 	}
 
-	// Enrich results with RPG context
-	enrichments := enrichWithRPG(projectRoot, cfg, results)
+	// Enrich results with RPG context; the graph describes the working tree
+	enrichments := make([]rpgEnrichment, len(results))
+	if searchRef == "" {
+		enrichments = enrichWithRPG(projectRoot, cfg, results)
+	}
+	if blameCache != nil && (searchBlame || cfg.Search.Blame.Enabled) {
+		for i, info := range search.Blame(ctx, blameCache, results) {
+			enrichments[i].Blame = info
+		}
+	}
 
 	// JSON output mode
 	if searchJSON {
@@ -227,16 +301,16 @@ func runSearch(cmd *cobra.Command, args []string) error {
 
 	for i, result := range results {
 		fmt.Printf("─── Result %d (score: %.4f) ───\n", i+1, result.Score)
-		fmt.Printf("File: %s:%d-%d\n", result.Chunk.FilePath, result.Chunk.StartLine, result.Chunk.EndLine)
+		fmt.Printf("File: %s-%d\n", formatLocation(result.Chunk.FilePath, result.Chunk.StartLine, result.Chunk.Cell), result.Chunk.EndLine)
+		if info := enrichments[i].Blame; info != nil {
+			fmt.Printf("Last change: %s\n", formatBlame(info))
+		}
 		fmt.Println()
 
 		// Display content with line numbers
 		lines := strings.Split(result.Chunk.Content, "\n")
-		// Skip the "File: xxx" prefix line if present
-		startIdx := 0
-		if len(lines) > 0 && strings.HasPrefix(lines[0], "File: ") {
-			startIdx = 2 // Skip "File: xxx" and empty line
-		}
+		// Skip the "File: xxx" context header if present
+		startIdx := contextHeaderLines(lines)
 
 		lineNum := result.Chunk.StartLine
 		for j := startIdx; j < len(lines) && j < startIdx+15; j++ {
@@ -260,6 +334,8 @@ func outputSearchJSON(results []store.SearchResult, enrichments []rpgEnrichment)
 			FilePath:    r.Chunk.FilePath,
 			StartLine:   r.Chunk.StartLine,
 			EndLine:     r.Chunk.EndLine,
+			Cell:        r.Chunk.Cell,
+			Language:    r.Chunk.Language,
 			Score:       r.Score,
 			Content:     r.Chunk.Content,
 			FeaturePath: enrichments[i].FeaturePath,
@@ -269,7 +345,7 @@ func outputSearchJSON(results []store.SearchResult, enrichments []rpgEnrichment)
 
 	encoder := json.NewEncoder(os.Stdout)
 	encoder.SetIndent("", "  ")
-	return encoder.Encode(jsonResults)
+	return encoder.Encode(withBlame(jsonResults, enrichments))
 }
 
 // outputSearchCompactJSON outputs results in minimal JSON format (without content)
@@ -280,6 +356,8 @@ func outputSearchCompactJSON(results []store.SearchResult, enrichments []rpgEnri
 			FilePath:    r.Chunk.FilePath,
 			StartLine:   r.Chunk.StartLine,
 			EndLine:     r.Chunk.EndLine,
+			Cell:        r.Chunk.Cell,
+			Language:    r.Chunk.Language,
 			Score:       r.Score,
 			FeaturePath: enrichments[i].FeaturePath,
 			SymbolName:  enrichments[i].SymbolName,
@@ -288,7 +366,52 @@ func outputSearchCompactJSON(results []store.SearchResult, enrichments []rpgEnri
 
 	encoder := json.NewEncoder(os.Stdout)
 	encoder.SetIndent("", "  ")
-	return encoder.Encode(jsonResults)
+	return encoder.Encode(withBlame(jsonResults, enrichments))
+}
+
+// withBlame pairs results with their git blame metadata, if any
+func withBlame(results any, enrichments []rpgEnrichment) any {
+	blamed := false
+	for _, e := range enrichments {
+		blamed = blamed || e.Blame != nil
+	}
+	if !blamed {
+		return results
+	}
+
+	switch results := results.(type) {
+	case []SearchResultJSON:
+		out := make([]SearchResultBlameJSON, len(results))
+		for i, r := range results {
+			out[i] = SearchResultBlameJSON{SearchResultJSON: r, Blame: enrichments[i].Blame}
+		}
+		return out
+	case []SearchResultCompactJSON:
+		out := make([]SearchResultCompactBlameJSON, len(results))
+		for i, r := range results {
+			out[i] = SearchResultCompactBlameJSON{SearchResultCompactJSON: r, Blame: enrichments[i].Blame}
+		}
+		return out
+	}
+	return results
+}
+
+// encodeTOON encodes results in TOON format. Results with blame metadata go
+// through JSON first, as the TOON encoder does not flatten embedded structs.
+func encodeTOON(results any) (string, error) {
+	switch results.(type) {
+	case []SearchResultBlameJSON, []SearchResultCompactBlameJSON:
+		data, err := json.Marshal(results)
+		if err != nil {
+			return "", err
+		}
+		var generic any
+		if err := json.Unmarshal(data, &generic); err != nil {
+			return "", err
+		}
+		results = generic
+	}
+	return gotoon.Encode(results)
 }
 
 // outputSearchErrorJSON outputs an error in JSON format
@@ -307,6 +430,8 @@ func outputSearchTOON(results []store.SearchResult, enrichments []rpgEnrichment)
 			FilePath:    r.Chunk.FilePath,
 			StartLine:   r.Chunk.StartLine,
 			EndLine:     r.Chunk.EndLine,
+			Cell:        r.Chunk.Cell,
+			Language:    r.Chunk.Language,
 			Score:       r.Score,
 			Content:     r.Chunk.Content,
 			FeaturePath: enrichments[i].FeaturePath,
@@ -314,7 +439,7 @@ func outputSearchTOON(results []store.SearchResult, enrichments []rpgEnrichment)
 		}
 	}
 
-	output, err := gotoon.Encode(toonResults)
+	output, err := encodeTOON(withBlame(toonResults, enrichments))
 	if err != nil {
 		return fmt.Errorf("failed to encode TOON: %w", err)
 	}
@@ -330,13 +455,15 @@ func outputSearchCompactTOON(results []store.SearchResult, enrichments []rpgEnri
 			FilePath:    r.Chunk.FilePath,
 			StartLine:   r.Chunk.StartLine,
 			EndLine:     r.Chunk.EndLine,
+			Cell:        r.Chunk.Cell,
+			Language:    r.Chunk.Language,
 			Score:       r.Score,
 			FeaturePath: enrichments[i].FeaturePath,
 			SymbolName:  enrichments[i].SymbolName,
 		}
 	}
 
-	output, err := gotoon.Encode(toonResults)
+	output, err := encodeTOON(withBlame(toonResults, enrichments))
 	if err != nil {
 		return fmt.Errorf("failed to encode TOON: %w", err)
 	}
@@ -363,7 +490,7 @@ func SearchJSON(projectRoot string, query string, limit int) ([]store.SearchResu
 		return nil, err
 	}
 
-	emb, err := embedder.NewFromConfig(cfg)
+	emb, err := embedder.NewFromConfig(cfg, embedder.WithUsageFile(config.GetUsagePath(projectRoot)))
 	if err != nil {
 		return nil, err
 	}
@@ -386,8 +513,15 @@ func SearchJSON(projectRoot string, query string, limit int) ([]store.SearchResu
 	}
 	defer st.Close()
 
+	overlay := initializeSearchOverlay(ctx, cfg, projectRoot)
+	if overlay != nil {
+		defer overlay.Close()
+	}
+
 	// Create searcher with boost config
-	searcher := search.NewSearcher(st, emb, cfg.Search)
+	searcher := search.NewSearcher(st, emb, cfg.Search,
+		search.WithDirConfigs(loadDirConfigs(projectRoot, cfg, nil)),
+		search.WithOverlay(overlay))
 
 	return searcher.Search(ctx, query, limit, "")
 }
@@ -398,7 +532,7 @@ func init() {
 }
 
 // runWorkspaceSearch handles workspace-level search operations
-func runWorkspaceSearch(ctx context.Context, query string, projects []string, pathOpt string) error {
+func runWorkspaceSearch(ctx context.Context, query string, projects []string, pathOpt, language string) error {
 	// Load workspace config
 	wsCfg, err := config.LoadWorkspaceConfig()
 	if err != nil {
@@ -419,7 +553,7 @@ func runWorkspaceSearch(ctx context.Context, query string, projects []string, pa
 	}
 
 	// Initialize embedder
-	emb, err := embedder.NewFromWorkspaceConfig(ws)
+	emb, err := embedder.NewFromWorkspaceConfig(ws, workspaceUsageFile(ws)...)
 	if err != nil {
 		return fmt.Errorf("failed to initialize embedder: %w", err)
 	}
@@ -470,7 +604,7 @@ func runWorkspaceSearch(ctx context.Context, query string, projects []string, pa
 	}
 
 	// Search
-	results, err := searcher.Search(ctx, query, searchLimit, fullPathPrefix)
+	results, err := searcher.SearchWithOptions(ctx, query, searchLimit, store.SearchOptions{PathPrefix: fullPathPrefix, Language: language})
 	if err != nil {
 		if searchJSON {
 			return outputSearchErrorJSON(err)
@@ -527,15 +661,12 @@ func runWorkspaceSearch(ctx context.Context, query string, projects []string, pa
 
 	for i, result := range results {
 		fmt.Printf("─── Result %d (score: %.4f) ───\n", i+1, result.Score)
-		fmt.Printf("File: %s:%d-%d\n", result.Chunk.FilePath, result.Chunk.StartLine, result.Chunk.EndLine)
+		fmt.Printf("File: %s-%d\n", formatLocation(result.Chunk.FilePath, result.Chunk.StartLine, result.Chunk.Cell), result.Chunk.EndLine)
 		fmt.Println()
 
 		// Display content with line numbers
 		lines := strings.Split(result.Chunk.Content, "\n")
-		startIdx := 0
-		if len(lines) > 0 && strings.HasPrefix(lines[0], "File: ") {
-			startIdx = 2
-		}
+		startIdx := contextHeaderLines(lines)
 
 		lineNum := result.Chunk.StartLine
 		for j := startIdx; j < len(lines) && j < startIdx+15; j++ {
@@ -550,3 +681,17 @@ func runWorkspaceSearch(ctx context.Context, query string, projects []string, pa
 
 	return nil
 }
+
+// contextHeaderLines returns the number of leading lines taken by the context
+// header added at index time ("File: xxx", optional "Keys: ..." and an empty line).
+func contextHeaderLines(lines []string) int {
+	if len(lines) == 0 || !strings.HasPrefix(lines[0], "File: ") {
+		return 0
+	}
+	for i, line := range lines {
+		if line == "" {
+			return i + 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yoanbernabeu/grepai/config"
+	"github.com/yoanbernabeu/grepai/indexer"
+	"github.com/yoanbernabeu/grepai/redact"
+)
+
+var (
+	secretsJSON bool
+	secretsScan bool
+)
+
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "List files in which secrets were redacted",
+	Long: `List the files in which secrets (API keys, private keys, JWTs, connection
+string passwords, high-entropy tokens) were found and masked before their
+chunks were sent to the embedding provider.
+
+The report is kept in .grepai/secrets.json by 'grepai watch' and 'grepai index'
+while redaction is active (see the redaction section of the configuration).
+Only rule names and line numbers are recorded, never the secrets themselves.
+
+Examples:
+  grepai secrets           Show the report of the last indexing runs
+  grepai secrets --scan    Scan the project now, without indexing
+  grepai secrets --json    Print the report as JSON`,
+	RunE: runSecrets,
+}
+
+func init() {
+	secretsCmd.Flags().BoolVar(&secretsJSON, "json", false, "Output results in JSON format")
+	secretsCmd.Flags().BoolVar(&secretsScan, "scan", false, "Scan the project files instead of reading the report")
+}
+
+func runSecrets(cmd *cobra.Command, args []string) error {
+	projectRoot, err := config.FindProjectRoot()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load(projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	var entries []redact.FileFindings
+	if secretsScan {
+		entries, err = scanSecrets(projectRoot, cfg)
+		if err != nil {
+			return err
+		}
+	} else {
+		report, err := redact.LoadReport(config.GetSecretsReportPath(projectRoot))
+		if err != nil {
+			return err
+		}
+		entries = report.Entries()
+	}
+
+	if secretsJSON {
+		return printJSON(entries)
+	}
+
+	if !secretsScan && !cfg.Redaction.Enabled(cfg.Embedder) {
+		fmt.Printf("Redaction is inactive (mode: %s, provider: %s); the report is not updated.\n", cfg.Redaction.Mode, cfg.Embedder.Provider)
+		fmt.Println("Set redaction.mode to 'always' to redact for local providers, or use --scan.")
+	}
+	if len(entries) == 0 {
+		fmt.Println("No secrets found.")
+		return nil
+	}
+	fmt.Printf("Secrets found in %d files:\n\n", len(entries))
+	for _, entry := range entries {
+		fmt.Printf("%s (%s)\n", entry.Path, formatFindingRules(entry.Findings))
+		for _, f := range entry.Findings {
+			fmt.Printf("  line %d: %s\n", f.Line, f.Rule)
+		}
+	}
+	return nil
+}
+
+// scanSecrets runs the configured redactor over every indexable file.
+func scanSecrets(projectRoot string, cfg *config.Config) ([]redact.FileFindings, error) {
+	ignoreMatcher, err := indexer.NewIgnoreMatcher(projectRoot, cfg.Ignore, cfg.ExternalGitignore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize ignore matcher: %w", err)
+	}
+	scanner := newScanner(projectRoot, ignoreMatcher, cfg.Index)
+	fileMetas, _, err := scanner.ScanMetadata()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan files: %w", err)
+	}
+
+	redactor := cfg.Redaction.NewRedactor()
+	var entries []redact.FileFindings
+	for _, fileMeta := range fileMetas {
+		file, err := scanner.ScanFile(fileMeta.Path)
+		if err != nil || file == nil {
+			continue
+		}
+		if findings := redactor.Find(file.Content); len(findings) > 0 {
+			entries = append(entries, redact.FileFindings{Path: file.Path, Findings: findings})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries, nil
+}
+
+// formatFindingRules renders the rules of a file's findings as "jwt: 1, openai-key: 2".
+func formatFindingRules(findings []redact.Finding) string {
+	counts := make(map[string]int)
+	for _, f := range findings {
+		counts[f.Rule]++
+	}
+	rules := make([]string, 0, len(counts))
+	for rule := range counts {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	parts := make([]string, len(rules))
+	for i, rule := range rules {
+		parts[i] = fmt.Sprintf("%s: %d", rule, counts[rule])
+	}
+	return strings.Join(parts, ", ")
+}
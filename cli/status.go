@@ -3,13 +3,17 @@ package cli
 import (
 	"context"
 	"fmt"
+	"log"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
 	"github.com/yoanbernabeu/grepai/config"
+	"github.com/yoanbernabeu/grepai/embedder"
+	"github.com/yoanbernabeu/grepai/indexer"
 	"github.com/yoanbernabeu/grepai/store"
 )
 
@@ -39,6 +43,9 @@ type model struct {
 	cfg           *config.Config
 	state         viewState
 	stats         *store.IndexStats
+	generated     indexer.GeneratedSummary
+	checkpoint    *indexer.Checkpoint // Indexing run in progress or interrupted
+	usage         *embedder.Usage     // Embedding usage of the project, nil when unreadable
 	files         []store.FileStats
 	chunks        []store.Chunk
 	selectedFile  int
@@ -182,15 +189,85 @@ func (m model) viewStats() string {
 		sb.WriteString(fmt.Sprintf("%s\n", m.stats.LastUpdated.Format("2006-01-02 15:04:05")))
 	}
 
+	if m.checkpoint != nil {
+		sb.WriteString(normalStyle.Render("Indexing:         "))
+		sb.WriteString(fmt.Sprintf("%.0f%% complete (%d/%d files)\n",
+			m.checkpoint.Percent(), m.checkpoint.Completed(), m.checkpoint.Total))
+		sb.WriteString(dimStyle.Render(fmt.Sprintf("                  started %s; in progress, or resumed by the next grepai watch or grepai index",
+			m.checkpoint.StartedAt.Format("2006-01-02 15:04:05"))))
+		sb.WriteString("\n")
+	}
+
+	if m.cfg.Index.Generated.Mode != "keep" && (m.generated.Skipped > 0 || m.generated.Penalized > 0) {
+		sb.WriteString(normalStyle.Render("Generated files:  "))
+		sb.WriteString(fmt.Sprintf("%d skipped, %d down-ranked (mode: %s)\n",
+			m.generated.Skipped, m.generated.Penalized, m.cfg.Index.Generated.Mode))
+		sb.WriteString(dimStyle.Render("                  " + m.generated.FormatReasons()))
+		sb.WriteString("\n")
+	}
+
 	sb.WriteString(normalStyle.Render("Provider:         "))
 	sb.WriteString(fmt.Sprintf("%s (%s)\n", m.cfg.Embedder.Provider, m.cfg.Embedder.Model))
 
+	if m.usage != nil && len(m.usage.Days) > 0 {
+		m.writeUsage(&sb)
+	}
+
 	sb.WriteString("\n")
 	sb.WriteString(helpStyle.Render("[Enter] Browse files  [q] Quit"))
 
 	return boxStyle.Render(sb.String())
 }
 
+// writeUsage writes the embedding usage of today and of this month, by
+// provider and model, and the token budgets.
+func (m model) writeUsage(sb *strings.Builder) {
+	now := time.Now()
+	today, month := now.Format("2006-01-02"), now.Format("2006-01")
+
+	sb.WriteString(normalStyle.Render("Usage today:      "))
+	sb.WriteString(formatUsage(m.usage.Total(today)) + "\n")
+	sb.WriteString(normalStyle.Render("Usage this month: "))
+	sb.WriteString(formatUsage(m.usage.Total(month)) + "\n")
+
+	byModel := m.usage.Sum(month)
+	names := make([]string, 0, len(byModel))
+	for name := range byModel {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		line := fmt.Sprintf("%s: %s", name, formatUsage(byModel[name].Total()))
+		if queries := byModel[name].Query.Tokens; queries > 0 {
+			line += fmt.Sprintf("; %d for queries", queries)
+		}
+		sb.WriteString(dimStyle.Render("                  " + line))
+		sb.WriteString("\n")
+	}
+
+	budget := m.cfg.Embedder.Budget
+	if budget.DailyTokens > 0 || budget.MonthlyTokens > 0 {
+		var parts []string
+		if budget.DailyTokens > 0 {
+			parts = append(parts, fmt.Sprintf("%d/%d tokens today", m.usage.Total(today).Tokens, budget.DailyTokens))
+		}
+		if budget.MonthlyTokens > 0 {
+			parts = append(parts, fmt.Sprintf("%d/%d tokens this month", m.usage.Total(month).Tokens, budget.MonthlyTokens))
+		}
+		sb.WriteString(normalStyle.Render("Budget:           "))
+		sb.WriteString(strings.Join(parts, ", ") + "\n")
+	}
+}
+
+// formatUsage formats the usage counters of a period.
+func formatUsage(c embedder.UsageCounters) string {
+	s := fmt.Sprintf("%d tokens, %d requests", c.Tokens, c.Requests)
+	if c.Retries > 0 || c.RateLimited > 0 {
+		s += fmt.Sprintf(" (%d retries, %d rate limited)", c.Retries, c.RateLimited)
+	}
+	return s
+}
+
 func (m model) viewFiles() string {
 	var sb strings.Builder
 
@@ -360,13 +437,34 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		return files[i].Path < files[j].Path
 	})
 
+	// Generated/vendored files detected by the last scans
+	report, err := indexer.LoadGeneratedReport(config.GetGeneratedReportPath(projectRoot))
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	// Indexing run in progress or interrupted
+	checkpoint, err := indexer.LoadCheckpoint(config.GetCheckpointPath(projectRoot))
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	// Embedding usage recorded by index, watch, search and MCP
+	usage, err := embedder.LoadUsage(config.GetUsagePath(projectRoot))
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
 	// Create model
 	m := model{
-		st:    st,
-		cfg:   cfg,
-		state: viewStats,
-		stats: stats,
-		files: files,
+		st:         st,
+		cfg:        cfg,
+		state:      viewStats,
+		stats:      stats,
+		generated:  report.Summary(),
+		checkpoint: checkpoint,
+		usage:      usage,
+		files:      files,
 	}
 
 	// Run TUI
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/alpkeskin/gotoon"
@@ -21,6 +22,8 @@ var (
 	traceTOON      bool
 	traceWorkspace string
 	traceProject   string
+	traceRef       string
+	traceBlame     bool
 )
 
 var traceCmd = &cobra.Command{
@@ -83,6 +86,9 @@ func init() {
 		cmd.MarkFlagsMutuallyExclusive("json", "toon")
 		cmd.Flags().StringVar(&traceWorkspace, "workspace", "", "Workspace name for cross-project trace")
 		cmd.Flags().StringVar(&traceProject, "project", "", "Project name within workspace (requires --workspace)")
+		cmd.Flags().StringVar(&traceRef, "ref", "", "Trace in the index of a git ref built with 'grepai index --ref'")
+		cmd.Flags().BoolVar(&traceBlame, "blame", false, "Add the last commit and change count of each symbol (git blame)")
+		cmd.MarkFlagsMutuallyExclusive("ref", "workspace")
 	}
 	traceGraphCmd.Flags().IntVarP(&traceDepth, "depth", "d", 2, "Maximum depth for graph traversal")
 
@@ -130,6 +136,7 @@ func runTraceCallers(cmd *cobra.Command, args []string) error {
 						File:    ref.File,
 						Line:    ref.Line,
 						Context: ref.Context,
+						Cell:    ref.Cell,
 					},
 				})
 			}
@@ -161,7 +168,11 @@ func runTraceCallers(cmd *cobra.Command, args []string) error {
 	}
 
 	// Initialize symbol store
-	symbolStore := trace.NewGOBSymbolStore(config.GetSymbolIndexPath(projectRoot))
+	symbolIndexPath, err := traceSymbolIndexPath(projectRoot)
+	if err != nil {
+		return err
+	}
+	symbolStore := trace.NewGOBSymbolStore(symbolIndexPath)
 	if err := symbolStore.Load(ctx); err != nil {
 		return fmt.Errorf("failed to load symbol index: %w", err)
 	}
@@ -218,6 +229,7 @@ func runTraceCallers(cmd *cobra.Command, args []string) error {
 				File:    ref.File,
 				Line:    ref.Line,
 				Context: ref.Context,
+				Cell:    ref.Cell,
 			},
 		})
 	}
@@ -226,6 +238,7 @@ func runTraceCallers(cmd *cobra.Command, args []string) error {
 	cfg, _ := config.Load(projectRoot)
 	if cfg != nil {
 		enrichTraceWithRPG(projectRoot, cfg, &result)
+		enrichTraceWithBlame(projectRoot, cfg, &result)
 	}
 
 	if traceJSON {
@@ -276,6 +289,7 @@ func runTraceCallees(cmd *cobra.Command, args []string) error {
 							File:    ref.File,
 							Line:    ref.Line,
 							Context: ref.Context,
+							Cell:    ref.Cell,
 						},
 					})
 				}
@@ -307,7 +321,11 @@ func runTraceCallees(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	symbolStore := trace.NewGOBSymbolStore(config.GetSymbolIndexPath(projectRoot))
+	symbolIndexPath, err := traceSymbolIndexPath(projectRoot)
+	if err != nil {
+		return err
+	}
+	symbolStore := trace.NewGOBSymbolStore(symbolIndexPath)
 	if err := symbolStore.Load(ctx); err != nil {
 		return fmt.Errorf("failed to load symbol index: %w", err)
 	}
@@ -363,6 +381,7 @@ func runTraceCallees(cmd *cobra.Command, args []string) error {
 				File:    ref.File,
 				Line:    ref.Line,
 				Context: ref.Context,
+				Cell:    ref.Cell,
 			},
 		})
 	}
@@ -371,6 +390,7 @@ func runTraceCallees(cmd *cobra.Command, args []string) error {
 	cfg, _ := config.Load(projectRoot)
 	if cfg != nil {
 		enrichTraceWithRPG(projectRoot, cfg, &result)
+		enrichTraceWithBlame(projectRoot, cfg, &result)
 	}
 
 	if traceJSON {
@@ -447,7 +467,11 @@ func runTraceGraph(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	symbolStore := trace.NewGOBSymbolStore(config.GetSymbolIndexPath(projectRoot))
+	symbolIndexPath, err := traceSymbolIndexPath(projectRoot)
+	if err != nil {
+		return err
+	}
+	symbolStore := trace.NewGOBSymbolStore(symbolIndexPath)
 	if err := symbolStore.Load(ctx); err != nil {
 		return fmt.Errorf("failed to load symbol index: %w", err)
 	}
@@ -474,6 +498,7 @@ func runTraceGraph(cmd *cobra.Command, args []string) error {
 	cfg, _ := config.Load(projectRoot)
 	if cfg != nil {
 		enrichTraceWithRPG(projectRoot, cfg, &result)
+		enrichTraceWithBlame(projectRoot, cfg, &result)
 	}
 
 	if traceJSON {
@@ -486,8 +511,54 @@ func runTraceGraph(cmd *cobra.Command, args []string) error {
 	return displayGraphResult(result)
 }
 
+// traceSymbolIndexPath returns the symbol index to trace in: the one of the
+// working tree, or of the git ref given with --ref.
+func traceSymbolIndexPath(projectRoot string) (string, error) {
+	if traceRef == "" {
+		return config.GetSymbolIndexPath(projectRoot), nil
+	}
+	if err := requireRefIndex(projectRoot, traceRef); err != nil {
+		return "", err
+	}
+	return filepath.Join(config.GetRefDir(projectRoot, traceRef), config.SymbolIndexFileName), nil
+}
+
 // enrichTraceWithRPG enriches all symbols in a TraceResult with RPG feature paths.
+// The RPG graph describes the working tree, so git ref results are left as is.
 func enrichTraceWithRPG(projectRoot string, cfg *config.Config, result *trace.TraceResult) {
+	if traceRef != "" {
+		return
+	}
+
+	symbols := []*trace.Symbol{result.Symbol}
+	for i := range result.Callers {
+		symbols = append(symbols, &result.Callers[i].Symbol)
+	}
+	for i := range result.Callees {
+		symbols = append(symbols, &result.Callees[i].Symbol)
+	}
+
+	// Graph nodes are enriched through copies, written back afterwards
+	var names []string
+	var nodes []trace.Symbol
+	if result.Graph != nil {
+		for name, sym := range result.Graph.Nodes {
+			names = append(names, name)
+			nodes = append(nodes, sym)
+		}
+		for i := range nodes {
+			symbols = append(symbols, &nodes[i])
+		}
+	}
+
+	enrichSymbolsWithRPG(projectRoot, cfg, symbols...)
+	for i, name := range names {
+		result.Graph.Nodes[name] = nodes[i]
+	}
+}
+
+// enrichSymbolsWithRPG sets the RPG feature path of symbols.
+func enrichSymbolsWithRPG(projectRoot string, cfg *config.Config, symbols ...*trace.Symbol) {
 	if !cfg.RPG.Enabled {
 		return
 	}
@@ -502,9 +573,9 @@ func enrichTraceWithRPG(projectRoot string, cfg *config.Config, result *trace.Tr
 	graph := rpgStore.GetGraph()
 	qe := rpg.NewQueryEngine(graph)
 
-	lookupFeaturePath := func(sym *trace.Symbol) {
+	for _, sym := range symbols {
 		if sym == nil || sym.File == "" {
-			return
+			continue
 		}
 		nodes := graph.GetNodesByFile(sym.File)
 		for _, n := range nodes {
@@ -513,32 +584,25 @@ func enrichTraceWithRPG(projectRoot string, cfg *config.Config, result *trace.Tr
 				if err == nil && fetchResult != nil {
 					sym.FeaturePath = fetchResult.FeaturePath
 				}
-				return
+				break
 			}
 		}
 	}
+}
 
-	// Enrich the main symbol
-	if result.Symbol != nil {
-		lookupFeaturePath(result.Symbol)
-	}
-
-	// Enrich callers
-	for i := range result.Callers {
-		lookupFeaturePath(&result.Callers[i].Symbol)
+// enrichTraceWithBlame adds git blame metadata to all symbols in a TraceResult
+// when requested. Like RPG feature paths, it describes the working tree only.
+func enrichTraceWithBlame(projectRoot string, cfg *config.Config, result *trace.TraceResult) {
+	if (!traceBlame && !cfg.Search.Blame.Enabled) || traceRef != "" {
+		return
 	}
-
-	// Enrich callees
-	for i := range result.Callees {
-		lookupFeaturePath(&result.Callees[i].Symbol)
+	cache := openBlameCache(projectRoot, cfg)
+	if cache == nil {
+		return
 	}
-
-	// Enrich graph nodes
-	if result.Graph != nil {
-		for name, sym := range result.Graph.Nodes {
-			lookupFeaturePath(&sym)
-			result.Graph.Nodes[name] = sym
-		}
+	trace.EnrichWithBlame(context.Background(), cache, result)
+	if err := cache.Persist(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save blame cache: %v\n", err)
 	}
 }
 
@@ -559,10 +623,13 @@ func outputTOON(result trace.TraceResult) error {
 
 func displayCallersResult(result trace.TraceResult) error {
 	fmt.Printf("Symbol: %s (%s)\n", result.Symbol.Name, result.Symbol.Kind)
-	fmt.Printf("File: %s:%d\n", result.Symbol.File, result.Symbol.Line)
+	fmt.Printf("File: %s\n", formatLocation(result.Symbol.File, result.Symbol.Line, result.Symbol.Cell))
 	if result.Symbol.FeaturePath != "" {
 		fmt.Printf("Feature: %s\n", result.Symbol.FeaturePath)
 	}
+	if result.Symbol.Blame != nil {
+		fmt.Printf("Last change: %s\n", formatBlame(result.Symbol.Blame))
+	}
 	fmt.Printf("\nCallers (%d):\n", len(result.Callers))
 	fmt.Println(strings.Repeat("-", 60))
 
@@ -574,12 +641,15 @@ func displayCallersResult(result trace.TraceResult) error {
 	for i, caller := range result.Callers {
 		fmt.Printf("\n%d. %s\n", i+1, caller.Symbol.Name)
 		if caller.Symbol.File != "" {
-			fmt.Printf("   Defined: %s:%d\n", caller.Symbol.File, caller.Symbol.Line)
+			fmt.Printf("   Defined: %s\n", formatLocation(caller.Symbol.File, caller.Symbol.Line, caller.Symbol.Cell))
 		}
 		if caller.Symbol.FeaturePath != "" {
 			fmt.Printf("   Feature: %s\n", caller.Symbol.FeaturePath)
 		}
-		fmt.Printf("   Calls at: %s:%d\n", caller.CallSite.File, caller.CallSite.Line)
+		if caller.Symbol.Blame != nil {
+			fmt.Printf("   Last change: %s\n", formatBlame(caller.Symbol.Blame))
+		}
+		fmt.Printf("   Calls at: %s\n", formatLocation(caller.CallSite.File, caller.CallSite.Line, caller.CallSite.Cell))
 		if caller.CallSite.Context != "" {
 			fmt.Printf("   Context: %s\n", truncate(caller.CallSite.Context, 80))
 		}
@@ -590,10 +660,13 @@ func displayCallersResult(result trace.TraceResult) error {
 
 func displayCalleesResult(result trace.TraceResult) error {
 	fmt.Printf("Symbol: %s (%s)\n", result.Symbol.Name, result.Symbol.Kind)
-	fmt.Printf("File: %s:%d\n", result.Symbol.File, result.Symbol.Line)
+	fmt.Printf("File: %s\n", formatLocation(result.Symbol.File, result.Symbol.Line, result.Symbol.Cell))
 	if result.Symbol.FeaturePath != "" {
 		fmt.Printf("Feature: %s\n", result.Symbol.FeaturePath)
 	}
+	if result.Symbol.Blame != nil {
+		fmt.Printf("Last change: %s\n", formatBlame(result.Symbol.Blame))
+	}
 	fmt.Printf("\nCallees (%d):\n", len(result.Callees))
 	fmt.Println(strings.Repeat("-", 60))
 
@@ -605,17 +678,29 @@ func displayCalleesResult(result trace.TraceResult) error {
 	for i, callee := range result.Callees {
 		fmt.Printf("\n%d. %s\n", i+1, callee.Symbol.Name)
 		if callee.Symbol.File != "" {
-			fmt.Printf("   Defined: %s:%d\n", callee.Symbol.File, callee.Symbol.Line)
+			fmt.Printf("   Defined: %s\n", formatLocation(callee.Symbol.File, callee.Symbol.Line, callee.Symbol.Cell))
 		}
 		if callee.Symbol.FeaturePath != "" {
 			fmt.Printf("   Feature: %s\n", callee.Symbol.FeaturePath)
 		}
-		fmt.Printf("   Called at: %s:%d\n", callee.CallSite.File, callee.CallSite.Line)
+		if callee.Symbol.Blame != nil {
+			fmt.Printf("   Last change: %s\n", formatBlame(callee.Symbol.Blame))
+		}
+		fmt.Printf("   Called at: %s\n", formatLocation(callee.CallSite.File, callee.CallSite.Line, callee.CallSite.Cell))
 	}
 
 	return nil
 }
 
+// formatLocation renders a file:line location, including the cell number for
+// symbols defined in notebooks (e.g. "analysis.ipynb#cell-3:12").
+func formatLocation(file string, line int, cell int) string {
+	if cell > 0 {
+		return fmt.Sprintf("%s#cell-%d:%d", file, cell, line)
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
 func displayGraphResult(result trace.TraceResult) error {
 	fmt.Printf("Call Graph for: %s (depth: %d)\n", result.Query, result.Graph.Depth)
 	fmt.Println(strings.Repeat("=", 60))
@@ -623,9 +708,9 @@ func displayGraphResult(result trace.TraceResult) error {
 	fmt.Printf("\nNodes (%d):\n", len(result.Graph.Nodes))
 	for name, sym := range result.Graph.Nodes {
 		if sym.FeaturePath != "" {
-			fmt.Printf("  - %s (%s) @ %s:%d [%s]\n", name, sym.Kind, sym.File, sym.Line, sym.FeaturePath)
+			fmt.Printf("  - %s (%s) @ %s [%s]\n", name, sym.Kind, formatLocation(sym.File, sym.Line, sym.Cell), sym.FeaturePath)
 		} else {
-			fmt.Printf("  - %s (%s) @ %s:%d\n", name, sym.Kind, sym.File, sym.Line)
+			fmt.Printf("  - %s (%s) @ %s\n", name, sym.Kind, formatLocation(sym.File, sym.Line, sym.Cell))
 		}
 	}
 
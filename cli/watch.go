@@ -19,8 +19,10 @@ import (
 	"github.com/yoanbernabeu/grepai/embedder"
 	"github.com/yoanbernabeu/grepai/git"
 	"github.com/yoanbernabeu/grepai/indexer"
+	"github.com/yoanbernabeu/grepai/redact"
 	"github.com/yoanbernabeu/grepai/rpg"
 	"github.com/yoanbernabeu/grepai/store"
+	"github.com/yoanbernabeu/grepai/tokenizer"
 	"github.com/yoanbernabeu/grepai/trace"
 	"github.com/yoanbernabeu/grepai/watcher"
 	"golang.org/x/sync/errgroup"
@@ -341,8 +343,19 @@ func startBackgroundWatch(logDir, worktreeID string) error {
 	return fmt.Errorf("timeout waiting for process to become ready after %v (check logs at %s)", startupTimeout, logFile)
 }
 
-func initializeEmbedder(ctx context.Context, cfg *config.Config) (embedder.Embedder, error) {
-	emb, err := embedder.NewFromConfig(cfg)
+// workspaceUsageFile records the embedding usage of a workspace, shared by
+// its projects, in the usage file of the workspace.
+func workspaceUsageFile(ws *config.Workspace) []embedder.FactoryOption {
+	usagePath, err := config.GetWorkspaceUsagePath(ws.Name)
+	if err != nil {
+		log.Printf("Warning: embedding usage will not be recorded: %v", err)
+		return nil
+	}
+	return []embedder.FactoryOption{embedder.WithUsageFile(usagePath)}
+}
+
+func initializeEmbedder(ctx context.Context, cfg *config.Config, opts ...embedder.FactoryOption) (embedder.Embedder, error) {
+	emb, err := embedder.NewFromConfig(cfg, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -364,6 +377,18 @@ func initializeEmbedder(ctx context.Context, cfg *config.Config) (embedder.Embed
 				return nil, fmt.Errorf("cannot connect to LM Studio: %w\nMake sure LM Studio is running with the %s model loaded", err, cfg.Embedder.Model)
 			}
 		}
+	case "tei":
+		if p, ok := emb.(pinger); ok {
+			if err := p.Ping(ctx); err != nil {
+				return nil, fmt.Errorf("cannot connect to Text Embeddings Inference: %w\nMake sure the TEI server is running at %s", err, cfg.Embedder.Endpoint)
+			}
+		}
+	case "llamacpp":
+		if p, ok := emb.(pinger); ok {
+			if err := p.Ping(ctx); err != nil {
+				return nil, fmt.Errorf("cannot connect to llama.cpp: %w\nMake sure llama-server is running with --embedding at %s", err, cfg.Embedder.Endpoint)
+			}
+		}
 	}
 
 	return emb, nil
@@ -723,8 +748,7 @@ func runInitialScan(ctx context.Context, idx *indexer.Indexer, scanner *indexer.
 	}
 
 	for _, file := range files {
-		ext := strings.ToLower(filepath.Ext(file.Path))
-		if !isTracedLanguage(ext, tracedLanguages) {
+		if !isTracedFile(idx, file.Path, tracedLanguages) {
 			continue
 		}
 
@@ -839,6 +863,134 @@ func canonicalPath(path string) string {
 	return filepath.Clean(path)
 }
 
+// newChunker creates a chunker sized with the configured tokenizer.
+func newChunker(cfg config.ChunkingConfig) *indexer.Chunker {
+	var opts []indexer.ChunkerOption
+	if cfg.Tokenizer != "" {
+		opts = append(opts, indexer.WithTokenizer(tokenizer.Load(cfg.Tokenizer, cfg.TokenizerVocab)))
+	}
+	return indexer.NewChunker(cfg.Size, cfg.Overlap, opts...)
+}
+
+// newFileTypes builds the file-type registry from the project's index settings.
+func newFileTypes(cfg config.IndexConfig) *indexer.FileTypes {
+	return indexer.NewFileTypes(cfg.IncludeExtensions, cfg.ExcludeExtensions, cfg.Filenames)
+}
+
+// newScanner creates a scanner honoring the project's file-type and generated
+// file settings. Detections are recorded in .grepai/generated.json for `grepai status`.
+func newScanner(projectRoot string, ignoreMatcher *indexer.IgnoreMatcher, cfg config.IndexConfig) *indexer.Scanner {
+	var report *indexer.GeneratedReport
+	if config.Exists(projectRoot) {
+		report = loadGeneratedReport(config.GetGeneratedReportPath(projectRoot))
+	}
+	return indexer.NewScanner(projectRoot, ignoreMatcher, scannerOptions(cfg, report)...)
+}
+
+// scannerOptions returns the scanner options for the index configuration.
+func scannerOptions(cfg config.IndexConfig, report *indexer.GeneratedReport) []indexer.ScannerOption {
+	return []indexer.ScannerOption{
+		indexer.WithFileTypes(newFileTypes(cfg)),
+		indexer.WithGeneratedDetection(cfg.Generated.Mode, cfg.Generated.MaxAvgLineLength, report),
+	}
+}
+
+func loadGeneratedReport(path string) *indexer.GeneratedReport {
+	report, err := indexer.LoadGeneratedReport(path)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	return report
+}
+
+// indexerOptions returns the indexer options shared by every command that
+// indexes a project: checkpointing, nested .grepai.yaml overrides and, when
+// enabled for the configured embedder, secret redaction recorded in
+// .grepai/secrets.json.
+func indexerOptions(projectRoot string, cfg *config.Config, ignoreMatcher *indexer.IgnoreMatcher) []indexer.IndexerOption {
+	return indexerOptionsIn(config.GetConfigDir(projectRoot), loadDirConfigs(projectRoot, cfg, ignoreMatcher), cfg)
+}
+
+// loadDirConfigs finds the nested .grepai.yaml files of the project outside
+// the directories ignoreMatcher ignores. A nil matcher is built from the
+// ignore files of the project.
+func loadDirConfigs(projectRoot string, cfg *config.Config, ignoreMatcher *indexer.IgnoreMatcher) *config.DirConfigs {
+	if ignoreMatcher == nil {
+		var err error
+		ignoreMatcher, err = indexer.NewIgnoreMatcher(projectRoot, cfg.Ignore, cfg.ExternalGitignore)
+		if err != nil {
+			log.Printf("Warning: failed to initialize ignore matcher: %v", err)
+			return config.LoadDirConfigs(projectRoot, cfg.Ignore)
+		}
+	}
+	return config.LoadDirConfigs(projectRoot, cfg.Ignore, config.WithIgnoredDirs(ignoreMatcher.ShouldIgnore))
+}
+
+// indexerOptionsIn is indexerOptions for an index whose checkpoint and
+// secrets report live in dataDir.
+func indexerOptionsIn(dataDir string, dirConfigs *config.DirConfigs, cfg *config.Config) []indexer.IndexerOption {
+	opts := []indexer.IndexerOption{
+		indexer.WithCheckpoint(filepath.Join(dataDir, config.CheckpointFileName)),
+		indexer.WithDirConfigs(dirConfigs),
+		indexer.WithScanWorkers(cfg.Index.Workers),
+	}
+	if !cfg.Redaction.Enabled(cfg.Embedder) {
+		return opts
+	}
+	report, err := redact.LoadReport(filepath.Join(dataDir, config.SecretsReportName))
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	return append(opts, indexer.WithRedaction(cfg.Redaction.NewRedactor(), cfg.Redaction.MaskStored, report))
+}
+
+// newRPGIndexer loads the RPG store and creates its indexer when RPG is
+// enabled. Both are nil otherwise.
+func newRPGIndexer(ctx context.Context, cfg *config.Config, projectRoot string) (*rpg.RPGIndexer, rpg.RPGStore) {
+	if !cfg.RPG.Enabled {
+		return nil, nil
+	}
+
+	rpgStore := rpg.NewGOBRPGStore(config.GetRPGIndexPath(projectRoot))
+	if err := rpgStore.Load(ctx); err != nil {
+		log.Printf("Warning: failed to load RPG index for %s: %v", projectRoot, err)
+	}
+
+	var featureExtractor rpg.FeatureExtractor
+	switch cfg.RPG.FeatureMode {
+	case "llm", "hybrid":
+		if cfg.RPG.LLMEndpoint == "" || cfg.RPG.LLMModel == "" {
+			log.Printf("Warning: RPG feature_mode=%q but llm_endpoint or llm_model is empty, falling back to local extractor", cfg.RPG.FeatureMode)
+			featureExtractor = rpg.NewLocalExtractor()
+		} else {
+			featureExtractor = rpg.NewLLMExtractor(rpg.LLMExtractorConfig{
+				Provider: cfg.RPG.LLMProvider,
+				Model:    cfg.RPG.LLMModel,
+				Endpoint: cfg.RPG.LLMEndpoint,
+				APIKey:   cfg.RPG.LLMAPIKey,
+				Timeout:  time.Duration(cfg.RPG.LLMTimeoutMs) * time.Millisecond,
+			})
+		}
+	default:
+		featureExtractor = rpg.NewLocalExtractor()
+	}
+
+	rpgIndexer := rpg.NewRPGIndexer(rpgStore, featureExtractor, projectRoot, rpg.RPGIndexerConfig{
+		DriftThreshold:       cfg.RPG.DriftThreshold,
+		MaxTraversalDepth:    cfg.RPG.MaxTraversalDepth,
+		FeatureGroupStrategy: cfg.RPG.FeatureGroupStrategy,
+	})
+	return rpgIndexer, rpgStore
+}
+
+// tracedLanguagesFor returns the extensions indexed for symbols.
+func tracedLanguagesFor(cfg config.TraceConfig) []string {
+	if len(cfg.EnabledLanguages) == 0 {
+		return []string{".go", ".js", ".ts", ".jsx", ".tsx", ".py", ".php", ".java", ".cs"}
+	}
+	return cfg.EnabledLanguages
+}
+
 // watchProject runs the full watch lifecycle for a single project.
 // The embedder is shared across all projects to avoid duplicate connections.
 // If onReady is non-nil, it is called once after initial indexing and watcher start.
@@ -852,11 +1004,12 @@ func watchProject(ctx context.Context, projectRoot string, emb embedder.Embedder
 	log.Printf("Watching project: %s (backend: %s)", projectRoot, cfg.Store.Backend)
 
 	// Initialize store
-	st, err := initializeStore(ctx, cfg, projectRoot)
+	st, err := initializeIndexStore(ctx, cfg, projectRoot)
 	if err != nil {
 		return err
 	}
 	defer st.Close()
+	branches := newBranchTracker(cfg, projectRoot, st)
 
 	// Initialize ignore matcher
 	ignoreMatcher, err := indexer.NewIgnoreMatcher(projectRoot, cfg.Ignore, cfg.ExternalGitignore)
@@ -865,13 +1018,18 @@ func watchProject(ctx context.Context, projectRoot string, emb embedder.Embedder
 	}
 
 	// Initialize scanner
-	scanner := indexer.NewScanner(projectRoot, ignoreMatcher)
+	scanner := newScanner(projectRoot, ignoreMatcher, cfg.Index)
 
 	// Initialize chunker
-	chunker := indexer.NewChunker(cfg.Chunking.Size, cfg.Chunking.Overlap)
+	chunker := newChunker(cfg.Chunking)
+
+	dataDir := config.GetConfigDir(projectRoot)
+	recordFingerprint := checkFingerprint(ctx, st, dataDir, cfg, false)
+	rechunk := recordFingerprint && staleChunks(ctx, st, dataDir)
 
 	// Initialize indexer
-	idx := indexer.NewIndexer(projectRoot, st, emb, chunker, scanner, cfg.Watch.LastIndexTime)
+	idx := indexer.NewIndexer(projectRoot, st, emb, chunker, scanner, cfg.Watch.LastIndexTime,
+		append(indexerOptions(projectRoot, cfg, ignoreMatcher), indexer.WithRechunk(rechunk))...)
 
 	// Initialize symbol store and extractor
 	symbolStore := trace.NewGOBSymbolStore(config.GetSymbolIndexPath(projectRoot))
@@ -883,55 +1041,31 @@ func watchProject(ctx context.Context, projectRoot string, emb embedder.Embedder
 	extractor := trace.NewRegexExtractor()
 
 	// Initialize RPG if enabled.
-	var rpgIndexer *rpg.RPGIndexer
-	var rpgStore rpg.RPGStore
-	if cfg.RPG.Enabled {
-		rpgStore = rpg.NewGOBRPGStore(config.GetRPGIndexPath(projectRoot))
-		if err := rpgStore.Load(ctx); err != nil {
-			log.Printf("Warning: failed to load RPG index for %s: %v", projectRoot, err)
-		}
-
-		var featureExtractor rpg.FeatureExtractor
-		switch cfg.RPG.FeatureMode {
-		case "llm", "hybrid":
-			if cfg.RPG.LLMEndpoint == "" || cfg.RPG.LLMModel == "" {
-				log.Printf("Warning: RPG feature_mode=%q but llm_endpoint or llm_model is empty, falling back to local extractor", cfg.RPG.FeatureMode)
-				featureExtractor = rpg.NewLocalExtractor()
-			} else {
-				featureExtractor = rpg.NewLLMExtractor(rpg.LLMExtractorConfig{
-					Provider: cfg.RPG.LLMProvider,
-					Model:    cfg.RPG.LLMModel,
-					Endpoint: cfg.RPG.LLMEndpoint,
-					APIKey:   cfg.RPG.LLMAPIKey,
-					Timeout:  time.Duration(cfg.RPG.LLMTimeoutMs) * time.Millisecond,
-				})
-			}
-		default:
-			featureExtractor = rpg.NewLocalExtractor()
-		}
-
-		rpgIndexer = rpg.NewRPGIndexer(rpgStore, featureExtractor, projectRoot, rpg.RPGIndexerConfig{
-			DriftThreshold:       cfg.RPG.DriftThreshold,
-			MaxTraversalDepth:    cfg.RPG.MaxTraversalDepth,
-			FeatureGroupStrategy: cfg.RPG.FeatureGroupStrategy,
-		})
-	}
-
+	rpgIndexer, rpgStore := newRPGIndexer(ctx, cfg, projectRoot)
 	if rpgStore != nil {
 		defer rpgStore.Close()
 	}
 
-	tracedLanguages := cfg.Trace.EnabledLanguages
-	if len(tracedLanguages) == 0 {
-		tracedLanguages = []string{".go", ".js", ".ts", ".jsx", ".tsx", ".py", ".php", ".java", ".cs"}
-	}
+	tracedLanguages := tracedLanguagesFor(cfg.Trace)
 
 	// Run initial scan and build symbol index.
 	// In multi-worktree mode callers pass isBackgroundChild=true for non-interactive output.
 	stats, err := runInitialScan(ctx, idx, scanner, extractor, symbolStore, tracedLanguages, cfg.Watch.LastIndexTime, isBackgroundChild)
-	if err != nil {
+	if embedder.AsBudgetExceededError(err) != nil {
+		// Changes are still watched, and indexed once the budget allows it;
+		// the next watch or index run resumes the interrupted scan
+		log.Printf("Warning: %v", err)
+		stats = &indexer.IndexStats{}
+		// Files the scan did not reach must still be chunked again
+		recordFingerprint = recordFingerprint && !rechunk
+	} else if err != nil {
 		return err
 	}
+	if recordFingerprint {
+		if err := config.SaveFingerprint(dataDir, config.NewFingerprint(cfg.Embedder)); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
 
 	if stats.FilesIndexed > 0 || stats.ChunksCreated > 0 {
 		cfg.Watch.LastIndexTime = time.Now()
@@ -959,7 +1093,7 @@ func watchProject(ctx context.Context, projectRoot string, emb embedder.Embedder
 	}
 
 	// Initialize watcher
-	w, err := watcher.NewWatcher(projectRoot, ignoreMatcher, cfg.Watch.DebounceMs)
+	w, err := watcher.NewWatcher(projectRoot, ignoreMatcher, cfg.Watch.DebounceMs, watcher.WithFileTypes(newFileTypes(cfg.Index)))
 	if err != nil {
 		return fmt.Errorf("failed to initialize watcher for %s: %w", projectRoot, err)
 	}
@@ -973,11 +1107,13 @@ func watchProject(ctx context.Context, projectRoot string, emb embedder.Embedder
 		onReady()
 	}
 
+	startHistoryUpdates(ctx, projectRoot, cfg, emb)
+
 	// Run watch loop (responds to ctx.Done() for graceful shutdown)
-	return runProjectWatchLoop(ctx, st, symbolStore, w, idx, scanner, extractor, rpgIndexer, rpgStore, tracedLanguages, projectRoot, cfg)
+	return runProjectWatchLoop(ctx, st, symbolStore, w, idx, scanner, extractor, rpgIndexer, rpgStore, tracedLanguages, projectRoot, cfg, branches)
 }
 
-func runProjectWatchLoop(ctx context.Context, st store.VectorStore, symbolStore *trace.GOBSymbolStore, w *watcher.Watcher, idx *indexer.Indexer, scanner *indexer.Scanner, extractor *trace.RegexExtractor, rpgIndexer *rpg.RPGIndexer, rpgStore rpg.RPGStore, tracedLanguages []string, projectRoot string, cfg *config.Config) error {
+func runProjectWatchLoop(ctx context.Context, st store.VectorStore, symbolStore *trace.GOBSymbolStore, w *watcher.Watcher, idx *indexer.Indexer, scanner *indexer.Scanner, extractor *trace.RegexExtractor, rpgIndexer *rpg.RPGIndexer, rpgStore rpg.RPGStore, tracedLanguages []string, projectRoot string, cfg *config.Config, branches *branchTracker) error {
 	persistTicker := time.NewTicker(30 * time.Second)
 	defer persistTicker.Stop()
 
@@ -1018,6 +1154,7 @@ func runProjectWatchLoop(ctx context.Context, st store.VectorStore, symbolStore
 			}
 
 		case event := <-w.Events():
+			branches.sync(ctx)
 			handleFileEvent(ctx, idx, scanner, extractor, symbolStore, rpgIndexer, st, tracedLanguages, projectRoot, cfg, &lastConfigWrite, rpgManager, event)
 		}
 	}
@@ -1138,7 +1275,7 @@ func runWatchForeground() error {
 	}
 
 	// Initialize shared embedder (reused across all worktrees)
-	emb, err := initializeEmbedder(ctx, cfg)
+	emb, err := initializeEmbedder(ctx, cfg, embedder.WithUsageFile(config.GetUsagePath(projectRoot)))
 	if err != nil {
 		return err
 	}
@@ -1288,8 +1425,17 @@ func handleFileEvent(ctx context.Context, idx *indexer.Indexer, scanner *indexer
 			log.Printf("Failed to scan %s: %v", event.Path, err)
 			return
 		}
+		if err := scanner.SaveGeneratedReport(); err != nil {
+			log.Printf("Warning: %v", err)
+		}
 		if fileInfo == nil {
-			return // File was skipped (binary, too large, etc.)
+			if scanner.SkippedAsGenerated(event.Path) {
+				// The file may have been indexed before it became generated
+				if err := idx.RemoveFile(ctx, event.Path); err != nil {
+					log.Printf("Failed to remove %s: %v", event.Path, err)
+				}
+			}
+			return // File was skipped (binary, too large, generated, etc.)
 		}
 
 		needsReindex, err := idx.NeedsReindex(ctx, fileInfo.Path, fileInfo.Hash)
@@ -1308,6 +1454,9 @@ func handleFileEvent(ctx context.Context, idx *indexer.Indexer, scanner *indexer
 			return
 		}
 		log.Printf("Indexed %s (%d chunks)", event.Path, chunks)
+		if err := idx.SaveSecretsReport(); err != nil {
+			log.Printf("Warning: %v", err)
+		}
 
 		// Update last_index_time with throttling (only write if 30 seconds have passed)
 		now := time.Now()
@@ -1320,8 +1469,7 @@ func handleFileEvent(ctx context.Context, idx *indexer.Indexer, scanner *indexer
 		}
 
 		// Extract symbols if language is supported
-		ext := strings.ToLower(filepath.Ext(event.Path))
-		if isTracedLanguage(ext, enabledLanguages) {
+		if isTracedFile(idx, event.Path, enabledLanguages) {
 			symbols, refs, err := extractor.ExtractAll(ctx, fileInfo.Path, fileInfo.Content)
 			if err != nil {
 				log.Printf("Failed to extract symbols from %s: %v", event.Path, err)
@@ -1360,12 +1508,18 @@ func handleFileEvent(ctx context.Context, idx *indexer.Indexer, scanner *indexer
 			}
 		}
 
+	case watcher.EventIgnoreChange, watcher.EventDirConfigChange:
+		handleSettingsChange(ctx, idx, scanner, extractor, symbolStore, rpgIndexer, enabledLanguages, event)
+
 	case watcher.EventDelete, watcher.EventRename:
 		start := time.Now()
 		if err := idx.RemoveFile(ctx, event.Path); err != nil {
 			log.Printf("Failed to remove %s from index: %v", event.Path, err)
 			return
 		}
+		if err := idx.SaveSecretsReport(); err != nil {
+			log.Printf("Warning: %v", err)
+		}
 		// Also remove from symbol index
 		if err := symbolStore.DeleteFile(ctx, event.Path); err != nil {
 			log.Printf("Failed to remove symbols for %s: %v", event.Path, err)
@@ -1388,6 +1542,74 @@ func handleFileEvent(ctx context.Context, idx *indexer.Indexer, scanner *indexer
 	}
 }
 
+// handleSettingsChange brings the subtree of a changed ignore file or nested
+// .grepai.yaml in line with the new settings. The watcher reloads ignore files
+// itself; nested settings are reloaded here and re-index the whole subtree,
+// since chunking may have changed.
+func handleSettingsChange(ctx context.Context, idx *indexer.Indexer, scanner *indexer.Scanner, extractor *trace.RegexExtractor, symbolStore *trace.GOBSymbolStore, rpgIndexer *rpg.RPGIndexer, enabledLanguages []string, event watcher.FileEvent) {
+	force := event.Type == watcher.EventDirConfigChange
+	if force {
+		idx.DirConfigs().Reload()
+	}
+
+	diff, err := idx.ReindexDir(ctx, filepath.Dir(event.Path), force)
+	if err != nil {
+		log.Printf("Failed to apply %s: %v", event.Path, err)
+		return
+	}
+	log.Printf("Applied %s: %d files added, %d updated, %d removed", event.Path, len(diff.Added), len(diff.Updated), len(diff.Removed))
+	if err := idx.SaveSecretsReport(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	if symbolStore == nil {
+		return
+	}
+
+	for _, path := range diff.Removed {
+		if err := symbolStore.DeleteFile(ctx, path); err != nil {
+			log.Printf("Failed to remove symbols for %s: %v", path, err)
+		}
+		if rpgIndexer != nil {
+			if err := rpgIndexer.HandleFileEvent(ctx, "delete", path, nil); err != nil {
+				log.Printf("Warning: failed to update RPG for %s: %v", path, err)
+			}
+		}
+	}
+	for _, path := range append(diff.Added, diff.Updated...) {
+		if !isTracedFile(idx, path, enabledLanguages) {
+			if err := symbolStore.DeleteFile(ctx, path); err != nil {
+				log.Printf("Failed to remove symbols for %s: %v", path, err)
+			}
+			continue
+		}
+		fileInfo, err := scanner.ScanFile(path)
+		if err != nil || fileInfo == nil {
+			continue
+		}
+		symbols, refs, err := extractor.ExtractAll(ctx, fileInfo.Path, fileInfo.Content)
+		if err != nil {
+			log.Printf("Failed to extract symbols from %s: %v", path, err)
+			continue
+		}
+		if err := symbolStore.SaveFileWithContentHash(ctx, fileInfo.Path, fileInfo.Hash, symbols, refs); err != nil {
+			log.Printf("Failed to save symbols for %s: %v", path, err)
+			continue
+		}
+		if rpgIndexer != nil {
+			if err := rpgIndexer.HandleFileEvent(ctx, "modify", fileInfo.Path, symbols); err != nil {
+				log.Printf("Warning: failed to update RPG for %s: %v", path, err)
+			}
+		}
+	}
+}
+
+// isTracedFile reports whether symbols are extracted from a file, honoring the
+// trace.enabled_languages overrides of nested .grepai.yaml files.
+func isTracedFile(idx *indexer.Indexer, relPath string, enabledLanguages []string) bool {
+	enabledLanguages = idx.DirConfigs().TraceLanguages(relPath, enabledLanguages)
+	return isTracedLanguage(strings.ToLower(filepath.Ext(relPath)), enabledLanguages)
+}
+
 // isTracedLanguage checks if a file extension is in the enabled languages list.
 func isTracedLanguage(ext string, enabledLanguages []string) bool {
 	for _, lang := range enabledLanguages {
@@ -1656,7 +1878,7 @@ func runWorkspaceWatchForeground(logDir string, ws *config.Workspace) error {
 
 	// Initialize shared embedder
 	embCfg := &config.Config{Embedder: ws.Embedder}
-	emb, err := initializeEmbedder(ctx, embCfg)
+	emb, err := initializeEmbedder(ctx, embCfg, workspaceUsageFile(ws)...)
 	if err != nil {
 		return fmt.Errorf("failed to initialize embedder: %w", err)
 	}
@@ -1863,15 +2085,16 @@ func initializeWorkspaceRuntime(ctx context.Context, ws *config.Workspace, proje
 		return nil, nil, fmt.Errorf("failed to initialize ignore matcher: %w", err)
 	}
 
-	scanner := indexer.NewScanner(project.Path, ignoreMatcher)
-	chunker := indexer.NewChunker(projectCfg.Chunking.Size, projectCfg.Chunking.Overlap)
+	scanner := newScanner(project.Path, ignoreMatcher, projectCfg.Index)
+	chunker := newChunker(projectCfg.Chunking)
 	vectorStore := &projectPrefixStore{
 		store:         sharedStore,
 		workspaceName: ws.Name,
 		projectName:   project.Name,
 		projectPath:   project.Path,
 	}
-	idx := indexer.NewIndexer(project.Path, vectorStore, emb, chunker, scanner, projectCfg.Watch.LastIndexTime)
+	idx := indexer.NewIndexer(project.Path, vectorStore, emb, chunker, scanner, projectCfg.Watch.LastIndexTime,
+		indexerOptions(project.Path, projectCfg, ignoreMatcher)...)
 	extractor := trace.NewRegexExtractor()
 	symbolStore := trace.NewGOBSymbolStore(config.GetSymbolIndexPath(project.Path))
 	if err := symbolStore.Load(ctx); err != nil {
@@ -1884,7 +2107,11 @@ func initializeWorkspaceRuntime(ctx context.Context, ws *config.Workspace, proje
 	}
 
 	stats, err := runInitialScan(ctx, idx, scanner, extractor, symbolStore, tracedLanguages, projectCfg.Watch.LastIndexTime, isBackgroundChild)
-	if err != nil {
+	if embedder.AsBudgetExceededError(err) != nil {
+		// As for a project watch, changes are still watched
+		log.Printf("Warning: %s: %v", project.Name, err)
+		stats = &indexer.IndexStats{}
+	} else if err != nil {
 		_ = symbolStore.Close()
 		return nil, nil, err
 	}
@@ -1939,7 +2166,7 @@ func initializeWorkspaceRuntime(ctx context.Context, ws *config.Workspace, proje
 		startRPGRealtimeWorkers(ctx, fmt.Sprintf("workspace:%s/%s", ws.Name, project.Name), symbolStore, rpgIndexer, rpgStore, projectCfg.Watch, manager)
 	}
 
-	w, err := watcher.NewWatcher(project.Path, ignoreMatcher, projectCfg.Watch.DebounceMs)
+	w, err := watcher.NewWatcher(project.Path, ignoreMatcher, projectCfg.Watch.DebounceMs, watcher.WithFileTypes(newFileTypes(projectCfg.Index)))
 	if err != nil {
 		if rpgStore != nil {
 			_ = rpgStore.Close()
@@ -10,6 +10,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/yoanbernabeu/grepai/config"
+	"github.com/yoanbernabeu/grepai/embedder"
 	"gopkg.in/yaml.v3"
 )
 
@@ -87,7 +88,7 @@ func init() {
 
 	// Non-interactive workspace create flags
 	workspaceCreateCmd.Flags().String("backend", "", "Storage backend: postgres, qdrant")
-	workspaceCreateCmd.Flags().String("provider", "", "Embedding provider: ollama, openai, lmstudio")
+	workspaceCreateCmd.Flags().String("provider", "", "Embedding provider: ollama, openai, azure-openai, lmstudio, local")
 	workspaceCreateCmd.Flags().String("model", "", "Embedding model name")
 	workspaceCreateCmd.Flags().String("endpoint", "", "Embedder endpoint URL")
 	workspaceCreateCmd.Flags().String("dsn", "", "PostgreSQL DSN (when backend=postgres)")
@@ -235,8 +236,10 @@ func buildWorkspaceFromFlags(name, backend, provider, model, dsn, endpoint, qdra
 	}
 	if model == "" {
 		switch provider {
-		case "openai":
+		case "openai", "azure-openai":
 			model = "text-embedding-3-small"
+		case "local":
+			model = embedder.LocalModel
 		default:
 			model = "nomic-embed-text"
 		}
@@ -289,8 +292,16 @@ func buildWorkspaceFromFlags(name, backend, provider, model, dsn, endpoint, qdra
 			endpoint = "https://api.openai.com/v1"
 		}
 		embedderConfig.Endpoint = endpoint
+	case "azure-openai":
+		if endpoint == "" {
+			return nil, fmt.Errorf("--endpoint is required for azure-openai (https://RESOURCE.openai.azure.com)")
+		}
+		embedderConfig.Endpoint = endpoint
+	case "local":
+		dim := embedder.NewLocalEmbedder().Dimensions()
+		embedderConfig.Dimensions = &dim
 	default:
-		return nil, fmt.Errorf("unsupported provider: %s (use ollama, openai, or lmstudio)", provider)
+		return nil, fmt.Errorf("unsupported provider: %s (use ollama, openai, azure-openai, lmstudio, or local)", provider)
 	}
 
 	return &config.Workspace{
@@ -477,6 +488,7 @@ func createWorkspaceInteractive(workspaceName string) (*config.Workspace, error)
 	fmt.Println("  1. Ollama (local, default)")
 	fmt.Println("  2. OpenAI")
 	fmt.Println("  3. LM Studio (local)")
+	fmt.Println("  4. Azure OpenAI")
 	fmt.Print("Choice [1]: ")
 	embedderChoice, _ := reader.ReadString('\n')
 	embedderChoice = strings.TrimSpace(embedderChoice)
@@ -535,6 +547,27 @@ func createWorkspaceInteractive(workspaceName string) (*config.Workspace, error)
 		embedderConfig.Model = model
 		dim := 768
 		embedderConfig.Dimensions = &dim
+	case "4":
+		embedderConfig.Provider = "azure-openai"
+		fmt.Print("Azure OpenAI endpoint (https://RESOURCE.openai.azure.com): ")
+		endpoint, _ := reader.ReadString('\n')
+		embedderConfig.Endpoint = strings.TrimSpace(endpoint)
+		if embedderConfig.Endpoint == "" {
+			return nil, fmt.Errorf("endpoint is required for Azure OpenAI")
+		}
+		fmt.Print("Model [text-embedding-3-small]: ")
+		model, _ := reader.ReadString('\n')
+		model = strings.TrimSpace(model)
+		if model == "" {
+			model = "text-embedding-3-small"
+		}
+		embedderConfig.Model = model
+		fmt.Printf("Deployment [%s]: ", model)
+		deployment, _ := reader.ReadString('\n')
+		embedderConfig.Azure.Deployment = strings.TrimSpace(deployment)
+		fmt.Print("API key (empty to use AZURE_OPENAI_API_KEY or a token command): ")
+		apiKey, _ := reader.ReadString('\n')
+		embedderConfig.APIKey = strings.TrimSpace(apiKey)
 	default:
 		return nil, fmt.Errorf("invalid choice: %s", embedderChoice)
 	}
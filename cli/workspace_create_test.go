@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/yoanbernabeu/grepai/config"
+	"github.com/yoanbernabeu/grepai/embedder"
 )
 
 func setTestHomeDirCLI(t *testing.T, dir string) func() {
@@ -67,6 +68,36 @@ func TestCreateWorkspaceNonInteractive(t *testing.T) {
 		}
 	})
 
+	t.Run("flags_azure_openai", func(t *testing.T) {
+		if _, err := buildWorkspaceFromFlags("test-ws", "qdrant", "azure-openai", "", "", "", "", 0, "", false); err == nil {
+			t.Error("expected error without an Azure endpoint")
+		}
+
+		ws, err := buildWorkspaceFromFlags("test-ws", "qdrant", "azure-openai", "", "", "https://res.openai.azure.com", "", 0, "", false)
+		if err != nil {
+			t.Fatalf("buildWorkspaceFromFlags error: %v", err)
+		}
+		if ws.Embedder.Provider != "azure-openai" || ws.Embedder.Model != "text-embedding-3-small" {
+			t.Errorf("unexpected embedder %+v", ws.Embedder)
+		}
+		if ws.Embedder.Endpoint != "https://res.openai.azure.com" {
+			t.Errorf("expected the Azure endpoint, got %s", ws.Embedder.Endpoint)
+		}
+	})
+
+	t.Run("flags_local", func(t *testing.T) {
+		ws, err := buildWorkspaceFromFlags("test-ws", "qdrant", "local", "", "", "", "", 0, "", false)
+		if err != nil {
+			t.Fatalf("buildWorkspaceFromFlags error: %v", err)
+		}
+		if ws.Embedder.Model != embedder.LocalModel || ws.Embedder.Endpoint != "" {
+			t.Errorf("unexpected embedder %+v", ws.Embedder)
+		}
+		if ws.Embedder.Dimensions == nil || *ws.Embedder.Dimensions != 384 {
+			t.Errorf("expected 384 dimensions, got %v", ws.Embedder.Dimensions)
+		}
+	})
+
 	t.Run("yes_defaults", func(t *testing.T) {
 		tmpDir, _ := os.MkdirTemp("", "grepai-test-cli")
 		defer os.RemoveAll(tmpDir)
@@ -154,6 +185,26 @@ embedder:
 			t.Errorf("expected openai, got %s", ws.Embedder.Provider)
 		}
 	})
+
+	t.Run("from_json_file_azure_openai", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		jsonContent := `{
+  "store": {"backend": "qdrant"},
+  "embedder": {"provider": "azure-openai", "model": "text-embedding-3-large", "endpoint": "https://res.openai.azure.com",
+    "azure": {"deployment": "embeddings", "api_version": "2024-06-01", "token_command": "az account get-access-token"}}
+}`
+		jsonPath := filepath.Join(tmpDir, "ws-config.json")
+		os.WriteFile(jsonPath, []byte(jsonContent), 0644)
+
+		ws, err := buildWorkspaceFromFile("test-ws", jsonPath)
+		if err != nil {
+			t.Fatalf("buildWorkspaceFromFile error: %v", err)
+		}
+		azure := ws.Embedder.Azure
+		if azure.Deployment != "embeddings" || azure.APIVersion != "2024-06-01" || azure.TokenCommand != "az account get-access-token" {
+			t.Errorf("unexpected Azure settings %+v", azure)
+		}
+	})
 }
 
 func TestIntegration_WorkspaceCreateAndMCPResolve(t *testing.T) {
@@ -2,12 +2,16 @@ package config
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/yoanbernabeu/grepai/git"
+	"github.com/yoanbernabeu/grepai/redact"
+	"github.com/yoanbernabeu/grepai/tokenizer"
 	"gopkg.in/yaml.v3"
 )
 
@@ -17,6 +21,13 @@ const (
 	IndexFileName       = "index.gob"
 	SymbolIndexFileName = "symbols.gob"
 	RPGIndexFileName    = "rpg.gob"
+	GeneratedReportName = "generated.json"
+	CheckpointFileName  = "checkpoint.json"
+	SecretsReportName   = "secrets.json"
+	UsageFileName       = "usage.json"
+	HistoryDir          = "history"     // Commit history index and its commit metadata
+	HistoryLogFileName  = "commits.gob" // Metadata of the indexed commits
+	BlameCacheFileName  = "blame.gob"   // Blame and churn of result files, by content hash
 
 	// RPG default configuration values.
 	DefaultRPGDriftThreshold       = 0.35
@@ -30,20 +41,36 @@ const (
 	DefaultWatchRPGDerivedDebounceMs      = 300
 	DefaultWatchRPGFullReconcileIntervalS = 300
 	DefaultWatchRPGMaxDirtyFilesPerBatch  = 128
+
+	// DefaultChurnDays is the window of churn counts on search results.
+	DefaultChurnDays = 90
+
+	// DefaultEmbeddingCacheMaxSizeMB is the default size limit of the shared
+	// embedding cache.
+	DefaultEmbeddingCacheMaxSizeMB = 1024
+
+	// Commit history index defaults.
+	DefaultHistoryMaxCommits        = 1000
+	DefaultHistoryMaxFilesPerCommit = 20
+	DefaultHistoryMaxDiffLines      = 40
 )
 
 type Config struct {
-	Version           int            `yaml:"version"`
-	Embedder          EmbedderConfig `yaml:"embedder"`
-	Store             StoreConfig    `yaml:"store"`
-	Chunking          ChunkingConfig `yaml:"chunking"`
-	Watch             WatchConfig    `yaml:"watch"`
-	Search            SearchConfig   `yaml:"search"`
-	Trace             TraceConfig    `yaml:"trace"`
-	RPG               RPGConfig      `yaml:"rpg"`
-	Update            UpdateConfig   `yaml:"update"`
-	Ignore            []string       `yaml:"ignore"`
-	ExternalGitignore string         `yaml:"external_gitignore,omitempty"`
+	Version           int             `yaml:"version"`
+	Embedder          EmbedderConfig  `yaml:"embedder"`
+	Store             StoreConfig     `yaml:"store"`
+	Chunking          ChunkingConfig  `yaml:"chunking"`
+	Index             IndexConfig     `yaml:"index"`
+	Watch             WatchConfig     `yaml:"watch"`
+	Branches          BranchesConfig  `yaml:"branches"`
+	History           HistoryConfig   `yaml:"history"`
+	Search            SearchConfig    `yaml:"search"`
+	Trace             TraceConfig     `yaml:"trace"`
+	RPG               RPGConfig       `yaml:"rpg"`
+	Redaction         RedactionConfig `yaml:"redaction"`
+	Update            UpdateConfig    `yaml:"update"`
+	Ignore            []string        `yaml:"ignore"`
+	ExternalGitignore string          `yaml:"external_gitignore,omitempty"`
 }
 
 // UpdateConfig holds auto-update settings
@@ -54,6 +81,15 @@ type UpdateConfig struct {
 type SearchConfig struct {
 	Boost  BoostConfig  `yaml:"boost"`
 	Hybrid HybridConfig `yaml:"hybrid"`
+	Blame  BlameConfig  `yaml:"blame"`
+}
+
+// BlameConfig controls the git blame and churn metadata added to search and
+// trace results: the last commit of each result's lines and the number of
+// commits that touched its file recently.
+type BlameConfig struct {
+	Enabled   bool `yaml:"enabled"`    // Add blame metadata without --blame
+	ChurnDays int  `yaml:"churn_days"` // Window of churn counts (default: 90)
 }
 
 type HybridConfig struct {
@@ -65,6 +101,11 @@ type BoostConfig struct {
 	Enabled   bool        `yaml:"enabled"`
 	Penalties []BoostRule `yaml:"penalties"`
 	Bonuses   []BoostRule `yaml:"bonuses"`
+	Generated float32     `yaml:"generated"` // Factor for chunks of generated/vendored files (index.generated.mode: penalty)
+
+	// Recency rules scale results by the age of the last commit of their
+	// lines, computed with git blame. Matching rules are multiplied.
+	Recency []RecencyRule `yaml:"recency,omitempty"`
 }
 
 type BoostRule struct {
@@ -72,25 +113,174 @@ type BoostRule struct {
 	Factor  float32 `yaml:"factor"`
 }
 
+// RecencyRule applies Factor to results last changed between MinAgeDays and
+// MaxAgeDays ago (0 for no bound). Uncommitted lines are 0 days old.
+type RecencyRule struct {
+	MinAgeDays int     `yaml:"min_age_days,omitempty"`
+	MaxAgeDays int     `yaml:"max_age_days,omitempty"`
+	Factor     float32 `yaml:"factor"`
+}
+
 type EmbedderConfig struct {
-	Provider    string `yaml:"provider"` // ollama | lmstudio | openai | synthetic | openrouter
+	Provider    string `yaml:"provider"` // ollama | lmstudio | openai | azure-openai | synthetic | openrouter | tei | llamacpp | local
 	Model       string `yaml:"model"`
 	Endpoint    string `yaml:"endpoint,omitempty"`
 	APIKey      string `yaml:"api_key,omitempty"`
 	Dimensions  *int   `yaml:"dimensions,omitempty"`
-	Parallelism int    `yaml:"parallelism"` // Number of parallel workers for batch embedding (default: 4)
+	Parallelism int    `yaml:"parallelism"`         // Number of parallel workers for batch embedding (default: 4)
+	TPMLimit    int64  `yaml:"tpm_limit,omitempty"` // Tokens per minute limit for OpenAI (0 = disabled)
+	Truncate    bool   `yaml:"truncate,omitempty"`  // Truncate inputs longer than the model context instead of re-chunking them (TEI, llama.cpp)
+
+	// Prices overrides DefaultEmbeddingPrices, in USD per million tokens by model.
+	Prices map[string]float64 `yaml:"prices,omitempty"`
+
+	// Prefixes overrides DefaultEmbeddingPrefixes, by model.
+	Prefixes map[string]EmbeddingPrefixes `yaml:"prefixes,omitempty"`
+
+	Cache EmbeddingCacheConfig `yaml:"cache"`
+
+	Azure AzureOpenAIConfig `yaml:"azure,omitempty"`
+
+	Budget EmbeddingBudgetConfig `yaml:"budget,omitempty"`
+
+	// Fallbacks are providers used in order while this one fails. They must
+	// serve the same model family and dimensions; their vectors are recorded
+	// and embedded again once this provider answers.
+	Fallbacks []EmbedderConfig `yaml:"fallbacks,omitempty"`
+}
+
+// AzureOpenAIConfig configures the azure-openai provider. The endpoint is the
+// Azure resource (https://RESOURCE.openai.azure.com). Requests authenticate
+// with api_key (or AZURE_OPENAI_API_KEY), or with a bearer token printed by
+// TokenCommand or read from TokenFile.
+type AzureOpenAIConfig struct {
+	Deployment   string `yaml:"deployment,omitempty" json:"deployment,omitempty"`       // Deployment name (default: model)
+	APIVersion   string `yaml:"api_version,omitempty" json:"api_version,omitempty"`     // api-version query parameter (default: 2024-10-21)
+	TokenCommand string `yaml:"token_command,omitempty" json:"token_command,omitempty"` // Command printing a Microsoft Entra ID token
+	TokenFile    string `yaml:"token_file,omitempty" json:"token_file,omitempty"`       // File holding a bearer token, re-read when rejected
+}
+
+// EmbeddingBudgetConfig limits the tokens a project sends to embedding
+// providers per calendar day and month (local time), as recorded in
+// .grepai/usage.json. Indexing pauses once a budget is reached; search
+// queries are counted but never refused. 0 disables a budget.
+type EmbeddingBudgetConfig struct {
+	DailyTokens   int64 `yaml:"daily_tokens,omitempty"`
+	MonthlyTokens int64 `yaml:"monthly_tokens,omitempty"`
+}
+
+// EmbeddingCacheConfig controls the embedding cache shared by all projects of
+// the machine, in ~/.grepai/cache. Vectors are keyed by provider, model,
+// dimensions and content, so identical code is embedded once.
+type EmbeddingCacheConfig struct {
+	Enabled   bool `yaml:"enabled"`
+	MaxSizeMB int  `yaml:"max_size_mb"` // Least recently used vectors are evicted above this size (default: 1024)
+}
+
+// DefaultEmbeddingPrices lists known embedding prices in USD per million
+// tokens, used by `grepai index --dry-run`.
+var DefaultEmbeddingPrices = map[string]float64{
+	"text-embedding-3-small": 0.02,
+	"text-embedding-3-large": 0.13,
+	"text-embedding-ada-002": 0.10,
+}
+
+// EmbeddingPrefixes are the instructions asymmetric embedding models expect
+// before search queries and indexed documents.
+type EmbeddingPrefixes struct {
+	Query    string `yaml:"query" json:"query"`
+	Document string `yaml:"document" json:"document"`
+}
+
+const bgeQueryInstruction = "Represent this sentence for searching relevant passages: "
+
+// DefaultEmbeddingPrefixes lists the prefixes of popular asymmetric models,
+// by model name without tag or vendor.
+var DefaultEmbeddingPrefixes = map[string]EmbeddingPrefixes{
+	"nomic-embed-text":                     {Query: "search_query: ", Document: "search_document: "},
+	"nomic-embed-text-v1":                  {Query: "search_query: ", Document: "search_document: "},
+	"nomic-embed-text-v1.5":                {Query: "search_query: ", Document: "search_document: "},
+	"nomic-embed-text-v2-moe":              {Query: "search_query: ", Document: "search_document: "},
+	"nomic-embed-code":                     {Query: "search_query: ", Document: "search_document: "},
+	"text-embedding-nomic-embed-text-v1.5": {Query: "search_query: ", Document: "search_document: "},
+	"e5-small-v2":                          {Query: "query: ", Document: "passage: "},
+	"e5-base-v2":                           {Query: "query: ", Document: "passage: "},
+	"e5-large-v2":                          {Query: "query: ", Document: "passage: "},
+	"multilingual-e5-small":                {Query: "query: ", Document: "passage: "},
+	"multilingual-e5-base":                 {Query: "query: ", Document: "passage: "},
+	"multilingual-e5-large":                {Query: "query: ", Document: "passage: "},
+	"bge-small-en-v1.5":                    {Query: bgeQueryInstruction},
+	"bge-base-en-v1.5":                     {Query: bgeQueryInstruction},
+	"bge-large-en-v1.5":                    {Query: bgeQueryInstruction},
+	"mxbai-embed-large":                    {Query: bgeQueryInstruction},
+	"mxbai-embed-large-v1":                 {Query: bgeQueryInstruction},
+	"snowflake-arctic-embed":               {Query: bgeQueryInstruction},
+}
+
+// EmbeddingPrefixes returns the query and document prefixes of the configured
+// model: from Prefixes, else DefaultEmbeddingPrefixes. Symmetric and unknown
+// models get none.
+func (e *EmbedderConfig) EmbeddingPrefixes() EmbeddingPrefixes {
+	if prefixes, ok := e.Prefixes[e.Model]; ok {
+		return prefixes
+	}
+	// Ollama tags (nomic-embed-text:latest) and vendors (intfloat/e5-base-v2)
+	// do not change the prefixes
+	model := strings.ToLower(e.Model)
+	if i := strings.LastIndex(model, ":"); i >= 0 {
+		model = model[:i]
+	}
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	return DefaultEmbeddingPrefixes[model]
+}
+
+// PricePerMillionTokens returns the embedding price of the configured model in
+// USD per million tokens. Local providers are free; ok is false when the price
+// is unknown.
+func (e *EmbedderConfig) PricePerMillionTokens() (price float64, ok bool) {
+	if price, ok := e.Prices[e.Model]; ok {
+		return price, true
+	}
+	switch e.Provider {
+	case "ollama", "lmstudio", "tei", "llamacpp", "local":
+		return 0, true
+	}
+	// OpenRouter model names are prefixed by the vendor (openai/text-embedding-3-small)
+	model := e.Model
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	price, ok = DefaultEmbeddingPrices[model]
+	return price, ok
+}
+
+// IsRemote reports whether the provider sends content to a third party.
+// Self-hosted servers (TEI, llama.cpp) and the in-process local embedder are
+// not remote.
+func (e *EmbedderConfig) IsRemote() bool {
+	switch e.Provider {
+	case "ollama", "lmstudio", "tei", "llamacpp", "local":
+		return false
+	default:
+		return true
+	}
 }
 
 // GetDimensions returns the configured dimensions or a default value.
-// For OpenAI/OpenRouter, defaults to 1536 (text-embedding-3-small).
+// For OpenAI/Azure OpenAI/OpenRouter, defaults to 1536 (text-embedding-3-small).
 // For Ollama/LMStudio/Synthetic, defaults to 768 (nomic-embed-text-v1.5).
+// For the local embedder, defaults to 384.
 func (e *EmbedderConfig) GetDimensions() int {
 	if e.Dimensions != nil {
 		return *e.Dimensions
 	}
 	switch e.Provider {
-	case "openai", "openrouter":
+	case "openai", "azure-openai", "openrouter":
 		return 1536
+	case "local":
+		return 384
 	default:
 		return 768
 	}
@@ -115,8 +305,82 @@ type QdrantConfig struct {
 }
 
 type ChunkingConfig struct {
-	Size    int `yaml:"size"`
-	Overlap int `yaml:"overlap"`
+	Size           int    `yaml:"size"`
+	Overlap        int    `yaml:"overlap"`
+	Tokenizer      string `yaml:"tokenizer,omitempty"`       // estimate (default) | cl100k | wordpiece
+	TokenizerVocab string `yaml:"tokenizer_vocab,omitempty"` // cl100k_base.tiktoken or vocab.txt path (required for wordpiece)
+}
+
+// IndexConfig controls which files are indexed and how.
+type IndexConfig struct {
+	IncludeExtensions []string        `yaml:"include_extensions,omitempty"` // Extra extensions to index (e.g. ".vhdl")
+	ExcludeExtensions []string        `yaml:"exclude_extensions,omitempty"` // Default extensions to stop indexing (e.g. ".txt")
+	Filenames         []string        `yaml:"filenames,omitempty"`          // Extra file names or globs to index (e.g. "Tiltfile")
+	Generated         GeneratedConfig `yaml:"generated"`
+	Workers           int             `yaml:"workers,omitempty"` // Files read, hashed and chunked in parallel (default: number of CPUs)
+}
+
+// GeneratedConfig controls detection of generated and vendored files.
+type GeneratedConfig struct {
+	Mode             string `yaml:"mode"`                // skip | penalty (default) | keep
+	MaxAvgLineLength int    `yaml:"max_avg_line_length"` // Files with a longer average line are treated as generated (default: 300, 0 disables)
+}
+
+// RedactionConfig controls masking of secrets in chunks before they are sent
+// to the embedding provider.
+type RedactionConfig struct {
+	Mode             string          `yaml:"mode"`                     // auto (default, remote providers only) | always | off
+	MaskStored       bool            `yaml:"mask_stored"`              // Also mask secrets in the chunk content stored in the index
+	EntropyThreshold float64         `yaml:"entropy_threshold"`        // Bits per character for high-entropy tokens (default: 4.0, -1 disables)
+	DisabledRules    []string        `yaml:"disabled_rules,omitempty"` // Built-in rules to turn off (e.g. "high-entropy")
+	Rules            []RedactionRule `yaml:"rules,omitempty"`          // Extra rules
+}
+
+// RedactionRule is a custom secret pattern. When the regular expression has a
+// capturing group, only the first group is masked.
+type RedactionRule struct {
+	Name    string `yaml:"name"`
+	Pattern string `yaml:"pattern"`
+}
+
+// Enabled reports whether chunks are redacted for the given embedder.
+func (r *RedactionConfig) Enabled(embedder EmbedderConfig) bool {
+	switch r.Mode {
+	case "always":
+		return true
+	case "off":
+		return false
+	default:
+		return embedder.IsRemote()
+	}
+}
+
+// NewRedactor builds a redactor from the built-in rules, minus disabled ones,
+// and the custom rules. Invalid custom rules are skipped with a warning.
+func (r *RedactionConfig) NewRedactor() *redact.Redactor {
+	disabled := make(map[string]bool, len(r.DisabledRules))
+	for _, name := range r.DisabledRules {
+		disabled[name] = true
+	}
+	var rules []redact.Rule
+	for _, rule := range redact.DefaultRules() {
+		if !disabled[rule.Name] {
+			rules = append(rules, rule)
+		}
+	}
+	for _, custom := range r.Rules {
+		rule, err := redact.CompileRule(custom.Name, custom.Pattern)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	threshold := r.EntropyThreshold
+	if disabled[redact.EntropyRule] {
+		threshold = 0
+	}
+	return redact.New(rules, threshold)
 }
 
 type WatchConfig struct {
@@ -128,6 +392,24 @@ type WatchConfig struct {
 	RPGMaxDirtyFilesPerBatch    int       `yaml:"rpg_max_dirty_files_per_batch,omitempty"`
 }
 
+// BranchesConfig controls branch overlay indexes. With overlays, the main
+// index holds the default branch and each other branch gets an overlay with
+// only the files that differ from it.
+type BranchesConfig struct {
+	Overlay       bool   `yaml:"overlay"`
+	DefaultBranch string `yaml:"default_branch,omitempty"` // Detected when empty
+}
+
+// HistoryConfig controls the commit history index searched by
+// `grepai log-search`: each commit message and a summarized diff of each
+// file it touched are embedded into an index of their own.
+type HistoryConfig struct {
+	Enabled           bool `yaml:"enabled"`
+	MaxCommits        int  `yaml:"max_commits"`          // Most recent commits indexed (-1 = all)
+	MaxFilesPerCommit int  `yaml:"max_files_per_commit"` // Files whose diff is embedded, per commit
+	MaxDiffLines      int  `yaml:"max_diff_lines"`       // Diff lines kept in each file summary
+}
+
 type TraceConfig struct {
 	Mode             string   `yaml:"mode"`              // fast or precise
 	EnabledLanguages []string `yaml:"enabled_languages"` // File extensions to index
@@ -171,6 +453,44 @@ func ValidateRPGConfig(cfg RPGConfig) error {
 	return nil
 }
 
+// ValidateIndexConfig checks index configuration values for validity.
+func ValidateIndexConfig(cfg IndexConfig) error {
+	switch cfg.Generated.Mode {
+	case "skip", "penalty", "keep":
+	default:
+		return fmt.Errorf("index.generated.mode must be skip, penalty or keep, got %q", cfg.Generated.Mode)
+	}
+	if cfg.Generated.MaxAvgLineLength < 0 {
+		return fmt.Errorf("index.generated.max_avg_line_length must be >= 0, got %d", cfg.Generated.MaxAvgLineLength)
+	}
+	return nil
+}
+
+// ValidateChunkingConfig checks that the configured tokenizer can be loaded,
+// so that a missing vocabulary is reported instead of silently falling back
+// to the estimate.
+func ValidateChunkingConfig(cfg ChunkingConfig) error {
+	return tokenizer.Check(cfg.Tokenizer, cfg.TokenizerVocab)
+}
+
+// ValidateRedactionConfig checks redaction configuration values for validity.
+func ValidateRedactionConfig(cfg RedactionConfig) error {
+	switch cfg.Mode {
+	case "auto", "always", "off":
+	default:
+		return fmt.Errorf("redaction.mode must be auto, always or off, got %q", cfg.Mode)
+	}
+	for _, rule := range cfg.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("redaction rule %q has no name", rule.Pattern)
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("invalid redaction rule %s: %w", rule.Name, err)
+		}
+	}
+	return nil
+}
+
 // ValidateWatchConfig checks watch configuration values for validity.
 func ValidateWatchConfig(cfg WatchConfig) error {
 	if cfg.RPGPersistIntervalMs < 200 {
@@ -197,7 +517,11 @@ func DefaultConfig() *Config {
 			Model:      "nomic-embed-text",
 			Endpoint:   "http://localhost:11434",
 			Dimensions: &defaultDim,
-			// Parallelism intentionally omitted - only applies to OpenAI
+			// Parallelism intentionally omitted - applied by applyDefaults
+			Cache: EmbeddingCacheConfig{
+				Enabled:   false,
+				MaxSizeMB: DefaultEmbeddingCacheMaxSizeMB,
+			},
 		},
 		Store: StoreConfig{
 			Backend: "gob",
@@ -206,6 +530,16 @@ func DefaultConfig() *Config {
 			Size:    512,
 			Overlap: 50,
 		},
+		Index: IndexConfig{
+			Generated: GeneratedConfig{
+				Mode:             "penalty",
+				MaxAvgLineLength: 300,
+			},
+		},
+		Redaction: RedactionConfig{
+			Mode:             "auto",
+			EntropyThreshold: 4.0,
+		},
 		Watch: WatchConfig{
 			DebounceMs:                  500,
 			RPGPersistIntervalMs:        DefaultWatchRPGPersistIntervalMs,
@@ -213,11 +547,21 @@ func DefaultConfig() *Config {
 			RPGFullReconcileIntervalSec: DefaultWatchRPGFullReconcileIntervalS,
 			RPGMaxDirtyFilesPerBatch:    DefaultWatchRPGMaxDirtyFilesPerBatch,
 		},
+		History: HistoryConfig{
+			Enabled:           false,
+			MaxCommits:        DefaultHistoryMaxCommits,
+			MaxFilesPerCommit: DefaultHistoryMaxFilesPerCommit,
+			MaxDiffLines:      DefaultHistoryMaxDiffLines,
+		},
 		Search: SearchConfig{
 			Hybrid: HybridConfig{
 				Enabled: false,
 				K:       60,
 			},
+			Blame: BlameConfig{
+				Enabled:   false,
+				ChurnDays: DefaultChurnDays,
+			},
 			Boost: BoostConfig{
 				Enabled: true,
 				Penalties: []BoostRule{
@@ -250,6 +594,7 @@ func DefaultConfig() *Config {
 					{Pattern: "/lib/", Factor: 1.1},
 					{Pattern: "/app/", Factor: 1.1},
 				},
+				Generated: 0.5,
 			},
 		},
 		Trace: TraceConfig{
@@ -259,6 +604,8 @@ func DefaultConfig() *Config {
 				".c", ".h", ".cpp", ".hpp", ".cc", ".cxx",
 				".rs", ".zig", ".cs", ".java",
 				".pas", ".dpr", // Pascal/Delphi
+				".yaml", ".yml", ".json", ".toml", ".tf", ".hcl", // Config keys
+				".ipynb", // Jupyter notebooks (Python cells)
 			},
 			ExcludePatterns: []string{
 				"*_test.go",
@@ -319,10 +666,40 @@ func GetSymbolIndexPath(projectRoot string) string {
 	return filepath.Join(GetConfigDir(projectRoot), SymbolIndexFileName)
 }
 
+// GetBlameCachePath returns the path of the cache of blame metadata.
+func GetBlameCachePath(projectRoot string) string {
+	return filepath.Join(GetConfigDir(projectRoot), BlameCacheFileName)
+}
+
+// GetHistoryDir returns the directory of the commit history index.
+func GetHistoryDir(projectRoot string) string {
+	return filepath.Join(GetConfigDir(projectRoot), HistoryDir)
+}
+
 func GetRPGIndexPath(projectRoot string) string {
 	return filepath.Join(GetConfigDir(projectRoot), RPGIndexFileName)
 }
 
+// GetGeneratedReportPath returns the path of the generated/vendored file report.
+func GetGeneratedReportPath(projectRoot string) string {
+	return filepath.Join(GetConfigDir(projectRoot), GeneratedReportName)
+}
+
+// GetCheckpointPath returns the path of the indexing checkpoint manifest.
+func GetCheckpointPath(projectRoot string) string {
+	return filepath.Join(GetConfigDir(projectRoot), CheckpointFileName)
+}
+
+// GetSecretsReportPath returns the path of the report of files with redacted secrets.
+func GetSecretsReportPath(projectRoot string) string {
+	return filepath.Join(GetConfigDir(projectRoot), SecretsReportName)
+}
+
+// GetUsagePath returns the path of the embedding usage of the project.
+func GetUsagePath(projectRoot string) string {
+	return filepath.Join(GetConfigDir(projectRoot), UsageFileName)
+}
+
 func Load(projectRoot string) (*Config, error) {
 	configPath := GetConfigPath(projectRoot)
 
@@ -344,6 +721,18 @@ func Load(projectRoot string) (*Config, error) {
 		return nil, fmt.Errorf("invalid watch configuration: %w", err)
 	}
 
+	if err := ValidateIndexConfig(cfg.Index); err != nil {
+		return nil, fmt.Errorf("invalid index configuration: %w", err)
+	}
+
+	if err := ValidateRedactionConfig(cfg.Redaction); err != nil {
+		return nil, fmt.Errorf("invalid redaction configuration: %w", err)
+	}
+
+	if err := ValidateChunkingConfig(cfg.Chunking); err != nil {
+		return nil, fmt.Errorf("invalid chunking configuration: %w", err)
+	}
+
 	// Validate RPG config when enabled
 	if cfg.RPG.Enabled {
 		if err := ValidateRPGConfig(cfg.RPG); err != nil {
@@ -354,49 +743,78 @@ func Load(projectRoot string) (*Config, error) {
 	return &cfg, nil
 }
 
-// applyDefaults fills in missing configuration values with sensible defaults.
-// This ensures backward compatibility with older config files that may not
-// have newer fields like dimensions or endpoint.
-func (c *Config) applyDefaults() {
-	defaults := DefaultConfig()
-
-	// Embedder defaults
-	if c.Embedder.Endpoint == "" {
-		switch c.Embedder.Provider {
+// applyProviderDefaults fills in the endpoint, dimensions and parallelism of
+// an embedder from its provider.
+func (e *EmbedderConfig) applyProviderDefaults(defaultEndpoint string) {
+	if e.Endpoint == "" {
+		switch e.Provider {
 		case "ollama":
-			c.Embedder.Endpoint = "http://localhost:11434"
+			e.Endpoint = "http://localhost:11434"
 		case "lmstudio":
-			c.Embedder.Endpoint = "http://127.0.0.1:1234"
+			e.Endpoint = "http://127.0.0.1:1234"
 		case "openai":
-			c.Embedder.Endpoint = "https://api.openai.com/v1"
+			e.Endpoint = "https://api.openai.com/v1"
 		case "synthetic":
-			c.Embedder.Endpoint = "https://api.synthetic.new/openai/v1"
+			e.Endpoint = "https://api.synthetic.new/openai/v1"
 		case "openrouter":
-			c.Embedder.Endpoint = "https://openrouter.ai/api/v1"
+			e.Endpoint = "https://openrouter.ai/api/v1"
+		case "tei", "llamacpp":
+			e.Endpoint = "http://localhost:8080"
+		case "azure-openai":
+			// No default: the endpoint is the Azure resource
+		case "local":
+			// Embedded in-process, without endpoint
 		default:
-			c.Embedder.Endpoint = defaults.Embedder.Endpoint
+			e.Endpoint = defaultEndpoint
 		}
 	}
 
-	// Only set default dimensions for specific embedders (Ollama, LMStudio, Synthetic).
+	// Only set default dimensions for specific embedders (Ollama, LMStudio, Synthetic, local).
 	// For OpenAI, leave nil to let the API use the model's native dimensions.
-	if c.Embedder.Dimensions == nil {
-		switch c.Embedder.Provider {
+	if e.Dimensions == nil {
+		switch e.Provider {
 		case "ollama":
 			dim := 768 // nomic-embed-text default
-			c.Embedder.Dimensions = &dim
+			e.Dimensions = &dim
 		case "lmstudio":
 			dim := 768 // nomic default
-			c.Embedder.Dimensions = &dim
+			e.Dimensions = &dim
 		case "synthetic":
 			dim := 768 // nomic-embed-text-v1.5 default
-			c.Embedder.Dimensions = &dim
+			e.Dimensions = &dim
+		case "local":
+			dim := 384 // hashed-ngrams-v1 default
+			e.Dimensions = &dim
+		}
+	}
+
+	// Parallelism default (used by batch embedders: OpenAI, Ollama, LM Studio,
+	// TEI and llama.cpp)
+	if e.Parallelism <= 0 {
+		e.Parallelism = 4
+	}
+}
+
+// applyDefaults fills in missing configuration values with sensible defaults.
+// This ensures backward compatibility with older config files that may not
+// have newer fields like dimensions or endpoint.
+func (c *Config) applyDefaults() {
+	defaults := DefaultConfig()
+
+	// Embedder defaults. Fallbacks serve the same dimensions as the primary
+	// provider unless they set them.
+	c.Embedder.applyProviderDefaults(defaults.Embedder.Endpoint)
+	for i := range c.Embedder.Fallbacks {
+		fallback := &c.Embedder.Fallbacks[i]
+		if fallback.Dimensions == nil && c.Embedder.Dimensions != nil {
+			dim := *c.Embedder.Dimensions
+			fallback.Dimensions = &dim
 		}
+		fallback.applyProviderDefaults(defaults.Embedder.Endpoint)
 	}
 
-	// Parallelism default (only used by OpenAI embedder)
-	if c.Embedder.Parallelism <= 0 {
-		c.Embedder.Parallelism = 4
+	if c.Embedder.Cache.MaxSizeMB == 0 {
+		c.Embedder.Cache.MaxSizeMB = defaults.Embedder.Cache.MaxSizeMB
 	}
 
 	// Chunking defaults
@@ -407,6 +825,29 @@ func (c *Config) applyDefaults() {
 		c.Chunking.Overlap = defaults.Chunking.Overlap
 	}
 
+	// Index defaults
+	if c.Index.Generated.Mode == "" {
+		c.Index.Generated.Mode = defaults.Index.Generated.Mode
+		// Older configs have no index section at all; enable the line length heuristic too.
+		if c.Index.Generated.MaxAvgLineLength == 0 {
+			c.Index.Generated.MaxAvgLineLength = defaults.Index.Generated.MaxAvgLineLength
+		}
+	}
+	if c.Search.Boost.Generated == 0 {
+		c.Search.Boost.Generated = defaults.Search.Boost.Generated
+	}
+	if c.Search.Blame.ChurnDays == 0 {
+		c.Search.Blame.ChurnDays = defaults.Search.Blame.ChurnDays
+	}
+
+	// Redaction defaults
+	if c.Redaction.Mode == "" {
+		c.Redaction.Mode = defaults.Redaction.Mode
+	}
+	if c.Redaction.EntropyThreshold == 0 {
+		c.Redaction.EntropyThreshold = defaults.Redaction.EntropyThreshold
+	}
+
 	// Watch defaults
 	if c.Watch.DebounceMs == 0 {
 		c.Watch.DebounceMs = defaults.Watch.DebounceMs
@@ -424,6 +865,17 @@ func (c *Config) applyDefaults() {
 		c.Watch.RPGMaxDirtyFilesPerBatch = defaults.Watch.RPGMaxDirtyFilesPerBatch
 	}
 
+	// History defaults
+	if c.History.MaxCommits == 0 {
+		c.History.MaxCommits = defaults.History.MaxCommits
+	}
+	if c.History.MaxFilesPerCommit == 0 {
+		c.History.MaxFilesPerCommit = defaults.History.MaxFilesPerCommit
+	}
+	if c.History.MaxDiffLines == 0 {
+		c.History.MaxDiffLines = defaults.History.MaxDiffLines
+	}
+
 	// Qdrant defaults
 	if c.Store.Backend == "qdrant" && c.Store.Qdrant.Port <= 0 {
 		c.Store.Qdrant.Port = 6334
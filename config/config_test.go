@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -552,6 +553,24 @@ func TestApplyDefaults_WatchRealtimeFields(t *testing.T) {
 	}
 }
 
+func TestApplyDefaults_GeneratedFiles(t *testing.T) {
+	cfg := &Config{}
+	cfg.applyDefaults()
+
+	if cfg.Index.Generated.Mode != "penalty" {
+		t.Errorf("expected index.generated.mode=penalty, got %q", cfg.Index.Generated.Mode)
+	}
+	if cfg.Index.Generated.MaxAvgLineLength != 300 {
+		t.Errorf("expected index.generated.max_avg_line_length=300, got %d", cfg.Index.Generated.MaxAvgLineLength)
+	}
+	if cfg.Search.Boost.Generated != 0.5 {
+		t.Errorf("expected search.boost.generated=0.5, got %f", cfg.Search.Boost.Generated)
+	}
+	if err := ValidateIndexConfig(IndexConfig{Generated: GeneratedConfig{Mode: "drop"}}); err == nil {
+		t.Error("expected error for unknown generated mode")
+	}
+}
+
 func TestValidateWatchConfig(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -619,3 +638,94 @@ func TestValidateWatchConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestPricePerMillionTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    EmbedderConfig
+		want   float64
+		wantOK bool
+	}{
+		{"openai default", EmbedderConfig{Provider: "openai", Model: "text-embedding-3-small"}, 0.02, true},
+		{"openrouter prefixed model", EmbedderConfig{Provider: "openrouter", Model: "openai/text-embedding-3-large"}, 0.13, true},
+		{"local provider", EmbedderConfig{Provider: "ollama", Model: "nomic-embed-text"}, 0, true},
+		{"unknown model", EmbedderConfig{Provider: "synthetic", Model: "hf:nomic-ai/nomic-embed-text-v1.5"}, 0, false},
+		{"configured price", EmbedderConfig{Provider: "openai", Model: "text-embedding-3-small", Prices: map[string]float64{"text-embedding-3-small": 0.01}}, 0.01, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.cfg.PricePerMillionTokens()
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("PricePerMillionTokens() = (%v, %v), want (%v, %v)", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRedactionConfig(t *testing.T) {
+	cfg := &Config{Embedder: EmbedderConfig{Provider: "openai"}}
+	cfg.applyDefaults()
+
+	if cfg.Redaction.Mode != "auto" || cfg.Redaction.EntropyThreshold != 4.0 {
+		t.Errorf("expected auto mode with a 4.0 threshold, got %+v", cfg.Redaction)
+	}
+	if !cfg.Redaction.Enabled(cfg.Embedder) {
+		t.Error("redaction should be enabled for openai in auto mode")
+	}
+	if cfg.Redaction.Enabled(EmbedderConfig{Provider: "ollama"}) {
+		t.Error("redaction should be disabled for ollama in auto mode")
+	}
+	always := RedactionConfig{Mode: "always"}
+	if !always.Enabled(EmbedderConfig{Provider: "ollama"}) {
+		t.Error("redaction should be enabled for ollama in always mode")
+	}
+
+	if err := ValidateRedactionConfig(RedactionConfig{Mode: "sometimes"}); err == nil {
+		t.Error("expected error for unknown redaction mode")
+	}
+	if err := ValidateRedactionConfig(RedactionConfig{Mode: "auto", Rules: []RedactionRule{{Name: "bad", Pattern: "("}}}); err == nil {
+		t.Error("expected error for an invalid rule pattern")
+	}
+}
+
+func TestLoad_TokenizerWithoutVocab(t *testing.T) {
+	projectRoot := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.Chunking.Tokenizer = "wordpiece"
+	if err := cfg.Save(projectRoot); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if _, err := Load(projectRoot); err == nil || !strings.Contains(err.Error(), "tokenizer_vocab") {
+		t.Errorf("expected a missing vocab to be reported, got %v", err)
+	}
+}
+
+func TestEmbeddingPrefixes(t *testing.T) {
+	tests := []struct {
+		model    string
+		prefixes map[string]EmbeddingPrefixes
+		want     EmbeddingPrefixes
+	}{
+		{model: "nomic-embed-text", want: EmbeddingPrefixes{Query: "search_query: ", Document: "search_document: "}},
+		{model: "nomic-embed-text:latest", want: EmbeddingPrefixes{Query: "search_query: ", Document: "search_document: "}},
+		{model: "intfloat/e5-base-v2", want: EmbeddingPrefixes{Query: "query: ", Document: "passage: "}},
+		{model: "BAAI/bge-small-en-v1.5", want: EmbeddingPrefixes{Query: bgeQueryInstruction}},
+		{model: "text-embedding-3-small", want: EmbeddingPrefixes{}},
+		{
+			model:    "nomic-embed-text",
+			prefixes: map[string]EmbeddingPrefixes{"nomic-embed-text": {}},
+			want:     EmbeddingPrefixes{},
+		},
+		{
+			model:    "custom-model",
+			prefixes: map[string]EmbeddingPrefixes{"custom-model": {Query: "q: ", Document: "d: "}},
+			want:     EmbeddingPrefixes{Query: "q: ", Document: "d: "},
+		},
+	}
+	for _, tt := range tests {
+		e := EmbedderConfig{Model: tt.model, Prefixes: tt.prefixes}
+		if got := e.EmbeddingPrefixes(); got != tt.want {
+			t.Errorf("EmbeddingPrefixes(%q) = %+v, want %+v", tt.model, got, tt.want)
+		}
+	}
+}
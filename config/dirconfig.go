@@ -0,0 +1,234 @@
+package config
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DirConfigFileName is the name of nested configuration files. A .grepai.yaml
+// in any directory overrides the project configuration for its subtree.
+const DirConfigFileName = ".grepai.yaml"
+
+// DirConfig holds the settings a nested .grepai.yaml may override. Unset
+// fields inherit from the parent directories and the project configuration.
+type DirConfig struct {
+	Chunking DirChunkingConfig `yaml:"chunking"`
+	Search   DirSearchConfig   `yaml:"search"`
+	Trace    DirTraceConfig    `yaml:"trace"`
+}
+
+type DirChunkingConfig struct {
+	Size    int `yaml:"size"`    // 0 inherits
+	Overlap int `yaml:"overlap"` // 0 inherits
+}
+
+type DirSearchConfig struct {
+	Boost DirBoostConfig `yaml:"boost"`
+}
+
+// DirBoostConfig adds boost rules for results in the subtree. Rules are added
+// to the inherited ones; enabled turns boosting on or off for the subtree.
+type DirBoostConfig struct {
+	Enabled   *bool       `yaml:"enabled,omitempty"`
+	Penalties []BoostRule `yaml:"penalties,omitempty"`
+	Bonuses   []BoostRule `yaml:"bonuses,omitempty"`
+}
+
+type DirTraceConfig struct {
+	EnabledLanguages []string `yaml:"enabled_languages,omitempty"` // Replaces the inherited list
+}
+
+// DirConfigs holds the nested .grepai.yaml files of a project. A nil
+// *DirConfigs has no overrides. It is safe for concurrent use and can be
+// reloaded when the files change.
+type DirConfigs struct {
+	fsys     fs.FS // Project files
+	skipDirs []string
+	ignored  func(relPath string) bool // nil = only skipDirs
+
+	mu   sync.RWMutex
+	dirs map[string]*DirConfig // By slash-separated directory relative to root ("" for root)
+}
+
+// DirConfigsOption configures the search of nested .grepai.yaml files.
+type DirConfigsOption func(*DirConfigs)
+
+// WithIgnoredDirs skips the directories for which ignored, given their
+// slash-separated path relative to the project root, returns true; typically
+// IgnoreMatcher.ShouldIgnore, so that gitignored trees are not walked.
+func WithIgnoredDirs(ignored func(relPath string) bool) DirConfigsOption {
+	return func(d *DirConfigs) {
+		d.ignored = ignored
+	}
+}
+
+// LoadDirConfigs finds the .grepai.yaml files below projectRoot. Hidden
+// directories and directories named in skipDirs (config.Ignore) are not searched.
+func LoadDirConfigs(projectRoot string, skipDirs []string, opts ...DirConfigsOption) *DirConfigs {
+	return LoadDirConfigsFS(os.DirFS(projectRoot), skipDirs, opts...)
+}
+
+// LoadDirConfigsFS is LoadDirConfigs for the project files in fsys, e.g. a
+// git tree.
+func LoadDirConfigsFS(fsys fs.FS, skipDirs []string, opts ...DirConfigsOption) *DirConfigs {
+	d := &DirConfigs{fsys: fsys, skipDirs: skipDirs}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.Reload()
+	return d
+}
+
+// Reload finds and parses the .grepai.yaml files again. Invalid files are
+// skipped with a warning.
+func (d *DirConfigs) Reload() {
+	if d == nil {
+		return
+	}
+	dirs := make(map[string]*DirConfig)
+	_ = fs.WalkDir(d.fsys, ".", func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip inaccessible paths
+		}
+		if entry.IsDir() {
+			if p == "." {
+				return nil
+			}
+			name := entry.Name()
+			if strings.HasPrefix(name, ".") {
+				return fs.SkipDir
+			}
+			for _, skip := range d.skipDirs {
+				if name == skip {
+					return fs.SkipDir
+				}
+			}
+			if d.ignored != nil && d.ignored(p) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if entry.Name() != DirConfigFileName {
+			return nil
+		}
+
+		dc, err := loadDirConfig(d.fsys, p)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			return nil
+		}
+		rel := path.Dir(p)
+		if rel == "." {
+			rel = ""
+		}
+		dirs[rel] = dc
+		return nil
+	})
+
+	d.mu.Lock()
+	d.dirs = dirs
+	d.mu.Unlock()
+}
+
+func loadDirConfig(fsys fs.FS, configPath string) (*DirConfig, error) {
+	data, err := fs.ReadFile(fsys, configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", configPath, err)
+	}
+	var dc DirConfig
+	if err := yaml.Unmarshal(data, &dc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", configPath, err)
+	}
+	if dc.Chunking.Size < 0 || dc.Chunking.Overlap < 0 {
+		return nil, fmt.Errorf("invalid %s: chunking size and overlap must be >= 0", configPath)
+	}
+	return &dc, nil
+}
+
+// Len returns the number of nested configuration files.
+func (d *DirConfigs) Len() int {
+	if d == nil {
+		return 0
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.dirs)
+}
+
+// chain returns the configurations applying to a file, from the root down.
+func (d *DirConfigs) chain(relPath string) []*DirConfig {
+	if d == nil {
+		return nil
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if len(d.dirs) == 0 {
+		return nil
+	}
+
+	var chain []*DirConfig
+	if dc, ok := d.dirs[""]; ok {
+		chain = append(chain, dc)
+	}
+	dir := path.Dir(filepath.ToSlash(relPath))
+	if dir == "." || dir == "/" {
+		return chain
+	}
+	parts := strings.Split(dir, "/")
+	for i := range parts {
+		if dc, ok := d.dirs[strings.Join(parts[:i+1], "/")]; ok {
+			chain = append(chain, dc)
+		}
+	}
+	return chain
+}
+
+// Chunking returns the chunking settings for a file.
+func (d *DirConfigs) Chunking(relPath string, base ChunkingConfig) ChunkingConfig {
+	for _, dc := range d.chain(relPath) {
+		if dc.Chunking.Size > 0 {
+			base.Size = dc.Chunking.Size
+		}
+		if dc.Chunking.Overlap > 0 {
+			base.Overlap = dc.Chunking.Overlap
+		}
+	}
+	return base
+}
+
+// Boost returns the boost settings for search results of a file.
+func (d *DirConfigs) Boost(relPath string, base BoostConfig) BoostConfig {
+	chain := d.chain(relPath)
+	if len(chain) == 0 {
+		return base
+	}
+	boost := base
+	boost.Penalties = append([]BoostRule(nil), base.Penalties...)
+	boost.Bonuses = append([]BoostRule(nil), base.Bonuses...)
+	for _, dc := range chain {
+		if dc.Search.Boost.Enabled != nil {
+			boost.Enabled = *dc.Search.Boost.Enabled
+		}
+		boost.Penalties = append(boost.Penalties, dc.Search.Boost.Penalties...)
+		boost.Bonuses = append(boost.Bonuses, dc.Search.Boost.Bonuses...)
+	}
+	return boost
+}
+
+// TraceLanguages returns the extensions indexed for symbols for a file.
+func (d *DirConfigs) TraceLanguages(relPath string, base []string) []string {
+	for _, dc := range d.chain(relPath) {
+		if dc.Trace.EnabledLanguages != nil {
+			base = dc.Trace.EnabledLanguages
+		}
+	}
+	return base
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeDirConfig(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, DirConfigFileName), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", DirConfigFileName, err)
+	}
+}
+
+func TestDirConfigs(t *testing.T) {
+	root := t.TempDir()
+	writeDirConfig(t, filepath.Join(root, "services"), `
+chunking:
+  size: 256
+search:
+  boost:
+    penalties:
+      - pattern: /legacy/
+        factor: 0.5
+trace:
+  enabled_languages: [.go]
+`)
+	writeDirConfig(t, filepath.Join(root, "services", "web"), `
+chunking:
+  overlap: 10
+search:
+  boost:
+    enabled: false
+trace:
+  enabled_languages: [.ts, .tsx]
+`)
+	writeDirConfig(t, filepath.Join(root, "node_modules", "pkg"), "chunking:\n  size: 64\n")
+	writeDirConfig(t, filepath.Join(root, "broken"), "chunking: [")
+
+	dirs := LoadDirConfigs(root, []string{"node_modules"})
+	if dirs.Len() != 2 {
+		t.Fatalf("expected 2 nested configs, got %d", dirs.Len())
+	}
+
+	base := ChunkingConfig{Size: 512, Overlap: 50}
+	if got := dirs.Chunking("main.go", base); got != base {
+		t.Errorf("root file chunking = %+v, want %+v", got, base)
+	}
+	if got := dirs.Chunking(filepath.Join("services", "api", "main.go"), base); got != (ChunkingConfig{Size: 256, Overlap: 50}) {
+		t.Errorf("services chunking = %+v", got)
+	}
+	if got := dirs.Chunking(filepath.Join("services", "web", "app.ts"), base); got != (ChunkingConfig{Size: 256, Overlap: 10}) {
+		t.Errorf("services/web chunking = %+v", got)
+	}
+	if got := dirs.Chunking(filepath.Join("node_modules", "pkg", "index.js"), base); got != base {
+		t.Errorf("skipped directory should not be searched, got %+v", got)
+	}
+
+	baseBoost := BoostConfig{Enabled: true, Penalties: []BoostRule{{Pattern: "_test.", Factor: 0.5}}}
+	boost := dirs.Boost("services/api/legacy/old.go", baseBoost)
+	if !boost.Enabled || len(boost.Penalties) != 2 {
+		t.Errorf("services boost = %+v", boost)
+	}
+	if len(baseBoost.Penalties) != 1 {
+		t.Error("Boost must not modify the base configuration")
+	}
+	if dirs.Boost("services/web/app.ts", baseBoost).Enabled {
+		t.Error("boosting should be disabled in services/web")
+	}
+
+	baseLangs := []string{".go", ".py"}
+	if got := dirs.TraceLanguages("services/web/app.ts", baseLangs); !reflect.DeepEqual(got, []string{".ts", ".tsx"}) {
+		t.Errorf("services/web trace languages = %v", got)
+	}
+	if got := dirs.TraceLanguages("tools/gen.py", baseLangs); !reflect.DeepEqual(got, baseLangs) {
+		t.Errorf("root trace languages = %v", got)
+	}
+
+	// Reload picks up removed files
+	if err := os.Remove(filepath.Join(root, "services", DirConfigFileName)); err != nil {
+		t.Fatalf("failed to remove config: %v", err)
+	}
+	dirs.Reload()
+	if got := dirs.Chunking(filepath.Join("services", "api", "main.go"), base); got != base {
+		t.Errorf("chunking after reload = %+v, want %+v", got, base)
+	}
+
+	var none *DirConfigs
+	if got := none.Chunking("a.go", base); got != base || none.Len() != 0 {
+		t.Error("a nil DirConfigs has no overrides")
+	}
+}
+
+func TestDirConfigs_IgnoredDirs(t *testing.T) {
+	root := t.TempDir()
+	writeDirConfig(t, filepath.Join(root, "services"), "chunking:\n  size: 256\n")
+	writeDirConfig(t, filepath.Join(root, "build", "gen"), "chunking:\n  size: 64\n")
+
+	var walked []string
+	dirs := LoadDirConfigs(root, nil, WithIgnoredDirs(func(relPath string) bool {
+		walked = append(walked, relPath)
+		return relPath == "build"
+	}))
+	if dirs.Len() != 1 {
+		t.Fatalf("expected the config of the ignored directory to be skipped, got %d configs", dirs.Len())
+	}
+	if !reflect.DeepEqual(walked, []string{"build", "services"}) {
+		t.Errorf("expected the ignored directory not to be walked, checked %v", walked)
+	}
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// FingerprintFileName records the embedding settings an index was built with.
+const FingerprintFileName = "fingerprint.json"
+
+// ChunkSchema is the version of the chunk metadata recorded in the index.
+// Indexes of an older version are chunked again, reusing their vectors.
+//
+//	1: chunks record their language
+const ChunkSchema = 1
+
+// Fingerprint describes the embedding settings of an index: vectors computed
+// with other settings are not comparable with it.
+type Fingerprint struct {
+	Provider       string `json:"provider"`
+	Model          string `json:"model"`
+	Dimensions     int    `json:"dimensions,omitempty"`
+	QueryPrefix    string `json:"query_prefix,omitempty"`
+	DocumentPrefix string `json:"document_prefix,omitempty"`
+	ChunkSchema    int    `json:"chunk_schema,omitempty"` // 0 for indexes that predate ChunkSchema
+}
+
+// NewFingerprint returns the fingerprint of the embedding settings of a
+// configuration.
+func NewFingerprint(e EmbedderConfig) Fingerprint {
+	prefixes := e.EmbeddingPrefixes()
+	f := Fingerprint{
+		Provider:       e.Provider,
+		Model:          e.Model,
+		QueryPrefix:    prefixes.Query,
+		DocumentPrefix: prefixes.Document,
+		ChunkSchema:    ChunkSchema,
+	}
+	if e.Dimensions != nil {
+		f.Dimensions = *e.Dimensions
+	}
+	return f
+}
+
+// Diff returns the names of the settings that differ between two
+// fingerprints.
+func (f Fingerprint) Diff(other Fingerprint) []string {
+	var diff []string
+	if f.Provider != other.Provider {
+		diff = append(diff, "provider")
+	}
+	if f.Model != other.Model {
+		diff = append(diff, "model")
+	}
+	if f.Dimensions != other.Dimensions {
+		diff = append(diff, "dimensions")
+	}
+	if f.QueryPrefix != other.QueryPrefix {
+		diff = append(diff, "query prefix")
+	}
+	if f.DocumentPrefix != other.DocumentPrefix {
+		diff = append(diff, "document prefix")
+	}
+	return diff
+}
+
+// LoadFingerprint reads the fingerprint of the index in dataDir. It returns
+// nil without error when the index has none.
+func LoadFingerprint(dataDir string) (*Fingerprint, error) {
+	data, err := os.ReadFile(filepath.Join(dataDir, FingerprintFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index fingerprint: %w", err)
+	}
+	var f Fingerprint
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse index fingerprint: %w", err)
+	}
+	return &f, nil
+}
+
+// SaveFingerprint records the fingerprint of the index in dataDir.
+func SaveFingerprint(dataDir string, f Fingerprint) error {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal index fingerprint: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dataDir, FingerprintFileName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write index fingerprint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	dir := t.TempDir()
+
+	stored, err := LoadFingerprint(dir)
+	if err != nil || stored != nil {
+		t.Fatalf("expected no fingerprint for a new index, got %+v, %v", stored, err)
+	}
+
+	nomic := NewFingerprint(EmbedderConfig{Provider: "ollama", Model: "nomic-embed-text"})
+	if nomic.DocumentPrefix != "search_document: " || nomic.QueryPrefix != "search_query: " {
+		t.Errorf("expected the prefixes of the model in its fingerprint, got %+v", nomic)
+	}
+	if err := SaveFingerprint(dir, nomic); err != nil {
+		t.Fatalf("SaveFingerprint failed: %v", err)
+	}
+	stored, err = LoadFingerprint(dir)
+	if err != nil || stored == nil || *stored != nomic {
+		t.Fatalf("expected %+v, got %+v, %v", nomic, stored, err)
+	}
+	if diff := stored.Diff(nomic); len(diff) != 0 {
+		t.Errorf("expected no difference, got %v", diff)
+	}
+	legacy := nomic
+	legacy.ChunkSchema = 0
+	if diff := legacy.Diff(nomic); len(diff) != 0 {
+		t.Errorf("expected the chunk schema not to be an embedding difference, got %v", diff)
+	}
+
+	dims := 768
+	unprefixed := NewFingerprint(EmbedderConfig{
+		Provider:   "ollama",
+		Model:      "nomic-embed-text",
+		Dimensions: &dims,
+		Prefixes:   map[string]EmbeddingPrefixes{"nomic-embed-text": {}},
+	})
+	want := []string{"dimensions", "query prefix", "document prefix"}
+	if diff := stored.Diff(unprefixed); !reflect.DeepEqual(diff, want) {
+		t.Errorf("expected differences %v, got %v", want, diff)
+	}
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/yoanbernabeu/grepai/git"
+)
+
+const (
+	// OverlaysDir holds one directory per branch overlay, with its metadata
+	// and, for the GOB backend, its index.
+	OverlaysDir         = "overlays"
+	OverlayInfoFileName = "overlay.json"
+)
+
+// OverlayInfo describes the overlay index of a branch.
+type OverlayInfo struct {
+	Branch    string    `json:"branch"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GetOverlayDir returns the directory of a branch overlay.
+func GetOverlayDir(projectRoot, branch string) string {
+	return filepath.Join(GetConfigDir(projectRoot), OverlaysDir, RefSlug(branch))
+}
+
+// GetOverlayIndexPath returns the GOB index of a branch overlay.
+func GetOverlayIndexPath(projectRoot, branch string) string {
+	return filepath.Join(GetOverlayDir(projectRoot, branch), IndexFileName)
+}
+
+// OverlayProjectID returns the PostgreSQL project id of a branch overlay.
+func OverlayProjectID(projectRoot, branch string) string {
+	return projectRoot + "#" + branch
+}
+
+// SupportsOverlays reports whether a storage backend can hold branch
+// overlays. Qdrant does not store document hashes, which overlays need to
+// tell the files that differ from the base.
+func SupportsOverlays(backend string) bool {
+	return backend == "gob" || backend == "postgres"
+}
+
+// OverlayBranch returns the branch whose overlay indexes the project at
+// projectRoot, or "" when the main index is used: overlays are disabled or
+// unsupported, the project is not a git repository, or the default branch is
+// checked out.
+func OverlayBranch(projectRoot string, cfg *Config) (string, error) {
+	if !cfg.Branches.Overlay || !SupportsOverlays(cfg.Store.Backend) || !git.IsGitRepo(projectRoot) {
+		return "", nil
+	}
+	branch, err := git.CurrentBranch(projectRoot)
+	if err != nil {
+		return "", err
+	}
+	defaultBranch, err := DefaultBranch(projectRoot, cfg)
+	if err != nil {
+		return "", err
+	}
+	if branch == defaultBranch {
+		return "", nil
+	}
+	return branch, nil
+}
+
+// DefaultBranch returns branches.default_branch, or the detected default
+// branch of the repository.
+func DefaultBranch(projectRoot string, cfg *Config) (string, error) {
+	if cfg.Branches.DefaultBranch != "" {
+		return cfg.Branches.DefaultBranch, nil
+	}
+	return git.DefaultBranch(projectRoot)
+}
+
+// EnsureOverlayInfo records the overlay of a branch if it is new.
+func EnsureOverlayInfo(projectRoot, branch string) error {
+	dir := GetOverlayDir(projectRoot, branch)
+	path := filepath.Join(dir, OverlayInfoFileName)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create overlay directory: %w", err)
+	}
+	data, err := json.MarshalIndent(OverlayInfo{Branch: branch, CreatedAt: time.Now()}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal overlay info: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write overlay info: %w", err)
+	}
+	return nil
+}
+
+// HasOverlay reports whether the overlay of a branch was created.
+func HasOverlay(projectRoot, branch string) bool {
+	_, err := os.Stat(filepath.Join(GetOverlayDir(projectRoot, branch), OverlayInfoFileName))
+	return err == nil
+}
+
+// ListOverlays returns the branch overlays of a project, sorted by branch.
+// Unreadable overlay directories are skipped.
+func ListOverlays(projectRoot string) ([]OverlayInfo, error) {
+	entries, err := os.ReadDir(filepath.Join(GetConfigDir(projectRoot), OverlaysDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list overlays: %w", err)
+	}
+	var overlays []OverlayInfo
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(GetConfigDir(projectRoot), OverlaysDir, entry.Name(), OverlayInfoFileName))
+		if err != nil {
+			continue
+		}
+		var info OverlayInfo
+		if err := json.Unmarshal(data, &info); err != nil || info.Branch == "" {
+			continue
+		}
+		overlays = append(overlays, info)
+	}
+	sort.Slice(overlays, func(i, j int) bool { return overlays[i].Branch < overlays[j].Branch })
+	return overlays, nil
+}
+
+// RemoveOverlayDir deletes the directory of a branch overlay.
+func RemoveOverlayDir(projectRoot, branch string) error {
+	if err := os.RemoveAll(GetOverlayDir(projectRoot, branch)); err != nil {
+		return fmt.Errorf("failed to remove overlay of %s: %w", branch, err)
+	}
+	return nil
+}
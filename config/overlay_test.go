@@ -0,0 +1,49 @@
+package config
+
+import "testing"
+
+func TestOverlayInfo(t *testing.T) {
+	root := t.TempDir()
+
+	if HasOverlay(root, "feature/x") {
+		t.Fatal("expected no overlay before it is created")
+	}
+	if overlays, err := ListOverlays(root); err != nil || len(overlays) != 0 {
+		t.Fatalf("expected no overlays, got %v (%v)", overlays, err)
+	}
+
+	for _, branch := range []string{"feature/x", "bugfix"} {
+		if err := EnsureOverlayInfo(root, branch); err != nil {
+			t.Fatalf("EnsureOverlayInfo(%q) failed: %v", branch, err)
+		}
+	}
+	if err := EnsureOverlayInfo(root, "bugfix"); err != nil {
+		t.Fatalf("EnsureOverlayInfo on an existing overlay failed: %v", err)
+	}
+	if !HasOverlay(root, "feature/x") {
+		t.Error("expected the overlay of feature/x to exist")
+	}
+
+	overlays, err := ListOverlays(root)
+	if err != nil {
+		t.Fatalf("ListOverlays failed: %v", err)
+	}
+	if len(overlays) != 2 || overlays[0].Branch != "bugfix" || overlays[1].Branch != "feature/x" {
+		t.Errorf("expected bugfix and feature/x, got %+v", overlays)
+	}
+
+	if err := RemoveOverlayDir(root, "feature/x"); err != nil {
+		t.Fatalf("RemoveOverlayDir failed: %v", err)
+	}
+	if HasOverlay(root, "feature/x") {
+		t.Error("expected the overlay of feature/x to be removed")
+	}
+}
+
+func TestOverlayBranchDisabled(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Branches.Overlay = false
+	if branch, err := OverlayBranch(t.TempDir(), cfg); err != nil || branch != "" {
+		t.Errorf("expected no overlay branch when disabled, got %q (%v)", branch, err)
+	}
+}
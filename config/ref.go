@@ -0,0 +1,80 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// RefsDir holds one directory per git ref indexed with `grepai index --ref`,
+	// each with its own index, symbols, checkpoint and reports.
+	RefsDir         = "refs"
+	RefInfoFileName = "ref.json"
+)
+
+// RefInfo describes the index of a git ref.
+type RefInfo struct {
+	Ref       string    `json:"ref"`
+	Commit    string    `json:"commit"` // Commit the ref pointed to when indexed
+	IndexedAt time.Time `json:"indexed_at"`
+}
+
+// RefSlug returns a file name safe identifier for a git ref. Characters other
+// than letters, digits, '.', '-' and '_' are replaced, and a short hash of the
+// ref is appended when that happens so that distinct refs never collide
+// (e.g. "feature/x" and "feature-x").
+func RefSlug(ref string) string {
+	slug := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '-'
+	}, ref)
+	if slug == ref && strings.Trim(slug, ".") != "" {
+		return slug
+	}
+	sum := sha256.Sum256([]byte(ref))
+	return slug + "-" + hex.EncodeToString(sum[:4])
+}
+
+// GetRefDir returns the directory holding the index of a git ref.
+func GetRefDir(projectRoot, ref string) string {
+	return filepath.Join(GetConfigDir(projectRoot), RefsDir, RefSlug(ref))
+}
+
+// LoadRefInfo reads the description of a ref index. It returns an error
+// wrapping os.ErrNotExist when the ref was never indexed.
+func LoadRefInfo(projectRoot, ref string) (*RefInfo, error) {
+	data, err := os.ReadFile(filepath.Join(GetRefDir(projectRoot, ref), RefInfoFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index of ref %s: %w", ref, err)
+	}
+	var info RefInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse index of ref %s: %w", ref, err)
+	}
+	return &info, nil
+}
+
+// SaveRefInfo records that a ref was indexed.
+func SaveRefInfo(projectRoot string, info RefInfo) error {
+	dir := GetRefDir(projectRoot, info.Ref)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create ref index directory: %w", err)
+	}
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal ref info: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, RefInfoFileName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write ref info: %w", err)
+	}
+	return nil
+}
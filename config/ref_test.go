@@ -0,0 +1,53 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRefSlug(t *testing.T) {
+	for _, ref := range []string{"main", "v1.2.0", "a1b2c3d", "release_2"} {
+		if got := RefSlug(ref); got != ref {
+			t.Errorf("RefSlug(%q) = %q, want it unchanged", ref, got)
+		}
+	}
+
+	slash, dash := RefSlug("feature/x"), RefSlug("feature-x")
+	if slash == dash {
+		t.Errorf("expected distinct slugs for feature/x and feature-x, got %q", slash)
+	}
+	if !strings.HasPrefix(slash, "feature-x-") {
+		t.Errorf("expected a readable slug for feature/x, got %q", slash)
+	}
+	for _, ref := range []string{"feature/x", "HEAD~1", "..", "../../etc"} {
+		if slug := RefSlug(ref); strings.ContainsAny(slug, `/\~`) || strings.Trim(slug, ".") == "" {
+			t.Errorf("RefSlug(%q) = %q is not a safe directory name", ref, slug)
+		}
+	}
+}
+
+func TestRefInfo(t *testing.T) {
+	root := t.TempDir()
+
+	if _, err := LoadRefInfo(root, "feature/x"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected not exist error for an unindexed ref, got %v", err)
+	}
+
+	info := RefInfo{Ref: "feature/x", Commit: "abc123", IndexedAt: time.Now().Truncate(time.Second)}
+	if err := SaveRefInfo(root, info); err != nil {
+		t.Fatalf("SaveRefInfo failed: %v", err)
+	}
+	loaded, err := LoadRefInfo(root, "feature/x")
+	if err != nil {
+		t.Fatalf("LoadRefInfo failed: %v", err)
+	}
+	if loaded.Ref != info.Ref || loaded.Commit != info.Commit || !loaded.IndexedAt.Equal(info.IndexedAt) {
+		t.Errorf("expected %+v, got %+v", info, *loaded)
+	}
+	if _, err := LoadRefInfo(root, "feature-x"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected feature-x to have its own index, got %v", err)
+	}
+}
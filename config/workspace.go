@@ -44,6 +44,16 @@ func GetGlobalConfigDir() (string, error) {
 	return filepath.Join(homeDir, ".grepai"), nil
 }
 
+// GetEmbeddingCacheDir returns the directory of the embedding cache shared by
+// all projects, ~/.grepai/cache.
+func GetEmbeddingCacheDir() (string, error) {
+	globalDir, err := GetGlobalConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(globalDir, "cache"), nil
+}
+
 // GetWorkspaceConfigPath returns the path to the workspace config file.
 func GetWorkspaceConfigPath() (string, error) {
 	globalDir, err := GetGlobalConfigDir()
@@ -53,6 +63,17 @@ func GetWorkspaceConfigPath() (string, error) {
 	return filepath.Join(globalDir, WorkspaceConfigFileName), nil
 }
 
+// GetWorkspaceUsagePath returns the path of the embedding usage of a
+// workspace, ~/.grepai/workspace-<name>-usage.json: its projects share the
+// embedder and the budgets of the workspace.
+func GetWorkspaceUsagePath(name string) (string, error) {
+	globalDir, err := GetGlobalConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(globalDir, "workspace-"+name+"-usage.json"), nil
+}
+
 // LoadWorkspaceConfig loads the workspace configuration from ~/.grepai/workspace.yaml.
 // Returns nil, nil if the file doesn't exist.
 func LoadWorkspaceConfig() (*WorkspaceConfig, error) {
@@ -0,0 +1,207 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAzureOpenAIAPIVersion = "2024-10-21"
+	// azureTokenTTL is how long a bearer token read from a command or file is
+	// reused. Microsoft Entra ID tokens last an hour or more; a token rejected
+	// earlier is read again.
+	azureTokenTTL = 5 * time.Minute
+)
+
+type azureOpenAISettings struct {
+	endpoint     string
+	deployment   string
+	apiVersion   string
+	apiKey       string
+	tokenCommand string
+	tokenFile    string
+	openAI       []OpenAIOption
+}
+
+type AzureOpenAIOption func(*azureOpenAISettings)
+
+// WithAzureOpenAIEndpoint sets the endpoint of the Azure OpenAI resource,
+// https://RESOURCE.openai.azure.com.
+func WithAzureOpenAIEndpoint(endpoint string) AzureOpenAIOption {
+	return func(s *azureOpenAISettings) {
+		s.endpoint = endpoint
+	}
+}
+
+func WithAzureOpenAIDeployment(deployment string) AzureOpenAIOption {
+	return func(s *azureOpenAISettings) {
+		s.deployment = deployment
+	}
+}
+
+func WithAzureOpenAIAPIVersion(version string) AzureOpenAIOption {
+	return func(s *azureOpenAISettings) {
+		if version != "" {
+			s.apiVersion = version
+		}
+	}
+}
+
+// WithAzureOpenAIKey authenticates with a resource key, sent in the api-key
+// header.
+func WithAzureOpenAIKey(key string) AzureOpenAIOption {
+	return func(s *azureOpenAISettings) {
+		s.apiKey = key
+	}
+}
+
+// WithAzureOpenAITokenCommand authenticates with a bearer token printed by a
+// shell command, such as `az account get-access-token --resource
+// https://cognitiveservices.azure.com --query accessToken -o tsv`.
+func WithAzureOpenAITokenCommand(command string) AzureOpenAIOption {
+	return func(s *azureOpenAISettings) {
+		s.tokenCommand = command
+	}
+}
+
+// WithAzureOpenAITokenFile authenticates with a bearer token read from a
+// file, such as a token projected by a workload identity.
+func WithAzureOpenAITokenFile(path string) AzureOpenAIOption {
+	return func(s *azureOpenAISettings) {
+		s.tokenFile = path
+	}
+}
+
+// WithAzureOpenAIOptions applies OpenAI options: dimensions, parallelism,
+// TPM limit, tokenizer and retry policy.
+func WithAzureOpenAIOptions(opts ...OpenAIOption) AzureOpenAIOption {
+	return func(s *azureOpenAISettings) {
+		s.openAI = append(s.openAI, opts...)
+	}
+}
+
+// NewAzureOpenAIEmbedder returns an OpenAIEmbedder for an Azure OpenAI
+// deployment, with the batching and rate limiting of the OpenAI provider.
+func NewAzureOpenAIEmbedder(opts ...AzureOpenAIOption) (*OpenAIEmbedder, error) {
+	s := &azureOpenAISettings{apiVersion: defaultAzureOpenAIAPIVersion}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	endpoint := strings.TrimSuffix(strings.TrimSuffix(s.endpoint, "/"), "/openai")
+	if endpoint == "" {
+		return nil, fmt.Errorf("Azure OpenAI endpoint not set (use https://RESOURCE.openai.azure.com)")
+	}
+	if s.deployment == "" {
+		return nil, fmt.Errorf("Azure OpenAI deployment not set")
+	}
+
+	e := newOpenAIEmbedder(s.openAI)
+	e.endpoint = endpoint
+	e.embeddingsURL = fmt.Sprintf("%s/openai/deployments/%s/embeddings?api-version=%s",
+		endpoint, url.PathEscape(s.deployment), url.QueryEscape(s.apiVersion))
+
+	switch {
+	case s.tokenCommand != "" && s.tokenFile != "":
+		return nil, fmt.Errorf("Azure OpenAI token command and token file are exclusive")
+	case s.tokenCommand != "" || s.tokenFile != "":
+		e.auth = &bearerTokenSource{command: s.tokenCommand, file: s.tokenFile}
+	default:
+		if s.apiKey == "" {
+			s.apiKey = os.Getenv("AZURE_OPENAI_API_KEY")
+		}
+		if s.apiKey == "" {
+			return nil, fmt.Errorf("Azure OpenAI credentials not set (use AZURE_OPENAI_API_KEY, api_key, token_command or token_file)")
+		}
+		e.auth = azureKey(s.apiKey)
+	}
+
+	return e, nil
+}
+
+// azureKey authenticates with an Azure resource key.
+type azureKey string
+
+func (k azureKey) apply(_ context.Context, req *http.Request) error {
+	req.Header.Set("api-key", string(k))
+	return nil
+}
+
+func (k azureKey) refresh() bool { return false }
+
+// bearerTokenSource authenticates with a bearer token printed by a command or
+// read from a file, reused for azureTokenTTL.
+type bearerTokenSource struct {
+	command string
+	file    string
+
+	mu      sync.Mutex
+	token   string
+	fetched time.Time
+}
+
+func (s *bearerTokenSource) apply(ctx context.Context, req *http.Request) error {
+	token, err := s.get(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (s *bearerTokenSource) refresh() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = ""
+	return true
+}
+
+func (s *bearerTokenSource) get(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && time.Since(s.fetched) < azureTokenTTL {
+		return s.token, nil
+	}
+
+	var out []byte
+	var err error
+	if s.command != "" {
+		out, err = shellCommand(ctx, s.command).Output()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("token command failed: %w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		if err != nil {
+			return "", fmt.Errorf("token command failed: %w", err)
+		}
+	} else {
+		out, err = os.ReadFile(s.file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read token file: %w", err)
+		}
+	}
+
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", fmt.Errorf("empty bearer token")
+	}
+	s.token, s.fetched = token, time.Now()
+	return token, nil
+}
+
+// shellCommand runs a command line with the shell of the platform.
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command) // #nosec G204 - command from the user's configuration
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command) // #nosec G204 - command from the user's configuration
+}
@@ -0,0 +1,177 @@
+package embedder
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// azureTestServer serves the embeddings of a deployment, accepting requests
+// for which authorized returns true.
+func azureTestServer(t *testing.T, authorized func(r *http.Request) bool) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openai/deployments/embed-prod/embeddings" || r.URL.Query().Get("api-version") != "2024-06-01" {
+			http.NotFound(w, r)
+			return
+		}
+		if !authorized(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"code":"401","message":"Access denied"}}`))
+			return
+		}
+		var req openAIEmbedRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var resp openAIEmbedResponse
+		for i, input := range req.Input {
+			resp.Data = append(resp.Data, struct {
+				Embedding []float32 `json:"embedding"`
+				Index     int       `json:"index"`
+			}{Embedding: []float32{float32(len(input))}, Index: i})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestAzureOpenAIEmbedder_Key(t *testing.T) {
+	server := azureTestServer(t, func(r *http.Request) bool {
+		return r.Header.Get("api-key") == "secret" && r.Header.Get("Authorization") == ""
+	})
+	defer server.Close()
+
+	e, err := NewAzureOpenAIEmbedder(
+		WithAzureOpenAIEndpoint(server.URL+"/openai/"),
+		WithAzureOpenAIDeployment("embed-prod"),
+		WithAzureOpenAIAPIVersion("2024-06-01"),
+		WithAzureOpenAIKey("secret"),
+		WithAzureOpenAIOptions(WithOpenAIParallelism(2), WithOpenAIRetryPolicy(fastRetryPolicy)),
+	)
+	if err != nil {
+		t.Fatalf("NewAzureOpenAIEmbedder failed: %v", err)
+	}
+
+	ctx := context.Background()
+	vector, err := e.Embed(ctx, "abc")
+	if err != nil || vector[0] != 3 {
+		t.Fatalf("expected a 3 embedding, got %v, %v", vector, err)
+	}
+
+	batches := FormBatches([]FileChunks{{FileIndex: 0, Chunks: []string{"a", "bb"}}, {FileIndex: 1, Chunks: []string{"ccc"}}})
+	results, err := e.EmbedBatches(ctx, batches, nil)
+	if err != nil {
+		t.Fatalf("EmbedBatches failed: %v", err)
+	}
+	files := MapResultsToFiles(batches, results, 2)
+	if files[0][1][0] != 2 || files[1][0][0] != 3 {
+		t.Errorf("unexpected embeddings %v", files)
+	}
+}
+
+func TestAzureOpenAIEmbedder_TokenFile(t *testing.T) {
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenPath, []byte("expired\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var rejected atomic.Int32
+	server := azureTestServer(t, func(r *http.Request) bool {
+		if r.Header.Get("Authorization") == "Bearer rotated" {
+			return true
+		}
+		// The token is rotated while the first request is rejected
+		rejected.Add(1)
+		os.WriteFile(tokenPath, []byte("rotated\n"), 0600)
+		return false
+	})
+	defer server.Close()
+
+	e, err := NewAzureOpenAIEmbedder(
+		WithAzureOpenAIEndpoint(server.URL),
+		WithAzureOpenAIDeployment("embed-prod"),
+		WithAzureOpenAIAPIVersion("2024-06-01"),
+		WithAzureOpenAITokenFile(tokenPath),
+	)
+	if err != nil {
+		t.Fatalf("NewAzureOpenAIEmbedder failed: %v", err)
+	}
+	if _, err := e.EmbedBatch(context.Background(), []string{"a"}); err != nil {
+		t.Fatalf("expected the request to succeed with the rotated token, got %v", err)
+	}
+	if rejected.Load() != 1 {
+		t.Errorf("expected 1 rejected request, got %d", rejected.Load())
+	}
+}
+
+func TestAzureOpenAIEmbedder_TokenCommand(t *testing.T) {
+	server := azureTestServer(t, func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "Bearer from-command"
+	})
+	defer server.Close()
+
+	e, err := NewAzureOpenAIEmbedder(
+		WithAzureOpenAIEndpoint(server.URL),
+		WithAzureOpenAIDeployment("embed-prod"),
+		WithAzureOpenAIAPIVersion("2024-06-01"),
+		WithAzureOpenAITokenCommand("echo from-command"),
+	)
+	if err != nil {
+		t.Fatalf("NewAzureOpenAIEmbedder failed: %v", err)
+	}
+	if _, err := e.Embed(context.Background(), "a"); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+
+	e, err = NewAzureOpenAIEmbedder(
+		WithAzureOpenAIEndpoint(server.URL),
+		WithAzureOpenAIDeployment("embed-prod"),
+		WithAzureOpenAIAPIVersion("2024-06-01"),
+		WithAzureOpenAITokenCommand("exit 3"),
+	)
+	if err != nil {
+		t.Fatalf("NewAzureOpenAIEmbedder failed: %v", err)
+	}
+	if _, err := e.Embed(context.Background(), "a"); err == nil {
+		t.Error("expected an error when the token command fails")
+	}
+}
+
+func TestNewAzureOpenAIEmbedder_Validation(t *testing.T) {
+	t.Setenv("AZURE_OPENAI_API_KEY", "")
+	tests := []struct {
+		name string
+		opts []AzureOpenAIOption
+	}{
+		{"no endpoint", []AzureOpenAIOption{WithAzureOpenAIDeployment("d"), WithAzureOpenAIKey("k")}},
+		{"no deployment", []AzureOpenAIOption{WithAzureOpenAIEndpoint("https://res.openai.azure.com"), WithAzureOpenAIKey("k")}},
+		{"no credentials", []AzureOpenAIOption{WithAzureOpenAIEndpoint("https://res.openai.azure.com"), WithAzureOpenAIDeployment("d")}},
+		{"command and file", []AzureOpenAIOption{
+			WithAzureOpenAIEndpoint("https://res.openai.azure.com"), WithAzureOpenAIDeployment("d"),
+			WithAzureOpenAITokenCommand("echo t"), WithAzureOpenAITokenFile("/tmp/t"),
+		}},
+	}
+	for _, tt := range tests {
+		if _, err := NewAzureOpenAIEmbedder(tt.opts...); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+
+	t.Setenv("AZURE_OPENAI_API_KEY", "env-key")
+	e, err := NewAzureOpenAIEmbedder(WithAzureOpenAIEndpoint("https://res.openai.azure.com"), WithAzureOpenAIDeployment("d"))
+	if err != nil {
+		t.Fatalf("expected the key from the environment, got %v", err)
+	}
+	if e.auth != azureKey("env-key") {
+		t.Errorf("unexpected credentials %v", e.auth)
+	}
+	if want := "https://res.openai.azure.com/openai/deployments/d/embeddings?api-version=" + defaultAzureOpenAIAPIVersion; e.embeddingsURL != want {
+		t.Errorf("expected URL %s, got %s", want, e.embeddingsURL)
+	}
+}
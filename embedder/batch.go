@@ -1,5 +1,7 @@
 package embedder
 
+import "github.com/yoanbernabeu/grepai/tokenizer"
+
 // MaxBatchSize is the maximum number of inputs per OpenAI embedding API call.
 // OpenAI allows 2048, but we use 2000 as a safety margin.
 const MaxBatchSize = 2000
@@ -8,6 +10,15 @@ const MaxBatchSize = 2000
 // OpenAI has a 300,000 token limit. We use 280,000 for safety margin.
 const MaxBatchTokens = 280000
 
+// CountTokens returns the token count of text using tok, falling back to
+// EstimateTokens when tok is nil.
+func CountTokens(tok tokenizer.Tokenizer, text string) int {
+	if tok == nil {
+		return EstimateTokens(text)
+	}
+	return tok.Count(text)
+}
+
 // EstimateTokens estimates the token count for a text string.
 // Uses a conservative estimate of ~4 characters per token for English text.
 // This is intentionally conservative to avoid hitting API limits.
@@ -113,6 +124,12 @@ func (b *batchBuilder) build() []Batch {
 // MaxBatchSize (input count) and MaxBatchTokens (token limit).
 // Chunks maintain their file/chunk index tracking for result mapping.
 func FormBatches(files []FileChunks) []Batch {
+	return FormBatchesWithTokenizer(files, nil)
+}
+
+// FormBatchesWithTokenizer is like FormBatches but counts tokens with tok
+// instead of the character-based estimate. A nil tok uses EstimateTokens.
+func FormBatchesWithTokenizer(files []FileChunks, tok tokenizer.Tokenizer) []Batch {
 	totalChunks := countTotalChunks(files)
 	if totalChunks == 0 {
 		return nil
@@ -123,7 +140,7 @@ func FormBatches(files []FileChunks) []Batch {
 
 	for _, file := range files {
 		for chunkIdx, chunk := range file.Chunks {
-			tokens := EstimateTokens(chunk)
+			tokens := CountTokens(tok, chunk)
 			if builder.isFull(tokens) {
 				builder.finalizeCurrent()
 			}
@@ -148,6 +165,9 @@ type BatchResult struct {
 	BatchIndex int
 	// Embeddings contains the embedding vectors in the same order as batch entries
 	Embeddings [][]float32
+	// Source is the fallback provider that embedded the batch, empty for the
+	// primary provider (see FallbackEmbedder)
+	Source string
 }
 
 // MapResultsToFiles maps batch results back to per-file embeddings.
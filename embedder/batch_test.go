@@ -475,3 +475,29 @@ func TestFormBatches_SmallChunksIgnoreTokenLimit(t *testing.T) {
 		t.Errorf("first batch should have %d entries, got %d", MaxBatchSize, len(batches[0].Entries))
 	}
 }
+
+// fixedTokenizer counts every text as the same number of tokens.
+type fixedTokenizer int
+
+func (f fixedTokenizer) Name() string          { return "fixed" }
+func (f fixedTokenizer) Count(text string) int { return int(f) }
+
+func TestFormBatchesWithTokenizer_UsesTokenizerCounts(t *testing.T) {
+	files := []FileChunks{{FileIndex: 0, Chunks: []string{"a", "b", "c", "d"}}}
+
+	// With the estimate, four one-byte chunks fit in a single batch.
+	if batches := FormBatches(files); len(batches) != 1 {
+		t.Fatalf("expected 1 batch with estimate, got %d", len(batches))
+	}
+
+	// A tokenizer reporting MaxBatchTokens/2 per chunk allows two chunks per batch.
+	batches := FormBatchesWithTokenizer(files, fixedTokenizer(MaxBatchTokens/2))
+	if len(batches) != 2 {
+		t.Fatalf("expected 2 batches with tokenizer counts, got %d", len(batches))
+	}
+	for i, b := range batches {
+		if b.Size() != 2 {
+			t.Errorf("batch %d: expected 2 entries, got %d", i, b.Size())
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package embedder
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync/atomic"
+)
+
+// cachePruneInterval is the number of vectors added to the cache between two
+// checks of its size limit.
+const cachePruneInterval = 1000
+
+// VectorCache stores embedding vectors by key, typically a
+// *store.DiskEmbeddingCache.
+type VectorCache interface {
+	Get(key string) ([]float32, bool)
+	Put(key string, vector []float32) error
+}
+
+// vectorCachePruner is implemented by caches with a size limit.
+type vectorCachePruner interface {
+	Prune(maxBytes int64) (removed int, freed int64, err error)
+}
+
+// CacheChecker is implemented by embedders that cache vectors, to tell which
+// texts would not be embedded again.
+type CacheChecker interface {
+	Cached(text string) bool
+}
+
+// CacheNamespace identifies the vectors of a model in a shared cache: the
+// same text embedded by another provider, model or dimension count gets
+// another vector. dimensions is 0 for the native dimensions of the model.
+func CacheNamespace(provider, model string, dimensions int) string {
+	return fmt.Sprintf("%s\x00%s\x00%d", provider, model, dimensions)
+}
+
+// CachedEmbedder decorates an Embedder with a content-addressed cache, so
+// that texts already embedded by any project are not embedded again.
+type CachedEmbedder struct {
+	inner     Embedder
+	cache     VectorCache
+	namespace string
+	maxBytes  int64 // Size limit of the cache, <= 0 for none
+
+	added atomic.Int64 // Vectors added, to check the size limit periodically
+}
+
+// cachedBatchEmbedder is a CachedEmbedder over a BatchEmbedder.
+type cachedBatchEmbedder struct {
+	*CachedEmbedder
+	batch BatchEmbedder
+}
+
+// NewCachedEmbedder returns inner decorated with cache. The result implements
+// BatchEmbedder when inner does. maxBytes limits the size of the cache when it
+// can be pruned.
+func NewCachedEmbedder(inner Embedder, cache VectorCache, namespace string, maxBytes int64) Embedder {
+	c := &CachedEmbedder{inner: inner, cache: cache, namespace: namespace, maxBytes: maxBytes}
+	if batch, ok := inner.(BatchEmbedder); ok {
+		return &cachedBatchEmbedder{CachedEmbedder: c, batch: batch}
+	}
+	return c
+}
+
+// Embed returns the cached vector of text, embedding it on a miss.
+func (c *CachedEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	key := c.key(text)
+	if vector, ok := c.cache.Get(key); ok {
+		return vector, nil
+	}
+	vector, err := c.inner.Embed(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	c.put(key, vector)
+	return vector, nil
+}
+
+// EmbedBatch embeds the texts missing from the cache in one batch.
+func (c *CachedEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	keys := make([]string, len(texts))
+	var missing []int
+	for i, text := range texts {
+		keys[i] = c.key(text)
+		if vector, ok := c.cache.Get(keys[i]); ok {
+			vectors[i] = vector
+		} else {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+
+	missingTexts := make([]string, len(missing))
+	for j, i := range missing {
+		missingTexts[j] = texts[i]
+	}
+	embedded, err := c.inner.EmbedBatch(ctx, missingTexts)
+	if err != nil {
+		return nil, err
+	}
+	if len(embedded) != len(missing) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(missing), len(embedded))
+	}
+	for j, i := range missing {
+		vectors[i] = embedded[j]
+		c.put(keys[i], embedded[j])
+	}
+	return vectors, nil
+}
+
+// Cached reports whether the vector of text is in the cache.
+func (c *CachedEmbedder) Cached(text string) bool {
+	_, ok := c.cache.Get(c.key(text))
+	return ok
+}
+
+// Dimensions returns the dimensions of the decorated embedder.
+func (c *CachedEmbedder) Dimensions() int {
+	return c.inner.Dimensions()
+}
+
+// Ping checks the decorated embedder when it supports it.
+func (c *CachedEmbedder) Ping(ctx context.Context) error {
+	return ping(ctx, c.inner)
+}
+
+// Close enforces the size limit of the cache and closes the decorated
+// embedder.
+func (c *CachedEmbedder) Close() error {
+	if c.added.Load() > 0 {
+		c.prune()
+	}
+	return c.inner.Close()
+}
+
+// EmbedBatches embeds the entries missing from the cache with the decorated
+// BatchEmbedder. Progress counts cached entries as completed.
+func (c *cachedBatchEmbedder) EmbedBatches(ctx context.Context, batches []Batch, progress BatchProgress) ([]BatchResult, error) {
+	results := make([]BatchResult, len(batches))
+	keys := make([][]string, len(batches))
+	type origin struct{ batch, entry int }
+	var misses []Batch
+	var origins [][]origin
+	cached, total := 0, 0
+
+	for b, batch := range batches {
+		results[b] = BatchResult{BatchIndex: batch.Index, Embeddings: make([][]float32, len(batch.Entries))}
+		keys[b] = make([]string, len(batch.Entries))
+		miss := Batch{Index: len(misses)}
+		var missOrigins []origin
+		for e, entry := range batch.Entries {
+			total++
+			keys[b][e] = c.key(entry.Content)
+			if vector, ok := c.cache.Get(keys[b][e]); ok {
+				results[b].Embeddings[e] = vector
+				cached++
+				continue
+			}
+			miss.Entries = append(miss.Entries, entry)
+			missOrigins = append(missOrigins, origin{b, e})
+		}
+		if len(miss.Entries) > 0 {
+			misses = append(misses, miss)
+			origins = append(origins, missOrigins)
+		}
+	}
+
+	if len(misses) == 0 {
+		if progress != nil && total > 0 {
+			progress(len(batches)-1, len(batches), total, total, false, 0, 0)
+		}
+		return results, nil
+	}
+
+	var innerProgress BatchProgress
+	if progress != nil {
+		innerProgress = func(batchIndex, totalBatches, completedChunks, totalChunks int, retrying bool, attempt int, statusCode int) {
+			progress(batchIndex, totalBatches, completedChunks+cached, totalChunks+cached, retrying, attempt, statusCode)
+		}
+	}
+	embedded, err := c.batch.EmbedBatches(ctx, misses, innerProgress)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range embedded {
+		if result.BatchIndex < 0 || result.BatchIndex >= len(misses) {
+			return nil, fmt.Errorf("unexpected batch index %d", result.BatchIndex)
+		}
+		batchOrigins := origins[result.BatchIndex]
+		if len(result.Embeddings) != len(batchOrigins) {
+			return nil, fmt.Errorf("expected %d embeddings in batch %d, got %d", len(batchOrigins), result.BatchIndex, len(result.Embeddings))
+		}
+		for i, o := range batchOrigins {
+			results[o.batch].Embeddings[o.entry] = result.Embeddings[i]
+			c.put(keys[o.batch][o.entry], result.Embeddings[i])
+		}
+	}
+	return results, nil
+}
+
+// key returns the cache key of a text.
+func (c *CachedEmbedder) key(text string) string {
+	sum := sha256.Sum256([]byte(c.namespace + "\x00" + text))
+	return hex.EncodeToString(sum[:])
+}
+
+// put caches a vector. The cache is best-effort: failures are logged, not
+// returned.
+func (c *CachedEmbedder) put(key string, vector []float32) {
+	if len(vector) == 0 {
+		return
+	}
+	if err := c.cache.Put(key, vector); err != nil {
+		log.Printf("Warning: failed to cache embedding: %v", err)
+		return
+	}
+	if c.added.Add(1)%cachePruneInterval == 0 {
+		c.prune()
+	}
+}
+
+// ping checks the connection of a decorated embedder that supports it, so
+// that decorators do not hide the check from callers.
+func ping(ctx context.Context, e Embedder) error {
+	if p, ok := e.(interface {
+		Ping(ctx context.Context) error
+	}); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// prune enforces the size limit of the cache.
+func (c *CachedEmbedder) prune() {
+	pruner, ok := c.cache.(vectorCachePruner)
+	if !ok || c.maxBytes <= 0 {
+		return
+	}
+	if _, _, err := pruner.Prune(c.maxBytes); err != nil {
+		log.Printf("Warning: failed to prune embedding cache: %v", err)
+	}
+}
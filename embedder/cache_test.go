@@ -0,0 +1,137 @@
+package embedder
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// memoryCache is an in-memory VectorCache.
+type memoryCache struct {
+	mu      sync.Mutex
+	vectors map[string][]float32
+}
+
+func (m *memoryCache) Get(key string) ([]float32, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.vectors[key]
+	return v, ok
+}
+
+func (m *memoryCache) Put(key string, vector []float32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.vectors[key] = vector
+	return nil
+}
+
+// countingEmbedder embeds a text as its length and records what it embeds.
+type countingEmbedder struct {
+	embedded []string
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	e.embedded = append(e.embedded, text)
+	return []float32{float32(len(text))}, nil
+}
+
+func (e *countingEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i], _ = e.Embed(ctx, text)
+	}
+	return vectors, nil
+}
+
+func (e *countingEmbedder) Dimensions() int { return 1 }
+func (e *countingEmbedder) Close() error    { return nil }
+
+type countingBatchEmbedder struct {
+	countingEmbedder
+}
+
+func (e *countingBatchEmbedder) EmbedBatches(ctx context.Context, batches []Batch, progress BatchProgress) ([]BatchResult, error) {
+	results := make([]BatchResult, len(batches))
+	completed, total := 0, 0
+	for _, batch := range batches {
+		total += batch.Size()
+	}
+	for i, batch := range batches {
+		vectors, _ := e.EmbedBatch(ctx, batch.Contents())
+		results[i] = BatchResult{BatchIndex: batch.Index, Embeddings: vectors}
+		completed += batch.Size()
+		if progress != nil {
+			progress(i, len(batches), completed, total, false, 0, 0)
+		}
+	}
+	return results, nil
+}
+
+func TestCachedEmbedder(t *testing.T) {
+	ctx := context.Background()
+	cache := &memoryCache{vectors: make(map[string][]float32)}
+	inner := &countingEmbedder{}
+	emb := NewCachedEmbedder(inner, cache, CacheNamespace("ollama", "nomic-embed-text", 768), 0)
+	if _, ok := emb.(BatchEmbedder); ok {
+		t.Error("expected no BatchEmbedder over a plain Embedder")
+	}
+
+	if _, err := emb.Embed(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	vectors, err := emb.EmbedBatch(ctx, []string{"a", "bb", "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != 3 || vectors[1][0] != 2 || vectors[2][0] != 1 {
+		t.Errorf("unexpected vectors %v", vectors)
+	}
+	if len(inner.embedded) != 2 || inner.embedded[1] != "bb" {
+		t.Errorf("expected only misses to be embedded, got %v", inner.embedded)
+	}
+
+	// Another model does not share vectors
+	other := &countingEmbedder{}
+	if _, err := NewCachedEmbedder(other, cache, CacheNamespace("ollama", "mxbai-embed-large", 1024), 0).Embed(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if len(other.embedded) != 1 {
+		t.Errorf("expected the text to be embedded by another model, got %v", other.embedded)
+	}
+}
+
+func TestCachedEmbedder_EmbedBatches(t *testing.T) {
+	ctx := context.Background()
+	cache := &memoryCache{vectors: make(map[string][]float32)}
+	inner := &countingBatchEmbedder{}
+	emb, ok := NewCachedEmbedder(inner, cache, CacheNamespace("openai", "text-embedding-3-small", 0), 0).(BatchEmbedder)
+	if !ok {
+		t.Fatal("expected a BatchEmbedder over a BatchEmbedder")
+	}
+	if _, err := emb.Embed(ctx, "cached"); err != nil {
+		t.Fatal(err)
+	}
+
+	batches := FormBatches([]FileChunks{
+		{FileIndex: 0, Chunks: []string{"cached", "x"}},
+		{FileIndex: 1, Chunks: []string{"cached"}},
+	})
+	var completed, total int
+	results, err := emb.EmbedBatches(ctx, batches, func(_, _, c, t int, _ bool, _, _ int) {
+		completed, total = c, t
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := MapResultsToFiles(batches, results, 2)
+	if files[0][0][0] != 6 || files[0][1][0] != 1 || files[1][0][0] != 6 {
+		t.Errorf("unexpected embeddings %v", files)
+	}
+	if len(inner.embedded) != 2 || inner.embedded[1] != "x" {
+		t.Errorf("expected only x to be embedded in batches, got %v", inner.embedded)
+	}
+	if completed != 3 || total != 3 {
+		t.Errorf("expected progress to count cached chunks, got %d/%d", completed, total)
+	}
+}
@@ -3,6 +3,7 @@ package embedder
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 // ContextLengthError indicates that the input text exceeds the model's context limit.
@@ -48,3 +49,28 @@ func AsContextLengthError(err error) *ContextLengthError {
 	}
 	return nil
 }
+
+// BudgetExceededError indicates that embedding more documents would exceed a
+// token budget of the project. Indexing is paused until the budget period
+// ends.
+type BudgetExceededError struct {
+	Period string    // "daily" or "monthly"
+	Budget int64     // Tokens allowed in the period
+	Used   int64     // Tokens already used in the period
+	Resets time.Time // Start of the next period
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("%s embedding token budget of %d tokens reached (%d used): indexing paused until %s; raise embedder.budget.%s_tokens to continue",
+		e.Period, e.Budget, e.Used, e.Resets.Format("2006-01-02 15:04"), e.Period)
+}
+
+// AsBudgetExceededError extracts a BudgetExceededError from an error chain.
+// Returns nil if the error is not a BudgetExceededError.
+func AsBudgetExceededError(err error) *BudgetExceededError {
+	var budgetErr *BudgetExceededError
+	if errors.As(err, &budgetErr) {
+		return budgetErr
+	}
+	return nil
+}
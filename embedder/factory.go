@@ -4,17 +4,140 @@ import (
 	"fmt"
 
 	"github.com/yoanbernabeu/grepai/config"
+	"github.com/yoanbernabeu/grepai/store"
+	"github.com/yoanbernabeu/grepai/tokenizer"
 )
 
+// FactoryOption configures NewFromConfig.
+type FactoryOption func(*factoryOptions)
+
+type factoryOptions struct {
+	usagePath string
+}
+
+// WithUsageFile records the usage of each provider in the file at path,
+// typically .grepai/usage.json, and enforces the token budgets of the
+// configuration.
+func WithUsageFile(path string) FactoryOption {
+	return func(o *factoryOptions) {
+		o.usagePath = path
+	}
+}
+
 // NewFromConfig creates an Embedder based on the provided configuration.
 // This factory function centralizes provider initialization and eliminates
 // code duplication across CLI commands and MCP server.
-func NewFromConfig(cfg *config.Config) (Embedder, error) {
+func NewFromConfig(cfg *config.Config, opts ...FactoryOption) (Embedder, error) {
+	var options factoryOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	var tracker *UsageTracker
+	if options.usagePath != "" {
+		tracker = NewUsageTracker(options.usagePath, cfg.Embedder.Budget.DailyTokens, cfg.Embedder.Budget.MonthlyTokens)
+	}
+
+	if len(cfg.Embedder.Fallbacks) == 0 {
+		return newEmbedder(cfg, cfg.Embedder, tracker)
+	}
+
+	primary := cfg.Embedder
+	members := make([]FallbackMember, 0, 1+len(primary.Fallbacks))
+	closeAll := func() {
+		for _, m := range members {
+			m.Embedder.Close()
+		}
+	}
+	for i, ec := range append([]config.EmbedderConfig{primary}, primary.Fallbacks...) {
+		name := providerName(ec)
+		if i > 0 {
+			if ec.Dimensions != nil && primary.Dimensions != nil && *ec.Dimensions != *primary.Dimensions {
+				closeAll()
+				return nil, fmt.Errorf("fallback embedder %s has %d dimensions, the primary has %d", name, *ec.Dimensions, *primary.Dimensions)
+			}
+			// Fallbacks share the cache settings and prefix overrides
+			ec.Cache = primary.Cache
+			if ec.Prefixes == nil {
+				ec.Prefixes = primary.Prefixes
+			}
+		}
+		emb, err := newEmbedder(cfg, ec, tracker)
+		if err != nil {
+			closeAll()
+			if i > 0 {
+				return nil, fmt.Errorf("fallback embedder %s: %w", name, err)
+			}
+			return nil, err
+		}
+		members = append(members, FallbackMember{Name: name, Embedder: emb})
+	}
+	emb, err := NewFallbackEmbedder(members)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	return emb, nil
+}
+
+// providerName identifies a provider and model in fallback chains and usage,
+// such as "ollama:nomic-embed-text".
+func providerName(ec config.EmbedderConfig) string {
+	return fmt.Sprintf("%s:%s", ec.Provider, ec.Model)
+}
+
+// newEmbedder creates the Embedder of a provider, decorated with the usage
+// tracker when not nil, the shared cache and the prefixes of its model.
+func newEmbedder(cfg *config.Config, ec config.EmbedderConfig, tracker *UsageTracker) (Embedder, error) {
+	providerCfg := *cfg
+	providerCfg.Embedder = ec
+	emb, err := newProvider(&providerCfg)
+	if err != nil {
+		return nil, err
+	}
+	// Only requests sent to the provider are metered, not cache hits
+	if tracker != nil {
+		var tok tokenizer.Tokenizer
+		if cfg.Chunking.Tokenizer != "" {
+			tok = tokenizer.Load(cfg.Chunking.Tokenizer, cfg.Chunking.TokenizerVocab)
+		}
+		emb = NewMeteredEmbedder(emb, tracker, providerName(ec), tok)
+	}
+	// The cache is keyed by the prefixed texts sent to the provider
+	if ec.Cache.Enabled {
+		if emb, err = withSharedCache(emb, ec); err != nil {
+			return nil, err
+		}
+	}
+	if prefixes := ec.EmbeddingPrefixes(); prefixes != (config.EmbeddingPrefixes{}) {
+		emb = NewPrefixedEmbedder(emb, prefixes.Query, prefixes.Document)
+	}
+	return emb, nil
+}
+
+// withSharedCache decorates an embedder with the embedding cache shared by
+// all projects.
+func withSharedCache(emb Embedder, cfg config.EmbedderConfig) (Embedder, error) {
+	dir, err := config.GetEmbeddingCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate embedding cache: %w", err)
+	}
+	dimensions := 0
+	if cfg.Dimensions != nil {
+		dimensions = *cfg.Dimensions
+	}
+	namespace := CacheNamespace(cfg.Provider, cfg.Model, dimensions)
+	maxBytes := int64(cfg.Cache.MaxSizeMB) << 20
+	return NewCachedEmbedder(emb, store.NewDiskEmbeddingCache(dir), namespace, maxBytes), nil
+}
+
+// newProvider creates the Embedder of the configured provider.
+func newProvider(cfg *config.Config) (Embedder, error) {
 	switch cfg.Embedder.Provider {
 	case "ollama":
 		opts := []OllamaOption{
 			WithOllamaEndpoint(cfg.Embedder.Endpoint),
 			WithOllamaModel(cfg.Embedder.Model),
+			WithOllamaParallelism(cfg.Embedder.Parallelism),
 		}
 		if cfg.Embedder.Dimensions != nil {
 			opts = append(opts, WithOllamaDimensions(*cfg.Embedder.Dimensions))
@@ -27,16 +150,47 @@ func NewFromConfig(cfg *config.Config) (Embedder, error) {
 			WithOpenAIKey(cfg.Embedder.APIKey),
 			WithOpenAIEndpoint(cfg.Embedder.Endpoint),
 			WithOpenAIParallelism(cfg.Embedder.Parallelism),
+			WithOpenAITPMLimit(cfg.Embedder.TPMLimit),
 		}
 		if cfg.Embedder.Dimensions != nil {
 			opts = append(opts, WithOpenAIDimensions(*cfg.Embedder.Dimensions))
 		}
+		if cfg.Chunking.Tokenizer != "" {
+			opts = append(opts, WithOpenAITokenizer(tokenizer.Load(cfg.Chunking.Tokenizer, cfg.Chunking.TokenizerVocab)))
+		}
 		return NewOpenAIEmbedder(opts...)
 
+	case "azure-openai":
+		openAIOpts := []OpenAIOption{
+			WithOpenAIModel(cfg.Embedder.Model),
+			WithOpenAIParallelism(cfg.Embedder.Parallelism),
+			WithOpenAITPMLimit(cfg.Embedder.TPMLimit),
+		}
+		if cfg.Embedder.Dimensions != nil {
+			openAIOpts = append(openAIOpts, WithOpenAIDimensions(*cfg.Embedder.Dimensions))
+		}
+		if cfg.Chunking.Tokenizer != "" {
+			openAIOpts = append(openAIOpts, WithOpenAITokenizer(tokenizer.Load(cfg.Chunking.Tokenizer, cfg.Chunking.TokenizerVocab)))
+		}
+		deployment := cfg.Embedder.Azure.Deployment
+		if deployment == "" {
+			deployment = cfg.Embedder.Model
+		}
+		return NewAzureOpenAIEmbedder(
+			WithAzureOpenAIEndpoint(cfg.Embedder.Endpoint),
+			WithAzureOpenAIDeployment(deployment),
+			WithAzureOpenAIAPIVersion(cfg.Embedder.Azure.APIVersion),
+			WithAzureOpenAIKey(cfg.Embedder.APIKey),
+			WithAzureOpenAITokenCommand(cfg.Embedder.Azure.TokenCommand),
+			WithAzureOpenAITokenFile(cfg.Embedder.Azure.TokenFile),
+			WithAzureOpenAIOptions(openAIOpts...),
+		)
+
 	case "lmstudio":
 		opts := []LMStudioOption{
 			WithLMStudioEndpoint(cfg.Embedder.Endpoint),
 			WithLMStudioModel(cfg.Embedder.Model),
+			WithLMStudioParallelism(cfg.Embedder.Parallelism),
 		}
 		if cfg.Embedder.Dimensions != nil {
 			opts = append(opts, WithLMStudioDimensions(*cfg.Embedder.Dimensions))
@@ -65,6 +219,41 @@ func NewFromConfig(cfg *config.Config) (Embedder, error) {
 		}
 		return NewOpenRouterEmbedder(opts...)
 
+	case "tei":
+		opts := []TEIOption{
+			WithTEIEndpoint(cfg.Embedder.Endpoint),
+			WithTEIKey(cfg.Embedder.APIKey),
+			WithTEITruncate(cfg.Embedder.Truncate),
+			WithTEIParallelism(cfg.Embedder.Parallelism),
+		}
+		if cfg.Embedder.Dimensions != nil {
+			opts = append(opts, WithTEIDimensions(*cfg.Embedder.Dimensions))
+		}
+		return NewTEIEmbedder(opts...), nil
+
+	case "llamacpp":
+		opts := []LlamaCppOption{
+			WithLlamaCppEndpoint(cfg.Embedder.Endpoint),
+			WithLlamaCppModel(cfg.Embedder.Model),
+			WithLlamaCppKey(cfg.Embedder.APIKey),
+			WithLlamaCppTruncate(cfg.Embedder.Truncate),
+			WithLlamaCppParallelism(cfg.Embedder.Parallelism),
+		}
+		if cfg.Embedder.Dimensions != nil {
+			opts = append(opts, WithLlamaCppDimensions(*cfg.Embedder.Dimensions))
+		}
+		return NewLlamaCppEmbedder(opts...), nil
+
+	case "local":
+		if cfg.Embedder.Model != "" && cfg.Embedder.Model != LocalModel {
+			return nil, fmt.Errorf("unknown local embedding model: %s (use %s)", cfg.Embedder.Model, LocalModel)
+		}
+		var opts []LocalOption
+		if cfg.Embedder.Dimensions != nil {
+			opts = append(opts, WithLocalDimensions(*cfg.Embedder.Dimensions))
+		}
+		return NewLocalEmbedder(opts...), nil
+
 	default:
 		return nil, fmt.Errorf("unknown embedding provider: %s", cfg.Embedder.Provider)
 	}
@@ -72,10 +261,10 @@ func NewFromConfig(cfg *config.Config) (Embedder, error) {
 
 // NewFromWorkspaceConfig creates an Embedder from workspace configuration.
 // This is a convenience wrapper for workspace-specific embedder creation.
-func NewFromWorkspaceConfig(ws *config.Workspace) (Embedder, error) {
+func NewFromWorkspaceConfig(ws *config.Workspace, opts ...FactoryOption) (Embedder, error) {
 	// Convert workspace embedder config to regular config
 	cfg := &config.Config{
 		Embedder: ws.Embedder,
 	}
-	return NewFromConfig(cfg)
+	return NewFromConfig(cfg, opts...)
 }
@@ -1,6 +1,9 @@
 package embedder
 
 import (
+	"context"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/yoanbernabeu/grepai/config"
@@ -21,9 +24,18 @@ func TestNewFromConfig_Ollama(t *testing.T) {
 	}
 	defer emb.Close()
 
-	ollamaEmb, ok := emb.(*OllamaEmbedder)
+	// nomic-embed-text expects instruction prefixes
+	prefixed, ok := emb.(*prefixedBatchEmbedder)
 	if !ok {
-		t.Errorf("expected *OllamaEmbedder, got %T", emb)
+		t.Fatalf("expected a prefixed embedder, got %T", emb)
+	}
+	if prefixed.query != "search_query: " || prefixed.document != "search_document: " {
+		t.Errorf("unexpected prefixes %q and %q", prefixed.query, prefixed.document)
+	}
+
+	ollamaEmb, ok := prefixed.inner.(*OllamaEmbedder)
+	if !ok {
+		t.Fatalf("expected *OllamaEmbedder, got %T", prefixed.inner)
 	}
 
 	if ollamaEmb.model != "nomic-embed-text" {
@@ -31,6 +43,35 @@ func TestNewFromConfig_Ollama(t *testing.T) {
 	}
 }
 
+func TestNewFromConfig_Cache(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+	cfg := &config.Config{
+		Embedder: config.EmbedderConfig{
+			Provider: "ollama",
+			Model:    "all-minilm",
+			Cache:    config.EmbeddingCacheConfig{Enabled: true, MaxSizeMB: 16},
+		},
+	}
+
+	emb, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("failed to create embedder: %v", err)
+	}
+	defer emb.Close()
+
+	cached, ok := emb.(*cachedBatchEmbedder)
+	if !ok {
+		t.Fatalf("expected a cached BatchEmbedder, got %T", emb)
+	}
+	if _, ok := cached.inner.(*OllamaEmbedder); !ok {
+		t.Errorf("expected a cached *OllamaEmbedder, got %T", cached.inner)
+	}
+	if cached.maxBytes != 16<<20 {
+		t.Errorf("expected a 16 MB limit, got %d", cached.maxBytes)
+	}
+}
+
 func TestNewFromConfig_OpenAI(t *testing.T) {
 	t.Setenv("OPENAI_API_KEY", "test-key")
 
@@ -55,6 +96,42 @@ func TestNewFromConfig_OpenAI(t *testing.T) {
 	}
 }
 
+func TestNewFromConfig_AzureOpenAI(t *testing.T) {
+	cfg := &config.Config{
+		Embedder: config.EmbedderConfig{
+			Provider:    "azure-openai",
+			Model:       "text-embedding-3-small",
+			Endpoint:    "https://res.openai.azure.com",
+			APIKey:      "test-key",
+			Parallelism: 4,
+		},
+	}
+
+	emb, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("failed to create embedder: %v", err)
+	}
+	defer emb.Close()
+
+	azure, ok := emb.(*OpenAIEmbedder)
+	if !ok {
+		t.Fatalf("expected *OpenAIEmbedder, got %T", emb)
+	}
+	// The deployment defaults to the model
+	if !strings.Contains(azure.embeddingsURL, "/openai/deployments/text-embedding-3-small/embeddings?") {
+		t.Errorf("unexpected embeddings URL %s", azure.embeddingsURL)
+	}
+
+	cfg.Embedder.Azure.Deployment = "embed-prod"
+	emb, err = NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("failed to create embedder: %v", err)
+	}
+	if azure := emb.(*OpenAIEmbedder); !strings.Contains(azure.embeddingsURL, "/deployments/embed-prod/") {
+		t.Errorf("expected the configured deployment, got %s", azure.embeddingsURL)
+	}
+}
+
 func TestNewFromConfig_LMStudio(t *testing.T) {
 	cfg := &config.Config{
 		Embedder: config.EmbedderConfig{
@@ -70,9 +147,12 @@ func TestNewFromConfig_LMStudio(t *testing.T) {
 	}
 	defer emb.Close()
 
-	_, ok := emb.(*LMStudioEmbedder)
+	prefixed, ok := emb.(*prefixedBatchEmbedder)
 	if !ok {
-		t.Errorf("expected *LMStudioEmbedder, got %T", emb)
+		t.Fatalf("expected a prefixed embedder, got %T", emb)
+	}
+	if _, ok := prefixed.inner.(*LMStudioEmbedder); !ok {
+		t.Errorf("expected *LMStudioEmbedder, got %T", prefixed.inner)
 	}
 }
 
@@ -122,6 +202,135 @@ func TestNewFromConfig_OpenRouter(t *testing.T) {
 	}
 }
 
+func TestNewFromConfig_SelfHosted(t *testing.T) {
+	dimensions := 384
+	tests := []struct {
+		provider string
+		check    func(Embedder) bool
+	}{
+		{"tei", func(e Embedder) bool { tei, ok := e.(*TEIEmbedder); return ok && tei.truncate }},
+		{"llamacpp", func(e Embedder) bool { l, ok := e.(*LlamaCppEmbedder); return ok && l.truncate }},
+	}
+	for _, tt := range tests {
+		cfg := &config.Config{
+			Embedder: config.EmbedderConfig{
+				Provider:   tt.provider,
+				Model:      "all-minilm",
+				Endpoint:   "http://localhost:8080",
+				Dimensions: &dimensions,
+				Truncate:   true,
+			},
+		}
+		emb, err := NewFromConfig(cfg)
+		if err != nil {
+			t.Fatalf("%s: failed to create embedder: %v", tt.provider, err)
+		}
+		if !tt.check(emb) {
+			t.Errorf("%s: unexpected embedder %T", tt.provider, emb)
+		}
+		if _, ok := emb.(BatchEmbedder); !ok {
+			t.Errorf("%s: expected a BatchEmbedder", tt.provider)
+		}
+		if emb.Dimensions() != dimensions {
+			t.Errorf("%s: expected %d dimensions, got %d", tt.provider, dimensions, emb.Dimensions())
+		}
+		emb.Close()
+	}
+}
+
+func TestNewFromConfig_Local(t *testing.T) {
+	dimensions := 128
+	cfg := &config.Config{
+		Embedder: config.EmbedderConfig{
+			Provider:   "local",
+			Model:      LocalModel,
+			Dimensions: &dimensions,
+		},
+	}
+	emb, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("failed to create embedder: %v", err)
+	}
+	if _, ok := emb.(*LocalEmbedder); !ok {
+		t.Fatalf("expected *LocalEmbedder, got %T", emb)
+	}
+	if emb.Dimensions() != dimensions {
+		t.Errorf("expected %d dimensions, got %d", dimensions, emb.Dimensions())
+	}
+
+	cfg.Embedder.Model = "nomic-embed-text"
+	if _, err := NewFromConfig(cfg); err == nil {
+		t.Error("expected error for an unknown local model")
+	}
+}
+
+func TestNewFromConfig_UsageFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	cfg := &config.Config{
+		Embedder: config.EmbedderConfig{
+			Provider: "local",
+			Model:    LocalModel,
+			Budget:   config.EmbeddingBudgetConfig{DailyTokens: 1},
+		},
+	}
+	emb, err := NewFromConfig(cfg, WithUsageFile(path))
+	if err != nil {
+		t.Fatalf("failed to create embedder: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := EmbedQuery(ctx, emb, "parse config"); err != nil {
+		t.Fatalf("EmbedQuery failed: %v", err)
+	}
+	if _, err := emb.Embed(ctx, "func ParseConfig() {}"); AsBudgetExceededError(err) == nil {
+		t.Errorf("expected the daily budget to refuse documents, got %v", err)
+	}
+
+	usage, err := LoadUsage(path)
+	if err != nil {
+		t.Fatalf("LoadUsage failed: %v", err)
+	}
+	if got := usage.Sum("")["local:"+LocalModel].Query; got.Requests != 1 || got.Tokens == 0 {
+		t.Errorf("expected the query recorded, got %+v", got)
+	}
+}
+
+func TestNewFromConfig_Fallbacks(t *testing.T) {
+	dimensions := 384
+	cfg := &config.Config{
+		Embedder: config.EmbedderConfig{
+			Provider:   "ollama",
+			Model:      "all-minilm",
+			Endpoint:   "http://127.0.0.1:1",
+			Dimensions: &dimensions,
+			Fallbacks: []config.EmbedderConfig{
+				{Provider: "local", Model: LocalModel, Dimensions: &dimensions},
+			},
+		},
+	}
+	emb, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("failed to create embedder: %v", err)
+	}
+	if _, ok := emb.(BatchEmbedder); !ok {
+		t.Errorf("expected a BatchEmbedder over Ollama, got %T", emb)
+	}
+	sourced, ok := emb.(SourceEmbedder)
+	if !ok {
+		t.Fatalf("expected a SourceEmbedder, got %T", emb)
+	}
+	// Ollama is not running: the local fallback embeds
+	_, source, err := sourced.EmbedBatchWithSource(context.Background(), []string{"func main() {}"})
+	if err != nil || source != "local:"+LocalModel {
+		t.Errorf("expected the local fallback to embed, got %q (%v)", source, err)
+	}
+
+	other := 768
+	cfg.Embedder.Fallbacks[0].Dimensions = &other
+	if _, err := NewFromConfig(cfg); err == nil {
+		t.Error("expected error for a fallback with other dimensions")
+	}
+}
+
 func TestNewFromConfig_UnknownProvider(t *testing.T) {
 	cfg := &config.Config{
 		Embedder: config.EmbedderConfig{
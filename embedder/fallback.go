@@ -0,0 +1,346 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultFailureThreshold is the number of consecutive failures of a
+	// provider that trips its circuit breaker.
+	defaultFailureThreshold = 3
+	// defaultProbeInterval is the time between two Pings of a provider whose
+	// circuit breaker tripped.
+	defaultProbeInterval = 30 * time.Second
+)
+
+// FallbackMember is a provider of a FallbackEmbedder.
+type FallbackMember struct {
+	// Name identifies the provider in the vectors it embeds, such as
+	// "ollama:nomic-embed-text".
+	Name     string
+	Embedder Embedder
+}
+
+// SourceEmbedder is implemented by embedders whose vectors may come from a
+// fallback provider. The source of a vector is empty for the primary
+// provider, else the name of the fallback that embedded it.
+type SourceEmbedder interface {
+	// EmbedBatchWithSource embeds documents like EmbedBatch and returns the
+	// source of the vectors.
+	EmbedBatchWithSource(ctx context.Context, texts []string) ([][]float32, string, error)
+
+	// PrimaryAvailable reports whether the primary provider answers, so that
+	// vectors of fallback providers can be embedded again.
+	PrimaryAvailable(ctx context.Context) bool
+}
+
+// FallbackEmbedder embeds with the first available of an ordered list of
+// providers serving the same model family and dimensions. Each provider has a
+// circuit breaker: after consecutive failures it is skipped, and probed with
+// Ping periodically until it answers again. Inputs over the context of the
+// model, exhausted token budgets and cancellations are returned without
+// failing over.
+type FallbackEmbedder struct {
+	members       []*fallbackMember
+	threshold     int
+	probeInterval time.Duration
+}
+
+// fallbackBatchEmbedder is a FallbackEmbedder over a primary BatchEmbedder.
+type fallbackBatchEmbedder struct {
+	*FallbackEmbedder
+}
+
+// fallbackMember is a provider with its circuit breaker.
+type fallbackMember struct {
+	FallbackMember
+
+	mu        sync.Mutex
+	failures  int       // Consecutive failures
+	nextProbe time.Time // Next Ping once the breaker tripped
+}
+
+type FallbackOption func(*FallbackEmbedder)
+
+// WithFailureThreshold sets the number of consecutive failures that trips the
+// circuit breaker of a provider.
+func WithFailureThreshold(failures int) FallbackOption {
+	return func(e *FallbackEmbedder) {
+		if failures > 0 {
+			e.threshold = failures
+		}
+	}
+}
+
+// WithProbeInterval sets the time between two Pings of a provider whose
+// circuit breaker tripped.
+func WithProbeInterval(interval time.Duration) FallbackOption {
+	return func(e *FallbackEmbedder) {
+		if interval > 0 {
+			e.probeInterval = interval
+		}
+	}
+}
+
+// NewFallbackEmbedder returns an embedder over members, the primary provider
+// first. The result implements BatchEmbedder when the primary does. It fails
+// when members report different dimensions.
+func NewFallbackEmbedder(members []FallbackMember, opts ...FallbackOption) (Embedder, error) {
+	if len(members) == 0 {
+		return nil, errors.New("no embedding provider")
+	}
+	e := &FallbackEmbedder{
+		threshold:     defaultFailureThreshold,
+		probeInterval: defaultProbeInterval,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	primary := members[0].Embedder.Dimensions()
+	for _, m := range members {
+		if dimensions := m.Embedder.Dimensions(); primary > 0 && dimensions > 0 && dimensions != primary {
+			return nil, fmt.Errorf("fallback embedder %s has %d dimensions, the primary has %d", m.Name, dimensions, primary)
+		}
+		e.members = append(e.members, &fallbackMember{FallbackMember: m})
+	}
+
+	if _, ok := members[0].Embedder.(BatchEmbedder); ok {
+		return &fallbackBatchEmbedder{FallbackEmbedder: e}, nil
+	}
+	return e, nil
+}
+
+// Embed embeds a document.
+func (e *FallbackEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	var vector []float32
+	_, err := e.run(ctx, func(emb Embedder) (err error) {
+		vector, err = emb.Embed(ctx, text)
+		return err
+	})
+	return vector, err
+}
+
+// EmbedQuery embeds a search query with the query prefix of the provider
+// that answers.
+func (e *FallbackEmbedder) EmbedQuery(ctx context.Context, query string) ([]float32, error) {
+	var vector []float32
+	_, err := e.run(ctx, func(emb Embedder) (err error) {
+		vector, err = EmbedQuery(ctx, emb, query)
+		return err
+	})
+	return vector, err
+}
+
+// EmbedBatch embeds documents.
+func (e *FallbackEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors, _, err := e.EmbedBatchWithSource(ctx, texts)
+	return vectors, err
+}
+
+// EmbedBatchWithSource embeds documents and returns the source of the
+// vectors: empty for the primary provider, else the name of the fallback.
+func (e *FallbackEmbedder) EmbedBatchWithSource(ctx context.Context, texts []string) ([][]float32, string, error) {
+	var vectors [][]float32
+	source, err := e.run(ctx, func(emb Embedder) (err error) {
+		vectors, err = emb.EmbedBatch(ctx, texts)
+		return err
+	})
+	return vectors, source, err
+}
+
+// EmbedBatches embeds batches of documents, all with the same provider: on a
+// failure, the batches are embedded again by the next provider. The Source of
+// the results is empty for the primary provider.
+func (e *fallbackBatchEmbedder) EmbedBatches(ctx context.Context, batches []Batch, progress BatchProgress) ([]BatchResult, error) {
+	var results []BatchResult
+	source, err := e.run(ctx, func(emb Embedder) (err error) {
+		if batch, ok := emb.(BatchEmbedder); ok {
+			results, err = batch.EmbedBatches(ctx, batches, progress)
+			return err
+		}
+		results, err = embedBatchesSequentially(ctx, emb, batches, progress)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Source = source
+	}
+	return results, nil
+}
+
+// embedBatchesSequentially embeds batches with EmbedBatch, for fallback
+// providers without batch support.
+func embedBatchesSequentially(ctx context.Context, emb Embedder, batches []Batch, progress BatchProgress) ([]BatchResult, error) {
+	totalChunks := 0
+	for _, batch := range batches {
+		totalChunks += batch.Size()
+	}
+	results := make([]BatchResult, len(batches))
+	completed := 0
+	for i, batch := range batches {
+		embeddings, err := emb.EmbedBatch(ctx, batch.Contents())
+		if err != nil {
+			return nil, fmt.Errorf("batch %d failed: %w", batch.Index, err)
+		}
+		results[i] = BatchResult{BatchIndex: i, Embeddings: embeddings}
+		completed += batch.Size()
+		if progress != nil {
+			progress(batch.Index, len(batches), completed, totalChunks, false, 0, 0)
+		}
+	}
+	return results, nil
+}
+
+// run calls embed with the first available provider, failing over to the
+// next ones, and returns the source of the provider that succeeded. When the
+// breakers of all providers are open, all are tried anyway.
+func (e *FallbackEmbedder) run(ctx context.Context, embed func(Embedder) error) (string, error) {
+	var lastErr error
+	try := func(i int) (done bool, err error) {
+		m := e.members[i]
+		err = embed(m.Embedder)
+		if err == nil {
+			m.succeeded()
+			return true, nil
+		}
+		if ctx.Err() != nil || AsContextLengthError(err) != nil || AsBudgetExceededError(err) != nil {
+			return true, err
+		}
+		lastErr = err
+		m.failed(e.threshold, e.probeInterval)
+		if i < len(e.members)-1 {
+			log.Printf("Warning: embedder %s failed, falling back: %v", m.Name, err)
+		}
+		return false, nil
+	}
+
+	var skipped []int
+	for i, m := range e.members {
+		if !m.available(ctx, e.probeInterval, e.threshold) {
+			skipped = append(skipped, i)
+			continue
+		}
+		if done, err := try(i); done {
+			return e.source(i), err
+		}
+	}
+	if len(skipped) == len(e.members) {
+		for _, i := range skipped {
+			if done, err := try(i); done {
+				return e.source(i), err
+			}
+		}
+	}
+	return "", fmt.Errorf("all embedding providers failed: %w", lastErr)
+}
+
+// source returns the source recorded for the vectors of the i-th provider.
+func (e *FallbackEmbedder) source(i int) string {
+	if i == 0 {
+		return ""
+	}
+	return e.members[i].Name
+}
+
+// PrimaryAvailable reports whether the primary provider answers a Ping. It
+// is not pinged while its circuit breaker is open and no probe is due.
+func (e *FallbackEmbedder) PrimaryAvailable(ctx context.Context) bool {
+	primary := e.members[0]
+	if !primary.available(ctx, e.probeInterval, e.threshold) {
+		return false
+	}
+	return ping(ctx, primary.Embedder) == nil
+}
+
+// Cached reports whether the vector of a document is in the cache of the
+// primary provider.
+func (e *FallbackEmbedder) Cached(text string) bool {
+	c, ok := e.members[0].Embedder.(CacheChecker)
+	return ok && c.Cached(text)
+}
+
+// Dimensions returns the dimensions of the primary provider, or of the first
+// fallback that knows them.
+func (e *FallbackEmbedder) Dimensions() int {
+	for _, m := range e.members {
+		if dimensions := m.Embedder.Dimensions(); dimensions > 0 {
+			return dimensions
+		}
+	}
+	return 0
+}
+
+// Ping succeeds when any provider answers.
+func (e *FallbackEmbedder) Ping(ctx context.Context) error {
+	var errs []error
+	for _, m := range e.members {
+		err := ping(ctx, m.Embedder)
+		if err == nil {
+			m.succeeded()
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", m.Name, err))
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes all providers.
+func (e *FallbackEmbedder) Close() error {
+	var errs []error
+	for _, m := range e.members {
+		if err := m.Embedder.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// available reports whether the provider may be called: its breaker is
+// closed, or the provider answered the probe due since it tripped. Only one
+// caller probes at a time; the others skip the provider meanwhile.
+func (m *fallbackMember) available(ctx context.Context, probeInterval time.Duration, threshold int) bool {
+	m.mu.Lock()
+	if m.failures < threshold {
+		m.mu.Unlock()
+		return true
+	}
+	if time.Now().Before(m.nextProbe) {
+		m.mu.Unlock()
+		return false
+	}
+	m.nextProbe = time.Now().Add(probeInterval)
+	m.mu.Unlock()
+
+	if err := ping(ctx, m.Embedder); err != nil {
+		return false
+	}
+	log.Printf("Embedder %s is available again", m.Name)
+	m.succeeded()
+	return true
+}
+
+func (m *fallbackMember) succeeded() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failures = 0
+}
+
+func (m *fallbackMember) failed(threshold int, probeInterval time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failures++
+	if m.failures == threshold {
+		log.Printf("Warning: embedder %s failed %d times in a row, skipping it for %s", m.Name, threshold, probeInterval)
+	}
+	if m.failures >= threshold {
+		m.nextProbe = time.Now().Add(probeInterval)
+	}
+}
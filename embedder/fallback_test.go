@@ -0,0 +1,221 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyEmbedder fails while down is set; its vectors hold value.
+type flakyEmbedder struct {
+	value      float32
+	dimensions int
+	down       atomic.Bool
+	calls      atomic.Int32
+	pings      atomic.Int32
+	err        error // Returned instead of errDown when set
+}
+
+var errDown = errors.New("connection refused")
+
+func (e *flakyEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := e.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+func (e *flakyEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	e.calls.Add(1)
+	if e.err != nil {
+		return nil, e.err
+	}
+	if e.down.Load() {
+		return nil, errDown
+	}
+	vectors := make([][]float32, len(texts))
+	for i := range texts {
+		vectors[i] = []float32{e.value}
+	}
+	return vectors, nil
+}
+
+func (e *flakyEmbedder) Ping(ctx context.Context) error {
+	e.pings.Add(1)
+	if e.down.Load() {
+		return errDown
+	}
+	return nil
+}
+
+func (e *flakyEmbedder) Dimensions() int { return e.dimensions }
+func (e *flakyEmbedder) Close() error    { return nil }
+
+type flakyBatchEmbedder struct {
+	flakyEmbedder
+}
+
+func (e *flakyBatchEmbedder) EmbedBatches(ctx context.Context, batches []Batch, progress BatchProgress) ([]BatchResult, error) {
+	results := make([]BatchResult, len(batches))
+	for i, batch := range batches {
+		vectors, err := e.EmbedBatch(ctx, batch.Contents())
+		if err != nil {
+			return nil, err
+		}
+		results[i] = BatchResult{BatchIndex: i, Embeddings: vectors}
+	}
+	return results, nil
+}
+
+func TestFallbackEmbedder_FailsOverAndRecovers(t *testing.T) {
+	ctx := context.Background()
+	primary := &flakyEmbedder{value: 1}
+	fallback := &flakyEmbedder{value: 2}
+	emb, err := NewFallbackEmbedder([]FallbackMember{
+		{Name: "ollama:nomic-embed-text", Embedder: primary},
+		{Name: "tei:nomic-embed-text", Embedder: fallback},
+	}, WithFailureThreshold(2), WithProbeInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("NewFallbackEmbedder failed: %v", err)
+	}
+	fe := emb.(*FallbackEmbedder)
+
+	vectors, source, err := fe.EmbedBatchWithSource(ctx, []string{"a"})
+	if err != nil || source != "" || vectors[0][0] != 1 {
+		t.Fatalf("expected the primary to embed, got %v from %q (%v)", vectors, source, err)
+	}
+
+	// Each call fails over until the breaker trips, then skips the primary
+	primary.down.Store(true)
+	for i := 0; i < 3; i++ {
+		vectors, source, err = fe.EmbedBatchWithSource(ctx, []string{"a"})
+		if err != nil || source != "tei:nomic-embed-text" || vectors[0][0] != 2 {
+			t.Fatalf("call %d: expected the fallback to embed, got %v from %q (%v)", i, vectors, source, err)
+		}
+	}
+	if calls := primary.calls.Load(); calls != 3 {
+		t.Errorf("expected the primary to be skipped once tripped, got %d calls", calls)
+	}
+	if fe.PrimaryAvailable(ctx) {
+		t.Error("expected the primary to be unavailable while its breaker is open")
+	}
+
+	// The primary is probed again once the probe is due
+	primary.down.Store(false)
+	fe.members[0].nextProbe = time.Now().Add(-time.Second)
+	vector, err := fe.EmbedQuery(ctx, "query")
+	if err != nil || vector[0] != 1 {
+		t.Fatalf("expected the primary to embed after recovery, got %v (%v)", vector, err)
+	}
+	if primary.pings.Load() == 0 {
+		t.Error("expected the primary to be probed with Ping")
+	}
+	if !fe.PrimaryAvailable(ctx) {
+		t.Error("expected the primary to be available after recovery")
+	}
+}
+
+func TestFallbackEmbedder_NoFailover(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"context length", NewContextLengthError(0, 9000, 8192, "too long")},
+		{"budget", &BudgetExceededError{Period: "daily", Budget: 1000, Used: 1000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &flakyEmbedder{value: 1, err: tt.err}
+			fallback := &flakyEmbedder{value: 2}
+			emb, err := NewFallbackEmbedder([]FallbackMember{
+				{Name: "primary", Embedder: primary},
+				{Name: "fallback", Embedder: fallback},
+			})
+			if err != nil {
+				t.Fatalf("NewFallbackEmbedder failed: %v", err)
+			}
+
+			if _, err := emb.EmbedBatch(context.Background(), []string{"long"}); !errors.Is(err, tt.err) {
+				t.Errorf("expected the error of the primary, got %v", err)
+			}
+			if fallback.calls.Load() != 0 {
+				t.Error("expected no failover")
+			}
+		})
+	}
+}
+
+func TestFallbackEmbedder_AllFail(t *testing.T) {
+	primary := &flakyEmbedder{value: 1}
+	fallback := &flakyEmbedder{value: 2}
+	primary.down.Store(true)
+	fallback.down.Store(true)
+	emb, err := NewFallbackEmbedder([]FallbackMember{
+		{Name: "primary", Embedder: primary},
+		{Name: "fallback", Embedder: fallback},
+	}, WithFailureThreshold(1), WithProbeInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("NewFallbackEmbedder failed: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := emb.Embed(context.Background(), "a")
+		if !errors.Is(err, errDown) || !strings.Contains(err.Error(), "all embedding providers failed") {
+			t.Fatalf("call %d: expected all providers to fail, got %v", i, err)
+		}
+	}
+	// With all breakers open, the providers are still tried
+	if primary.calls.Load() != 2 || fallback.calls.Load() != 2 {
+		t.Errorf("expected both providers tried twice, got %d and %d", primary.calls.Load(), fallback.calls.Load())
+	}
+
+	if err := emb.(*FallbackEmbedder).Ping(context.Background()); err == nil {
+		t.Error("expected Ping to fail when no provider answers")
+	}
+}
+
+func TestFallbackEmbedder_EmbedBatches(t *testing.T) {
+	primary := &flakyBatchEmbedder{flakyEmbedder{value: 1}}
+	fallback := &flakyEmbedder{value: 2}
+	emb, err := NewFallbackEmbedder([]FallbackMember{
+		{Name: "primary", Embedder: primary},
+		{Name: "fallback", Embedder: fallback},
+	})
+	if err != nil {
+		t.Fatalf("NewFallbackEmbedder failed: %v", err)
+	}
+	batchEmb, ok := emb.(BatchEmbedder)
+	if !ok {
+		t.Fatal("expected a BatchEmbedder over a batch primary")
+	}
+
+	batches := []Batch{{Index: 0, Entries: []BatchEntry{{Content: "a"}, {Content: "b"}}}}
+	results, err := batchEmb.EmbedBatches(context.Background(), batches, nil)
+	if err != nil || len(results) != 1 || results[0].Source != "" {
+		t.Fatalf("expected results of the primary, got %+v (%v)", results, err)
+	}
+
+	// A fallback without batch support embeds the batches one at a time
+	primary.down.Store(true)
+	results, err = batchEmb.EmbedBatches(context.Background(), batches, nil)
+	if err != nil {
+		t.Fatalf("EmbedBatches failed: %v", err)
+	}
+	if results[0].Source != "fallback" || len(results[0].Embeddings) != 2 || results[0].Embeddings[0][0] != 2 {
+		t.Errorf("expected results of the fallback, got %+v", results)
+	}
+}
+
+func TestNewFallbackEmbedder_Dimensions(t *testing.T) {
+	_, err := NewFallbackEmbedder([]FallbackMember{
+		{Name: "primary", Embedder: &flakyEmbedder{dimensions: 768}},
+		{Name: "fallback", Embedder: &flakyEmbedder{dimensions: 1024}},
+	})
+	if err == nil {
+		t.Error("expected an error for fallbacks with other dimensions")
+	}
+}
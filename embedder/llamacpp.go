@@ -0,0 +1,417 @@
+package embedder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultLlamaCppEndpoint = "http://localhost:8080"
+
+// LlamaCppEmbedder implements the Embedder interface for llama.cpp servers
+// started with `llama-server --embedding`. Dimensions and context size are
+// read from the model metadata of the server.
+type LlamaCppEmbedder struct {
+	endpoint    string
+	model       string
+	apiKey      string
+	dimensions  atomic.Int64 // Configured, or detected from the model metadata
+	truncate    bool
+	parallelism int
+	retryPolicy RetryPolicy
+	client      *http.Client
+
+	modelMu sync.Mutex
+	served  *llamaCppModel // Fetched on first use, nil until the server answers
+	ctxSize atomic.Int64   // Context size of a server slot, 0 until fetched
+}
+
+// llamaCppEmbedRequest is an OpenAI-style request; Input holds strings, or
+// token arrays when inputs are truncated.
+type llamaCppEmbedRequest struct {
+	Model          string `json:"model,omitempty"`
+	Input          any    `json:"input"`
+	EncodingFormat string `json:"encoding_format"`
+}
+
+type llamaCppEmbedResponse struct {
+	Data []struct {
+		Embedding []float32 `json:"embedding"`
+		Index     int       `json:"index"`
+	} `json:"data"`
+}
+
+type llamaCppErrorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error"`
+}
+
+type llamaCppModelsResponse struct {
+	Data []llamaCppModel `json:"data"`
+}
+
+// llamaCppModel is the model metadata reported by /v1/models.
+type llamaCppModel struct {
+	ID   string `json:"id"`
+	Meta struct {
+		NEmbd     int `json:"n_embd"`
+		NCtxTrain int `json:"n_ctx_train"`
+	} `json:"meta"`
+}
+
+type llamaCppPropsResponse struct {
+	DefaultGenerationSettings struct {
+		NCtx int `json:"n_ctx"`
+	} `json:"default_generation_settings"`
+}
+
+type llamaCppTokenizeRequest struct {
+	Content string `json:"content"`
+}
+
+type llamaCppTokenizeResponse struct {
+	Tokens []int `json:"tokens"`
+}
+
+type LlamaCppOption func(*LlamaCppEmbedder)
+
+func WithLlamaCppEndpoint(endpoint string) LlamaCppOption {
+	return func(e *LlamaCppEmbedder) {
+		e.endpoint = endpoint
+	}
+}
+
+// WithLlamaCppModel sets the model name sent with requests. The server
+// embeds with the model it was started with.
+func WithLlamaCppModel(model string) LlamaCppOption {
+	return func(e *LlamaCppEmbedder) {
+		e.model = model
+	}
+}
+
+// WithLlamaCppKey sets the API key of servers started with --api-key.
+func WithLlamaCppKey(key string) LlamaCppOption {
+	return func(e *LlamaCppEmbedder) {
+		e.apiKey = key
+	}
+}
+
+func WithLlamaCppDimensions(dimensions int) LlamaCppOption {
+	return func(e *LlamaCppEmbedder) {
+		e.dimensions.Store(int64(dimensions))
+	}
+}
+
+// WithLlamaCppTruncate truncates inputs longer than the context of the
+// server instead of rejecting them. The server has no truncation option:
+// inputs are tokenized by the server and sent as truncated token arrays.
+func WithLlamaCppTruncate(truncate bool) LlamaCppOption {
+	return func(e *LlamaCppEmbedder) {
+		e.truncate = truncate
+	}
+}
+
+// WithLlamaCppParallelism sets the number of concurrent requests of
+// EmbedBatches.
+func WithLlamaCppParallelism(parallelism int) LlamaCppOption {
+	return func(e *LlamaCppEmbedder) {
+		if parallelism > 0 {
+			e.parallelism = parallelism
+		}
+	}
+}
+
+func WithLlamaCppRetryPolicy(policy RetryPolicy) LlamaCppOption {
+	return func(e *LlamaCppEmbedder) {
+		e.retryPolicy = policy
+	}
+}
+
+func NewLlamaCppEmbedder(opts ...LlamaCppOption) *LlamaCppEmbedder {
+	e := &LlamaCppEmbedder{
+		endpoint:    defaultLlamaCppEndpoint,
+		parallelism: defaultParallelism,
+		retryPolicy: DefaultRetryPolicy(),
+		client: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+func (e *LlamaCppEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := e.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EmbedBatch embeds texts in requests of a few texts.
+func (e *LlamaCppEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	return embedLocalBatch(ctx, texts, LocalRequestSize, e.retryPolicy, e.embedRequest)
+}
+
+// EmbedBatches implements the BatchEmbedder interface: the requests of all
+// batches are sent by parallel workers and retried with exponential backoff.
+func (e *LlamaCppEmbedder) EmbedBatches(ctx context.Context, batches []Batch, progress BatchProgress) ([]BatchResult, error) {
+	return embedBatchesParallel(ctx, batches, progress, LocalRequestSize, e.parallelism, e.retryPolicy, e.embedRequest)
+}
+
+// embedRequest embeds texts in a single /v1/embeddings request.
+func (e *LlamaCppEmbedder) embedRequest(ctx context.Context, texts []string) ([][]float32, error) {
+	reqBody := llamaCppEmbedRequest{Model: e.model, Input: texts, EncodingFormat: "float"}
+	if e.truncate {
+		tokens, err := e.truncatedTokens(ctx, texts)
+		if err != nil {
+			return nil, err
+		}
+		reqBody.Input = tokens
+	}
+
+	body, status, err := e.post(ctx, "/v1/embeddings", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != http.StatusOK {
+		msg := llamaCppErrorMessage(body)
+
+		// "input is too large to process. increase the physical batch size",
+		// or "the request exceeds the available context size"
+		if strings.Contains(msg, "too large to process") ||
+			strings.Contains(msg, "context size") ||
+			strings.Contains(msg, "context length") {
+			maxTokens := 0
+			if model := e.modelInfo(ctx); model != nil {
+				maxTokens = model.Meta.NCtxTrain
+			}
+			totalChars := 0
+			for _, t := range texts {
+				totalChars += len(t)
+			}
+			return nil, NewContextLengthError(0, totalChars/4, maxTokens, msg)
+		}
+
+		return nil, NewRetryableError(status, fmt.Sprintf("llama.cpp returned status %d: %s", status, msg))
+	}
+
+	var result llamaCppEmbedResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(result.Data))
+	}
+
+	embeddings := make([][]float32, len(texts))
+	for _, item := range result.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("unexpected embedding index %d", item.Index)
+		}
+		embeddings[item.Index] = item.Embedding
+	}
+
+	return embeddings, nil
+}
+
+// truncatedTokens tokenizes texts with the server, keeping the tokens that
+// fit its context.
+func (e *LlamaCppEmbedder) truncatedTokens(ctx context.Context, texts []string) ([][]int, error) {
+	limit := e.contextSize(ctx)
+	tokens := make([][]int, len(texts))
+	for i, text := range texts {
+		body, status, err := e.post(ctx, "/tokenize", llamaCppTokenizeRequest{Content: text})
+		if err != nil {
+			return nil, err
+		}
+		if status != http.StatusOK {
+			return nil, NewRetryableError(status, fmt.Sprintf("llama.cpp returned status %d: %s", status, llamaCppErrorMessage(body)))
+		}
+		var result llamaCppTokenizeResponse
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("failed to decode tokenize response: %w", err)
+		}
+		tokens[i] = result.Tokens
+		if limit > 0 && len(tokens[i]) > limit {
+			tokens[i] = tokens[i][:limit]
+		}
+	}
+	return tokens, nil
+}
+
+// contextSize returns the context size of a server slot, or the training
+// context of the model when the server does not report it. It is 0 when
+// neither is known.
+func (e *LlamaCppEmbedder) contextSize(ctx context.Context) int {
+	if size := e.ctxSize.Load(); size > 0 {
+		return int(size)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.endpoint+"/props", nil)
+	if err == nil {
+		e.authorize(req)
+		if resp, err := e.client.Do(req); err == nil {
+			var props llamaCppPropsResponse
+			decodeErr := json.NewDecoder(resp.Body).Decode(&props)
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK && decodeErr == nil && props.DefaultGenerationSettings.NCtx > 0 {
+				e.ctxSize.Store(int64(props.DefaultGenerationSettings.NCtx))
+				return props.DefaultGenerationSettings.NCtx
+			}
+		}
+	}
+	if model := e.modelInfo(ctx); model != nil {
+		return model.Meta.NCtxTrain
+	}
+	return 0
+}
+
+// modelInfo returns the metadata of the served model, fetched once. It
+// returns nil when the server cannot be reached, to be retried on next use.
+func (e *LlamaCppEmbedder) modelInfo(ctx context.Context) *llamaCppModel {
+	e.modelMu.Lock()
+	defer e.modelMu.Unlock()
+	if e.served != nil {
+		return e.served
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.endpoint+"/v1/models", nil)
+	if err != nil {
+		return nil
+	}
+	e.authorize(req)
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+
+	var models llamaCppModelsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&models); err != nil || len(models.Data) == 0 {
+		return nil
+	}
+	e.served = &models.Data[0]
+	return e.served
+}
+
+// ModelID returns the model served by the server, or "" when it cannot be
+// reached.
+func (e *LlamaCppEmbedder) ModelID(ctx context.Context) string {
+	if model := e.modelInfo(ctx); model != nil {
+		return model.ID
+	}
+	return ""
+}
+
+// DetectDimensions returns the dimensions of the served model, from its
+// metadata.
+func (e *LlamaCppEmbedder) DetectDimensions(ctx context.Context) (int, error) {
+	if dimensions := e.dimensions.Load(); dimensions > 0 {
+		return int(dimensions), nil
+	}
+	model := e.modelInfo(ctx)
+	if model == nil || model.Meta.NEmbd <= 0 {
+		return 0, fmt.Errorf("llama.cpp at %s did not report the dimensions of its model", e.endpoint)
+	}
+	e.dimensions.Store(int64(model.Meta.NEmbd))
+	return model.Meta.NEmbd, nil
+}
+
+// Dimensions returns the configured dimensions, or those detected from the
+// model metadata. It is 0 until either is known.
+func (e *LlamaCppEmbedder) Dimensions() int {
+	return int(e.dimensions.Load())
+}
+
+func (e *LlamaCppEmbedder) Close() error {
+	return nil
+}
+
+// Ping checks that the server is up and its model loaded, and detects the
+// dimensions of the model.
+func (e *LlamaCppEmbedder) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.endpoint+"/health", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	e.authorize(req)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach llama.cpp at %s: %w", e.endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("llama.cpp returned status %d: %s", resp.StatusCode, llamaCppErrorMessage(body))
+	}
+
+	_, _ = e.DetectDimensions(ctx)
+	return nil
+}
+
+// post sends a JSON request and returns the response body and status.
+func (e *LlamaCppEmbedder) post(ctx context.Context, path string, reqBody any) ([]byte, int, error) {
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint+path, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	e.authorize(req)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to send request to llama.cpp: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read response: %w", err)
+	}
+	return body, resp.StatusCode, nil
+}
+
+func (e *LlamaCppEmbedder) authorize(req *http.Request) {
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+}
+
+// llamaCppErrorMessage returns the message of an OpenAI-style error response, or the
+// body itself.
+func llamaCppErrorMessage(body []byte) string {
+	var errResp llamaCppErrorResponse
+	if json.Unmarshal(body, &errResp) == nil && errResp.Error.Message != "" {
+		return errResp.Error.Message
+	}
+	return string(body)
+}
@@ -0,0 +1,122 @@
+package embedder
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// llamaCppTestServer serves a 4-dimension model with a context of 8 tokens,
+// one token per character, embedding each input as its token count.
+func llamaCppTestServer(t *testing.T, tokenized *atomic.Int32) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+		case "/v1/models":
+			json.NewEncoder(w).Encode(map[string]any{
+				"data": []map[string]any{{"id": "nomic-embed-text-v1.5.Q8_0.gguf", "meta": map[string]int{"n_embd": 4, "n_ctx_train": 2048}}},
+			})
+		case "/props":
+			json.NewEncoder(w).Encode(map[string]any{"default_generation_settings": map[string]int{"n_ctx": 8}})
+		case "/tokenize":
+			tokenized.Add(1)
+			var req llamaCppTokenizeRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			tokens := make([]int, len(req.Content))
+			json.NewEncoder(w).Encode(llamaCppTokenizeResponse{Tokens: tokens})
+		case "/v1/embeddings":
+			var req struct {
+				Input []json.RawMessage `json:"input"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			var resp llamaCppEmbedResponse
+			for i, raw := range req.Input {
+				var text string
+				var tokens []int
+				n := 0
+				if json.Unmarshal(raw, &text) == nil {
+					n = len(text)
+				} else if json.Unmarshal(raw, &tokens) == nil {
+					n = len(tokens)
+				}
+				if n > 8 {
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write([]byte(`{"error":{"code":500,"message":"input is too large to process. increase the physical batch size","type":"server_error"}}`))
+					return
+				}
+				resp.Data = append(resp.Data, struct {
+					Embedding []float32 `json:"embedding"`
+					Index     int       `json:"index"`
+				}{Embedding: []float32{float32(n), 0, 0, 0}, Index: i})
+			}
+			json.NewEncoder(w).Encode(resp)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestLlamaCppEmbedder(t *testing.T) {
+	var tokenized atomic.Int32
+	server := llamaCppTestServer(t, &tokenized)
+	defer server.Close()
+
+	ctx := context.Background()
+	e := NewLlamaCppEmbedder(WithLlamaCppEndpoint(server.URL), WithLlamaCppRetryPolicy(fastRetryPolicy))
+	if err := e.Ping(ctx); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if e.Dimensions() != 4 {
+		t.Errorf("expected 4 dimensions from the model metadata, got %d", e.Dimensions())
+	}
+	if model := e.ModelID(ctx); model != "nomic-embed-text-v1.5.Q8_0.gguf" {
+		t.Errorf("unexpected model %q", model)
+	}
+
+	texts := []string{"a", "bb", "ccc"}
+	batches := FormBatches([]FileChunks{{FileIndex: 0, Chunks: texts}})
+	results, err := e.EmbedBatches(ctx, batches, nil)
+	if err != nil {
+		t.Fatalf("EmbedBatches failed: %v", err)
+	}
+	for i, vector := range MapResultsToFiles(batches, results, 1)[0] {
+		if vector[0] != float32(i+1) {
+			t.Fatalf("expected chunk %d embedded in order, got %v", i, vector)
+		}
+	}
+
+	_, err = e.EmbedBatch(ctx, []string{"a", strings.Repeat("x", 9)})
+	if ctxErr := AsContextLengthError(err); ctxErr == nil || ctxErr.ChunkIndex != 1 {
+		t.Fatalf("expected a context length error for chunk 1, got %v", err)
+	}
+	if tokenized.Load() != 0 {
+		t.Error("expected no tokenization without truncation")
+	}
+}
+
+func TestLlamaCppEmbedder_Truncate(t *testing.T) {
+	var tokenized atomic.Int32
+	server := llamaCppTestServer(t, &tokenized)
+	defer server.Close()
+
+	e := NewLlamaCppEmbedder(WithLlamaCppEndpoint(server.URL), WithLlamaCppTruncate(true), WithLlamaCppDimensions(4))
+	embeddings, err := e.EmbedBatch(context.Background(), []string{"a", strings.Repeat("x", 20)})
+	if err != nil {
+		t.Fatalf("expected long inputs to be truncated, got %v", err)
+	}
+	if embeddings[0][0] != 1 || embeddings[1][0] != 8 {
+		t.Errorf("expected inputs of 1 and 8 tokens, got %v", embeddings)
+	}
+	if tokenized.Load() != 2 {
+		t.Errorf("expected 2 tokenize requests, got %d", tokenized.Load())
+	}
+}
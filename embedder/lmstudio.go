@@ -18,10 +18,12 @@ const (
 )
 
 type LMStudioEmbedder struct {
-	endpoint   string
-	model      string
-	dimensions int
-	client     *http.Client
+	endpoint    string
+	model       string
+	dimensions  int
+	parallelism int
+	retryPolicy RetryPolicy
+	client      *http.Client
 }
 
 type lmStudioEmbedRequest struct {
@@ -62,11 +64,29 @@ func WithLMStudioDimensions(dimensions int) LMStudioOption {
 	}
 }
 
+// WithLMStudioParallelism sets the number of concurrent requests of
+// EmbedBatches.
+func WithLMStudioParallelism(parallelism int) LMStudioOption {
+	return func(e *LMStudioEmbedder) {
+		if parallelism > 0 {
+			e.parallelism = parallelism
+		}
+	}
+}
+
+func WithLMStudioRetryPolicy(policy RetryPolicy) LMStudioOption {
+	return func(e *LMStudioEmbedder) {
+		e.retryPolicy = policy
+	}
+}
+
 func NewLMStudioEmbedder(opts ...LMStudioOption) *LMStudioEmbedder {
 	e := &LMStudioEmbedder{
-		endpoint:   defaultLMStudioEndpoint,
-		model:      defaultLMStudioModel,
-		dimensions: lmStudioNomicDimensions,
+		endpoint:    defaultLMStudioEndpoint,
+		model:       defaultLMStudioModel,
+		dimensions:  lmStudioNomicDimensions,
+		parallelism: defaultParallelism,
+		retryPolicy: DefaultRetryPolicy(),
 		client: &http.Client{
 			Timeout: 60 * time.Second,
 		},
@@ -87,11 +107,22 @@ func (e *LMStudioEmbedder) Embed(ctx context.Context, text string) ([]float32, e
 	return embeddings[0], nil
 }
 
+// EmbedBatch embeds texts in requests of a few texts.
 func (e *LMStudioEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
 	if len(texts) == 0 {
 		return nil, nil
 	}
+	return embedLocalBatch(ctx, texts, LocalRequestSize, e.retryPolicy, e.embedRequest)
+}
+
+// EmbedBatches implements the BatchEmbedder interface: the requests of all
+// batches are sent by parallel workers and retried with exponential backoff.
+func (e *LMStudioEmbedder) EmbedBatches(ctx context.Context, batches []Batch, progress BatchProgress) ([]BatchResult, error) {
+	return embedBatchesParallel(ctx, batches, progress, LocalRequestSize, e.parallelism, e.retryPolicy, e.embedRequest)
+}
 
+// embedRequest embeds texts in a single OpenAI-style request.
+func (e *LMStudioEmbedder) embedRequest(ctx context.Context, texts []string) ([][]float32, error) {
 	reqBody := lmStudioEmbedRequest{
 		Model: e.model,
 		Input: texts,
@@ -141,7 +172,7 @@ func (e *LMStudioEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]
 			return nil, NewContextLengthError(0, estimatedTokens, 0, msg)
 		}
 
-		return nil, fmt.Errorf("LM Studio returned status %d: %s", resp.StatusCode, msg)
+		return nil, NewRetryableError(resp.StatusCode, fmt.Sprintf("LM Studio returned status %d: %s", resp.StatusCode, msg))
 	}
 
 	var result lmStudioEmbedResponse
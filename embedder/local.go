@@ -0,0 +1,211 @@
+package embedder
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const (
+	// LocalModel names the feature hashing scheme of the local embedder. It is
+	// recorded as the model of the index, so a change to the scheme must
+	// change the name.
+	LocalModel             = "hashed-ngrams-v1"
+	defaultLocalDimensions = 384
+)
+
+// Weights of the features of the local embedder: identifier parts carry the
+// meaning, bigrams their order and trigrams the variants of a word
+// (user/users, parse/parser).
+const (
+	localWordWeight       = 1.0
+	localIdentifierWeight = 1.0
+	localBigramWeight     = 0.5
+	localTrigramWeight    = 0.25
+)
+
+// LocalEmbedder implements the Embedder interface in-process, without model
+// or network: the identifiers, word bigrams and character trigrams of a text
+// are hashed into a fixed number of dimensions. Texts sharing identifiers get
+// close vectors. Embeddings are deterministic but of far lower quality than
+// those of a neural model; the embedder is meant for air-gapped CI and tests.
+type LocalEmbedder struct {
+	dimensions int
+}
+
+type LocalOption func(*LocalEmbedder)
+
+func WithLocalDimensions(dimensions int) LocalOption {
+	return func(e *LocalEmbedder) {
+		if dimensions > 0 {
+			e.dimensions = dimensions
+		}
+	}
+}
+
+func NewLocalEmbedder(opts ...LocalOption) *LocalEmbedder {
+	e := &LocalEmbedder{
+		dimensions: defaultLocalDimensions,
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+func (e *LocalEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return e.embed(text), nil
+}
+
+func (e *LocalEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		embeddings[i] = e.embed(text)
+	}
+	return embeddings, nil
+}
+
+// localFeature accumulates the occurrences of a hashed feature.
+type localFeature struct {
+	weight float64
+	count  int
+}
+
+// embed returns the L2-normalized sum of the hashed features of text. Each
+// feature adds its weight times 1+log(count), so that repeated keywords do
+// not drown the rest of a chunk, to one dimension with a sign taken from its
+// hash.
+func (e *LocalEmbedder) embed(text string) []float32 {
+	features := make(map[uint64]*localFeature)
+	add := func(kind byte, value string, weight float64) {
+		h := fnv.New64a()
+		h.Write([]byte{kind})
+		h.Write([]byte(value))
+		key := h.Sum64()
+		if f, ok := features[key]; ok {
+			f.count++
+			return
+		}
+		features[key] = &localFeature{weight: weight, count: 1}
+	}
+
+	var previous string
+	for _, identifier := range splitIdentifiers(text) {
+		words := splitWords(identifier)
+		if len(words) > 1 {
+			add('i', strings.Join(words, ""), localIdentifierWeight)
+		}
+		for _, word := range words {
+			if len([]rune(word)) < 2 {
+				previous = ""
+				continue
+			}
+			add('w', word, localWordWeight)
+			if previous != "" {
+				add('b', previous+" "+word, localBigramWeight)
+			}
+			previous = word
+			padded := []rune("^" + word + "$")
+			for i := 0; i+3 <= len(padded); i++ {
+				add('t', string(padded[i:i+3]), localTrigramWeight)
+			}
+		}
+	}
+	// Vector stores reject or mishandle zero vectors (cosine of an empty
+	// text), so texts without words share a single feature
+	if len(features) == 0 {
+		add('e', "", localWordWeight)
+	}
+
+	// Sum in a fixed order: floating point addition is not associative and
+	// map iteration order is random, so the same text would get embeddings
+	// differing in their last bits
+	keys := make([]uint64, 0, len(features))
+	for key := range features {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	vector := make([]float64, e.dimensions)
+	for _, key := range keys {
+		f := features[key]
+		value := f.weight * (1 + math.Log(float64(f.count)))
+		if key>>63 == 1 {
+			value = -value
+		}
+		vector[key%uint64(e.dimensions)] += value
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+	embedding := make([]float32, e.dimensions)
+	for i, v := range vector {
+		if norm > 0 {
+			embedding[i] = float32(v / norm)
+		}
+	}
+	return embedding
+}
+
+// splitIdentifiers returns the runs of letters and digits of text.
+func splitIdentifiers(text string) []string {
+	var identifiers []string
+	start := -1
+	for i, r := range text {
+		isPart := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case isPart && start < 0:
+			start = i
+		case !isPart && start >= 0:
+			identifiers = append(identifiers, text[start:i])
+			start = -1
+		}
+	}
+	if start >= 0 {
+		identifiers = append(identifiers, text[start:])
+	}
+	return identifiers
+}
+
+// splitWords splits an identifier into lowercase words at case and digit
+// boundaries: HTTPServer2 gives http, server and 2.
+func splitWords(identifier string) []string {
+	runes := []rune(identifier)
+	var words []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+		boundary := unicode.IsLower(prev) && unicode.IsUpper(cur) ||
+			unicode.IsDigit(prev) != unicode.IsDigit(cur) ||
+			// The last capital of an acronym starts the next word: HTTPServer
+			unicode.IsUpper(prev) && unicode.IsUpper(cur) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if boundary {
+			words = append(words, strings.ToLower(string(runes[start:i])))
+			start = i
+		}
+	}
+	return append(words, strings.ToLower(string(runes[start:])))
+}
+
+// Dimensions returns the number of dimensions of the embeddings.
+func (e *LocalEmbedder) Dimensions() int {
+	return e.dimensions
+}
+
+func (e *LocalEmbedder) Close() error {
+	return nil
+}
@@ -0,0 +1,152 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// LocalRequestSize is the number of texts per request to local providers
+// (Ollama, LM Studio). Batches are formed for the limits of cloud APIs, while
+// local servers embed a request in model passes of a few texts, so larger
+// requests only risk the client timeout.
+const LocalRequestSize = 32
+
+// embedFunc embeds texts in a single request.
+type embedFunc func(ctx context.Context, texts []string) ([][]float32, error)
+
+// embedBatchesParallel implements EmbedBatches for self-hosted providers:
+// batches are split into requests of requestSize texts (LocalRequestSize when
+// <= 0), sent by up to parallelism workers and retried with policy.
+func embedBatchesParallel(ctx context.Context, batches []Batch, progress BatchProgress, requestSize, parallelism int, policy RetryPolicy, embed embedFunc) ([]BatchResult, error) {
+	if len(batches) == 0 {
+		return nil, nil
+	}
+	if requestSize <= 0 {
+		requestSize = LocalRequestSize
+	}
+	if parallelism <= 0 {
+		parallelism = defaultParallelism
+	}
+
+	totalChunks := 0
+	results := make([]BatchResult, len(batches))
+	for i, batch := range batches {
+		totalChunks += batch.Size()
+		results[i] = BatchResult{BatchIndex: batch.Index, Embeddings: make([][]float32, batch.Size())}
+	}
+
+	var completedChunks atomic.Int64
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(parallelism)
+	for i, batch := range batches {
+		contents := batch.Contents()
+		for start := 0; start < len(contents); start += requestSize {
+			end := min(start+requestSize, len(contents))
+			g.Go(func() error {
+				onRetry := func(attempt, statusCode int) {
+					if progress != nil {
+						progress(batch.Index, len(batches), int(completedChunks.Load()), totalChunks, true, attempt, statusCode)
+					}
+				}
+				embeddings, err := embedLocal(ctx, contents[start:end], policy, embed, onRetry)
+				if err != nil {
+					if ctxErr := AsContextLengthError(err); ctxErr != nil {
+						ctxErr.ChunkIndex += start
+					}
+					return fmt.Errorf("batch %d failed: %w", batch.Index, err)
+				}
+				copy(results[i].Embeddings[start:end], embeddings)
+				completed := completedChunks.Add(int64(end - start))
+				if progress != nil {
+					progress(batch.Index, len(batches), int(completed), totalChunks, false, 0, 0)
+				}
+				return nil
+			})
+		}
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// embedLocalBatch embeds texts in sequential requests of requestSize texts
+// (LocalRequestSize when <= 0), for EmbedBatch.
+func embedLocalBatch(ctx context.Context, texts []string, requestSize int, policy RetryPolicy, embed embedFunc) ([][]float32, error) {
+	if requestSize <= 0 {
+		requestSize = LocalRequestSize
+	}
+	embeddings := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += requestSize {
+		end := min(start+requestSize, len(texts))
+		vectors, err := embedLocal(ctx, texts[start:end], policy, embed, nil)
+		if err != nil {
+			if ctxErr := AsContextLengthError(err); ctxErr != nil {
+				ctxErr.ChunkIndex += start
+			}
+			return nil, err
+		}
+		embeddings = append(embeddings, vectors...)
+	}
+	return embeddings, nil
+}
+
+// embedLocal embeds the texts of a request, retrying retryable errors. When a
+// text exceeds the context of the model, the texts are embedded one by one to
+// report the index of the culprit in a ContextLengthError.
+func embedLocal(ctx context.Context, texts []string, policy RetryPolicy, embed embedFunc, onRetry func(attempt, statusCode int)) ([][]float32, error) {
+	embeddings, err := embedWithRetry(ctx, texts, policy, embed, onRetry)
+	if err == nil || len(texts) == 1 || !IsContextLengthError(err) {
+		return embeddings, err
+	}
+
+	embeddings = make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors, err := embedWithRetry(ctx, []string{text}, policy, embed, onRetry)
+		if err != nil {
+			if ctxErr := AsContextLengthError(err); ctxErr != nil {
+				ctxErr.ChunkIndex = i
+			}
+			return nil, err
+		}
+		embeddings[i] = vectors[0]
+	}
+	return embeddings, nil
+}
+
+// embedWithRetry sends a request, retrying RetryableErrors with policy.
+func embedWithRetry(ctx context.Context, texts []string, policy RetryPolicy, embed embedFunc, onRetry func(attempt, statusCode int)) ([][]float32, error) {
+	for attempt := 0; ; attempt++ {
+		countRequest(ctx)
+		embeddings, err := embed(ctx, texts)
+		if err == nil {
+			if len(embeddings) != len(texts) {
+				return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(embeddings))
+			}
+			return embeddings, nil
+		}
+
+		var retryErr *RetryableError
+		if !errors.As(err, &retryErr) || !retryErr.Retryable {
+			return nil, err
+		}
+		if !policy.ShouldRetry(attempt) {
+			return nil, fmt.Errorf("request failed after %d attempts: %w", attempt+1, err)
+		}
+		if onRetry != nil {
+			onRetry(attempt+1, retryErr.StatusCode)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(policy.Calculate(attempt)):
+		}
+	}
+}
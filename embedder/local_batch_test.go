@@ -0,0 +1,183 @@
+package embedder
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fastRetryPolicy retries without waiting.
+var fastRetryPolicy = RetryPolicy{BaseDelay: time.Millisecond, Multiplier: 1, MaxDelay: time.Millisecond, MaxAttempts: 3}
+
+// ollamaTestServer embeds each input as its length, fails "too long" inputs
+// with a context length error and the first failures requests with a 503.
+func ollamaTestServer(t *testing.T, failures int32, requests *atomic.Int32) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			http.NotFound(w, r)
+			return
+		}
+		n := requests.Add(1)
+		var req ollamaBatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Truncate {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if n <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(ollamaErrorResponse{Error: "server busy"})
+			return
+		}
+		resp := ollamaBatchResponse{}
+		for _, input := range req.Input {
+			if input == "too long" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(ollamaErrorResponse{Error: "the input length exceeds the context length"})
+				return
+			}
+			resp.Embeddings = append(resp.Embeddings, []float32{float32(len(input))})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestOllamaEmbedder_EmbedBatches(t *testing.T) {
+	var requests atomic.Int32
+	server := ollamaTestServer(t, 1, &requests)
+	defer server.Close()
+
+	e := NewOllamaEmbedder(WithOllamaEndpoint(server.URL), WithOllamaParallelism(2), WithOllamaRetryPolicy(fastRetryPolicy))
+	var texts []string
+	for i := 0; i < LocalRequestSize+5; i++ {
+		texts = append(texts, strings.Repeat("x", i+1))
+	}
+	batches := FormBatches([]FileChunks{{FileIndex: 0, Chunks: texts}, {FileIndex: 1, Chunks: []string{"abc"}}})
+
+	var retried atomic.Bool
+	var lastCompleted atomic.Int32
+	results, err := e.EmbedBatches(context.Background(), batches, func(_, _, completed, total int, retrying bool, _, statusCode int) {
+		if retrying && statusCode == http.StatusServiceUnavailable {
+			retried.Store(true)
+		}
+		if !retrying && total == len(texts)+1 && int32(completed) > lastCompleted.Load() {
+			lastCompleted.Store(int32(completed))
+		}
+	})
+	if err != nil {
+		t.Fatalf("EmbedBatches failed: %v", err)
+	}
+	files := MapResultsToFiles(batches, results, 2)
+	for i, vector := range files[0] {
+		if vector[0] != float32(i+1) {
+			t.Fatalf("expected chunk %d embedded in order, got %v", i, vector)
+		}
+	}
+	if files[1][0][0] != 3 {
+		t.Errorf("unexpected embedding of file 1: %v", files[1])
+	}
+	// One batch of 38 texts: two requests, plus the retried one
+	if got := requests.Load(); got != 3 {
+		t.Errorf("expected 3 requests, got %d", got)
+	}
+	if !retried.Load() {
+		t.Error("expected a retry to be reported")
+	}
+	if lastCompleted.Load() != int32(len(texts)+1) {
+		t.Errorf("expected progress to reach all chunks, got %d", lastCompleted.Load())
+	}
+}
+
+func TestOllamaEmbedder_EmbedBatch_ContextLength(t *testing.T) {
+	var requests atomic.Int32
+	server := ollamaTestServer(t, 0, &requests)
+	defer server.Close()
+
+	e := NewOllamaEmbedder(WithOllamaEndpoint(server.URL), WithOllamaRetryPolicy(fastRetryPolicy))
+	texts := make([]string, LocalRequestSize+3)
+	for i := range texts {
+		texts[i] = "ok"
+	}
+	texts[LocalRequestSize+1] = "too long"
+
+	_, err := e.EmbedBatch(context.Background(), texts)
+	ctxErr := AsContextLengthError(err)
+	if ctxErr == nil {
+		t.Fatalf("expected a ContextLengthError, got %v", err)
+	}
+	if ctxErr.ChunkIndex != LocalRequestSize+1 {
+		t.Errorf("expected chunk %d to exceed the context, got %d", LocalRequestSize+1, ctxErr.ChunkIndex)
+	}
+}
+
+func TestOllamaEmbedder_EmbedBatch_LegacyAPI(t *testing.T) {
+	var batched atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/embed" {
+			batched.Add(1)
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("404 page not found"))
+			return
+		}
+		var req ollamaEmbedRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(ollamaEmbedResponse{Embedding: []float32{float32(len(req.Prompt))}})
+	}))
+	defer server.Close()
+
+	e := NewOllamaEmbedder(WithOllamaEndpoint(server.URL))
+	for i := 0; i < 2; i++ {
+		vectors, err := e.EmbedBatch(context.Background(), []string{"a", "bb"})
+		if err != nil {
+			t.Fatalf("EmbedBatch failed: %v", err)
+		}
+		if len(vectors) != 2 || vectors[1][0] != 2 {
+			t.Errorf("unexpected vectors %v", vectors)
+		}
+	}
+	if batched.Load() != 1 {
+		t.Errorf("expected /api/embed to be tried once, got %d", batched.Load())
+	}
+}
+
+func TestLMStudioEmbedder_EmbedBatches(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(lmStudioErrorResponse{})
+			return
+		}
+		var req lmStudioEmbedRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		resp := mockEmbeddingResponse(len(req.Input))
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	e := NewLMStudioEmbedder(WithLMStudioEndpoint(server.URL), WithLMStudioRetryPolicy(fastRetryPolicy))
+	texts := make([]string, LocalRequestSize+1)
+	for i := range texts {
+		texts[i] = "text"
+	}
+	batches := FormBatches([]FileChunks{{FileIndex: 0, Chunks: texts}})
+	results, err := e.EmbedBatches(context.Background(), batches, nil)
+	if err != nil {
+		t.Fatalf("EmbedBatches failed: %v", err)
+	}
+	if len(results) != 1 || len(results[0].Embeddings) != len(texts) {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	// Each request embeds its inputs from index 0
+	if results[0].Embeddings[LocalRequestSize][0] != 0 || results[0].Embeddings[1][0] != 1 {
+		t.Errorf("unexpected embeddings %v", results[0].Embeddings)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("expected 2 requests and a retry, got %d", got)
+	}
+}
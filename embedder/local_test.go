@@ -0,0 +1,101 @@
+package embedder
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestLocalEmbedder_Deterministic(t *testing.T) {
+	ctx := context.Background()
+	text := "func (s *UserService) GetUserByID(ctx context.Context, id string) (*User, error)"
+
+	first, err := NewLocalEmbedder().Embed(ctx, text)
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	second, err := NewLocalEmbedder().EmbedBatch(ctx, []string{"other", text})
+	if err != nil {
+		t.Fatalf("EmbedBatch failed: %v", err)
+	}
+
+	if len(first) != defaultLocalDimensions {
+		t.Fatalf("expected %d dimensions, got %d", defaultLocalDimensions, len(first))
+	}
+	if !reflect.DeepEqual(first, second[1]) {
+		t.Error("expected identical embeddings for the same text")
+	}
+
+	var norm float64
+	for _, v := range first {
+		norm += float64(v) * float64(v)
+	}
+	if math.Abs(norm-1) > 1e-5 {
+		t.Errorf("expected a unit vector, got norm %f", math.Sqrt(norm))
+	}
+}
+
+func TestLocalEmbedder_Similarity(t *testing.T) {
+	e := NewLocalEmbedder(WithLocalDimensions(256))
+	embeddings, err := e.EmbedBatch(context.Background(), []string{
+		"parse the configuration file",
+		"func ParseConfigFile(path string) (*Config, error)",
+		"func renderHTMLTemplate(w io.Writer, page *Page) error",
+	})
+	if err != nil {
+		t.Fatalf("EmbedBatch failed: %v", err)
+	}
+	if len(embeddings[0]) != 256 {
+		t.Fatalf("expected 256 dimensions, got %d", len(embeddings[0]))
+	}
+
+	dot := func(a, b []float32) float32 {
+		var sum float32
+		for i := range a {
+			sum += a[i] * b[i]
+		}
+		return sum
+	}
+	related, unrelated := dot(embeddings[0], embeddings[1]), dot(embeddings[0], embeddings[2])
+	if related <= unrelated {
+		t.Errorf("expected the query closer to ParseConfigFile (%f) than to renderHTMLTemplate (%f)", related, unrelated)
+	}
+}
+
+func TestLocalEmbedder_EmptyText(t *testing.T) {
+	embedding, err := NewLocalEmbedder().Embed(context.Background(), "  {}  ")
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	var norm float64
+	for _, v := range embedding {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		t.Error("expected a non-zero vector for a text without words")
+	}
+}
+
+func TestLocalEmbedder_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewLocalEmbedder().EmbedBatch(ctx, []string{"text"}); err == nil {
+		t.Error("expected an error for a canceled context")
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	tests := map[string][]string{
+		"getUserName": {"get", "user", "name"},
+		"HTTPServer2": {"http", "server", "2"},
+		"ParseJSON":   {"parse", "json"},
+		"utf8":        {"utf", "8"},
+		"lower":       {"lower"},
+	}
+	for identifier, want := range tests {
+		if got := splitWords(identifier); !reflect.DeepEqual(got, want) {
+			t.Errorf("splitWords(%q) = %v, want %v", identifier, got, want)
+		}
+	}
+}
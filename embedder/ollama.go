@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -18,10 +19,13 @@ const (
 )
 
 type OllamaEmbedder struct {
-	endpoint   string
-	model      string
-	dimensions int
-	client     *http.Client
+	endpoint    string
+	model       string
+	dimensions  int
+	parallelism int
+	retryPolicy RetryPolicy
+	client      *http.Client
+	legacyAPI   atomic.Bool // Server predates the batched /api/embed endpoint
 }
 
 type ollamaEmbedRequest struct {
@@ -33,6 +37,20 @@ type ollamaEmbedResponse struct {
 	Embedding []float32 `json:"embedding"`
 }
 
+type ollamaBatchRequest struct {
+	Model    string   `json:"model"`
+	Input    []string `json:"input"`
+	Truncate bool     `json:"truncate"`
+}
+
+type ollamaBatchResponse struct {
+	Embeddings [][]float32 `json:"embeddings"`
+}
+
+type ollamaErrorResponse struct {
+	Error string `json:"error"`
+}
+
 type OllamaOption func(*OllamaEmbedder)
 
 func WithOllamaEndpoint(endpoint string) OllamaOption {
@@ -52,11 +70,28 @@ func WithOllamaDimensions(dimensions int) OllamaOption {
 	}
 }
 
+// WithOllamaParallelism sets the number of concurrent requests of EmbedBatches.
+func WithOllamaParallelism(parallelism int) OllamaOption {
+	return func(e *OllamaEmbedder) {
+		if parallelism > 0 {
+			e.parallelism = parallelism
+		}
+	}
+}
+
+func WithOllamaRetryPolicy(policy RetryPolicy) OllamaOption {
+	return func(e *OllamaEmbedder) {
+		e.retryPolicy = policy
+	}
+}
+
 func NewOllamaEmbedder(opts ...OllamaOption) *OllamaEmbedder {
 	e := &OllamaEmbedder{
-		endpoint:   defaultOllamaEndpoint,
-		model:      defaultOllamaModel,
-		dimensions: nomicEmbedDimensions,
+		endpoint:    defaultOllamaEndpoint,
+		model:       defaultOllamaModel,
+		dimensions:  nomicEmbedDimensions,
+		parallelism: defaultParallelism,
+		retryPolicy: DefaultRetryPolicy(),
 		client: &http.Client{
 			Timeout: 60 * time.Second,
 		},
@@ -119,7 +154,95 @@ func (e *OllamaEmbedder) Embed(ctx context.Context, text string) ([]float32, err
 	return result.Embedding, nil
 }
 
+// EmbedBatch embeds texts with the batched /api/embed endpoint, in requests
+// of a few texts.
 func (e *OllamaEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	return embedLocalBatch(ctx, texts, LocalRequestSize, e.retryPolicy, e.embedRequest)
+}
+
+// EmbedBatches implements the BatchEmbedder interface: the requests of all
+// batches are sent by parallel workers and retried with exponential backoff.
+func (e *OllamaEmbedder) EmbedBatches(ctx context.Context, batches []Batch, progress BatchProgress) ([]BatchResult, error) {
+	return embedBatchesParallel(ctx, batches, progress, LocalRequestSize, e.parallelism, e.retryPolicy, e.embedRequest)
+}
+
+// embedRequest embeds texts in a single /api/embed request. Servers without
+// that endpoint get one /api/embeddings request per text instead.
+func (e *OllamaEmbedder) embedRequest(ctx context.Context, texts []string) ([][]float32, error) {
+	if e.legacyAPI.Load() {
+		return e.embedEach(ctx, texts)
+	}
+
+	// Without truncation, texts beyond the context of the model fail with a
+	// ContextLengthError instead of being silently cut
+	jsonData, err := json.Marshal(ollamaBatchRequest{Model: e.model, Input: texts, Truncate: false})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/api/embed", e.endpoint)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request to Ollama: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		msg := string(body)
+		var errResp ollamaErrorResponse
+		if json.Unmarshal(body, &errResp) == nil && errResp.Error != "" {
+			msg = errResp.Error
+		}
+
+		// Servers before Ollama 0.3 have no /api/embed route (a missing
+		// model is a 404 with a JSON error)
+		if resp.StatusCode == http.StatusNotFound && strings.Contains(msg, "page not found") {
+			e.legacyAPI.Store(true)
+			return e.embedEach(ctx, texts)
+		}
+
+		if strings.Contains(msg, "context length") {
+			estimatedTokens := 0
+			for _, text := range texts {
+				estimatedTokens += EstimateTokens(text)
+			}
+			return nil, NewContextLengthError(0, estimatedTokens, 0, msg)
+		}
+
+		return nil, NewRetryableError(resp.StatusCode, fmt.Sprintf("Ollama returned status %d: %s", resp.StatusCode, msg))
+	}
+
+	var result ollamaBatchResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(result.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(result.Embeddings))
+	}
+	for _, embedding := range result.Embeddings {
+		if len(embedding) == 0 {
+			return nil, fmt.Errorf("Ollama returned empty embedding")
+		}
+	}
+	return result.Embeddings, nil
+}
+
+// embedEach embeds texts one /api/embeddings request at a time.
+func (e *OllamaEmbedder) embedEach(ctx context.Context, texts []string) ([][]float32, error) {
 	results := make([][]float32, len(texts))
 
 	for i, text := range texts {
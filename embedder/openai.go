@@ -12,6 +12,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/yoanbernabeu/grepai/tokenizer"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -32,9 +33,31 @@ type OpenAIEmbedder struct {
 	client      *http.Client
 	rateLimiter *AdaptiveRateLimiter
 	tokenBucket *TokenBucket
-	tpmLimit    int64 // Tokens per minute limit (0 = disabled)
+	tpmLimit    int64               // Tokens per minute limit (0 = disabled)
+	tokenizer   tokenizer.Tokenizer // Tokenizer for TPM accounting (nil = estimate)
+
+	embeddingsURL string      // Embeddings URL, endpoint + "/embeddings" when empty
+	auth          requestAuth // Credentials, the API key as a bearer token when nil
+}
+
+// requestAuth authenticates requests to an OpenAI-compatible API.
+type requestAuth interface {
+	apply(ctx context.Context, req *http.Request) error
+	// refresh drops cached credentials rejected by the server, reporting
+	// whether new ones may be fetched.
+	refresh() bool
+}
+
+// bearerKey authenticates with an API key as a bearer token.
+type bearerKey string
+
+func (k bearerKey) apply(_ context.Context, req *http.Request) error {
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", string(k)))
+	return nil
 }
 
+func (k bearerKey) refresh() bool { return false }
+
 type openAIEmbedRequest struct {
 	Model      string   `json:"model"`
 	Input      []string `json:"input"`
@@ -109,7 +132,33 @@ func WithOpenAITPMLimit(tpm int64) OpenAIOption {
 	}
 }
 
+// WithOpenAITokenizer sets the tokenizer used for TPM accounting.
+// Default: nil (4-chars-per-token estimate).
+func WithOpenAITokenizer(t tokenizer.Tokenizer) OpenAIOption {
+	return func(e *OpenAIEmbedder) {
+		e.tokenizer = t
+	}
+}
+
 func NewOpenAIEmbedder(opts ...OpenAIOption) (*OpenAIEmbedder, error) {
+	e := newOpenAIEmbedder(opts)
+
+	// Try to get API key from environment if not set
+	if e.apiKey == "" {
+		e.apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+
+	if e.apiKey == "" {
+		return nil, fmt.Errorf("OpenAI API key not set (use OPENAI_API_KEY environment variable)")
+	}
+	e.auth = bearerKey(e.apiKey)
+
+	return e, nil
+}
+
+// newOpenAIEmbedder applies options to the defaults, without checking
+// credentials.
+func newOpenAIEmbedder(opts []OpenAIOption) *OpenAIEmbedder {
 	e := &OpenAIEmbedder{
 		endpoint:    defaultOpenAIEndpoint,
 		model:       defaultOpenAIModel,
@@ -125,15 +174,6 @@ func NewOpenAIEmbedder(opts ...OpenAIOption) (*OpenAIEmbedder, error) {
 		opt(e)
 	}
 
-	// Try to get API key from environment if not set
-	if e.apiKey == "" {
-		e.apiKey = os.Getenv("OPENAI_API_KEY")
-	}
-
-	if e.apiKey == "" {
-		return nil, fmt.Errorf("OpenAI API key not set (use OPENAI_API_KEY environment variable)")
-	}
-
 	// Initialize adaptive rate limiter with configured parallelism
 	e.rateLimiter = NewAdaptiveRateLimiter(e.parallelism)
 
@@ -142,7 +182,7 @@ func NewOpenAIEmbedder(opts ...OpenAIOption) (*OpenAIEmbedder, error) {
 		e.tokenBucket = NewTokenBucket(e.tpmLimit)
 	}
 
-	return e, nil
+	return e
 }
 
 func (e *OpenAIEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
@@ -158,34 +198,9 @@ func (e *OpenAIEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]fl
 		return nil, nil
 	}
 
-	reqBody := openAIEmbedRequest{
-		Model:      e.model,
-		Input:      texts,
-		Dimensions: e.dimensions,
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	url := fmt.Sprintf("%s/embeddings", e.endpoint)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonData))
+	resp, body, err := e.sendEmbedRequest(ctx, texts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", e.apiKey))
-
-	resp, err := e.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request to OpenAI: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, err
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -330,7 +345,7 @@ func (e *OpenAIEmbedder) estimateBatchTokens(contents []string) int64 {
 	}
 	var total int64
 	for _, content := range contents {
-		total += int64(EstimateTokens(content))
+		total += int64(CountTokens(e.tokenizer, content))
 	}
 	return total
 }
@@ -395,17 +410,55 @@ func (e *OpenAIEmbedder) buildEmbedHTTPRequest(ctx context.Context, texts []stri
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/embeddings", e.endpoint)
+	url := e.embeddingsURL
+	if url == "" {
+		url = fmt.Sprintf("%s/embeddings", e.endpoint)
+	}
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", e.apiKey))
+
+	var auth requestAuth = bearerKey(e.apiKey)
+	if e.auth != nil {
+		auth = e.auth
+	}
+	if err := auth.apply(ctx, req); err != nil {
+		return nil, fmt.Errorf("failed to authenticate request: %w", err)
+	}
 
 	return req, nil
 }
 
+// sendEmbedRequest sends an embeddings request and reads the response. A
+// request rejected with 401 is sent once more when the credentials can be
+// refreshed.
+func (e *OpenAIEmbedder) sendEmbedRequest(ctx context.Context, texts []string) (*http.Response, []byte, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := e.buildEmbedHTTPRequest(ctx, texts)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		countRequest(ctx)
+		resp, err := e.client.Do(req)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to send request to OpenAI: %w", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read response: %w", err)
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 && e.auth != nil && e.auth.refresh() {
+			continue
+		}
+		return resp, body, nil
+	}
+}
+
 // handleEmbedErrorResponse parses an error response and returns an appropriate error.
 // Returns a ContextLengthError for context limit exceeded, or a RetryableError for other cases.
 func handleEmbedErrorResponse(resp *http.Response, body []byte) error {
@@ -461,22 +514,11 @@ func (e *OpenAIEmbedder) embedBatchRequest(ctx context.Context, texts []string)
 		return nil, nil
 	}
 
-	req, err := e.buildEmbedHTTPRequest(ctx, texts)
+	resp, body, err := e.sendEmbedRequest(ctx, texts)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := e.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request to OpenAI: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
 	if resp.StatusCode != http.StatusOK {
 		return nil, handleEmbedErrorResponse(resp, body)
 	}
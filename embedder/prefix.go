@@ -0,0 +1,118 @@
+package embedder
+
+import "context"
+
+// QueryEmbedder is implemented by embedders that embed search queries
+// differently from documents. Embed and EmbedBatch embed documents.
+type QueryEmbedder interface {
+	EmbedQuery(ctx context.Context, query string) ([]float32, error)
+}
+
+// queryKey marks the context of search queries, so that decorators below the
+// query prefix tell them from documents.
+type queryKey struct{}
+
+// EmbedQuery embeds a search query, with EmbedQuery when the embedder
+// distinguishes queries from documents.
+func EmbedQuery(ctx context.Context, e Embedder, query string) ([]float32, error) {
+	ctx = context.WithValue(ctx, queryKey{}, true)
+	if q, ok := e.(QueryEmbedder); ok {
+		return q.EmbedQuery(ctx, query)
+	}
+	return e.Embed(ctx, query)
+}
+
+// isQuery reports whether ctx embeds a search query.
+func isQuery(ctx context.Context) bool {
+	query, _ := ctx.Value(queryKey{}).(bool)
+	return query
+}
+
+// PrefixedEmbedder decorates an Embedder for asymmetric models, which expect
+// an instruction before queries ("search_query: ") and documents
+// ("search_document: ").
+type PrefixedEmbedder struct {
+	inner    Embedder
+	query    string
+	document string
+}
+
+// prefixedBatchEmbedder is a PrefixedEmbedder over a BatchEmbedder.
+type prefixedBatchEmbedder struct {
+	*PrefixedEmbedder
+	batch BatchEmbedder
+}
+
+// NewPrefixedEmbedder returns inner with query and document prefixes. The
+// result implements BatchEmbedder when inner does.
+func NewPrefixedEmbedder(inner Embedder, query, document string) Embedder {
+	p := &PrefixedEmbedder{inner: inner, query: query, document: document}
+	if batch, ok := inner.(BatchEmbedder); ok {
+		return &prefixedBatchEmbedder{PrefixedEmbedder: p, batch: batch}
+	}
+	return p
+}
+
+// Embed embeds a document.
+func (p *PrefixedEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	return p.inner.Embed(ctx, p.document+text)
+}
+
+// EmbedQuery embeds a search query.
+func (p *PrefixedEmbedder) EmbedQuery(ctx context.Context, query string) ([]float32, error) {
+	return p.inner.Embed(ctx, p.query+query)
+}
+
+// EmbedBatch embeds documents.
+func (p *PrefixedEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	return p.inner.EmbedBatch(ctx, p.documents(texts))
+}
+
+// Cached reports whether the vector of a document is in the cache of the
+// decorated embedder.
+func (p *PrefixedEmbedder) Cached(text string) bool {
+	c, ok := p.inner.(CacheChecker)
+	return ok && c.Cached(p.document+text)
+}
+
+// Dimensions returns the dimensions of the decorated embedder.
+func (p *PrefixedEmbedder) Dimensions() int {
+	return p.inner.Dimensions()
+}
+
+// Ping checks the decorated embedder when it supports it.
+func (p *PrefixedEmbedder) Ping(ctx context.Context) error {
+	return ping(ctx, p.inner)
+}
+
+// Close closes the decorated embedder.
+func (p *PrefixedEmbedder) Close() error {
+	return p.inner.Close()
+}
+
+// EmbedBatches embeds batches of documents.
+func (p *prefixedBatchEmbedder) EmbedBatches(ctx context.Context, batches []Batch, progress BatchProgress) ([]BatchResult, error) {
+	if p.document == "" {
+		return p.batch.EmbedBatches(ctx, batches, progress)
+	}
+	prefixed := make([]Batch, len(batches))
+	for i, batch := range batches {
+		prefixed[i] = Batch{Index: batch.Index, Entries: make([]BatchEntry, len(batch.Entries))}
+		for j, entry := range batch.Entries {
+			entry.Content = p.document + entry.Content
+			prefixed[i].Entries[j] = entry
+		}
+	}
+	return p.batch.EmbedBatches(ctx, prefixed, progress)
+}
+
+func (p *PrefixedEmbedder) documents(texts []string) []string {
+	if p.document == "" {
+		return texts
+	}
+	prefixed := make([]string, len(texts))
+	for i, text := range texts {
+		prefixed[i] = p.document + text
+	}
+	return prefixed
+}
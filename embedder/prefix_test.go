@@ -0,0 +1,96 @@
+package embedder
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// recordingEmbedder records the texts it embeds.
+type recordingEmbedder struct {
+	texts []string
+}
+
+func (e *recordingEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	e.texts = append(e.texts, text)
+	return []float32{1}, nil
+}
+
+func (e *recordingEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i], _ = e.Embed(ctx, text)
+	}
+	return vectors, nil
+}
+
+func (e *recordingEmbedder) Dimensions() int { return 1 }
+func (e *recordingEmbedder) Close() error    { return nil }
+
+type recordingBatchEmbedder struct {
+	recordingEmbedder
+}
+
+func (e *recordingBatchEmbedder) EmbedBatches(ctx context.Context, batches []Batch, progress BatchProgress) ([]BatchResult, error) {
+	results := make([]BatchResult, len(batches))
+	for i, batch := range batches {
+		vectors, _ := e.EmbedBatch(ctx, batch.Contents())
+		results[i] = BatchResult{BatchIndex: batch.Index, Embeddings: vectors}
+	}
+	return results, nil
+}
+
+func TestPrefixedEmbedder(t *testing.T) {
+	ctx := context.Background()
+	inner := &recordingEmbedder{}
+	emb := NewPrefixedEmbedder(inner, "q: ", "d: ")
+	if _, ok := emb.(BatchEmbedder); ok {
+		t.Fatal("expected no BatchEmbedder over a plain embedder")
+	}
+
+	if _, err := EmbedQuery(ctx, emb, "parse config"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := emb.Embed(ctx, "func a() {}"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := emb.EmbedBatch(ctx, []string{"b", "c"}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"q: parse config", "d: func a() {}", "d: b", "d: c"}
+	if !reflect.DeepEqual(inner.texts, want) {
+		t.Errorf("expected %q, got %q", want, inner.texts)
+	}
+
+	// Without a QueryEmbedder, queries are embedded as is
+	plain := &recordingEmbedder{}
+	if _, err := EmbedQuery(ctx, plain, "parse config"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(plain.texts, []string{"parse config"}) {
+		t.Errorf("expected the query unchanged, got %q", plain.texts)
+	}
+}
+
+func TestPrefixedEmbedder_EmbedBatches(t *testing.T) {
+	inner := &recordingBatchEmbedder{}
+	emb, ok := NewPrefixedEmbedder(inner, "", "d: ").(BatchEmbedder)
+	if !ok {
+		t.Fatal("expected a BatchEmbedder over a BatchEmbedder")
+	}
+
+	batches := []Batch{{Index: 0, Entries: []BatchEntry{{Content: "a"}, {Content: "b"}}}}
+	results, err := emb.EmbedBatches(context.Background(), batches, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || len(results[0].Embeddings) != 2 {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if want := []string{"d: a", "d: b"}; !reflect.DeepEqual(inner.texts, want) {
+		t.Errorf("expected %q, got %q", want, inner.texts)
+	}
+	if batches[0].Entries[0].Content != "a" {
+		t.Error("expected the batches of the caller unchanged")
+	}
+}
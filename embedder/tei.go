@@ -0,0 +1,296 @@
+package embedder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultTEIEndpoint = "http://localhost:8080"
+
+// TEIEmbedder implements the Embedder interface for Hugging Face Text
+// Embeddings Inference servers. The server serves a single model; its
+// dimensions and batch size limit are detected from the server.
+type TEIEmbedder struct {
+	endpoint    string
+	apiKey      string
+	dimensions  atomic.Int64 // Configured, or detected from the first embedding
+	truncate    bool
+	parallelism int
+	retryPolicy RetryPolicy
+	client      *http.Client
+
+	infoMu sync.Mutex
+	info   *teiInfo // Fetched on first use, nil until the server answers
+}
+
+type teiEmbedRequest struct {
+	Inputs    []string `json:"inputs"`
+	Truncate  bool     `json:"truncate"`
+	Normalize bool     `json:"normalize"`
+}
+
+type teiErrorResponse struct {
+	Error     string `json:"error"`
+	ErrorType string `json:"error_type"`
+}
+
+// teiInfo is the subset of the /info response used by the embedder.
+type teiInfo struct {
+	ModelID            string `json:"model_id"`
+	MaxInputLength     int    `json:"max_input_length"`
+	MaxClientBatchSize int    `json:"max_client_batch_size"`
+}
+
+type TEIOption func(*TEIEmbedder)
+
+func WithTEIEndpoint(endpoint string) TEIOption {
+	return func(e *TEIEmbedder) {
+		e.endpoint = endpoint
+	}
+}
+
+// WithTEIKey sets the API key of servers started with --api-key.
+func WithTEIKey(key string) TEIOption {
+	return func(e *TEIEmbedder) {
+		e.apiKey = key
+	}
+}
+
+func WithTEIDimensions(dimensions int) TEIOption {
+	return func(e *TEIEmbedder) {
+		e.dimensions.Store(int64(dimensions))
+	}
+}
+
+// WithTEITruncate makes the server truncate inputs longer than the model
+// context instead of rejecting them.
+func WithTEITruncate(truncate bool) TEIOption {
+	return func(e *TEIEmbedder) {
+		e.truncate = truncate
+	}
+}
+
+// WithTEIParallelism sets the number of concurrent requests of EmbedBatches.
+func WithTEIParallelism(parallelism int) TEIOption {
+	return func(e *TEIEmbedder) {
+		if parallelism > 0 {
+			e.parallelism = parallelism
+		}
+	}
+}
+
+func WithTEIRetryPolicy(policy RetryPolicy) TEIOption {
+	return func(e *TEIEmbedder) {
+		e.retryPolicy = policy
+	}
+}
+
+func NewTEIEmbedder(opts ...TEIOption) *TEIEmbedder {
+	e := &TEIEmbedder{
+		endpoint:    defaultTEIEndpoint,
+		parallelism: defaultParallelism,
+		retryPolicy: DefaultRetryPolicy(),
+		client: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+func (e *TEIEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := e.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EmbedBatch embeds texts in requests of at most the batch size of the server.
+func (e *TEIEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	return embedLocalBatch(ctx, texts, e.requestSize(ctx), e.retryPolicy, e.embedRequest)
+}
+
+// EmbedBatches implements the BatchEmbedder interface: the requests of all
+// batches are sent by parallel workers and retried with exponential backoff.
+func (e *TEIEmbedder) EmbedBatches(ctx context.Context, batches []Batch, progress BatchProgress) ([]BatchResult, error) {
+	return embedBatchesParallel(ctx, batches, progress, e.requestSize(ctx), e.parallelism, e.retryPolicy, e.embedRequest)
+}
+
+// embedRequest embeds texts in a single /embed request.
+func (e *TEIEmbedder) embedRequest(ctx context.Context, texts []string) ([][]float32, error) {
+	jsonData, err := json.Marshal(teiEmbedRequest{Inputs: texts, Truncate: e.truncate, Normalize: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint+"/embed", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	e.authorize(req)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request to TEI: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp teiErrorResponse
+		msg := string(body)
+		if json.Unmarshal(body, &errResp) == nil && errResp.Error != "" {
+			msg = errResp.Error
+		}
+
+		// Inputs over the model context are rejected as validation errors:
+		// "`inputs` must have less than 512 tokens. Given: 600"
+		if strings.Contains(msg, "must have less than") {
+			maxTokens := 0
+			if info := e.serverInfo(ctx); info != nil {
+				maxTokens = info.MaxInputLength
+			}
+			totalChars := 0
+			for _, t := range texts {
+				totalChars += len(t)
+			}
+			return nil, NewContextLengthError(0, totalChars/4, maxTokens, msg)
+		}
+
+		return nil, NewRetryableError(resp.StatusCode, fmt.Sprintf("TEI returned status %d: %s", resp.StatusCode, msg))
+	}
+
+	var embeddings [][]float32
+	if err := json.Unmarshal(body, &embeddings); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(embeddings) > 0 && e.dimensions.Load() == 0 {
+		e.dimensions.Store(int64(len(embeddings[0])))
+	}
+
+	return embeddings, nil
+}
+
+// requestSize returns the batch size limit of the server, or 0 for the
+// default request size when the server does not report it.
+func (e *TEIEmbedder) requestSize(ctx context.Context) int {
+	if info := e.serverInfo(ctx); info != nil {
+		return info.MaxClientBatchSize
+	}
+	return 0
+}
+
+// serverInfo returns the /info response of the server, fetched once. It
+// returns nil when the server cannot be reached, to be retried on next use.
+// The request is sent under the lock so that parallel workers wait for it.
+func (e *TEIEmbedder) serverInfo(ctx context.Context) *teiInfo {
+	e.infoMu.Lock()
+	defer e.infoMu.Unlock()
+	if e.info != nil {
+		return e.info
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.endpoint+"/info", nil)
+	if err != nil {
+		return nil
+	}
+	e.authorize(req)
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	// A server without /info is not asked again
+	var info teiInfo
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+			return nil
+		}
+	}
+	e.info = &info
+	return e.info
+}
+
+// ModelID returns the model served by the server, or "" when it cannot be
+// reached.
+func (e *TEIEmbedder) ModelID(ctx context.Context) string {
+	if info := e.serverInfo(ctx); info != nil {
+		return info.ModelID
+	}
+	return ""
+}
+
+// DetectDimensions returns the dimensions of the served model. /info does
+// not report them, so a probe text is embedded unless they are known.
+func (e *TEIEmbedder) DetectDimensions(ctx context.Context) (int, error) {
+	if dimensions := e.dimensions.Load(); dimensions > 0 {
+		return int(dimensions), nil
+	}
+	embedding, err := e.embedRequest(ctx, []string{"dimensions"})
+	if err != nil {
+		return 0, err
+	}
+	if len(embedding) != 1 {
+		return 0, fmt.Errorf("expected 1 embedding, got %d", len(embedding))
+	}
+	return len(embedding[0]), nil
+}
+
+// Dimensions returns the configured dimensions, or those of the embeddings
+// returned by the server. It is 0 until either is known.
+func (e *TEIEmbedder) Dimensions() int {
+	return int(e.dimensions.Load())
+}
+
+func (e *TEIEmbedder) Close() error {
+	return nil
+}
+
+// Ping checks that the server is up and its model loaded.
+func (e *TEIEmbedder) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.endpoint+"/health", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	e.authorize(req)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach TEI at %s: %w", e.endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("TEI returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (e *TEIEmbedder) authorize(req *http.Request) {
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+}
@@ -0,0 +1,128 @@
+package embedder
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// teiTestServer serves a 3-dimension model accepting batches of batchSize
+// inputs of at most 8 characters, embedding each input as its length.
+func teiTestServer(t *testing.T, batchSize int, requests *atomic.Int32, truncated *atomic.Bool) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/health":
+			w.WriteHeader(http.StatusOK)
+		case "/info":
+			json.NewEncoder(w).Encode(map[string]any{
+				"model_id":              "BAAI/bge-small-en-v1.5",
+				"max_input_length":      8,
+				"max_client_batch_size": batchSize,
+			})
+		case "/embed":
+			requests.Add(1)
+			var req teiEmbedRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !req.Normalize {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			truncated.Store(req.Truncate)
+			if len(req.Inputs) > batchSize {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				json.NewEncoder(w).Encode(teiErrorResponse{Error: "batch size too large", ErrorType: "Validation"})
+				return
+			}
+			var embeddings [][]float32
+			for _, input := range req.Inputs {
+				if len(input) > 8 && !req.Truncate {
+					w.WriteHeader(http.StatusUnprocessableEntity)
+					json.NewEncoder(w).Encode(teiErrorResponse{Error: "Input validation error: `inputs` must have less than 8 tokens. Given: 9", ErrorType: "Validation"})
+					return
+				}
+				embeddings = append(embeddings, []float32{float32(min(len(input), 8)), 0, 0})
+			}
+			json.NewEncoder(w).Encode(embeddings)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestTEIEmbedder_EmbedBatches(t *testing.T) {
+	var requests atomic.Int32
+	var truncated atomic.Bool
+	server := teiTestServer(t, 4, &requests, &truncated)
+	defer server.Close()
+
+	e := NewTEIEmbedder(WithTEIEndpoint(server.URL), WithTEIKey("secret"), WithTEIRetryPolicy(fastRetryPolicy))
+	ctx := context.Background()
+	if err := e.Ping(ctx); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if e.Dimensions() != 0 {
+		t.Errorf("expected unknown dimensions before the first embedding, got %d", e.Dimensions())
+	}
+
+	texts := []string{"a", "bb", "ccc", "dddd", "eeeee", "ffffff"}
+	batches := FormBatches([]FileChunks{{FileIndex: 0, Chunks: texts}})
+	results, err := e.EmbedBatches(ctx, batches, nil)
+	if err != nil {
+		t.Fatalf("EmbedBatches failed: %v", err)
+	}
+	for i, vector := range MapResultsToFiles(batches, results, 1)[0] {
+		if vector[0] != float32(i+1) {
+			t.Fatalf("expected chunk %d embedded in order, got %v", i, vector)
+		}
+	}
+	// Requests are limited to the batch size of the server
+	if got := requests.Load(); got != 2 {
+		t.Errorf("expected 2 requests, got %d", got)
+	}
+	if e.Dimensions() != 3 {
+		t.Errorf("expected 3 dimensions detected, got %d", e.Dimensions())
+	}
+	if model := e.ModelID(ctx); model != "BAAI/bge-small-en-v1.5" {
+		t.Errorf("unexpected model %q", model)
+	}
+}
+
+func TestTEIEmbedder_ContextLength(t *testing.T) {
+	var requests atomic.Int32
+	var truncated atomic.Bool
+	server := teiTestServer(t, 32, &requests, &truncated)
+	defer server.Close()
+
+	ctx := context.Background()
+	e := NewTEIEmbedder(WithTEIEndpoint(server.URL), WithTEIKey("secret"), WithTEIRetryPolicy(fastRetryPolicy))
+	_, err := e.EmbedBatch(ctx, []string{"a", strings.Repeat("x", 9)})
+	ctxErr := AsContextLengthError(err)
+	if ctxErr == nil {
+		t.Fatalf("expected a context length error, got %v", err)
+	}
+	if ctxErr.ChunkIndex != 1 || ctxErr.MaxTokens != 8 {
+		t.Errorf("expected chunk 1 over 8 tokens, got chunk %d over %d", ctxErr.ChunkIndex, ctxErr.MaxTokens)
+	}
+
+	e = NewTEIEmbedder(WithTEIEndpoint(server.URL), WithTEIKey("secret"), WithTEITruncate(true))
+	vector, err := e.Embed(ctx, strings.Repeat("x", 9))
+	if err != nil {
+		t.Fatalf("expected the server to truncate the input, got %v", err)
+	}
+	if !truncated.Load() || vector[0] != 8 {
+		t.Errorf("expected a truncated embedding, got %v", vector)
+	}
+
+	dimensions, err := e.DetectDimensions(ctx)
+	if err != nil || dimensions != 3 {
+		t.Errorf("expected 3 dimensions, got %d, %v", dimensions, err)
+	}
+}
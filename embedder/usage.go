@@ -0,0 +1,427 @@
+package embedder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yoanbernabeu/grepai/store"
+	"github.com/yoanbernabeu/grepai/tokenizer"
+)
+
+// usageDateFormat is the layout of the days of a Usage; a month is a prefix.
+const usageDateFormat = "2006-01-02"
+
+// UsageCounters count the requests sent to an embedding provider.
+type UsageCounters struct {
+	Requests    int64 `json:"requests"`     // Requests sent, retries included
+	Tokens      int64 `json:"tokens"`       // Tokens of the inputs embedded
+	Retries     int64 `json:"retries"`      // Requests sent again after a failure
+	RateLimited int64 `json:"rate_limited"` // 429 responses
+}
+
+// Add adds other to the counters.
+func (c *UsageCounters) Add(other UsageCounters) {
+	c.Requests += other.Requests
+	c.Tokens += other.Tokens
+	c.Retries += other.Retries
+	c.RateLimited += other.RateLimited
+}
+
+// ModelUsage is the usage of a provider and model, split between indexing
+// and search queries.
+type ModelUsage struct {
+	Index UsageCounters `json:"index"`
+	Query UsageCounters `json:"query"`
+}
+
+// Total returns the usage of indexing and queries.
+func (m ModelUsage) Total() UsageCounters {
+	total := m.Index
+	total.Add(m.Query)
+	return total
+}
+
+// Usage is the embedding usage of a project, persisted in .grepai/usage.json:
+// by day (YYYY-MM-DD, local time), then by provider and model
+// ("openai:text-embedding-3-small").
+type Usage struct {
+	Days map[string]map[string]*ModelUsage `json:"days"`
+}
+
+// LoadUsage loads the usage at path. A missing file yields an empty usage.
+func LoadUsage(path string) (*Usage, error) {
+	u := &Usage{Days: make(map[string]map[string]*ModelUsage)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return u, nil
+		}
+		return nil, fmt.Errorf("failed to read embedding usage: %w", err)
+	}
+	if err := json.Unmarshal(data, u); err != nil {
+		return nil, fmt.Errorf("failed to parse embedding usage %s: %w", path, err)
+	}
+	if u.Days == nil {
+		u.Days = make(map[string]map[string]*ModelUsage)
+	}
+	return u, nil
+}
+
+// Sum returns the usage by provider and model over the days starting with
+// prefix: a day ("2026-10-18"), a month ("2026-10") or "" for all days.
+func (u *Usage) Sum(prefix string) map[string]ModelUsage {
+	sum := make(map[string]ModelUsage)
+	for day, models := range u.Days {
+		if !strings.HasPrefix(day, prefix) {
+			continue
+		}
+		for name, m := range models {
+			total := sum[name]
+			total.Index.Add(m.Index)
+			total.Query.Add(m.Query)
+			sum[name] = total
+		}
+	}
+	return sum
+}
+
+// Total returns the usage of all providers over the days starting with
+// prefix.
+func (u *Usage) Total(prefix string) UsageCounters {
+	var total UsageCounters
+	for _, m := range u.Sum(prefix) {
+		total.Add(m.Total())
+	}
+	return total
+}
+
+func (u *Usage) add(day, name string, query bool, c UsageCounters) {
+	models := u.Days[day]
+	if models == nil {
+		models = make(map[string]*ModelUsage)
+		u.Days[day] = models
+	}
+	m := models[name]
+	if m == nil {
+		m = &ModelUsage{}
+		models[name] = m
+	}
+	if query {
+		m.Query.Add(c)
+	} else {
+		m.Index.Add(c)
+	}
+}
+
+// save writes the usage atomically, through a temporary file of its own.
+func (u *Usage) save(path string) error {
+	data, err := json.MarshalIndent(u, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal embedding usage: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write embedding usage: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write embedding usage: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write embedding usage: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write embedding usage: %w", err)
+	}
+	return nil
+}
+
+// UsageTracker records the embedding usage of a project and enforces its
+// token budgets. Each request is merged into the file as it is on disk, so
+// that the processes of a project (index, watch, search, MCP) share it.
+type UsageTracker struct {
+	path          string
+	dailyTokens   int64 // 0 = no daily budget
+	monthlyTokens int64 // 0 = no monthly budget
+	now           func() time.Time
+
+	mu    sync.Mutex
+	usage *Usage // Usage of the file after the last request
+}
+
+// NewUsageTracker returns a tracker of the usage at path. Budgets of 0 tokens
+// are disabled. An unreadable usage file is logged and replaced on the next
+// request, so that it never prevents embedding.
+func NewUsageTracker(path string, dailyTokens, monthlyTokens int64) *UsageTracker {
+	usage, err := LoadUsage(path)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		usage = &Usage{Days: make(map[string]map[string]*ModelUsage)}
+	}
+	return &UsageTracker{
+		path:          path,
+		dailyTokens:   dailyTokens,
+		monthlyTokens: monthlyTokens,
+		now:           time.Now,
+		usage:         usage,
+	}
+}
+
+// reserve checks that embedding documents of tokens stays within the
+// budgets, against the usage on disk, and records the tokens in the same
+// locked section, so that concurrent requests and processes cannot all pass
+// the check. A failed request records the tokens back with a negative count.
+func (t *UsageTracker) reserve(name string, tokens int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var budgetErr error
+	err := store.WithFileLock(t.path+".lock", func() error {
+		t.reload()
+		if budgetErr = t.checkBudget(tokens); budgetErr != nil {
+			return nil
+		}
+		t.usage.add(t.now().Format(usageDateFormat), name, false, UsageCounters{Tokens: tokens})
+		return t.usage.save(t.path)
+	})
+	if err != nil {
+		// Without the file, the budgets are enforced with the usage last read
+		log.Printf("Warning: %v", err)
+		return t.checkBudget(tokens)
+	}
+	return budgetErr
+}
+
+// checkBudget returns a BudgetExceededError when embedding documents of
+// tokens would exceed a budget. The caller holds t.mu.
+func (t *UsageTracker) checkBudget(tokens int64) error {
+	now := t.now()
+	year, month, day := now.Date()
+	if t.dailyTokens > 0 {
+		if used := t.usage.Total(now.Format(usageDateFormat)).Tokens; used+tokens > t.dailyTokens {
+			return &BudgetExceededError{
+				Period: "daily",
+				Budget: t.dailyTokens,
+				Used:   used,
+				Resets: time.Date(year, month, day+1, 0, 0, 0, 0, now.Location()),
+			}
+		}
+	}
+	if t.monthlyTokens > 0 {
+		if used := t.usage.Total(now.Format("2006-01")).Tokens; used+tokens > t.monthlyTokens {
+			return &BudgetExceededError{
+				Period: "monthly",
+				Budget: t.monthlyTokens,
+				Used:   used,
+				Resets: time.Date(year, month+1, 1, 0, 0, 0, 0, now.Location()),
+			}
+		}
+	}
+	return nil
+}
+
+// record adds the usage of a request of the provider name and saves it,
+// holding a lock on the file so that concurrent processes do not lose each
+// other's requests. Saving is best-effort: failures are logged, not returned.
+func (t *UsageTracker) record(name string, query bool, c UsageCounters) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	err := store.WithFileLock(t.path+".lock", func() error {
+		t.reload()
+		t.usage.add(t.now().Format(usageDateFormat), name, query, c)
+		return t.usage.save(t.path)
+	})
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// reload reads the usage recorded by other processes since the last request.
+// The caller holds t.mu and the file lock.
+func (t *UsageTracker) reload() {
+	if current, err := LoadUsage(t.path); err != nil {
+		log.Printf("Warning: %v", err)
+	} else {
+		t.usage = current
+	}
+}
+
+// requestCounterKey carries the number of requests sent for a call of a
+// MeteredEmbedder, for providers that split a call into several requests.
+type requestCounterKey struct{}
+
+// countRequest counts a request sent to the provider for the MeteredEmbedder
+// call of ctx, if any.
+func countRequest(ctx context.Context) {
+	if counter, ok := ctx.Value(requestCounterKey{}).(*atomic.Int64); ok {
+		counter.Add(1)
+	}
+}
+
+// withRequestCounter returns ctx with a counter of the requests sent.
+func withRequestCounter(ctx context.Context) (context.Context, *atomic.Int64) {
+	counter := &atomic.Int64{}
+	return context.WithValue(ctx, requestCounterKey{}, counter), counter
+}
+
+// MeteredEmbedder decorates the Embedder of a provider to record its usage
+// and enforce the token budgets of the project. Documents are refused with a
+// BudgetExceededError once a budget is reached; search queries are recorded
+// but never refused. Tokens are counted with the configured tokenizer.
+type MeteredEmbedder struct {
+	inner     Embedder
+	tracker   *UsageTracker
+	name      string              // Provider and model, such as "openai:text-embedding-3-small"
+	tokenizer tokenizer.Tokenizer // nil = estimate
+}
+
+// meteredBatchEmbedder is a MeteredEmbedder over a BatchEmbedder.
+type meteredBatchEmbedder struct {
+	*MeteredEmbedder
+	batch BatchEmbedder
+}
+
+// NewMeteredEmbedder returns inner with its usage recorded by tracker under
+// name. The result implements BatchEmbedder when inner does.
+func NewMeteredEmbedder(inner Embedder, tracker *UsageTracker, name string, tok tokenizer.Tokenizer) Embedder {
+	m := &MeteredEmbedder{inner: inner, tracker: tracker, name: name, tokenizer: tok}
+	if batch, ok := inner.(BatchEmbedder); ok {
+		return &meteredBatchEmbedder{MeteredEmbedder: m, batch: batch}
+	}
+	return m
+}
+
+// Embed embeds a text.
+func (m *MeteredEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	var vector []float32
+	err := m.meter(ctx, []string{text}, func(ctx context.Context) (err error) {
+		vector, err = m.inner.Embed(ctx, text)
+		return err
+	})
+	return vector, err
+}
+
+// EmbedBatch embeds texts.
+func (m *MeteredEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	var vectors [][]float32
+	err := m.meter(ctx, texts, func(ctx context.Context) (err error) {
+		vectors, err = m.inner.EmbedBatch(ctx, texts)
+		return err
+	})
+	return vectors, err
+}
+
+// meter reserves the tokens of documents within the budgets, then records
+// the requests made by embed. Providers that split a call report each
+// request they send; a call of the others is one request.
+func (m *MeteredEmbedder) meter(ctx context.Context, texts []string, embed func(ctx context.Context) error) error {
+	query := isQuery(ctx)
+	tokens := m.countTokens(texts)
+	if !query {
+		if err := m.tracker.reserve(m.name, tokens); err != nil {
+			return err
+		}
+	}
+	ctx, requests := withRequestCounter(ctx)
+	err := embed(ctx)
+	c := UsageCounters{Requests: max(requests.Load(), 1)}
+	switch {
+	case err == nil && query:
+		c.Tokens = tokens
+	case err != nil && !query:
+		c.Tokens = -tokens // Release the reservation
+	}
+	if err != nil && isRateLimited(err) {
+		c.RateLimited = 1
+	}
+	m.tracker.record(m.name, query, c)
+	return err
+}
+
+// EmbedBatches embeds batches of documents once the budgets allow all of
+// them. Retries and 429 responses are counted from the progress reports of
+// the decorated embedder.
+func (m *meteredBatchEmbedder) EmbedBatches(ctx context.Context, batches []Batch, progress BatchProgress) ([]BatchResult, error) {
+	var tokens int64
+	for _, batch := range batches {
+		tokens += m.countTokens(batch.Contents())
+	}
+	if err := m.tracker.reserve(m.name, tokens); err != nil {
+		return nil, err
+	}
+
+	ctx, requests := withRequestCounter(ctx)
+	var retries, rateLimited atomic.Int64
+	results, err := m.batch.EmbedBatches(ctx, batches, func(batchIndex, totalBatches, completedChunks, totalChunks int, retrying bool, attempt int, statusCode int) {
+		if retrying {
+			retries.Add(1)
+			if statusCode == http.StatusTooManyRequests {
+				rateLimited.Add(1)
+			}
+		}
+		if progress != nil {
+			progress(batchIndex, totalBatches, completedChunks, totalChunks, retrying, attempt, statusCode)
+		}
+	})
+
+	c := UsageCounters{
+		Requests:    requests.Load(),
+		Retries:     retries.Load(),
+		RateLimited: rateLimited.Load(),
+	}
+	if c.Requests == 0 {
+		// The provider does not report its requests: one per batch and retry
+		c.Requests = int64(len(batches)) + retries.Load()
+	}
+	if err != nil {
+		c.Tokens = -tokens // Release the reservation
+		if isRateLimited(err) {
+			// The last attempt of a batch is not reported as a retry
+			c.RateLimited++
+		}
+	}
+	m.tracker.record(m.name, false, c)
+	return results, err
+}
+
+// countTokens returns the token count of texts.
+func (m *MeteredEmbedder) countTokens(texts []string) int64 {
+	var tokens int64
+	for _, text := range texts {
+		tokens += int64(CountTokens(m.tokenizer, text))
+	}
+	return tokens
+}
+
+// Dimensions returns the dimensions of the decorated embedder.
+func (m *MeteredEmbedder) Dimensions() int {
+	return m.inner.Dimensions()
+}
+
+// Ping checks the decorated embedder when it supports it.
+func (m *MeteredEmbedder) Ping(ctx context.Context) error {
+	return ping(ctx, m.inner)
+}
+
+// Close closes the decorated embedder.
+func (m *MeteredEmbedder) Close() error {
+	return m.inner.Close()
+}
+
+// isRateLimited reports whether err is a 429 response.
+func isRateLimited(err error) bool {
+	var retryErr *RetryableError
+	return errors.As(err, &retryErr) && retryErr.StatusCode == http.StatusTooManyRequests
+}
@@ -0,0 +1,245 @@
+package embedder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestUsageTracker(t *testing.T, path string, dailyTokens, monthlyTokens int64, now time.Time) *UsageTracker {
+	t.Helper()
+	tracker := NewUsageTracker(path, dailyTokens, monthlyTokens)
+	tracker.now = func() time.Time { return now }
+	return tracker
+}
+
+func TestUsageTracker_SharedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	day := time.Date(2026, 10, 18, 15, 0, 0, 0, time.Local)
+
+	// Two processes of the project record into the same file
+	first := newTestUsageTracker(t, path, 0, 0, day)
+	second := newTestUsageTracker(t, path, 0, 0, day)
+	first.record("openai:text-embedding-3-small", false, UsageCounters{Requests: 2, Tokens: 100, Retries: 1, RateLimited: 1})
+	second.record("openai:text-embedding-3-small", true, UsageCounters{Requests: 1, Tokens: 5})
+	second.now = func() time.Time { return day.AddDate(0, 0, -1) }
+	second.record("ollama:nomic-embed-text", false, UsageCounters{Requests: 1, Tokens: 40})
+
+	usage, err := LoadUsage(path)
+	if err != nil {
+		t.Fatalf("LoadUsage failed: %v", err)
+	}
+	today := usage.Sum("2026-10-18")["openai:text-embedding-3-small"]
+	if today.Index.Tokens != 100 || today.Index.Retries != 1 || today.Index.RateLimited != 1 || today.Query.Tokens != 5 {
+		t.Errorf("unexpected usage of today: %+v", today)
+	}
+	if total := usage.Total("2026-10"); total.Tokens != 145 || total.Requests != 4 {
+		t.Errorf("expected 145 tokens in 4 requests this month, got %+v", total)
+	}
+	if total := usage.Total("2026-10-17"); total.Tokens != 40 {
+		t.Errorf("expected 40 tokens yesterday, got %+v", total)
+	}
+}
+
+func TestUsageTracker_ConcurrentProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	now := time.Now()
+
+	// Each tracker stands for a process: only the file lock serializes them
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		tracker := newTestUsageTracker(t, path, 0, 0, now)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				tracker.record("openai:text-embedding-3-small", false, UsageCounters{Requests: 1, Tokens: 10})
+			}
+		}()
+	}
+	wg.Wait()
+
+	usage, err := LoadUsage(path)
+	if err != nil {
+		t.Fatalf("LoadUsage failed: %v", err)
+	}
+	if total := usage.Total(""); total.Requests != 80 || total.Tokens != 800 {
+		t.Errorf("expected 80 requests recorded, got %+v", total)
+	}
+}
+
+func TestUsageTracker_CorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tracker := newTestUsageTracker(t, path, 100, 0, time.Now())
+	tracker.record("openai:text-embedding-3-small", false, UsageCounters{Requests: 1, Tokens: 10})
+	usage, err := LoadUsage(path)
+	if err != nil {
+		t.Fatalf("expected the corrupt file to be replaced, got %v", err)
+	}
+	if total := usage.Total(""); total.Tokens != 10 {
+		t.Errorf("expected 10 tokens recorded, got %+v", total)
+	}
+}
+
+func TestMeteredEmbedder_Budgets(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 18, 15, 0, 0, 0, time.Local)
+	text := "func main() {}" // 4 estimated tokens
+
+	tests := []struct {
+		name          string
+		daily         int64
+		monthly       int64
+		period        string
+		resets        time.Time
+		usedThisMonth int64
+	}{
+		{"daily", 6, 0, "daily", time.Date(2026, 10, 19, 0, 0, 0, 0, time.Local), 0},
+		{"monthly", 0, 10, "monthly", time.Date(2026, 11, 1, 0, 0, 0, 0, time.Local), 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "usage.json")
+			tracker := newTestUsageTracker(t, path, tt.daily, tt.monthly, now)
+			if tt.usedThisMonth > 0 {
+				tracker.now = func() time.Time { return now.AddDate(0, 0, -10) }
+				tracker.record("openai:text-embedding-3-small", false, UsageCounters{Requests: 1, Tokens: tt.usedThisMonth})
+				tracker.now = func() time.Time { return now }
+			}
+			inner := &flakyEmbedder{value: 1}
+			emb := NewMeteredEmbedder(inner, tracker, "openai:text-embedding-3-small", nil)
+
+			if _, err := emb.EmbedBatch(ctx, []string{text}); err != nil {
+				t.Fatalf("expected the first request within the budget, got %v", err)
+			}
+			_, err := emb.Embed(ctx, text)
+			budgetErr := AsBudgetExceededError(err)
+			if budgetErr == nil {
+				t.Fatalf("expected a budget error, got %v", err)
+			}
+			if budgetErr.Period != tt.period || !budgetErr.Resets.Equal(tt.resets) {
+				t.Errorf("expected the %s budget to reset at %s, got %+v", tt.period, tt.resets, budgetErr)
+			}
+			if inner.calls.Load() != 1 {
+				t.Errorf("expected the refused request not to be sent, got %d calls", inner.calls.Load())
+			}
+
+			// Search queries are never refused
+			if _, err := EmbedQuery(ctx, emb, text); err != nil {
+				t.Errorf("expected queries over the budget to be embedded, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMeteredEmbedder_SharedBudget(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "usage.json")
+	now := time.Date(2026, 10, 18, 15, 0, 0, 0, time.Local)
+	text := "func main() {}" // 4 estimated tokens
+
+	// A long-lived process loads the usage before another one spends it
+	watch := NewMeteredEmbedder(&flakyEmbedder{value: 1}, newTestUsageTracker(t, path, 6, 0, now), "openai:text-embedding-3-small", nil)
+	index := NewMeteredEmbedder(&flakyEmbedder{value: 1}, newTestUsageTracker(t, path, 6, 0, now), "openai:text-embedding-3-small", nil)
+	if _, err := index.Embed(ctx, text); err != nil {
+		t.Fatalf("expected the first request within the budget, got %v", err)
+	}
+	if _, err := watch.Embed(ctx, text); AsBudgetExceededError(err) == nil {
+		t.Fatalf("expected the tokens of the other process to count, got %v", err)
+	}
+
+	// A failed request gives its reservation back
+	failing := NewMeteredEmbedder(&flakyEmbedder{value: 1, err: errDown}, newTestUsageTracker(t, path, 0, 0, now), "openai:text-embedding-3-small", nil)
+	if _, err := failing.Embed(ctx, text); err == nil {
+		t.Fatal("expected the request to fail")
+	}
+	usage, err := LoadUsage(path)
+	if err != nil {
+		t.Fatalf("LoadUsage failed: %v", err)
+	}
+	if total := usage.Total(""); total.Tokens != 4 || total.Requests != 2 {
+		t.Errorf("expected 4 tokens in 2 requests, got %+v", total)
+	}
+}
+
+// retryingBatchEmbedder reports a rate limited retry for each batch.
+type retryingBatchEmbedder struct {
+	flakyEmbedder
+}
+
+func (e *retryingBatchEmbedder) EmbedBatches(ctx context.Context, batches []Batch, progress BatchProgress) ([]BatchResult, error) {
+	results := make([]BatchResult, len(batches))
+	for i, batch := range batches {
+		progress(batch.Index, len(batches), 0, 0, true, 1, 429)
+		vectors, err := e.EmbedBatch(ctx, batch.Contents())
+		if err != nil {
+			return nil, err
+		}
+		results[i] = BatchResult{BatchIndex: i, Embeddings: vectors}
+	}
+	return results, nil
+}
+
+// splittingBatchEmbedder sends the batches in requests of two texts, like
+// the local providers.
+type splittingBatchEmbedder struct {
+	flakyEmbedder
+}
+
+func (e *splittingBatchEmbedder) EmbedBatches(ctx context.Context, batches []Batch, progress BatchProgress) ([]BatchResult, error) {
+	return embedBatchesParallel(ctx, batches, progress, 2, 1, DefaultRetryPolicy(), e.EmbedBatch)
+}
+
+func TestMeteredEmbedder_SplitRequests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	tracker := newTestUsageTracker(t, path, 0, 0, time.Now())
+	emb := NewMeteredEmbedder(&splittingBatchEmbedder{flakyEmbedder{value: 1}}, tracker, "ollama:nomic-embed-text", nil)
+
+	batches := []Batch{{Index: 0, Entries: []BatchEntry{{Content: "a"}, {Content: "b"}, {Content: "c"}, {Content: "d"}, {Content: "e"}}}}
+	if _, err := emb.(BatchEmbedder).EmbedBatches(context.Background(), batches, nil); err != nil {
+		t.Fatalf("EmbedBatches failed: %v", err)
+	}
+
+	usage, err := LoadUsage(path)
+	if err != nil {
+		t.Fatalf("LoadUsage failed: %v", err)
+	}
+	if got := usage.Total("").Requests; got != 3 {
+		t.Errorf("expected a batch of 5 texts sent in 3 requests, got %d", got)
+	}
+}
+
+func TestMeteredEmbedder_EmbedBatches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	tracker := newTestUsageTracker(t, path, 0, 0, time.Now())
+	emb := NewMeteredEmbedder(&retryingBatchEmbedder{flakyEmbedder{value: 1}}, tracker, "openai:text-embedding-3-small", nil)
+	batchEmb, ok := emb.(BatchEmbedder)
+	if !ok {
+		t.Fatal("expected a BatchEmbedder over a batch embedder")
+	}
+
+	batches := []Batch{
+		{Index: 0, Entries: []BatchEntry{{Content: "func a() {}"}, {Content: "func b() {}"}}},
+		{Index: 1, Entries: []BatchEntry{{Content: "func c() {}"}}},
+	}
+	if _, err := batchEmb.EmbedBatches(context.Background(), batches, nil); err != nil {
+		t.Fatalf("EmbedBatches failed: %v", err)
+	}
+
+	usage, err := LoadUsage(path)
+	if err != nil {
+		t.Fatalf("LoadUsage failed: %v", err)
+	}
+	got := usage.Sum("")["openai:text-embedding-3-small"].Index
+	want := UsageCounters{Requests: 4, Tokens: 3 * int64(EstimateTokens("func a() {}")), Retries: 2, RateLimited: 2}
+	if got != want {
+		t.Errorf("usage = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,319 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// uncommittedSHA is the commit git blame reports for lines not committed yet.
+const uncommittedSHA = "0000000000000000000000000000000000000000"
+
+// BlameInfo describes who last changed a range of lines and how often the
+// file changes.
+type BlameInfo struct {
+	SHA         string    `json:"sha"` // Most recent commit of the range, "" when not committed yet
+	Author      string    `json:"author"`
+	Email       string    `json:"email,omitempty"`
+	Date        time.Time `json:"date"`
+	Uncommitted bool      `json:"uncommitted,omitempty"` // Some lines have uncommitted changes
+	Churn       int       `json:"churn"`                 // Commits that touched the file in the churn window
+	ChurnDays   int       `json:"churn_days"`            // Length of the churn window
+}
+
+// BlameCommit is a commit that last changed some lines of a file.
+type BlameCommit struct {
+	SHA    string
+	Author string
+	Email  string
+	Date   time.Time
+}
+
+// FileBlame is the blame of every line of a file.
+type FileBlame struct {
+	Commits []BlameCommit
+	Lines   []int // Index in Commits of the commit of each line (line n at n-1)
+}
+
+// Range returns the most recent commit among lines start to end (1-based,
+// inclusive, end <= 0 for the end of the file) and whether some of them are
+// not committed yet. The commit is zero when no line is committed. ok is
+// false when the range has no lines.
+func (b *FileBlame) Range(start, end int) (commit BlameCommit, uncommitted bool, ok bool) {
+	if start < 1 {
+		start = 1
+	}
+	if end <= 0 || end > len(b.Lines) {
+		end = len(b.Lines)
+	}
+	for line := start; line <= end; line++ {
+		c := b.Commits[b.Lines[line-1]]
+		if c.SHA == uncommittedSHA {
+			uncommitted = true
+			continue
+		}
+		if !ok || c.Date.After(commit.Date) {
+			commit, ok = c, true
+		}
+	}
+	if uncommitted && !ok {
+		return BlameCommit{}, true, true
+	}
+	return commit, uncommitted, ok
+}
+
+// BlameFile runs git blame --porcelain on a file of the working tree at dir.
+func BlameFile(ctx context.Context, dir, path string) (*FileBlame, error) {
+	out, err := exec.CommandContext(ctx, "git", "-C", dir, "blame", "--porcelain", "--", path).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to blame %s: %w", path, gitError(err))
+	}
+	return parseBlame(out)
+}
+
+// parseBlame parses git blame --porcelain output. Commit details are only
+// given the first time a commit appears.
+func parseBlame(out []byte) (*FileBlame, error) {
+	blame := &FileBlame{}
+	index := make(map[string]int)
+	current := -1
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "\t") {
+			if current < 0 {
+				return nil, fmt.Errorf("unexpected git blame output")
+			}
+			blame.Lines = append(blame.Lines, current)
+			continue
+		}
+		key, value, _ := strings.Cut(line, " ")
+		if len(key) == 40 && isHex(key) {
+			i, ok := index[key]
+			if !ok {
+				i = len(blame.Commits)
+				index[key] = i
+				blame.Commits = append(blame.Commits, BlameCommit{SHA: key})
+			}
+			current = i
+			continue
+		}
+		if current < 0 {
+			continue
+		}
+		commit := &blame.Commits[current]
+		switch key {
+		case "author":
+			commit.Author = value
+		case "author-mail":
+			commit.Email = strings.Trim(value, "<>")
+		case "author-time":
+			if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+				commit.Date = time.Unix(seconds, 0)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read git blame output: %w", err)
+	}
+	return blame, nil
+}
+
+func isHex(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Churn returns the number of commits that touched a file in the last days.
+func Churn(ctx context.Context, dir, path string, days int) (int, error) {
+	out, err := runGit(ctx, dir, "log", "--no-merges", "--format=%H", fmt.Sprintf("--since=%d.days.ago", days), "--", path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count changes of %s: %w", path, err)
+	}
+	if out == "" {
+		return 0, nil
+	}
+	return strings.Count(out, "\n") + 1, nil
+}
+
+// blameCacheTTL bounds the age of cached churn counts, which change as
+// days pass even when the file does not.
+const blameCacheTTL = 24 * time.Hour
+
+// BlameCache computes blame and churn of the files of a working tree and
+// caches them by file content hash, so that only changed files are blamed
+// again. It is safe for concurrent use.
+type BlameCache struct {
+	path      string
+	dir       string
+	churnDays int
+
+	mu      sync.Mutex
+	entries map[string]*blameEntry
+	dirty   bool
+}
+
+type blameEntry struct {
+	Hash       string // SHA-256 of the file content
+	Head       string // HEAD when computed, checked when lines were uncommitted
+	ComputedAt time.Time
+	Blame      *FileBlame // nil for files git does not track
+	Churn      int
+}
+
+// NewBlameCache returns a cache of blame data for the working tree at dir,
+// persisted at path. churnDays is the window of churn counts.
+func NewBlameCache(path, dir string, churnDays int) *BlameCache {
+	return &BlameCache{path: path, dir: dir, churnDays: churnDays, entries: make(map[string]*blameEntry)}
+}
+
+// Load reads the cache from disk. A missing or unreadable cache is ignored.
+func (c *BlameCache) Load() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	file, err := os.Open(c.path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	var entries map[string]*blameEntry
+	if err := gob.NewDecoder(file).Decode(&entries); err == nil && entries != nil {
+		c.entries = entries
+	}
+}
+
+// Persist writes the cache to disk if it changed.
+func (c *BlameCache) Persist() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create blame cache directory: %w", err)
+	}
+	tmpPath := c.path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create blame cache: %w", err)
+	}
+	if err := gob.NewEncoder(file).Encode(c.entries); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to encode blame cache: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write blame cache: %w", err)
+	}
+	if err := os.Rename(tmpPath, c.path); err != nil {
+		return fmt.Errorf("failed to write blame cache: %w", err)
+	}
+	c.dirty = false
+	return nil
+}
+
+// Lookup returns the blame of lines start to end (1-based, inclusive) of a
+// file relative to the working tree, and the churn of the file. start <= 0
+// covers the whole file. It returns nil for files git does not track.
+func (c *BlameCache) Lookup(ctx context.Context, path string, start, end int) (*BlameInfo, error) {
+	entry, err := c.entry(ctx, path)
+	if err != nil || entry.Blame == nil {
+		return nil, err
+	}
+	if start <= 0 {
+		start, end = 1, 0
+	}
+	commit, uncommitted, ok := entry.Blame.Range(start, end)
+	if !ok {
+		return nil, nil
+	}
+	info := &BlameInfo{
+		SHA:         commit.SHA,
+		Author:      commit.Author,
+		Email:       commit.Email,
+		Date:        commit.Date,
+		Uncommitted: uncommitted,
+		Churn:       entry.Churn,
+		ChurnDays:   c.churnDays,
+	}
+	if commit.SHA == "" {
+		info.Author = "Not Committed Yet"
+	}
+	return info, nil
+}
+
+// entry returns the cached blame of a file, computing it when the file
+// changed, the cache expired, or uncommitted lines may have been committed.
+func (c *BlameCache) entry(ctx context.Context, path string) (*blameEntry, error) {
+	content, err := os.ReadFile(filepath.Join(c.dir, filepath.FromSlash(path)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+
+	c.mu.Lock()
+	cached := c.entries[path]
+	c.mu.Unlock()
+
+	var head string
+	if cached != nil && cached.Hash == hash && time.Since(cached.ComputedAt) < blameCacheTTL {
+		if cached.Head == "" {
+			return cached, nil
+		}
+		head, _ = ResolveCommit(ctx, c.dir, "HEAD")
+		if head == cached.Head {
+			return cached, nil
+		}
+	}
+
+	entry := &blameEntry{Hash: hash, ComputedAt: time.Now()}
+	blame, err := BlameFile(ctx, c.dir, path)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err == nil {
+		entry.Blame = blame
+		if entry.Churn, err = Churn(ctx, c.dir, path, c.churnDays); err != nil {
+			return nil, err
+		}
+	}
+	// Files git does not track yet and uncommitted lines are only cached until
+	// the next commit
+	uncommitted := entry.Blame == nil
+	if !uncommitted {
+		_, uncommitted, _ = entry.Blame.Range(1, 0)
+	}
+	if uncommitted {
+		if head == "" {
+			head, _ = ResolveCommit(ctx, c.dir, "HEAD")
+		}
+		entry.Head = head
+	}
+
+	c.mu.Lock()
+	c.entries[path] = entry
+	c.dirty = true
+	c.mu.Unlock()
+	return entry, nil
+}
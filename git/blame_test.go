@@ -0,0 +1,103 @@
+package git
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseBlame(t *testing.T) {
+	out := "1111111111111111111111111111111111111111 1 1 2\n" +
+		"author Alice\n" +
+		"author-mail <alice@example.com>\n" +
+		"author-time 1577836800\n" +
+		"summary Add main\n" +
+		"filename main.go\n" +
+		"\tpackage main\n" +
+		"1111111111111111111111111111111111111111 2 2\n" +
+		"\t\n" +
+		uncommittedSHA + " 3 3 1\n" +
+		"author Not Committed Yet\n" +
+		"author-time 1893456000\n" +
+		"filename main.go\n" +
+		"\tfunc main() {}\n"
+
+	blame, err := parseBlame([]byte(out))
+	if err != nil {
+		t.Fatalf("parseBlame failed: %v", err)
+	}
+	if len(blame.Commits) != 2 || len(blame.Lines) != 3 {
+		t.Fatalf("expected 2 commits and 3 lines, got %+v", blame)
+	}
+	alice := blame.Commits[0]
+	if alice.Author != "Alice" || alice.Email != "alice@example.com" || alice.Date.Unix() != 1577836800 {
+		t.Errorf("unexpected commit %+v", alice)
+	}
+
+	commit, uncommitted, ok := blame.Range(1, 2)
+	if !ok || uncommitted || commit.SHA != alice.SHA {
+		t.Errorf("expected Alice's commit for lines 1-2, got %+v (uncommitted %v)", commit, uncommitted)
+	}
+	commit, uncommitted, ok = blame.Range(1, 0)
+	if !ok || !uncommitted || commit.SHA != alice.SHA {
+		t.Errorf("expected Alice's commit with uncommitted lines, got %+v (uncommitted %v)", commit, uncommitted)
+	}
+	if commit, uncommitted, ok = blame.Range(3, 3); !ok || !uncommitted || commit.SHA != "" {
+		t.Errorf("expected no commit for an uncommitted line, got %+v", commit)
+	}
+	if _, _, ok = blame.Range(4, 10); ok {
+		t.Error("expected no lines past the end of the file")
+	}
+}
+
+func TestBlameCache(t *testing.T) {
+	ctx := context.Background()
+	repo := t.TempDir()
+	setupGitRepo(t, repo)
+	writeRepoFile(t, repo, "app/main.go", "package app\n\nfunc A() {}\n\nfunc B() {}\n", 0644)
+	gitRun(t, repo, "add", "-A")
+	gitRun(t, repo, "commit", "-m", "Add app", "--date", "2020-01-01T00:00:00Z")
+	writeRepoFile(t, repo, "app/main.go", "package app\n\nfunc A() {}\n\nfunc B() { A() }\n", 0644)
+	gitRun(t, repo, "commit", "-am", "Call A from B")
+
+	path := filepath.Join(t.TempDir(), "blame.gob")
+	cache := NewBlameCache(path, repo, 90)
+
+	old, err := cache.Lookup(ctx, "app/main.go", 1, 3)
+	if err != nil || old == nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if old.Author != "Test" || old.Date.Year() != 2020 || old.Uncommitted {
+		t.Errorf("expected the first commit for lines 1-3, got %+v", old)
+	}
+	// Both commits were made now, whatever their author date
+	if old.Churn != 2 || old.ChurnDays != 90 {
+		t.Errorf("expected 2 changes in 90 days, got %d in %d", old.Churn, old.ChurnDays)
+	}
+	recent, _ := cache.Lookup(ctx, "app/main.go", 0, 0)
+	if recent == nil || recent.SHA == old.SHA || time.Since(recent.Date) > time.Hour {
+		t.Errorf("expected the last commit for the whole file, got %+v", recent)
+	}
+
+	// Untracked files have no blame
+	writeRepoFile(t, repo, "app/new.go", "package app\n", 0644)
+	if info, err := cache.Lookup(ctx, "app/new.go", 1, 1); err != nil || info != nil {
+		t.Errorf("expected no blame for an untracked file, got %+v (%v)", info, err)
+	}
+
+	// The cache survives a reload and notices changed files
+	if err := cache.Persist(); err != nil {
+		t.Fatalf("Persist failed: %v", err)
+	}
+	writeRepoFile(t, repo, "app/main.go", "package app\n\nfunc A() { B() }\n\nfunc B() { A() }\n", 0644)
+	reloaded := NewBlameCache(path, repo, 90)
+	reloaded.Load()
+	if len(reloaded.entries) != 2 {
+		t.Errorf("expected 2 cached files, got %d", len(reloaded.entries))
+	}
+	changed, err := reloaded.Lookup(ctx, "app/main.go", 3, 3)
+	if err != nil || changed == nil || !changed.Uncommitted || changed.SHA != "" || changed.Author != "Not Committed Yet" {
+		t.Errorf("expected an uncommitted line, got %+v (%v)", changed, err)
+	}
+}
@@ -0,0 +1,101 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DetachedHead is the branch name reported for a detached HEAD.
+const DetachedHead = "HEAD"
+
+// CurrentBranch returns the branch checked out at path, or DetachedHead when
+// HEAD is detached (e.g. during a rebase).
+func CurrentBranch(path string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	branch, err := runGit(ctx, path, "symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return DetachedHead, nil
+		}
+		return "", fmt.Errorf("failed to read current branch: %w", err)
+	}
+	return branch, nil
+}
+
+// DefaultBranch returns the default branch of the repository at path: the
+// branch origin/HEAD points to, else main or master when they exist, else
+// the current branch.
+func DefaultBranch(path string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if remoteHead, err := runGit(ctx, path, "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		if _, branch, ok := strings.Cut(remoteHead, "/"); ok && branch != "" {
+			return branch, nil
+		}
+	}
+	for _, branch := range []string{"main", "master"} {
+		if _, err := runGit(ctx, path, "show-ref", "--verify", "--quiet", "refs/heads/"+branch); err == nil {
+			return branch, nil
+		}
+	}
+	return CurrentBranch(path)
+}
+
+// Branches returns the local branches of the repository at path.
+func Branches(path string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	out, err := runGit(ctx, path, "for-each-ref", "--format=%(refname:short)", "refs/heads")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// Head reads the branch checked out in a worktree from its HEAD file, which
+// is much cheaper than running git when checked on every file event.
+type Head struct {
+	path string
+}
+
+// OpenHead locates the HEAD file of the worktree containing path.
+func OpenHead(path string) (*Head, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	headPath, err := runGit(ctx, path, "rev-parse", "--git-path", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate HEAD: %w", err)
+	}
+	if !filepath.IsAbs(headPath) {
+		headPath = filepath.Join(path, headPath)
+	}
+	return &Head{path: headPath}, nil
+}
+
+// Branch returns the branch HEAD points to, or DetachedHead.
+func (h *Head) Branch() (string, error) {
+	data, err := os.ReadFile(h.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read HEAD: %w", err)
+	}
+	ref, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "ref: ")
+	if !ok {
+		return DetachedHead, nil
+	}
+	return strings.TrimPrefix(ref, "refs/heads/"), nil
+}
@@ -0,0 +1,136 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// LineRange is a range of lines, 1-based and inclusive.
+type LineRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Diff returns the changes to the files under dir, with paths relative to
+// it. rng is either "<base>..<head>", compared commit to commit, or a single
+// revision compared to the working tree ("" for HEAD). With staged, the
+// index is compared to rng ("" for HEAD) instead. Patches have no context
+// lines and untracked files are not included.
+func Diff(ctx context.Context, dir, rng string, staged bool) ([]FileDiff, error) {
+	args := []string{
+		"-C", dir, "-c", "core.quotePath=false",
+		"diff", "--no-color", "--no-ext-diff", "--unified=0", "--find-renames", "--relative",
+	}
+	if staged {
+		args = append(args, "--cached")
+	}
+	if rng == "" {
+		rng = "HEAD"
+	}
+	if err := ValidateRevision(rng); err != nil {
+		return nil, err
+	}
+	out, err := exec.CommandContext(ctx, "git", append(args, rng, "--")...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff %s: %w", rng, gitError(err))
+	}
+	return ParsePatch(string(out)), nil
+}
+
+// DescribeDiff names the changes Diff compares for rng and staged.
+func DescribeDiff(rng string, staged bool) string {
+	switch {
+	case staged && rng == "":
+		return "staged changes"
+	case staged:
+		return "staged changes against " + rng
+	case rng == "":
+		return "uncommitted changes"
+	case strings.Contains(rng, ".."):
+		return rng
+	}
+	return "working tree against " + rng
+}
+
+// Hunk is a hunk of a patch.
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Added    []string // Added lines, without their "+"
+	Removed  []string // Removed lines, without their "-"
+}
+
+// Range returns the lines of the new version of the file the hunk adds or
+// modifies. A hunk that only removes lines covers the line before them, so
+// that the code around a deletion counts as changed.
+func (h Hunk) Range() LineRange {
+	if h.NewLines == 0 {
+		line := max(h.NewStart, 1)
+		return LineRange{Start: line, End: line}
+	}
+	return LineRange{Start: h.NewStart, End: h.NewStart + h.NewLines - 1}
+}
+
+// ParseHunks splits the patch of a file into hunks.
+func ParseHunks(patch string) []Hunk {
+	var hunks []Hunk
+	var current *Hunk
+	for _, line := range strings.Split(patch, "\n") {
+		switch {
+		case strings.HasPrefix(line, "@@ "):
+			// @@ -old[,count] +new[,count] @@ context
+			fields := strings.Fields(line)
+			if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+				current = nil
+				continue
+			}
+			oldStart, oldLines, okOld := parseHunkRange(strings.TrimPrefix(fields[1], "-"))
+			newStart, newLines, okNew := parseHunkRange(strings.TrimPrefix(fields[2], "+"))
+			if !okOld || !okNew {
+				current = nil
+				continue
+			}
+			hunks = append(hunks, Hunk{OldStart: oldStart, OldLines: oldLines, NewStart: newStart, NewLines: newLines})
+			current = &hunks[len(hunks)-1]
+		case current == nil:
+		case strings.HasPrefix(line, "+"):
+			current.Added = append(current.Added, line[1:])
+		case strings.HasPrefix(line, "-"):
+			current.Removed = append(current.Removed, line[1:])
+		}
+	}
+	return hunks
+}
+
+// ChangedLines returns the line ranges of the hunks of a patch, see
+// Hunk.Range.
+func ChangedLines(patch string) []LineRange {
+	hunks := ParseHunks(patch)
+	ranges := make([]LineRange, len(hunks))
+	for i, h := range hunks {
+		ranges[i] = h.Range()
+	}
+	return ranges
+}
+
+// parseHunkRange parses the "start[,count]" of a hunk header, count
+// defaulting to 1.
+func parseHunkRange(s string) (start, count int, ok bool) {
+	startStr, countStr, hasCount := strings.Cut(s, ",")
+	start, err := strconv.Atoi(startStr)
+	if err != nil {
+		return 0, 0, false
+	}
+	count = 1
+	if hasCount {
+		if count, err = strconv.Atoi(countStr); err != nil {
+			return 0, 0, false
+		}
+	}
+	return start, count, true
+}
@@ -0,0 +1,82 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChangedLines(t *testing.T) {
+	patch := "@@ -3 +3,2 @@ func A() {\n-a\n+b\n+c\n@@ -10,2 +11,0 @@\n-d\n-e\n@@ -0,0 +1 @@\n+f\n"
+	got := ChangedLines(patch)
+	want := []LineRange{{3, 4}, {11, 11}, {1, 1}}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestParseHunks(t *testing.T) {
+	patch := "@@ -3 +3,2 @@ func A() {\n-a\n+b\n+c\n\\ No newline at end of file\n@@ -10,2 +11,0 @@\n-d\n-e\n"
+	hunks := ParseHunks(patch)
+	if len(hunks) != 2 {
+		t.Fatalf("expected 2 hunks, got %+v", hunks)
+	}
+	if h := hunks[0]; len(h.Removed) != 1 || h.Removed[0] != "a" || len(h.Added) != 2 || h.Added[1] != "c" {
+		t.Errorf("unexpected lines of the first hunk: %+v", h)
+	}
+	if r := hunks[1].Range(); r != (LineRange{11, 11}) || len(hunks[1].Removed) != 2 {
+		t.Errorf("expected a deletion at line 11, got %+v", hunks[1])
+	}
+}
+
+func TestDiff(t *testing.T) {
+	ctx := context.Background()
+	repo := t.TempDir()
+	setupGitRepo(t, repo)
+	writeRepoFile(t, repo, "app/main.go", "package app\n\nfunc A() {}\n", 0644)
+	gitRun(t, repo, "add", "-A")
+	gitRun(t, repo, "commit", "-m", "Add app")
+	writeRepoFile(t, repo, "app/main.go", "package app\n\nfunc A() { B() }\n", 0644)
+	gitRun(t, repo, "add", "-A")
+	writeRepoFile(t, repo, "app/other.go", "package app\n", 0644)
+	gitRun(t, repo, "add", "app/other.go")
+	gitRun(t, repo, "commit", "-m", "Call B")
+	writeRepoFile(t, repo, "app/main.go", "package app\n\nfunc A() { B() }\n\nfunc B() {}\n", 0644)
+
+	uncommitted, err := Diff(ctx, repo, "", false)
+	if err != nil || len(uncommitted) != 1 || uncommitted[0].Path != "app/main.go" {
+		t.Fatalf("expected the uncommitted change of main.go, got %+v (%v)", uncommitted, err)
+	}
+	if lines := ChangedLines(uncommitted[0].Patch); len(lines) != 1 || lines[0] != (LineRange{4, 5}) {
+		t.Errorf("expected lines 4-5 added, got %v", lines)
+	}
+
+	if staged, err := Diff(ctx, repo, "", true); err != nil || len(staged) != 0 {
+		t.Errorf("expected nothing staged, got %+v (%v)", staged, err)
+	}
+
+	committed, err := Diff(ctx, repo+"/app", "HEAD~1..HEAD", false)
+	if err != nil || len(committed) != 2 || committed[0].Path != "main.go" || committed[1].Status != "added" {
+		t.Fatalf("expected main.go and other.go relative to app, got %+v (%v)", committed, err)
+	}
+	if lines := ChangedLines(committed[0].Patch); len(lines) != 1 || lines[0] != (LineRange{3, 3}) {
+		t.Errorf("expected line 3 changed, got %v", lines)
+	}
+
+	// Ranges must not be parsed as options of git diff
+	output := filepath.Join(t.TempDir(), "out")
+	for _, rng := range []string{"--output=" + output, "HEAD..--output=" + output, "HEAD...-p"} {
+		if _, err := Diff(ctx, repo, rng, false); err == nil {
+			t.Errorf("expected %q to be rejected", rng)
+		}
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("expected no file written by git diff, got %v", err)
+	}
+}
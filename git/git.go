@@ -98,3 +98,15 @@ func IsGitRepo(path string) bool {
 	err := cmd.Run()
 	return err == nil
 }
+
+// ValidateRevision rejects a revision or range ("<base>..<head>",
+// "<base>...<head>") that git would parse as an option, such as
+// "--output=<file>".
+func ValidateRevision(rev string) error {
+	for _, part := range strings.Split(rev, "..") {
+		if strings.HasPrefix(strings.TrimPrefix(part, "."), "-") {
+			return fmt.Errorf("invalid git revision %q", rev)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Commit is a commit with the changes it made to each file.
+type Commit struct {
+	SHA     string
+	Author  string
+	Email   string
+	Date    time.Time // Author date
+	Message string    // Full message, subject first
+	Files   []FileDiff
+}
+
+// Subject returns the first line of the commit message.
+func (c *Commit) Subject() string {
+	subject, _, _ := strings.Cut(c.Message, "\n")
+	return subject
+}
+
+// FileDiff is the change a commit made to one file.
+type FileDiff struct {
+	Path      string // Path after the change, the old path for deleted files
+	OldPath   string // Path before a rename, "" otherwise
+	Status    string // added, deleted, renamed or modified
+	Binary    bool
+	Additions int
+	Deletions int
+	Patch     string // Hunks without the file header, "" for binary files
+}
+
+// recordSep starts each commit in the output of ShowCommits. The header
+// fields of a commit are separated by NUL.
+const recordSep = "\x1e"
+
+// RevList returns the commits reachable from rev that touched files under
+// dir, newest first, skipping merge commits. max <= 0 returns them all.
+func RevList(ctx context.Context, dir, rev string, max int) ([]string, error) {
+	if err := ValidateRevision(rev); err != nil {
+		return nil, err
+	}
+	args := []string{"rev-list", "--no-merges"}
+	if max > 0 {
+		args = append(args, "--max-count="+strconv.Itoa(max))
+	}
+	out, err := runGit(ctx, dir, append(args, rev, "--", ".")...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits of %s: %w", rev, err)
+	}
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// ResolveCommit returns the commit SHA rev points to.
+func ResolveCommit(ctx context.Context, dir, rev string) (string, error) {
+	if err := ValidateRevision(rev); err != nil {
+		return "", err
+	}
+	sha, err := runGit(ctx, dir, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("unknown revision %s: %w", rev, err)
+	}
+	return sha, nil
+}
+
+// ShowCommits reads the metadata and patches of the given commits with a
+// single git show. Patches have no context lines and only cover files under
+// dir, with paths relative to it.
+func ShowCommits(ctx context.Context, dir string, shas []string) ([]Commit, error) {
+	if len(shas) == 0 {
+		return nil, nil
+	}
+	for _, sha := range shas {
+		if err := ValidateRevision(sha); err != nil {
+			return nil, err
+		}
+	}
+	args := []string{
+		"-C", dir, "-c", "core.quotePath=false",
+		"show", "--no-color", "--no-ext-diff", "--unified=0", "--find-renames", "--relative",
+		"--format=" + recordSep + "%H%x00%an%x00%ae%x00%aI%x00%B%x00",
+	}
+	out, err := exec.CommandContext(ctx, "git", append(append(args, shas...), "--")...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commits: %w", gitError(err))
+	}
+	return parseShow(out)
+}
+
+// parseShow parses the output of ShowCommits.
+func parseShow(out []byte) ([]Commit, error) {
+	var commits []Commit
+	for _, record := range bytes.Split(out, []byte(recordSep)) {
+		if len(bytes.TrimSpace(record)) == 0 {
+			continue
+		}
+		fields := bytes.SplitN(record, []byte{0}, 6)
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("unexpected git show output")
+		}
+		date, err := time.Parse(time.RFC3339, string(fields[3]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid date of commit %s: %w", fields[0], err)
+		}
+		commits = append(commits, Commit{
+			SHA:     string(fields[0]),
+			Author:  string(fields[1]),
+			Email:   string(fields[2]),
+			Date:    date,
+			Message: strings.TrimSpace(string(fields[4])),
+			Files:   ParsePatch(string(fields[5])),
+		})
+	}
+	return commits, nil
+}
+
+// ParsePatch splits a unified diff produced by git into per-file changes.
+func ParsePatch(patch string) []FileDiff {
+	var files []FileDiff
+	var current *FileDiff
+	var hunks strings.Builder
+	flush := func() {
+		if current != nil {
+			current.Patch = hunks.String()
+			files = append(files, *current)
+		}
+		hunks.Reset()
+	}
+
+	inHunk := false
+	for _, line := range strings.Split(patch, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			flush()
+			current = &FileDiff{Path: diffHeaderPath(line), Status: "modified"}
+			inHunk = false
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if !inHunk {
+			switch {
+			case strings.HasPrefix(line, "new file mode"):
+				current.Status = "added"
+			case strings.HasPrefix(line, "deleted file mode"):
+				current.Status = "deleted"
+			case strings.HasPrefix(line, "rename from "):
+				current.OldPath = unquotePath(strings.TrimPrefix(line, "rename from "))
+				current.Status = "renamed"
+			case strings.HasPrefix(line, "rename to "):
+				current.Path = unquotePath(strings.TrimPrefix(line, "rename to "))
+			case strings.HasPrefix(line, "Binary files "):
+				current.Binary = true
+			case strings.HasPrefix(line, "+++ "):
+				if path := strings.TrimPrefix(line, "+++ "); path != "/dev/null" {
+					current.Path = strings.TrimPrefix(unquotePath(path), "b/")
+				}
+			case strings.HasPrefix(line, "@@"):
+				inHunk = true
+			}
+			if !inHunk {
+				continue
+			}
+		}
+		switch {
+		case strings.HasPrefix(line, "+"):
+			current.Additions++
+		case strings.HasPrefix(line, "-"):
+			current.Deletions++
+		case strings.HasPrefix(line, "@@"):
+		case strings.HasPrefix(line, `\`): // \ No newline at end of file
+			continue
+		default:
+			if line == "" {
+				continue
+			}
+		}
+		hunks.WriteString(line)
+		hunks.WriteByte('\n')
+	}
+	flush()
+	return files
+}
+
+// diffHeaderPath returns the path of the "b/" side of a diff --git header.
+// Renames and paths with spaces are corrected by the lines that follow.
+func diffHeaderPath(line string) string {
+	header := strings.TrimPrefix(line, "diff --git ")
+	if i := strings.LastIndex(header, " b/"); i >= 0 {
+		return header[i+3:]
+	}
+	return unquotePath(header)
+}
+
+// unquotePath removes the quotes git puts around unusual paths.
+func unquotePath(path string) string {
+	if unquoted, err := strconv.Unquote(path); err == nil {
+		return unquoted
+	}
+	return path
+}
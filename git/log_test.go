@@ -0,0 +1,117 @@
+package git
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParsePatch(t *testing.T) {
+	patch := `
+diff --git a/auth/session.go b/auth/session.go
+index 1111111..2222222 100644
+--- a/auth/session.go
++++ b/auth/session.go
+@@ -10 +10,2 @@ func Expire() {
+-	ttl := time.Hour
++	ttl := 30 * time.Minute
++	// Sliding expiration
+diff --git a/old name.go b/new name.go
+similarity index 90%
+rename from old name.go
+rename to new name.go
+diff --git a/logo.png b/logo.png
+new file mode 100644
+index 0000000..3333333
+Binary files /dev/null and b/logo.png differ
+diff --git a/gone.go b/gone.go
+deleted file mode 100644
+index 4444444..0000000
+--- a/gone.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package gone
+\ No newline at end of file
+`
+	files := ParsePatch(patch)
+	if len(files) != 4 {
+		t.Fatalf("expected 4 files, got %+v", files)
+	}
+
+	session := files[0]
+	if session.Path != "auth/session.go" || session.Status != "modified" || session.Additions != 2 || session.Deletions != 1 {
+		t.Errorf("unexpected session.go diff %+v", session)
+	}
+	if !strings.HasPrefix(session.Patch, "@@ -10 +10,2 @@") || !strings.Contains(session.Patch, "+\t// Sliding expiration\n") {
+		t.Errorf("unexpected session.go patch %q", session.Patch)
+	}
+
+	if files[1].Path != "new name.go" || files[1].OldPath != "old name.go" || files[1].Status != "renamed" {
+		t.Errorf("unexpected rename %+v", files[1])
+	}
+	if files[2].Path != "logo.png" || files[2].Status != "added" || !files[2].Binary || files[2].Patch != "" {
+		t.Errorf("unexpected binary file %+v", files[2])
+	}
+	if files[3].Path != "gone.go" || files[3].Status != "deleted" || files[3].Deletions != 1 || strings.Contains(files[3].Patch, "No newline") {
+		t.Errorf("unexpected deleted file %+v", files[3])
+	}
+}
+
+func TestShowCommits(t *testing.T) {
+	ctx := context.Background()
+	repo := t.TempDir()
+	setupGitRepo(t, repo)
+	writeRepoFile(t, repo, "app/session.go", "package app\n\nconst ttl = 60\n", 0644)
+	writeRepoFile(t, repo, "docs/README.md", "# Docs\n", 0644)
+	gitRun(t, repo, "add", "-A")
+	gitRun(t, repo, "commit", "-m", "Add sessions")
+	writeRepoFile(t, repo, "app/session.go", "package app\n\nconst ttl = 30\n", 0644)
+	gitRun(t, repo, "add", "-A")
+	gitRun(t, repo, "commit", "-m", "Shorten session expiry\n\nSessions now expire after 30 minutes.")
+
+	shas, err := RevList(ctx, repo, "HEAD", 0)
+	if err != nil || len(shas) != 2 {
+		t.Fatalf("expected 2 commits, got %v (%v)", shas, err)
+	}
+	if limited, _ := RevList(ctx, repo, "HEAD", 1); len(limited) != 1 || limited[0] != shas[0] {
+		t.Errorf("expected the newest commit only, got %v", limited)
+	}
+
+	if _, err := RevList(ctx, repo, "--all", 0); err == nil {
+		t.Error("expected RevList to reject an option")
+	}
+	if _, err := ShowCommits(ctx, repo, []string{shas[0], "--output=x"}); err == nil {
+		t.Error("expected ShowCommits to reject an option")
+	}
+
+	commits, err := ShowCommits(ctx, repo, shas)
+	if err != nil {
+		t.Fatalf("ShowCommits failed: %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("expected 2 commits, got %d", len(commits))
+	}
+	latest := commits[0]
+	if latest.SHA != shas[0] || latest.Subject() != "Shorten session expiry" || !strings.Contains(latest.Message, "30 minutes") {
+		t.Errorf("unexpected commit %+v", latest)
+	}
+	if latest.Author == "" || latest.Date.IsZero() {
+		t.Errorf("expected author and date, got %q %v", latest.Author, latest.Date)
+	}
+	if len(latest.Files) != 1 || latest.Files[0].Path != "app/session.go" || !strings.Contains(latest.Files[0].Patch, "+const ttl = 30") {
+		t.Errorf("unexpected files %+v", latest.Files)
+	}
+	if len(commits[1].Files) != 2 {
+		t.Errorf("expected 2 files in the first commit, got %+v", commits[1].Files)
+	}
+
+	// Only the commits and files under the directory
+	sub := repo + "/docs"
+	if shas, _ := RevList(ctx, sub, "HEAD", 0); len(shas) != 1 {
+		t.Errorf("expected 1 commit touching docs, got %v", shas)
+	}
+	commits, err = ShowCommits(ctx, sub, shas[1:])
+	if err != nil || len(commits) != 1 || len(commits[0].Files) != 1 || commits[0].Files[0].Path != "README.md" {
+		t.Errorf("expected README.md relative to docs, got %+v (%v)", commits, err)
+	}
+}
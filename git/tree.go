@@ -0,0 +1,343 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tree is a read-only view of the files of a commit, read straight from the
+// git object database with git ls-tree and git cat-file --batch, without
+// touching the working tree. It implements fs.FS, fs.ReadDirFS, fs.ReadFileFS
+// and fs.StatFS. Symlinks and submodules are left out.
+type Tree struct {
+	Commit     string    // Resolved commit hash
+	CommitTime time.Time // Modification time reported for every file
+
+	files map[string]treeEntry     // By slash-separated path relative to the tree root
+	dirs  map[string][]fs.DirEntry // Sorted children by directory ("." for the root)
+
+	ctx    context.Context
+	dir    string
+	mu     sync.Mutex
+	cat    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+}
+
+type treeEntry struct {
+	blob string
+	size int64
+	mode fs.FileMode
+}
+
+// OpenTree resolves rev (a branch, tag or commit) in the repository containing
+// dir and lists the files of its tree below dir. Paths are relative to dir, so
+// a project in a subdirectory of a repository sees the same paths as in its
+// working tree. Close must be called to stop the git cat-file process.
+func OpenTree(ctx context.Context, dir, rev string) (*Tree, error) {
+	if rev == "" {
+		return nil, fmt.Errorf("invalid git revision %q", rev)
+	}
+	if err := ValidateRevision(rev); err != nil {
+		return nil, err
+	}
+	commit, err := runGit(ctx, dir, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("unknown git revision %q: %w", rev, err)
+	}
+	commitTime, err := runGit(ctx, dir, "show", "-s", "--format=%ct", commit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit %s: %w", commit, err)
+	}
+	seconds, err := strconv.ParseInt(commitTime, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse commit time %q: %w", commitTime, err)
+	}
+	prefix, err := runGit(ctx, dir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate %s in the repository: %w", dir, err)
+	}
+
+	t := &Tree{
+		Commit:     commit,
+		CommitTime: time.Unix(seconds, 0),
+		files:      make(map[string]treeEntry),
+		ctx:        ctx,
+		dir:        dir,
+	}
+	listing, err := exec.CommandContext(ctx, "git", "-C", dir, "ls-tree", "-r", "-z", "--long", "--full-tree", commit+":"+prefix).Output()
+	if err != nil {
+		// The project directory did not exist at that commit
+		return nil, fmt.Errorf("failed to list tree of %s: %w", rev, gitError(err))
+	}
+	if err := t.parseListing(listing); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// parseListing reads `git ls-tree -r -z --long` records:
+// "<mode> <type> <object> <size>\t<path>\x00".
+func (t *Tree) parseListing(listing []byte) error {
+	children := map[string]map[string]fs.DirEntry{".": {}}
+	for _, record := range bytes.Split(listing, []byte{0}) {
+		if len(record) == 0 {
+			continue
+		}
+		meta, name, ok := strings.Cut(string(record), "\t")
+		fields := strings.Fields(meta)
+		if !ok || len(fields) != 4 {
+			return fmt.Errorf("unexpected git ls-tree output: %q", record)
+		}
+		if fields[1] != "blob" || (fields[0] != "100644" && fields[0] != "100755") {
+			continue // Symlink or submodule
+		}
+		size, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return fmt.Errorf("unexpected git ls-tree size %q: %w", fields[3], err)
+		}
+		mode := fs.FileMode(0644)
+		if fields[0] == "100755" {
+			mode = 0755
+		}
+		t.files[name] = treeEntry{blob: fields[2], size: size, mode: mode}
+
+		// Register the file and its parent directories
+		child := name
+		for {
+			parent := path.Dir(child)
+			if children[parent] == nil {
+				children[parent] = make(map[string]fs.DirEntry)
+			}
+			base := path.Base(child)
+			if _, seen := children[parent][base]; seen {
+				break
+			}
+			info := t.dirInfo(child)
+			if child == name {
+				info = t.fileInfo(child, t.files[name])
+			}
+			children[parent][base] = fs.FileInfoToDirEntry(info)
+			if parent == "." {
+				break
+			}
+			child = parent
+		}
+	}
+
+	t.dirs = make(map[string][]fs.DirEntry, len(children))
+	for dir, entries := range children {
+		list := make([]fs.DirEntry, 0, len(entries))
+		for _, entry := range entries {
+			list = append(list, entry)
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+		t.dirs[dir] = list
+	}
+	return nil
+}
+
+// Open implements fs.FS.
+func (t *Tree) Open(name string) (fs.File, error) {
+	info, err := t.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	if info.IsDir() {
+		return &treeDir{info: info, entries: t.dirs[name]}, nil
+	}
+	data, err := t.readBlob(t.files[name].blob)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &treeFile{info: info, Reader: bytes.NewReader(data)}, nil
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (t *Tree) ReadFile(name string) ([]byte, error) {
+	entry, ok := t.files[name]
+	if !ok || !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	data, err := t.readBlob(entry.blob)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return data, nil
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (t *Tree) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, ok := t.dirs[name]
+	if !ok || !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]fs.DirEntry(nil), entries...), nil
+}
+
+// Stat implements fs.StatFS.
+func (t *Tree) Stat(name string) (fs.FileInfo, error) {
+	info, err := t.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return info, nil
+}
+
+func (t *Tree) stat(name string) (*treeFileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, fs.ErrInvalid
+	}
+	if entry, ok := t.files[name]; ok {
+		return t.fileInfo(name, entry), nil
+	}
+	if _, ok := t.dirs[name]; ok {
+		return t.dirInfo(name), nil
+	}
+	return nil, fs.ErrNotExist
+}
+
+func (t *Tree) fileInfo(name string, entry treeEntry) *treeFileInfo {
+	return &treeFileInfo{name: path.Base(name), size: entry.size, mode: entry.mode, modTime: t.CommitTime}
+}
+
+func (t *Tree) dirInfo(name string) *treeFileInfo {
+	return &treeFileInfo{name: path.Base(name), mode: fs.ModeDir | 0755, modTime: t.CommitTime}
+}
+
+// readBlob reads an object through a long-running git cat-file --batch
+// process, started on first use.
+func (t *Tree) readBlob(blob string) ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.cat == nil {
+		cmd := exec.CommandContext(t.ctx, "git", "-C", t.dir, "cat-file", "--batch")
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, fmt.Errorf("failed to start git cat-file: %w", err)
+		}
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, fmt.Errorf("failed to start git cat-file: %w", err)
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("failed to start git cat-file: %w", err)
+		}
+		t.cat, t.stdin, t.stdout = cmd, stdin, bufio.NewReader(stdout)
+	}
+
+	if _, err := fmt.Fprintf(t.stdin, "%s\n", blob); err != nil {
+		return nil, fmt.Errorf("failed to request blob %s: %w", blob, err)
+	}
+	header, err := t.stdout.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob %s: %w", blob, err)
+	}
+	fields := strings.Fields(header)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("failed to read blob %s: %s", blob, strings.TrimSpace(header))
+	}
+	size, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob %s: invalid size %q", blob, fields[2])
+	}
+	data := make([]byte, size+1) // Content is followed by a newline
+	if _, err := io.ReadFull(t.stdout, data); err != nil {
+		return nil, fmt.Errorf("failed to read blob %s: %w", blob, err)
+	}
+	return data[:size], nil
+}
+
+// Close stops the git cat-file process.
+func (t *Tree) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.cat == nil {
+		return nil
+	}
+	_ = t.stdin.Close()
+	err := t.cat.Wait()
+	t.cat = nil
+	return err
+}
+
+type treeFileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (fi *treeFileInfo) Name() string       { return fi.name }
+func (fi *treeFileInfo) Size() int64        { return fi.size }
+func (fi *treeFileInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi *treeFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *treeFileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *treeFileInfo) Sys() any           { return nil }
+
+type treeFile struct {
+	*bytes.Reader
+	info fs.FileInfo
+}
+
+func (f *treeFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *treeFile) Close() error               { return nil }
+
+type treeDir struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *treeDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *treeDir) Close() error               { return nil }
+func (d *treeDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: fs.ErrInvalid}
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *treeDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return append([]fs.DirEntry(nil), rest...), nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return append([]fs.DirEntry(nil), rest[:n]...), nil
+}
+
+// runGit runs a git command in dir and returns its trimmed output.
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	out, err := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...).Output()
+	if err != nil {
+		return "", gitError(err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// gitError adds the stderr of a failed git command to its error.
+func gitError(err error) error {
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		return fmt.Errorf("%w (stderr: %s)", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return err
+}
@@ -0,0 +1,89 @@
+package git
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func gitRun(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+}
+
+func writeRepoFile(t *testing.T, root, name, content string, perm os.FileMode) {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), perm); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+}
+
+func TestOpenTree(t *testing.T) {
+	repo := t.TempDir()
+	setupGitRepo(t, repo)
+	writeRepoFile(t, repo, "README.md", "# v1\n", 0644)
+	writeRepoFile(t, repo, "app/main.go", "package main\n", 0644)
+	writeRepoFile(t, repo, "app/run.sh", "#!/bin/sh\necho hi\n", 0755)
+	gitRun(t, repo, "add", "-A")
+	gitRun(t, repo, "commit", "-m", "v1")
+	gitRun(t, repo, "tag", "v1")
+
+	// Later changes, committed and uncommitted, are not visible at v1
+	writeRepoFile(t, repo, "app/main.go", "package main\n\nfunc main() {}\n", 0644)
+	writeRepoFile(t, repo, "app/new.go", "package main\n", 0644)
+	gitRun(t, repo, "add", "-A")
+	gitRun(t, repo, "commit", "-m", "v2")
+	writeRepoFile(t, repo, "README.md", "# dirty\n", 0644)
+
+	tree, err := OpenTree(context.Background(), repo, "v1")
+	if err != nil {
+		t.Fatalf("OpenTree failed: %v", err)
+	}
+	defer tree.Close()
+
+	if err := fstest.TestFS(tree, "README.md", "app/main.go", "app/run.sh"); err != nil {
+		t.Fatalf("tree is not a valid fs.FS: %v", err)
+	}
+	data, err := fs.ReadFile(tree, "README.md")
+	if err != nil || string(data) != "# v1\n" {
+		t.Errorf("README.md = %q, %v; want the committed content", data, err)
+	}
+	if _, err := fs.Stat(tree, "app/new.go"); err == nil {
+		t.Error("app/new.go was added after v1")
+	}
+	info, err := fs.Stat(tree, "app/run.sh")
+	if err != nil || info.Mode().Perm()&0111 == 0 {
+		t.Errorf("expected app/run.sh to be executable, got %v, %v", info, err)
+	}
+	if !info.ModTime().Equal(tree.CommitTime) {
+		t.Errorf("ModTime = %v, want the commit time %v", info.ModTime(), tree.CommitTime)
+	}
+
+	// A project in a subdirectory sees paths relative to it
+	sub, err := OpenTree(context.Background(), filepath.Join(repo, "app"), "HEAD")
+	if err != nil {
+		t.Fatalf("OpenTree failed: %v", err)
+	}
+	defer sub.Close()
+	if data, err := fs.ReadFile(sub, "new.go"); err != nil || string(data) != "package main\n" {
+		t.Errorf("new.go = %q, %v", data, err)
+	}
+
+	if _, err := OpenTree(context.Background(), repo, "no-such-branch"); err == nil {
+		t.Error("expected an error for an unknown revision")
+	}
+	if _, err := OpenTree(context.Background(), repo, "--output=x"); err == nil {
+		t.Error("expected an error for a revision that looks like an option")
+	}
+}
@@ -0,0 +1,187 @@
+// Package history indexes the commit history of a project so that commits
+// can be searched by intent ("when and why did session expiry change").
+//
+// Each commit is embedded as one chunk for its message and one chunk per
+// touched file holding a summarized diff. Chunks live in a vector store of
+// their own, and the commit metadata (author, date, files) in a GOB log next
+// to it.
+package history
+
+import (
+	"context"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/yoanbernabeu/grepai/config"
+	"github.com/yoanbernabeu/grepai/store"
+)
+
+// Commit is the metadata of an indexed commit.
+type Commit struct {
+	SHA     string
+	Author  string
+	Email   string
+	Date    time.Time
+	Message string
+	Files   []File
+}
+
+// File is a file touched by a commit.
+type File struct {
+	Path      string
+	OldPath   string // Path before a rename
+	Status    string // added, deleted, renamed or modified
+	Additions int
+	Deletions int
+	Summary   string // Summarized diff, "" when the file was not embedded
+}
+
+// Log holds the metadata of the indexed commits.
+type Log struct {
+	path string
+
+	mu      sync.RWMutex
+	head    string // HEAD when the history was last indexed
+	commits map[string]*Commit
+}
+
+type gobLogData struct {
+	Head    string
+	Commits map[string]*Commit
+}
+
+// NewLog returns an empty log persisted at path.
+func NewLog(path string) *Log {
+	return &Log{path: path, commits: make(map[string]*Commit)}
+}
+
+// Load reads the log from disk. A missing file leaves it empty.
+func (l *Log) Load() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.Open(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to open commit log: %w", err)
+	}
+	defer file.Close()
+
+	var data gobLogData
+	if err := gob.NewDecoder(file).Decode(&data); err != nil {
+		return fmt.Errorf("failed to decode commit log: %w", err)
+	}
+	l.head = data.Head
+	l.commits = data.Commits
+	if l.commits == nil {
+		l.commits = make(map[string]*Commit)
+	}
+	return nil
+}
+
+// Persist writes the log to disk.
+func (l *Log) Persist() error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	tmpPath := l.path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create commit log: %w", err)
+	}
+	if err := gob.NewEncoder(file).Encode(gobLogData{Head: l.head, Commits: l.commits}); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to encode commit log: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write commit log: %w", err)
+	}
+	return os.Rename(tmpPath, l.path)
+}
+
+// Head returns the HEAD commit the history was last indexed at.
+func (l *Log) Head() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.head
+}
+
+// SetHead records the HEAD commit the history is indexed at.
+func (l *Log) SetHead(head string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.head = head
+}
+
+// Get returns an indexed commit, or nil.
+func (l *Log) Get(sha string) *Commit {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.commits[sha]
+}
+
+// Add records an indexed commit.
+func (l *Log) Add(commit *Commit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.commits[commit.SHA] = commit
+}
+
+// Len returns the number of indexed commits.
+func (l *Log) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.commits)
+}
+
+// Reset forgets every commit.
+func (l *Log) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.head = ""
+	l.commits = make(map[string]*Commit)
+}
+
+// OpenLog loads the commit log of a project.
+func OpenLog(projectRoot string) (*Log, error) {
+	log := NewLog(filepath.Join(config.GetHistoryDir(projectRoot), config.HistoryLogFileName))
+	if err := log.Load(); err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+// OpenStore opens the vector store of the commit history of a project. It is
+// kept apart from the code index: .grepai/history/index.gob for gob, its own
+// project id for postgres and its own collection for qdrant.
+func OpenStore(ctx context.Context, cfg *config.Config, projectRoot string) (store.VectorStore, error) {
+	switch cfg.Store.Backend {
+	case "gob":
+		gobStore := store.NewGOBStore(filepath.Join(config.GetHistoryDir(projectRoot), config.IndexFileName))
+		if err := gobStore.Load(ctx); err != nil {
+			return nil, fmt.Errorf("failed to load history index: %w", err)
+		}
+		return gobStore, nil
+	case "postgres":
+		return store.NewPostgresStore(ctx, cfg.Store.Postgres.DSN, projectRoot+"!history", cfg.Embedder.GetDimensions())
+	case "qdrant":
+		collectionName := cfg.Store.Qdrant.Collection
+		if collectionName == "" {
+			collectionName = store.SanitizeCollectionName(projectRoot)
+		}
+		return store.NewQdrantStore(ctx, cfg.Store.Qdrant.Endpoint, cfg.Store.Qdrant.Port, cfg.Store.Qdrant.UseTLS, collectionName+"_history", cfg.Store.Qdrant.APIKey, cfg.Embedder.GetDimensions())
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", cfg.Store.Backend)
+	}
+}
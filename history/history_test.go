@@ -0,0 +1,160 @@
+package history
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yoanbernabeu/grepai/redact"
+	"github.com/yoanbernabeu/grepai/store"
+)
+
+// keywordEmbedder embeds texts by counting a few keywords, enough to rank
+// commits in tests.
+type keywordEmbedder struct{}
+
+var keywords = []string{"session", "readme", "cache"}
+
+func (e *keywordEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	text = strings.ToLower(text)
+	vector := make([]float32, len(keywords)+1)
+	for i, keyword := range keywords {
+		vector[i] = float32(strings.Count(text, keyword))
+	}
+	vector[len(keywords)] = 0.1
+	return vector, nil
+}
+
+func (e *keywordEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i], _ = e.Embed(ctx, text)
+	}
+	return vectors, nil
+}
+
+func (e *keywordEmbedder) Dimensions() int { return len(keywords) + 1 }
+func (e *keywordEmbedder) Close() error    { return nil }
+
+func gitCommit(t *testing.T, repo, file, content, message string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(repo, file), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", file, err)
+	}
+	for _, args := range [][]string{{"add", "-A"}, {"commit", "-q", "-m", message}} {
+		cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+}
+
+func setupRepo(t *testing.T) string {
+	t.Helper()
+	repo := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.email", "dev@example.com"},
+		{"config", "user.name", "Dev"},
+		{"config", "commit.gpgsign", "false"},
+	} {
+		cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	return repo
+}
+
+func TestIndexerUpdateAndSearch(t *testing.T) {
+	ctx := context.Background()
+	repo := setupRepo(t)
+	gitCommit(t, repo, "README.md", "# Project\n", "Add readme")
+	gitCommit(t, repo, "expiry.go", "package app\n\nconst sessionTTL = 60\n", "Shorten expiry\n\nUsers asked for it.")
+
+	dir := t.TempDir()
+	st := store.NewGOBStore(filepath.Join(dir, "index.gob"))
+	commitLog := NewLog(filepath.Join(dir, "commits.gob"))
+	emb := &keywordEmbedder{}
+	idx := NewIndexer(repo, st, emb, commitLog, Options{MaxFilesPerCommit: 20, MaxDiffLines: 40})
+
+	indexed, err := idx.Update(ctx, nil)
+	if err != nil || indexed != 2 {
+		t.Fatalf("expected 2 commits indexed, got %d (%v)", indexed, err)
+	}
+	if indexed, _ := idx.Update(ctx, nil); indexed != 0 {
+		t.Errorf("expected nothing to index when HEAD did not move, got %d", indexed)
+	}
+
+	// The session commit matches through its diff, the message says nothing
+	results, err := Search(ctx, st, emb, commitLog, "session", 5)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected one result per commit, got %+v", results)
+	}
+	top := results[0]
+	if top.Subject != "Shorten expiry" || top.Author != "Dev" || top.MatchedFile != "expiry.go" {
+		t.Errorf("unexpected top result %+v", top)
+	}
+	if !strings.Contains(top.DiffExcerpt, "+const sessionTTL = 60") || len(top.Files) != 1 {
+		t.Errorf("expected the expiry.go diff excerpt, got %+v", top)
+	}
+
+	// New commits are indexed incrementally, and the log survives a reload
+	gitCommit(t, repo, "cache.go", "package app\n\nvar cache = map[string]string{}\n", "Add a cache")
+	if indexed, err := idx.Update(ctx, nil); err != nil || indexed != 1 {
+		t.Fatalf("expected 1 new commit indexed, got %d (%v)", indexed, err)
+	}
+	reloaded := NewLog(filepath.Join(dir, "commits.gob"))
+	if err := reloaded.Load(); err != nil || reloaded.Len() != 3 {
+		t.Fatalf("expected 3 commits in the reloaded log, got %d (%v)", reloaded.Len(), err)
+	}
+	results, err = Search(ctx, st, emb, reloaded, "cache", 1)
+	if err != nil || len(results) != 1 || results[0].Subject != "Add a cache" {
+		t.Errorf("expected the cache commit, got %+v (%v)", results, err)
+	}
+}
+
+func TestSummarizePatch(t *testing.T) {
+	patch := "@@ -1 +1 @@\n-a\n+b\n+" + strings.Repeat("x", 300) + "\n+c\n"
+	summary := SummarizePatch(patch, 4)
+	lines := strings.Split(strings.TrimRight(summary, "\n"), "\n")
+	if len(lines) != 5 || lines[4] != "... 1 more lines" {
+		t.Errorf("expected 4 lines and a note, got %q", summary)
+	}
+	if len(lines[3]) != maxLineLength+3 {
+		t.Errorf("expected the long line to be truncated, got %d characters", len(lines[3]))
+	}
+}
+
+func TestIndexerMaskStored(t *testing.T) {
+	ctx := context.Background()
+	repo := setupRepo(t)
+	key := "AKIA" + "IOSFODNN7EXAMPLE"
+	gitCommit(t, repo, "session.go", "package app\n\nconst sessionKey = \""+key+"\"\n", "Add session key "+key)
+
+	dir := t.TempDir()
+	st := store.NewGOBStore(filepath.Join(dir, "index.gob"))
+	commitLog := NewLog(filepath.Join(dir, "commits.gob"))
+	emb := &keywordEmbedder{}
+	opts := Options{MaxFilesPerCommit: 20, MaxDiffLines: 40, Redactor: redact.New(redact.DefaultRules(), 0), MaskStored: true}
+	if _, err := NewIndexer(repo, st, emb, commitLog, opts).Update(ctx, nil); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	results, err := Search(ctx, st, emb, commitLog, "session", 1)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("expected the session commit, got %+v (%v)", results, err)
+	}
+	r := results[0]
+	for field, text := range map[string]string{"message": r.Message, "subject": r.Subject, "diff excerpt": r.DiffExcerpt} {
+		if strings.Contains(text, key) || !strings.Contains(text, "[REDACTED:") {
+			t.Errorf("expected the secret masked in the %s, got %q", field, text)
+		}
+	}
+}
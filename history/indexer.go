@@ -0,0 +1,271 @@
+package history
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yoanbernabeu/grepai/embedder"
+	"github.com/yoanbernabeu/grepai/git"
+	"github.com/yoanbernabeu/grepai/redact"
+	"github.com/yoanbernabeu/grepai/store"
+)
+
+const (
+	// showBatchSize is the number of commits read by a single git show.
+	showBatchSize = 50
+	// maxLineLength truncates long diff lines (minified files, lock files).
+	maxLineLength = 200
+	// maxListedFiles caps the files listed in the message chunk of a commit.
+	maxListedFiles = 30
+)
+
+// Options configure what the indexer embeds.
+type Options struct {
+	MaxCommits        int              // Most recent commits indexed, <= 0 for all
+	MaxFilesPerCommit int              // Files whose diff is embedded, per commit
+	MaxDiffLines      int              // Diff lines kept in each file summary
+	Redactor          *redact.Redactor // Masks secrets in embedding input (nil disables)
+	MaskStored        bool             // Also mask secrets in the stored chunk content
+}
+
+// Progress is called after each batch of commits is embedded.
+type Progress func(done, total int)
+
+// Indexer embeds the commits of a repository that are not indexed yet.
+type Indexer struct {
+	repoDir  string
+	store    store.VectorStore
+	embedder embedder.Embedder
+	log      *Log
+	opts     Options
+}
+
+// NewIndexer creates an indexer of the history of the repository at repoDir.
+func NewIndexer(repoDir string, st store.VectorStore, emb embedder.Embedder, log *Log, opts Options) *Indexer {
+	return &Indexer{repoDir: repoDir, store: st, embedder: emb, log: log, opts: opts}
+}
+
+// Update indexes the commits reachable from HEAD that are not indexed yet,
+// within the most recent MaxCommits, and returns how many were indexed.
+// Nothing is read when HEAD did not move since the last update. The store
+// and the log are persisted after each batch, so an interrupted update
+// resumes where it stopped.
+func (idx *Indexer) Update(ctx context.Context, progress Progress) (int, error) {
+	head, err := git.ResolveCommit(ctx, idx.repoDir, "HEAD")
+	if err != nil {
+		return 0, err
+	}
+	if head == idx.log.Head() {
+		return 0, nil
+	}
+
+	shas, err := git.RevList(ctx, idx.repoDir, head, idx.opts.MaxCommits)
+	if err != nil {
+		return 0, err
+	}
+	var pending []string
+	for _, sha := range shas {
+		if idx.log.Get(sha) == nil {
+			pending = append(pending, sha)
+		}
+	}
+
+	// Oldest first, so that the log is a prefix of the history if interrupted
+	for i, j := 0, len(pending)-1; i < j; i, j = i+1, j-1 {
+		pending[i], pending[j] = pending[j], pending[i]
+	}
+
+	indexed := 0
+	for start := 0; start < len(pending); start += showBatchSize {
+		end := min(start+showBatchSize, len(pending))
+		commits, err := git.ShowCommits(ctx, idx.repoDir, pending[start:end])
+		if err != nil {
+			return indexed, err
+		}
+		if err := idx.indexCommits(ctx, commits); err != nil {
+			return indexed, err
+		}
+		indexed += len(commits)
+		if progress != nil {
+			progress(indexed, len(pending))
+		}
+	}
+
+	idx.log.SetHead(head)
+	if err := idx.log.Persist(); err != nil {
+		return indexed, err
+	}
+	return indexed, nil
+}
+
+// indexCommits embeds a batch of commits, then persists the store and the
+// log.
+func (idx *Indexer) indexCommits(ctx context.Context, commits []git.Commit) error {
+	var texts []string
+	var chunks []store.Chunk
+	docs := make([]store.Document, 0, len(commits))
+	metadata := make([]*Commit, 0, len(commits))
+	now := time.Now()
+
+	for i := range commits {
+		commit := idx.summarize(&commits[i])
+		metadata = append(metadata, commit)
+
+		doc := store.Document{Path: commit.SHA, Hash: commit.SHA, ModTime: commit.Date}
+		for n, text := range chunkTexts(commit) {
+			chunk := store.Chunk{
+				ID:          ChunkID(commit.SHA, n),
+				FilePath:    commit.SHA,
+				StartLine:   text.file,
+				EndLine:     text.file,
+				Content:     text.content,
+				Hash:        commit.SHA,
+				ContentHash: contentHash(text.content),
+				UpdatedAt:   now,
+			}
+			embedded := text.content
+			if idx.opts.Redactor != nil {
+				embedded = idx.opts.Redactor.Redact(embedded)
+				if idx.opts.MaskStored {
+					chunk.Content = embedded
+				}
+			}
+			texts = append(texts, embedded)
+			chunks = append(chunks, chunk)
+			doc.ChunkIDs = append(doc.ChunkIDs, chunk.ID)
+		}
+		docs = append(docs, doc)
+	}
+
+	vectors, err := idx.embedder.EmbedBatch(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("failed to embed commits: %w", err)
+	}
+	if len(vectors) != len(chunks) {
+		return fmt.Errorf("embedder returned %d vectors for %d chunks", len(vectors), len(chunks))
+	}
+	for i := range chunks {
+		chunks[i].Vector = vectors[i]
+	}
+
+	if err := idx.store.SaveChunks(ctx, chunks); err != nil {
+		return fmt.Errorf("failed to save commit chunks: %w", err)
+	}
+	for _, doc := range docs {
+		if err := idx.store.SaveDocument(ctx, doc); err != nil {
+			return fmt.Errorf("failed to save commit %s: %w", doc.Path, err)
+		}
+	}
+	if err := idx.store.Persist(ctx); err != nil {
+		return fmt.Errorf("failed to persist history index: %w", err)
+	}
+
+	for _, commit := range metadata {
+		idx.log.Add(commit)
+	}
+	return idx.log.Persist()
+}
+
+// summarize converts a commit read from git, keeping a summarized diff for
+// the first MaxFilesPerCommit text files. With MaskStored, secrets are masked
+// in the message and summaries kept in the log, which search results show.
+func (idx *Indexer) summarize(c *git.Commit) *Commit {
+	mask := func(text string) string { return text }
+	if idx.opts.Redactor != nil && idx.opts.MaskStored {
+		mask = idx.opts.Redactor.Redact
+	}
+	commit := &Commit{
+		SHA:     c.SHA,
+		Author:  c.Author,
+		Email:   c.Email,
+		Date:    c.Date,
+		Message: mask(c.Message),
+		Files:   make([]File, 0, len(c.Files)),
+	}
+	summarized := 0
+	for _, f := range c.Files {
+		file := File{
+			Path:      f.Path,
+			OldPath:   f.OldPath,
+			Status:    f.Status,
+			Additions: f.Additions,
+			Deletions: f.Deletions,
+		}
+		if !f.Binary && f.Patch != "" && summarized < idx.opts.MaxFilesPerCommit {
+			file.Summary = mask(SummarizePatch(f.Patch, idx.opts.MaxDiffLines))
+			summarized++
+		}
+		commit.Files = append(commit.Files, file)
+	}
+	return commit
+}
+
+// SummarizePatch keeps the first maxLines lines of a patch, truncating long
+// lines, and notes how many lines were left out.
+func SummarizePatch(patch string, maxLines int) string {
+	lines := strings.Split(strings.TrimRight(patch, "\n"), "\n")
+	var b strings.Builder
+	for i, line := range lines {
+		if maxLines > 0 && i == maxLines {
+			fmt.Fprintf(&b, "... %d more lines\n", len(lines)-maxLines)
+			break
+		}
+		if len(line) > maxLineLength {
+			line = line[:maxLineLength] + "..."
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// chunkText is a text embedded for a commit.
+type chunkText struct {
+	file    int // 1-based index in the commit files, 0 for the message
+	content string
+}
+
+// chunkTexts returns the texts embedded for a commit: its message with the
+// list of touched files, then one text per summarized file. The commit
+// subject is repeated in file texts to give their diff some intent.
+//
+// Chunks record the file index as their line range, since not every store
+// returns chunk ids with search results.
+func chunkTexts(c *Commit) []chunkText {
+	var files strings.Builder
+	for i, f := range c.Files {
+		if i == maxListedFiles {
+			fmt.Fprintf(&files, "\n... %d more files", len(c.Files)-maxListedFiles)
+			break
+		}
+		files.WriteString("\n" + f.Path)
+	}
+	texts := []chunkText{{content: fmt.Sprintf("Commit: %s\n\nFiles:%s", c.Message, files.String())}}
+
+	subject := subject(c.Message)
+	for i, f := range c.Files {
+		if f.Summary == "" {
+			continue
+		}
+		texts = append(texts, chunkText{
+			file:    i + 1,
+			content: fmt.Sprintf("Commit: %s\nFile: %s (%s)\n\n%s", subject, f.Path, f.Status, f.Summary),
+		})
+	}
+	return texts
+}
+
+// ChunkID returns the id of the n-th chunk of a commit.
+func ChunkID(sha string, n int) string {
+	return sha + "_" + strconv.Itoa(n)
+}
+
+func contentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
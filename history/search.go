@@ -0,0 +1,99 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yoanbernabeu/grepai/embedder"
+	"github.com/yoanbernabeu/grepai/store"
+)
+
+// searchOverfetch is how many chunks are fetched per requested commit, since
+// several chunks of the same commit often match.
+const searchOverfetch = 5
+
+// Result is a commit matching a history search.
+type Result struct {
+	SHA         string    `json:"sha"`
+	Author      string    `json:"author"`
+	Email       string    `json:"email"`
+	Date        time.Time `json:"date"`
+	Subject     string    `json:"subject"`
+	Message     string    `json:"message"`
+	Files       []string  `json:"files"`
+	MatchedFile string    `json:"matched_file,omitempty"` // File whose diff matched best, "" for the message
+	DiffExcerpt string    `json:"diff_excerpt,omitempty"`
+	Score       float32   `json:"score"`
+}
+
+// Search returns the commits whose message or diff best match a query, best
+// first, one result per commit.
+func Search(ctx context.Context, st store.VectorStore, emb embedder.Embedder, log *Log, query string, limit int) ([]Result, error) {
+	vector, err := embedder.EmbedQuery(ctx, emb, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	matches, err := st.Search(ctx, vector, limit*searchOverfetch, store.SearchOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search history: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var results []Result
+	for _, match := range matches {
+		sha := match.Chunk.FilePath
+		if seen[sha] {
+			continue
+		}
+		commit := log.Get(sha)
+		if commit == nil {
+			continue // Embedded but its batch did not complete
+		}
+		seen[sha] = true
+		results = append(results, newResult(commit, match.Chunk.StartLine, match.Score))
+		if len(results) == limit {
+			break
+		}
+	}
+	return results, nil
+}
+
+// newResult describes a commit matched through its file-th file (1-based),
+// or its message when file is 0. The excerpt is the diff of the matched
+// file, or of the first summarized file for message matches.
+func newResult(c *Commit, file int, score float32) Result {
+	r := Result{
+		SHA:     c.SHA,
+		Author:  c.Author,
+		Email:   c.Email,
+		Date:    c.Date,
+		Message: c.Message,
+		Files:   make([]string, 0, len(c.Files)),
+		Score:   score,
+	}
+	r.Subject = subject(c.Message)
+	for _, f := range c.Files {
+		r.Files = append(r.Files, f.Path)
+	}
+
+	if file > 0 && file <= len(c.Files) {
+		r.MatchedFile = c.Files[file-1].Path
+		r.DiffExcerpt = c.Files[file-1].Summary
+		return r
+	}
+	for _, f := range c.Files {
+		if f.Summary != "" {
+			r.DiffExcerpt = f.Path + "\n" + f.Summary
+			break
+		}
+	}
+	return r
+}
+
+// subject returns the first line of a commit message.
+func subject(message string) string {
+	subject, _, _ := strings.Cut(message, "\n")
+	return subject
+}
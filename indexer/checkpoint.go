@@ -0,0 +1,144 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/yoanbernabeu/grepai/embedder"
+)
+
+// checkpointChunks is how many chunks are embedded between two checkpoints
+// by batch embedders (a variable so tests can lower it).
+var checkpointChunks = 4 * embedder.MaxBatchSize
+
+// checkpointInterval is how often sequential indexing checkpoints.
+const checkpointInterval = 30 * time.Second
+
+// Checkpoint is the manifest of an indexing run in progress. Completed files
+// are persisted to the store as the run goes; if it is interrupted, the next
+// run resumes with the pending files instead of rescanning and re-embedding
+// everything. The manifest is removed once a run completes.
+type Checkpoint struct {
+	StartedAt time.Time `json:"started_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Total     int       `json:"total"`
+	Pending   []string  `json:"pending"`
+
+	path    string
+	pending map[string]bool
+}
+
+// LoadCheckpoint loads the manifest at path. It returns nil when there is no
+// interrupted run.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+	cp.path = path
+	cp.pending = make(map[string]bool, len(cp.Pending))
+	for _, p := range cp.Pending {
+		cp.pending[p] = true
+	}
+	return &cp, nil
+}
+
+func newCheckpoint(path string, startedAt time.Time, files []string) *Checkpoint {
+	cp := &Checkpoint{
+		StartedAt: startedAt,
+		Total:     len(files),
+		path:      path,
+		pending:   make(map[string]bool, len(files)),
+	}
+	for _, f := range files {
+		cp.pending[f] = true
+	}
+	return cp
+}
+
+// Completed returns the number of files indexed so far.
+func (cp *Checkpoint) Completed() int {
+	return cp.Total - len(cp.pending)
+}
+
+// Percent returns the share of files indexed so far, from 0 to 100.
+func (cp *Checkpoint) Percent() float64 {
+	if cp.Total == 0 {
+		return 100
+	}
+	return float64(cp.Completed()) * 100 / float64(cp.Total)
+}
+
+// isPending reports whether the file still had to be indexed.
+func (cp *Checkpoint) isPending(relPath string) bool {
+	return cp.pending[relPath]
+}
+
+// complete marks files as indexed.
+func (cp *Checkpoint) complete(paths []string) {
+	for _, p := range paths {
+		delete(cp.pending, p)
+	}
+}
+
+// save writes the manifest atomically.
+func (cp *Checkpoint) save() error {
+	cp.UpdatedAt = time.Now()
+	cp.Pending = make([]string, 0, len(cp.pending))
+	for p := range cp.pending {
+		cp.Pending = append(cp.Pending, p)
+	}
+	sort.Strings(cp.Pending)
+
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+	tmpPath := cp.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmpPath, cp.path); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// removeCheckpoint deletes the manifest once a run is complete.
+func removeCheckpoint(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove checkpoint: %w", err)
+	}
+	return nil
+}
+
+// saveCheckpoint persists the store and marks files as completed in the
+// manifest of the active run. Files are only marked once the store holds them.
+func (idx *Indexer) saveCheckpoint(ctx context.Context, completed []string) {
+	if idx.checkpoint == nil || len(completed) == 0 {
+		return
+	}
+	if err := idx.store.Persist(ctx); err != nil {
+		log.Printf("Warning: failed to persist index checkpoint: %v", err)
+		return
+	}
+	if err := idx.SaveSecretsReport(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	idx.checkpoint.complete(completed)
+	if err := idx.checkpoint.save(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
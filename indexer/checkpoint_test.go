@@ -0,0 +1,172 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yoanbernabeu/grepai/embedder"
+)
+
+// interruptedBatchEmbedder fails every EmbedBatches call after the first
+// failAfter calls, like a run killed by a 429 storm or Ctrl-C.
+type interruptedBatchEmbedder struct {
+	mockBatchEmbedder
+	failAfter int
+	calls     int
+}
+
+func (m *interruptedBatchEmbedder) EmbedBatches(ctx context.Context, batches []embedder.Batch, progress embedder.BatchProgress) ([]embedder.BatchResult, error) {
+	m.calls++
+	if m.calls > m.failAfter {
+		return nil, errors.New("interrupted")
+	}
+	return m.mockBatchEmbedder.EmbedBatches(ctx, batches, progress)
+}
+
+func TestIndexAll_ResumesFromCheckpoint(t *testing.T) {
+	oldChunks := checkpointChunks
+	checkpointChunks = 1 // One file per checkpoint group
+	defer func() { checkpointChunks = oldChunks }()
+
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	for _, name := range []string{"a.go", "b.go", "c.go"} {
+		content := "package main\n\nfunc " + name[:1] + "() {}\n"
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+	}
+
+	ignoreMatcher, err := NewIgnoreMatcher(tmpDir, []string{}, "")
+	if err != nil {
+		t.Fatalf("failed to create ignore matcher: %v", err)
+	}
+	scanner := NewScanner(tmpDir, ignoreMatcher)
+	mockStore := newMockStore()
+	checkpointPath := filepath.Join(t.TempDir(), "checkpoint.json") // Outside the project, like .grepai
+
+	// First run is interrupted after the first group
+	first := NewIndexer(tmpDir, mockStore, &interruptedBatchEmbedder{failAfter: 1}, NewChunker(512, 50), scanner, time.Time{},
+		WithCheckpoint(checkpointPath))
+	if _, err := first.IndexAll(ctx); err == nil {
+		t.Fatal("expected the first run to fail")
+	}
+
+	cp, err := LoadCheckpoint(checkpointPath)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint failed: %v", err)
+	}
+	if cp == nil {
+		t.Fatal("expected a checkpoint after an interrupted run")
+	}
+	if cp.Total != 3 || cp.Completed() != 1 || len(cp.Pending) != 2 {
+		t.Errorf("expected 1/3 files completed, got %d/%d (pending %v)", cp.Completed(), cp.Total, cp.Pending)
+	}
+	if percent := cp.Percent(); percent < 33 || percent > 34 {
+		t.Errorf("expected 33%% complete, got %.1f", percent)
+	}
+	if len(mockStore.documents) != 1 {
+		t.Errorf("expected the completed file to be saved, got %d documents", len(mockStore.documents))
+	}
+
+	// The restart only embeds the pending files
+	second := NewIndexer(tmpDir, mockStore, &interruptedBatchEmbedder{failAfter: 10}, NewChunker(512, 50), scanner, time.Time{},
+		WithCheckpoint(checkpointPath))
+	stats, err := second.IndexAll(ctx)
+	if err != nil {
+		t.Fatalf("resumed run failed: %v", err)
+	}
+	if stats.FilesIndexed != 2 {
+		t.Errorf("expected 2 files indexed on resume, got %d", stats.FilesIndexed)
+	}
+	if len(mockStore.documents) != 3 {
+		t.Errorf("expected 3 documents, got %d", len(mockStore.documents))
+	}
+	if _, err := os.Stat(checkpointPath); !os.IsNotExist(err) {
+		t.Error("checkpoint should be removed once the run completes")
+	}
+}
+
+func TestIndexAll_ResumeReindexesFilesModifiedInTheStartSecond(t *testing.T) {
+	oldChunks := checkpointChunks
+	checkpointChunks = 1
+	defer func() { checkpointChunks = oldChunks }()
+
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	for _, name := range []string{"a.go", "b.go"} {
+		content := "package main\n\nfunc " + name[:1] + "() {}\n"
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+	}
+
+	ignoreMatcher, err := NewIgnoreMatcher(tmpDir, []string{}, "")
+	if err != nil {
+		t.Fatalf("failed to create ignore matcher: %v", err)
+	}
+	scanner := NewScanner(tmpDir, ignoreMatcher)
+	mockStore := newMockStore()
+	checkpointPath := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	first := NewIndexer(tmpDir, mockStore, &interruptedBatchEmbedder{failAfter: 1}, NewChunker(512, 50), scanner, time.Time{},
+		WithCheckpoint(checkpointPath))
+	if _, err := first.IndexAll(ctx); err == nil {
+		t.Fatal("expected the first run to fail")
+	}
+	cp, err := LoadCheckpoint(checkpointPath)
+	if err != nil || cp == nil {
+		t.Fatalf("expected a checkpoint, got (%v, %v)", cp, err)
+	}
+
+	// Edit the completed file within the second the run started
+	var completed string
+	for path := range mockStore.documents {
+		completed = path
+	}
+	full := filepath.Join(tmpDir, completed)
+	if err := os.WriteFile(full, []byte("package main\n\nfunc edited() {}\n"), 0644); err != nil {
+		t.Fatalf("failed to edit %s: %v", completed, err)
+	}
+	modTime := cp.StartedAt.Truncate(time.Second)
+	if err := os.Chtimes(full, modTime, modTime); err != nil {
+		t.Fatalf("failed to set mtime: %v", err)
+	}
+
+	second := NewIndexer(tmpDir, mockStore, &interruptedBatchEmbedder{failAfter: 10}, NewChunker(512, 50), scanner, time.Time{},
+		WithCheckpoint(checkpointPath))
+	stats, err := second.IndexAll(ctx)
+	if err != nil {
+		t.Fatalf("resumed run failed: %v", err)
+	}
+	if stats.FilesIndexed != 2 {
+		t.Errorf("expected the edited file to be indexed again with the pending one, got %d files indexed", stats.FilesIndexed)
+	}
+}
+
+func TestLoadCheckpoint_Missing(t *testing.T) {
+	cp, err := LoadCheckpoint(filepath.Join(t.TempDir(), "checkpoint.json"))
+	if err != nil || cp != nil {
+		t.Errorf("LoadCheckpoint() = (%v, %v), want (nil, nil)", cp, err)
+	}
+}
+
+func TestGroupFileChunks(t *testing.T) {
+	files := []embedder.FileChunks{
+		{FileIndex: 0, Chunks: []string{"a", "b"}},
+		{FileIndex: 1, Chunks: []string{"c"}},
+		{FileIndex: 2, Chunks: []string{"d", "e", "f", "g"}},
+		{FileIndex: 3, Chunks: []string{"h"}},
+	}
+	groups := groupFileChunks(files, 3)
+	if len(groups) != 3 {
+		t.Fatalf("expected 3 groups, got %d", len(groups))
+	}
+	if len(groups[0]) != 2 || len(groups[1]) != 1 || len(groups[2]) != 1 {
+		t.Errorf("unexpected grouping: %v", groups)
+	}
+}
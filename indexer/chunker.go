@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"strings"
 	"unicode/utf8"
+
+	"github.com/yoanbernabeu/grepai/tokenizer"
+	"github.com/yoanbernabeu/grepai/trace"
 )
 
 const (
@@ -21,15 +24,31 @@ type ChunkInfo struct {
 	EndLine     int
 	Content     string
 	Hash        string
-	ContentHash string // SHA256 of raw content text (without file path prefix)
+	ContentHash string   // SHA256 of raw content text (without file path prefix)
+	KeyPaths    []string // Config key paths contained in the chunk (structured config files only)
+	Cell        int      // 1-based notebook cell number; lines are relative to the cell (notebooks only)
+	CellType    string   // Notebook cell type: "code" or "markdown" (notebooks only)
+	Embedder    string   // Fallback provider that embedded the chunk; empty for the primary provider
 }
 
 type Chunker struct {
 	chunkSize int
 	overlap   int
+	tokenizer tokenizer.Tokenizer // nil means the CharsPerToken estimate
+}
+
+// ChunkerOption configures a Chunker.
+type ChunkerOption func(*Chunker)
+
+// WithTokenizer sizes chunks by real token counts instead of the
+// CharsPerToken estimate.
+func WithTokenizer(t tokenizer.Tokenizer) ChunkerOption {
+	return func(c *Chunker) {
+		c.tokenizer = t
+	}
 }
 
-func NewChunker(chunkSize, overlap int) *Chunker {
+func NewChunker(chunkSize, overlap int, opts ...ChunkerOption) *Chunker {
 	if chunkSize <= 0 {
 		chunkSize = DefaultChunkSize
 	}
@@ -40,10 +59,91 @@ func NewChunker(chunkSize, overlap int) *Chunker {
 		overlap = chunkSize / 10
 	}
 
-	return &Chunker{
+	c := &Chunker{
 		chunkSize: chunkSize,
 		overlap:   overlap,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Tokenizer returns the tokenizer used to size chunks, or nil when chunks are
+// sized with the CharsPerToken estimate.
+func (c *Chunker) Tokenizer() tokenizer.Tokenizer {
+	if tokenizer.IsEstimate(c.tokenizer) {
+		return nil
+	}
+	return c.tokenizer
+}
+
+// countTokens returns the token count of text using the configured tokenizer.
+func (c *Chunker) countTokens(text string) int {
+	if tokenizer.IsEstimate(c.tokenizer) {
+		return (len(text) + CharsPerToken - 1) / CharsPerToken
+	}
+	return c.tokenizer.Count(text)
+}
+
+// chunkEnd returns the end offset of a chunk starting at pos that holds at
+// most maxTokens tokens. Always advances by at least one rune.
+func (c *Chunker) chunkEnd(content string, pos, maxTokens int) int {
+	if tokenizer.IsEstimate(c.tokenizer) {
+		return min(pos+maxTokens*CharsPerToken, len(content))
+	}
+
+	fits := func(end int) bool {
+		return c.tokenizer.Count(content[pos:end]) <= maxTokens
+	}
+
+	// Probe from the estimate, doubling until the window overflows,
+	// then binary search the largest window that fits.
+	lo, hi := pos, pos+maxTokens*CharsPerToken
+	for hi < len(content) && fits(hi) {
+		lo, hi = hi, pos+2*(hi-pos)
+	}
+	if hi >= len(content) {
+		if fits(len(content)) {
+			return len(content)
+		}
+		hi = len(content)
+	}
+	for hi-lo > 1 {
+		mid := (lo + hi) / 2
+		if fits(mid) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	if lo == pos {
+		_, size := utf8.DecodeRuneInString(content[pos:])
+		return pos + size
+	}
+	return lo
+}
+
+// overlapStart returns where the chunk following content[pos:end] starts so
+// that it repeats about overlapTokens tokens of the previous chunk.
+func (c *Chunker) overlapStart(content string, pos, end, overlapTokens int) int {
+	if tokenizer.IsEstimate(c.tokenizer) {
+		return end - overlapTokens*CharsPerToken
+	}
+	if overlapTokens <= 0 {
+		return end
+	}
+
+	lo, hi := pos, end
+	for hi-lo > 1 {
+		mid := (lo + hi) / 2
+		if c.tokenizer.Count(content[mid:end]) <= overlapTokens {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return hi
 }
 
 // alignRuneBoundary adjusts a byte offset forward to the start of the next
@@ -62,8 +162,6 @@ func (c *Chunker) Chunk(filePath string, content string) []ChunkInfo {
 
 	// Use character-based chunking instead of line-based
 	// This handles minified files with very long lines
-	maxChars := c.chunkSize * CharsPerToken
-	overlapChars := c.overlap * CharsPerToken
 
 	var chunks []ChunkInfo
 	chunkIndex := 0
@@ -73,10 +171,7 @@ func (c *Chunker) Chunk(filePath string, content string) []ChunkInfo {
 
 	pos := 0
 	for pos < len(content) {
-		end := pos + maxChars
-		if end > len(content) {
-			end = len(content)
-		}
+		end := c.chunkEnd(content, pos, c.chunkSize)
 		end = alignRuneBoundary(content, end)
 
 		// Try to break at a newline if possible (cleaner chunks)
@@ -117,7 +212,7 @@ func (c *Chunker) Chunk(filePath string, content string) []ChunkInfo {
 		chunkIndex++
 
 		// Move to next chunk with overlap
-		nextPos := end - overlapChars
+		nextPos := c.overlapStart(content, pos, end, c.overlap)
 		if nextPos <= pos {
 			nextPos = end // Prevent infinite loop
 		}
@@ -156,11 +251,20 @@ func getLineNumber(lineStarts []int, pos int) int {
 
 // ChunkWithContext adds surrounding context to improve embedding quality
 func (c *Chunker) ChunkWithContext(filePath string, content string) []ChunkInfo {
-	chunks := c.Chunk(filePath, content)
+	var chunks []ChunkInfo
+	switch {
+	case trace.IsStructuredConfig(filePath):
+		chunks = c.chunkStructured(filePath, content)
+	case trace.IsNotebook(filePath):
+		chunks = c.chunkNotebook(filePath, content)
+	}
+	if chunks == nil {
+		chunks = c.Chunk(filePath, content)
+	}
 
-	// Add file path context to each chunk
+	// Add file path (and key path or cell) context to each chunk
 	for i := range chunks {
-		chunks[i].Content = fmt.Sprintf("File: %s\n\n%s", filePath, chunks[i].Content)
+		chunks[i].Content = contextHeader(chunks[i]) + chunks[i].Content
 	}
 
 	return chunks
@@ -171,12 +275,8 @@ func (c *Chunker) ChunkWithContext(filePath string, content string) []ChunkInfo
 // The parentIndex is used to generate unique sub-chunk IDs (e.g., "file.go_0_0", "file.go_0_1").
 func (c *Chunker) ReChunk(parent ChunkInfo, parentIndex int) []ChunkInfo {
 	// Strip the file context prefix if present (we'll re-add it later)
-	content := parent.Content
-	filePrefix := fmt.Sprintf("File: %s\n\n", parent.FilePath)
-	hasContext := strings.HasPrefix(content, filePrefix)
-	if hasContext {
-		content = strings.TrimPrefix(content, filePrefix)
-	}
+	header, content := SplitContextHeader(parent.FilePath, parent.Content)
+	hasContext := header != ""
 
 	if len(content) == 0 {
 		return nil
@@ -190,22 +290,17 @@ func (c *Chunker) ReChunk(parent ChunkInfo, parentIndex int) []ChunkInfo {
 	halfOverlap := c.overlap / 2
 
 	// Create a temporary chunker with smaller settings
-	subChunker := NewChunker(halfSize, halfOverlap)
+	subChunker := NewChunker(halfSize, halfOverlap, WithTokenizer(c.tokenizer))
 
 	// Build line index for the original chunk content
 	lineStarts := buildLineStarts(content)
-	maxChars := halfSize * CharsPerToken
-	overlapChars := halfOverlap * CharsPerToken
 
 	var subChunks []ChunkInfo
 	subIndex := 0
 	pos := 0
 
 	for pos < len(content) {
-		end := pos + maxChars
-		if end > len(content) {
-			end = len(content)
-		}
+		end := subChunker.chunkEnd(content, pos, halfSize)
 		end = alignRuneBoundary(content, end)
 
 		// Try to break at a newline if possible
@@ -240,7 +335,7 @@ func (c *Chunker) ReChunk(parent ChunkInfo, parentIndex int) []ChunkInfo {
 		// Re-add file context if it was present in the parent
 		finalContent := chunkContent
 		if hasContext {
-			finalContent = fmt.Sprintf("File: %s\n\n%s", parent.FilePath, chunkContent)
+			finalContent = header + chunkContent
 		}
 
 		subChunks = append(subChunks, ChunkInfo{
@@ -251,12 +346,15 @@ func (c *Chunker) ReChunk(parent ChunkInfo, parentIndex int) []ChunkInfo {
 			Content:     finalContent,
 			Hash:        hex.EncodeToString(hash[:8]),
 			ContentHash: hex.EncodeToString(contentHash[:]),
+			KeyPaths:    parent.KeyPaths,
+			Cell:        parent.Cell,
+			CellType:    parent.CellType,
 		})
 
 		subIndex++
 
 		// Move to next sub-chunk with overlap
-		nextPos := end - overlapChars
+		nextPos := subChunker.overlapStart(content, pos, end, halfOverlap)
 		if nextPos <= pos {
 			nextPos = end // Prevent infinite loop
 		}
@@ -264,8 +362,6 @@ func (c *Chunker) ReChunk(parent ChunkInfo, parentIndex int) []ChunkInfo {
 		pos = nextPos
 	}
 
-	_ = subChunker // Mark as used (we might use it in the future for more complex scenarios)
-
 	return subChunks
 }
 
@@ -0,0 +1,50 @@
+package indexer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/yoanbernabeu/grepai/trace"
+)
+
+// chunkNotebook splits a Jupyter notebook into chunks of code and markdown
+// cell sources. Outputs are ignored. Line numbers are relative to the cell,
+// which is recorded on each chunk. Returns nil when the notebook cannot be
+// parsed so callers can fall back to character-based chunking.
+func (c *Chunker) chunkNotebook(filePath string, content string) []ChunkInfo {
+	nb, err := trace.ParseNotebook(content)
+	if err != nil {
+		return nil
+	}
+
+	chunks := []ChunkInfo{}
+	for _, cell := range nb.Cells {
+		if cell.Type != "code" && cell.Type != "markdown" {
+			continue
+		}
+		if strings.TrimSpace(cell.Source) == "" {
+			continue
+		}
+
+		for _, piece := range c.Chunk(filePath, cell.Source) {
+			chunkIndex := len(chunks)
+			hash := sha256.Sum256([]byte(fmt.Sprintf("%s:%d:%d:%d:%s", filePath, cell.Index, piece.StartLine, piece.EndLine, piece.Content)))
+
+			chunks = append(chunks, ChunkInfo{
+				ID:          fmt.Sprintf("%s_%d", filePath, chunkIndex),
+				FilePath:    filePath,
+				StartLine:   piece.StartLine,
+				EndLine:     piece.EndLine,
+				Content:     piece.Content,
+				Hash:        hex.EncodeToString(hash[:8]),
+				ContentHash: piece.ContentHash,
+				Cell:        cell.Index + 1,
+				CellType:    cell.Type,
+			})
+		}
+	}
+
+	return chunks
+}
@@ -0,0 +1,76 @@
+package indexer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testNotebook = `{
+ "cells": [
+  {"cell_type": "markdown", "metadata": {}, "source": ["# Churn model\n", "Explore customer churn."]},
+  {"cell_type": "code", "execution_count": 1, "metadata": {},
+   "outputs": [{"output_type": "display_data", "data": {"image/png": "iVBORw0KGgoAAAANSUhEUgAAAAEAAAAB"}}],
+   "source": ["import pandas as pd\n", "\n", "def load_data(path):\n", "    return pd.read_csv(path)\n"]},
+  {"cell_type": "code", "execution_count": 2, "metadata": {}, "outputs": [], "source": []}
+ ],
+ "metadata": {"kernelspec": {"language": "python", "name": "python3"}},
+ "nbformat": 4,
+ "nbformat_minor": 5
+}`
+
+func TestChunker_ChunkWithContext_Notebook(t *testing.T) {
+	chunker := NewChunker(512, 50)
+	chunks := chunker.ChunkWithContext("analysis.ipynb", testNotebook)
+
+	if len(chunks) != 2 {
+		t.Fatalf("expected 2 chunks (empty cell skipped), got %d", len(chunks))
+	}
+
+	md := chunks[0]
+	if md.Cell != 1 || md.CellType != "markdown" {
+		t.Errorf("expected first chunk from markdown cell 1, got cell %d (%s)", md.Cell, md.CellType)
+	}
+	if md.Content != "File: analysis.ipynb\nCell: 1 (markdown)\n\n# Churn model\nExplore customer churn." {
+		t.Errorf("unexpected markdown chunk content: %q", md.Content)
+	}
+
+	code := chunks[1]
+	if code.Cell != 2 || code.StartLine != 1 || code.EndLine != 4 {
+		t.Errorf("expected code chunk at cell 2 lines 1-4, got cell %d lines %d-%d", code.Cell, code.StartLine, code.EndLine)
+	}
+	if strings.Contains(code.Content, "iVBORw0KGgo") || strings.Contains(code.Content, "outputs") {
+		t.Error("notebook outputs should not be indexed")
+	}
+	if code.ID == md.ID {
+		t.Error("chunk IDs should be unique across cells")
+	}
+}
+
+func TestScanner_ScanFile_NotebookOutputsStripped(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "analysis.ipynb"), []byte(testNotebook), 0644); err != nil {
+		t.Fatalf("failed to create notebook: %v", err)
+	}
+
+	ignoreMatcher, err := NewIgnoreMatcher(tmpDir, []string{}, "")
+	if err != nil {
+		t.Fatalf("failed to create ignore matcher: %v", err)
+	}
+
+	scanner := NewScanner(tmpDir, ignoreMatcher)
+	fileInfo, err := scanner.ScanFile("analysis.ipynb")
+	if err != nil {
+		t.Fatalf("scan file failed: %v", err)
+	}
+	if fileInfo == nil {
+		t.Fatal("expected notebook to be scanned")
+	}
+	if strings.Contains(fileInfo.Content, "iVBORw0KGgo") {
+		t.Error("expected notebook outputs to be stripped from scanned content")
+	}
+	if !strings.Contains(fileInfo.Content, "def load_data(path)") {
+		t.Error("expected cell sources to be kept")
+	}
+}
@@ -0,0 +1,134 @@
+package indexer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/yoanbernabeu/grepai/trace"
+)
+
+// maxHeaderKeyPaths caps how many key paths are listed in a chunk's context header.
+const maxHeaderKeyPaths = 16
+
+// chunkStructured splits a YAML, JSON, TOML or HCL file into one chunk per
+// top-level key, table or block, annotating each chunk with the key paths it
+// contains. Returns nil when the file cannot be parsed so callers can fall
+// back to character-based chunking.
+func (c *Chunker) chunkStructured(filePath string, content string) []ChunkInfo {
+	keys, err := trace.ParseConfigKeys(filePath, content)
+	if err != nil {
+		return nil
+	}
+
+	var topLevel []trace.ConfigKey
+	for _, key := range keys {
+		if key.TopLevel {
+			topLevel = append(topLevel, key)
+		}
+	}
+	if len(topLevel) == 0 {
+		return nil
+	}
+
+	lineStarts := buildLineStarts(content)
+	totalLines := len(lineStarts)
+
+	var chunks []ChunkInfo
+	spanStart := 1
+	for i, key := range topLevel {
+		spanEnd := key.EndLine
+		if i == len(topLevel)-1 || spanEnd > totalLines {
+			spanEnd = totalLines
+		}
+		if spanEnd < spanStart {
+			continue
+		}
+
+		startPos := lineStarts[spanStart-1]
+		endPos := len(content)
+		if spanEnd < totalLines {
+			endPos = lineStarts[spanEnd]
+		}
+		spanContent := content[startPos:endPos]
+		spanFirstLine := spanStart
+		spanStart = spanEnd + 1
+
+		if strings.TrimSpace(spanContent) == "" {
+			continue
+		}
+
+		// Oversized blocks fall back to character-based chunking within the block.
+		pieces := []ChunkInfo{{StartLine: spanFirstLine, EndLine: spanEnd, Content: spanContent}}
+		if c.countTokens(spanContent) > c.chunkSize {
+			pieces = c.Chunk(filePath, spanContent)
+			for j := range pieces {
+				pieces[j].StartLine += spanFirstLine - 1
+				pieces[j].EndLine += spanFirstLine - 1
+			}
+		}
+
+		for _, piece := range pieces {
+			chunkIndex := len(chunks)
+			hash := sha256.Sum256([]byte(fmt.Sprintf("%s:%d:%d:%s", filePath, piece.StartLine, piece.EndLine, piece.Content)))
+			contentHash := sha256.Sum256([]byte(piece.Content))
+
+			chunks = append(chunks, ChunkInfo{
+				ID:          fmt.Sprintf("%s_%d", filePath, chunkIndex),
+				FilePath:    filePath,
+				StartLine:   piece.StartLine,
+				EndLine:     piece.EndLine,
+				Content:     piece.Content,
+				Hash:        hex.EncodeToString(hash[:8]),
+				ContentHash: hex.EncodeToString(contentHash[:]),
+				KeyPaths:    keyPathsInRange(keys, piece.StartLine, piece.EndLine),
+			})
+		}
+	}
+
+	return chunks
+}
+
+// keyPathsInRange returns the paths of keys declared between startLine and endLine.
+func keyPathsInRange(keys []trace.ConfigKey, startLine, endLine int) []string {
+	var paths []string
+	for _, key := range keys {
+		if key.Line >= startLine && key.Line <= endLine {
+			paths = append(paths, key.Path)
+		}
+	}
+	return paths
+}
+
+// contextHeader builds the header prepended to chunk content before embedding.
+// Structured config chunks also list the key paths they contain so that queries
+// naming a nested key match the chunk defining it; notebook chunks name their cell.
+func contextHeader(chunk ChunkInfo) string {
+	if chunk.Cell > 0 {
+		return fmt.Sprintf("File: %s\nCell: %d (%s)\n\n", chunk.FilePath, chunk.Cell, chunk.CellType)
+	}
+	if len(chunk.KeyPaths) == 0 {
+		return fmt.Sprintf("File: %s\n\n", chunk.FilePath)
+	}
+	listed := chunk.KeyPaths
+	suffix := ""
+	if len(listed) > maxHeaderKeyPaths {
+		listed = listed[:maxHeaderKeyPaths]
+		suffix = ", ..."
+	}
+	return fmt.Sprintf("File: %s\nKeys: %s%s\n\n", chunk.FilePath, strings.Join(listed, ", "), suffix)
+}
+
+// SplitContextHeader separates a context header added by ChunkWithContext from
+// the chunk body. Returns an empty header when the content has none.
+func SplitContextHeader(filePath string, content string) (header string, body string) {
+	if !strings.HasPrefix(content, fmt.Sprintf("File: %s\n", filePath)) {
+		return "", content
+	}
+	idx := strings.Index(content, "\n\n")
+	if idx == -1 {
+		return "", content
+	}
+	return content[:idx+2], content[idx+2:]
+}
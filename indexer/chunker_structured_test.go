@@ -0,0 +1,119 @@
+package indexer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChunker_ChunkWithContext_YAML(t *testing.T) {
+	chunker := NewChunker(512, 50)
+	content := `# compose file
+version: "3"
+services:
+  api:
+    env:
+      REDIS_TIMEOUT: 5s
+volumes:
+  data: {}
+`
+	chunks := chunker.ChunkWithContext("compose.yaml", content)
+
+	if len(chunks) != 3 {
+		t.Fatalf("expected 3 chunks (one per top-level key), got %d", len(chunks))
+	}
+
+	if chunks[0].StartLine != 1 || chunks[0].EndLine != 2 {
+		t.Errorf("expected first chunk to span lines 1-2 including leading comment, got %d-%d", chunks[0].StartLine, chunks[0].EndLine)
+	}
+
+	services := chunks[1]
+	if services.StartLine != 3 || services.EndLine != 6 {
+		t.Errorf("expected services chunk to span lines 3-6, got %d-%d", services.StartLine, services.EndLine)
+	}
+	if !strings.HasPrefix(services.Content, "File: compose.yaml\nKeys: services, services.api, services.api.env, services.api.env.REDIS_TIMEOUT\n\n") {
+		t.Errorf("unexpected context header: %q", services.Content)
+	}
+	if strings.Contains(services.Content, "volumes") {
+		t.Error("services chunk should not contain the next top-level key")
+	}
+}
+
+func TestChunker_ChunkWithContext_YAMLDocuments(t *testing.T) {
+	chunker := NewChunker(512, 50)
+	content := `service:
+  port: 80
+---
+deployment:
+  replicas: 2
+`
+	chunks := chunker.ChunkWithContext("deploy.yaml", content)
+
+	if len(chunks) != 2 {
+		t.Fatalf("expected one chunk per document key, got %d", len(chunks))
+	}
+	if chunks[0].StartLine != 1 || chunks[0].EndLine != 2 || strings.Contains(chunks[0].Content, "deployment") {
+		t.Errorf("expected the first document in lines 1-2, got %d-%d %q", chunks[0].StartLine, chunks[0].EndLine, chunks[0].Content)
+	}
+	if chunks[1].StartLine != 3 || !strings.Contains(chunks[1].Content, "Keys: deployment, deployment.replicas\n") {
+		t.Errorf("expected the second document from line 3, got %d-%d %q", chunks[1].StartLine, chunks[1].EndLine, chunks[1].Content)
+	}
+}
+
+func TestChunker_ChunkWithContext_StructuredFallback(t *testing.T) {
+	chunker := NewChunker(512, 50)
+	chunks := chunker.ChunkWithContext("broken.json", `{"a": `)
+
+	if len(chunks) != 1 {
+		t.Fatalf("expected fallback to a single character-based chunk, got %d", len(chunks))
+	}
+	if chunks[0].Content != "File: broken.json\n\n{\"a\": " {
+		t.Errorf("unexpected fallback content: %q", chunks[0].Content)
+	}
+}
+
+func TestChunker_ChunkWithContext_OversizedBlock(t *testing.T) {
+	chunker := NewChunker(64, 0)
+	var sb strings.Builder
+	sb.WriteString("small: 1\nbig:\n")
+	for i := 0; i < 100; i++ {
+		sb.WriteString("  key_with_a_long_name: value\n")
+	}
+	chunks := chunker.ChunkWithContext("values.yml", sb.String())
+
+	if len(chunks) < 3 {
+		t.Fatalf("expected oversized block to be split, got %d chunks", len(chunks))
+	}
+	if chunks[1].StartLine != 2 {
+		t.Errorf("expected split block to start at line 2, got %d", chunks[1].StartLine)
+	}
+	last := chunks[len(chunks)-1]
+	if last.EndLine != 102 {
+		t.Errorf("expected last chunk to end at line 102, got %d", last.EndLine)
+	}
+}
+
+func TestChunker_ReChunk_PreservesKeysHeader(t *testing.T) {
+	chunker := NewChunker(128, 0)
+	header := "File: main.tf\nKeys: resource.aws_s3_bucket.logs\n\n"
+	parent := ChunkInfo{
+		ID:        "main.tf_0",
+		FilePath:  "main.tf",
+		StartLine: 1,
+		EndLine:   60,
+		Content:   header + strings.Repeat("  acl = \"private\"\n", 60),
+		KeyPaths:  []string{"resource.aws_s3_bucket.logs"},
+	}
+
+	subChunks := chunker.ReChunk(parent, 0)
+	if len(subChunks) < 2 {
+		t.Fatalf("expected multiple sub-chunks, got %d", len(subChunks))
+	}
+	for i, sub := range subChunks {
+		if !strings.HasPrefix(sub.Content, header) {
+			t.Errorf("sub-chunk %d lost context header: %q", i, sub.Content[:40])
+		}
+		if strings.Count(sub.Content, "Keys:") != 1 {
+			t.Errorf("sub-chunk %d has a duplicated header", i)
+		}
+	}
+}
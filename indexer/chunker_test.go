@@ -418,3 +418,40 @@ func TestChunker_ReChunkUTF8Boundaries(t *testing.T) {
 		}
 	}
 }
+
+// wordTokenizer counts whitespace-separated words as tokens.
+type wordTokenizer struct{}
+
+func (wordTokenizer) Name() string          { return "words" }
+func (wordTokenizer) Count(text string) int { return len(strings.Fields(text)) }
+
+func TestChunker_ChunkWithTokenizer(t *testing.T) {
+	chunker := NewChunker(20, 5, WithTokenizer(wordTokenizer{}))
+	content := strings.Repeat("alpha beta gamma delta\n", 30) // 120 words
+
+	chunks := chunker.Chunk("words.txt", content)
+	if len(chunks) < 6 {
+		t.Fatalf("expected at least 6 chunks of <= 20 words, got %d", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if n := len(strings.Fields(chunk.Content)); n > 20 {
+			t.Errorf("chunk %d has %d tokens, exceeds chunk size 20", i, n)
+		}
+	}
+	if chunks[1].StartLine >= chunks[0].EndLine+1 {
+		t.Errorf("expected overlap between chunks 0 and 1, got %d-%d then %d-%d",
+			chunks[0].StartLine, chunks[0].EndLine, chunks[1].StartLine, chunks[1].EndLine)
+	}
+	if last := chunks[len(chunks)-1]; last.EndLine != 30 {
+		t.Errorf("expected last chunk to end at line 30, got %d", last.EndLine)
+	}
+}
+
+func TestChunker_TokenizerAccessor(t *testing.T) {
+	if NewChunker(100, 10).Tokenizer() != nil {
+		t.Error("expected nil tokenizer for estimate-based chunker")
+	}
+	if NewChunker(100, 10, WithTokenizer(wordTokenizer{})).Tokenizer() == nil {
+		t.Error("expected configured tokenizer")
+	}
+}
@@ -0,0 +1,154 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yoanbernabeu/grepai/embedder"
+	"github.com/yoanbernabeu/grepai/store"
+)
+
+// DirEstimate aggregates an Estimate for one top-level directory.
+type DirEstimate struct {
+	Path         string `json:"path"` // "." for files at the project root
+	Files        int    `json:"files"`
+	Chunks       int    `json:"chunks"`
+	Tokens       int    `json:"tokens"`
+	CachedChunks int    `json:"cached_chunks"`
+}
+
+// Estimate describes the work an indexing run would do, without embedding
+// anything.
+type Estimate struct {
+	Files          int           // Files that would be (re)indexed
+	Chunks         int           // Chunks produced by those files
+	Tokens         int           // Tokens in all chunks
+	CachedChunks   int           // Chunks whose embedding is already in the store or the shared cache
+	UncachedTokens int           // Tokens that would be sent to the embedder
+	FilesToEmbed   int           // Files with at least one uncached chunk
+	Batches        int           // Cross-file batches for batch embedders
+	Dirs           []DirEstimate // Per top-level directory, by tokens descending
+}
+
+// Estimate runs the scanner and chunker over the files an indexing run would
+// process and counts chunks, tokens and embedding cache hits. When paths is
+// non-empty only those files are considered. With full, unchanged files are
+// included and the embedding cache of the store is ignored, since a full run
+// clears the index first.
+func (idx *Indexer) Estimate(ctx context.Context, paths []string, full bool) (*Estimate, error) {
+	if len(paths) == 0 {
+		fileMetas, _, err := idx.scanner.ScanMetadata()
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan files: %w", err)
+		}
+		for _, fileMeta := range fileMetas {
+			if !full && !idx.lastIndexTime.IsZero() && !time.Unix(fileMeta.ModTime, 0).After(idx.lastIndexTime) {
+				continue
+			}
+			paths = append(paths, fileMeta.Path)
+		}
+	}
+
+	var cache store.EmbeddingCache
+	if !full {
+		cache, _ = idx.store.(store.EmbeddingCache)
+	}
+	// The shared embedding cache outlives the index, even with full
+	shared, _ := idx.embedder.(embedder.CacheChecker)
+
+	est := &Estimate{}
+	dirs := make(map[string]*DirEstimate)
+	var toEmbed []embedder.FileChunks
+
+	for _, path := range paths {
+		if !idx.scanner.Indexable(path) {
+			continue
+		}
+		file, err := idx.scanner.ScanFile(path)
+		if err != nil || file == nil {
+			continue
+		}
+		if !full {
+			doc, err := idx.store.GetDocument(ctx, path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get document %s: %w", path, err)
+			}
+			if doc != nil && doc.Hash == file.Hash {
+				continue // File unchanged
+			}
+		}
+
+		dir := topLevelDir(file.Path)
+		d, ok := dirs[dir]
+		if !ok {
+			d = &DirEstimate{Path: dir}
+			dirs[dir] = d
+		}
+		est.Files++
+		d.Files++
+
+		var uncached []string
+		chunker := idx.chunkerFor(file.Path)
+		for _, chunk := range chunker.ChunkWithContext(file.Path, file.Content) {
+			tokens := chunker.countTokens(chunk.Content)
+			est.Chunks++
+			est.Tokens += tokens
+			d.Chunks++
+			d.Tokens += tokens
+
+			if cache != nil && chunk.ContentHash != "" {
+				_, found, err := cache.LookupByContentHash(ctx, chunk.ContentHash)
+				if err != nil {
+					log.Printf("Warning: cache lookup failed: %v", err)
+				} else if found {
+					est.CachedChunks++
+					d.CachedChunks++
+					continue
+				}
+			}
+			// The shared cache is keyed by what a run sends: the chunk with
+			// secrets masked (the document prefix is added by the embedder)
+			if shared != nil && shared.Cached(idx.embeddingInput(chunk.Content)) {
+				est.CachedChunks++
+				d.CachedChunks++
+				continue
+			}
+			est.UncachedTokens += tokens
+			uncached = append(uncached, chunk.Content)
+		}
+		if len(uncached) > 0 {
+			toEmbed = append(toEmbed, embedder.FileChunks{FileIndex: len(toEmbed), Chunks: uncached})
+		}
+	}
+
+	est.FilesToEmbed = len(toEmbed)
+	est.Batches = len(embedder.FormBatchesWithTokenizer(toEmbed, idx.chunker.Tokenizer()))
+
+	est.Dirs = make([]DirEstimate, 0, len(dirs))
+	for _, d := range dirs {
+		est.Dirs = append(est.Dirs, *d)
+	}
+	sort.Slice(est.Dirs, func(i, j int) bool {
+		if est.Dirs[i].Tokens != est.Dirs[j].Tokens {
+			return est.Dirs[i].Tokens > est.Dirs[j].Tokens
+		}
+		return est.Dirs[i].Path < est.Dirs[j].Path
+	})
+	return est, nil
+}
+
+// topLevelDir returns the first path component of a directory-qualified
+// path, or "." for files at the root.
+func topLevelDir(relPath string) string {
+	relPath = filepath.ToSlash(relPath)
+	dir, _, ok := strings.Cut(relPath, "/")
+	if !ok {
+		return "."
+	}
+	return dir
+}
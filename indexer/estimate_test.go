@@ -0,0 +1,87 @@
+package indexer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yoanbernabeu/grepai/store"
+)
+
+func TestEstimate(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+
+	files := map[string]string{
+		"main.go":          "package main\n\nfunc main() {}\n",
+		"pkg/a.go":         "package pkg\n\nfunc A() {}\n",
+		"pkg/b.go":         "package pkg\n\nfunc B() {}\n",
+		"pkg/unchanged.go": "package pkg\n\nfunc Unchanged() {}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+	}
+
+	ignoreMatcher, err := NewIgnoreMatcher(tmpDir, []string{}, "")
+	if err != nil {
+		t.Fatalf("failed to create ignore matcher: %v", err)
+	}
+	scanner := NewScanner(tmpDir, ignoreMatcher)
+	unchanged, err := scanner.ScanFile(filepath.Join("pkg", "unchanged.go"))
+	if err != nil {
+		t.Fatalf("ScanFile failed: %v", err)
+	}
+
+	mockStore := newMockStore()
+	mockStore.documents[unchanged.Path] = store.Document{Path: unchanged.Path, Hash: unchanged.Hash}
+	indexer := NewIndexer(tmpDir, mockStore, nil, NewChunker(512, 50), scanner, time.Time{})
+
+	est, err := indexer.Estimate(ctx, nil, false)
+	if err != nil {
+		t.Fatalf("Estimate failed: %v", err)
+	}
+	if est.Files != 3 {
+		t.Errorf("expected 3 files (unchanged skipped), got %d", est.Files)
+	}
+	if est.Chunks == 0 || est.Tokens == 0 || est.UncachedTokens != est.Tokens {
+		t.Errorf("unexpected counts: %+v", est)
+	}
+	if est.FilesToEmbed != 3 || est.Batches != 1 {
+		t.Errorf("expected 3 files to embed in 1 batch, got %d files and %d batches", est.FilesToEmbed, est.Batches)
+	}
+	if len(est.Dirs) != 2 || est.Dirs[0].Path != "pkg" || est.Dirs[0].Files != 2 || est.Dirs[1].Path != "." {
+		t.Errorf("unexpected directories: %+v", est.Dirs)
+	}
+
+	full, err := indexer.Estimate(ctx, nil, true)
+	if err != nil {
+		t.Fatalf("Estimate failed: %v", err)
+	}
+	if full.Files != 4 {
+		t.Errorf("expected 4 files for a full estimate, got %d", full.Files)
+	}
+	if len(mockStore.documents) != 1 || mockStore.saveDocCalled {
+		t.Error("Estimate should not modify the index")
+	}
+}
+
+func TestTopLevelDir(t *testing.T) {
+	tests := map[string]string{
+		"main.go":                         ".",
+		filepath.Join("pkg", "a.go"):      "pkg",
+		filepath.Join("a", "b", "c", "d"): "a",
+	}
+	for path, want := range tests {
+		if got := topLevelDir(path); got != want {
+			t.Errorf("topLevelDir(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
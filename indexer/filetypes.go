@@ -0,0 +1,294 @@
+package indexer
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// extensionLanguages maps indexed extensions to the language recorded on chunks.
+// Extensions missing here (e.g. from index.include_extensions) use the
+// extension itself without the dot.
+var extensionLanguages = map[string]string{
+	".go":         "go",
+	".js":         "javascript",
+	".jsx":        "javascript",
+	".ts":         "typescript",
+	".tsx":        "typescript",
+	".py":         "python",
+	".rb":         "ruby",
+	".java":       "java",
+	".c":          "c",
+	".h":          "c",
+	".cpp":        "cpp",
+	".cc":         "cpp",
+	".hpp":        "cpp",
+	".cs":         "csharp",
+	".php":        "php",
+	".rs":         "rust",
+	".swift":      "swift",
+	".kt":         "kotlin",
+	".kts":        "kotlin",
+	".scala":      "scala",
+	".vue":        "vue",
+	".svelte":     "svelte",
+	".html":       "html",
+	".css":        "css",
+	".scss":       "scss",
+	".less":       "less",
+	".sql":        "sql",
+	".sh":         "shell",
+	".bash":       "shell",
+	".zsh":        "shell",
+	".yaml":       "yaml",
+	".yml":        "yaml",
+	".json":       "json",
+	".xml":        "xml",
+	".md":         "markdown",
+	".txt":        "text",
+	".toml":       "toml",
+	".ini":        "ini",
+	".cfg":        "ini",
+	".conf":       "ini",
+	".env":        "dotenv",
+	".lua":        "lua",
+	".r":          "r",
+	".dart":       "dart",
+	".ex":         "elixir",
+	".exs":        "elixir",
+	".erl":        "erlang",
+	".clj":        "clojure",
+	".hs":         "haskell",
+	".ml":         "ocaml",
+	".fs":         "fsharp",
+	".elm":        "elm",
+	".nim":        "nim",
+	".zig":        "zig",
+	".proto":      "protobuf",
+	".tf":         "terraform",
+	".hcl":        "hcl",
+	".pas":        "pascal",
+	".dpr":        "pascal",
+	".ipynb":      "jupyter",
+	".gradle":     "groovy",
+	".groovy":     "groovy",
+	".cmake":      "cmake",
+	".graphql":    "graphql",
+	".gql":        "graphql",
+	".mk":         "make",
+	".dockerfile": "dockerfile",
+	".bazel":      "starlark",
+	".bzl":        "starlark",
+}
+
+// DefaultFilenames lists extensionless (or specially named) files indexed by
+// name, with the language recorded for them. Patterns use filepath.Match syntax
+// against the base name.
+var DefaultFilenames = map[string]string{
+	"Dockerfile":     "dockerfile",
+	"Dockerfile.*":   "dockerfile",
+	"Containerfile":  "dockerfile",
+	"Makefile":       "make",
+	"makefile":       "make",
+	"GNUmakefile":    "make",
+	"Jenkinsfile":    "groovy",
+	"Rakefile":       "ruby",
+	"Gemfile":        "ruby",
+	"Vagrantfile":    "ruby",
+	"Brewfile":       "ruby",
+	"CMakeLists.txt": "cmake",
+	"BUILD":          "starlark",
+	"WORKSPACE":      "starlark",
+	"Justfile":       "just",
+	"justfile":       "just",
+	"Procfile":       "procfile",
+}
+
+// shebangLanguages maps interpreter names (version suffix removed) to languages.
+var shebangLanguages = map[string]string{
+	"sh":      "shell",
+	"bash":    "shell",
+	"zsh":     "shell",
+	"ksh":     "shell",
+	"dash":    "shell",
+	"ash":     "shell",
+	"python":  "python",
+	"node":    "javascript",
+	"nodejs":  "javascript",
+	"deno":    "typescript",
+	"bun":     "javascript",
+	"ruby":    "ruby",
+	"php":     "php",
+	"lua":     "lua",
+	"Rscript": "r",
+	"pwsh":    "powershell",
+}
+
+// maxShebangLength bounds how much of a file is read when sniffing a shebang.
+const maxShebangLength = 256
+
+// FileTypes decides which files are indexed and which language they are
+// recorded with: by extension, by file name, or by shebang for extensionless
+// executables.
+type FileTypes struct {
+	extensions map[string]string // lowercase extension -> language
+	filenames  map[string]string // base name or pattern -> language
+}
+
+// DefaultFileTypes returns the built-in registry (SupportedExtensions and DefaultFilenames).
+func DefaultFileTypes() *FileTypes {
+	return NewFileTypes(nil, nil, nil)
+}
+
+// NewFileTypes builds a registry from the defaults plus the include/exclude
+// extensions and extra file names from the index configuration. Extensions
+// may be given with or without the leading dot.
+func NewFileTypes(include, exclude, filenames []string) *FileTypes {
+	ft := &FileTypes{
+		extensions: make(map[string]string, len(SupportedExtensions)+len(include)),
+		filenames:  make(map[string]string, len(DefaultFilenames)+len(filenames)),
+	}
+	for ext := range SupportedExtensions {
+		ft.addExtension(ext)
+	}
+	for _, ext := range include {
+		ft.addExtension(ext)
+	}
+	for _, ext := range exclude {
+		delete(ft.extensions, normalizeExtension(ext))
+	}
+	for name, lang := range DefaultFilenames {
+		ft.filenames[name] = lang
+	}
+	for _, name := range filenames {
+		if _, ok := ft.filenames[name]; !ok {
+			ft.filenames[name] = ""
+		}
+	}
+	return ft
+}
+
+func (ft *FileTypes) addExtension(ext string) {
+	ext = normalizeExtension(ext)
+	if ext == "" {
+		return
+	}
+	lang, ok := extensionLanguages[ext]
+	if !ok {
+		lang = strings.TrimPrefix(ext, ".")
+	}
+	ft.extensions[ext] = lang
+}
+
+func normalizeExtension(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// Match returns the language of a file from its name or extension. File names
+// take precedence, so CMakeLists.txt is cmake rather than text.
+func (ft *FileTypes) Match(relPath string) (string, bool) {
+	base := filepath.Base(relPath)
+	if lang, ok := ft.filenames[base]; ok {
+		return lang, true
+	}
+	for pattern, lang := range ft.filenames {
+		if ok, _ := filepath.Match(pattern, base); ok {
+			return lang, true
+		}
+	}
+	lang, ok := ft.extensions[strings.ToLower(filepath.Ext(base))]
+	return lang, ok
+}
+
+// MaybeScript reports whether a file has no extension and is not matched by
+// name, i.e. whether it should be sniffed for a shebang.
+func (ft *FileTypes) MaybeScript(relPath string) bool {
+	base := filepath.Base(relPath)
+	if filepath.Ext(base) != "" || strings.HasPrefix(base, ".") {
+		return false
+	}
+	_, ok := ft.Match(relPath)
+	return !ok
+}
+
+// Detect returns the language of the file at absPath, sniffing the shebang of
+// extensionless executables. ok is false when the file should not be indexed.
+func (ft *FileTypes) Detect(absPath, relPath string) (string, bool) {
+	return ft.detect(os.DirFS(filepath.Dir(absPath)), filepath.Base(absPath), relPath)
+}
+
+// DetectFS is Detect for the file at relPath in fsys.
+func (ft *FileTypes) DetectFS(fsys fs.FS, relPath string) (string, bool) {
+	return ft.detect(fsys, filepath.ToSlash(relPath), relPath)
+}
+
+func (ft *FileTypes) detect(fsys fs.FS, name, relPath string) (string, bool) {
+	if lang, ok := ft.Match(relPath); ok {
+		return lang, true
+	}
+	if !ft.MaybeScript(relPath) {
+		return "", false
+	}
+
+	info, err := fs.Stat(fsys, name)
+	if err != nil || !info.Mode().IsRegular() {
+		return "", false
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0111 == 0 {
+		return "", false // Only executables are sniffed
+	}
+
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	line, err := bufio.NewReaderSize(f, maxShebangLength).ReadSlice('\n')
+	if err != nil && len(line) == 0 {
+		return "", false
+	}
+	lang := ShebangLanguage(string(line))
+	return lang, lang != ""
+}
+
+// ShebangLanguage returns the language named by a "#!" interpreter line at the
+// start of content, or "" if there is none. "#!/usr/bin/env -S python3 -u"
+// yields python; unknown interpreters are returned as-is (e.g. perl).
+func ShebangLanguage(content string) string {
+	if !strings.HasPrefix(content, "#!") {
+		return ""
+	}
+	line, _, _ := strings.Cut(content[2:], "\n")
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if strings.HasPrefix(field, "-") || strings.Contains(field, "=") {
+				continue // env options and VAR=value assignments
+			}
+			interpreter = filepath.Base(field)
+			break
+		}
+	}
+	interpreter = strings.TrimRight(interpreter, "0123456789.")
+	if interpreter == "" {
+		return ""
+	}
+	if lang, ok := shebangLanguages[interpreter]; ok {
+		return lang
+	}
+	return interpreter
+}
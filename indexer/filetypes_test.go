@@ -0,0 +1,107 @@
+package indexer
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestFileTypes_Match(t *testing.T) {
+	ft := NewFileTypes([]string{"vhdl", ".TXT"}, []string{".txt", "json"}, []string{"Tiltfile", "*.nix"})
+
+	tests := []struct {
+		path   string
+		lang   string
+		wantOK bool
+	}{
+		{"cmd/main.go", "go", true},
+		{"build/Dockerfile", "dockerfile", true},
+		{"Dockerfile.dev", "dockerfile", true},
+		{"Makefile", "make", true},
+		{"ci/Jenkinsfile", "groovy", true},
+		{"app/build.gradle", "groovy", true},
+		{"cmake/deps.cmake", "cmake", true},
+		{"CMakeLists.txt", "cmake", true}, // File names win over excluded extensions
+		{"schema.graphql", "graphql", true},
+		{"rtl/alu.vhdl", "vhdl", true},
+		{"Tiltfile", "", true},
+		{"shell.nix", "", true},
+		{"notes.txt", "", false},
+		{"package.json", "", false},
+		{"image.png", "", false},
+	}
+
+	for _, tt := range tests {
+		lang, ok := ft.Match(tt.path)
+		if ok != tt.wantOK || lang != tt.lang {
+			t.Errorf("Match(%q) = %q, %v; want %q, %v", tt.path, lang, ok, tt.lang, tt.wantOK)
+		}
+	}
+}
+
+func TestShebangLanguage(t *testing.T) {
+	tests := map[string]string{
+		"#!/bin/sh\necho hi\n":              "shell",
+		"#!/usr/bin/env bash\n":             "shell",
+		"#!/usr/bin/env python3\n":          "python",
+		"#!/usr/bin/env -S python3.11 -u\n": "python",
+		"#!/usr/bin/env FOO=1 node\n":       "javascript",
+		"#!/usr/local/bin/ruby -w\n":        "ruby",
+		"#!/usr/bin/perl\n":                 "perl",
+		"#!\n":                              "",
+		"# just a comment\n":                "",
+		"package main\n\nfunc main() {}\n":  "",
+	}
+	for content, want := range tests {
+		if got := ShebangLanguage(content); got != want {
+			t.Errorf("ShebangLanguage(%q) = %q, want %q", content, got, want)
+		}
+	}
+}
+
+func TestScanner_FileTypes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("executable bits are not available on windows")
+	}
+	root := t.TempDir()
+	files := []struct {
+		name    string
+		content string
+		mode    os.FileMode
+	}{
+		{"main.go", "package main\n", 0644},
+		{"Dockerfile", "FROM golang:1.24\n", 0644},
+		{"deploy", "#!/usr/bin/env bash\nset -e\n", 0755},
+		{"notes", "#!/bin/sh\nnot executable\n", 0644},
+		{"LICENSE", "MIT\n", 0644},
+	}
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(root, f.name), []byte(f.content), f.mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ignore, err := NewIgnoreMatcher(root, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scanned, _, err := NewScanner(root, ignore).Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, f := range scanned {
+		got[f.Path] = f.Language
+	}
+	want := map[string]string{"main.go": "go", "Dockerfile": "dockerfile", "deploy": "shell"}
+	if len(got) != len(want) {
+		t.Errorf("scanned %v, want %v", got, want)
+	}
+	for path, lang := range want {
+		if got[path] != lang {
+			t.Errorf("language of %s = %q, want %q", path, got[path], lang)
+		}
+	}
+}
@@ -0,0 +1,373 @@
+package indexer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	ignore "github.com/sabhiram/go-gitignore"
+)
+
+// Generated file handling modes (index.generated.mode).
+const (
+	GeneratedSkip    = "skip"    // Do not index generated or vendored files
+	GeneratedPenalty = "penalty" // Index them, flagged so search can down-rank them
+	GeneratedKeep    = "keep"    // Index them like any other file
+)
+
+// Reasons recorded when a file is detected as generated or vendored.
+const (
+	ReasonGeneratedHeader = "header"             // "Code generated" / "@generated" marker
+	ReasonLongLines       = "long lines"         // Average line length above the threshold
+	ReasonLinguistGen     = "linguist-generated" // .gitattributes linguist-generated
+	ReasonLinguistVendor  = "linguist-vendored"  // .gitattributes linguist-vendored
+)
+
+const (
+	// DefaultMaxAvgLineLength is the average line length above which a file is
+	// treated as machine-produced (minified bundles, embedded data, ...).
+	DefaultMaxAvgLineLength = 300
+
+	// generatedHeaderLines is how many leading lines are searched for markers.
+	generatedHeaderLines = 40
+
+	// minLongLineFileSize avoids flagging tiny one-liners as minified.
+	minLongLineFileSize = 1024
+)
+
+var (
+	// Go convention (https://go.dev/s/generatedcode), also followed by protoc,
+	// swagger, sqlc and friends: a "Code generated by X." comment ending in
+	// "DO NOT EDIT.".
+	codeGeneratedRe = regexp.MustCompile(`(?i)\bcode generated\b.*\bdo not edit\b`)
+
+	// Facebook/Meta style marker, also used by Relay, Thrift and Buck.
+	atGeneratedRe = regexp.MustCompile(`@generated\b`)
+)
+
+// proseExtensions lists extensions whose long lines are normal (soft-wrapped
+// paragraphs) or synthetic (re-serialized notebooks).
+var proseExtensions = map[string]bool{
+	".md":    true,
+	".txt":   true,
+	".ipynb": true,
+}
+
+// DetectGeneratedContent inspects file content and returns the reason it looks
+// generated, or "" when it looks hand-written. Markers are only honored in
+// comment lines near the top of the file so that code mentioning them (like
+// this detector) is not flagged.
+func DetectGeneratedContent(filePath, content string, maxAvgLineLength int) string {
+	lines := 0
+	for line := range strings.Lines(content) {
+		lines++
+		if lines > generatedHeaderLines {
+			break
+		}
+		trimmed := strings.TrimSpace(line)
+		if !isCommentLine(trimmed) {
+			continue
+		}
+		if codeGeneratedRe.MatchString(trimmed) || atGeneratedRe.MatchString(trimmed) {
+			return ReasonGeneratedHeader
+		}
+	}
+
+	if maxAvgLineLength > 0 && len(content) >= minLongLineFileSize &&
+		!proseExtensions[strings.ToLower(filepath.Ext(filePath))] {
+		lineCount := strings.Count(content, "\n")
+		if !strings.HasSuffix(content, "\n") {
+			lineCount++
+		}
+		if len(content)/lineCount > maxAvgLineLength {
+			return ReasonLongLines
+		}
+	}
+
+	return ""
+}
+
+// isCommentLine reports whether a trimmed line starts with a comment marker
+// of one of the supported languages.
+func isCommentLine(line string) bool {
+	for _, prefix := range []string{"//", "#", "/*", "*", "--", "<!--", ";", "%", "{-", "(*"} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// attrRule is one linguist attribute assignment from a .gitattributes file.
+type attrRule struct {
+	matcher *ignore.GitIgnore
+	reason  string // ReasonLinguistGen or ReasonLinguistVendor
+	set     bool
+}
+
+// GitAttributes resolves linguist-generated and linguist-vendored attributes
+// from the .gitattributes files of a project. Files are loaded lazily per
+// directory; deeper files override shallower ones and later lines override
+// earlier ones, as in git.
+type GitAttributes struct {
+	fsys fs.FS
+
+	mu   sync.Mutex
+	dirs map[string][]attrRule
+}
+
+// NewGitAttributes creates a resolver for the project at root.
+func NewGitAttributes(root string) *GitAttributes {
+	return NewGitAttributesFS(os.DirFS(root))
+}
+
+// NewGitAttributesFS creates a resolver reading .gitattributes files from fsys.
+func NewGitAttributesFS(fsys fs.FS) *GitAttributes {
+	return &GitAttributes{
+		fsys: fsys,
+		dirs: make(map[string][]attrRule),
+	}
+}
+
+// Lookup returns ReasonLinguistGen or ReasonLinguistVendor when the file is
+// marked as such, or "" otherwise. relPath is relative to the project root.
+func (g *GitAttributes) Lookup(relPath string) string {
+	relPath = filepath.ToSlash(relPath)
+
+	generated, vendored := false, false
+	dir := ""
+	rest := relPath
+	for {
+		for _, rule := range g.rulesFor(dir) {
+			if !rule.matcher.MatchesPath(rest) {
+				continue
+			}
+			if rule.reason == ReasonLinguistGen {
+				generated = rule.set
+			} else {
+				vendored = rule.set
+			}
+		}
+
+		head, tail, ok := strings.Cut(rest, "/")
+		if !ok {
+			break
+		}
+		dir = path.Join(dir, head)
+		rest = tail
+	}
+
+	switch {
+	case generated:
+		return ReasonLinguistGen
+	case vendored:
+		return ReasonLinguistVendor
+	}
+	return ""
+}
+
+func (g *GitAttributes) rulesFor(dir string) []attrRule {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if rules, ok := g.dirs[dir]; ok {
+		return rules
+	}
+	rules := loadAttrRules(g.fsys, path.Join(dir, ".gitattributes"))
+	g.dirs[dir] = rules
+	return rules
+}
+
+// loadAttrRules parses the linguist attributes of a .gitattributes file.
+// A missing or unreadable file yields no rules.
+func loadAttrRules(fsys fs.FS, name string) []attrRule {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var rules []attrRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		var matcher *ignore.GitIgnore
+		for _, attr := range fields[1:] {
+			reason, set, ok := parseLinguistAttr(attr)
+			if !ok {
+				continue
+			}
+			if matcher == nil {
+				matcher = ignore.CompileIgnoreLines(fields[0])
+			}
+			rules = append(rules, attrRule{matcher: matcher, reason: reason, set: set})
+		}
+	}
+	return rules
+}
+
+// parseLinguistAttr parses "linguist-generated", "-linguist-generated",
+// "!linguist-generated" and "linguist-generated=true|false" (and the same for
+// linguist-vendored).
+func parseLinguistAttr(attr string) (reason string, set bool, ok bool) {
+	set = true
+	if strings.HasPrefix(attr, "-") || strings.HasPrefix(attr, "!") {
+		attr = attr[1:]
+		set = false
+	}
+	name, value, hasValue := strings.Cut(attr, "=")
+	switch name {
+	case "linguist-generated":
+		reason = ReasonLinguistGen
+	case "linguist-vendored":
+		reason = ReasonLinguistVendor
+	default:
+		return "", false, false
+	}
+	if hasValue {
+		set = set && value != "false"
+	}
+	return reason, set, true
+}
+
+// GeneratedFile records why a file was detected as generated and whether it
+// was skipped or indexed with a penalty.
+type GeneratedFile struct {
+	Reason  string `json:"reason"`
+	Skipped bool   `json:"skipped"`
+}
+
+// GeneratedReport tracks generated and vendored files detected across scans.
+// It is persisted so that `grepai status` can report counts without rescanning.
+type GeneratedReport struct {
+	mu    sync.Mutex
+	path  string
+	dirty bool
+	Files map[string]GeneratedFile `json:"files"`
+}
+
+// LoadGeneratedReport loads the report at path. A missing file yields an empty report.
+func LoadGeneratedReport(reportPath string) (*GeneratedReport, error) {
+	r := &GeneratedReport{path: reportPath, Files: make(map[string]GeneratedFile)}
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return r, nil
+		}
+		return r, fmt.Errorf("failed to read generated file report: %w", err)
+	}
+	if err := json.Unmarshal(data, r); err != nil {
+		return r, fmt.Errorf("failed to parse generated file report: %w", err)
+	}
+	if r.Files == nil {
+		r.Files = make(map[string]GeneratedFile)
+	}
+	return r, nil
+}
+
+// record sets or clears (reason == "") the entry for a file.
+func (r *GeneratedReport) record(relPath, reason string, skipped bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if reason == "" {
+		if _, ok := r.Files[relPath]; ok {
+			delete(r.Files, relPath)
+			r.dirty = true
+		}
+		return
+	}
+	entry := GeneratedFile{Reason: reason, Skipped: skipped}
+	if r.Files[relPath] != entry {
+		r.Files[relPath] = entry
+		r.dirty = true
+	}
+}
+
+// isSkipped reports whether the file was last seen as a skipped generated file.
+func (r *GeneratedReport) isSkipped(relPath string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Files[relPath].Skipped
+}
+
+// prune drops entries for files that no longer exist in the project.
+func (r *GeneratedReport) prune(present map[string]bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for p := range r.Files {
+		if !present[p] {
+			delete(r.Files, p)
+			r.dirty = true
+		}
+	}
+}
+
+// Save writes the report to disk if it changed since it was loaded.
+func (r *GeneratedReport) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.dirty || r.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal generated file report: %w", err)
+	}
+	if err := os.WriteFile(r.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write generated file report: %w", err)
+	}
+	r.dirty = false
+	return nil
+}
+
+// GeneratedSummary aggregates a report for display.
+type GeneratedSummary struct {
+	Skipped   int
+	Penalized int
+	ByReason  map[string]int
+}
+
+// Summary counts skipped and penalized files, and all files by reason.
+func (r *GeneratedReport) Summary() GeneratedSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := GeneratedSummary{ByReason: make(map[string]int)}
+	for _, f := range r.Files {
+		if f.Skipped {
+			s.Skipped++
+		} else {
+			s.Penalized++
+		}
+		s.ByReason[f.Reason]++
+	}
+	return s
+}
+
+// FormatReasons renders the per-reason counts as "header: 3, long lines: 1".
+func (s GeneratedSummary) FormatReasons() string {
+	reasons := make([]string, 0, len(s.ByReason))
+	for reason := range s.ByReason {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	parts := make([]string, len(reasons))
+	for i, reason := range reasons {
+		parts[i] = fmt.Sprintf("%s: %d", reason, s.ByReason[reason])
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,127 @@
+package indexer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectGeneratedContent(t *testing.T) {
+	longLine := strings.Repeat("var a=1;", 100) + "\n"
+
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		want    string
+	}{
+		{"go header", "api.pb.go", "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage api\n", ReasonGeneratedHeader},
+		{"python header", "models.py", "# -*- coding: utf-8 -*-\n# Code generated by sqlc; DO NOT EDIT.\n", ReasonGeneratedHeader},
+		{"at generated", "Schema.java", "/**\n * @generated SignedSource<<abc>>\n */\nclass Schema {}\n", ReasonGeneratedHeader},
+		{"marker in code is ignored", "detect.go", "package x\n\nvar re = \"code generated .* do not edit\"\n", ""},
+		{"marker below header is ignored", "late.go", strings.Repeat("x := 1\n", 50) + "// Code generated by hand. DO NOT EDIT.\n", ""},
+		{"long lines", "bundle.js", strings.Repeat(longLine, 3), ReasonLongLines},
+		{"long lines in markdown", "README.md", strings.Repeat(longLine, 3), ""},
+		{"small file", "one.js", longLine[:500], ""},
+		{"hand written", "main.go", "package main\n\nfunc main() {}\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectGeneratedContent(tt.path, tt.content, DefaultMaxAvgLineLength); got != tt.want {
+				t.Errorf("DetectGeneratedContent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitAttributes_Lookup(t *testing.T) {
+	root := t.TempDir()
+	writeFile := func(rel, content string) {
+		t.Helper()
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(".gitattributes", "# linguist overrides\n*.pb.go linguist-generated\nthird_party/** linguist-vendored\ndocs/** -linguist-documentation\n")
+	writeFile("api/.gitattributes", "handwritten.pb.go linguist-generated=false\n")
+
+	tests := map[string]string{
+		"api/service.pb.go":     ReasonLinguistGen,
+		"api/handwritten.pb.go": "",
+		"third_party/lib/x.go":  ReasonLinguistVendor,
+		"docs/guide.md":         "",
+		"main.go":               "",
+	}
+
+	attrs := NewGitAttributes(root)
+	for path, want := range tests {
+		if got := attrs.Lookup(path); got != want {
+			t.Errorf("Lookup(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestScanner_GeneratedModes(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"main.go":   "package main\n\nfunc main() {}\n",
+		"api.pb.go": "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage main\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ignore, err := NewIgnoreMatcher(root, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reportPath := filepath.Join(root, "generated.json")
+	report, _ := LoadGeneratedReport(reportPath)
+	skip := NewScanner(root, ignore, WithGeneratedDetection(GeneratedSkip, DefaultMaxAvgLineLength, report))
+	scanned, skipped, err := skip.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(scanned) != 1 || scanned[0].Path != "main.go" {
+		t.Errorf("expected only main.go to be scanned, got %v", scanned)
+	}
+	if len(skipped) != 1 || skipped[0] != "api.pb.go (generated: header)" {
+		t.Errorf("unexpected skipped list: %v", skipped)
+	}
+	if !skip.SkippedAsGenerated("api.pb.go") {
+		t.Error("expected api.pb.go to be recorded as skipped")
+	}
+	if err := skip.SaveGeneratedReport(); err != nil {
+		t.Fatalf("SaveGeneratedReport failed: %v", err)
+	}
+	reloaded, err := LoadGeneratedReport(reportPath)
+	if err != nil {
+		t.Fatalf("LoadGeneratedReport failed: %v", err)
+	}
+	if summary := reloaded.Summary(); summary.Skipped != 1 || summary.ByReason[ReasonGeneratedHeader] != 1 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+
+	penalty := NewScanner(root, ignore, WithGeneratedDetection(GeneratedPenalty, DefaultMaxAvgLineLength, nil))
+	file, err := penalty.ScanFile("api.pb.go")
+	if err != nil || file == nil {
+		t.Fatalf("ScanFile returned %v, %v", file, err)
+	}
+	if !file.Generated {
+		t.Error("expected penalty mode to flag the file as generated")
+	}
+
+	keep := NewScanner(root, ignore, WithGeneratedDetection(GeneratedKeep, DefaultMaxAvgLineLength, nil))
+	file, err = keep.ScanFile("api.pb.go")
+	if err != nil || file == nil || file.Generated {
+		t.Errorf("expected keep mode to index the file unflagged, got %+v, %v", file, err)
+	}
+}
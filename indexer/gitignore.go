@@ -2,10 +2,13 @@ package indexer
 
 import (
 	"bufio"
+	"io/fs"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	ignore "github.com/sabhiram/go-gitignore"
 )
@@ -28,6 +31,16 @@ func expandTilde(path string) string {
 	return path
 }
 
+// GrepaiIgnoreFileName is the name of grepai-specific ignore files. They use
+// gitignore syntax and, like .gitignore files, apply to their directory and below.
+const GrepaiIgnoreFileName = ".grepaiignore"
+
+// IsIgnoreFile reports whether a file name is one of the ignore files read by
+// IgnoreMatcher.
+func IsIgnoreFile(name string) bool {
+	return name == ".gitignore" || name == GrepaiIgnoreFileName
+}
+
 // nestedMatcher holds a gitignore matcher and its base directory
 type nestedMatcher struct {
 	matcher *ignore.GitIgnore
@@ -35,20 +48,39 @@ type nestedMatcher struct {
 }
 
 type IgnoreMatcher struct {
-	projectRoot    string
+	fsys              fs.FS // Project files
+	externalGitignore string
+	extraDirs         []string
+
+	mu             sync.RWMutex
 	nestedMatchers []nestedMatcher
-	extraDirs      []string
 }
 
 func NewIgnoreMatcher(projectRoot string, extraIgnore []string, externalGitignore string) (*IgnoreMatcher, error) {
+	return NewIgnoreMatcherFS(os.DirFS(projectRoot), extraIgnore, externalGitignore)
+}
+
+// NewIgnoreMatcherFS creates a matcher reading the project's ignore files from
+// fsys, e.g. a git tree.
+func NewIgnoreMatcherFS(fsys fs.FS, extraIgnore []string, externalGitignore string) (*IgnoreMatcher, error) {
 	m := &IgnoreMatcher{
-		projectRoot: projectRoot,
-		extraDirs:   extraIgnore,
+		fsys:              fsys,
+		externalGitignore: externalGitignore,
+		extraDirs:         extraIgnore,
+	}
+	if err := m.Reload(); err != nil {
+		return nil, err
 	}
+	return m, nil
+}
+
+// Reload reads the .gitignore and .grepaiignore files of the project again.
+func (m *IgnoreMatcher) Reload() error {
+	var matchers []nestedMatcher
 
 	// Load external gitignore file if specified
-	if externalGitignore != "" {
-		expandedPath := expandTilde(externalGitignore)
+	if m.externalGitignore != "" {
+		expandedPath := expandTilde(m.externalGitignore)
 		gi, err := ignore.CompileIgnoreFile(expandedPath)
 		if err != nil {
 			if os.IsNotExist(err) {
@@ -57,50 +89,47 @@ func NewIgnoreMatcher(projectRoot string, extraIgnore []string, externalGitignor
 				log.Printf("Warning: failed to load external gitignore: %v", err)
 			}
 		} else {
-			m.nestedMatchers = append(m.nestedMatchers, nestedMatcher{
+			matchers = append(matchers, nestedMatcher{
 				matcher: gi,
 				baseDir: "", // External gitignore applies from root
 			})
 		}
 	}
 
-	// Walk the project to find all .gitignore files
-	err := filepath.Walk(projectRoot, func(path string, info os.FileInfo, err error) error {
+	// Walk the project to find all .gitignore and .grepaiignore files
+	err := fs.WalkDir(m.fsys, ".", func(name string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return nil // Skip inaccessible paths
 		}
 
 		// Skip directories that should be ignored by default
-		if info.IsDir() {
-			base := filepath.Base(path)
-			for _, dir := range extraIgnore {
-				if base == dir {
-					return filepath.SkipDir
+		if d.IsDir() {
+			for _, dir := range m.extraDirs {
+				if d.Name() == dir {
+					return fs.SkipDir
 				}
 			}
 			return nil
 		}
 
-		// Only process .gitignore files
-		if filepath.Base(path) != ".gitignore" {
+		// Only process ignore files
+		if !IsIgnoreFile(d.Name()) {
 			return nil
 		}
 
-		gi, err := ignore.CompileIgnoreFile(path)
+		data, err := fs.ReadFile(m.fsys, name)
 		if err != nil {
-			return nil // Skip invalid .gitignore files
+			return nil // Skip unreadable ignore files
 		}
+		gi := ignore.CompileIgnoreLines(strings.Split(string(data), "\n")...)
 
 		// Get relative base directory
-		relPath, err := filepath.Rel(projectRoot, filepath.Dir(path))
-		if err != nil {
-			return nil
-		}
+		relPath := filepath.FromSlash(path.Dir(name))
 		if relPath == "." {
 			relPath = ""
 		}
 
-		m.nestedMatchers = append(m.nestedMatchers, nestedMatcher{
+		matchers = append(matchers, nestedMatcher{
 			matcher: gi,
 			baseDir: relPath,
 		})
@@ -109,19 +138,22 @@ func NewIgnoreMatcher(projectRoot string, extraIgnore []string, externalGitignor
 	})
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// Add extra ignore patterns as a root-level matcher
-	if len(extraIgnore) > 0 {
-		gi := ignore.CompileIgnoreLines(extraIgnore...)
-		m.nestedMatchers = append(m.nestedMatchers, nestedMatcher{
+	if len(m.extraDirs) > 0 {
+		gi := ignore.CompileIgnoreLines(m.extraDirs...)
+		matchers = append(matchers, nestedMatcher{
 			matcher: gi,
 			baseDir: "",
 		})
 	}
 
-	return m, nil
+	m.mu.Lock()
+	m.nestedMatchers = matchers
+	m.mu.Unlock()
+	return nil
 }
 
 func (m *IgnoreMatcher) ShouldIgnore(path string) bool {
@@ -137,6 +169,8 @@ func (m *IgnoreMatcher) ShouldIgnore(path string) bool {
 	}
 
 	// Check nested gitignore patterns
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	for _, nm := range m.nestedMatchers {
 		// Determine the relative path from this matcher's base directory
 		var relPath string
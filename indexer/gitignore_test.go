@@ -555,3 +555,53 @@ func TestIgnoreMatcher_ExternalGitignore_WithTilde(t *testing.T) {
 		t.Error("ShouldIgnore(\"test.ignored\") = false, expected true")
 	}
 }
+
+func TestIgnoreMatcher_GrepaiignoreAndReload(t *testing.T) {
+	tmpDir := t.TempDir()
+	teamDir := filepath.Join(tmpDir, "teams", "web")
+	if err := os.MkdirAll(teamDir, 0755); err != nil {
+		t.Fatalf("failed to create team dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(teamDir, GrepaiIgnoreFileName), []byte("fixtures/\n*.snap\n"), 0644); err != nil {
+		t.Fatalf("failed to create .grepaiignore: %v", err)
+	}
+
+	matcher, err := NewIgnoreMatcher(tmpDir, []string{}, "")
+	if err != nil {
+		t.Fatalf("failed to create ignore matcher: %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"teams/web/fixtures", true},
+		{"teams/web/fixtures/user.json", true},
+		{"teams/web/app.snap", true},
+		{"teams/web/app.go", false},
+		{"teams/api/app.snap", false},
+		{"app.snap", false},
+	}
+	for _, tt := range tests {
+		if got := matcher.ShouldIgnore(tt.path); got != tt.expected {
+			t.Errorf("ShouldIgnore(%q) = %v, expected %v", tt.path, got, tt.expected)
+		}
+	}
+
+	// Changes apply after Reload
+	if err := os.WriteFile(filepath.Join(tmpDir, GrepaiIgnoreFileName), []byte("*.go\n"), 0644); err != nil {
+		t.Fatalf("failed to create root .grepaiignore: %v", err)
+	}
+	if err := os.Remove(filepath.Join(teamDir, GrepaiIgnoreFileName)); err != nil {
+		t.Fatalf("failed to remove .grepaiignore: %v", err)
+	}
+	if err := matcher.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if !matcher.ShouldIgnore("teams/web/app.go") {
+		t.Error("expected *.go to be ignored after reload")
+	}
+	if matcher.ShouldIgnore("teams/web/app.snap") {
+		t.Error("expected *.snap to no longer be ignored after reload")
+	}
+}
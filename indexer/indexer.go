@@ -4,9 +4,16 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/yoanbernabeu/grepai/config"
 	"github.com/yoanbernabeu/grepai/embedder"
+	"github.com/yoanbernabeu/grepai/redact"
 	"github.com/yoanbernabeu/grepai/store"
 )
 
@@ -17,14 +24,73 @@ type Indexer struct {
 	chunker       *Chunker
 	scanner       *Scanner
 	lastIndexTime time.Time
+
+	checkpointPath string      // Manifest of the run in progress ("" disables checkpoints)
+	checkpoint     *Checkpoint // Active during IndexAll when checkpointPath is set
+
+	redactor      *redact.Redactor // Masks secrets in embedding input (nil disables)
+	maskStored    bool             // Also mask secrets in stored chunk content
+	secretsReport *redact.Report   // Files in which secrets were masked (optional)
+
+	dirConfigs  *config.DirConfigs // Nested .grepai.yaml overrides (optional)
+	chunkersMu  sync.Mutex
+	dirChunkers map[config.ChunkingConfig]*Chunker // Chunkers for overridden sizes
+
+	scanWorkers int  // Files read, hashed and chunked in parallel (0 = number of CPUs)
+	rechunk     bool // Chunk unchanged files again during IndexAll
+
+	tokensEmbedded atomic.Int64
+}
+
+// IndexerOption configures an Indexer.
+type IndexerOption func(*Indexer)
+
+// WithCheckpoint persists completed files during IndexAll, with a manifest at
+// path, so that an interrupted run resumes where it stopped.
+func WithCheckpoint(path string) IndexerOption {
+	return func(idx *Indexer) {
+		idx.checkpointPath = path
+	}
+}
+
+// WithRedaction masks secrets found by r in the content sent to the embedder.
+// With maskStored, the content stored in the index is masked too. Files
+// containing secrets are recorded in report when it is not nil.
+func WithRedaction(r *redact.Redactor, maskStored bool, report *redact.Report) IndexerOption {
+	return func(idx *Indexer) {
+		idx.redactor = r
+		idx.maskStored = maskStored
+		idx.secretsReport = report
+	}
+}
+
+// WithDirConfigs applies the chunking overrides of nested .grepai.yaml files
+// to files in their subtree.
+func WithDirConfigs(dirs *config.DirConfigs) IndexerOption {
+	return func(idx *Indexer) {
+		idx.dirConfigs = dirs
+	}
+}
+
+// WithRechunk makes IndexAll chunk every file again, even when unchanged, so
+// that chunks of an index built before a change of the chunk metadata get
+// it. Vectors of unchanged chunks are reused from the store by content hash.
+func WithRechunk(rechunk bool) IndexerOption {
+	return func(idx *Indexer) {
+		idx.rechunk = rechunk
+	}
 }
 
 type IndexStats struct {
-	FilesIndexed  int
-	FilesSkipped  int
-	ChunksCreated int
-	FilesRemoved  int
-	Duration      time.Duration
+	FilesIndexed   int
+	FilesAdded     int // Indexed files that were not in the index before
+	FilesUpdated   int // Indexed files whose content changed
+	FilesSkipped   int
+	FilesFailed    int // Files that could not be chunked, embedded or saved
+	ChunksCreated  int
+	FilesRemoved   int
+	TokensEmbedded int64 // Tokens sent to the embedder (cached embeddings excluded)
+	Duration       time.Duration
 }
 
 // ProgressInfo contains progress information for indexing
@@ -58,8 +124,9 @@ func NewIndexer(
 	chunker *Chunker,
 	scanner *Scanner,
 	lastIndexTime time.Time,
+	opts ...IndexerOption,
 ) *Indexer {
-	return &Indexer{
+	idx := &Indexer{
 		root:          root,
 		store:         st,
 		embedder:      emb,
@@ -67,6 +134,10 @@ func NewIndexer(
 		scanner:       scanner,
 		lastIndexTime: lastIndexTime,
 	}
+	for _, opt := range opts {
+		opt(idx)
+	}
+	return idx
 }
 
 // IndexAll performs a full index of the project (no progress reporting)
@@ -103,22 +174,46 @@ func (idx *Indexer) IndexAllWithBatchProgress(ctx context.Context, onProgress Pr
 		existingMap[doc] = true
 	}
 
-	// Filter files that need indexing
-	filesToIndex := make([]FileInfo, 0, len(fileMetas))
-	for i, fileMeta := range fileMetas {
-		// Report progress for scanning phase
+	// Resume an interrupted run: files it completed or found unchanged are
+	// not read again unless modified since it started.
+	var resume *Checkpoint
+	if idx.checkpointPath != "" {
+		resume, err = LoadCheckpoint(idx.checkpointPath)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+		} else if resume != nil {
+			log.Printf("Resuming interrupted indexing: %d/%d files already indexed", resume.Completed(), resume.Total)
+		}
+	}
+
+	// Files embedded by a fallback provider are embedded again by the primary
+	var reembed map[string]bool
+	if len(existingDocs) > 0 {
+		reembed = idx.fallbackEmbedded(ctx)
+	}
+
+	// Filter files on metadata first; only files that may have changed are read.
+	jobs := make([]scanJob, 0, len(fileMetas))
+	processed := 0
+	reportProgress := func(path string) {
+		processed++
 		if onProgress != nil {
 			onProgress(ProgressInfo{
-				Current:     i + 1,
+				Current:     processed,
 				Total:       len(fileMetas),
-				CurrentFile: fileMeta.Path,
+				CurrentFile: path,
 			})
 		}
-
-		// Skip files modified before lastIndexTime
-		if !idx.lastIndexTime.IsZero() {
+	}
+	for _, fileMeta := range fileMetas {
+		refresh := reembed[fileMeta.Path] || idx.rechunk
+		// Skip files modified before lastIndexTime. An interrupted run is
+		// resumed from its checkpoint instead, as the files it did not reach
+		// may be older than lastIndexTime (watch advances it as it goes).
+		if !idx.lastIndexTime.IsZero() && resume == nil && !refresh {
 			fileModTime := time.Unix(fileMeta.ModTime, 0)
 			if fileModTime.Before(idx.lastIndexTime) || fileModTime.Equal(idx.lastIndexTime) {
+				reportProgress(fileMeta.Path)
 				stats.FilesSkipped++
 				delete(existingMap, fileMeta.Path)
 				continue
@@ -131,65 +226,98 @@ func (idx *Indexer) IndexAllWithBatchProgress(ctx context.Context, onProgress Pr
 			return nil, fmt.Errorf("failed to get document %s: %w", fileMeta.Path, err)
 		}
 
-		// Load file content and hash only after metadata filtering.
-		file, err := idx.scanner.ScanFile(fileMeta.Path)
-		if err != nil {
-			log.Printf("Failed to scan %s: %v", fileMeta.Path, err)
-			stats.FilesSkipped++
-			delete(existingMap, fileMeta.Path)
-			continue
-		}
-		if file == nil {
+		// Modification times have a precision of a second: a file modified in
+		// the second the interrupted run started may have changed since.
+		if resume != nil && doc != nil && !reembed[fileMeta.Path] && !resume.isPending(fileMeta.Path) &&
+			time.Unix(fileMeta.ModTime, 0).Before(resume.StartedAt.Truncate(time.Second)) {
+			reportProgress(fileMeta.Path)
 			stats.FilesSkipped++
 			delete(existingMap, fileMeta.Path)
 			continue
 		}
 
-		if doc != nil && doc.Hash == file.Hash {
-			delete(existingMap, fileMeta.Path)
+		delete(existingMap, fileMeta.Path)
+		if doc != nil && !refresh && unchangedOnDisk(doc, fileMeta) {
+			reportProgress(fileMeta.Path)
 			continue // File unchanged
 		}
+		jobs = append(jobs, scanJob{meta: fileMeta, doc: doc, refresh: refresh})
+	}
 
-		filesToIndex = append(filesToIndex, *file)
-		delete(existingMap, fileMeta.Path)
+	if idx.secretsReport != nil {
+		idx.secretsReport.Prune(present(fileMetas))
+		defer func() {
+			if err := idx.SaveSecretsReport(); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}()
+	}
+	if idx.checkpointPath != "" {
+		defer func() { idx.checkpoint = nil }()
 	}
 
-	// Index files using batch processing if available, otherwise sequentially
-	if batchEmbedder, ok := idx.embedder.(embedder.BatchEmbedder); ok && len(filesToIndex) > 0 {
-		indexed, chunks, err := idx.indexFilesBatched(ctx, filesToIndex, batchEmbedder, onBatchProgress)
-		if err != nil {
-			return nil, err
+	// Read, hash and chunk the remaining files in parallel; changed files are
+	// embedded while scanning continues.
+	scanCtx, cancelScan := context.WithCancel(ctx)
+	defer cancelScan()
+	unresolved := make(map[string]bool, len(jobs))
+	for _, job := range jobs {
+		unresolved[job.meta.Path] = true
+	}
+	newFiles := make(map[string]bool)
+	tokensBefore := idx.tokensEmbedded.Load()
+	stream := newIndexStream(idx, onBatchProgress, stats, len(jobs))
+	for res := range idx.scanFiles(scanCtx, jobs) {
+		path := res.meta.Path
+		delete(unresolved, path)
+		reportProgress(path)
+
+		if res.file == nil || res.unchanged {
+			stream.skip()
+			if res.err != nil {
+				log.Printf("Failed to scan %s: %v", path, res.err)
+			}
+			if res.file == nil {
+				stats.FilesSkipped++
+				if res.doc != nil && idx.scanner.SkippedAsGenerated(path) {
+					existingMap[path] = true // Remove the stale chunks of generated files
+				}
+			}
+			if idx.checkpoint != nil {
+				idx.checkpoint.complete([]string{path})
+			}
+			continue
 		}
-		stats.FilesIndexed = indexed
-		stats.ChunksCreated = chunks
-	} else if len(filesToIndex) > 0 {
-		// Sequential indexing for non-batch embedders (e.g., Ollama)
-		total := len(filesToIndex)
-		for i, file := range filesToIndex {
-			if onBatchProgress != nil {
-				onBatchProgress(BatchProgressInfo{
-					BatchIndex:      i,
-					TotalBatches:    total,
-					CompletedChunks: i,
-					TotalChunks:     total,
-				})
+
+		// Start the checkpoint manifest with the first file to index
+		if idx.checkpointPath != "" && idx.checkpoint == nil {
+			pending := []string{path}
+			for p := range unresolved {
+				pending = append(pending, p)
 			}
-			chunks, err := idx.IndexFile(ctx, file)
-			if err != nil {
-				log.Printf("Failed to index %s: %v", file.Path, err)
-				continue
+			idx.checkpoint = newCheckpoint(idx.checkpointPath, start, pending)
+			if err := idx.checkpoint.save(); err != nil {
+				log.Printf("Warning: %v", err)
 			}
-			stats.FilesIndexed++
-			stats.ChunksCreated += chunks
-		}
-		if onBatchProgress != nil {
-			onBatchProgress(BatchProgressInfo{
-				BatchIndex:      total,
-				TotalBatches:    total,
-				CompletedChunks: total,
-				TotalChunks:     total,
-			})
 		}
+
+		if res.doc == nil {
+			newFiles[path] = true
+		}
+		if err := stream.add(ctx, *res.file); err != nil {
+			return nil, err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := stream.finish(ctx); err != nil {
+		return nil, err
+	}
+
+	idx.scanner.PruneGeneratedReport(present(fileMetas))
+	if err := idx.scanner.SaveGeneratedReport(); err != nil {
+		log.Printf("Warning: %v", err)
 	}
 
 	// Remove deleted files
@@ -201,10 +329,257 @@ func (idx *Indexer) IndexAllWithBatchProgress(ctx context.Context, onProgress Pr
 		stats.FilesRemoved++
 	}
 
+	// Split indexed files into added and updated; new files that failed have no document.
+	for path := range newFiles {
+		doc, err := idx.store.GetDocument(ctx, path)
+		if err == nil && doc != nil {
+			stats.FilesAdded++
+		}
+	}
+	stats.FilesUpdated = stats.FilesIndexed - stats.FilesAdded
+	stats.TokensEmbedded = idx.tokensEmbedded.Load() - tokensBefore
+
+	// The run is complete: files that failed are retried by the next run anyway.
+	if idx.checkpoint != nil || resume != nil {
+		if err := idx.store.Persist(ctx); err != nil {
+			log.Printf("Warning: failed to persist index: %v", err)
+		} else if err := removeCheckpoint(idx.checkpointPath); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+
 	stats.Duration = time.Since(start)
 	return stats, nil
 }
 
+// fallbackEmbedded returns the files with vectors computed by a fallback
+// provider, to embed them again once the primary provider answers. It is nil
+// without fallback providers or while the primary is unavailable.
+func (idx *Indexer) fallbackEmbedded(ctx context.Context) map[string]bool {
+	sourced, ok := idx.embedder.(embedder.SourceEmbedder)
+	if !ok || !sourced.PrimaryAvailable(ctx) {
+		return nil
+	}
+	paths, err := store.FallbackEmbeddedFiles(ctx, idx.store)
+	if err != nil {
+		log.Printf("Warning: failed to find chunks embedded by fallback providers: %v", err)
+		return nil
+	}
+	files := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		files[path] = true
+	}
+	if len(files) > 0 {
+		log.Printf("Re-embedding %d files embedded by a fallback provider", len(files))
+	}
+	return files
+}
+
+// present returns the set of scanned file paths.
+func present(fileMetas []FileMeta) map[string]bool {
+	paths := make(map[string]bool, len(fileMetas))
+	for _, fileMeta := range fileMetas {
+		paths[fileMeta.Path] = true
+	}
+	return paths
+}
+
+// TokensEmbedded returns the number of tokens sent to the embedder by this
+// indexer so far.
+func (idx *Indexer) TokensEmbedded() int64 {
+	return idx.tokensEmbedded.Load()
+}
+
+// uncached returns the contents the embedder sends to its provider: those a
+// shared cache holds are served without spending tokens. It is called before
+// embedding, as embedding fills the cache.
+func (idx *Indexer) uncached(contents []string) []string {
+	cache, ok := idx.embedder.(embedder.CacheChecker)
+	if !ok {
+		return contents
+	}
+	var missing []string
+	for _, content := range contents {
+		if !cache.Cached(content) {
+			missing = append(missing, content)
+		}
+	}
+	return missing
+}
+
+// recordEmbedded adds the token count of successfully embedded contents,
+// without the contents served by a shared cache (see uncached).
+func (idx *Indexer) recordEmbedded(contents []string) {
+	var total int64
+	for _, content := range contents {
+		total += int64(idx.chunker.countTokens(content))
+	}
+	idx.tokensEmbedded.Add(total)
+}
+
+// IndexDiff lists files whose index entries are out of date.
+type IndexDiff struct {
+	Added   []string `json:"added"`   // Indexable files missing from the index
+	Updated []string `json:"updated"` // Files whose content hash changed
+	Removed []string `json:"removed"` // Indexed files that no longer exist or are no longer indexable
+}
+
+// Stale reports whether the index differs from the working tree.
+func (d *IndexDiff) Stale() bool {
+	return len(d.Added) > 0 || len(d.Updated) > 0 || len(d.Removed) > 0
+}
+
+// Diff compares the index with the files on disk without modifying anything.
+// Unlike IndexAll it ignores the last index time and hashes every file.
+// When paths is non-empty only those files are compared.
+func (idx *Indexer) Diff(ctx context.Context, paths []string) (*IndexDiff, error) {
+	diff := &IndexDiff{}
+
+	var candidates []string
+	if len(paths) > 0 {
+		candidates = paths
+	} else {
+		fileMetas, _, err := idx.scanner.ScanMetadata()
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan files: %w", err)
+		}
+		for _, fileMeta := range fileMetas {
+			candidates = append(candidates, fileMeta.Path)
+		}
+	}
+
+	seen := make(map[string]bool, len(candidates))
+	for _, path := range candidates {
+		doc, err := idx.store.GetDocument(ctx, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get document %s: %w", path, err)
+		}
+		var file *FileInfo
+		if idx.scanner.Indexable(path) {
+			file, err = idx.scanner.ScanFile(path)
+		}
+		if err != nil || file == nil {
+			// Missing, unreadable, ignored or no longer indexable
+			if doc != nil {
+				diff.Removed = append(diff.Removed, path)
+			}
+			seen[path] = true
+			continue
+		}
+		seen[path] = true
+		switch {
+		case doc == nil:
+			diff.Added = append(diff.Added, path)
+		case doc.Hash != file.Hash:
+			diff.Updated = append(diff.Updated, path)
+		}
+	}
+
+	if len(paths) == 0 {
+		existingDocs, err := idx.store.ListDocuments(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list documents: %w", err)
+		}
+		for _, path := range existingDocs {
+			if !seen[path] {
+				diff.Removed = append(diff.Removed, path)
+			}
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Updated)
+	sort.Strings(diff.Removed)
+	return diff, nil
+}
+
+// ReindexDir brings the index of the files under dir ("" for the whole
+// project) in line with the working tree after ignore rules or nested
+// settings changed: files no longer indexable are removed and new or changed
+// files are indexed. With force, unchanged files are re-indexed too, e.g. to
+// apply new chunking settings. It returns the files it changed.
+func (idx *Indexer) ReindexDir(ctx context.Context, dir string, force bool) (*IndexDiff, error) {
+	dir = filepath.ToSlash(filepath.Clean(dir))
+	if dir == "." {
+		dir = ""
+	}
+	inDir := func(p string) bool {
+		p = filepath.ToSlash(p)
+		return dir == "" || p == dir || strings.HasPrefix(p, dir+"/")
+	}
+
+	var candidates []string
+	seen := make(map[string]bool)
+	fileMetas, _, err := idx.scanner.ScanMetadata()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan files: %w", err)
+	}
+	for _, fileMeta := range fileMetas {
+		if inDir(fileMeta.Path) {
+			candidates = append(candidates, fileMeta.Path)
+			seen[fileMeta.Path] = true
+		}
+	}
+	existingDocs, err := idx.store.ListDocuments(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	for _, path := range existingDocs {
+		if inDir(path) && !seen[path] {
+			candidates = append(candidates, path)
+		}
+	}
+	if len(candidates) == 0 {
+		return &IndexDiff{}, nil
+	}
+
+	diff, err := idx.Diff(ctx, candidates)
+	if err != nil {
+		return nil, err
+	}
+	if force {
+		changed := make(map[string]bool)
+		for _, paths := range [][]string{diff.Added, diff.Updated, diff.Removed} {
+			for _, path := range paths {
+				changed[path] = true
+			}
+		}
+		for _, path := range candidates {
+			if seen[path] && !changed[path] {
+				diff.Updated = append(diff.Updated, path)
+			}
+		}
+		sort.Strings(diff.Updated)
+	}
+
+	applied := &IndexDiff{}
+	for _, path := range diff.Removed {
+		if err := idx.RemoveFile(ctx, path); err != nil {
+			log.Printf("Failed to remove %s: %v", path, err)
+			continue
+		}
+		applied.Removed = append(applied.Removed, path)
+	}
+	index := func(paths []string) []string {
+		var indexed []string
+		for _, path := range paths {
+			file, err := idx.scanner.ScanFile(path)
+			if err != nil || file == nil {
+				continue
+			}
+			if _, err := idx.IndexFile(ctx, *file); err != nil {
+				log.Printf("Failed to index %s: %v", path, err)
+				continue
+			}
+			indexed = append(indexed, path)
+		}
+		return indexed
+	}
+	applied.Added = index(diff.Added)
+	applied.Updated = index(diff.Updated)
+	return applied, nil
+}
+
 // fileChunkData holds chunking information for a single file during batch processing.
 type fileChunkData struct {
 	fileIndex  int // Index in the files slice (for result mapping)
@@ -226,14 +601,14 @@ func (idx *Indexer) prepareFileChunks(
 			return nil, nil, fmt.Errorf("failed to delete existing chunks for %s: %w", file.Path, err)
 		}
 
-		chunkInfos := idx.chunker.ChunkWithContext(file.Path, file.Content)
+		chunkInfos := idx.chunkFile(file)
 		if len(chunkInfos) == 0 {
 			continue
 		}
 
 		contents := make([]string, len(chunkInfos))
 		for j, c := range chunkInfos {
-			contents[j] = c.Content
+			contents[j] = idx.embeddingInput(c.Content)
 		}
 
 		fileData = append(fileData, fileChunkData{
@@ -266,6 +641,8 @@ func createStoreChunks(chunkInfos []ChunkInfo, embeddings [][]float32, now time.
 			Vector:      embeddings[i],
 			Hash:        info.Hash,
 			ContentHash: info.ContentHash,
+			Cell:        info.Cell,
+			Embedder:    info.Embedder,
 			UpdatedAt:   now,
 		}
 		chunkIDs[i] = info.ID
@@ -276,6 +653,10 @@ func createStoreChunks(chunkInfos []ChunkInfo, embeddings [][]float32, now time.
 
 // saveFileData saves chunks and document metadata for a single file.
 func (idx *Indexer) saveFileData(ctx context.Context, fd fileChunkData, chunks []store.Chunk, chunkIDs []string) error {
+	for i := range chunks {
+		chunks[i].Language = fd.file.Language
+		chunks[i].Generated = fd.file.Generated
+	}
 	if err := idx.store.SaveChunks(ctx, chunks); err != nil {
 		return fmt.Errorf("failed to save chunks for %s: %w", fd.file.Path, err)
 	}
@@ -284,6 +665,7 @@ func (idx *Indexer) saveFileData(ctx context.Context, fd fileChunkData, chunks [
 		Path:     fd.file.Path,
 		Hash:     fd.file.Hash,
 		ModTime:  time.Unix(fd.file.ModTime, 0),
+		Size:     fd.file.Size,
 		ChunkIDs: chunkIDs,
 	}
 
@@ -314,7 +696,8 @@ func wrapBatchProgress(onProgress BatchProgressCallback) embedder.BatchProgress
 
 // indexFilesBatched indexes multiple files using cross-file batch embedding.
 // It collects chunks from all files, forms batches, embeds them in parallel,
-// then maps results back and stores them.
+// then maps results back and stores them. Files are embedded in groups of
+// about checkpointChunks chunks, checkpointed after each group.
 func (idx *Indexer) indexFilesBatched(
 	ctx context.Context,
 	files []FileInfo,
@@ -326,8 +709,23 @@ func (idx *Indexer) indexFilesBatched(
 		return 0, 0, err
 	}
 
+	// Files without chunks (e.g. empty) count as indexed, as in IndexFile
+	filesIndexed = len(files) - len(fileData)
+	if idx.checkpoint != nil {
+		withChunks := make(map[int]bool, len(fileData))
+		for _, fd := range fileData {
+			withChunks[fd.fileIndex] = true
+		}
+		var empty []string
+		for i, file := range files {
+			if !withChunks[i] {
+				empty = append(empty, file.Path)
+			}
+		}
+		idx.saveCheckpoint(ctx, empty)
+	}
 	if len(fileChunks) == 0 {
-		return 0, 0, nil
+		return filesIndexed, 0, nil
 	}
 
 	// Check embedding cache for content-addressed deduplication
@@ -387,6 +785,7 @@ func (idx *Indexer) indexFilesBatched(
 
 	// Save fully-cached files immediately
 	now := time.Now()
+	var completed []string
 	for _, pf := range preFilledFiles {
 		fd := fileData[pf.fdIndex]
 		chunks, chunkIDs := createStoreChunks(fd.chunkInfos, pf.vectors, now)
@@ -395,19 +794,46 @@ func (idx *Indexer) indexFilesBatched(
 		}
 		filesIndexed++
 		chunksCreated += len(chunks)
+		completed = append(completed, fd.file.Path)
+	}
+	idx.saveCheckpoint(ctx, completed)
+
+	// Embed remaining (non-cached) files, one checkpoint group at a time.
+	// Batches are formed upfront so progress spans all groups.
+	groups := groupFileChunks(remainingFileChunks, checkpointChunks)
+	groupBatches := make([][]embedder.Batch, len(groups))
+	totalBatches, totalChunks := 0, 0
+	for i, group := range groups {
+		groupBatches[i] = embedder.FormBatchesWithTokenizer(group, idx.chunker.Tokenizer())
+		totalBatches += len(groupBatches[i])
+		totalChunks += countFileChunks(group)
+	}
+
+	dataByFile := make(map[int]fileChunkData, len(remainingFileData))
+	for _, fd := range remainingFileData {
+		dataByFile[fd.fileIndex] = fd
 	}
 
-	// Embed remaining (non-cached) files
-	if len(remainingFileChunks) > 0 {
-		batches := embedder.FormBatches(remainingFileChunks)
-		results, err := batchEmb.EmbedBatches(ctx, batches, wrapBatchProgress(onProgress))
+	batchOffset, chunkOffset := 0, 0
+	for i, group := range groups {
+		batches := groupBatches[i]
+		progress := offsetBatchProgress(onProgress, batchOffset, totalBatches, chunkOffset, totalChunks)
+		var spent []string
+		for _, fc := range group {
+			spent = append(spent, idx.uncached(fc.Chunks)...)
+		}
+		results, err := batchEmb.EmbedBatches(ctx, batches, progress)
 		if err != nil {
 			return filesIndexed, chunksCreated, fmt.Errorf("failed to embed batches: %w", err)
 		}
+		idx.recordEmbedded(spent)
+		recordSources(batches, results, dataByFile)
 
 		fileEmbeddings := embedder.MapResultsToFiles(batches, results, len(files))
 
-		for _, fd := range remainingFileData {
+		completed = completed[:0]
+		for _, fc := range group {
+			fd := dataByFile[fc.FileIndex]
 			embeddings := fileEmbeddings[fd.fileIndex]
 			if len(embeddings) != len(fd.chunkInfos) {
 				log.Printf("Warning: embedding count mismatch for %s: got %d, expected %d",
@@ -420,12 +846,57 @@ func (idx *Indexer) indexFilesBatched(
 			}
 			filesIndexed++
 			chunksCreated += len(chunks)
+			completed = append(completed, fd.file.Path)
 		}
+		idx.saveCheckpoint(ctx, completed)
+
+		batchOffset += len(batches)
+		chunkOffset += countFileChunks(group)
 	}
 
 	return filesIndexed, chunksCreated, nil
 }
 
+// groupFileChunks splits files into consecutive groups of about maxChunks
+// chunks. A file is never split; larger files form their own group.
+func groupFileChunks(files []embedder.FileChunks, maxChunks int) [][]embedder.FileChunks {
+	var groups [][]embedder.FileChunks
+	var current []embedder.FileChunks
+	count := 0
+	for _, fc := range files {
+		if count > 0 && count+len(fc.Chunks) > maxChunks {
+			groups = append(groups, current)
+			current, count = nil, 0
+		}
+		current = append(current, fc)
+		count += len(fc.Chunks)
+	}
+	if len(current) > 0 {
+		groups = append(groups, current)
+	}
+	return groups
+}
+
+func countFileChunks(files []embedder.FileChunks) int {
+	total := 0
+	for _, fc := range files {
+		total += len(fc.Chunks)
+	}
+	return total
+}
+
+// offsetBatchProgress reports the progress of one group of batches relative
+// to the whole run.
+func offsetBatchProgress(onProgress BatchProgressCallback, batchOffset, totalBatches, chunkOffset, totalChunks int) embedder.BatchProgress {
+	if onProgress == nil {
+		return nil
+	}
+	progress := wrapBatchProgress(onProgress)
+	return func(batchIndex, _, completedChunks, _ int, retrying bool, attempt int, statusCode int) {
+		progress(batchOffset+batchIndex, totalBatches, chunkOffset+completedChunks, totalChunks, retrying, attempt, statusCode)
+	}
+}
+
 // maxReChunkAttempts is the maximum number of times we'll try to re-chunk
 // before giving up on a file.
 const maxReChunkAttempts = 3
@@ -438,7 +909,7 @@ func (idx *Indexer) IndexFile(ctx context.Context, file FileInfo) (int, error) {
 	}
 
 	// Chunk the file
-	chunkInfos := idx.chunker.ChunkWithContext(file.Path, file.Content)
+	chunkInfos := idx.chunkFile(file)
 	if len(chunkInfos) == 0 {
 		return 0, nil
 	}
@@ -528,6 +999,10 @@ func (idx *Indexer) IndexFile(ctx context.Context, file FileInfo) (int, error) {
 			Vector:      vectors[i],
 			Hash:        info.Hash,
 			ContentHash: info.ContentHash,
+			Cell:        info.Cell,
+			Language:    file.Language,
+			Generated:   file.Generated,
+			Embedder:    info.Embedder,
 			UpdatedAt:   now,
 		}
 		chunkIDs[i] = info.ID
@@ -543,6 +1018,7 @@ func (idx *Indexer) IndexFile(ctx context.Context, file FileInfo) (int, error) {
 		Path:     file.Path,
 		Hash:     file.Hash,
 		ModTime:  time.Unix(file.ModTime, 0),
+		Size:     file.Size,
 		ChunkIDs: chunkIDs,
 	}
 
@@ -563,11 +1039,14 @@ func (idx *Indexer) embedWithReChunking(ctx context.Context, chunks []ChunkInfo)
 	for attempt := 0; attempt < maxReChunkAttempts; attempt++ {
 		contents := make([]string, len(currentChunks))
 		for i, c := range currentChunks {
-			contents[i] = c.Content
+			contents[i] = idx.embeddingInput(c.Content)
 		}
 
-		vectors, err := idx.embedder.EmbedBatch(ctx, contents)
+		spent := idx.uncached(contents)
+		vectors, source, err := idx.embedBatch(ctx, contents)
 		if err == nil {
+			idx.recordEmbedded(spent)
+			setSource(currentChunks, source)
 			// Success! Append all results
 			allVectors = append(allVectors, vectors...)
 			finalChunks = append(finalChunks, currentChunks...)
@@ -595,18 +1074,21 @@ func (idx *Indexer) embedWithReChunking(ctx context.Context, chunks []ChunkInfo)
 		if failedIndex > 0 {
 			beforeContents := make([]string, failedIndex)
 			for i := 0; i < failedIndex; i++ {
-				beforeContents[i] = currentChunks[i].Content
+				beforeContents[i] = idx.embeddingInput(currentChunks[i].Content)
 			}
-			beforeVectors, err := idx.embedder.EmbedBatch(ctx, beforeContents)
+			spent := idx.uncached(beforeContents)
+			beforeVectors, source, err := idx.embedBatch(ctx, beforeContents)
 			if err != nil {
 				return nil, nil, fmt.Errorf("failed to embed chunks before failed index: %w", err)
 			}
+			idx.recordEmbedded(spent)
+			setSource(currentChunks[:failedIndex], source)
 			allVectors = append(allVectors, beforeVectors...)
 			finalChunks = append(finalChunks, currentChunks[:failedIndex]...)
 		}
 
 		// Re-chunk the failed chunk
-		subChunks := idx.chunker.ReChunk(failedChunk, failedIndex)
+		subChunks := idx.chunkerFor(failedChunk.FilePath).ReChunk(failedChunk, failedIndex)
 		if len(subChunks) == 0 {
 			return nil, nil, fmt.Errorf("re-chunking produced no chunks for %s", failedChunk.FilePath)
 		}
@@ -620,6 +1102,105 @@ func (idx *Indexer) embedWithReChunking(ctx context.Context, chunks []ChunkInfo)
 	return nil, nil, fmt.Errorf("exceeded maximum re-chunk attempts (%d) for file", maxReChunkAttempts)
 }
 
+// embedBatch embeds texts and returns the fallback provider that embedded
+// them, empty for the primary provider.
+func (idx *Indexer) embedBatch(ctx context.Context, texts []string) ([][]float32, string, error) {
+	if sourced, ok := idx.embedder.(embedder.SourceEmbedder); ok {
+		return sourced.EmbedBatchWithSource(ctx, texts)
+	}
+	vectors, err := idx.embedder.EmbedBatch(ctx, texts)
+	return vectors, "", err
+}
+
+// setSource records the provider that embedded chunks.
+func setSource(chunks []ChunkInfo, source string) {
+	for i := range chunks {
+		chunks[i].Embedder = source
+	}
+}
+
+// recordSources records the provider that embedded each chunk of the batch
+// results in the chunks of the files.
+func recordSources(batches []embedder.Batch, results []embedder.BatchResult, dataByFile map[int]fileChunkData) {
+	for _, result := range results {
+		for _, entry := range batches[result.BatchIndex].Entries {
+			if fd, ok := dataByFile[entry.FileIndex]; ok && entry.ChunkIndex < len(fd.chunkInfos) {
+				fd.chunkInfos[entry.ChunkIndex].Embedder = result.Source
+			}
+		}
+	}
+}
+
+// chunkFile chunks a file and records the secrets it contains. With
+// maskStored, secrets are masked in the returned chunk content. Files chunked
+// by the scan pipeline are returned as is.
+func (idx *Indexer) chunkFile(file FileInfo) []ChunkInfo {
+	if file.chunked {
+		return file.chunks
+	}
+	chunkInfos := idx.chunkerFor(file.Path).ChunkWithContext(file.Path, file.Content)
+	if idx.redactor == nil {
+		return chunkInfos
+	}
+	if idx.secretsReport != nil {
+		idx.secretsReport.Record(file.Path, idx.redactor.Find(file.Content))
+	}
+	if idx.maskStored {
+		for i := range chunkInfos {
+			chunkInfos[i].Content = idx.redactor.Redact(chunkInfos[i].Content)
+		}
+	}
+	return chunkInfos
+}
+
+// DirConfigs returns the nested .grepai.yaml overrides, nil when there are none.
+func (idx *Indexer) DirConfigs() *config.DirConfigs {
+	return idx.dirConfigs
+}
+
+// chunkerFor returns the chunker for a file, honoring chunking overrides of
+// nested .grepai.yaml files.
+func (idx *Indexer) chunkerFor(relPath string) *Chunker {
+	if idx.dirConfigs == nil {
+		return idx.chunker
+	}
+	base := config.ChunkingConfig{Size: idx.chunker.chunkSize, Overlap: idx.chunker.overlap}
+	cc := idx.dirConfigs.Chunking(relPath, base)
+	if cc == base {
+		return idx.chunker
+	}
+
+	idx.chunkersMu.Lock()
+	defer idx.chunkersMu.Unlock()
+	if c, ok := idx.dirChunkers[cc]; ok {
+		return c
+	}
+	if idx.dirChunkers == nil {
+		idx.dirChunkers = make(map[config.ChunkingConfig]*Chunker)
+	}
+	c := NewChunker(cc.Size, cc.Overlap, WithTokenizer(idx.chunker.tokenizer))
+	idx.dirChunkers[cc] = c
+	return c
+}
+
+// embeddingInput returns the content sent to the embedder for a chunk, with
+// secrets masked. Redaction runs on every embedder call rather than once per
+// file so that sub-chunks produced by re-chunking are masked too.
+func (idx *Indexer) embeddingInput(content string) string {
+	if idx.redactor == nil {
+		return content
+	}
+	return idx.redactor.Redact(content)
+}
+
+// SaveSecretsReport writes the report of files with redacted secrets.
+func (idx *Indexer) SaveSecretsReport() error {
+	if idx.secretsReport == nil {
+		return nil
+	}
+	return idx.secretsReport.Save()
+}
+
 // lookupCachedEmbeddings checks if the store implements EmbeddingCache and returns
 // cached vectors for chunks with matching content hashes. The returned map maps
 // chunk index to cached vector. Chunks not in the map need fresh embedding.
@@ -657,6 +1238,10 @@ func (idx *Indexer) RemoveFile(ctx context.Context, path string) error {
 		return fmt.Errorf("failed to delete document: %w", err)
 	}
 
+	if idx.secretsReport != nil {
+		idx.secretsReport.Remove(path)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,125 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yoanbernabeu/grepai/embedder"
+)
+
+// outageEmbedder is a mockEmbedder that fails and does not answer Ping
+// while down.
+type outageEmbedder struct {
+	mockEmbedder
+	down bool
+}
+
+var errOutage = errors.New("connection refused")
+
+func (e *outageEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if e.down {
+		return nil, errOutage
+	}
+	return e.mockEmbedder.EmbedBatch(ctx, texts)
+}
+
+func (e *outageEmbedder) Ping(ctx context.Context) error {
+	if e.down {
+		return errOutage
+	}
+	return nil
+}
+
+// outageBatchEmbedder is an outageEmbedder with batch support.
+type outageBatchEmbedder struct {
+	outageEmbedder
+}
+
+func (e *outageBatchEmbedder) EmbedBatches(ctx context.Context, batches []embedder.Batch, progress embedder.BatchProgress) ([]embedder.BatchResult, error) {
+	results := make([]embedder.BatchResult, len(batches))
+	for i, batch := range batches {
+		vectors, err := e.EmbedBatch(ctx, batch.Contents())
+		if err != nil {
+			return nil, err
+		}
+		results[i] = embedder.BatchResult{BatchIndex: i, Embeddings: vectors}
+	}
+	return results, nil
+}
+
+func TestIndexAllWithProgress_FallbackVectorsReembedded(t *testing.T) {
+	tests := []struct {
+		name    string
+		primary func() (embedder.Embedder, *outageEmbedder)
+	}{
+		{"sequential", func() (embedder.Embedder, *outageEmbedder) {
+			e := &outageEmbedder{down: true}
+			return e, e
+		}},
+		{"batch", func() (embedder.Embedder, *outageEmbedder) {
+			e := &outageBatchEmbedder{outageEmbedder{down: true}}
+			return e, &e.outageEmbedder
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			tmpDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+				t.Fatalf("failed to create test file: %v", err)
+			}
+
+			primary, outage := tt.primary()
+			emb, err := embedder.NewFallbackEmbedder([]embedder.FallbackMember{
+				{Name: "ollama:nomic-embed-text", Embedder: primary},
+				{Name: "tei:nomic-embed-text", Embedder: newMockEmbedder()},
+			}, embedder.WithProbeInterval(time.Millisecond))
+			if err != nil {
+				t.Fatalf("NewFallbackEmbedder failed: %v", err)
+			}
+
+			ignoreMatcher, err := NewIgnoreMatcher(tmpDir, []string{}, "")
+			if err != nil {
+				t.Fatalf("failed to create ignore matcher: %v", err)
+			}
+			mockStore := newMockStore()
+			indexer := NewIndexer(tmpDir, mockStore, emb, NewChunker(512, 50), NewScanner(tmpDir, ignoreMatcher), time.Time{})
+
+			// The primary is down: the fallback embeds and is recorded
+			if _, err := indexer.IndexAll(ctx); err != nil {
+				t.Fatalf("IndexAll failed: %v", err)
+			}
+			chunks, _ := mockStore.GetChunksForFile(ctx, "main.go")
+			if len(chunks) == 0 || chunks[0].Embedder != "tei:nomic-embed-text" {
+				t.Fatalf("expected chunks embedded by the fallback, got %+v", chunks)
+			}
+
+			// Still down: the file is not embedded again
+			stats, err := indexer.IndexAll(ctx)
+			if err != nil {
+				t.Fatalf("IndexAll failed: %v", err)
+			}
+			if stats.FilesIndexed != 0 {
+				t.Errorf("expected no file indexed while the primary is down, got %d", stats.FilesIndexed)
+			}
+
+			// The primary is back: the unchanged file is embedded again by it
+			outage.down = false
+			stats, err = indexer.IndexAll(ctx)
+			if err != nil {
+				t.Fatalf("IndexAll failed: %v", err)
+			}
+			if stats.FilesIndexed != 1 {
+				t.Errorf("expected the file embedded by the fallback to be indexed again, got %d", stats.FilesIndexed)
+			}
+			chunks, _ = mockStore.GetChunksForFile(ctx, "main.go")
+			if len(chunks) == 0 || chunks[0].Embedder != "" {
+				t.Errorf("expected chunks embedded by the primary, got %+v", chunks)
+			}
+		})
+	}
+}
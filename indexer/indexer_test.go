@@ -10,7 +10,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/yoanbernabeu/grepai/config"
 	"github.com/yoanbernabeu/grepai/embedder"
+	"github.com/yoanbernabeu/grepai/redact"
 	"github.com/yoanbernabeu/grepai/store"
 )
 
@@ -1170,3 +1172,373 @@ func TestEmbedWithReChunking_ReChunksOnError(t *testing.T) {
 		t.Errorf("vectors count %d != chunks count %d", len(vectors), len(finalChunks))
 	}
 }
+
+// TestIndexAllWithProgress_AddedUpdatedAndTokens tests that stats split indexed
+// files into added and updated and count embedded tokens
+func TestIndexAllWithProgress_AddedUpdatedAndTokens(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	for name, content := range map[string]string{
+		"new.go":     "package main\n\nfunc New() {}",
+		"changed.go": "package main\n\nfunc Changed() {}",
+	} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+	}
+
+	mockStore := newMockStore()
+	mockStore.documents["changed.go"] = store.Document{Path: "changed.go", Hash: "oldHash"}
+
+	ignoreMatcher, err := NewIgnoreMatcher(tmpDir, []string{}, "")
+	if err != nil {
+		t.Fatalf("failed to create ignore matcher: %v", err)
+	}
+	indexer := NewIndexer(tmpDir, mockStore, newMockEmbedder(), NewChunker(512, 50), NewScanner(tmpDir, ignoreMatcher), time.Time{})
+
+	stats, err := indexer.IndexAllWithProgress(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("IndexAllWithProgress failed: %v", err)
+	}
+
+	if stats.FilesAdded != 1 || stats.FilesUpdated != 1 {
+		t.Errorf("expected 1 added and 1 updated, got %d added and %d updated", stats.FilesAdded, stats.FilesUpdated)
+	}
+	if stats.FilesFailed != 0 {
+		t.Errorf("expected no failed files, got %d", stats.FilesFailed)
+	}
+	if stats.TokensEmbedded <= 0 {
+		t.Errorf("expected embedded tokens to be counted, got %d", stats.TokensEmbedded)
+	}
+	if indexer.TokensEmbedded() != stats.TokensEmbedded {
+		t.Errorf("TokensEmbedded() = %d, want %d", indexer.TokensEmbedded(), stats.TokensEmbedded)
+	}
+}
+
+// TestDiff tests that Diff reports added, updated and removed files without
+// touching the index
+func TestDiff(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	files := map[string]string{
+		"same.go":    "package main\n\nfunc Same() {}",
+		"changed.go": "package main\n\nfunc Changed() {}",
+		"new.go":     "package main\n\nfunc New() {}",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+	}
+
+	ignoreMatcher, err := NewIgnoreMatcher(tmpDir, []string{}, "")
+	if err != nil {
+		t.Fatalf("failed to create ignore matcher: %v", err)
+	}
+	scanner := NewScanner(tmpDir, ignoreMatcher)
+	same, err := scanner.ScanFile("same.go")
+	if err != nil {
+		t.Fatalf("ScanFile failed: %v", err)
+	}
+
+	mockStore := newMockStore()
+	mockStore.documents["same.go"] = store.Document{Path: "same.go", Hash: same.Hash}
+	mockStore.documents["changed.go"] = store.Document{Path: "changed.go", Hash: "oldHash"}
+	mockStore.documents["deleted.go"] = store.Document{Path: "deleted.go", Hash: "hash"}
+
+	// Indexes newer than the files must not hide changes
+	indexer := NewIndexer(tmpDir, mockStore, nil, NewChunker(512, 50), scanner, time.Now().Add(time.Hour))
+
+	diff, err := indexer.Diff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if !diff.Stale() {
+		t.Fatal("expected index to be stale")
+	}
+	if len(diff.Added) != 1 || diff.Added[0] != "new.go" {
+		t.Errorf("Added = %v, want [new.go]", diff.Added)
+	}
+	if len(diff.Updated) != 1 || diff.Updated[0] != "changed.go" {
+		t.Errorf("Updated = %v, want [changed.go]", diff.Updated)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0] != "deleted.go" {
+		t.Errorf("Removed = %v, want [deleted.go]", diff.Removed)
+	}
+	if mockStore.saveDocCalled {
+		t.Error("Diff should not modify the index")
+	}
+
+	// Restricted to explicit paths
+	diff, err = indexer.Diff(context.Background(), []string{"same.go", "deleted.go"})
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if len(diff.Added) != 0 || len(diff.Updated) != 0 || len(diff.Removed) != 1 {
+		t.Errorf("unexpected diff for explicit paths: %+v", diff)
+	}
+
+	diff, err = indexer.Diff(context.Background(), []string{"same.go"})
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if diff.Stale() {
+		t.Errorf("expected same.go to be up to date, got %+v", diff)
+	}
+}
+
+// capturingEmbedder records the texts sent to the embedder.
+type capturingEmbedder struct {
+	mockEmbedder
+	mu    sync.Mutex
+	texts []string
+}
+
+func (m *capturingEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	m.mu.Lock()
+	m.texts = append(m.texts, texts...)
+	m.mu.Unlock()
+	return m.mockEmbedder.EmbedBatch(ctx, texts)
+}
+
+func TestIndexAll_Redaction(t *testing.T) {
+	const secret = "postgres://app:hunter2@db/app"
+	for _, maskStored := range []bool{false, true} {
+		tmpDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(tmpDir, ".env"), []byte("DATABASE_URL="+secret+"\n"), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+
+		ignoreMatcher, err := NewIgnoreMatcher(tmpDir, []string{}, "")
+		if err != nil {
+			t.Fatalf("failed to create ignore matcher: %v", err)
+		}
+		report, _ := redact.LoadReport(filepath.Join(t.TempDir(), "secrets.json"))
+		emb := &capturingEmbedder{}
+		mockStore := newMockStore()
+		idx := NewIndexer(tmpDir, mockStore, emb, NewChunker(512, 50), NewScanner(tmpDir, ignoreMatcher), time.Time{},
+			WithRedaction(redact.New(redact.DefaultRules(), redact.DefaultEntropyThreshold), maskStored, report))
+
+		if _, err := idx.IndexAll(context.Background()); err != nil {
+			t.Fatalf("IndexAll failed: %v", err)
+		}
+
+		for _, text := range emb.texts {
+			if strings.Contains(text, "hunter2") {
+				t.Errorf("maskStored=%v: secret sent to the embedder: %q", maskStored, text)
+			}
+		}
+		stored := false
+		for _, chunk := range mockStore.chunks {
+			if chunk.FilePath == ".env" && strings.Contains(chunk.Content, "hunter2") {
+				stored = true
+			}
+		}
+		if stored == maskStored {
+			t.Errorf("maskStored=%v: secret stored in chunk content = %v", maskStored, stored)
+		}
+
+		entries := report.Entries()
+		if len(entries) != 1 || entries[0].Path != ".env" || entries[0].Findings[0].Rule != "connection-string" {
+			t.Errorf("maskStored=%v: unexpected report %+v", maskStored, entries)
+		}
+	}
+}
+
+func TestReindexDir_NestedSettings(t *testing.T) {
+	tmpDir := t.TempDir()
+	var long strings.Builder
+	long.WriteString("package web\n\n")
+	for i := 0; i < 40; i++ {
+		long.WriteString("func handlerWithALongName() string { return \"some response body\" }\n")
+	}
+	files := map[string]string{
+		"web/a.go":       long.String(),
+		"web/b_gen.go":   "package web\n\nfunc Generated() {}",
+		"api/b_gen.go":   "package api\n\nfunc Generated() {}",
+		"api/handler.go": "package api\n\nfunc Handle() {}",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+	}
+
+	mockStore := newMockStore()
+	ignoreMatcher, err := NewIgnoreMatcher(tmpDir, []string{}, "")
+	if err != nil {
+		t.Fatalf("failed to create ignore matcher: %v", err)
+	}
+	dirs := config.LoadDirConfigs(tmpDir, nil)
+	scanner := NewScanner(tmpDir, ignoreMatcher)
+	idx := NewIndexer(tmpDir, mockStore, newMockEmbedder(), NewChunker(512, 50), scanner, time.Time{}, WithDirConfigs(dirs))
+	if _, err := idx.IndexAll(context.Background()); err != nil {
+		t.Fatalf("IndexAll failed: %v", err)
+	}
+	chunksBefore := len(mockStore.documents["web/a.go"].ChunkIDs)
+
+	// A new .grepaiignore removes matching files of its subtree only
+	if err := os.WriteFile(filepath.Join(tmpDir, "web", GrepaiIgnoreFileName), []byte("*_gen.go\n"), 0644); err != nil {
+		t.Fatalf("failed to write .grepaiignore: %v", err)
+	}
+	if err := ignoreMatcher.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	diff, err := idx.ReindexDir(context.Background(), "web", false)
+	if err != nil {
+		t.Fatalf("ReindexDir failed: %v", err)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0] != "web/b_gen.go" || len(diff.Updated) != 0 {
+		t.Errorf("unexpected diff after ignore change: %+v", diff)
+	}
+	if _, ok := mockStore.documents["api/b_gen.go"]; !ok {
+		t.Error("api/b_gen.go is outside the subtree and should stay indexed")
+	}
+
+	// A nested .grepai.yaml changes chunking; force re-indexes unchanged files
+	dirConfig := "chunking:\n  size: 64\n  overlap: 8\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "web", config.DirConfigFileName), []byte(dirConfig), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", config.DirConfigFileName, err)
+	}
+	dirs.Reload()
+	diff, err = idx.ReindexDir(context.Background(), "web", true)
+	if err != nil {
+		t.Fatalf("ReindexDir failed: %v", err)
+	}
+	if len(diff.Updated) != 1 || diff.Updated[0] != "web/a.go" {
+		t.Errorf("expected web/a.go to be re-indexed, got %+v", diff)
+	}
+	if chunksAfter := len(mockStore.documents["web/a.go"].ChunkIDs); chunksAfter <= chunksBefore {
+		t.Errorf("expected smaller chunks under web/, got %d chunks (was %d)", chunksAfter, chunksBefore)
+	}
+}
+
+// TestIndexAll_BudgetExceeded tests that indexing stops once the token budget
+// is reached, and resumes with a larger budget even though watch advanced the
+// last index time in between
+func TestIndexAll_BudgetExceeded(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	ignoreMatcher, err := NewIgnoreMatcher(tmpDir, []string{}, "")
+	if err != nil {
+		t.Fatalf("failed to create ignore matcher: %v", err)
+	}
+	mockStore := newMockStore()
+	usagePath := filepath.Join(t.TempDir(), "usage.json")
+	checkpointPath := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	lastIndexTime := time.Time{}
+	for _, budget := range []int64{1, 1000} {
+		tracker := embedder.NewUsageTracker(usagePath, budget, 0)
+		emb := embedder.NewMeteredEmbedder(newMockEmbedder(), tracker, "ollama:nomic-embed-text", nil)
+		indexer := NewIndexer(tmpDir, mockStore, emb, NewChunker(512, 50), NewScanner(tmpDir, ignoreMatcher), lastIndexTime,
+			WithCheckpoint(checkpointPath))
+
+		stats, err := indexer.IndexAll(context.Background())
+		if budget == 1 {
+			if embedder.AsBudgetExceededError(err) == nil {
+				t.Fatalf("expected indexing to stop on the budget, got %v", err)
+			}
+			if len(mockStore.documents) != 0 {
+				t.Errorf("expected no file indexed over the budget, got %d", len(mockStore.documents))
+			}
+			lastIndexTime = time.Now().Add(time.Minute)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("IndexAll failed: %v", err)
+		}
+		if stats.FilesIndexed != 1 {
+			t.Errorf("expected the file indexed within the budget, got %d", stats.FilesIndexed)
+		}
+	}
+}
+
+func TestIndexAll_Rechunk(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	ignoreMatcher, err := NewIgnoreMatcher(tmpDir, []string{}, "")
+	if err != nil {
+		t.Fatalf("failed to create ignore matcher: %v", err)
+	}
+	scanner := NewScanner(tmpDir, ignoreMatcher)
+	mockStore := newMockStore()
+	if _, err := NewIndexer(tmpDir, mockStore, newMockEmbedder(), NewChunker(512, 50), scanner, time.Time{}).IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll failed: %v", err)
+	}
+
+	// Chunks of an index built before languages were recorded
+	for id, chunk := range mockStore.chunks {
+		chunk.Language = ""
+		mockStore.chunks[id] = chunk
+	}
+	lastIndexTime := time.Now().Add(time.Hour)
+
+	stats, err := NewIndexer(tmpDir, mockStore, newMockEmbedder(), NewChunker(512, 50), scanner, lastIndexTime).IndexAll(ctx)
+	if err != nil {
+		t.Fatalf("IndexAll failed: %v", err)
+	}
+	if stats.FilesIndexed != 0 {
+		t.Fatalf("expected the unchanged file to be skipped, got %d indexed", stats.FilesIndexed)
+	}
+
+	stats, err = NewIndexer(tmpDir, mockStore, newMockEmbedder(), NewChunker(512, 50), scanner, lastIndexTime,
+		WithRechunk(true)).IndexAll(ctx)
+	if err != nil {
+		t.Fatalf("IndexAll failed: %v", err)
+	}
+	if stats.FilesIndexed != 1 {
+		t.Errorf("expected the unchanged file to be chunked again, got %d indexed", stats.FilesIndexed)
+	}
+	chunks, _ := mockStore.GetChunksForFile(ctx, "main.go")
+	if len(chunks) == 0 || chunks[0].Language != "go" {
+		t.Errorf("expected chunks recording their language, got %+v", chunks)
+	}
+}
+
+func TestIndexAll_TokensEmbeddedExcludeSharedCache(t *testing.T) {
+	for _, name := range []string{"sequential", "batch"} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			tmpDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+				t.Fatalf("failed to create test file: %v", err)
+			}
+			ignoreMatcher, err := NewIgnoreMatcher(tmpDir, []string{}, "")
+			if err != nil {
+				t.Fatalf("failed to create ignore matcher: %v", err)
+			}
+			var inner embedder.Embedder = newMockEmbedder()
+			if name == "batch" {
+				inner = newMockBatchEmbedder()
+			}
+			emb := embedder.NewCachedEmbedder(inner, store.NewDiskEmbeddingCache(t.TempDir()), "mock", 0)
+
+			// A second project indexes the same content: the shared cache serves it
+			var spent []int64
+			for i := 0; i < 2; i++ {
+				idx := NewIndexer(tmpDir, newMockStore(), emb, NewChunker(512, 50), NewScanner(tmpDir, ignoreMatcher), time.Time{})
+				stats, err := idx.IndexAll(ctx)
+				if err != nil {
+					t.Fatalf("IndexAll failed: %v", err)
+				}
+				spent = append(spent, stats.TokensEmbedded)
+			}
+			if spent[0] <= 0 || spent[1] != 0 {
+				t.Errorf("expected tokens only for the first run, got %v", spent)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package indexer
+
+import (
+	"context"
+	"log"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/yoanbernabeu/grepai/embedder"
+	"github.com/yoanbernabeu/grepai/store"
+)
+
+// scanQueueSize is how many scanned files may wait for the embedder, so
+// scanning continues while a group of files is embedded.
+const scanQueueSize = 256
+
+// WithScanWorkers sets how many files IndexAll reads, hashes and chunks in
+// parallel. n <= 0 uses the number of CPUs.
+func WithScanWorkers(n int) IndexerOption {
+	return func(idx *Indexer) {
+		idx.scanWorkers = n
+	}
+}
+
+func (idx *Indexer) scanWorkerCount() int {
+	if idx.scanWorkers > 0 {
+		return idx.scanWorkers
+	}
+	return runtime.NumCPU()
+}
+
+// unchangedOnDisk reports whether a file still has the modification time and
+// size it had when indexed, in which case it is not read again. Sizes are
+// only compared when the store records them.
+func unchangedOnDisk(doc *store.Document, meta FileMeta) bool {
+	if doc.ModTime.Unix() != meta.ModTime {
+		return false
+	}
+	return doc.Size == 0 || doc.Size == meta.Size
+}
+
+// scanJob is a file whose content must be read to know whether it changed.
+type scanJob struct {
+	meta    FileMeta
+	doc     *store.Document // Indexed version, nil for new files
+	refresh bool            // Embedded by a fallback provider or chunked again: indexed even if unchanged
+}
+
+// scanResult is a scanJob once the file was read, hashed and, when changed,
+// chunked.
+type scanResult struct {
+	scanJob
+	file      *FileInfo // nil when the file is skipped
+	unchanged bool      // Same content as the indexed version
+	err       error
+}
+
+// scanFiles reads, hashes and chunks files on a pool of workers and streams
+// the results in completion order. Workers never touch the store, so the
+// caller can write to it while results arrive. The channel is closed once all
+// jobs are done or ctx is cancelled.
+func (idx *Indexer) scanFiles(ctx context.Context, jobs []scanJob) <-chan scanResult {
+	in := make(chan scanJob)
+	out := make(chan scanResult, scanQueueSize)
+
+	go func() {
+		defer close(in)
+		for _, job := range jobs {
+			select {
+			case in <- job:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range idx.scanWorkerCount() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range in {
+				select {
+				case out <- idx.scanOne(job):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+func (idx *Indexer) scanOne(job scanJob) scanResult {
+	res := scanResult{scanJob: job}
+	file, err := idx.scanner.ScanFile(job.meta.Path)
+	if err != nil || file == nil {
+		res.err = err
+		return res
+	}
+	res.file = file
+	if job.doc != nil && !job.refresh && job.doc.Hash == file.Hash {
+		res.unchanged = true
+		return res
+	}
+	file.chunks = idx.chunkFile(*file)
+	file.chunked = true
+	return res
+}
+
+// indexStream indexes files as the scan pipeline delivers them. With a batch
+// embedder, files are embedded in groups of about checkpointChunks chunks as
+// soon as a group is complete; otherwise they are embedded one at a time.
+type indexStream struct {
+	idx        *Indexer
+	batchEmb   embedder.BatchEmbedder // nil for sequential embedding
+	onProgress BatchProgressCallback
+	stats      *IndexStats
+
+	// Batch embedding
+	pending       []FileInfo
+	pendingChunks int
+	batchOffset   int // Batches embedded by previous groups
+	chunkOffset   int // Chunks embedded by previous groups
+
+	// Sequential embedding
+	queued         int // Files received so far
+	remaining      int // Files still being scanned that may be received
+	completed      []string
+	lastCheckpoint time.Time
+}
+
+func newIndexStream(idx *Indexer, onProgress BatchProgressCallback, stats *IndexStats, remaining int) *indexStream {
+	s := &indexStream{
+		idx:            idx,
+		onProgress:     onProgress,
+		stats:          stats,
+		remaining:      remaining,
+		lastCheckpoint: time.Now(),
+	}
+	if batchEmb, ok := idx.embedder.(embedder.BatchEmbedder); ok {
+		s.batchEmb = batchEmb
+	}
+	return s
+}
+
+// skip records that a scanned file will not be received.
+func (s *indexStream) skip() {
+	s.remaining--
+}
+
+// add indexes a changed file, or queues it for the next group.
+func (s *indexStream) add(ctx context.Context, file FileInfo) error {
+	s.remaining--
+	if s.batchEmb != nil {
+		s.pending = append(s.pending, file)
+		s.pendingChunks += len(file.chunks)
+		if s.pendingChunks >= checkpointChunks {
+			return s.flush(ctx)
+		}
+		return nil
+	}
+
+	// Sequential indexing for non-batch embedders (e.g., Ollama). The total
+	// shrinks as files still being scanned turn out unchanged.
+	s.queued++
+	if s.onProgress != nil {
+		total := s.queued + s.remaining
+		s.onProgress(BatchProgressInfo{
+			BatchIndex:      s.queued - 1,
+			TotalBatches:    total,
+			CompletedChunks: s.queued - 1,
+			TotalChunks:     total,
+		})
+	}
+	chunks, err := s.idx.IndexFile(ctx, file)
+	if err != nil {
+		// The run stops; the next one resumes from the checkpoint
+		if embedder.AsBudgetExceededError(err) != nil {
+			s.idx.saveCheckpoint(ctx, s.completed)
+			s.completed = nil
+			return err
+		}
+		log.Printf("Failed to index %s: %v", file.Path, err)
+		s.stats.FilesFailed++
+		return nil
+	}
+	s.stats.FilesIndexed++
+	s.stats.ChunksCreated += chunks
+	s.completed = append(s.completed, file.Path)
+	if time.Since(s.lastCheckpoint) >= checkpointInterval {
+		s.idx.saveCheckpoint(ctx, s.completed)
+		s.completed = nil
+		s.lastCheckpoint = time.Now()
+	}
+	return nil
+}
+
+// flush embeds the queued group of files.
+func (s *indexStream) flush(ctx context.Context) error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	var groupBatches, groupChunks int
+	var progress BatchProgressCallback
+	if s.onProgress != nil {
+		progress = func(info BatchProgressInfo) {
+			groupBatches, groupChunks = info.TotalBatches, info.TotalChunks
+			info.BatchIndex += s.batchOffset
+			info.TotalBatches += s.batchOffset
+			info.CompletedChunks += s.chunkOffset
+			info.TotalChunks += s.chunkOffset
+			s.onProgress(info)
+		}
+	}
+
+	indexed, chunks, err := s.idx.indexFilesBatched(ctx, s.pending, s.batchEmb, progress)
+	if err != nil {
+		return err
+	}
+	s.stats.FilesIndexed += indexed
+	s.stats.FilesFailed += len(s.pending) - indexed
+	s.stats.ChunksCreated += chunks
+	s.batchOffset += groupBatches
+	s.chunkOffset += groupChunks
+	s.pending, s.pendingChunks = nil, 0
+	return nil
+}
+
+// finish indexes what is left once scanning is complete.
+func (s *indexStream) finish(ctx context.Context) error {
+	if s.batchEmb != nil {
+		return s.flush(ctx)
+	}
+	s.idx.saveCheckpoint(ctx, s.completed)
+	s.completed = nil
+	if s.onProgress != nil && s.queued > 0 {
+		s.onProgress(BatchProgressInfo{
+			BatchIndex:      s.queued,
+			TotalBatches:    s.queued,
+			CompletedChunks: s.queued,
+			TotalChunks:     s.queued,
+		})
+	}
+	return nil
+}
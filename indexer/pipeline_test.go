@@ -0,0 +1,128 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yoanbernabeu/grepai/embedder"
+)
+
+func TestIndexAll_SkipsReadingFilesUnchangedOnDisk(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "a.go")
+	if err := os.WriteFile(path, []byte("package a\n\nfunc A() {}\n"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to set modification time: %v", err)
+	}
+
+	ignoreMatcher, err := NewIgnoreMatcher(tmpDir, []string{}, "")
+	if err != nil {
+		t.Fatalf("failed to create ignore matcher: %v", err)
+	}
+	mockStore := newMockStore()
+	idx := NewIndexer(tmpDir, mockStore, newMockEmbedder(), NewChunker(512, 50), NewScanner(tmpDir, ignoreMatcher), time.Time{})
+	if _, err := idx.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll failed: %v", err)
+	}
+	if doc := mockStore.documents["a.go"]; doc.Size != 23 {
+		t.Errorf("expected the file size to be recorded, got %d", doc.Size)
+	}
+
+	// Same size and modification time: the file is not read again
+	if err := os.WriteFile(path, []byte("package a\n\nfunc B() {}\n"), 0644); err != nil {
+		t.Fatalf("failed to rewrite test file: %v", err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to set modification time: %v", err)
+	}
+	stats, err := idx.IndexAll(ctx)
+	if err != nil {
+		t.Fatalf("IndexAll failed: %v", err)
+	}
+	if stats.FilesIndexed != 0 {
+		t.Errorf("expected no file to be re-indexed, got %d", stats.FilesIndexed)
+	}
+
+	// A newer modification time makes the file read and hashed
+	if err := os.Chtimes(path, modTime.Add(time.Minute), modTime.Add(time.Minute)); err != nil {
+		t.Fatalf("failed to set modification time: %v", err)
+	}
+	stats, err = idx.IndexAll(ctx)
+	if err != nil {
+		t.Fatalf("IndexAll failed: %v", err)
+	}
+	if stats.FilesIndexed != 1 || stats.FilesUpdated != 1 {
+		t.Errorf("expected a.go to be re-indexed, got %+v", stats)
+	}
+}
+
+// groupCountingEmbedder counts EmbedBatches calls, one per group of files.
+type groupCountingEmbedder struct {
+	mockBatchEmbedder
+	calls int
+}
+
+func (m *groupCountingEmbedder) EmbedBatches(ctx context.Context, batches []embedder.Batch, progress embedder.BatchProgress) ([]embedder.BatchResult, error) {
+	m.calls++
+	return m.mockBatchEmbedder.EmbedBatches(ctx, batches, progress)
+}
+
+func TestIndexAll_ParallelScanStreamsGroups(t *testing.T) {
+	oldChunks := checkpointChunks
+	checkpointChunks = 1 // One file per group
+	defer func() { checkpointChunks = oldChunks }()
+
+	tmpDir := t.TempDir()
+	const fileCount = 40
+	for i := range fileCount {
+		content := fmt.Sprintf("package main\n\nfunc f%d() {}\n", i)
+		if err := os.WriteFile(filepath.Join(tmpDir, fmt.Sprintf("file_%02d.go", i)), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+	}
+
+	ignoreMatcher, err := NewIgnoreMatcher(tmpDir, []string{}, "")
+	if err != nil {
+		t.Fatalf("failed to create ignore matcher: %v", err)
+	}
+	mockStore := newMockStore()
+	emb := &groupCountingEmbedder{}
+	idx := NewIndexer(tmpDir, mockStore, emb, NewChunker(512, 50), NewScanner(tmpDir, ignoreMatcher), time.Time{},
+		WithScanWorkers(4), WithCheckpoint(filepath.Join(t.TempDir(), "checkpoint.json")))
+
+	var files, lastChunks int
+	stats, err := idx.IndexAllWithBatchProgress(context.Background(),
+		func(info ProgressInfo) {
+			files++
+			if info.Current != files || info.Total != fileCount {
+				t.Errorf("unexpected file progress %+v after %d files", info, files)
+			}
+		},
+		func(info BatchProgressInfo) {
+			if info.CompletedChunks < lastChunks || info.CompletedChunks > info.TotalChunks {
+				t.Errorf("unexpected batch progress %+v after %d chunks", info, lastChunks)
+			}
+			lastChunks = info.CompletedChunks
+		})
+	if err != nil {
+		t.Fatalf("IndexAllWithBatchProgress failed: %v", err)
+	}
+
+	if stats.FilesIndexed != fileCount || stats.FilesAdded != fileCount || len(mockStore.documents) != fileCount {
+		t.Errorf("expected %d files indexed, got %+v with %d documents", fileCount, stats, len(mockStore.documents))
+	}
+	if emb.calls != fileCount {
+		t.Errorf("expected files to be embedded in %d groups as they are scanned, got %d", fileCount, emb.calls)
+	}
+	if lastChunks != fileCount {
+		t.Errorf("expected %d chunks embedded in total, got %d", fileCount, lastChunks)
+	}
+}
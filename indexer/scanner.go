@@ -9,10 +9,13 @@ import (
 	"path/filepath"
 	"strings"
 	"unicode/utf8"
+
+	"github.com/yoanbernabeu/grepai/trace"
 )
 
 const (
-	maxFileSize = 1 * 1024 * 1024 // 1 MB
+	maxFileSize     = 1 * 1024 * 1024  // 1 MB
+	maxNotebookSize = 20 * 1024 * 1024 // 20 MB on disk; outputs are stripped before the 1 MB limit applies
 )
 
 // MinifiedPatterns lists patterns for minified files to skip by default
@@ -34,75 +37,115 @@ func isMinifiedFile(path string) bool {
 	return false
 }
 
-// SupportedExtensions lists file extensions to index
+// maxSizeFor returns the on-disk size limit for a file.
+func maxSizeFor(path string) int64 {
+	if trace.IsNotebook(path) {
+		return maxNotebookSize
+	}
+	return maxFileSize
+}
+
+// prepareContent normalizes file content before indexing. Notebook outputs
+// (plots, tables, logs) are stripped so only cell sources are kept.
+// Returns false when the file should be skipped.
+func prepareContent(path string, content []byte) ([]byte, bool) {
+	if !trace.IsNotebook(path) {
+		return content, true
+	}
+	stripped, err := trace.StripNotebookOutputs(content)
+	if err != nil {
+		return nil, false
+	}
+	return stripped, len(stripped) <= maxFileSize
+}
+
+// SupportedExtensions lists the file extensions indexed by default. The
+// effective set is built by NewFileTypes from index.include_extensions and
+// index.exclude_extensions.
 var SupportedExtensions = map[string]bool{
-	".go":     true,
-	".js":     true,
-	".ts":     true,
-	".jsx":    true,
-	".tsx":    true,
-	".py":     true,
-	".rb":     true,
-	".java":   true,
-	".c":      true,
-	".cpp":    true,
-	".cc":     true,
-	".h":      true,
-	".hpp":    true,
-	".cs":     true,
-	".php":    true,
-	".rs":     true,
-	".swift":  true,
-	".kt":     true,
-	".scala":  true,
-	".vue":    true,
-	".svelte": true,
-	".html":   true,
-	".css":    true,
-	".scss":   true,
-	".less":   true,
-	".sql":    true,
-	".sh":     true,
-	".bash":   true,
-	".zsh":    true,
-	".yaml":   true,
-	".yml":    true,
-	".json":   true,
-	".xml":    true,
-	".md":     true,
-	".txt":    true,
-	".toml":   true,
-	".ini":    true,
-	".cfg":    true,
-	".conf":   true,
-	".env":    true,
-	".lua":    true,
-	".r":      true,
-	".R":      true,
-	".dart":   true,
-	".ex":     true,
-	".exs":    true,
-	".erl":    true,
-	".clj":    true,
-	".hs":     true,
-	".ml":     true,
-	".fs":     true,
-	".elm":    true,
-	".nim":    true,
-	".zig":    true,
-	".proto":  true,
-	".tf":     true,
-	".hcl":    true,
-	".pas":    true, // Pascal source file
-	".dpr":    true, // Delphi project file
+	".go":         true,
+	".js":         true,
+	".ts":         true,
+	".jsx":        true,
+	".tsx":        true,
+	".py":         true,
+	".rb":         true,
+	".java":       true,
+	".c":          true,
+	".cpp":        true,
+	".cc":         true,
+	".h":          true,
+	".hpp":        true,
+	".cs":         true,
+	".php":        true,
+	".rs":         true,
+	".swift":      true,
+	".kt":         true,
+	".scala":      true,
+	".vue":        true,
+	".svelte":     true,
+	".html":       true,
+	".css":        true,
+	".scss":       true,
+	".less":       true,
+	".sql":        true,
+	".sh":         true,
+	".bash":       true,
+	".zsh":        true,
+	".yaml":       true,
+	".yml":        true,
+	".json":       true,
+	".xml":        true,
+	".md":         true,
+	".txt":        true,
+	".toml":       true,
+	".ini":        true,
+	".cfg":        true,
+	".conf":       true,
+	".env":        true,
+	".lua":        true,
+	".r":          true,
+	".R":          true,
+	".dart":       true,
+	".ex":         true,
+	".exs":        true,
+	".erl":        true,
+	".clj":        true,
+	".hs":         true,
+	".ml":         true,
+	".fs":         true,
+	".elm":        true,
+	".nim":        true,
+	".zig":        true,
+	".proto":      true,
+	".tf":         true,
+	".hcl":        true,
+	".pas":        true, // Pascal source file
+	".dpr":        true, // Delphi project file
+	".ipynb":      true, // Jupyter notebook (cells are extracted, outputs stripped)
+	".gradle":     true,
+	".groovy":     true,
+	".kts":        true,
+	".cmake":      true,
+	".graphql":    true,
+	".gql":        true,
+	".mk":         true,
+	".dockerfile": true,
+	".bazel":      true,
+	".bzl":        true,
 }
 
 type FileInfo struct {
-	Path    string
-	Size    int64
-	ModTime int64
-	Hash    string
-	Content string
+	Path      string
+	Size      int64
+	ModTime   int64
+	Hash      string
+	Content   string
+	Language  string // Language detected from extension, file name or shebang
+	Generated bool   // Detected as generated or vendored (penalty mode)
+
+	chunks  []ChunkInfo // Set when the scan pipeline already chunked the file
+	chunked bool
 }
 
 type FileMeta struct {
@@ -112,37 +155,117 @@ type FileMeta struct {
 }
 
 type Scanner struct {
-	root   string
-	ignore *IgnoreMatcher
+	fsys      fs.FS // Files below the project root
+	ignore    *IgnoreMatcher
+	fileTypes *FileTypes
+
+	generatedMode    string
+	maxAvgLineLength int
+	attributes       *GitAttributes
+	generatedReport  *GeneratedReport
+}
+
+// ScannerOption configures a Scanner.
+type ScannerOption func(*Scanner)
+
+// WithFileTypes sets the registry deciding which files are indexed.
+// Defaults to DefaultFileTypes.
+func WithFileTypes(ft *FileTypes) ScannerOption {
+	return func(s *Scanner) {
+		s.fileTypes = ft
+	}
 }
 
-func NewScanner(root string, ignore *IgnoreMatcher) *Scanner {
-	return &Scanner{
-		root:   root,
-		ignore: ignore,
+// WithFS makes the scanner read files from fsys, e.g. a git tree, instead of
+// the directory at root. Paths in fsys are relative to root.
+func WithFS(fsys fs.FS) ScannerOption {
+	return func(s *Scanner) {
+		s.fsys = fsys
 	}
 }
 
+// WithGeneratedDetection enables detection of generated and vendored files.
+// mode is GeneratedSkip, GeneratedPenalty or GeneratedKeep; detections are
+// recorded in report when it is non-nil.
+func WithGeneratedDetection(mode string, maxAvgLineLength int, report *GeneratedReport) ScannerOption {
+	return func(s *Scanner) {
+		s.generatedMode = mode
+		s.maxAvgLineLength = maxAvgLineLength
+		s.generatedReport = report
+	}
+}
+
+func NewScanner(root string, ignore *IgnoreMatcher, opts ...ScannerOption) *Scanner {
+	s := &Scanner{
+		ignore:    ignore,
+		fileTypes: DefaultFileTypes(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.fsys == nil {
+		s.fsys = os.DirFS(root)
+	}
+	if s.generatedMode == GeneratedSkip || s.generatedMode == GeneratedPenalty {
+		s.attributes = NewGitAttributesFS(s.fsys)
+	}
+	return s
+}
+
+// detectGenerated returns why a file looks generated or vendored ("" if it
+// does not, or when detection is disabled) and records the result.
+func (s *Scanner) detectGenerated(relPath, content string) string {
+	if s.attributes == nil {
+		return ""
+	}
+	reason := s.attributes.Lookup(relPath)
+	if reason == "" {
+		reason = DetectGeneratedContent(relPath, content, s.maxAvgLineLength)
+	}
+	if s.generatedReport != nil {
+		s.generatedReport.record(relPath, reason, s.generatedMode == GeneratedSkip)
+	}
+	return reason
+}
+
+// SkippedAsGenerated reports whether the last scan of relPath skipped it as a
+// generated or vendored file.
+func (s *Scanner) SkippedAsGenerated(relPath string) bool {
+	return s.generatedReport != nil && s.generatedReport.isSkipped(relPath)
+}
+
+// PruneGeneratedReport drops report entries for files not in present.
+func (s *Scanner) PruneGeneratedReport(present map[string]bool) {
+	if s.generatedReport != nil {
+		s.generatedReport.prune(present)
+	}
+}
+
+// SaveGeneratedReport persists the generated file report if it changed.
+func (s *Scanner) SaveGeneratedReport() error {
+	if s.generatedReport == nil {
+		return nil
+	}
+	return s.generatedReport.Save()
+}
+
 // ScanMetadata scans indexable files and returns only file metadata.
 // It avoids reading file contents and hash computation for a faster first pass.
 func (s *Scanner) ScanMetadata() ([]FileMeta, []string, error) {
 	var files []FileMeta
 	var skipped []string
 
-	err := filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+	err := fs.WalkDir(s.fsys, ".", func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return nil // Skip files we can't access
 		}
 
-		relPath, err := filepath.Rel(s.root, path)
-		if err != nil {
-			return nil
-		}
+		relPath := filepath.FromSlash(path)
 
 		// Skip ignored paths
 		if s.ignore.ShouldIgnore(relPath) {
 			if d.IsDir() {
-				return filepath.SkipDir
+				return fs.SkipDir
 			}
 			return nil
 		}
@@ -151,9 +274,8 @@ func (s *Scanner) ScanMetadata() ([]FileMeta, []string, error) {
 			return nil
 		}
 
-		// Check extension
-		ext := strings.ToLower(filepath.Ext(path))
-		if !SupportedExtensions[ext] {
+		// Check extension, file name or shebang
+		if _, ok := s.fileTypes.DetectFS(s.fsys, relPath); !ok {
 			return nil
 		}
 
@@ -169,7 +291,7 @@ func (s *Scanner) ScanMetadata() ([]FileMeta, []string, error) {
 		}
 
 		// Skip large files
-		if info.Size() > maxFileSize {
+		if info.Size() > maxSizeFor(relPath) {
 			skipped = append(skipped, relPath+" (too large)")
 			return nil
 		}
@@ -190,20 +312,17 @@ func (s *Scanner) Scan() ([]FileInfo, []string, error) {
 	var files []FileInfo
 	var skipped []string
 
-	err := filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+	err := fs.WalkDir(s.fsys, ".", func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return nil // Skip files we can't access
 		}
 
-		relPath, err := filepath.Rel(s.root, path)
-		if err != nil {
-			return nil
-		}
+		relPath := filepath.FromSlash(path)
 
 		// Skip ignored paths
 		if s.ignore.ShouldIgnore(relPath) {
 			if d.IsDir() {
-				return filepath.SkipDir
+				return fs.SkipDir
 			}
 			return nil
 		}
@@ -212,9 +331,9 @@ func (s *Scanner) Scan() ([]FileInfo, []string, error) {
 			return nil
 		}
 
-		// Check extension
-		ext := strings.ToLower(filepath.Ext(path))
-		if !SupportedExtensions[ext] {
+		// Check extension, file name or shebang
+		language, ok := s.fileTypes.DetectFS(s.fsys, relPath)
+		if !ok {
 			return nil
 		}
 
@@ -230,13 +349,13 @@ func (s *Scanner) Scan() ([]FileInfo, []string, error) {
 		}
 
 		// Skip large files
-		if info.Size() > maxFileSize {
+		if info.Size() > maxSizeFor(relPath) {
 			skipped = append(skipped, relPath+" (too large)")
 			return nil
 		}
 
 		// Read file content
-		content, err := os.ReadFile(path)
+		content, err := fs.ReadFile(s.fsys, path)
 		if err != nil {
 			return nil
 		}
@@ -249,12 +368,26 @@ func (s *Scanner) Scan() ([]FileInfo, []string, error) {
 		// Calculate hash
 		hash := sha256.Sum256(content)
 
+		content, ok = prepareContent(relPath, content)
+		if !ok {
+			skipped = append(skipped, relPath+" (unparsable or too large)")
+			return nil
+		}
+
+		reason := s.detectGenerated(relPath, string(content))
+		if reason != "" && s.generatedMode == GeneratedSkip {
+			skipped = append(skipped, relPath+" (generated: "+reason+")")
+			return nil
+		}
+
 		files = append(files, FileInfo{
-			Path:    relPath,
-			Size:    info.Size(),
-			ModTime: info.ModTime().Unix(),
-			Hash:    hex.EncodeToString(hash[:]),
-			Content: string(content),
+			Path:      relPath,
+			Size:      info.Size(),
+			ModTime:   info.ModTime().Unix(),
+			Hash:      hex.EncodeToString(hash[:]),
+			Content:   string(content),
+			Language:  language,
+			Generated: reason != "",
 		})
 
 		return nil
@@ -263,24 +396,40 @@ func (s *Scanner) Scan() ([]FileInfo, []string, error) {
 	return files, skipped, err
 }
 
+// Indexable reports whether a scan would consider relPath: neither the file
+// nor any of its parent directories is ignored, and its type is supported.
+// Size and content checks are left to ScanFile.
+func (s *Scanner) Indexable(relPath string) bool {
+	for dir := filepath.Dir(relPath); dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+		if s.ignore.ShouldIgnore(dir) {
+			return false
+		}
+	}
+	if s.ignore.ShouldIgnore(relPath) {
+		return false
+	}
+	_, ok := s.fileTypes.DetectFS(s.fsys, relPath)
+	return ok
+}
+
 func (s *Scanner) ScanFile(relPath string) (*FileInfo, error) {
-	absPath := filepath.Join(s.root, relPath)
+	name := filepath.ToSlash(relPath)
 
 	// Skip minified files
 	if isMinifiedFile(relPath) {
 		return nil, nil
 	}
 
-	info, err := os.Stat(absPath)
+	info, err := fs.Stat(s.fsys, name)
 	if err != nil {
 		return nil, err
 	}
 
-	if info.Size() > maxFileSize {
+	if info.Size() > maxSizeFor(relPath) {
 		return nil, nil // Skip large files
 	}
 
-	content, err := os.ReadFile(absPath)
+	content, err := fs.ReadFile(s.fsys, name)
 	if err != nil {
 		return nil, err
 	}
@@ -291,12 +440,29 @@ func (s *Scanner) ScanFile(relPath string) (*FileInfo, error) {
 
 	hash := sha256.Sum256(content)
 
+	content, ok := prepareContent(relPath, content)
+	if !ok {
+		return nil, nil // Skip unparsable or oversized notebooks
+	}
+
+	reason := s.detectGenerated(relPath, string(content))
+	if reason != "" && s.generatedMode == GeneratedSkip {
+		return nil, nil // Skip generated or vendored files
+	}
+
+	language, ok := s.fileTypes.Match(relPath)
+	if !ok {
+		language = ShebangLanguage(string(content))
+	}
+
 	return &FileInfo{
-		Path:    relPath,
-		Size:    info.Size(),
-		ModTime: info.ModTime().Unix(),
-		Hash:    hex.EncodeToString(hash[:]),
-		Content: string(content),
+		Path:      relPath,
+		Size:      info.Size(),
+		ModTime:   info.ModTime().Unix(),
+		Hash:      hex.EncodeToString(hash[:]),
+		Content:   string(content),
+		Language:  language,
+		Generated: reason != "",
 	}, nil
 }
 
@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 func TestSupportedExtensions(t *testing.T) {
@@ -352,3 +354,66 @@ func TestScanner_ScanFile_SkipsMinified(t *testing.T) {
 		t.Error("expected nil for minified file, got file info")
 	}
 }
+
+func TestScanner_WithFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitignore":        {Data: []byte("build/\n")},
+		".gitattributes":    {Data: []byte("gen/** linguist-generated\n")},
+		"main.go":           {Data: []byte("package main\n"), ModTime: time.Unix(1700000000, 0)},
+		"build/out.go":      {Data: []byte("package build\n")},
+		"gen/api.go":        {Data: []byte("package gen\n")},
+		"scripts/deploy":    {Data: []byte("#!/bin/sh\necho deploy\n"), Mode: 0755},
+		"scripts/notes":     {Data: []byte("#!/bin/sh\nnot executable\n"), Mode: 0644},
+		"sub/.grepaiignore": {Data: []byte("skip.go\n")},
+		"sub/skip.go":       {Data: []byte("package sub\n")},
+		"sub/keep.go":       {Data: []byte("package sub\n")},
+		"node_modules/x.js": {Data: []byte("x")},
+		"assets/image.bin":  {Data: []byte{0, 1, 2}},
+	}
+
+	ignoreMatcher, err := NewIgnoreMatcherFS(fsys, []string{"node_modules"}, "")
+	if err != nil {
+		t.Fatalf("failed to create ignore matcher: %v", err)
+	}
+	// The root directory does not exist: everything is read from fsys
+	scanner := NewScanner(filepath.Join(t.TempDir(), "missing"), ignoreMatcher,
+		WithFS(fsys), WithGeneratedDetection(GeneratedSkip, 0, nil))
+
+	files, _, err := scanner.Scan()
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	got := make(map[string]FileInfo)
+	for _, f := range files {
+		got[filepath.ToSlash(f.Path)] = f
+	}
+	want := []string{"main.go", "scripts/deploy", "sub/keep.go"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for _, path := range want {
+		if _, ok := got[path]; !ok {
+			t.Errorf("expected %s to be scanned, got %v", path, got)
+		}
+	}
+	if got["scripts/deploy"].Language != "shell" {
+		t.Errorf("expected shebang detection for scripts/deploy, got %q", got["scripts/deploy"].Language)
+	}
+	if got["main.go"].ModTime != 1700000000 {
+		t.Errorf("expected modification time from fsys, got %d", got["main.go"].ModTime)
+	}
+
+	file, err := scanner.ScanFile(filepath.Join("sub", "keep.go"))
+	if err != nil || file == nil {
+		t.Fatalf("ScanFile failed: %v", err)
+	}
+	if file.Content != "package sub\n" {
+		t.Errorf("unexpected content %q", file.Content)
+	}
+	if _, err := scanner.ScanFile("deleted.go"); !os.IsNotExist(err) {
+		t.Errorf("expected not exist error for a missing file, got %v", err)
+	}
+	if scanner.Indexable(filepath.Join("sub", "skip.go")) {
+		t.Error("expected sub/skip.go to be ignored by sub/.grepaiignore")
+	}
+}
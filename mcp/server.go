@@ -10,12 +10,17 @@ import (
 	"log"
 	"os"
 	"strings"
+	"sync"
 
 	"github.com/alpkeskin/gotoon"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/yoanbernabeu/grepai/config"
 	"github.com/yoanbernabeu/grepai/embedder"
+	"github.com/yoanbernabeu/grepai/git"
+	"github.com/yoanbernabeu/grepai/history"
+	"github.com/yoanbernabeu/grepai/indexer"
+	"github.com/yoanbernabeu/grepai/review"
 	"github.com/yoanbernabeu/grepai/rpg"
 	"github.com/yoanbernabeu/grepai/search"
 	"github.com/yoanbernabeu/grepai/store"
@@ -27,6 +32,9 @@ type Server struct {
 	mcpServer     *server.MCPServer
 	projectRoot   string
 	workspaceName string // non-empty when started via --workspace or auto-detect
+
+	dirConfigsOnce sync.Once
+	dirConfigs     *config.DirConfigs // Nested .grepai.yaml files, loaded on first use
 }
 
 // SearchResult is a lightweight struct for MCP output.
@@ -34,22 +42,38 @@ type SearchResult struct {
 	FilePath    string  `json:"file_path"`
 	StartLine   int     `json:"start_line"`
 	EndLine     int     `json:"end_line"`
+	Cell        int     `json:"cell,omitempty"`
+	Language    string  `json:"language,omitempty"`
 	Score       float32 `json:"score"`
 	Content     string  `json:"content"`
 	FeaturePath string  `json:"feature_path,omitempty"`
 	SymbolName  string  `json:"symbol_name,omitempty"`
 }
 
+// SearchResultBlame is a SearchResult with git blame metadata.
+type SearchResultBlame struct {
+	SearchResult
+	Blame *git.BlameInfo `json:"blame,omitempty"`
+}
+
 // SearchResultCompact is a minimal struct for compact output (no content field).
 type SearchResultCompact struct {
 	FilePath    string  `json:"file_path"`
 	StartLine   int     `json:"start_line"`
 	EndLine     int     `json:"end_line"`
+	Cell        int     `json:"cell,omitempty"`
+	Language    string  `json:"language,omitempty"`
 	Score       float32 `json:"score"`
 	FeaturePath string  `json:"feature_path,omitempty"`
 	SymbolName  string  `json:"symbol_name,omitempty"`
 }
 
+// SearchResultCompactBlame is a SearchResultCompact with git blame metadata.
+type SearchResultCompactBlame struct {
+	SearchResultCompact
+	Blame *git.BlameInfo `json:"blame,omitempty"`
+}
+
 // CallSiteCompact is a minimal struct for compact output (no context field).
 type CallSiteCompact struct {
 	File string `json:"file"`
@@ -94,6 +118,17 @@ type IndexStatus struct {
 func encodeOutput(data any, format string) (string, error) {
 	switch format {
 	case "toon":
+		switch data.(type) {
+		case []SearchResultBlame, []SearchResultCompactBlame:
+			// The TOON encoder does not flatten embedded structs, JSON does
+			jsonBytes, err := json.Marshal(data)
+			if err != nil {
+				return "", err
+			}
+			if err := json.Unmarshal(jsonBytes, &data); err != nil {
+				return "", err
+			}
+		}
 		return gotoon.Encode(data)
 	default: // "json"
 		jsonBytes, err := json.MarshalIndent(data, "", "  ")
@@ -157,12 +192,18 @@ func (s *Server) registerTools() {
 		mcp.WithBoolean("compact",
 			mcp.Description("Return minimal output without content (default: false)"),
 		),
+		mcp.WithBoolean("blame",
+			mcp.Description("Add the last commit (SHA, author, date) and recent change count of each result from git blame (default: false)"),
+		),
 		mcp.WithString("format",
 			mcp.Description("Output format: 'json' (default) or 'toon' (token-efficient)"),
 		),
 		mcp.WithString("path",
 			mcp.Description("Path prefix to filter results. Relative to workspace root if only workspace provided, or project root if both workspace and projects provided (e.g., 'src/' or 'src/handlers/auth/')"),
 		),
+		mcp.WithString("language",
+			mcp.Description("Only return chunks of this language (e.g., 'go', 'python', 'dockerfile', 'shell')"),
+		),
 		mcp.WithString("workspace",
 			mcp.Description("Workspace name for cross-project search (optional)"),
 		),
@@ -172,6 +213,22 @@ func (s *Server) registerTools() {
 	)
 	s.mcpServer.AddTool(searchTool, s.handleSearch)
 
+	// grepai_history_search tool
+	historySearchTool := mcp.NewTool("grepai_history_search",
+		mcp.WithDescription("Search the commit history by intent (e.g., 'when and why did session expiry change'). Returns matching commits with SHA, author, date, touched files and a diff excerpt. Requires history.enabled in the grepai configuration."),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("Natural language description of the change to find"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of commits to return (default: 10)"),
+		),
+		mcp.WithString("format",
+			mcp.Description("Output format: 'json' (default) or 'toon' (token-efficient)"),
+		),
+	)
+	s.mcpServer.AddTool(historySearchTool, s.handleHistorySearch)
+
 	// grepai_trace_callers tool
 	traceCallersTool := mcp.NewTool("grepai_trace_callers",
 		mcp.WithDescription("Find all functions that call the specified symbol. Useful for understanding code dependencies before modifying a function."),
@@ -182,6 +239,9 @@ func (s *Server) registerTools() {
 		mcp.WithBoolean("compact",
 			mcp.Description("Return minimal output without context (default: false)"),
 		),
+		mcp.WithBoolean("blame",
+			mcp.Description("Add the last commit (SHA, author, date) and recent change count of each symbol from git blame (default: false)"),
+		),
 		mcp.WithString("format",
 			mcp.Description("Output format: 'json' (default) or 'toon' (token-efficient)"),
 		),
@@ -204,6 +264,9 @@ func (s *Server) registerTools() {
 		mcp.WithBoolean("compact",
 			mcp.Description("Return minimal output without context (default: false)"),
 		),
+		mcp.WithBoolean("blame",
+			mcp.Description("Add the last commit (SHA, author, date) and recent change count of each symbol from git blame (default: false)"),
+		),
 		mcp.WithString("format",
 			mcp.Description("Output format: 'json' (default) or 'toon' (token-efficient)"),
 		),
@@ -226,6 +289,9 @@ func (s *Server) registerTools() {
 		mcp.WithNumber("depth",
 			mcp.Description("Maximum depth for graph traversal (default: 2)"),
 		),
+		mcp.WithBoolean("blame",
+			mcp.Description("Add the last commit (SHA, author, date) and recent change count of each symbol from git blame (default: false)"),
+		),
 		mcp.WithString("format",
 			mcp.Description("Output format: 'json' (default) or 'toon' (token-efficient)"),
 		),
@@ -238,6 +304,43 @@ func (s *Server) registerTools() {
 	)
 	s.mcpServer.AddTool(traceGraphTool, s.handleTraceGraph)
 
+	// grepai_impact tool
+	impactTool := mcp.NewTool("grepai_impact",
+		mcp.WithDescription("Analyze what a git diff can break: maps changed lines to symbols and walks their callers transitively. Returns the changed symbols, affected callers, tests, files and RPG feature areas. Useful before merging or reviewing a change."),
+		mcp.WithString("range",
+			mcp.Description("'<base>..<head>' to compare two commits, or a single revision compared to the working tree (default: uncommitted changes against HEAD)"),
+		),
+		mcp.WithBoolean("staged",
+			mcp.Description("Analyze the changes staged for the next commit (default: false)"),
+		),
+		mcp.WithNumber("depth",
+			mcp.Description("Maximum number of caller levels to walk (default: 3)"),
+		),
+		mcp.WithString("format",
+			mcp.Description("Output format: 'json' (default) or 'toon' (token-efficient)"),
+		),
+	)
+	s.mcpServer.AddTool(impactTool, s.handleImpact)
+
+	// grepai_review_context tool
+	reviewContextTool := mcp.NewTool("grepai_review_context",
+		mcp.WithDescription("Build a token-budgeted context pack to review a git diff: for each changed hunk, the most similar code outside the changed files, the callers and callees of the changed symbols and the RPG feature areas involved. Use it alongside the diff when reviewing a pull request."),
+		mcp.WithString("range",
+			mcp.Required(),
+			mcp.Description("'<base>..<head>' range of commits to review"),
+		),
+		mcp.WithNumber("budget",
+			mcp.Description("Estimated token budget of the pack, 0 for no limit (default: 4000)"),
+		),
+		mcp.WithNumber("per_hunk",
+			mcp.Description("Related code chunks per hunk (default: 3)"),
+		),
+		mcp.WithString("format",
+			mcp.Description("Output format: 'json' (default) or 'toon' (token-efficient)"),
+		),
+	)
+	s.mcpServer.AddTool(reviewContextTool, s.handleReviewContext)
+
 	// grepai_index_status tool
 	indexStatusTool := mcp.NewTool("grepai_index_status",
 		mcp.WithDescription("Check the health and status of the grepai index. Returns statistics about indexed files, chunks, and configuration."),
@@ -347,8 +450,10 @@ func (s *Server) handleSearch(ctx context.Context, request mcp.CallToolRequest)
 	}
 
 	compact := request.GetBool("compact", false)
+	blame := request.GetBool("blame", false)
 	format := request.GetString("format", "json")
 	path := request.GetString("path", "")
+	language := request.GetString("language", "")
 	workspace := request.GetString("workspace", "")
 	projects := request.GetString("projects", "")
 
@@ -364,7 +469,7 @@ func (s *Server) handleSearch(ctx context.Context, request mcp.CallToolRequest)
 
 	// Workspace mode
 	if workspace != "" {
-		return s.handleWorkspaceSearch(ctx, query, limit, compact, format, path, workspace, projects)
+		return s.handleWorkspaceSearch(ctx, query, limit, compact, format, path, language, workspace, projects)
 	}
 
 	// Load configuration
@@ -387,9 +492,29 @@ func (s *Server) handleSearch(ctx context.Context, request mcp.CallToolRequest)
 	}
 	defer st.Close()
 
+	overlay := s.createOverlayStore(ctx, cfg)
+	if overlay != nil {
+		defer overlay.Close()
+	}
+
+	// Blame the working tree for metadata and recency boosts
+	blame = blame || cfg.Search.Blame.Enabled
+	var blameCache *git.BlameCache
+	if (blame || len(cfg.Search.Boost.Recency) > 0) && git.IsGitRepo(s.projectRoot) {
+		blameCache = s.openBlameCache(cfg)
+		defer s.persistBlameCache(blameCache)
+	}
+
 	// Create searcher and search
-	searcher := search.NewSearcher(st, emb, cfg.Search)
-	results, err := searcher.Search(ctx, query, limit, path)
+	opts := []search.SearcherOption{
+		search.WithDirConfigs(s.loadDirConfigs(cfg)),
+		search.WithOverlay(overlay),
+	}
+	if blameCache != nil && len(cfg.Search.Boost.Recency) > 0 {
+		opts = append(opts, search.WithRecency(search.BlameRecency(ctx, blameCache)))
+	}
+	searcher := search.NewSearcher(st, emb, cfg.Search, opts...)
+	results, err := searcher.SearchWithOptions(ctx, query, limit, store.SearchOptions{PathPrefix: path, Language: language})
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("search failed: %v", err)), nil
 	}
@@ -421,6 +546,11 @@ func (s *Server) handleSearch(ctx context.Context, request mcp.CallToolRequest)
 		}
 	}
 
+	var blameData []*git.BlameInfo
+	if blame && blameCache != nil {
+		blameData = search.Blame(ctx, blameCache, results)
+	}
+
 	var data any
 	if compact {
 		searchResultsCompact := make([]SearchResultCompact, len(results))
@@ -429,6 +559,8 @@ func (s *Server) handleSearch(ctx context.Context, request mcp.CallToolRequest)
 				FilePath:  r.Chunk.FilePath,
 				StartLine: r.Chunk.StartLine,
 				EndLine:   r.Chunk.EndLine,
+				Cell:      r.Chunk.Cell,
+				Language:  r.Chunk.Language,
 				Score:     r.Score,
 			}
 			if info, ok := rpgData[i]; ok {
@@ -437,6 +569,13 @@ func (s *Server) handleSearch(ctx context.Context, request mcp.CallToolRequest)
 			}
 		}
 		data = searchResultsCompact
+		if blameData != nil {
+			blamed := make([]SearchResultCompactBlame, len(results))
+			for i := range results {
+				blamed[i] = SearchResultCompactBlame{SearchResultCompact: searchResultsCompact[i], Blame: blameData[i]}
+			}
+			data = blamed
+		}
 	} else {
 		searchResults := make([]SearchResult, len(results))
 		for i, r := range results {
@@ -444,6 +583,8 @@ func (s *Server) handleSearch(ctx context.Context, request mcp.CallToolRequest)
 				FilePath:  r.Chunk.FilePath,
 				StartLine: r.Chunk.StartLine,
 				EndLine:   r.Chunk.EndLine,
+				Cell:      r.Chunk.Cell,
+				Language:  r.Chunk.Language,
 				Score:     r.Score,
 				Content:   r.Chunk.Content,
 			}
@@ -453,6 +594,13 @@ func (s *Server) handleSearch(ctx context.Context, request mcp.CallToolRequest)
 			}
 		}
 		data = searchResults
+		if blameData != nil {
+			blamed := make([]SearchResultBlame, len(results))
+			for i := range results {
+				blamed[i] = SearchResultBlame{SearchResult: searchResults[i], Blame: blameData[i]}
+			}
+			data = blamed
+		}
 	}
 
 	output, err := encodeOutput(data, format)
@@ -463,8 +611,69 @@ func (s *Server) handleSearch(ctx context.Context, request mcp.CallToolRequest)
 	return mcp.NewToolResultText(output), nil
 }
 
+// handleHistorySearch handles the grepai_history_search tool call. The
+// history index is only read: 'grepai watch' and 'grepai log-search' keep it
+// up to date.
+func (s *Server) handleHistorySearch(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, err := request.RequireString("query")
+	if err != nil {
+		return mcp.NewToolResultError("query parameter is required"), nil
+	}
+
+	limit := request.GetInt("limit", 10)
+	if limit <= 0 {
+		limit = 10
+	}
+	format := request.GetString("format", "json")
+	if format != "json" && format != "toon" {
+		return mcp.NewToolResultError("format must be 'json' or 'toon'"), nil
+	}
+
+	cfg, err := config.Load(s.projectRoot)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to load configuration: %v", err)), nil
+	}
+	if !cfg.History.Enabled {
+		return mcp.NewToolResultError("commit history indexing is disabled (set history.enabled: true in .grepai/config.yaml)"), nil
+	}
+
+	commitLog, err := history.OpenLog(s.projectRoot)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to load commit history: %v", err)), nil
+	}
+	if commitLog.Len() == 0 {
+		return mcp.NewToolResultError("commit history is not indexed yet: run 'grepai log-search' or 'grepai watch'"), nil
+	}
+
+	emb, err := s.createEmbedder(cfg)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to initialize embedder: %v", err)), nil
+	}
+	defer emb.Close()
+
+	st, err := history.OpenStore(ctx, cfg, s.projectRoot)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to initialize history store: %v", err)), nil
+	}
+	defer st.Close()
+
+	results, err := history.Search(ctx, st, emb, commitLog, query, limit)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("history search failed: %v", err)), nil
+	}
+	if results == nil {
+		results = []history.Result{}
+	}
+
+	output, err := encodeOutput(results, format)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode results: %v", err)), nil
+	}
+	return mcp.NewToolResultText(output), nil
+}
+
 // handleWorkspaceSearch handles workspace-level search via MCP.
-func (s *Server) handleWorkspaceSearch(ctx context.Context, query string, limit int, compact bool, format, pathPrefix, workspaceName, projectsStr string) (*mcp.CallToolResult, error) {
+func (s *Server) handleWorkspaceSearch(ctx context.Context, query string, limit int, compact bool, format, pathPrefix, language, workspaceName, projectsStr string) (*mcp.CallToolResult, error) {
 	// Load workspace config
 	wsCfg, err := config.LoadWorkspaceConfig()
 	if err != nil {
@@ -528,7 +737,7 @@ func (s *Server) handleWorkspaceSearch(ctx context.Context, query string, limit
 
 	// Search
 	var results []store.SearchResult
-	results, err = searcher.Search(ctx, query, limit, fullPathPrefix)
+	results, err = searcher.SearchWithOptions(ctx, query, limit, store.SearchOptions{PathPrefix: fullPathPrefix, Language: language})
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("search failed: %v", err)), nil
 	}
@@ -577,6 +786,8 @@ func (s *Server) handleWorkspaceSearch(ctx context.Context, query string, limit
 				FilePath:  r.Chunk.FilePath,
 				StartLine: r.Chunk.StartLine,
 				EndLine:   r.Chunk.EndLine,
+				Cell:      r.Chunk.Cell,
+				Language:  r.Chunk.Language,
 				Score:     r.Score,
 			}
 		}
@@ -588,6 +799,8 @@ func (s *Server) handleWorkspaceSearch(ctx context.Context, query string, limit
 				FilePath:  r.Chunk.FilePath,
 				StartLine: r.Chunk.StartLine,
 				EndLine:   r.Chunk.EndLine,
+				Cell:      r.Chunk.Cell,
+				Language:  r.Chunk.Language,
 				Score:     r.Score,
 				Content:   r.Chunk.Content,
 			}
@@ -605,7 +818,11 @@ func (s *Server) handleWorkspaceSearch(ctx context.Context, query string, limit
 
 // createWorkspaceEmbedder creates an embedder based on workspace configuration.
 func (s *Server) createWorkspaceEmbedder(ws *config.Workspace) (embedder.Embedder, error) {
-	return embedder.NewFromWorkspaceConfig(ws)
+	usagePath, err := config.GetWorkspaceUsagePath(ws.Name)
+	if err != nil {
+		return embedder.NewFromWorkspaceConfig(ws)
+	}
+	return embedder.NewFromWorkspaceConfig(ws, embedder.WithUsageFile(usagePath))
 }
 
 // createWorkspaceStore creates a vector store based on workspace configuration.
@@ -725,6 +942,53 @@ func (s *Server) enrichTraceSymbols(ctx context.Context, symbols ...*trace.Symbo
 	}
 }
 
+// loadDirConfigs returns the nested .grepai.yaml files of the project. They
+// are found once per server, outside the directories the ignore files ignore,
+// instead of walking the project on every request.
+func (s *Server) loadDirConfigs(cfg *config.Config) *config.DirConfigs {
+	s.dirConfigsOnce.Do(func() {
+		ignoreMatcher, err := indexer.NewIgnoreMatcher(s.projectRoot, cfg.Ignore, cfg.ExternalGitignore)
+		if err != nil {
+			log.Printf("Warning: failed to initialize ignore matcher: %v", err)
+			s.dirConfigs = config.LoadDirConfigs(s.projectRoot, cfg.Ignore)
+			return
+		}
+		s.dirConfigs = config.LoadDirConfigs(s.projectRoot, cfg.Ignore, config.WithIgnoredDirs(ignoreMatcher.ShouldIgnore))
+	})
+	return s.dirConfigs
+}
+
+// openBlameCache loads the blame cache of the project.
+func (s *Server) openBlameCache(cfg *config.Config) *git.BlameCache {
+	cache := git.NewBlameCache(config.GetBlameCachePath(s.projectRoot), s.projectRoot, cfg.Search.Blame.ChurnDays)
+	cache.Load()
+	return cache
+}
+
+// persistBlameCache saves the blame cache, logging failures.
+func (s *Server) persistBlameCache(cache *git.BlameCache) {
+	if err := cache.Persist(); err != nil {
+		log.Printf("Warning: failed to save blame cache: %v", err)
+	}
+}
+
+// blameTraceSymbols adds git blame metadata to trace symbols of the project.
+func (s *Server) blameTraceSymbols(ctx context.Context, symbols ...*trace.Symbol) {
+	if s.projectRoot == "" || !git.IsGitRepo(s.projectRoot) {
+		return
+	}
+	cfg, err := config.Load(s.projectRoot)
+	if err != nil {
+		return
+	}
+	cache := s.openBlameCache(cfg)
+	defer s.persistBlameCache(cache)
+
+	for _, sym := range symbols {
+		trace.BlameSymbol(ctx, cache, sym)
+	}
+}
+
 // handleTraceCallers handles the grepai_trace_callers tool call.
 func (s *Server) handleTraceCallers(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	symbolName, err := request.RequireString("symbol")
@@ -733,6 +997,7 @@ func (s *Server) handleTraceCallers(ctx context.Context, request mcp.CallToolReq
 	}
 
 	compact := request.GetBool("compact", false)
+	blame := request.GetBool("blame", false)
 	format := request.GetString("format", "json")
 	workspace := s.resolveWorkspace(request.GetString("workspace", ""))
 	project := request.GetString("project", "")
@@ -750,7 +1015,7 @@ func (s *Server) handleTraceCallers(ctx context.Context, request mcp.CallToolReq
 		}
 		defer closeSymbolStores(stores)
 
-		return s.handleTraceCallersFromStores(ctx, symbolName, compact, format, stores)
+		return s.handleTraceCallersFromStores(ctx, symbolName, compact, false, format, stores)
 	}
 
 	// Single-project mode
@@ -769,11 +1034,11 @@ func (s *Server) handleTraceCallers(ctx context.Context, request mcp.CallToolReq
 		return mcp.NewToolResultError("symbol index is empty. Run 'grepai watch' first to build the index"), nil
 	}
 
-	return s.handleTraceCallersFromStores(ctx, symbolName, compact, format, []trace.SymbolStore{symbolStore})
+	return s.handleTraceCallersFromStores(ctx, symbolName, compact, blame, format, []trace.SymbolStore{symbolStore})
 }
 
 // handleTraceCallersFromStores handles callers lookup across one or more symbol stores.
-func (s *Server) handleTraceCallersFromStores(ctx context.Context, symbolName string, compact bool, format string, stores []trace.SymbolStore) (*mcp.CallToolResult, error) {
+func (s *Server) handleTraceCallersFromStores(ctx context.Context, symbolName string, compact, blame bool, format string, stores []trace.SymbolStore) (*mcp.CallToolResult, error) {
 	// Aggregate results across stores
 	var firstSymbol *trace.Symbol
 	var allRefs []trace.Reference
@@ -835,6 +1100,9 @@ func (s *Server) handleTraceCallersFromStores(ctx context.Context, symbolName st
 			symPtrs = append(symPtrs, &resultCompact.Callers[i].Symbol)
 		}
 		s.enrichTraceSymbols(ctx, symPtrs...)
+		if blame {
+			s.blameTraceSymbols(ctx, symPtrs...)
+		}
 
 		data = resultCompact
 	} else {
@@ -861,6 +1129,7 @@ func (s *Server) handleTraceCallersFromStores(ctx context.Context, symbolName st
 					File:    ref.File,
 					Line:    ref.Line,
 					Context: ref.Context,
+					Cell:    ref.Cell,
 				},
 			})
 		}
@@ -871,6 +1140,9 @@ func (s *Server) handleTraceCallersFromStores(ctx context.Context, symbolName st
 			symPtrs = append(symPtrs, &result.Callers[i].Symbol)
 		}
 		s.enrichTraceSymbols(ctx, symPtrs...)
+		if blame {
+			s.blameTraceSymbols(ctx, symPtrs...)
+		}
 
 		data = result
 	}
@@ -891,6 +1163,7 @@ func (s *Server) handleTraceCallees(ctx context.Context, request mcp.CallToolReq
 	}
 
 	compact := request.GetBool("compact", false)
+	blame := request.GetBool("blame", false)
 	format := request.GetString("format", "json")
 	workspace := s.resolveWorkspace(request.GetString("workspace", ""))
 	project := request.GetString("project", "")
@@ -908,7 +1181,7 @@ func (s *Server) handleTraceCallees(ctx context.Context, request mcp.CallToolReq
 		}
 		defer closeSymbolStores(stores)
 
-		return s.handleTraceCalleesFromStores(ctx, symbolName, compact, format, stores)
+		return s.handleTraceCalleesFromStores(ctx, symbolName, compact, false, format, stores)
 	}
 
 	// Single-project mode
@@ -927,11 +1200,11 @@ func (s *Server) handleTraceCallees(ctx context.Context, request mcp.CallToolReq
 		return mcp.NewToolResultError("symbol index is empty. Run 'grepai watch' first to build the index"), nil
 	}
 
-	return s.handleTraceCalleesFromStores(ctx, symbolName, compact, format, []trace.SymbolStore{symbolStore})
+	return s.handleTraceCalleesFromStores(ctx, symbolName, compact, blame, format, []trace.SymbolStore{symbolStore})
 }
 
 // handleTraceCalleesFromStores handles callees lookup across one or more symbol stores.
-func (s *Server) handleTraceCalleesFromStores(ctx context.Context, symbolName string, compact bool, format string, stores []trace.SymbolStore) (*mcp.CallToolResult, error) {
+func (s *Server) handleTraceCalleesFromStores(ctx context.Context, symbolName string, compact, blame bool, format string, stores []trace.SymbolStore) (*mcp.CallToolResult, error) {
 	var firstSymbol *trace.Symbol
 	var allRefs []trace.Reference
 
@@ -994,6 +1267,9 @@ func (s *Server) handleTraceCalleesFromStores(ctx context.Context, symbolName st
 			symPtrs = append(symPtrs, &resultCompact.Callees[i].Symbol)
 		}
 		s.enrichTraceSymbols(ctx, symPtrs...)
+		if blame {
+			s.blameTraceSymbols(ctx, symPtrs...)
+		}
 
 		data = resultCompact
 	} else {
@@ -1020,6 +1296,7 @@ func (s *Server) handleTraceCalleesFromStores(ctx context.Context, symbolName st
 					File:    ref.File,
 					Line:    ref.Line,
 					Context: ref.Context,
+					Cell:    ref.Cell,
 				},
 			})
 		}
@@ -1030,6 +1307,9 @@ func (s *Server) handleTraceCalleesFromStores(ctx context.Context, symbolName st
 			symPtrs = append(symPtrs, &result.Callees[i].Symbol)
 		}
 		s.enrichTraceSymbols(ctx, symPtrs...)
+		if blame {
+			s.blameTraceSymbols(ctx, symPtrs...)
+		}
 
 		data = result
 	}
@@ -1054,6 +1334,7 @@ func (s *Server) handleTraceGraph(ctx context.Context, request mcp.CallToolReque
 		depth = 2
 	}
 
+	blame := request.GetBool("blame", false)
 	format := request.GetString("format", "json")
 	workspace := s.resolveWorkspace(request.GetString("workspace", ""))
 	project := request.GetString("project", "")
@@ -1152,6 +1433,9 @@ func (s *Server) handleTraceGraph(ctx context.Context, request mcp.CallToolReque
 			symPtrs = append(symPtrs, &entries[len(entries)-1].sym)
 		}
 		s.enrichTraceSymbols(ctx, symPtrs...)
+		if blame {
+			s.blameTraceSymbols(ctx, symPtrs...)
+		}
 		for _, e := range entries {
 			result.Graph.Nodes[e.name] = e.sym
 		}
@@ -1165,6 +1449,146 @@ func (s *Server) handleTraceGraph(ctx context.Context, request mcp.CallToolReque
 	return mcp.NewToolResultText(output), nil
 }
 
+// handleImpact handles the grepai_impact tool call.
+func (s *Server) handleImpact(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	rng := request.GetString("range", "")
+	staged := request.GetBool("staged", false)
+	depth := request.GetInt("depth", trace.DefaultImpactDepth)
+	if depth < 0 {
+		depth = trace.DefaultImpactDepth
+	}
+	format := request.GetString("format", "json")
+	if format != "json" && format != "toon" {
+		return mcp.NewToolResultError("format must be 'json' or 'toon'"), nil
+	}
+	if staged && strings.Contains(rng, "..") {
+		return mcp.NewToolResultError("staged compares the index to a single revision, not a range"), nil
+	}
+
+	if s.projectRoot == "" {
+		return mcp.NewToolResultError("impact requires a project context; start mcp-serve from a project directory"), nil
+	}
+	if !git.IsGitRepo(s.projectRoot) {
+		return mcp.NewToolResultError("impact requires a git repository"), nil
+	}
+
+	symbolStore := trace.NewGOBSymbolStore(config.GetSymbolIndexPath(s.projectRoot))
+	if err := symbolStore.Load(ctx); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to load symbol index: %v. Run 'grepai watch' first", err)), nil
+	}
+	defer symbolStore.Close()
+
+	stats, err := symbolStore.GetStats(ctx)
+	if err != nil || stats.TotalSymbols == 0 {
+		return mcp.NewToolResultError("symbol index is empty. Run 'grepai watch' first to build the index"), nil
+	}
+
+	diffs, err := git.Diff(ctx, s.projectRoot, rng, staged)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	impact, err := trace.AnalyzeImpact(ctx, symbolStore, trace.ChangesFromDiff(diffs), depth)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("impact analysis failed: %v", err)), nil
+	}
+	impact.Range = git.DescribeDiff(rng, staged)
+	s.enrichTraceSymbols(ctx, impact.Symbols()...)
+	impact.CollectFeatures()
+
+	output, err := encodeOutput(impact, format)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode results: %v", err)), nil
+	}
+	return mcp.NewToolResultText(output), nil
+}
+
+// handleReviewContext handles the grepai_review_context tool call.
+func (s *Server) handleReviewContext(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	rng, err := request.RequireString("range")
+	if err != nil {
+		return mcp.NewToolResultError("range parameter is required"), nil
+	}
+	if !strings.Contains(rng, "..") {
+		return mcp.NewToolResultError("range must be '<base>..<head>'"), nil
+	}
+	if err := git.ValidateRevision(rng); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	budget := request.GetInt("budget", review.DefaultBudget)
+	perHunk := request.GetInt("per_hunk", review.DefaultPerHunk)
+	format := request.GetString("format", "json")
+	if format != "json" && format != "toon" {
+		return mcp.NewToolResultError("format must be 'json' or 'toon'"), nil
+	}
+
+	if s.projectRoot == "" {
+		return mcp.NewToolResultError("review context requires a project context; start mcp-serve from a project directory"), nil
+	}
+	if !git.IsGitRepo(s.projectRoot) {
+		return mcp.NewToolResultError("review context requires a git repository"), nil
+	}
+
+	cfg, err := config.Load(s.projectRoot)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to load configuration: %v", err)), nil
+	}
+	diffs, err := git.Diff(ctx, s.projectRoot, rng, false)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	emb, err := s.createEmbedder(cfg)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to initialize embedder: %v", err)), nil
+	}
+	defer emb.Close()
+
+	st, err := s.createStore(ctx, cfg)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to initialize store: %v", err)), nil
+	}
+	defer st.Close()
+
+	overlay := s.createOverlayStore(ctx, cfg)
+	if overlay != nil {
+		defer overlay.Close()
+	}
+	searcher := search.NewSearcher(st, emb, cfg.Search,
+		search.WithDirConfigs(s.loadDirConfigs(cfg)),
+		search.WithOverlay(overlay))
+
+	opts := review.Options{
+		PerHunk: perHunk,
+		Budget:  budget,
+		Enrich: func(symbols ...*trace.Symbol) {
+			s.enrichTraceSymbols(ctx, symbols...)
+		},
+	}
+	if cfg.Redaction.Enabled(cfg.Embedder) {
+		opts.Redactor = cfg.Redaction.NewRedactor()
+	}
+	// The call graph is best-effort: the pack is still useful without it
+	symbolStore := trace.NewGOBSymbolStore(config.GetSymbolIndexPath(s.projectRoot))
+	if err := symbolStore.Load(ctx); err == nil {
+		defer symbolStore.Close()
+		if stats, err := symbolStore.GetStats(ctx); err == nil && stats.TotalSymbols > 0 {
+			opts.Symbols = symbolStore
+		}
+	}
+
+	pack, err := review.Build(ctx, searcher, diffs, opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to build review context: %v", err)), nil
+	}
+	pack.Range = rng
+
+	output, err := encodeOutput(pack, format)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode results: %v", err)), nil
+	}
+	return mcp.NewToolResultText(output), nil
+}
+
 // WorkspaceIndexStatus represents the status of a workspace index.
 type WorkspaceIndexStatus struct {
 	Workspace string                   `json:"workspace"`
@@ -1383,9 +1807,13 @@ func (s *Server) handleListProjects(ctx context.Context, request mcp.CallToolReq
 	return mcp.NewToolResultText(output), nil
 }
 
-// createEmbedder creates an embedder based on configuration.
+// createEmbedder creates an embedder based on configuration, recording its
+// usage in the project when there is one.
 func (s *Server) createEmbedder(cfg *config.Config) (embedder.Embedder, error) {
-	return embedder.NewFromConfig(cfg)
+	if s.projectRoot == "" {
+		return embedder.NewFromConfig(cfg)
+	}
+	return embedder.NewFromConfig(cfg, embedder.WithUsageFile(config.GetUsagePath(s.projectRoot)))
 }
 
 // createStore creates a vector store based on configuration.
@@ -1411,6 +1839,30 @@ func (s *Server) createStore(ctx context.Context, cfg *config.Config) (store.Vec
 	}
 }
 
+// createOverlayStore opens the overlay of the checked out branch when branch
+// overlays are enabled, or returns nil.
+func (s *Server) createOverlayStore(ctx context.Context, cfg *config.Config) store.VectorStore {
+	branch, err := config.OverlayBranch(s.projectRoot, cfg)
+	if err != nil || branch == "" || !config.HasOverlay(s.projectRoot, branch) {
+		return nil
+	}
+	switch cfg.Store.Backend {
+	case "gob":
+		gobStore := store.NewGOBStore(config.GetOverlayIndexPath(s.projectRoot, branch))
+		if err := gobStore.Load(ctx); err != nil {
+			return nil
+		}
+		return gobStore
+	case "postgres":
+		pgStore, err := store.NewPostgresStore(ctx, cfg.Store.Postgres.DSN, config.OverlayProjectID(s.projectRoot, branch), cfg.Embedder.GetDimensions())
+		if err != nil {
+			return nil
+		}
+		return pgStore
+	}
+	return nil
+}
+
 // Serve starts the MCP server using stdio transport.
 func (s *Server) Serve() error {
 	// Create stdio server with title fix wrapper
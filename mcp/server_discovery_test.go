@@ -1,7 +1,10 @@
 package mcp
 
 import (
+	"strings"
 	"testing"
+
+	"github.com/yoanbernabeu/grepai/git"
 )
 
 // TestDiscoveryToolsCompile verifies that the discovery tools are properly integrated
@@ -71,3 +74,19 @@ func TestEncodeOutput(t *testing.T) {
 		})
 	}
 }
+
+// TestEncodeOutputBlameTOON verifies that blamed search results are flattened in TOON
+func TestEncodeOutputBlameTOON(t *testing.T) {
+	data := []SearchResultCompactBlame{{
+		SearchResultCompact: SearchResultCompact{FilePath: "main.go", StartLine: 1, EndLine: 3, Score: 0.9},
+		Blame:               &git.BlameInfo{SHA: "abc123", Author: "Dev", Churn: 2, ChurnDays: 90},
+	}}
+
+	output, err := encodeOutput(data, "toon")
+	if err != nil {
+		t.Fatalf("encodeOutput() failed: %v", err)
+	}
+	if strings.Contains(output, "SearchResultCompact") || !strings.Contains(output, "file_path: main.go") || !strings.Contains(output, "sha: abc123") {
+		t.Errorf("expected flattened results with blame, got:\n%s", output)
+	}
+}
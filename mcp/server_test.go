@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	mcplib "github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/yoanbernabeu/grepai/config"
 	"github.com/yoanbernabeu/grepai/trace"
@@ -488,7 +489,7 @@ func TestHandleTraceCallersFromStores_should_aggregate_across_stores(t *testing.
 	s := &Server{}
 	stores := []trace.SymbolStore{store1, store2}
 
-	result, err := s.handleTraceCallersFromStores(ctx, "Login", false, "json", stores)
+	result, err := s.handleTraceCallersFromStores(ctx, "Login", false, false, "json", stores)
 	if err != nil {
 		t.Fatalf("handleTraceCallersFromStores returned error: %v", err)
 	}
@@ -543,7 +544,7 @@ func TestHandleTraceCalleesFromStores_should_aggregate_across_stores(t *testing.
 	s := &Server{}
 	stores := []trace.SymbolStore{store1, store2}
 
-	result, err := s.handleTraceCalleesFromStores(ctx, "HandleRequest", false, "json", stores)
+	result, err := s.handleTraceCalleesFromStores(ctx, "HandleRequest", false, false, "json", stores)
 	if err != nil {
 		t.Fatalf("handleTraceCalleesFromStores returned error: %v", err)
 	}
@@ -562,3 +563,21 @@ func TestHandleTraceCalleesFromStores_should_aggregate_across_stores(t *testing.
 		t.Errorf("expected result to contain callee 'SendResponse', got: %s", text)
 	}
 }
+
+func TestHandleReviewContext_should_reject_option_ranges(t *testing.T) {
+	s := &Server{projectRoot: t.TempDir()}
+	for _, rng := range []string{"--output=/tmp/x..HEAD", "HEAD..-p"} {
+		request := mcplib.CallToolRequest{}
+		request.Params.Arguments = map[string]any{"range": rng}
+		result, err := s.handleReviewContext(context.Background(), request)
+		if err != nil {
+			t.Fatalf("handleReviewContext returned error: %v", err)
+		}
+		if !result.IsError {
+			t.Errorf("expected %q to be rejected", rng)
+		}
+		if text := result.Content[0].(mcplib.TextContent).Text; !strings.Contains(text, "invalid git revision") {
+			t.Errorf("expected an invalid revision error, got %q", text)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// Package redact masks secrets (API keys, private keys, JWTs, credentials in
+// connection strings, high-entropy tokens) in text before it is sent to a
+// remote embedding provider.
+package redact
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultEntropyThreshold is the Shannon entropy, in bits per character,
+	// above which a token is treated as a secret.
+	DefaultEntropyThreshold = 4.0
+
+	// minEntropyTokenLength is the shortest token checked for entropy.
+	minEntropyTokenLength = 24
+
+	// EntropyRule is the rule name reported for high-entropy tokens.
+	EntropyRule = "high-entropy"
+)
+
+// Rule detects one kind of secret. When the pattern has a capturing group,
+// only the first group is masked (e.g. the password of a connection string).
+type Rule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// CompileRule builds a rule from a regular expression.
+func CompileRule(name, pattern string) (Rule, error) {
+	if name == "" {
+		return Rule{}, fmt.Errorf("redaction rule %q has no name", pattern)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Rule{}, fmt.Errorf("invalid redaction rule %s: %w", name, err)
+	}
+	return Rule{Name: name, Pattern: re}, nil
+}
+
+// DefaultRules returns the built-in rules.
+func DefaultRules() []Rule {
+	return []Rule{
+		{"private-key", regexp.MustCompile(`-----BEGIN[ A-Z0-9]*PRIVATE KEY-----(?:[\s\S]*?-----END[ A-Z0-9]*PRIVATE KEY-----|[\s\S]*\z)`)},
+		// The tail of a key block cut by a chunk boundary
+		{"private-key", regexp.MustCompile(`\A[A-Za-z0-9+/=\s]*?-----END[ A-Z0-9]*PRIVATE KEY-----`)},
+		{"aws-access-key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+		{"aws-secret-key", regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})`)},
+		{"github-token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+		{"gitlab-token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}\b`)},
+		{"openai-key", regexp.MustCompile(`\bsk-(?:proj-|ant-|or-v1-)?[A-Za-z0-9_-]{20,}`)},
+		{"slack-token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+		{"stripe-key", regexp.MustCompile(`\b[rsp]k_(?:live|test)_[A-Za-z0-9]{16,}\b`)},
+		{"google-api-key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+		{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+		{"connection-string", regexp.MustCompile(`\b[a-zA-Z][a-zA-Z0-9+.-]*://[^\s:/@'"]+:([^\s@/'"]+)@`)},
+		// KEY=value assignments of .env files and constants
+		{"secret-assignment", regexp.MustCompile(`(?m)^\s*(?:export\s+)?[A-Z0-9_]*(?:KEY|SECRET|TOKEN|PASSWORD|PASSWD|PWD|CREDENTIALS?)[A-Z0-9_]*\s*[:=]\s*["']?([^\s"'$<{][^\s"']{3,})["']?[,;]?\s*$`)},
+	}
+}
+
+// entropyCandidateRe finds tokens checked for entropy.
+var entropyCandidateRe = regexp.MustCompile(`[A-Za-z0-9+/_-]{24,}={0,2}`)
+
+// Finding is a secret found in a text. The secret itself is never kept.
+type Finding struct {
+	Rule string `json:"rule"`
+	Line int    `json:"line"` // 1-based
+}
+
+// Redactor masks secrets matched by its rules.
+type Redactor struct {
+	rules            []Rule
+	entropyThreshold float64 // 0 disables high-entropy detection
+}
+
+// New creates a redactor. entropyThreshold <= 0 disables high-entropy detection.
+func New(rules []Rule, entropyThreshold float64) *Redactor {
+	return &Redactor{rules: rules, entropyThreshold: entropyThreshold}
+}
+
+// span is a masked range of a text.
+type span struct {
+	start, end int
+	rule       string
+}
+
+// Redact returns text with every secret replaced by [REDACTED:<rule>].
+func (r *Redactor) Redact(text string) string {
+	spans := r.scan(text)
+	if len(spans) == 0 {
+		return text
+	}
+	var sb strings.Builder
+	sb.Grow(len(text))
+	pos := 0
+	for _, s := range spans {
+		sb.WriteString(text[pos:s.start])
+		sb.WriteString("[REDACTED:" + s.rule + "]")
+		pos = s.end
+	}
+	sb.WriteString(text[pos:])
+	return sb.String()
+}
+
+// Find returns the secrets in text with their line numbers.
+func (r *Redactor) Find(text string) []Finding {
+	spans := r.scan(text)
+	findings := make([]Finding, len(spans))
+	line, pos := 1, 0
+	for i, s := range spans {
+		line += strings.Count(text[pos:s.start], "\n")
+		pos = s.start
+		findings[i] = Finding{Rule: s.rule, Line: line}
+	}
+	return findings
+}
+
+// scan returns the non-overlapping spans to mask, in order.
+func (r *Redactor) scan(text string) []span {
+	var spans []span
+	for _, rule := range r.rules {
+		for _, m := range rule.Pattern.FindAllStringSubmatchIndex(text, -1) {
+			start, end := m[0], m[1]
+			if len(m) >= 4 && m[2] >= 0 {
+				start, end = m[2], m[3]
+			}
+			if start < end {
+				spans = append(spans, span{start: start, end: end, rule: rule.Name})
+			}
+		}
+	}
+	if r.entropyThreshold > 0 {
+		for _, m := range entropyCandidateRe.FindAllStringIndex(text, -1) {
+			if isHighEntropy(text[m[0]:m[1]], r.entropyThreshold) {
+				spans = append(spans, span{start: m[0], end: m[1], rule: EntropyRule})
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+
+	// Keep the earliest (then longest) span of overlapping matches
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].start != spans[j].start {
+			return spans[i].start < spans[j].start
+		}
+		return spans[i].end > spans[j].end
+	})
+	merged := spans[:1]
+	for _, s := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if s.start < last.end {
+			if s.end > last.end {
+				last.end = s.end
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+// isHighEntropy reports whether token looks like a random secret: mixed case
+// with several digits, which rules out identifiers and hex hashes, and a
+// Shannon entropy of at least threshold bits per character.
+func isHighEntropy(token string, threshold float64) bool {
+	if len(token) < minEntropyTokenLength {
+		return false
+	}
+	var upper, lower, digits int
+	counts := make(map[rune]int)
+	for _, c := range token {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			upper++
+		case c >= 'a' && c <= 'z':
+			lower++
+		case c >= '0' && c <= '9':
+			digits++
+		}
+		counts[c]++
+	}
+	if upper == 0 || lower == 0 || digits < 3 {
+		return false
+	}
+	return shannonEntropy(counts, len(token)) >= threshold
+}
+
+func shannonEntropy(counts map[rune]int, length int) float64 {
+	entropy := 0.0
+	for _, n := range counts {
+		p := float64(n) / float64(length)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
@@ -0,0 +1,112 @@
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// Report tracks the files in which secrets were masked. Only rule names and
+// line numbers are kept, never the secrets themselves.
+type Report struct {
+	mu    sync.Mutex
+	path  string
+	dirty bool
+	Files map[string][]Finding `json:"files"`
+}
+
+// LoadReport loads the report at path. A missing file yields an empty report.
+func LoadReport(reportPath string) (*Report, error) {
+	r := &Report{path: reportPath, Files: make(map[string][]Finding)}
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return r, nil
+		}
+		return r, fmt.Errorf("failed to read secrets report: %w", err)
+	}
+	if err := json.Unmarshal(data, r); err != nil {
+		return r, fmt.Errorf("failed to parse secrets report: %w", err)
+	}
+	if r.Files == nil {
+		r.Files = make(map[string][]Finding)
+	}
+	return r, nil
+}
+
+// Record sets the findings of a file, or clears its entry when there are none.
+func (r *Report) Record(relPath string, findings []Finding) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(findings) == 0 {
+		if _, ok := r.Files[relPath]; ok {
+			delete(r.Files, relPath)
+			r.dirty = true
+		}
+		return
+	}
+	if !slices.Equal(r.Files[relPath], findings) {
+		r.Files[relPath] = findings
+		r.dirty = true
+	}
+}
+
+// Remove drops the entry of a deleted file.
+func (r *Report) Remove(relPath string) {
+	r.Record(relPath, nil)
+}
+
+// Prune drops entries for files that no longer exist in the project.
+func (r *Report) Prune(present map[string]bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for p := range r.Files {
+		if !present[p] {
+			delete(r.Files, p)
+			r.dirty = true
+		}
+	}
+}
+
+// Save writes the report to disk if it changed since it was loaded.
+func (r *Report) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.dirty || r.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal secrets report: %w", err)
+	}
+	if err := os.WriteFile(r.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write secrets report: %w", err)
+	}
+	r.dirty = false
+	return nil
+}
+
+// FileFindings is the report entry of one file.
+type FileFindings struct {
+	Path     string    `json:"path"`
+	Findings []Finding `json:"findings"`
+}
+
+// Entries returns the files with secrets, sorted by path.
+func (r *Report) Entries() []FileFindings {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := make([]FileFindings, 0, len(r.Files))
+	for p, findings := range r.Files {
+		entries = append(entries, FileFindings{Path: p, Findings: findings})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries
+}
@@ -0,0 +1,325 @@
+// Package review builds review context packs: for each hunk of a diff, the
+// code of the repository that is related to it.
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yoanbernabeu/grepai/embedder"
+	"github.com/yoanbernabeu/grepai/git"
+	"github.com/yoanbernabeu/grepai/indexer"
+	"github.com/yoanbernabeu/grepai/redact"
+	"github.com/yoanbernabeu/grepai/store"
+	"github.com/yoanbernabeu/grepai/trace"
+)
+
+const (
+	// DefaultPerHunk is the default number of related chunks per hunk.
+	DefaultPerHunk = 3
+	// DefaultBudget is the default token budget of a context pack.
+	DefaultBudget = 4000
+
+	maxQueryLines   = 60 // Hunk lines embedded as the search query
+	maxSnippetLines = 8  // Lines of related chunks kept in the pack
+	maxLinks        = 5  // Callers and callees listed per hunk
+)
+
+// Searcher runs similarity searches, typically a *search.Searcher.
+type Searcher interface {
+	SearchWithOptions(ctx context.Context, query string, limit int, opts store.SearchOptions) ([]store.SearchResult, error)
+}
+
+// Options configures Build.
+type Options struct {
+	PerHunk int // Related chunks per hunk (default: DefaultPerHunk)
+	Budget  int // Estimated token budget, <= 0 for no limit
+
+	// Symbols adds the changed symbols of each hunk and their callers and
+	// callees. Optional.
+	Symbols trace.SymbolStore
+	// Enrich sets the RPG feature path of symbols. Optional.
+	Enrich func(symbols ...*trace.Symbol)
+	// Redactor masks secrets in the hunks before they are embedded as search
+	// queries. Optional.
+	Redactor *redact.Redactor
+}
+
+// Pack is the review context of a diff.
+type Pack struct {
+	Range     string   `json:"range"`
+	Tokens    int      `json:"tokens"`              // Estimated size of the pack
+	Truncated bool     `json:"truncated,omitempty"` // Context was left out to fit the budget
+	Hunks     []Hunk   `json:"hunks"`
+	Features  []string `json:"features,omitempty"` // RPG feature areas of the changed code and its callers and callees
+}
+
+// Hunk is the context of a hunk of the diff.
+type Hunk struct {
+	File      string      `json:"file"`
+	StartLine int         `json:"start_line"`
+	EndLine   int         `json:"end_line"`
+	Added     int         `json:"added"`
+	Removed   int         `json:"removed"`
+	Symbols   []string    `json:"symbols,omitempty"` // Changed symbols
+	Callers   []SymbolRef `json:"callers,omitempty"`
+	Callees   []SymbolRef `json:"callees,omitempty"`
+	Related   []Related   `json:"related,omitempty"` // Similar code outside the changed files
+}
+
+// SymbolRef locates a symbol.
+type SymbolRef struct {
+	Name string `json:"name"`
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// Related is a chunk similar to a hunk.
+type Related struct {
+	File      string  `json:"file"`
+	StartLine int     `json:"start_line"`
+	EndLine   int     `json:"end_line"`
+	Score     float32 `json:"score"`
+	Snippet   string  `json:"snippet"`
+}
+
+// candidate is context of a hunk that goes in the pack if the budget allows.
+type candidate struct {
+	hunk int
+	cost int
+	add  func()
+}
+
+// Build searches the code related to each hunk of a diff, excluding the files
+// the diff changes, and packs it within the token budget. Hunk locations come
+// first, then call graph context, then related chunks by rank, so that every
+// hunk gets some context before any gets all of it.
+func Build(ctx context.Context, searcher Searcher, diffs []git.FileDiff, opts Options) (*Pack, error) {
+	if opts.PerHunk <= 0 {
+		opts.PerHunk = DefaultPerHunk
+	}
+	changed := make(map[string]bool)
+	for _, d := range diffs {
+		changed[d.Path] = true
+		if d.OldPath != "" {
+			changed[d.OldPath] = true
+		}
+	}
+
+	pack := &Pack{Hunks: []Hunk{}}
+	var locations, links []candidate
+	var related [][]candidate // By rank
+	var linked []*trace.Symbol
+	seen := make(map[string]bool)
+
+	for _, d := range diffs {
+		for _, h := range git.ParseHunks(d.Patch) {
+			r := h.Range()
+			hunk := Hunk{File: d.Path, StartLine: r.Start, EndLine: r.End, Added: len(h.Added), Removed: len(h.Removed)}
+
+			var callers, callees []SymbolRef
+			if opts.Symbols != nil {
+				symbols, err := trace.ChangedSymbols(ctx, opts.Symbols, trace.Change{File: d.Path, Lines: []git.LineRange{r}})
+				if err != nil {
+					return nil, err
+				}
+				for _, sym := range symbols {
+					hunk.Symbols = append(hunk.Symbols, sym.Name)
+					linked = append(linked, &sym)
+				}
+				if callers, callees, err = callGraph(ctx, opts.Symbols, symbols); err != nil {
+					return nil, err
+				}
+				for _, ref := range append(append([]SymbolRef{}, callers...), callees...) {
+					linked = append(linked, &trace.Symbol{Name: ref.Name, File: ref.File, Line: ref.Line})
+				}
+			}
+
+			results, err := searchRelated(ctx, searcher, h, changed, opts.PerHunk, opts.Redactor)
+			if err != nil {
+				return nil, fmt.Errorf("failed to search code related to %s:%d: %w", d.Path, r.Start, err)
+			}
+
+			pack.Hunks = append(pack.Hunks, Hunk{})
+			i := len(pack.Hunks) - 1
+			locations = append(locations, candidate{hunk: i, cost: estimate(hunk), add: func() { pack.Hunks[i] = hunk }})
+			if len(callers)+len(callees) > 0 {
+				links = append(links, candidate{hunk: i, cost: estimate(callers) + estimate(callees), add: func() {
+					pack.Hunks[i].Callers, pack.Hunks[i].Callees = callers, callees
+				}})
+			}
+			rank := 0
+			for _, rel := range results {
+				key := fmt.Sprintf("%s:%d", rel.File, rel.StartLine)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				if rank == len(related) {
+					related = append(related, nil)
+				}
+				related[rank] = append(related[rank], candidate{hunk: i, cost: estimate(rel), add: func() {
+					pack.Hunks[i].Related = append(pack.Hunks[i].Related, rel)
+				}})
+				rank++
+			}
+		}
+	}
+
+	// A hunk whose location does not fit gets no context and leaves an empty
+	// slot, removed below
+	placed := make([]bool, len(pack.Hunks))
+	fill := func(candidates []candidate, location bool) {
+		for _, c := range candidates {
+			if !location && !placed[c.hunk] {
+				continue
+			}
+			if opts.Budget > 0 && pack.Tokens+c.cost > opts.Budget {
+				pack.Truncated = true
+				continue
+			}
+			pack.Tokens += c.cost
+			placed[c.hunk] = true
+			c.add()
+		}
+	}
+	fill(locations, true)
+	fill(links, false)
+	for _, candidates := range related {
+		fill(candidates, false)
+	}
+
+	hunks := pack.Hunks[:0]
+	for i, h := range pack.Hunks {
+		if placed[i] {
+			hunks = append(hunks, h)
+		}
+	}
+	pack.Hunks = hunks
+
+	if opts.Enrich != nil && len(linked) > 0 {
+		opts.Enrich(linked...)
+		seenFeatures := make(map[string]bool)
+		var features []string
+		for _, sym := range linked {
+			if sym.FeaturePath != "" && !seenFeatures[sym.FeaturePath] {
+				seenFeatures[sym.FeaturePath] = true
+				features = append(features, sym.FeaturePath)
+			}
+		}
+		sort.Strings(features)
+		if cost := estimate(features); opts.Budget > 0 && pack.Tokens+cost > opts.Budget {
+			pack.Truncated = true
+		} else if len(features) > 0 {
+			pack.Features = features
+			pack.Tokens += cost
+		}
+	}
+	return pack, nil
+}
+
+// callGraph returns the callers and callees of the changed symbols, up to
+// maxLinks of each.
+func callGraph(ctx context.Context, st trace.SymbolStore, symbols []trace.Symbol) (callers, callees []SymbolRef, err error) {
+	changed := make(map[string]bool)
+	for _, sym := range symbols {
+		changed[sym.Name] = true
+	}
+	seenCallers := make(map[string]bool)
+	seenCallees := make(map[string]bool)
+	for _, sym := range symbols {
+		refs, err := st.LookupCallers(ctx, sym.Name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to look up callers of %s: %w", sym.Name, err)
+		}
+		for _, ref := range refs {
+			if ref.CallerName == "" || changed[ref.CallerName] || seenCallers[ref.CallerName] || len(callers) == maxLinks {
+				continue
+			}
+			seenCallers[ref.CallerName] = true
+			callers = append(callers, SymbolRef{Name: ref.CallerName, File: ref.CallerFile, Line: ref.CallerLine})
+		}
+
+		refs, err = st.LookupCallees(ctx, sym.Name, sym.File)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to look up callees of %s: %w", sym.Name, err)
+		}
+		for _, ref := range refs {
+			if changed[ref.SymbolName] || seenCallees[ref.SymbolName] || len(callees) == maxLinks {
+				continue
+			}
+			seenCallees[ref.SymbolName] = true
+			callee := SymbolRef{Name: ref.SymbolName}
+			if defs, _ := st.LookupSymbol(ctx, ref.SymbolName); len(defs) > 0 {
+				callee.File, callee.Line = defs[0].File, defs[0].Line
+			}
+			callees = append(callees, callee)
+		}
+	}
+	return callers, callees, nil
+}
+
+// searchRelated returns the chunks most similar to a hunk outside the changed
+// files. The hunk is masked with redactor, when set, before it is embedded.
+func searchRelated(ctx context.Context, searcher Searcher, h git.Hunk, changed map[string]bool, limit int, redactor *redact.Redactor) ([]Related, error) {
+	lines := h.Added
+	if len(strings.TrimSpace(strings.Join(lines, ""))) == 0 {
+		lines = h.Removed
+	}
+	if len(lines) > maxQueryLines {
+		lines = lines[:maxQueryLines]
+	}
+	query := strings.TrimSpace(strings.Join(lines, "\n"))
+	if query == "" {
+		return nil, nil
+	}
+	if redactor != nil {
+		query = redactor.Redact(query)
+	}
+
+	// Ask for more results than needed, as chunks of changed files are dropped
+	results, err := searcher.SearchWithOptions(ctx, query, limit*4+10, store.SearchOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var related []Related
+	for _, r := range results {
+		if changed[r.Chunk.FilePath] {
+			continue
+		}
+		related = append(related, Related{
+			File:      r.Chunk.FilePath,
+			StartLine: r.Chunk.StartLine,
+			EndLine:   r.Chunk.EndLine,
+			Score:     r.Score,
+			Snippet:   snippet(r.Chunk),
+		})
+		if len(related) == limit {
+			break
+		}
+	}
+	return related, nil
+}
+
+// snippet keeps the first lines of a chunk, without its "File:" context
+// header.
+func snippet(chunk store.Chunk) string {
+	_, content := indexer.SplitContextHeader(chunk.FilePath, chunk.Content)
+	lines := strings.Split(content, "\n")
+	if len(lines) > maxSnippetLines {
+		lines = append(lines[:maxSnippetLines], "...")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+// estimate returns the estimated token count of a value in the pack.
+func estimate(v any) int {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return embedder.EstimateTokens(string(data))
+}
@@ -0,0 +1,118 @@
+package review
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yoanbernabeu/grepai/git"
+	"github.com/yoanbernabeu/grepai/redact"
+	"github.com/yoanbernabeu/grepai/store"
+	"github.com/yoanbernabeu/grepai/trace"
+)
+
+// fakeSearcher returns the same results for every query.
+type fakeSearcher struct {
+	results []store.SearchResult
+	queries []string
+}
+
+func (f *fakeSearcher) SearchWithOptions(ctx context.Context, query string, limit int, opts store.SearchOptions) ([]store.SearchResult, error) {
+	f.queries = append(f.queries, query)
+	if limit < len(f.results) {
+		return f.results[:limit], nil
+	}
+	return f.results, nil
+}
+
+func chunkResult(file string, start int, score float32) store.SearchResult {
+	content := "File: " + file + "\n\nfunc related() {\n\treturn\n}"
+	return store.SearchResult{Chunk: store.Chunk{FilePath: file, StartLine: start, EndLine: start + 2, Content: content}, Score: score}
+}
+
+func TestBuild(t *testing.T) {
+	ctx := context.Background()
+	symbols := trace.NewGOBSymbolStore(filepath.Join(t.TempDir(), "symbols.gob"))
+	if err := symbols.SaveFile(ctx, "auth/login.go", []trace.Symbol{
+		{Name: "Login", Kind: trace.KindFunction, File: "auth/login.go", Line: 1, EndLine: 10},
+	}, []trace.Reference{
+		{SymbolName: "hashPassword", File: "auth/login.go", Line: 4, CallerName: "Login", CallerFile: "auth/login.go", CallerLine: 1},
+	}); err != nil {
+		t.Fatalf("SaveFile failed: %v", err)
+	}
+	if err := symbols.SaveFile(ctx, "api/handler.go", []trace.Symbol{
+		{Name: "HandleAuth", Kind: trace.KindFunction, File: "api/handler.go", Line: 3},
+	}, []trace.Reference{
+		{SymbolName: "Login", File: "api/handler.go", Line: 5, CallerName: "HandleAuth", CallerFile: "api/handler.go", CallerLine: 3},
+	}); err != nil {
+		t.Fatalf("SaveFile failed: %v", err)
+	}
+
+	diffs := []git.FileDiff{
+		{Path: "auth/login.go", Patch: "@@ -4 +4 @@\n-\told := hash(p)\n+\thashed := hashPassword(p)\n"},
+		{Path: "auth/session.go", Patch: "@@ -1,2 +0,0 @@\n-package auth\n-var ttl = 60\n"},
+	}
+	searcher := &fakeSearcher{results: []store.SearchResult{
+		chunkResult("auth/login.go", 1, 0.95), // Changed file, dropped
+		chunkResult("auth/password.go", 1, 0.9),
+		chunkResult("auth/token.go", 10, 0.8),
+	}}
+	enrich := func(syms ...*trace.Symbol) {
+		for _, sym := range syms {
+			if sym.Name == "Login" {
+				sym.FeaturePath = "auth/login"
+			}
+		}
+	}
+
+	pack, err := Build(ctx, searcher, diffs, Options{PerHunk: 2, Symbols: symbols, Enrich: enrich})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if len(pack.Hunks) != 2 || pack.Truncated {
+		t.Fatalf("expected 2 hunks without truncation, got %+v", pack)
+	}
+	login := pack.Hunks[0]
+	if login.File != "auth/login.go" || login.StartLine != 4 || len(login.Symbols) != 1 || login.Symbols[0] != "Login" {
+		t.Errorf("unexpected hunk %+v", login)
+	}
+	if len(login.Callers) != 1 || login.Callers[0].Name != "HandleAuth" || len(login.Callees) != 1 || login.Callees[0].Name != "hashPassword" {
+		t.Errorf("expected HandleAuth calling and hashPassword called, got %+v %+v", login.Callers, login.Callees)
+	}
+	if len(login.Related) != 2 || login.Related[0].File != "auth/password.go" || strings.HasPrefix(login.Related[0].Snippet, "File:") {
+		t.Errorf("expected related chunks outside changed files, got %+v", login.Related)
+	}
+	// Chunks already related to an earlier hunk are not repeated
+	if len(pack.Hunks[1].Related) != 0 {
+		t.Errorf("expected no repeated related chunks, got %+v", pack.Hunks[1].Related)
+	}
+	if searcher.queries[1] != "package auth\nvar ttl = 60" {
+		t.Errorf("expected removed lines as the query of a deletion, got %q", searcher.queries[1])
+	}
+	if len(pack.Features) != 1 || pack.Features[0] != "auth/login" {
+		t.Errorf("expected the feature of Login, got %v", pack.Features)
+	}
+
+	// A small budget keeps the hunk locations and drops the rest
+	small, err := Build(ctx, searcher, diffs, Options{PerHunk: 2, Symbols: symbols, Budget: 60})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if !small.Truncated || small.Tokens > 60 || len(small.Hunks) == 0 || len(small.Hunks[0].Related) == 2 {
+		t.Errorf("expected a truncated pack within 60 tokens, got %+v", small)
+	}
+}
+
+func TestBuild_RedactsQueries(t *testing.T) {
+	key := "AKIA" + "IOSFODNN7EXAMPLE"
+	diffs := []git.FileDiff{{Path: "aws/client.go", Patch: "@@ -3 +3 @@\n-\tid := \"\"\n+\tid := \"" + key + "\"\n"}}
+	searcher := &fakeSearcher{results: []store.SearchResult{chunkResult("aws/session.go", 1, 0.9)}}
+
+	if _, err := Build(context.Background(), searcher, diffs, Options{Redactor: redact.New(redact.DefaultRules(), 0)}); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if len(searcher.queries) != 1 || strings.Contains(searcher.queries[0], key) || !strings.Contains(searcher.queries[0], "[REDACTED:") {
+		t.Errorf("expected the secret masked in the query, got %q", searcher.queries)
+	}
+}
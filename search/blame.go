@@ -0,0 +1,45 @@
+package search
+
+import (
+	"context"
+	"time"
+
+	"github.com/yoanbernabeu/grepai/git"
+	"github.com/yoanbernabeu/grepai/store"
+)
+
+// blameLines returns the line range of a chunk to blame. Lines of notebook
+// cells are relative to their cell, so the whole file is blamed instead.
+func blameLines(chunk store.Chunk) (int, int) {
+	if chunk.Cell > 0 {
+		return 0, 0
+	}
+	return chunk.StartLine, chunk.EndLine
+}
+
+// Blame returns the blame metadata of each result, nil for results whose
+// file git does not track.
+func Blame(ctx context.Context, cache *git.BlameCache, results []store.SearchResult) []*git.BlameInfo {
+	infos := make([]*git.BlameInfo, len(results))
+	for i, r := range results {
+		start, end := blameLines(r.Chunk)
+		infos[i], _ = cache.Lookup(ctx, r.Chunk.FilePath, start, end)
+	}
+	return infos
+}
+
+// BlameRecency returns a RecencyFunc reading the date of the last commit of
+// each chunk's lines from a blame cache. Uncommitted lines are new.
+func BlameRecency(ctx context.Context, cache *git.BlameCache) RecencyFunc {
+	return func(chunk store.Chunk) (time.Time, bool) {
+		start, end := blameLines(chunk)
+		info, err := cache.Lookup(ctx, chunk.FilePath, start, end)
+		if err != nil || info == nil {
+			return time.Time{}, false
+		}
+		if info.Uncommitted {
+			return time.Now(), true
+		}
+		return info.Date, true
+	}
+}
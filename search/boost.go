@@ -3,21 +3,45 @@ package search
 import (
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/yoanbernabeu/grepai/config"
 	"github.com/yoanbernabeu/grepai/store"
 )
 
 // ApplyBoost applies structural boosting to search results based on file path patterns.
+// Chunks of files detected as generated or vendored are scaled by boostCfg.Generated.
 // Penalties reduce scores (factor < 1), bonuses increase scores (factor > 1).
 // Results are re-sorted by adjusted score after boosting.
 func ApplyBoost(results []store.SearchResult, boostCfg config.BoostConfig) []store.SearchResult {
 	if !boostCfg.Enabled || len(results) == 0 {
 		return results
 	}
+	return applyBoost(results, func(string) config.BoostConfig { return boostCfg }, nil)
+}
+
+// RecencyFunc returns when the lines of a chunk last changed, or false when
+// it is unknown (e.g. the file is not tracked by git).
+type RecencyFunc func(chunk store.Chunk) (time.Time, bool)
 
+// applyBoost is ApplyBoost with per-file settings, for nested .grepai.yaml
+// overrides, and recency rules when lastChanged is set. Results of files
+// with boosting disabled keep their score.
+func applyBoost(results []store.SearchResult, boostFor func(filePath string) config.BoostConfig, lastChanged RecencyFunc) []store.SearchResult {
 	for i := range results {
+		boostCfg := boostFor(results[i].Chunk.FilePath)
+		if !boostCfg.Enabled {
+			continue
+		}
 		boost := computeBoostFactor(results[i].Chunk.FilePath, boostCfg)
+		if results[i].Chunk.Generated && boostCfg.Generated > 0 {
+			boost *= boostCfg.Generated
+		}
+		if len(boostCfg.Recency) > 0 && lastChanged != nil {
+			if changed, ok := lastChanged(results[i].Chunk); ok {
+				boost *= computeRecencyFactor(time.Since(changed), boostCfg.Recency)
+			}
+		}
 		results[i].Score *= boost
 	}
 
@@ -48,6 +72,20 @@ func computeBoostFactor(filePath string, boostCfg config.BoostConfig) float32 {
 	return factor
 }
 
+// computeRecencyFactor multiplies the factors of the recency rules matching
+// the age of a change.
+func computeRecencyFactor(age time.Duration, rules []config.RecencyRule) float32 {
+	days := int(age.Hours() / 24)
+	factor := float32(1.0)
+	for _, rule := range rules {
+		if days < rule.MinAgeDays || (rule.MaxAgeDays > 0 && days >= rule.MaxAgeDays) {
+			continue
+		}
+		factor *= rule.Factor
+	}
+	return factor
+}
+
 // matchesPattern checks if a file path contains the given pattern.
 // Patterns are simple substring matches (case-sensitive).
 func matchesPattern(filePath, pattern string) bool {
@@ -1,7 +1,10 @@
 package search
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/yoanbernabeu/grepai/config"
 	"github.com/yoanbernabeu/grepai/store"
@@ -140,3 +143,76 @@ func TestComputeBoostFactor(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyBoost_GeneratedPenalty(t *testing.T) {
+	results := []store.SearchResult{
+		{Chunk: store.Chunk{FilePath: "api/client.go", Generated: true}, Score: 0.9},
+		{Chunk: store.Chunk{FilePath: "api/handler.go"}, Score: 0.6},
+	}
+
+	boosted := ApplyBoost(results, config.BoostConfig{Enabled: true, Generated: 0.5})
+
+	if boosted[0].Chunk.FilePath != "api/handler.go" {
+		t.Errorf("expected handler.go first after generated penalty, got %s", boosted[0].Chunk.FilePath)
+	}
+	if boosted[1].Score != 0.45 {
+		t.Errorf("expected generated chunk score 0.45, got %f", boosted[1].Score)
+	}
+}
+
+func TestSearcher_DirConfigBoost(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "web"), 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	dirConfig := "search:\n  boost:\n    penalties:\n      - pattern: /stories/\n        factor: 0.1\n"
+	if err := os.WriteFile(filepath.Join(root, "web", config.DirConfigFileName), []byte(dirConfig), 0644); err != nil {
+		t.Fatalf("failed to write nested config: %v", err)
+	}
+	dirs := config.LoadDirConfigs(root, nil)
+
+	results := []store.SearchResult{
+		{Chunk: store.Chunk{FilePath: "web/stories/button.ts"}, Score: 0.9},
+		{Chunk: store.Chunk{FilePath: "api/stories/handler.go"}, Score: 0.8},
+		{Chunk: store.Chunk{FilePath: "web/button.ts"}, Score: 0.7},
+	}
+	base := config.BoostConfig{Enabled: true}
+	boosted := applyBoost(results, func(filePath string) config.BoostConfig {
+		return dirs.Boost(filePath, base)
+	}, nil)
+
+	// The penalty only applies under web/
+	if boosted[0].Chunk.FilePath != "api/stories/handler.go" || boosted[2].Chunk.FilePath != "web/stories/button.ts" {
+		t.Errorf("unexpected order: %s, %s, %s", boosted[0].Chunk.FilePath, boosted[1].Chunk.FilePath, boosted[2].Chunk.FilePath)
+	}
+}
+
+func TestApplyBoost_Recency(t *testing.T) {
+	now := time.Now()
+	changed := map[string]time.Time{
+		"old.go":   now.AddDate(-2, 0, 0),
+		"fresh.go": now.AddDate(0, 0, -3),
+	}
+	lastChanged := func(chunk store.Chunk) (time.Time, bool) {
+		t, ok := changed[chunk.FilePath]
+		return t, ok
+	}
+
+	results := []store.SearchResult{
+		{Chunk: store.Chunk{FilePath: "old.go"}, Score: 0.9},
+		{Chunk: store.Chunk{FilePath: "fresh.go"}, Score: 0.8},
+		{Chunk: store.Chunk{FilePath: "untracked.go"}, Score: 0.7},
+	}
+	boostCfg := config.BoostConfig{Enabled: true, Recency: []config.RecencyRule{
+		{MaxAgeDays: 30, Factor: 1.2},
+		{MinAgeDays: 365, Factor: 0.5},
+	}}
+	boosted := applyBoost(results, func(string) config.BoostConfig { return boostCfg }, lastChanged)
+
+	if boosted[0].Chunk.FilePath != "fresh.go" || boosted[1].Chunk.FilePath != "untracked.go" || boosted[2].Chunk.FilePath != "old.go" {
+		t.Errorf("unexpected order: %s, %s, %s", boosted[0].Chunk.FilePath, boosted[1].Chunk.FilePath, boosted[2].Chunk.FilePath)
+	}
+	if boosted[1].Score != 0.7 {
+		t.Errorf("expected the score of an unknown age to stay 0.7, got %f", boosted[1].Score)
+	}
+}
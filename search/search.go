@@ -13,20 +13,61 @@ type Searcher struct {
 	embedder  embedder.Embedder
 	boostCfg  config.BoostConfig
 	hybridCfg config.HybridConfig
+	dirs      *config.DirConfigs
+	recency   RecencyFunc
 }
 
-func NewSearcher(st store.VectorStore, emb embedder.Embedder, searchCfg config.SearchConfig) *Searcher {
-	return &Searcher{
+// SearcherOption configures a Searcher.
+type SearcherOption func(*Searcher)
+
+// WithDirConfigs applies the search.boost overrides of nested .grepai.yaml
+// files to results in their subtree.
+func WithDirConfigs(dirs *config.DirConfigs) SearcherOption {
+	return func(s *Searcher) {
+		s.dirs = dirs
+	}
+}
+
+// WithOverlay merges the results of a branch overlay with those of the
+// searcher's store, overlay documents shadowing store documents of the same
+// path. A nil overlay is ignored.
+func WithOverlay(overlay store.VectorStore) SearcherOption {
+	return func(s *Searcher) {
+		if overlay != nil {
+			s.store = store.NewOverlayStore(s.store, overlay)
+		}
+	}
+}
+
+// WithRecency enables the search.boost.recency rules, lastChanged telling
+// when the lines of a result last changed.
+func WithRecency(lastChanged RecencyFunc) SearcherOption {
+	return func(s *Searcher) {
+		s.recency = lastChanged
+	}
+}
+
+func NewSearcher(st store.VectorStore, emb embedder.Embedder, searchCfg config.SearchConfig, opts ...SearcherOption) *Searcher {
+	s := &Searcher{
 		store:     st,
 		embedder:  emb,
 		boostCfg:  searchCfg.Boost,
 		hybridCfg: searchCfg.Hybrid,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *Searcher) Search(ctx context.Context, query string, limit int, pathPrefix string) ([]store.SearchResult, error) {
+	return s.SearchWithOptions(ctx, query, limit, store.SearchOptions{PathPrefix: pathPrefix})
+}
+
+// SearchWithOptions is like Search with additional filters (path prefix, language).
+func (s *Searcher) SearchWithOptions(ctx context.Context, query string, limit int, opts store.SearchOptions) ([]store.SearchResult, error) {
 	// Embed the query
-	queryVector, err := s.embedder.Embed(ctx, query)
+	queryVector, err := embedder.EmbedQuery(ctx, s.embedder, query)
 	if err != nil {
 		return nil, err
 	}
@@ -38,10 +79,10 @@ func (s *Searcher) Search(ctx context.Context, query string, limit int, pathPref
 
 	if s.hybridCfg.Enabled {
 		// Hybrid search: combine vector + text search with RRF
-		results, err = s.hybridSearch(ctx, query, queryVector, fetchLimit, pathPrefix)
+		results, err = s.hybridSearch(ctx, query, queryVector, fetchLimit, opts)
 	} else {
 		// Vector-only search
-		results, err = s.store.Search(ctx, queryVector, fetchLimit, store.SearchOptions{PathPrefix: pathPrefix})
+		results, err = s.store.Search(ctx, queryVector, fetchLimit, opts)
 	}
 
 	if err != nil {
@@ -49,7 +90,13 @@ func (s *Searcher) Search(ctx context.Context, query string, limit int, pathPref
 	}
 
 	// Apply structural boosting
-	results = ApplyBoost(results, s.boostCfg)
+	if s.dirs.Len() > 0 {
+		results = applyBoost(results, func(filePath string) config.BoostConfig {
+			return s.dirs.Boost(filePath, s.boostCfg)
+		}, s.recency)
+	} else if s.boostCfg.Enabled {
+		results = applyBoost(results, func(string) config.BoostConfig { return s.boostCfg }, s.recency)
+	}
 
 	// Trim to requested limit
 	if len(results) > limit {
@@ -60,9 +107,9 @@ func (s *Searcher) Search(ctx context.Context, query string, limit int, pathPref
 }
 
 // hybridSearch combines vector search and text search using RRF.
-func (s *Searcher) hybridSearch(ctx context.Context, query string, queryVector []float32, limit int, pathPrefix string) ([]store.SearchResult, error) {
+func (s *Searcher) hybridSearch(ctx context.Context, query string, queryVector []float32, limit int, opts store.SearchOptions) ([]store.SearchResult, error) {
 	// Vector search
-	vectorResults, err := s.store.Search(ctx, queryVector, limit, store.SearchOptions{PathPrefix: pathPrefix})
+	vectorResults, err := s.store.Search(ctx, queryVector, limit, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -73,7 +120,17 @@ func (s *Searcher) hybridSearch(ctx context.Context, query string, queryVector [
 		return nil, err
 	}
 
-	textResults := TextSearch(ctx, allChunks, query, limit, pathPrefix)
+	if opts.Language != "" {
+		filtered := make([]store.Chunk, 0, len(allChunks))
+		for _, chunk := range allChunks {
+			if chunk.Language == opts.Language {
+				filtered = append(filtered, chunk)
+			}
+		}
+		allChunks = filtered
+	}
+
+	textResults := TextSearch(ctx, allChunks, query, limit, opts.PathPrefix)
 
 	// Combine with RRF
 	k := s.hybridCfg.K
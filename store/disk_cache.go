@@ -0,0 +1,222 @@
+package store
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	diskCacheLockName = ".lock"
+	diskCacheTmpName  = ".tmp-"
+
+	// touchInterval is how stale the access time of an entry gets before a hit
+	// refreshes it, to avoid a write per lookup.
+	touchInterval = time.Hour
+)
+
+// DiskCacheStats describes the content of a DiskEmbeddingCache.
+type DiskCacheStats struct {
+	Dir     string    `json:"dir"`
+	Entries int       `json:"entries"`
+	Bytes   int64     `json:"bytes"`
+	Oldest  time.Time `json:"oldest,omitempty"` // Least recently used entry
+	Newest  time.Time `json:"newest,omitempty"` // Most recently used entry
+}
+
+// DiskEmbeddingCache is a content-addressed embedding cache on disk, shared by
+// all the processes of a machine. Each vector is a file named after its key,
+// written atomically, whose modification time records its last use so that
+// Prune evicts the least recently used vectors first. Pruning holds an
+// exclusive file lock, so that concurrent watchers do not evict the same
+// entries twice.
+type DiskEmbeddingCache struct {
+	dir      string
+	lockPath string
+}
+
+// NewDiskEmbeddingCache returns the cache stored in dir.
+func NewDiskEmbeddingCache(dir string) *DiskEmbeddingCache {
+	return &DiskEmbeddingCache{dir: dir, lockPath: filepath.Join(dir, diskCacheLockName)}
+}
+
+// Dir returns the directory of the cache.
+func (c *DiskEmbeddingCache) Dir() string {
+	return c.dir
+}
+
+// Get returns the vector stored under key, if any.
+func (c *DiskEmbeddingCache) Get(key string) ([]float32, bool) {
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 || len(data)%4 != 0 {
+		return nil, false
+	}
+	vector := make([]float32, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > touchInterval {
+		now := time.Now()
+		_ = os.Chtimes(path, now, now)
+	}
+	return vector, true
+}
+
+// Put stores a vector under key.
+func (c *DiskEmbeddingCache) Put(key string, vector []float32) error {
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	data := make([]byte, len(vector)*4)
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(v))
+	}
+
+	// Write then rename, so that readers never see a partial vector
+	tmp, err := os.CreateTemp(filepath.Dir(path), diskCacheTmpName+"*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// Stats returns the number and size of the cached vectors.
+func (c *DiskEmbeddingCache) Stats() (*DiskCacheStats, error) {
+	stats := &DiskCacheStats{Dir: c.dir}
+	err := c.withLock(flockShared, func() error {
+		entries, err := c.entries()
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			stats.Entries++
+			stats.Bytes += e.size
+			if stats.Oldest.IsZero() || e.used.Before(stats.Oldest) {
+				stats.Oldest = e.used
+			}
+			if e.used.After(stats.Newest) {
+				stats.Newest = e.used
+			}
+		}
+		return nil
+	})
+	return stats, err
+}
+
+// Prune evicts the least recently used vectors until the cache holds at most
+// maxBytes, and returns the number and size of the evicted vectors. A
+// maxBytes of 0 empties the cache.
+func (c *DiskEmbeddingCache) Prune(maxBytes int64) (removed int, freed int64, err error) {
+	err = c.withLock(flockExclusive, func() error {
+		entries, err := c.entries()
+		if err != nil {
+			return err
+		}
+		var total int64
+		for _, e := range entries {
+			total += e.size
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].used.Before(entries[j].used) })
+		for _, e := range entries {
+			if total <= maxBytes {
+				break
+			}
+			if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove cache entry: %w", err)
+			}
+			total -= e.size
+			removed++
+			freed += e.size
+		}
+		return nil
+	})
+	return removed, freed, err
+}
+
+// path returns the file of a key, in a subdirectory named after its first two
+// characters to keep directories small.
+func (c *DiskEmbeddingCache) path(key string) string {
+	if len(key) < 3 {
+		return filepath.Join(c.dir, key)
+	}
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+type diskCacheEntry struct {
+	path string
+	size int64
+	used time.Time
+}
+
+// entries lists the vectors of the cache.
+func (c *DiskEmbeddingCache) entries() ([]diskCacheEntry, error) {
+	var entries []diskCacheEntry
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil // Entry or cache removed meanwhile
+			}
+			return err
+		}
+		if d.IsDir() || d.Name() == diskCacheLockName {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), diskCacheTmpName) {
+			// Left over by a process that died while writing
+			if time.Since(info.ModTime()) > touchInterval {
+				_ = os.Remove(path)
+			}
+			return nil
+		}
+		entries = append(entries, diskCacheEntry{path: path, size: info.Size(), used: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cache entries: %w", err)
+	}
+	return entries, nil
+}
+
+// withLock runs fn holding a file lock on the cache.
+func (c *DiskEmbeddingCache) withLock(lock func(*os.File) error, fn func() error) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	lockFile, err := os.OpenFile(c.lockPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open cache lock: %w", err)
+	}
+	defer lockFile.Close()
+	if err := lock(lockFile); err != nil {
+		return err
+	}
+	defer func() {
+		_ = funlock(lockFile)
+	}()
+	return fn()
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDiskEmbeddingCache(t *testing.T) {
+	cache := NewDiskEmbeddingCache(filepath.Join(t.TempDir(), "cache"))
+
+	if _, ok := cache.Get("aa01"); ok {
+		t.Fatal("expected a miss on an empty cache")
+	}
+	if err := cache.Put("aa01", []float32{0.5, -1, 2}); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	vector, ok := cache.Get("aa01")
+	if !ok || len(vector) != 3 || vector[0] != 0.5 || vector[1] != -1 || vector[2] != 2 {
+		t.Fatalf("expected the stored vector, got %v (%v)", vector, ok)
+	}
+
+	// Concurrent writers of the same entries
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, key := range []string{"bb01", "bb02", "cc01"} {
+				if err := cache.Put(key, []float32{1, 2}); err != nil {
+					t.Errorf("Put failed: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats, err := cache.Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.Entries != 4 || stats.Bytes != 12+3*8 {
+		t.Errorf("expected 4 entries of 36 bytes, got %+v", stats)
+	}
+}
+
+func TestDiskEmbeddingCache_Prune(t *testing.T) {
+	cache := NewDiskEmbeddingCache(t.TempDir())
+	now := time.Now()
+	for i, key := range []string{"aa01", "aa02", "bb01"} {
+		if err := cache.Put(key, []float32{1, 2}); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+		// aa02 is the least recently used, then bb01, then aa01
+		used := now.Add(-time.Duration([]int{1, 3, 2}[i]) * time.Minute)
+		if err := os.Chtimes(cache.path(key), used, used); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, freed, err := cache.Prune(10)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if removed != 2 || freed != 16 {
+		t.Errorf("expected 2 entries of 16 bytes removed, got %d (%d bytes)", removed, freed)
+	}
+	if _, ok := cache.Get("aa01"); !ok {
+		t.Error("expected the most recently used entry to be kept")
+	}
+	if _, ok := cache.Get("aa02"); ok {
+		t.Error("expected the least recently used entry to be evicted")
+	}
+
+	if removed, _, err := cache.Prune(0); err != nil || removed != 1 {
+		t.Errorf("expected Prune(0) to empty the cache, got %d (%v)", removed, err)
+	}
+}
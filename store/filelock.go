@@ -0,0 +1,24 @@
+package store
+
+import (
+	"fmt"
+	"os"
+)
+
+// WithFileLock runs fn holding an exclusive lock on the file at lockPath,
+// created if missing, so that processes sharing a file serialize their
+// read-modify-write cycles.
+func WithFileLock(lockPath string, fn func() error) error {
+	lockFile, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open lock file: %w", err)
+	}
+	defer lockFile.Close()
+	if err := flockExclusive(lockFile); err != nil {
+		return err
+	}
+	defer func() {
+		_ = funlock(lockFile)
+	}()
+	return fn()
+}
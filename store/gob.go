@@ -8,7 +8,6 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
-	"strings"
 	"sync"
 	"time"
 )
@@ -69,8 +68,8 @@ func (s *GOBStore) Search(ctx context.Context, queryVector []float32, limit int,
 	results := make([]SearchResult, 0, len(s.chunks))
 
 	for _, chunk := range s.chunks {
-		// Filter by path prefix if provided
-		if opts.PathPrefix != "" && !strings.HasPrefix(chunk.FilePath, opts.PathPrefix) {
+		// Filter by path prefix and language if provided
+		if !opts.Matches(chunk) {
 			continue
 		}
 		score := cosineSimilarity(queryVector, chunk.Vector)
@@ -318,12 +317,13 @@ func (s *GOBStore) GetAllChunks(ctx context.Context) ([]Chunk, error) {
 }
 
 // LookupByContentHash searches in-memory chunks for a matching content hash.
+// Vectors computed by a fallback provider are not reused.
 func (s *GOBStore) LookupByContentHash(ctx context.Context, contentHash string) ([]float32, bool, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	for _, chunk := range s.chunks {
-		if chunk.ContentHash == contentHash && len(chunk.Vector) > 0 {
+		if chunk.ContentHash == contentHash && len(chunk.Vector) > 0 && chunk.Embedder == "" {
 			vec := make([]float32, len(chunk.Vector))
 			copy(vec, chunk.Vector)
 			return vec, true, nil
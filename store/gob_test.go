@@ -406,6 +406,25 @@ func TestGOBStore_LookupByContentHash(t *testing.T) {
 	if found {
 		t.Fatal("Expected not found for non-existent hash")
 	}
+
+	// Vectors of a fallback provider are not reused
+	store.SaveChunks(ctx, []Chunk{
+		{
+			ID:          "chunk-2",
+			FilePath:    "util.go",
+			Content:     "func util() {}",
+			Vector:      []float32{0.4, 0.5, 0.6},
+			ContentHash: "def456",
+			Embedder:    "tei:nomic-embed-text",
+		},
+	})
+	_, found, err = store.LookupByContentHash(ctx, "def456")
+	if err != nil {
+		t.Fatalf("LookupByContentHash failed: %v", err)
+	}
+	if found {
+		t.Fatal("Expected vectors of a fallback provider not to be reused")
+	}
 }
 
 func TestGOBStore_FileLocking(t *testing.T) {
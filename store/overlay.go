@@ -0,0 +1,381 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// OverlayStore layers a branch overlay over a base index, typically the index
+// of the default branch. Reads merge both, overlay documents shadowing base
+// documents of the same path. Writes go to the overlay only, so that it holds
+// just the files that differ from the base: a file saved with the content it
+// has in the base is dropped from the overlay, and a file deleted on the
+// branch is recorded as a tombstone (a document without hash).
+//
+// Without overlay, reads and writes go to the base. The overlay can be
+// switched at any time, e.g. when another branch is checked out.
+type OverlayStore struct {
+	base VectorStore
+
+	mu      sync.RWMutex
+	overlay VectorStore
+}
+
+// NewOverlayStore layers overlay over base. overlay may be nil.
+func NewOverlayStore(base, overlay VectorStore) *OverlayStore {
+	return &OverlayStore{base: base, overlay: overlay}
+}
+
+// Base returns the base index.
+func (s *OverlayStore) Base() VectorStore {
+	return s.base
+}
+
+// Overlay returns the current overlay, or nil.
+func (s *OverlayStore) Overlay() VectorStore {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.overlay
+}
+
+// SetOverlay switches to another overlay (nil to write to the base) and
+// returns the previous one, which the caller persists and closes.
+func (s *OverlayStore) SetOverlay(overlay VectorStore) VectorStore {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous := s.overlay
+	s.overlay = overlay
+	return previous
+}
+
+// IsTombstone reports whether an overlay document records a file deleted on
+// the branch.
+func IsTombstone(doc *Document) bool {
+	return doc != nil && doc.Hash == ""
+}
+
+// shadowed returns the paths of the overlay documents, tombstones included.
+func shadowed(ctx context.Context, overlay VectorStore) (map[string]bool, error) {
+	paths, err := overlay.ListDocuments(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list overlay documents: %w", err)
+	}
+	set := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		set[path] = true
+	}
+	return set, nil
+}
+
+func (s *OverlayStore) SaveChunks(ctx context.Context, chunks []Chunk) error {
+	if overlay := s.Overlay(); overlay != nil {
+		return overlay.SaveChunks(ctx, chunks)
+	}
+	return s.base.SaveChunks(ctx, chunks)
+}
+
+func (s *OverlayStore) DeleteByFile(ctx context.Context, filePath string) error {
+	if overlay := s.Overlay(); overlay != nil {
+		// Base chunks stay, shadowed by the overlay document
+		return overlay.DeleteByFile(ctx, filePath)
+	}
+	return s.base.DeleteByFile(ctx, filePath)
+}
+
+// Search merges the results of the base and the overlay. The base search
+// excludes the files in the overlay, so that they don't take up its limit.
+func (s *OverlayStore) Search(ctx context.Context, queryVector []float32, limit int, opts SearchOptions) ([]SearchResult, error) {
+	overlay := s.Overlay()
+	if overlay == nil {
+		return s.base.Search(ctx, queryVector, limit, opts)
+	}
+	hidden, err := shadowed(ctx, overlay)
+	if err != nil {
+		return nil, err
+	}
+	baseOpts := opts
+	baseOpts.ExcludePaths = make(map[string]bool, len(opts.ExcludePaths)+len(hidden))
+	for path := range opts.ExcludePaths {
+		baseOpts.ExcludePaths[path] = true
+	}
+	for path := range hidden {
+		baseOpts.ExcludePaths[path] = true
+	}
+	baseResults, err := s.base.Search(ctx, queryVector, limit, baseOpts)
+	if err != nil {
+		return nil, err
+	}
+	overlayResults, err := overlay.Search(ctx, queryVector, limit, opts)
+	if err != nil {
+		return nil, err
+	}
+	return MergeOverlayResults(baseResults, overlayResults, hidden, limit), nil
+}
+
+// MergeOverlayResults merges base and overlay search results by score,
+// dropping base results of the shadowed files. limit <= 0 keeps all results.
+func MergeOverlayResults(base, overlay []SearchResult, shadowed map[string]bool, limit int) []SearchResult {
+	merged := make([]SearchResult, 0, len(base)+len(overlay))
+	for _, r := range base {
+		if !shadowed[r.Chunk.FilePath] {
+			merged = append(merged, r)
+		}
+	}
+	merged = append(merged, overlay...)
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Score > merged[j].Score
+	})
+	if limit > 0 && len(merged) > limit {
+		merged = merged[:limit]
+	}
+	return merged
+}
+
+func (s *OverlayStore) GetDocument(ctx context.Context, filePath string) (*Document, error) {
+	if overlay := s.Overlay(); overlay != nil {
+		doc, err := overlay.GetDocument(ctx, filePath)
+		if err != nil {
+			return nil, err
+		}
+		if IsTombstone(doc) {
+			return nil, nil
+		}
+		if doc != nil {
+			return doc, nil
+		}
+	}
+	return s.base.GetDocument(ctx, filePath)
+}
+
+// SaveDocument records a file in the overlay, unless the base already has the
+// same content, in which case the file is dropped from the overlay.
+func (s *OverlayStore) SaveDocument(ctx context.Context, doc Document) error {
+	overlay := s.Overlay()
+	if overlay == nil {
+		return s.base.SaveDocument(ctx, doc)
+	}
+	baseDoc, err := s.base.GetDocument(ctx, doc.Path)
+	if err != nil {
+		return err
+	}
+	if baseDoc != nil && baseDoc.Hash == doc.Hash {
+		// Stores may find the chunks of a file through its document, so the
+		// document is recorded before its chunks are dropped
+		if err := overlay.SaveDocument(ctx, doc); err != nil {
+			return err
+		}
+		if err := overlay.DeleteByFile(ctx, doc.Path); err != nil {
+			return err
+		}
+		return overlay.DeleteDocument(ctx, doc.Path)
+	}
+	return overlay.SaveDocument(ctx, doc)
+}
+
+// DeleteDocument removes a file from the overlay, leaving a tombstone when
+// the base has it.
+func (s *OverlayStore) DeleteDocument(ctx context.Context, filePath string) error {
+	overlay := s.Overlay()
+	if overlay == nil {
+		return s.base.DeleteDocument(ctx, filePath)
+	}
+	baseDoc, err := s.base.GetDocument(ctx, filePath)
+	if err != nil {
+		return err
+	}
+	if baseDoc != nil {
+		return overlay.SaveDocument(ctx, Document{Path: filePath})
+	}
+	return overlay.DeleteDocument(ctx, filePath)
+}
+
+func (s *OverlayStore) ListDocuments(ctx context.Context) ([]string, error) {
+	overlay := s.Overlay()
+	paths, err := s.base.ListDocuments(ctx)
+	if err != nil || overlay == nil {
+		return paths, err
+	}
+	overlayPaths, err := overlay.ListDocuments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	docs := make(map[string]bool, len(paths)+len(overlayPaths))
+	for _, path := range paths {
+		docs[path] = true
+	}
+	for _, path := range overlayPaths {
+		doc, err := overlay.GetDocument(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		docs[path] = !IsTombstone(doc)
+	}
+	merged := make([]string, 0, len(docs))
+	for path, present := range docs {
+		if present {
+			merged = append(merged, path)
+		}
+	}
+	sort.Strings(merged)
+	return merged, nil
+}
+
+func (s *OverlayStore) Load(ctx context.Context) error {
+	if err := s.base.Load(ctx); err != nil {
+		return err
+	}
+	if overlay := s.Overlay(); overlay != nil {
+		return overlay.Load(ctx)
+	}
+	return nil
+}
+
+func (s *OverlayStore) Persist(ctx context.Context) error {
+	if err := s.base.Persist(ctx); err != nil {
+		return err
+	}
+	if overlay := s.Overlay(); overlay != nil {
+		return overlay.Persist(ctx)
+	}
+	return nil
+}
+
+func (s *OverlayStore) Close() error {
+	err := s.base.Close()
+	if overlay := s.Overlay(); overlay != nil {
+		if overlayErr := overlay.Close(); err == nil {
+			err = overlayErr
+		}
+	}
+	return err
+}
+
+func (s *OverlayStore) GetStats(ctx context.Context) (*IndexStats, error) {
+	stats, err := s.base.GetStats(ctx)
+	if err != nil || s.Overlay() == nil {
+		return stats, err
+	}
+	files, err := s.ListFilesWithStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	merged := *stats
+	merged.TotalFiles, merged.TotalChunks = len(files), 0
+	for _, f := range files {
+		merged.TotalChunks += f.ChunkCount
+	}
+	if overlayStats, err := s.Overlay().GetStats(ctx); err == nil {
+		merged.IndexSize += overlayStats.IndexSize
+		if overlayStats.LastUpdated.After(merged.LastUpdated) {
+			merged.LastUpdated = overlayStats.LastUpdated
+		}
+	}
+	return &merged, nil
+}
+
+func (s *OverlayStore) ListFilesWithStats(ctx context.Context) ([]FileStats, error) {
+	overlay := s.Overlay()
+	files, err := s.base.ListFilesWithStats(ctx)
+	if err != nil || overlay == nil {
+		return files, err
+	}
+	hidden, err := shadowed(ctx, overlay)
+	if err != nil {
+		return nil, err
+	}
+	overlayFiles, err := overlay.ListFilesWithStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	merged := make([]FileStats, 0, len(files)+len(overlayFiles))
+	for _, f := range files {
+		if !hidden[f.Path] {
+			merged = append(merged, f)
+		}
+	}
+	for _, f := range overlayFiles {
+		if f.ChunkCount > 0 {
+			merged = append(merged, f)
+		}
+	}
+	return merged, nil
+}
+
+func (s *OverlayStore) GetChunksForFile(ctx context.Context, filePath string) ([]Chunk, error) {
+	if overlay := s.Overlay(); overlay != nil {
+		doc, err := overlay.GetDocument(ctx, filePath)
+		if err != nil {
+			return nil, err
+		}
+		if doc != nil {
+			return overlay.GetChunksForFile(ctx, filePath)
+		}
+	}
+	return s.base.GetChunksForFile(ctx, filePath)
+}
+
+func (s *OverlayStore) GetAllChunks(ctx context.Context) ([]Chunk, error) {
+	overlay := s.Overlay()
+	chunks, err := s.base.GetAllChunks(ctx)
+	if err != nil || overlay == nil {
+		return chunks, err
+	}
+	hidden, err := shadowed(ctx, overlay)
+	if err != nil {
+		return nil, err
+	}
+	overlayChunks, err := overlay.GetAllChunks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	merged := make([]Chunk, 0, len(chunks)+len(overlayChunks))
+	for _, c := range chunks {
+		if !hidden[c.FilePath] {
+			merged = append(merged, c)
+		}
+	}
+	return append(merged, overlayChunks...), nil
+}
+
+// ListFallbackEmbedded implements FallbackEmbeddedLister over both layers.
+func (s *OverlayStore) ListFallbackEmbedded(ctx context.Context) ([]string, error) {
+	overlay := s.Overlay()
+	paths, err := FallbackEmbeddedFiles(ctx, s.base)
+	if err != nil || overlay == nil {
+		return paths, err
+	}
+	hidden, err := shadowed(ctx, overlay)
+	if err != nil {
+		return nil, err
+	}
+	overlayPaths, err := FallbackEmbeddedFiles(ctx, overlay)
+	if err != nil {
+		return nil, err
+	}
+	merged := make([]string, 0, len(paths)+len(overlayPaths))
+	for _, path := range paths {
+		if !hidden[path] {
+			merged = append(merged, path)
+		}
+	}
+	merged = append(merged, overlayPaths...)
+	sort.Strings(merged)
+	return merged, nil
+}
+
+// LookupByContentHash implements EmbeddingCache: a chunk whose content is
+// already embedded in the overlay or the base is never embedded again, e.g.
+// when a file is changed back on a branch.
+func (s *OverlayStore) LookupByContentHash(ctx context.Context, contentHash string) ([]float32, bool, error) {
+	if cache, ok := s.Overlay().(EmbeddingCache); ok {
+		if vec, found, err := cache.LookupByContentHash(ctx, contentHash); err != nil || found {
+			return vec, found, err
+		}
+	}
+	if cache, ok := s.base.(EmbeddingCache); ok {
+		return cache.LookupByContentHash(ctx, contentHash)
+	}
+	return nil, false, nil
+}
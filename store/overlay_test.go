@@ -0,0 +1,191 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// saveFile indexes a file with a single chunk into st.
+func saveFile(t *testing.T, st VectorStore, path, hash string, vector []float32) {
+	t.Helper()
+	ctx := context.Background()
+	if err := st.DeleteByFile(ctx, path); err != nil {
+		t.Fatalf("failed to delete chunks of %s: %v", path, err)
+	}
+	chunk := Chunk{ID: path + "_0", FilePath: path, Content: hash, Vector: vector, ContentHash: hash}
+	if err := st.SaveChunks(ctx, []Chunk{chunk}); err != nil {
+		t.Fatalf("failed to save chunks of %s: %v", path, err)
+	}
+	if err := st.SaveDocument(ctx, Document{Path: path, Hash: hash, ChunkIDs: []string{chunk.ID}}); err != nil {
+		t.Fatalf("failed to save document %s: %v", path, err)
+	}
+}
+
+func TestOverlayStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	base := NewGOBStore(filepath.Join(dir, "base.gob"))
+	saveFile(t, base, "a.go", "a1", []float32{1, 0, 0})
+	saveFile(t, base, "b.go", "b1", []float32{0, 1, 0})
+	saveFile(t, base, "c.go", "c1", []float32{0, 0, 1})
+
+	overlay := NewGOBStore(filepath.Join(dir, "overlay.gob"))
+	st := NewOverlayStore(base, overlay)
+
+	saveFile(t, st, "a.go", "a2", []float32{0.9, 0.1, 0}) // Changed on the branch
+	if err := st.DeleteByFile(ctx, "b.go"); err != nil {  // Deleted on the branch
+		t.Fatalf("DeleteByFile failed: %v", err)
+	}
+	if err := st.DeleteDocument(ctx, "b.go"); err != nil {
+		t.Fatalf("DeleteDocument failed: %v", err)
+	}
+	saveFile(t, st, "d.go", "d1", []float32{0, 0.1, 0.9}) // Added on the branch
+	saveFile(t, st, "c.go", "c1", []float32{0, 0, 1})     // Same content as the base
+
+	// The base is untouched
+	if docs, _ := base.ListDocuments(ctx); len(docs) != 3 {
+		t.Errorf("expected the base to keep 3 documents, got %v", docs)
+	}
+	if doc, _ := base.GetDocument(ctx, "a.go"); doc == nil || doc.Hash != "a1" {
+		t.Errorf("expected the base version of a.go, got %+v", doc)
+	}
+
+	// The overlay only holds the differences
+	overlayDocs, _ := overlay.ListDocuments(ctx)
+	sort.Strings(overlayDocs)
+	if len(overlayDocs) != 3 || overlayDocs[0] != "a.go" || overlayDocs[1] != "b.go" || overlayDocs[2] != "d.go" {
+		t.Errorf("expected a.go, b.go (tombstone) and d.go in the overlay, got %v", overlayDocs)
+	}
+
+	// Reads merge both
+	docs, err := st.ListDocuments(ctx)
+	if err != nil {
+		t.Fatalf("ListDocuments failed: %v", err)
+	}
+	if len(docs) != 3 || docs[0] != "a.go" || docs[1] != "c.go" || docs[2] != "d.go" {
+		t.Errorf("expected a.go, c.go and d.go, got %v", docs)
+	}
+	if doc, _ := st.GetDocument(ctx, "a.go"); doc == nil || doc.Hash != "a2" {
+		t.Errorf("expected the overlay version of a.go, got %+v", doc)
+	}
+	if doc, _ := st.GetDocument(ctx, "b.go"); doc != nil {
+		t.Errorf("expected b.go to be deleted, got %+v", doc)
+	}
+	if doc, _ := st.GetDocument(ctx, "c.go"); doc == nil || doc.Hash != "c1" {
+		t.Errorf("expected the base version of c.go, got %+v", doc)
+	}
+
+	results, err := st.Search(ctx, []float32{1, 0, 0}, 10, SearchOptions{})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	var paths []string
+	for _, r := range results {
+		paths = append(paths, r.Chunk.FilePath+":"+r.Chunk.Content)
+	}
+	if len(results) != 3 || paths[0] != "a.go:a2" {
+		t.Errorf("expected a.go from the overlay first and no b.go, got %v", paths)
+	}
+	for _, p := range paths {
+		if p == "a.go:a1" || p == "b.go:b1" {
+			t.Errorf("shadowed base result %s returned", p)
+		}
+	}
+
+	chunks, err := st.GetAllChunks(ctx)
+	if err != nil || len(chunks) != 3 {
+		t.Errorf("expected 3 chunks, got %d (%v)", len(chunks), err)
+	}
+	if vec, found, _ := st.LookupByContentHash(ctx, "b1"); !found || len(vec) != 3 {
+		t.Error("expected embeddings of the base to be reused")
+	}
+
+	// Changing a file back to its base content drops it from the overlay
+	saveFile(t, st, "a.go", "a1", []float32{1, 0, 0})
+	if doc, _ := overlay.GetDocument(ctx, "a.go"); doc != nil {
+		t.Errorf("expected a.go to leave the overlay, got %+v", doc)
+	}
+
+	// Without overlay, the base is written
+	st.SetOverlay(nil)
+	if docs, _ := st.ListDocuments(ctx); len(docs) != 3 {
+		t.Errorf("expected the base documents, got %v", docs)
+	}
+	saveFile(t, st, "e.go", "e1", []float32{1, 1, 0})
+	if doc, _ := base.GetDocument(ctx, "e.go"); doc == nil {
+		t.Error("expected e.go in the base")
+	}
+}
+
+func TestOverlayStore_ListFallbackEmbedded(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	saveFallback := func(st VectorStore, path string) {
+		saveFile(t, st, path, path, []float32{1, 0, 0})
+		if err := st.SaveChunks(ctx, []Chunk{{ID: path + "_0", FilePath: path, Content: path, Vector: []float32{1, 0, 0}, Embedder: "tei:nomic-embed-text"}}); err != nil {
+			t.Fatalf("failed to save chunks of %s: %v", path, err)
+		}
+	}
+
+	base := NewGOBStore(filepath.Join(dir, "base.gob"))
+	saveFallback(base, "a.go")
+	saveFallback(base, "b.go")
+	saveFile(t, base, "c.go", "c1", []float32{0, 0, 1})
+
+	st := NewOverlayStore(base, NewGOBStore(filepath.Join(dir, "overlay.gob")))
+	saveFile(t, st, "a.go", "a2", []float32{0, 1, 0}) // Embedded by the primary on the branch
+	saveFallback(st, "d.go")
+
+	paths, err := st.ListFallbackEmbedded(ctx)
+	if err != nil {
+		t.Fatalf("ListFallbackEmbedded failed: %v", err)
+	}
+	if len(paths) != 2 || paths[0] != "b.go" || paths[1] != "d.go" {
+		t.Errorf("expected b.go and d.go, got %v", paths)
+	}
+}
+
+func TestOverlayStore_SearchShadowedFileWithManyChunks(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	base := NewGOBStore(filepath.Join(dir, "base.gob"))
+	// A large file whose base chunks all score above the other files
+	var chunks []Chunk
+	for i := 0; i < 10; i++ {
+		chunks = append(chunks, Chunk{ID: fmt.Sprintf("big.go_%d", i), FilePath: "big.go", Content: "big", Vector: []float32{1, 0, 0}})
+	}
+	if err := base.SaveChunks(ctx, chunks); err != nil {
+		t.Fatalf("failed to save chunks of big.go: %v", err)
+	}
+	saveFile(t, base, "a.go", "a1", []float32{0.8, 0.2, 0})
+	saveFile(t, base, "b.go", "b1", []float32{0.7, 0.3, 0})
+
+	st := NewOverlayStore(base, NewGOBStore(filepath.Join(dir, "overlay.gob")))
+	saveFile(t, st, "big.go", "big2", []float32{0, 0, 1}) // Rewritten on the branch
+
+	results, err := st.Search(ctx, []float32{1, 0, 0}, 2, SearchOptions{})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Chunk.FilePath != "a.go" || results[1].Chunk.FilePath != "b.go" {
+		t.Errorf("expected a.go and b.go, got %+v", results)
+	}
+}
+
+func TestMergeOverlayResults(t *testing.T) {
+	base := []SearchResult{
+		{Chunk: Chunk{FilePath: "a.go"}, Score: 0.9},
+		{Chunk: Chunk{FilePath: "b.go"}, Score: 0.8},
+		{Chunk: Chunk{FilePath: "c.go"}, Score: 0.5},
+	}
+	overlay := []SearchResult{
+		{Chunk: Chunk{FilePath: "a.go"}, Score: 0.6},
+	}
+	merged := MergeOverlayResults(base, overlay, map[string]bool{"a.go": true}, 2)
+	if len(merged) != 2 || merged[0].Chunk.FilePath != "b.go" || merged[1].Chunk.FilePath != "a.go" {
+		t.Errorf("unexpected merge %+v", merged)
+	}
+}
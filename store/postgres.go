@@ -62,6 +62,10 @@ func (s *PostgresStore) ensureSchema(ctx context.Context) error {
 		)`,
 		`ALTER TABLE chunks ADD COLUMN IF NOT EXISTS content_hash TEXT DEFAULT ''`,
 		`CREATE INDEX IF NOT EXISTS idx_chunks_content_hash ON chunks(content_hash) WHERE content_hash != ''`,
+		`ALTER TABLE chunks ADD COLUMN IF NOT EXISTS cell INTEGER DEFAULT 0`,
+		`ALTER TABLE chunks ADD COLUMN IF NOT EXISTS generated BOOLEAN DEFAULT FALSE`,
+		`ALTER TABLE chunks ADD COLUMN IF NOT EXISTS language TEXT DEFAULT ''`,
+		`ALTER TABLE chunks ADD COLUMN IF NOT EXISTS embedder TEXT DEFAULT ''`,
 		buildEnsureVectorSQL(s.dimensions),
 	}
 
@@ -80,8 +84,8 @@ func (s *PostgresStore) SaveChunks(ctx context.Context, chunks []Chunk) error {
 	for _, chunk := range chunks {
 		vec := pgvector.NewVector(chunk.Vector)
 		batch.Queue(
-			`INSERT INTO chunks (id, project_id, file_path, start_line, end_line, content, vector, hash, content_hash, updated_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			`INSERT INTO chunks (id, project_id, file_path, start_line, end_line, content, vector, hash, content_hash, updated_at, cell, generated, language, embedder)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 			ON CONFLICT (id) DO UPDATE SET
 				file_path = EXCLUDED.file_path,
 				start_line = EXCLUDED.start_line,
@@ -90,9 +94,13 @@ func (s *PostgresStore) SaveChunks(ctx context.Context, chunks []Chunk) error {
 				vector = EXCLUDED.vector,
 				hash = EXCLUDED.hash,
 				content_hash = EXCLUDED.content_hash,
-				updated_at = EXCLUDED.updated_at`,
+				updated_at = EXCLUDED.updated_at,
+				cell = EXCLUDED.cell,
+				generated = EXCLUDED.generated,
+				language = EXCLUDED.language,
+				embedder = EXCLUDED.embedder`,
 			chunk.ID, s.projectID, chunk.FilePath, chunk.StartLine, chunk.EndLine,
-			chunk.Content, vec, chunk.Hash, chunk.ContentHash, chunk.UpdatedAt,
+			chunk.Content, vec, chunk.Hash, chunk.ContentHash, chunk.UpdatedAt, chunk.Cell, chunk.Generated, chunk.Language, chunk.Embedder,
 		)
 	}
 
@@ -122,7 +130,7 @@ func (s *PostgresStore) DeleteByFile(ctx context.Context, filePath string) error
 func (s *PostgresStore) Search(ctx context.Context, queryVector []float32, limit int, opts SearchOptions) ([]SearchResult, error) {
 	vec := pgvector.NewVector(queryVector)
 
-	query := `SELECT id, file_path, start_line, end_line, content, vector, hash, updated_at,
+	query := `SELECT id, file_path, start_line, end_line, content, vector, hash, updated_at, COALESCE(cell, 0), COALESCE(generated, FALSE), COALESCE(language, ''), COALESCE(embedder, ''),
 		1 - (vector <=> $1) as score
 	FROM chunks
 	WHERE project_id = $2`
@@ -137,6 +145,20 @@ func (s *PostgresStore) Search(ctx context.Context, queryVector []float32, limit
 		nextParam++
 	}
 
+	// Add language filter if provided
+	if opts.Language != "" {
+		query += ` AND language = $` + fmt.Sprintf("%d", nextParam)
+		args = append(args, opts.Language)
+		nextParam++
+	}
+
+	// Skip excluded files in the query, so that they don't count in the limit
+	if len(opts.ExcludePaths) > 0 {
+		query += ` AND NOT (file_path = ANY($` + fmt.Sprintf("%d", nextParam) + `))`
+		args = append(args, sortedPaths(opts.ExcludePaths))
+		nextParam++
+	}
+
 	query += ` ORDER BY vector <=> $1
 	LIMIT $` + fmt.Sprintf("%d", nextParam)
 	args = append(args, limit)
@@ -155,7 +177,7 @@ func (s *PostgresStore) Search(ctx context.Context, queryVector []float32, limit
 
 		if err := rows.Scan(
 			&chunk.ID, &chunk.FilePath, &chunk.StartLine, &chunk.EndLine,
-			&chunk.Content, &vec, &chunk.Hash, &chunk.UpdatedAt, &score,
+			&chunk.Content, &vec, &chunk.Hash, &chunk.UpdatedAt, &chunk.Cell, &chunk.Generated, &chunk.Language, &chunk.Embedder, &score,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
@@ -239,6 +261,29 @@ func (s *PostgresStore) ListDocuments(ctx context.Context) ([]string, error) {
 	return paths, rows.Err()
 }
 
+// ListFallbackEmbedded implements FallbackEmbeddedLister.
+func (s *PostgresStore) ListFallbackEmbedded(ctx context.Context) ([]string, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT DISTINCT file_path FROM chunks WHERE project_id = $1 AND COALESCE(embedder, '') <> '' ORDER BY file_path`,
+		s.projectID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fallback embedded files: %w", err)
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, fmt.Errorf("failed to scan path: %w", err)
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, rows.Err()
+}
+
 func (s *PostgresStore) Load(ctx context.Context) error {
 	// No-op for Postgres, data is already persistent
 	return nil
@@ -309,7 +354,7 @@ func (s *PostgresStore) ListFilesWithStats(ctx context.Context) ([]FileStats, er
 
 func (s *PostgresStore) GetChunksForFile(ctx context.Context, filePath string) ([]Chunk, error) {
 	rows, err := s.pool.Query(ctx,
-		`SELECT id, file_path, start_line, end_line, content, hash, updated_at
+		`SELECT id, file_path, start_line, end_line, content, hash, updated_at, COALESCE(cell, 0), COALESCE(generated, FALSE), COALESCE(language, ''), COALESCE(embedder, '')
 		FROM chunks WHERE project_id = $1 AND file_path = $2
 		ORDER BY start_line`,
 		s.projectID, filePath,
@@ -322,7 +367,7 @@ func (s *PostgresStore) GetChunksForFile(ctx context.Context, filePath string) (
 	var chunks []Chunk
 	for rows.Next() {
 		var c Chunk
-		if err := rows.Scan(&c.ID, &c.FilePath, &c.StartLine, &c.EndLine, &c.Content, &c.Hash, &c.UpdatedAt); err != nil {
+		if err := rows.Scan(&c.ID, &c.FilePath, &c.StartLine, &c.EndLine, &c.Content, &c.Hash, &c.UpdatedAt, &c.Cell, &c.Generated, &c.Language, &c.Embedder); err != nil {
 			return nil, fmt.Errorf("failed to scan chunk: %w", err)
 		}
 		chunks = append(chunks, c)
@@ -333,7 +378,7 @@ func (s *PostgresStore) GetChunksForFile(ctx context.Context, filePath string) (
 
 func (s *PostgresStore) GetAllChunks(ctx context.Context) ([]Chunk, error) {
 	rows, err := s.pool.Query(ctx,
-		`SELECT id, file_path, start_line, end_line, content, hash, updated_at
+		`SELECT id, file_path, start_line, end_line, content, hash, updated_at, COALESCE(cell, 0), COALESCE(generated, FALSE), COALESCE(language, ''), COALESCE(embedder, '')
 		FROM chunks WHERE project_id = $1`,
 		s.projectID,
 	)
@@ -345,7 +390,7 @@ func (s *PostgresStore) GetAllChunks(ctx context.Context) ([]Chunk, error) {
 	var chunks []Chunk
 	for rows.Next() {
 		var c Chunk
-		if err := rows.Scan(&c.ID, &c.FilePath, &c.StartLine, &c.EndLine, &c.Content, &c.Hash, &c.UpdatedAt); err != nil {
+		if err := rows.Scan(&c.ID, &c.FilePath, &c.StartLine, &c.EndLine, &c.Content, &c.Hash, &c.UpdatedAt, &c.Cell, &c.Generated, &c.Language, &c.Embedder); err != nil {
 			return nil, fmt.Errorf("failed to scan chunk: %w", err)
 		}
 		chunks = append(chunks, c)
@@ -355,6 +400,7 @@ func (s *PostgresStore) GetAllChunks(ctx context.Context) ([]Chunk, error) {
 }
 
 // LookupByContentHash queries the chunks table for a matching content hash and returns the vector.
+// Vectors computed by a fallback provider are not reused.
 func (s *PostgresStore) LookupByContentHash(ctx context.Context, contentHash string) ([]float32, bool, error) {
 	if contentHash == "" {
 		return nil, false, nil
@@ -362,7 +408,7 @@ func (s *PostgresStore) LookupByContentHash(ctx context.Context, contentHash str
 
 	var vec pgvector.Vector
 	err := s.pool.QueryRow(ctx,
-		`SELECT vector FROM chunks WHERE content_hash = $1 AND vector IS NOT NULL LIMIT 1`,
+		`SELECT vector FROM chunks WHERE content_hash = $1 AND vector IS NOT NULL AND COALESCE(embedder, '') = '' LIMIT 1`,
 		contentHash,
 	).Scan(&vec)
 
@@ -102,6 +102,18 @@ func (s *QdrantStore) ensureCollection(ctx context.Context) error {
 		FieldName:      "content_hash",
 		FieldType:      qdrant.PtrOf(qdrant.FieldType_FieldTypeKeyword),
 	})
+	// Same for language, filtered on by searches
+	_, _ = s.client.CreateFieldIndex(ctx, &qdrant.CreateFieldIndexCollection{
+		CollectionName: s.collectionName,
+		FieldName:      "language",
+		FieldType:      qdrant.PtrOf(qdrant.FieldType_FieldTypeKeyword),
+	})
+	// And file_path, excluded by searches through a branch overlay
+	_, _ = s.client.CreateFieldIndex(ctx, &qdrant.CreateFieldIndexCollection{
+		CollectionName: s.collectionName,
+		FieldName:      "file_path",
+		FieldType:      qdrant.PtrOf(qdrant.FieldType_FieldTypeKeyword),
+	})
 
 	return nil
 }
@@ -199,6 +211,26 @@ func (s *QdrantStore) buildChunkPayload(chunk Chunk) (map[string]*qdrant.Value,
 		payload["content_hash"] = contentHashVal
 	}
 
+	if chunk.Cell > 0 {
+		cellVal, err := qdrant.NewValue(int64(chunk.Cell))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cell value: %w", err)
+		}
+		payload["cell"] = cellVal
+	}
+
+	if chunk.Generated {
+		payload["generated"] = qdrant.NewValueBool(true)
+	}
+
+	if chunk.Language != "" {
+		payload["language"] = qdrant.NewValueString(chunk.Language)
+	}
+
+	if chunk.Embedder != "" {
+		payload["embedder"] = qdrant.NewValueString(chunk.Embedder)
+	}
+
 	return payload, nil
 }
 
@@ -232,11 +264,25 @@ func (s *QdrantStore) Search(ctx context.Context, queryVector []float32, limit i
 		fetchLimit = limit * 2
 	}
 
+	// Languages and excluded files are selective: filter them in the query,
+	// not on the results
+	var filter *qdrant.Filter
+	if opts.Language != "" || len(opts.ExcludePaths) > 0 {
+		filter = &qdrant.Filter{}
+	}
+	if opts.Language != "" {
+		filter.Must = append(filter.Must, qdrant.NewMatch("language", opts.Language))
+	}
+	if len(opts.ExcludePaths) > 0 {
+		filter.MustNot = append(filter.MustNot, qdrant.NewMatchKeywords("file_path", sortedPaths(opts.ExcludePaths)...))
+	}
+
 	searchResult, err := s.client.Query(ctx, &qdrant.QueryPoints{
 		CollectionName: s.collectionName,
 		Query:          qdrant.NewQuery(queryVector...),
+		Filter:         filter,
 		Limit:          qdrant.PtrOf(uint64(fetchLimit)),
-		WithPayload:    qdrant.NewWithPayloadInclude("file_path", "start_line", "end_line", "content", "hash", "updated_at"),
+		WithPayload:    qdrant.NewWithPayloadInclude("file_path", "start_line", "end_line", "content", "hash", "updated_at", "cell", "generated", "language", "embedder"),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to search: %w", err)
@@ -247,7 +293,7 @@ func (s *QdrantStore) Search(ctx context.Context, queryVector []float32, limit i
 		chunk := s.parseChunkPayload(point.Payload)
 
 		// Filter by path prefix if provided
-		if opts.PathPrefix != "" && !strings.HasPrefix(chunk.FilePath, opts.PathPrefix) {
+		if !opts.Matches(*chunk) {
 			continue
 		}
 
@@ -291,6 +337,18 @@ func (s *QdrantStore) parseChunkPayload(payload map[string]*qdrant.Value) *Chunk
 	if val, ok := payload["content_hash"]; ok {
 		chunk.ContentHash = val.GetStringValue()
 	}
+	if val, ok := payload["cell"]; ok {
+		chunk.Cell = int(val.GetIntegerValue())
+	}
+	if val, ok := payload["generated"]; ok {
+		chunk.Generated = val.GetBoolValue()
+	}
+	if val, ok := payload["language"]; ok {
+		chunk.Language = val.GetStringValue()
+	}
+	if val, ok := payload["embedder"]; ok {
+		chunk.Embedder = val.GetStringValue()
+	}
 
 	return chunk
 }
@@ -440,7 +498,7 @@ func (s *QdrantStore) GetChunksForFile(ctx context.Context, filePath string) ([]
 		CollectionName: s.collectionName,
 		Filter:         filter,
 		Limit:          qdrant.PtrOf(uint32(10000)),
-		WithPayload:    qdrant.NewWithPayloadInclude("file_path", "start_line", "end_line", "content", "hash", "updated_at"),
+		WithPayload:    qdrant.NewWithPayloadInclude("file_path", "start_line", "end_line", "content", "hash", "updated_at", "cell", "generated", "language", "embedder"),
 		WithVectors:    qdrant.NewWithVectors(true),
 	})
 	if err != nil {
@@ -465,7 +523,7 @@ func (s *QdrantStore) GetAllChunks(ctx context.Context) ([]Chunk, error) {
 	scrollResult, err := s.client.Scroll(ctx, &qdrant.ScrollPoints{
 		CollectionName: s.collectionName,
 		Limit:          qdrant.PtrOf(uint32(100000)),
-		WithPayload:    qdrant.NewWithPayloadInclude("file_path", "start_line", "end_line", "content", "hash", "updated_at"),
+		WithPayload:    qdrant.NewWithPayloadInclude("file_path", "start_line", "end_line", "content", "hash", "updated_at", "cell", "generated", "language", "embedder"),
 		WithVectors:    qdrant.NewWithVectors(true),
 	})
 	if err != nil {
@@ -486,7 +544,32 @@ func (s *QdrantStore) GetAllChunks(ctx context.Context) ([]Chunk, error) {
 	return chunks, nil
 }
 
+// ListFallbackEmbedded implements FallbackEmbeddedLister: the embedder payload
+// is only set on points embedded by a fallback provider.
+func (s *QdrantStore) ListFallbackEmbedded(ctx context.Context) ([]string, error) {
+	scrollResult, err := s.client.Scroll(ctx, &qdrant.ScrollPoints{
+		CollectionName: s.collectionName,
+		Filter: &qdrant.Filter{
+			MustNot: []*qdrant.Condition{
+				qdrant.NewIsEmpty("embedder"),
+			},
+		},
+		Limit:       qdrant.PtrOf(uint32(100000)),
+		WithPayload: qdrant.NewWithPayloadInclude("file_path", "embedder"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fallback embedded files: %w", err)
+	}
+
+	chunks := make([]Chunk, 0, len(scrollResult))
+	for _, point := range scrollResult {
+		chunks = append(chunks, *s.parseChunkPayload(point.Payload))
+	}
+	return fallbackEmbeddedPaths(chunks), nil
+}
+
 // LookupByContentHash searches Qdrant for a point matching the content hash.
+// Vectors computed by a fallback provider are not reused.
 func (s *QdrantStore) LookupByContentHash(ctx context.Context, contentHash string) ([]float32, bool, error) {
 	if contentHash == "" {
 		return nil, false, nil
@@ -495,6 +578,7 @@ func (s *QdrantStore) LookupByContentHash(ctx context.Context, contentHash strin
 	filter := &qdrant.Filter{
 		Must: []*qdrant.Condition{
 			qdrant.NewMatch("content_hash", contentHash),
+			qdrant.NewIsEmpty("embedder"),
 		},
 	}
 
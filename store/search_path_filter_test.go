@@ -252,3 +252,34 @@ func testGetPostgresDSN(_ *testing.T) string {
 	// For now, return empty to skip PostgreSQL tests
 	return ""
 }
+
+// TestGOBStoreSearchWithLanguage tests language filtering in GOB store
+func TestGOBStoreSearchWithLanguage(t *testing.T) {
+	ctx := context.Background()
+	store := NewGOBStore(t.TempDir() + "/test.gob")
+
+	chunks := []Chunk{
+		{ID: "1", FilePath: "main.go", Language: "go", Vector: []float32{0.9, 0.1, 0.0}, UpdatedAt: time.Now()},
+		{ID: "2", FilePath: "Dockerfile", Language: "dockerfile", Vector: []float32{0.8, 0.2, 0.0}, UpdatedAt: time.Now()},
+		{ID: "3", FilePath: "scripts/deploy", Language: "shell", Vector: []float32{0.7, 0.3, 0.0}, UpdatedAt: time.Now()},
+	}
+	if err := store.SaveChunks(ctx, chunks); err != nil {
+		t.Fatalf("failed to save chunks: %v", err)
+	}
+
+	results, err := store.Search(ctx, []float32{1.0, 0.0, 0.0}, 10, SearchOptions{Language: "dockerfile"})
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Chunk.FilePath != "Dockerfile" {
+		t.Errorf("expected only Dockerfile, got %+v", results)
+	}
+
+	results, err = store.Search(ctx, []float32{1.0, 0.0, 0.0}, 10, SearchOptions{PathPrefix: "scripts/", Language: "go"})
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("expected no results for combined filters, got %d", len(results))
+	}
+}
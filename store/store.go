@@ -2,6 +2,8 @@ package store
 
 import (
 	"context"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -14,7 +16,11 @@ type Chunk struct {
 	Content     string    `json:"content"`
 	Vector      []float32 `json:"vector"`
 	Hash        string    `json:"hash"`
-	ContentHash string    `json:"content_hash"` // SHA256 of raw content (path-independent)
+	ContentHash string    `json:"content_hash"`        // SHA256 of raw content (path-independent)
+	Cell        int       `json:"cell,omitempty"`      // 1-based notebook cell number (lines are relative to the cell); 0 for regular files
+	Language    string    `json:"language,omitempty"`  // Language detected from extension, file name or shebang (e.g. "go", "dockerfile")
+	Generated   bool      `json:"generated,omitempty"` // File was detected as generated or vendored (down-ranked by search boost)
+	Embedder    string    `json:"embedder,omitempty"`  // Fallback provider that computed the vector; empty for the primary provider
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
@@ -23,6 +29,7 @@ type Document struct {
 	Path     string    `json:"path"`
 	Hash     string    `json:"hash"`
 	ModTime  time.Time `json:"mod_time"`
+	Size     int64     `json:"size,omitempty"` // 0 when the store does not record it
 	ChunkIDs []string  `json:"chunk_ids"`
 }
 
@@ -35,6 +42,32 @@ type SearchResult struct {
 // SearchOptions contains optional filters for vector search queries.
 type SearchOptions struct {
 	PathPrefix string
+	Language   string // Only return chunks recorded with this language
+	// ExcludePaths skips the chunks of these files, e.g. files shadowed by
+	// a branch overlay.
+	ExcludePaths map[string]bool
+}
+
+// Matches reports whether a chunk passes the filters. Backends that cannot
+// filter server-side apply it to their results.
+func (o SearchOptions) Matches(chunk Chunk) bool {
+	if o.PathPrefix != "" && !strings.HasPrefix(chunk.FilePath, o.PathPrefix) {
+		return false
+	}
+	if o.ExcludePaths[chunk.FilePath] {
+		return false
+	}
+	return o.Language == "" || chunk.Language == o.Language
+}
+
+// sortedPaths returns the paths of a set in order.
+func sortedPaths(set map[string]bool) []string {
+	paths := make([]string, 0, len(set))
+	for path := range set {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
 }
 
 // IndexStats contains statistics about the index
@@ -107,3 +140,40 @@ type EmbeddingCache interface {
 	// Returns (vector, true, nil) if found, (nil, false, nil) if not found.
 	LookupByContentHash(ctx context.Context, contentHash string) ([]float32, bool, error)
 }
+
+// FallbackEmbeddedLister is an optional interface for stores that can find the
+// files with vectors computed by a fallback provider without loading every
+// chunk and its vector.
+type FallbackEmbeddedLister interface {
+	// ListFallbackEmbedded returns the paths of the files with chunks whose
+	// Embedder is set.
+	ListFallbackEmbedded(ctx context.Context) ([]string, error)
+}
+
+// FallbackEmbeddedFiles returns the paths of the files of st with chunks
+// embedded by a fallback provider, scanning all chunks when st does not
+// implement FallbackEmbeddedLister.
+func FallbackEmbeddedFiles(ctx context.Context, st VectorStore) ([]string, error) {
+	if lister, ok := st.(FallbackEmbeddedLister); ok {
+		return lister.ListFallbackEmbedded(ctx)
+	}
+	chunks, err := st.GetAllChunks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fallbackEmbeddedPaths(chunks), nil
+}
+
+// fallbackEmbeddedPaths returns the sorted paths of the chunks with an Embedder.
+func fallbackEmbeddedPaths(chunks []Chunk) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, chunk := range chunks {
+		if chunk.Embedder != "" && !seen[chunk.FilePath] {
+			seen[chunk.FilePath] = true
+			paths = append(paths, chunk.FilePath)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
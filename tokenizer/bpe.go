@@ -0,0 +1,255 @@
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// BPE is a byte-level byte-pair encoder using tiktoken-format merge ranks and
+// the cl100k pre-tokenization rules.
+type BPE struct {
+	name  string
+	ranks map[string]int
+}
+
+// LoadTiktoken reads a tiktoken ranks file ("<base64 token> <rank>" per line).
+func LoadTiktoken(name string, r io.Reader) (*BPE, error) {
+	ranks := make(map[string]int, 100_000)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		token, rankStr, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("invalid tiktoken ranks at line %d", lineNum)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token at line %d: %w", lineNum, err)
+		}
+		rank, err := strconv.Atoi(rankStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rank at line %d: %w", lineNum, err)
+		}
+		ranks[string(decoded)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tiktoken ranks: %w", err)
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("tiktoken ranks file is empty")
+	}
+	return &BPE{name: name, ranks: ranks}, nil
+}
+
+// Name returns the tokenizer name.
+func (b *BPE) Name() string {
+	return b.name
+}
+
+// Count returns the number of BPE tokens in text.
+func (b *BPE) Count(text string) int {
+	total := 0
+	for _, piece := range splitCL100K(text) {
+		total += b.countPiece(piece)
+	}
+	return total
+}
+
+// countPiece merges the bytes of a pre-tokenized piece by ascending rank,
+// following tiktoken's byte_pair_merge, and returns the resulting token count.
+func (b *BPE) countPiece(piece string) int {
+	if len(piece) <= 1 {
+		return len(piece)
+	}
+	if _, ok := b.ranks[piece]; ok {
+		return 1
+	}
+
+	type part struct {
+		start int
+		rank  int
+	}
+	parts := make([]part, 0, len(piece)+1)
+	for i := 0; i < len(piece)-1; i++ {
+		parts = append(parts, part{start: i, rank: b.rank(piece[i : i+2])})
+	}
+	parts = append(parts, part{start: len(piece) - 1, rank: math.MaxInt}, part{start: len(piece), rank: math.MaxInt})
+
+	// rankAfterMerge returns the rank of the pair starting at parts[i] once
+	// parts[i+1] has been removed.
+	rankAfterMerge := func(i int) int {
+		if i+3 < len(parts) {
+			return b.rank(piece[parts[i].start:parts[i+3].start])
+		}
+		return math.MaxInt
+	}
+
+	for {
+		minIdx, minRank := -1, math.MaxInt
+		for i := 0; i < len(parts)-1; i++ {
+			if parts[i].rank < minRank {
+				minIdx, minRank = i, parts[i].rank
+			}
+		}
+		if minIdx < 0 {
+			break
+		}
+		if minIdx > 0 {
+			parts[minIdx-1].rank = rankAfterMerge(minIdx - 1)
+		}
+		parts[minIdx].rank = rankAfterMerge(minIdx)
+		parts = append(parts[:minIdx+1], parts[minIdx+2:]...)
+	}
+
+	return len(parts) - 1
+}
+
+func (b *BPE) rank(s string) int {
+	if r, ok := b.ranks[s]; ok {
+		return r
+	}
+	return math.MaxInt
+}
+
+// splitCL100K pre-tokenizes text the way the cl100k_base pattern does:
+//
+//	(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}|
+//	 ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+//
+// Go's regexp has no lookahead, so the alternatives are matched by hand.
+func splitCL100K(text string) []string {
+	var pieces []string
+	for pos := 0; pos < len(text); {
+		n := matchCL100K(text[pos:])
+		pieces = append(pieces, text[pos:pos+n])
+		pos += n
+	}
+	return pieces
+}
+
+// matchCL100K returns the byte length of the piece at the start of s.
+func matchCL100K(s string) int {
+	r, size := utf8.DecodeRuneInString(s)
+
+	// Contractions: 's 't 're 've 'm 'll 'd (case-insensitive)
+	if r == '\'' && len(s) > 1 {
+		lower := strings.ToLower(s[1:min(len(s), 3)])
+		if len(lower) >= 2 {
+			switch lower[:2] {
+			case "re", "ve", "ll":
+				return 3
+			}
+		}
+		switch lower[0] {
+		case 's', 't', 'm', 'd':
+			return 2
+		}
+	}
+
+	// [^\r\n\p{L}\p{N}]?\p{L}+
+	if unicode.IsLetter(r) {
+		return size + spanLetters(s[size:])
+	}
+	if r != '\r' && r != '\n' && !unicode.IsNumber(r) {
+		if n := spanLetters(s[size:]); n > 0 {
+			return size + n
+		}
+	}
+
+	// \p{N}{1,3}
+	if unicode.IsNumber(r) {
+		n := size
+		for count := 1; count < 3 && n < len(s); count++ {
+			next, nextSize := utf8.DecodeRuneInString(s[n:])
+			if !unicode.IsNumber(next) {
+				break
+			}
+			n += nextSize
+		}
+		return n
+	}
+
+	// ' ?[^\s\p{L}\p{N}]+[\r\n]*'
+	start := 0
+	if r == ' ' {
+		start = size
+	}
+	if n := spanPunct(s[start:]); n > 0 {
+		end := start + n
+		for end < len(s) && (s[end] == '\r' || s[end] == '\n') {
+			end++
+		}
+		return end
+	}
+
+	// Whitespace alternatives
+	wsEnd := spanSpace(s)
+	if wsEnd == 0 {
+		return size // Not reachable for valid patterns; consume one rune
+	}
+
+	// \s*[\r\n]+ : backtracks to the last newline in the whitespace run
+	if lastNL := strings.LastIndexAny(s[:wsEnd], "\r\n"); lastNL >= 0 {
+		return lastNL + 1
+	}
+
+	// \s+(?!\S) : leave the last whitespace rune for the following word
+	if wsEnd < len(s) {
+		_, lastSize := utf8.DecodeLastRuneInString(s[:wsEnd])
+		if wsEnd-lastSize > 0 {
+			return wsEnd - lastSize
+		}
+	}
+
+	// \s+
+	return wsEnd
+}
+
+func spanLetters(s string) int {
+	n := 0
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if !unicode.IsLetter(r) {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+func spanPunct(s string) int {
+	n := 0
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if unicode.IsSpace(r) || unicode.IsLetter(r) || unicode.IsNumber(r) {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+func spanSpace(s string) int {
+	n := 0
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		n += size
+	}
+	return n
+}
@@ -0,0 +1,59 @@
+package tokenizer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"embed"
+	"fmt"
+	"io"
+	"os"
+)
+
+//go:generate go run ./internal/fetchvocab -out data/cl100k_base.tiktoken.gz
+
+// vocabFS holds the vocabularies committed under data/.
+//
+//go:embed data
+var vocabFS embed.FS
+
+// embeddedCL100K holds the gzipped cl100k_base ranks produced by go generate.
+var embeddedCL100K, _ = vocabFS.ReadFile("data/cl100k_base.tiktoken.gz")
+
+// loadCL100K loads the cl100k_base ranks from vocabPath, or from the copy
+// embedded in the binary when vocabPath is empty. Files ending in .gz are
+// decompressed transparently.
+func loadCL100K(vocabPath string) (*BPE, error) {
+	var data []byte
+	if vocabPath != "" {
+		raw, err := os.ReadFile(vocabPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cl100k vocab: %w", err)
+		}
+		data = raw
+	} else {
+		if len(embeddedCL100K) == 0 {
+			return nil, fmt.Errorf("cl100k vocab is not embedded in this build (run go generate ./tokenizer/... or set chunking.tokenizer_vocab to a cl100k_base.tiktoken file)")
+		}
+		data = embeddedCL100K
+	}
+
+	var r io.Reader = bytes.NewReader(data)
+	if isGzip(data) {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress cl100k vocab: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	bpe, err := LoadTiktoken(NameCL100K, r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cl100k vocab: %w", err)
+	}
+	return bpe, nil
+}
+
+func isGzip(data []byte) bool {
+	return len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b
+}
@@ -0,0 +1,71 @@
+// Command fetchvocab downloads the cl100k_base BPE ranks, verifies their
+// checksum and writes them gzipped to tokenizer/data, which is embedded in
+// every build.
+package main
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	cl100kURL    = "https://openaipublic.blob.core.windows.net/encodings/cl100k_base.tiktoken"
+	cl100kSHA256 = "223921b76ee99bde995b7ff738513eef100fb51d18c93597a113bcffe865b2a7"
+)
+
+func main() {
+	out := flag.String("out", "data/cl100k_base.tiktoken.gz", "output path")
+	flag.Parse()
+
+	if err := fetch(*out); err != nil {
+		fmt.Fprintf(os.Stderr, "fetchvocab: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func fetch(out string) error {
+	client := &http.Client{Timeout: 2 * time.Minute}
+	resp, err := client.Get(cl100kURL)
+	if err != nil {
+		return fmt.Errorf("failed to download vocab: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download vocab: HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read vocab: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != cl100kSHA256 {
+		return fmt.Errorf("checksum mismatch: got %s, want %s", got, cl100kSHA256)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", out, err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewWriterLevel(f, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	if _, err := gz.Write(data); err != nil {
+		return fmt.Errorf("failed to write vocab: %w", err)
+	}
+	return gz.Close()
+}
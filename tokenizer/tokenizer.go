@@ -0,0 +1,142 @@
+// Package tokenizer provides token counting for chunk sizing and embedding
+// batch limits. It ships a character-based estimator (the default), a
+// cl100k-compatible byte-pair encoder and a WordPiece tokenizer.
+package tokenizer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+)
+
+// Tokenizer names accepted in configuration.
+const (
+	NameEstimate  = "estimate"
+	NameCL100K    = "cl100k"
+	NameWordPiece = "wordpiece"
+)
+
+// CharsPerToken is the ratio used by the estimator: ~4 characters per token.
+const CharsPerToken = 4
+
+// Tokenizer counts the tokens a model would see for a piece of text.
+type Tokenizer interface {
+	// Name returns the tokenizer name (estimate, cl100k or wordpiece).
+	Name() string
+
+	// Count returns the number of tokens in text.
+	Count(text string) int
+}
+
+// Estimator approximates token counts as one token per 4 bytes of text.
+// It needs no vocabulary and is the default when no tokenizer is configured.
+type Estimator struct{}
+
+// Name returns the tokenizer name.
+func (Estimator) Name() string {
+	return NameEstimate
+}
+
+// Count returns ceil(len(text) / 4).
+func (Estimator) Count(text string) int {
+	return (len(text) + CharsPerToken - 1) / CharsPerToken
+}
+
+// IsEstimate reports whether t is nil or the character-based estimator,
+// i.e. whether callers can use plain byte arithmetic instead of counting.
+func IsEstimate(t Tokenizer) bool {
+	if t == nil {
+		return true
+	}
+	_, ok := t.(Estimator)
+	return ok
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = make(map[string]Tokenizer)
+)
+
+// New returns the tokenizer with the given name. vocabPath points to a
+// tiktoken ranks file for cl100k (optional when the vocabulary is embedded in
+// the binary) or to a BERT-style vocab.txt for wordpiece (required).
+// Loaded vocabularies are cached for the lifetime of the process.
+func New(name, vocabPath string) (Tokenizer, error) {
+	switch name {
+	case "", NameEstimate:
+		return Estimator{}, nil
+	case NameCL100K, NameWordPiece:
+	default:
+		return nil, fmt.Errorf("unknown tokenizer: %s (expected estimate, cl100k or wordpiece)", name)
+	}
+
+	key := name + "\x00" + vocabPath
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if t, ok := cache[key]; ok {
+		return t, nil
+	}
+
+	var t Tokenizer
+	var err error
+	if name == NameCL100K {
+		t, err = loadCL100K(vocabPath)
+	} else {
+		t, err = loadWordPieceFile(vocabPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	cache[key] = t
+	return t, nil
+}
+
+// Check reports, without loading it, whether the tokenizer with the given
+// name can be loaded: the name is known and its vocabulary is embedded in the
+// binary or vocabPath exists.
+func Check(name, vocabPath string) error {
+	switch name {
+	case "", NameEstimate:
+		return nil
+	case NameCL100K:
+		if vocabPath == "" && len(embeddedCL100K) == 0 {
+			return fmt.Errorf("cl100k vocab is not embedded in this build (run go generate ./tokenizer/... or set chunking.tokenizer_vocab to a cl100k_base.tiktoken file)")
+		}
+	case NameWordPiece:
+		if vocabPath == "" {
+			return fmt.Errorf("wordpiece tokenizer requires chunking.tokenizer_vocab (path to vocab.txt)")
+		}
+	default:
+		return fmt.Errorf("unknown tokenizer: %s (expected estimate, cl100k or wordpiece)", name)
+	}
+	if vocabPath != "" {
+		if _, err := os.Stat(vocabPath); err != nil {
+			return fmt.Errorf("failed to read %s vocab: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Load is like New but falls back to the estimator, with a warning, when the
+// tokenizer cannot be loaded. Indexing keeps working with a missing vocabulary.
+func Load(name, vocabPath string) Tokenizer {
+	t, err := New(name, vocabPath)
+	if err != nil {
+		log.Printf("Warning: %v; falling back to the %d-chars-per-token estimate", err, CharsPerToken)
+		return Estimator{}
+	}
+	return t
+}
+
+func loadWordPieceFile(vocabPath string) (Tokenizer, error) {
+	if vocabPath == "" {
+		return nil, fmt.Errorf("wordpiece tokenizer requires chunking.tokenizer_vocab (path to vocab.txt)")
+	}
+	f, err := os.Open(vocabPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open wordpiece vocab: %w", err)
+	}
+	defer f.Close()
+	return LoadWordPiece(f)
+}
@@ -0,0 +1,151 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeTestRanks writes a tiny tiktoken ranks file: all single bytes plus a
+// handful of merges building "hello" and " world".
+func writeTestRanks(t *testing.T) string {
+	t.Helper()
+	var sb strings.Builder
+	for b := 0; b < 256; b++ {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(b)}), b)
+	}
+	merges := []string{"he", "ll", "hell", "hello", " w", "or", " wor", "ld", " world"}
+	for i, m := range merges {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(m)), 256+i)
+	}
+	path := filepath.Join(t.TempDir(), "test.tiktoken")
+	if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+		t.Fatalf("failed to write ranks: %v", err)
+	}
+	return path
+}
+
+func TestEstimator_Count(t *testing.T) {
+	tests := map[string]int{"": 0, "a": 1, "abcd": 1, "abcde": 2}
+	for text, want := range tests {
+		if got := (Estimator{}).Count(text); got != want {
+			t.Errorf("Count(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
+
+func TestSplitCL100K(t *testing.T) {
+	got := splitCL100K("Hello world's  foo\n\n  bar 123456 !!\n")
+	want := []string{"Hello", " world", "'s", " ", " foo", "\n\n", " ", " bar", " ", "123", "456", " !!\n"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitCL100K mismatch:\n got  %q\n want %q", got, want)
+	}
+}
+
+func TestSplitCL100K_CoversInput(t *testing.T) {
+	inputs := []string{
+		"func main() {\n\tfmt.Println(\"héllo, 世界\")\n}\n",
+		"   trailing spaces   ",
+		"x := a+b*c // comment\r\n",
+	}
+	for _, in := range inputs {
+		if joined := strings.Join(splitCL100K(in), ""); joined != in {
+			t.Errorf("pieces do not reassemble input: %q != %q", joined, in)
+		}
+	}
+}
+
+func TestBPE_Count(t *testing.T) {
+	tok, err := New(NameCL100K, writeTestRanks(t))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"hello world", 2},
+		{"hellhello", 2}, // merges to "hell" + "hello"
+		{"xyz", 3},       // no merges: one token per byte
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := tok.Count(tt.text); got != tt.want {
+			t.Errorf("Count(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestWordPiece_Count(t *testing.T) {
+	vocab := "[PAD]\n[UNK]\n[CLS]\n[SEP]\nthe\nquick\nbrown\nfox\n##es\njump\n##ed\n,\n.\nun\n##aff\n##able\ncafe\n"
+	wp, err := LoadWordPiece(strings.NewReader(vocab))
+	if err != nil {
+		t.Fatalf("LoadWordPiece failed: %v", err)
+	}
+	if !wp.lowercase {
+		t.Error("expected uncased vocab to enable lowercasing")
+	}
+
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"The quick brown foxes jumped, unaffable.", 12},
+		{"Café", 1},  // accents stripped
+		{"xyzzy", 1}, // unknown word is a single [UNK]
+	}
+	for _, tt := range tests {
+		if got := wp.Count(tt.text); got != tt.want {
+			t.Errorf("Count(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	if _, err := New("sentencepiece", ""); err == nil {
+		t.Error("expected error for unknown tokenizer")
+	}
+	if _, err := New(NameWordPiece, ""); err == nil {
+		t.Error("expected error for wordpiece without vocab")
+	}
+	if _, err := New(NameCL100K, filepath.Join(t.TempDir(), "missing.tiktoken")); err == nil {
+		t.Error("expected error for missing cl100k vocab")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	vocab := filepath.Join(t.TempDir(), "vocab.txt")
+	if err := os.WriteFile(vocab, []byte("[UNK]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Check("", ""); err != nil {
+		t.Errorf("expected the estimator to be available, got %v", err)
+	}
+	if err := Check(NameWordPiece, vocab); err != nil {
+		t.Errorf("expected wordpiece with a vocab to be available, got %v", err)
+	}
+	if err := Check("sentencepiece", ""); err == nil {
+		t.Error("expected error for unknown tokenizer")
+	}
+	if err := Check(NameWordPiece, ""); err == nil {
+		t.Error("expected error for wordpiece without vocab")
+	}
+	if err := Check(NameCL100K, filepath.Join(t.TempDir(), "missing.tiktoken")); err == nil {
+		t.Error("expected error for missing cl100k vocab")
+	}
+	if err := Check(NameCL100K, ""); (err == nil) != (len(embeddedCL100K) > 0) {
+		t.Errorf("expected cl100k without vocab to be available only when embedded, got %v", err)
+	}
+}
+
+func TestLoad_FallsBackToEstimator(t *testing.T) {
+	tok := Load(NameWordPiece, "")
+	if !IsEstimate(tok) {
+		t.Errorf("expected estimator fallback, got %s", tok.Name())
+	}
+}
@@ -0,0 +1,164 @@
+package tokenizer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxWordPieceChars mirrors BERT's max_input_chars_per_word: longer words
+// become a single [UNK] token.
+const maxWordPieceChars = 100
+
+// WordPiece implements BERT-style tokenization (basic tokenization followed by
+// greedy longest-match-first subword splitting) used by BERT-family embedding
+// models such as nomic-embed-text and bge.
+type WordPiece struct {
+	vocab     map[string]struct{}
+	lowercase bool
+}
+
+// LoadWordPiece reads a vocab.txt file (one token per line). Lowercasing and
+// accent stripping are enabled when the vocabulary has no uppercase tokens,
+// matching "uncased" models.
+func LoadWordPiece(r io.Reader) (*WordPiece, error) {
+	vocab := make(map[string]struct{}, 32_000)
+	hasUpper := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		token := strings.TrimRight(scanner.Text(), "\r")
+		if token == "" {
+			continue
+		}
+		vocab[token] = struct{}{}
+		if !hasUpper && !isSpecialToken(token) && strings.ToLower(token) != token {
+			hasUpper = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read wordpiece vocab: %w", err)
+	}
+	if len(vocab) == 0 {
+		return nil, fmt.Errorf("wordpiece vocab is empty")
+	}
+	return &WordPiece{vocab: vocab, lowercase: !hasUpper}, nil
+}
+
+// isSpecialToken reports whether token is a bracketed special token like [CLS].
+func isSpecialToken(token string) bool {
+	return strings.HasPrefix(token, "[") && strings.HasSuffix(token, "]")
+}
+
+// Name returns the tokenizer name.
+func (w *WordPiece) Name() string {
+	return NameWordPiece
+}
+
+// Count returns the number of WordPiece tokens in text, excluding the
+// [CLS]/[SEP] markers models add around each input.
+func (w *WordPiece) Count(text string) int {
+	total := 0
+	for _, word := range w.basicTokenize(text) {
+		total += w.countWord(word)
+	}
+	return total
+}
+
+// basicTokenize cleans text, optionally lowercases and strips accents, and
+// splits on whitespace, punctuation and CJK characters.
+func (w *WordPiece) basicTokenize(text string) []string {
+	if w.lowercase {
+		text = stripAccents(strings.ToLower(text))
+	}
+
+	var words []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			words = append(words, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case r == 0 || r == unicode.ReplacementChar || (unicode.IsControl(r) && !unicode.IsSpace(r)):
+			continue
+		case unicode.IsSpace(r):
+			flush()
+		case isBertPunct(r) || isCJK(r):
+			flush()
+			words = append(words, string(r))
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+	return words
+}
+
+// countWord splits a word greedily into the longest vocabulary pieces.
+// Words that cannot be fully covered count as a single [UNK] token.
+func (w *WordPiece) countWord(word string) int {
+	runes := []rune(word)
+	if len(runes) > maxWordPieceChars {
+		return 1
+	}
+
+	count := 0
+	for start := 0; start < len(runes); {
+		end := len(runes)
+		found := false
+		for end > start {
+			piece := string(runes[start:end])
+			if start > 0 {
+				piece = "##" + piece
+			}
+			if _, ok := w.vocab[piece]; ok {
+				found = true
+				break
+			}
+			end--
+		}
+		if !found {
+			return 1
+		}
+		count++
+		start = end
+	}
+	return count
+}
+
+func stripAccents(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// isBertPunct treats all non-alphanumeric ASCII symbols as punctuation, like BERT.
+func isBertPunct(r rune) bool {
+	if (r >= 33 && r <= 47) || (r >= 58 && r <= 64) || (r >= 91 && r <= 96) || (r >= 123 && r <= 126) {
+		return true
+	}
+	return unicode.IsPunct(r)
+}
+
+func isCJK(r rune) bool {
+	return (r >= 0x4E00 && r <= 0x9FFF) ||
+		(r >= 0x3400 && r <= 0x4DBF) ||
+		(r >= 0x20000 && r <= 0x2A6DF) ||
+		(r >= 0x2A700 && r <= 0x2B73F) ||
+		(r >= 0x2B740 && r <= 0x2B81F) ||
+		(r >= 0x2B820 && r <= 0x2CEAF) ||
+		(r >= 0xF900 && r <= 0xFAFF) ||
+		(r >= 0x2F800 && r <= 0x2FA1F)
+}
@@ -0,0 +1,40 @@
+package trace
+
+import (
+	"context"
+
+	"github.com/yoanbernabeu/grepai/git"
+)
+
+// EnrichWithBlame adds the blame metadata of their lines to the symbols of a
+// trace result. Symbols of files git does not track are left as is.
+func EnrichWithBlame(ctx context.Context, cache *git.BlameCache, result *TraceResult) {
+	BlameSymbol(ctx, cache, result.Symbol)
+	for i := range result.Callers {
+		BlameSymbol(ctx, cache, &result.Callers[i].Symbol)
+	}
+	for i := range result.Callees {
+		BlameSymbol(ctx, cache, &result.Callees[i].Symbol)
+	}
+	if result.Graph != nil {
+		for name, sym := range result.Graph.Nodes {
+			BlameSymbol(ctx, cache, &sym)
+			result.Graph.Nodes[name] = sym
+		}
+	}
+}
+
+// BlameSymbol sets the blame metadata of the lines of a symbol.
+func BlameSymbol(ctx context.Context, cache *git.BlameCache, sym *Symbol) {
+	if sym == nil || sym.File == "" || sym.Line <= 0 {
+		return
+	}
+	start, end := sym.Line, sym.EndLine
+	if end < start {
+		end = start
+	}
+	if sym.Cell > 0 {
+		start, end = 0, 0 // Lines are relative to the notebook cell
+	}
+	sym.Blame, _ = cache.Lookup(ctx, sym.File, start, end)
+}
@@ -0,0 +1,576 @@
+package trace
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KindConfigKey marks a key defined in a structured config file (YAML, JSON, TOML, HCL).
+const KindConfigKey SymbolKind = "config_key"
+
+// maxConfigKeyDepth bounds how deep nested config structures are walked.
+// Deeper keys are still covered by their ancestor's line range.
+const maxConfigKeyDepth = 8
+
+// ConfigKey is a key path found in a structured config file.
+type ConfigKey struct {
+	Path     string // Dotted key path, e.g. "services.api.env.REDIS_TIMEOUT"
+	Line     int    // 1-indexed line where the key is declared
+	EndLine  int    // 1-indexed last line covered by the key's value
+	TopLevel bool   // True for top-level keys, TOML tables and HCL blocks
+}
+
+// configLanguages maps structured config extensions to their language name.
+var configLanguages = map[string]string{
+	".yaml": "yaml",
+	".yml":  "yaml",
+	".json": "json",
+	".toml": "toml",
+	".tf":   "hcl",
+	".hcl":  "hcl",
+}
+
+// IsStructuredConfig reports whether the file is a YAML, JSON, TOML or HCL file.
+func IsStructuredConfig(filePath string) bool {
+	_, ok := configLanguages[strings.ToLower(filepath.Ext(filePath))]
+	return ok
+}
+
+// ParseConfigKeys extracts key paths with their line ranges from a structured
+// config file. Keys are returned in source order.
+func ParseConfigKeys(filePath string, content string) ([]ConfigKey, error) {
+	var keys []ConfigKey
+	var err error
+
+	switch configLanguages[strings.ToLower(filepath.Ext(filePath))] {
+	case "yaml":
+		keys, err = parseYAMLKeys(content)
+	case "json":
+		keys, err = parseJSONKeys(content)
+	case "toml":
+		keys = parseTOMLKeys(content)
+	case "hcl":
+		keys = parseHCLKeys(content)
+	default:
+		return nil, fmt.Errorf("unsupported config file: %s", filePath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	totalLines := countLines(content) + 1
+	for i := range keys {
+		if keys[i].EndLine < keys[i].Line {
+			keys[i].EndLine = keys[i].Line
+		}
+		if keys[i].EndLine > totalLines {
+			keys[i].EndLine = totalLines
+		}
+	}
+	return keys, nil
+}
+
+// joinKeyPath appends a key segment to a dotted path.
+func joinKeyPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// YAML
+
+func parseYAMLKeys(content string) ([]ConfigKey, error) {
+	var docs []*yaml.Node
+	dec := yaml.NewDecoder(strings.NewReader(content))
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse YAML: %w", err)
+		}
+		if len(doc.Content) > 0 {
+			docs = append(docs, &doc)
+		}
+	}
+
+	// The keys of a document end before the "---" marker of the next one
+	var keys []ConfigKey
+	lines := strings.Split(content, "\n")
+	for i, doc := range docs {
+		end := countLines(content) + 1
+		if i+1 < len(docs) {
+			end = yamlDocumentEnd(lines, doc.Content[0].Line, docs[i+1].Content[0].Line)
+		}
+		for _, node := range doc.Content {
+			walkYAMLNode(node, "", 0, end, &keys)
+		}
+	}
+	return keys, nil
+}
+
+// yamlDocumentEnd returns the last line of the document starting at
+// startLine, followed by a document whose content starts at nextLine: the
+// line before the "---" marker or after the "..." marker between them.
+func yamlDocumentEnd(lines []string, startLine, nextLine int) int {
+	for line := nextLine; line > startLine; line-- {
+		text := strings.TrimRight(lines[line-1], " \t\r")
+		switch {
+		case text == "---" || strings.HasPrefix(text, "--- "):
+			return line - 1
+		case text == "...":
+			return line
+		}
+	}
+	return nextLine - 1
+}
+
+func walkYAMLNode(node *yaml.Node, path string, depth int, endBound int, keys *[]ConfigKey) {
+	if depth >= maxConfigKeyDepth {
+		return
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			end := endBound
+			if i+2 < len(node.Content) {
+				end = node.Content[i+2].Line - 1
+			}
+			keyPath := joinKeyPath(path, keyNode.Value)
+			*keys = append(*keys, ConfigKey{
+				Path:     keyPath,
+				Line:     keyNode.Line,
+				EndLine:  end,
+				TopLevel: depth == 0,
+			})
+			walkYAMLNode(valueNode, keyPath, depth+1, end, keys)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			end := endBound
+			if i+1 < len(node.Content) {
+				end = node.Content[i+1].Line - 1
+			}
+			walkYAMLNode(item, fmt.Sprintf("%s[%d]", path, i), depth+1, end, keys)
+		}
+	}
+}
+
+// JSON
+
+func parseJSONKeys(content string) ([]ConfigKey, error) {
+	var keys []ConfigKey
+	p := &jsonKeyParser{dec: json.NewDecoder(strings.NewReader(content)), content: content}
+	if err := p.walkValue("", 0, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	return keys, nil
+}
+
+type jsonKeyParser struct {
+	dec     *json.Decoder
+	content string
+}
+
+func (p *jsonKeyParser) lineAt(offset int64) int {
+	if offset > int64(len(p.content)) {
+		offset = int64(len(p.content))
+	}
+	return countLines(p.content[:offset]) + 1
+}
+
+func (p *jsonKeyParser) walkValue(path string, depth int, keys *[]ConfigKey) error {
+	tok, err := p.dec.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return nil
+	}
+
+	switch delim {
+	case '{':
+		for p.dec.More() {
+			keyTok, err := p.dec.Token()
+			if err != nil {
+				return err
+			}
+			key, _ := keyTok.(string)
+			keyLine := p.lineAt(p.dec.InputOffset())
+			keyPath := joinKeyPath(path, key)
+
+			idx := -1
+			if depth < maxConfigKeyDepth {
+				idx = len(*keys)
+				*keys = append(*keys, ConfigKey{Path: keyPath, Line: keyLine, TopLevel: depth == 0})
+			}
+			if err := p.walkValue(keyPath, depth+1, keys); err != nil {
+				return err
+			}
+			if idx >= 0 {
+				(*keys)[idx].EndLine = p.lineAt(p.dec.InputOffset())
+			}
+		}
+	case '[':
+		for i := 0; p.dec.More(); i++ {
+			if err := p.walkValue(fmt.Sprintf("%s[%d]", path, i), depth+1, keys); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Consume the closing delimiter.
+	_, err = p.dec.Token()
+	return err
+}
+
+// TOML
+
+var (
+	tomlTableRe = regexp.MustCompile(`^\[\s*([^\[\]]+?)\s*\]\s*(?:#.*)?$`)
+	tomlArrayRe = regexp.MustCompile(`^\[\[\s*([^\[\]]+?)\s*\]\]\s*(?:#.*)?$`)
+	tomlKeyRe   = regexp.MustCompile(`^((?:"[^"]*"|'[^']*'|[A-Za-z0-9_-]+)(?:\s*\.\s*(?:"[^"]*"|'[^']*'|[A-Za-z0-9_-]+))*)\s*=`)
+)
+
+// normalizeTOMLKey turns a (possibly quoted, dotted) TOML key into a dotted path.
+func normalizeTOMLKey(raw string) string {
+	var parts []string
+	for _, part := range splitTOMLKey(raw) {
+		part = strings.TrimSpace(part)
+		part = strings.Trim(part, `"'`)
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ".")
+}
+
+func splitTOMLKey(raw string) []string {
+	var parts []string
+	var current strings.Builder
+	var quote byte
+	for i := 0; i < len(raw); i++ {
+		ch := raw[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+			current.WriteByte(ch)
+		case ch == '"' || ch == '\'':
+			quote = ch
+			current.WriteByte(ch)
+		case ch == '.':
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(ch)
+		}
+	}
+	return append(parts, current.String())
+}
+
+func parseTOMLKeys(content string) []ConfigKey {
+	var keys []ConfigKey
+	lines := strings.Split(content, "\n")
+	arrayCounts := make(map[string]int)
+
+	table := ""
+	tableIdx := -1
+	lastKeyIdx := -1
+	multiline := ""
+
+	closeKey := func(line int) {
+		if lastKeyIdx >= 0 {
+			keys[lastKeyIdx].EndLine = line
+			lastKeyIdx = -1
+		}
+	}
+
+	for i, rawLine := range lines {
+		lineNum := i + 1
+		line := strings.TrimSpace(rawLine)
+
+		if multiline != "" {
+			if strings.Contains(line, multiline) {
+				multiline = ""
+			}
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if m := tomlArrayRe.FindStringSubmatch(line); m != nil {
+			closeKey(lineNum - 1)
+			if tableIdx >= 0 {
+				keys[tableIdx].EndLine = lineNum - 1
+			}
+			name := normalizeTOMLKey(m[1])
+			table = fmt.Sprintf("%s[%d]", name, arrayCounts[name])
+			arrayCounts[name]++
+			tableIdx = len(keys)
+			keys = append(keys, ConfigKey{Path: table, Line: lineNum, TopLevel: true})
+			continue
+		}
+		if m := tomlTableRe.FindStringSubmatch(line); m != nil {
+			closeKey(lineNum - 1)
+			if tableIdx >= 0 {
+				keys[tableIdx].EndLine = lineNum - 1
+			}
+			table = normalizeTOMLKey(m[1])
+			tableIdx = len(keys)
+			keys = append(keys, ConfigKey{Path: table, Line: lineNum, TopLevel: true})
+			continue
+		}
+		if m := tomlKeyRe.FindStringSubmatch(line); m != nil {
+			closeKey(lineNum - 1)
+			lastKeyIdx = len(keys)
+			keys = append(keys, ConfigKey{
+				Path:     joinKeyPath(table, normalizeTOMLKey(m[1])),
+				Line:     lineNum,
+				TopLevel: table == "",
+			})
+
+			value := line[len(m[0]):]
+			for _, delim := range []string{`"""`, `'''`} {
+				if strings.Count(value, delim) == 1 {
+					multiline = delim
+				}
+			}
+		}
+	}
+
+	total := len(lines)
+	closeKey(total)
+	if tableIdx >= 0 {
+		keys[tableIdx].EndLine = total
+	}
+	return keys
+}
+
+// HCL / Terraform
+
+var (
+	hclBlockRe = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_-]*)((?:\s+(?:"[^"]*"|[A-Za-z_][A-Za-z0-9_-]*))*)\s*\{`)
+	hclLabelRe = regexp.MustCompile(`"([^"]*)"|([A-Za-z_][A-Za-z0-9_-]*)`)
+	hclAttrRe  = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_-]*)\s*=`)
+	hclHeredoc = regexp.MustCompile(`<<-?\s*([A-Za-z_][A-Za-z0-9_]*)\s*$`)
+)
+
+// countHCLNesting returns the number of opening and closing brackets in a line,
+// ignoring string literals and trailing comments.
+func countHCLNesting(line string) (opens, closes int) {
+	inString := false
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		if inString {
+			if ch == '\\' {
+				i++
+			} else if ch == '"' {
+				inString = false
+			}
+			continue
+		}
+		switch ch {
+		case '"':
+			inString = true
+		case '#':
+			return opens, closes
+		case '/':
+			if i+1 < len(line) && line[i+1] == '/' {
+				return opens, closes
+			}
+		case '{', '[', '(':
+			opens++
+		case '}', ']', ')':
+			closes++
+		}
+	}
+	return opens, closes
+}
+
+func parseHCLKeys(content string) []ConfigKey {
+	type frame struct {
+		path   string
+		keyIdx int
+	}
+
+	var keys []ConfigKey
+	var stack []frame
+	heredocEnd := ""
+	inComment := false
+
+	currentPath := func() string {
+		for i := len(stack) - 1; i >= 0; i-- {
+			if stack[i].keyIdx >= 0 {
+				return stack[i].path
+			}
+		}
+		return ""
+	}
+
+	for i, rawLine := range strings.Split(content, "\n") {
+		lineNum := i + 1
+		line := strings.TrimSpace(rawLine)
+
+		if heredocEnd != "" {
+			if line == heredocEnd {
+				heredocEnd = ""
+			}
+			continue
+		}
+		if inComment {
+			if strings.Contains(line, "*/") {
+				inComment = false
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "/*") {
+			inComment = !strings.Contains(line, "*/")
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		keyIdx := -1
+		keyPath := ""
+		if m := hclBlockRe.FindStringSubmatch(line); m != nil && !hclAttrRe.MatchString(line) {
+			segments := []string{m[1]}
+			for _, label := range hclLabelRe.FindAllStringSubmatch(m[2], -1) {
+				segments = append(segments, label[1]+label[2])
+			}
+			keyPath = joinKeyPath(currentPath(), strings.Join(segments, "."))
+		} else if m := hclAttrRe.FindStringSubmatch(line); m != nil {
+			keyPath = joinKeyPath(currentPath(), m[1])
+		}
+		if keyPath != "" && len(stack) < maxConfigKeyDepth {
+			keyIdx = len(keys)
+			keys = append(keys, ConfigKey{
+				Path:     keyPath,
+				Line:     lineNum,
+				EndLine:  lineNum,
+				TopLevel: len(stack) == 0,
+			})
+		}
+
+		if m := hclHeredoc.FindStringSubmatch(line); m != nil {
+			heredocEnd = m[1]
+		}
+
+		opens, closes := countHCLNesting(line)
+		switch {
+		case opens > closes:
+			for n := 0; n < opens-closes; n++ {
+				// Only the first bracket opened on a key line belongs to that key.
+				idx := -1
+				if n == 0 {
+					idx = keyIdx
+				}
+				stack = append(stack, frame{path: keyPath, keyIdx: idx})
+			}
+		case closes > opens:
+			for n := 0; n < closes-opens && len(stack) > 0; n++ {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				if top.keyIdx >= 0 {
+					keys[top.keyIdx].EndLine = lineNum
+				}
+			}
+		}
+	}
+
+	return keys
+}
+
+// configKeySymbols converts parsed config keys into trace symbols. Keys whose
+// leaf looks like an environment variable (UPPER_SNAKE) are also registered
+// under the bare leaf name so code reading them via getenv-style lookups can
+// be traced back to the config file.
+func configKeySymbols(filePath string, content string, keys []ConfigKey) []Symbol {
+	lang := configLanguages[strings.ToLower(filepath.Ext(filePath))]
+	lines := strings.Split(content, "\n")
+
+	symbols := make([]Symbol, 0, len(keys))
+	for _, key := range keys {
+		sig := ""
+		if key.Line-1 < len(lines) {
+			sig = strings.TrimSpace(lines[key.Line-1])
+			if len(sig) > 150 {
+				sig = sig[:150] + "..."
+			}
+		}
+		sym := Symbol{
+			Name:      key.Path,
+			Kind:      KindConfigKey,
+			File:      filePath,
+			Line:      key.Line,
+			EndLine:   key.EndLine,
+			Signature: sig,
+			Exported:  true,
+			Language:  lang,
+		}
+		symbols = append(symbols, sym)
+
+		if idx := strings.LastIndex(key.Path, "."); idx > 0 {
+			leaf := key.Path[idx+1:]
+			if envVarNameRe.MatchString(leaf) {
+				alias := sym
+				alias.Name = leaf
+				alias.Package = key.Path[:idx]
+				symbols = append(symbols, alias)
+			}
+		}
+	}
+	return symbols
+}
+
+var (
+	envVarNameRe = regexp.MustCompile(`^[A-Z][A-Z0-9]*(?:_[A-Z0-9]+)+$`)
+
+	// configKeyReadRe matches config key reads: a dotted key path
+	// ("services.api.timeout") or an environment variable name
+	// ("REDIS_TIMEOUT") passed to a getenv or config lookup, such as
+	// os.Getenv("X"), viper.GetString("a.b"), config('a.b'), ENV["X"],
+	// ENV.fetch("X") or process.env["X"]. A bare get or fetch is not a
+	// lookup: http.Get("example.com") and cache.get("a.b") are not reads.
+	configKeyReadRe = regexp.MustCompile("\\b(?i:get(?:env|property|environmentvariable|value|string\\w*|u?int\\w*|bool|float\\w*|duration|time)|lookup_?env|_?env(?:\\.fetch)?|_server|config|settings?|environ|environment|cfg|conf)\\s*[(\\[]\\s*" +
+		"[\"'`]([A-Za-z_][A-Za-z0-9_-]*(?:\\.[A-Za-z_][A-Za-z0-9_-]*)+|[A-Z][A-Z0-9]*(?:_[A-Z0-9]+)+)[\"'`]")
+)
+
+// extractConfigKeyReads finds config keys passed to getenv and config
+// lookups in source code and records them as references from the enclosing
+// function.
+func extractConfigKeyReads(filePath string, content string, lines []string, boundaries []functionBoundary) []Reference {
+	var refs []Reference
+	for _, match := range configKeyReadRe.FindAllStringSubmatchIndex(content, -1) {
+		name := content[match[2]:match[3]]
+		// Skip file names such as "config.yaml" or "main.go".
+		if ext := strings.ToLower(filepath.Ext(name)); configLanguages[ext] != "" || languagePatterns[ext] != nil {
+			continue
+		}
+
+		line := countLines(content[:match[2]]) + 1
+		caller := findContainingFunction(match[2], boundaries)
+		refs = append(refs, Reference{
+			SymbolName: name,
+			File:       filePath,
+			Line:       line,
+			Context:    getLineContext(lines, line-1, 0),
+			CallerName: caller.Name,
+			CallerFile: filePath,
+			CallerLine: caller.Line,
+		})
+	}
+	return refs
+}
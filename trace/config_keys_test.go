@@ -0,0 +1,271 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func findConfigKey(keys []ConfigKey, path string) *ConfigKey {
+	for i := range keys {
+		if keys[i].Path == path {
+			return &keys[i]
+		}
+	}
+	return nil
+}
+
+func TestParseConfigKeys_YAML(t *testing.T) {
+	content := `version: "3"
+services:
+  api:
+    image: api:latest
+    env:
+      REDIS_TIMEOUT: 5s
+      REDIS_HOST: redis
+  worker:
+    image: worker:latest
+`
+	keys, err := ParseConfigKeys("compose.yaml", content)
+	if err != nil {
+		t.Fatalf("ParseConfigKeys failed: %v", err)
+	}
+
+	key := findConfigKey(keys, "services.api.env.REDIS_TIMEOUT")
+	if key == nil {
+		t.Fatal("expected services.api.env.REDIS_TIMEOUT key")
+	}
+	if key.Line != 6 {
+		t.Errorf("expected REDIS_TIMEOUT on line 6, got %d", key.Line)
+	}
+
+	services := findConfigKey(keys, "services")
+	if services == nil || !services.TopLevel {
+		t.Fatal("expected top-level services key")
+	}
+	if services.Line != 2 || services.EndLine < 9 {
+		t.Errorf("expected services to span lines 2-9, got %d-%d", services.Line, services.EndLine)
+	}
+
+	api := findConfigKey(keys, "services.api")
+	if api == nil || api.TopLevel {
+		t.Fatal("expected nested services.api key")
+	}
+	if api.EndLine != 7 {
+		t.Errorf("expected services.api to end on line 7, got %d", api.EndLine)
+	}
+}
+
+func TestParseConfigKeys_YAMLDocuments(t *testing.T) {
+	content := `apiVersion: v1
+kind: Service
+---
+apiVersion: apps/v1
+kind: Deployment
+spec:
+  replicas: 2
+`
+	keys, err := ParseConfigKeys("deploy.yaml", content)
+	if err != nil {
+		t.Fatalf("ParseConfigKeys failed: %v", err)
+	}
+
+	var spans []string
+	for _, key := range keys {
+		if key.TopLevel {
+			spans = append(spans, fmt.Sprintf("%s:%d-%d", key.Path, key.Line, key.EndLine))
+		}
+	}
+	want := []string{"apiVersion:1-1", "kind:2-2", "apiVersion:4-4", "kind:5-5", "spec:6-8"}
+	if strings.Join(spans, " ") != strings.Join(want, " ") {
+		t.Errorf("expected each document to end before the next one, got %v, want %v", spans, want)
+	}
+}
+
+func TestParseConfigKeys_JSON(t *testing.T) {
+	content := `{
+  "name": "app",
+  "redis": {
+    "timeout": 5,
+    "hosts": ["a", "b"]
+  },
+  "items": [{"id": 1}]
+}`
+	keys, err := ParseConfigKeys("settings.json", content)
+	if err != nil {
+		t.Fatalf("ParseConfigKeys failed: %v", err)
+	}
+
+	redis := findConfigKey(keys, "redis")
+	if redis == nil || !redis.TopLevel {
+		t.Fatal("expected top-level redis key")
+	}
+	if redis.Line != 3 || redis.EndLine != 6 {
+		t.Errorf("expected redis to span lines 3-6, got %d-%d", redis.Line, redis.EndLine)
+	}
+	if key := findConfigKey(keys, "redis.timeout"); key == nil || key.Line != 4 {
+		t.Errorf("expected redis.timeout on line 4, got %+v", key)
+	}
+	if findConfigKey(keys, "items[0].id") == nil {
+		t.Error("expected items[0].id key for array element")
+	}
+}
+
+func TestParseConfigKeys_InvalidJSON(t *testing.T) {
+	if _, err := ParseConfigKeys("broken.json", `{"a": `); err == nil {
+		t.Error("expected error for truncated JSON")
+	}
+}
+
+func TestParseConfigKeys_TOML(t *testing.T) {
+	content := `title = "example"
+
+[database]
+server = "192.168.1.1"
+"connection.max" = 5000
+
+[[products]]
+name = "Hammer"
+
+[[products]]
+name = "Nail"
+`
+	keys, err := ParseConfigKeys("config.toml", content)
+	if err != nil {
+		t.Fatalf("ParseConfigKeys failed: %v", err)
+	}
+
+	if key := findConfigKey(keys, "title"); key == nil || !key.TopLevel {
+		t.Error("expected top-level title key")
+	}
+	db := findConfigKey(keys, "database")
+	if db == nil || !db.TopLevel {
+		t.Fatal("expected database table")
+	}
+	if db.Line != 3 || db.EndLine != 6 {
+		t.Errorf("expected database to span lines 3-6, got %d-%d", db.Line, db.EndLine)
+	}
+	if findConfigKey(keys, "database.connection.max") == nil {
+		t.Error("expected quoted key database.connection.max")
+	}
+	if key := findConfigKey(keys, "products[1].name"); key == nil || key.Line != 11 {
+		t.Errorf("expected products[1].name on line 11, got %+v", key)
+	}
+}
+
+func TestParseConfigKeys_HCL(t *testing.T) {
+	content := `variable "region" {
+  default = "us-east-1"
+}
+
+resource "aws_elasticache_cluster" "redis" {
+  engine = "redis"
+  tags = {
+    Team = "core"
+  }
+  parameter_group_name = "default.redis7"
+}
+`
+	keys, err := ParseConfigKeys("main.tf", content)
+	if err != nil {
+		t.Fatalf("ParseConfigKeys failed: %v", err)
+	}
+
+	block := findConfigKey(keys, "resource.aws_elasticache_cluster.redis")
+	if block == nil || !block.TopLevel {
+		t.Fatal("expected top-level resource block")
+	}
+	if block.Line != 5 || block.EndLine != 11 {
+		t.Errorf("expected resource block to span lines 5-11, got %d-%d", block.Line, block.EndLine)
+	}
+	if key := findConfigKey(keys, "resource.aws_elasticache_cluster.redis.tags.Team"); key == nil || key.Line != 8 {
+		t.Errorf("expected nested tags.Team on line 8, got %+v", key)
+	}
+	if key := findConfigKey(keys, "variable.region.default"); key == nil || key.TopLevel {
+		t.Errorf("expected nested variable.region.default, got %+v", key)
+	}
+}
+
+func TestRegexExtractor_ConfigKeySymbols(t *testing.T) {
+	extractor := NewRegexExtractor()
+	content := "services:\n  api:\n    env:\n      REDIS_TIMEOUT: 5s\n"
+
+	symbols, err := extractor.ExtractSymbols(context.Background(), "compose.yml", content)
+	if err != nil {
+		t.Fatalf("ExtractSymbols failed: %v", err)
+	}
+
+	byName := make(map[string]Symbol)
+	for _, sym := range symbols {
+		byName[sym.Name] = sym
+	}
+
+	full, ok := byName["services.api.env.REDIS_TIMEOUT"]
+	if !ok {
+		t.Fatal("expected full key path symbol")
+	}
+	if full.Kind != KindConfigKey || full.Language != "yaml" || full.Line != 4 {
+		t.Errorf("unexpected symbol: %+v", full)
+	}
+
+	alias, ok := byName["REDIS_TIMEOUT"]
+	if !ok {
+		t.Fatal("expected env-style leaf alias symbol")
+	}
+	if alias.Package != "services.api.env" {
+		t.Errorf("expected alias package services.api.env, got %q", alias.Package)
+	}
+}
+
+func TestRegexExtractor_ConfigKeyReads(t *testing.T) {
+	extractor := NewRegexExtractor()
+	content := `package main
+
+func loadTimeout() string {
+	_ = viper.GetString("services.api.timeout")
+	_ = readFile("config.yaml")
+	_ = config.Get("config.yaml")
+	_ = os.Environ["DB_HOST"]
+	log.Println("LOG_LEVEL")
+	_ = errors.New("services.api.missing")
+	_ = metrics.Counter("HTTP_REQUESTS")
+	_, _ = http.Get("example.com")
+	_ = cache.get("a.b")
+	_ = fetch("api.v1")
+	_ = ENV.fetch("CACHE_TTL")
+	return os.Getenv("REDIS_TIMEOUT")
+}
+`
+	refs, err := extractor.ExtractReferences(context.Background(), "main.go", content)
+	if err != nil {
+		t.Fatalf("ExtractReferences failed: %v", err)
+	}
+
+	found := make(map[string]Reference)
+	for _, ref := range refs {
+		found[ref.SymbolName] = ref
+	}
+
+	for _, name := range []string{"services.api.timeout", "REDIS_TIMEOUT", "CACHE_TTL"} {
+		ref, ok := found[name]
+		if !ok {
+			t.Errorf("expected config key read for %s", name)
+			continue
+		}
+		if ref.CallerName != "loadTimeout" {
+			t.Errorf("expected caller loadTimeout for %s, got %q", name, ref.CallerName)
+		}
+	}
+	if _, ok := found["config.yaml"]; ok {
+		t.Error("file names should not be recorded as config key reads")
+	}
+	if _, ok := found["DB_HOST"]; !ok {
+		t.Error("expected config key read for DB_HOST")
+	}
+	for _, name := range []string{"LOG_LEVEL", "services.api.missing", "HTTP_REQUESTS", "example.com", "a.b", "api.v1"} {
+		if _, ok := found[name]; ok {
+			t.Errorf("literals outside getenv or config lookups should not be recorded, got %s", name)
+		}
+	}
+}
@@ -27,15 +27,32 @@ func (e *RegexExtractor) Mode() string {
 
 // SupportedLanguages returns list of supported file extensions.
 func (e *RegexExtractor) SupportedLanguages() []string {
-	langs := make([]string, 0, len(e.patterns))
+	langs := make([]string, 0, len(e.patterns)+len(configLanguages)+1)
 	for ext := range e.patterns {
 		langs = append(langs, ext)
 	}
-	return langs
+	for ext := range configLanguages {
+		langs = append(langs, ext)
+	}
+	return append(langs, ".ipynb")
 }
 
 // ExtractSymbols extracts all symbol definitions from a file.
 func (e *RegexExtractor) ExtractSymbols(ctx context.Context, filePath string, content string) ([]Symbol, error) {
+	// Structured config files expose their key paths as symbols
+	if IsStructuredConfig(filePath) {
+		keys, err := ParseConfigKeys(filePath, content)
+		if err != nil {
+			return nil, nil // Malformed config files simply yield no symbols
+		}
+		return configKeySymbols(filePath, content, keys), nil
+	}
+
+	// Notebook code cells are extracted as Python
+	if IsNotebook(filePath) {
+		return extractNotebookSymbols(ctx, filePath, content, e.ExtractSymbols)
+	}
+
 	ext := strings.ToLower(filepath.Ext(filePath))
 	patterns := e.patterns[ext]
 	if patterns == nil {
@@ -141,6 +158,10 @@ func (e *RegexExtractor) extractMethodMatches(re *regexp.Regexp, content string,
 
 // ExtractReferences extracts all symbol references from a file.
 func (e *RegexExtractor) ExtractReferences(ctx context.Context, filePath string, content string) ([]Reference, error) {
+	if IsNotebook(filePath) {
+		return extractNotebookReferences(ctx, filePath, content, e.ExtractReferences)
+	}
+
 	ext := strings.ToLower(filepath.Ext(filePath))
 	patterns := e.patterns[ext]
 	if patterns == nil {
@@ -212,6 +233,9 @@ func (e *RegexExtractor) ExtractReferences(ctx context.Context, filePath string,
 		}
 	}
 
+	// Extract config key reads (string literals naming config keys)
+	refs = append(refs, extractConfigKeyReads(filePath, content, lines, functionBoundaries)...)
+
 	return refs, nil
 }
 
@@ -10,26 +10,33 @@ func TestRegexExtractor_SupportedLanguages(t *testing.T) {
 	langs := extractor.SupportedLanguages()
 
 	expected := map[string]bool{
-		".go":   true,
-		".js":   true,
-		".ts":   true,
-		".jsx":  true,
-		".tsx":  true,
-		".py":   true,
-		".php":  true,
-		".c":    true,
-		".h":    true,
-		".zig":  true,
-		".rs":   true,
-		".cpp":  true,
-		".hpp":  true,
-		".cc":   true,
-		".cxx":  true,
-		".hxx":  true,
-		".java": true,
-		".cs":   true,
-		".pas":  true,
-		".dpr":  true,
+		".go":    true,
+		".js":    true,
+		".ts":    true,
+		".jsx":   true,
+		".tsx":   true,
+		".py":    true,
+		".php":   true,
+		".c":     true,
+		".h":     true,
+		".zig":   true,
+		".rs":    true,
+		".cpp":   true,
+		".hpp":   true,
+		".cc":    true,
+		".cxx":   true,
+		".hxx":   true,
+		".java":  true,
+		".cs":    true,
+		".pas":   true,
+		".dpr":   true,
+		".yaml":  true,
+		".yml":   true,
+		".json":  true,
+		".toml":  true,
+		".tf":    true,
+		".hcl":   true,
+		".ipynb": true,
 	}
 
 	for _, lang := range langs {
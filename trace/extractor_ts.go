@@ -55,15 +55,19 @@ func (e *TreeSitterExtractor) Mode() string {
 
 // SupportedLanguages returns list of supported file extensions.
 func (e *TreeSitterExtractor) SupportedLanguages() []string {
-	langs := make([]string, 0, len(e.parsers))
+	langs := make([]string, 0, len(e.parsers)+1)
 	for ext := range e.parsers {
 		langs = append(langs, ext)
 	}
-	return langs
+	return append(langs, ".ipynb")
 }
 
 // ExtractSymbols extracts all symbol definitions from a file using tree-sitter.
 func (e *TreeSitterExtractor) ExtractSymbols(ctx context.Context, filePath string, content string) ([]Symbol, error) {
+	if IsNotebook(filePath) {
+		return extractNotebookSymbols(ctx, filePath, content, e.ExtractSymbols)
+	}
+
 	ext := strings.ToLower(filepath.Ext(filePath))
 	parser, ok := e.parsers[ext]
 	if !ok {
@@ -401,6 +405,10 @@ func (e *TreeSitterExtractor) extractCSharpSymbol(node *sitter.Node, nodeType st
 
 // ExtractReferences extracts all symbol references from a file.
 func (e *TreeSitterExtractor) ExtractReferences(ctx context.Context, filePath string, content string) ([]Reference, error) {
+	if IsNotebook(filePath) {
+		return extractNotebookReferences(ctx, filePath, content, e.ExtractReferences)
+	}
+
 	ext := strings.ToLower(filepath.Ext(filePath))
 	parser, ok := e.parsers[ext]
 	if !ok {
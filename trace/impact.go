@@ -0,0 +1,266 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/yoanbernabeu/grepai/git"
+)
+
+// DefaultImpactDepth is the default number of caller levels walked from the
+// changed symbols.
+const DefaultImpactDepth = 3
+
+// Change is the set of changed lines of a file.
+type Change struct {
+	File  string
+	Lines []git.LineRange
+}
+
+// ChangesFromDiff returns the changed lines of each file of a diff.
+func ChangesFromDiff(diffs []git.FileDiff) []Change {
+	changes := make([]Change, len(diffs))
+	for i, d := range diffs {
+		changes[i] = Change{File: d.Path, Lines: git.ChangedLines(d.Patch)}
+	}
+	return changes
+}
+
+// ImpactSymbol is a symbol a change can break.
+type ImpactSymbol struct {
+	Symbol
+	Depth int    `json:"depth"`         // 0 for changed symbols, else the number of calls to a changed symbol
+	Via   string `json:"via,omitempty"` // Symbol it calls on the way to a changed symbol
+}
+
+// Impact lists what a change can break: the symbols whose lines changed, the
+// functions that call them transitively and the tests among those.
+type Impact struct {
+	Range    string         `json:"range,omitempty"`
+	Changed  []ImpactSymbol `json:"changed"`
+	Affected []ImpactSymbol `json:"affected"`
+	Tests    []ImpactSymbol `json:"tests"`
+	Files    []string       `json:"files"`
+	Features []string       `json:"features,omitempty"` // RPG feature areas (populated when RPG enabled)
+}
+
+// AnalyzeImpact maps changed lines to the symbols of the index and walks
+// their callers up to depth levels.
+func AnalyzeImpact(ctx context.Context, st SymbolStore, changes []Change, depth int) (*Impact, error) {
+	impact := &Impact{Changed: []ImpactSymbol{}, Affected: []ImpactSymbol{}, Tests: []ImpactSymbol{}}
+	visited := make(map[string]bool)
+	files := make(map[string]bool)
+
+	var frontier []string
+	for _, change := range changes {
+		files[change.File] = true
+		symbols, err := ChangedSymbols(ctx, st, change)
+		if err != nil {
+			return nil, err
+		}
+		for _, sym := range symbols {
+			if visited[sym.Name] {
+				continue
+			}
+			visited[sym.Name] = true
+			frontier = append(frontier, sym.Name)
+			impact.add(ImpactSymbol{Symbol: sym})
+		}
+	}
+
+	for level := 1; level <= depth && len(frontier) > 0; level++ {
+		var next []string
+		for _, name := range frontier {
+			refs, err := st.LookupCallers(ctx, name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to look up callers of %s: %w", name, err)
+			}
+			for _, ref := range refs {
+				if ref.CallerName == "" || visited[ref.CallerName] {
+					continue
+				}
+				visited[ref.CallerName] = true
+				next = append(next, ref.CallerName)
+				caller, err := callerSymbol(ctx, st, ref)
+				if err != nil {
+					return nil, err
+				}
+				impact.add(ImpactSymbol{Symbol: caller, Depth: level, Via: name})
+			}
+		}
+		frontier = next
+	}
+
+	for _, list := range [][]ImpactSymbol{impact.Changed, impact.Affected, impact.Tests} {
+		for _, sym := range list {
+			files[sym.File] = true
+		}
+		sortImpactSymbols(list)
+	}
+	impact.Files = make([]string, 0, len(files))
+	for file := range files {
+		impact.Files = append(impact.Files, file)
+	}
+	sort.Strings(impact.Files)
+	return impact, nil
+}
+
+// add files a symbol as changed, test or affected.
+func (i *Impact) add(sym ImpactSymbol) {
+	switch {
+	case IsTestFile(sym.File):
+		i.Tests = append(i.Tests, sym)
+	case sym.Depth == 0:
+		i.Changed = append(i.Changed, sym)
+	default:
+		i.Affected = append(i.Affected, sym)
+	}
+}
+
+// Symbols returns pointers to all symbols of the impact, for enrichment.
+func (i *Impact) Symbols() []*Symbol {
+	var symbols []*Symbol
+	for _, list := range [][]ImpactSymbol{i.Changed, i.Affected, i.Tests} {
+		for j := range list {
+			symbols = append(symbols, &list[j].Symbol)
+		}
+	}
+	return symbols
+}
+
+// CollectFeatures sets Features to the RPG feature paths of the symbols.
+func (i *Impact) CollectFeatures() {
+	seen := make(map[string]bool)
+	i.Features = nil
+	for _, sym := range i.Symbols() {
+		if sym.FeaturePath != "" && !seen[sym.FeaturePath] {
+			seen[sym.FeaturePath] = true
+			i.Features = append(i.Features, sym.FeaturePath)
+		}
+	}
+	sort.Strings(i.Features)
+}
+
+// ChangedSymbols returns the symbols of the index whose lines a change
+// touches.
+func ChangedSymbols(ctx context.Context, st SymbolStore, change Change) ([]Symbol, error) {
+	symbols, err := st.GetSymbolsForFile(ctx, change.File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get symbols of %s: %w", change.File, err)
+	}
+	return changedSymbols(symbols, change.Lines), nil
+}
+
+// changedSymbols returns the innermost symbols overlapping the changed lines.
+// Symbols without an end line extend to the next symbol of the file.
+func changedSymbols(symbols []Symbol, lines []git.LineRange) []Symbol {
+	var candidates []Symbol
+	for _, sym := range symbols {
+		if sym.Cell == 0 { // Notebook cell lines are not file lines
+			candidates = append(candidates, sym)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Line < candidates[j].Line })
+
+	spans := make([]git.LineRange, len(candidates))
+	for i, sym := range candidates {
+		end := sym.EndLine
+		if end < sym.Line {
+			end = math.MaxInt
+			for _, next := range candidates[i+1:] {
+				if next.Line > sym.Line {
+					end = next.Line - 1
+					break
+				}
+			}
+		}
+		spans[i] = git.LineRange{Start: sym.Line, End: end}
+	}
+
+	var changed []Symbol
+	seen := make(map[int]bool)
+	for _, r := range lines {
+		var overlapping []int
+		for i, span := range spans {
+			if span.Start <= r.End && r.Start <= span.End {
+				overlapping = append(overlapping, i)
+			}
+		}
+		for _, i := range overlapping {
+			if seen[i] || containsOther(spans, i, overlapping) {
+				continue
+			}
+			seen[i] = true
+			changed = append(changed, candidates[i])
+		}
+	}
+	return changed
+}
+
+// containsOther reports whether span i strictly contains another of the
+// overlapping spans, i.e. is not the innermost symbol.
+func containsOther(spans []git.LineRange, i int, overlapping []int) bool {
+	for _, j := range overlapping {
+		if j == i {
+			continue
+		}
+		inner, outer := spans[j], spans[i]
+		if outer.Start <= inner.Start && inner.End <= outer.End && inner != outer {
+			return true
+		}
+	}
+	return false
+}
+
+// callerSymbol returns the definition of the caller of a reference, falling
+// back to the location recorded with the reference.
+func callerSymbol(ctx context.Context, st SymbolStore, ref Reference) (Symbol, error) {
+	symbols, err := st.LookupSymbol(ctx, ref.CallerName)
+	if err != nil {
+		return Symbol{}, fmt.Errorf("failed to look up %s: %w", ref.CallerName, err)
+	}
+	for _, sym := range symbols {
+		if sym.File == ref.CallerFile {
+			return sym, nil
+		}
+	}
+	if len(symbols) > 0 {
+		return symbols[0], nil
+	}
+	return Symbol{Name: ref.CallerName, File: ref.CallerFile, Line: ref.CallerLine}, nil
+}
+
+func sortImpactSymbols(symbols []ImpactSymbol) {
+	sort.SliceStable(symbols, func(i, j int) bool {
+		a, b := symbols[i], symbols[j]
+		if a.Depth != b.Depth {
+			return a.Depth < b.Depth
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+}
+
+// IsTestFile reports whether a file holds tests, from common naming
+// conventions (foo_test.go, test_foo.py, foo.spec.ts, tests/ directories...).
+func IsTestFile(file string) bool {
+	base := path.Base(file)
+	if strings.HasPrefix(base, "test_") || strings.Contains(base, "_test.") ||
+		strings.Contains(base, ".test.") || strings.Contains(base, ".spec.") ||
+		strings.HasSuffix(strings.TrimSuffix(base, path.Ext(base)), "Test") ||
+		strings.HasSuffix(strings.TrimSuffix(base, path.Ext(base)), "Tests") {
+		return true
+	}
+	for _, dir := range strings.Split(path.Dir(file), "/") {
+		if dir == "test" || dir == "tests" || dir == "__tests__" {
+			return true
+		}
+	}
+	return false
+}
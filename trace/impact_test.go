@@ -0,0 +1,126 @@
+package trace
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/yoanbernabeu/grepai/git"
+)
+
+func TestAnalyzeImpact(t *testing.T) {
+	ctx := context.Background()
+	store := NewGOBSymbolStore(filepath.Join(t.TempDir(), "symbols.gob"))
+
+	files := []struct {
+		path    string
+		symbols []Symbol
+		refs    []Reference
+	}{
+		{"auth/login.go", []Symbol{
+			{Name: "Login", Kind: KindFunction, File: "auth/login.go", Line: 3},
+			{Name: "hashPassword", Kind: KindFunction, File: "auth/login.go", Line: 10},
+		}, []Reference{
+			{SymbolName: "hashPassword", File: "auth/login.go", Line: 5, CallerName: "Login", CallerFile: "auth/login.go", CallerLine: 3},
+		}},
+		{"api/handler.go", []Symbol{
+			{Name: "HandleAuth", Kind: KindFunction, File: "api/handler.go", Line: 5, EndLine: 20},
+		}, []Reference{
+			{SymbolName: "Login", File: "api/handler.go", Line: 8, CallerName: "HandleAuth", CallerFile: "api/handler.go", CallerLine: 5},
+		}},
+		{"cmd/main.go", []Symbol{
+			{Name: "main", Kind: KindFunction, File: "cmd/main.go", Line: 1},
+		}, []Reference{
+			{SymbolName: "HandleAuth", File: "cmd/main.go", Line: 4, CallerName: "main", CallerFile: "cmd/main.go", CallerLine: 1},
+		}},
+		{"api/handler_test.go", []Symbol{
+			{Name: "TestHandleAuth", Kind: KindFunction, File: "api/handler_test.go", Line: 7},
+		}, []Reference{
+			{SymbolName: "HandleAuth", File: "api/handler_test.go", Line: 9, CallerName: "TestHandleAuth", CallerFile: "api/handler_test.go", CallerLine: 7},
+		}},
+	}
+	for _, f := range files {
+		if err := store.SaveFile(ctx, f.path, f.symbols, f.refs); err != nil {
+			t.Fatalf("SaveFile(%s) failed: %v", f.path, err)
+		}
+	}
+
+	// Line 12 is in hashPassword, which extends to the end of the file
+	changes := []Change{{File: "auth/login.go", Lines: []git.LineRange{{Start: 12, End: 12}}}}
+	impact, err := AnalyzeImpact(ctx, store, changes, 3)
+	if err != nil {
+		t.Fatalf("AnalyzeImpact failed: %v", err)
+	}
+
+	if len(impact.Changed) != 1 || impact.Changed[0].Name != "hashPassword" {
+		t.Fatalf("expected hashPassword changed, got %+v", impact.Changed)
+	}
+	if len(impact.Affected) != 3 || impact.Affected[0].Name != "Login" || impact.Affected[1].Name != "HandleAuth" || impact.Affected[2].Name != "main" {
+		t.Fatalf("expected Login, HandleAuth and main affected, got %+v", impact.Affected)
+	}
+	if impact.Affected[1].Depth != 2 || impact.Affected[1].Via != "Login" {
+		t.Errorf("expected HandleAuth at depth 2 via Login, got %+v", impact.Affected[1])
+	}
+	if len(impact.Tests) != 1 || impact.Tests[0].Name != "TestHandleAuth" {
+		t.Errorf("expected TestHandleAuth, got %+v", impact.Tests)
+	}
+	want := []string{"api/handler.go", "api/handler_test.go", "auth/login.go", "cmd/main.go"}
+	if len(impact.Files) != len(want) {
+		t.Fatalf("expected files %v, got %v", want, impact.Files)
+	}
+	for i := range want {
+		if impact.Files[i] != want[i] {
+			t.Errorf("expected files %v, got %v", want, impact.Files)
+		}
+	}
+
+	// Callers further than depth are left out
+	shallow, err := AnalyzeImpact(ctx, store, changes, 1)
+	if err != nil || len(shallow.Affected) != 1 || len(shallow.Tests) != 0 {
+		t.Errorf("expected only Login within depth 1, got %+v (%v)", shallow, err)
+	}
+
+	impact.Affected[0].FeaturePath = "auth/login"
+	impact.CollectFeatures()
+	if len(impact.Features) != 1 || impact.Features[0] != "auth/login" {
+		t.Errorf("expected the feature of Login, got %v", impact.Features)
+	}
+}
+
+func TestChangedSymbols_Innermost(t *testing.T) {
+	symbols := []Symbol{
+		{Name: "Server", Kind: KindClass, Line: 1, EndLine: 30},
+		{Name: "Start", Kind: KindMethod, Line: 5, EndLine: 10},
+		{Name: "Stop", Kind: KindMethod, Line: 12, EndLine: 20},
+	}
+
+	changed := changedSymbols(symbols, []git.LineRange{{Start: 7, End: 7}})
+	if len(changed) != 1 || changed[0].Name != "Start" {
+		t.Errorf("expected the method only, got %+v", changed)
+	}
+	changed = changedSymbols(symbols, []git.LineRange{{Start: 9, End: 14}})
+	if len(changed) != 2 {
+		t.Errorf("expected both methods, got %+v", changed)
+	}
+	changed = changedSymbols(symbols, []git.LineRange{{Start: 25, End: 25}})
+	if len(changed) != 1 || changed[0].Name != "Server" {
+		t.Errorf("expected the class, got %+v", changed)
+	}
+}
+
+func TestIsTestFile(t *testing.T) {
+	for file, want := range map[string]bool{
+		"auth/login_test.go":       true,
+		"tests/test_login.py":      true,
+		"web/login.spec.ts":        true,
+		"src/__tests__/login.js":   true,
+		"src/LoginTest.java":       true,
+		"auth/login.go":            false,
+		"contests/login.go":        false,
+		"internal/testutil/fix.go": false,
+	} {
+		if got := IsTestFile(file); got != want {
+			t.Errorf("IsTestFile(%q) = %v, want %v", file, got, want)
+		}
+	}
+}
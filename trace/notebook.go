@@ -0,0 +1,190 @@
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// NotebookCell is a single cell of a Jupyter notebook with its outputs stripped.
+type NotebookCell struct {
+	Index  int    // 0-based position of the cell in the notebook
+	Type   string // "code", "markdown" or "raw"
+	Source string
+}
+
+// Notebook is the parsed content of a .ipynb file.
+type Notebook struct {
+	Language string // kernel language, e.g. "python"
+	Cells    []NotebookCell
+}
+
+// IsNotebook reports whether the file is a Jupyter notebook.
+func IsNotebook(filePath string) bool {
+	return strings.ToLower(filepath.Ext(filePath)) == ".ipynb"
+}
+
+// notebookSource accepts both nbformat encodings of multi-line text:
+// a single string or a list of lines.
+type notebookSource string
+
+func (s *notebookSource) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		*s = notebookSource(str)
+		return nil
+	}
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err != nil {
+		return err
+	}
+	*s = notebookSource(strings.Join(lines, ""))
+	return nil
+}
+
+type rawNotebook struct {
+	Cells []struct {
+		CellType string         `json:"cell_type"`
+		Source   notebookSource `json:"source"`
+	} `json:"cells"`
+	Metadata struct {
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+}
+
+// ParseNotebook extracts the cells of a Jupyter notebook, ignoring outputs.
+// Notebooks that do not declare a kernel language are assumed to be Python.
+func ParseNotebook(content string) (*Notebook, error) {
+	var raw rawNotebook
+	if err := json.Unmarshal([]byte(content), &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse notebook: %w", err)
+	}
+
+	nb := &Notebook{Language: "python"}
+	if lang := raw.Metadata.LanguageInfo.Name; lang != "" {
+		nb.Language = strings.ToLower(lang)
+	} else if lang := raw.Metadata.Kernelspec.Language; lang != "" {
+		nb.Language = strings.ToLower(lang)
+	}
+
+	for i, cell := range raw.Cells {
+		nb.Cells = append(nb.Cells, NotebookCell{
+			Index:  i,
+			Type:   cell.CellType,
+			Source: string(cell.Source),
+		})
+	}
+	return nb, nil
+}
+
+// StripNotebookOutputs removes cell outputs, execution counts and attachments
+// from a notebook, keeping everything else (including cell order) intact.
+func StripNotebookOutputs(content []byte) ([]byte, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse notebook: %w", err)
+	}
+	cells, _ := doc["cells"].([]any)
+	for _, c := range cells {
+		cell, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+		if _, ok := cell["outputs"]; ok {
+			cell["outputs"] = []any{}
+		}
+		if _, ok := cell["execution_count"]; ok {
+			cell["execution_count"] = nil
+		}
+		delete(cell, "attachments")
+	}
+	return json.Marshal(doc)
+}
+
+// pythonCellSource returns the source of a code cell with IPython magics and
+// shell escapes blanked out, preserving line numbers. Returns false for cells
+// that should not be parsed as Python (non-Python kernels, cell magics).
+func (nb *Notebook) pythonCellSource(cell NotebookCell) (string, bool) {
+	if cell.Type != "code" || nb.Language != "python" {
+		return "", false
+	}
+	if strings.HasPrefix(strings.TrimSpace(cell.Source), "%%") {
+		return "", false
+	}
+	lines := strings.Split(cell.Source, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "%") || strings.HasPrefix(trimmed, "!") {
+			lines[i] = ""
+		}
+	}
+	return strings.Join(lines, "\n"), true
+}
+
+// extractNotebookSymbols runs a Python symbol extractor over each code cell.
+// Line numbers are relative to the cell; Cell holds the 1-based cell number.
+func extractNotebookSymbols(ctx context.Context, filePath string, content string,
+	extract func(ctx context.Context, filePath string, content string) ([]Symbol, error)) ([]Symbol, error) {
+	nb, err := ParseNotebook(content)
+	if err != nil {
+		return nil, nil // Malformed notebooks simply yield no symbols
+	}
+
+	var symbols []Symbol
+	for _, cell := range nb.Cells {
+		src, ok := nb.pythonCellSource(cell)
+		if !ok {
+			continue
+		}
+		cellSymbols, err := extract(ctx, notebookCellPath(filePath, cell), src)
+		if err != nil {
+			return nil, err
+		}
+		for _, sym := range cellSymbols {
+			sym.File = filePath
+			sym.Cell = cell.Index + 1
+			symbols = append(symbols, sym)
+		}
+	}
+	return symbols, nil
+}
+
+// extractNotebookReferences runs a Python reference extractor over each code cell.
+func extractNotebookReferences(ctx context.Context, filePath string, content string,
+	extract func(ctx context.Context, filePath string, content string) ([]Reference, error)) ([]Reference, error) {
+	nb, err := ParseNotebook(content)
+	if err != nil {
+		return nil, nil
+	}
+
+	var refs []Reference
+	for _, cell := range nb.Cells {
+		src, ok := nb.pythonCellSource(cell)
+		if !ok {
+			continue
+		}
+		cellRefs, err := extract(ctx, notebookCellPath(filePath, cell), src)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range cellRefs {
+			ref.File = filePath
+			ref.CallerFile = filePath
+			ref.Cell = cell.Index + 1
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
+
+// notebookCellPath builds a virtual .py path so cells are parsed as Python.
+func notebookCellPath(filePath string, cell NotebookCell) string {
+	return fmt.Sprintf("%s#cell-%d.py", filePath, cell.Index+1)
+}
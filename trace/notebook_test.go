@@ -0,0 +1,112 @@
+package trace
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const testNotebook = `{
+ "cells": [
+  {"cell_type": "markdown", "metadata": {}, "source": ["# Churn model\n", "Loads data."]},
+  {"cell_type": "code", "execution_count": 1, "metadata": {}, "outputs": [{"output_type": "stream", "text": ["done\n"]}],
+   "source": ["%matplotlib inline\n", "import pandas as pd\n", "\n", "def load_data(path):\n", "    return pd.read_csv(path)\n"]},
+  {"cell_type": "code", "execution_count": 2, "metadata": {}, "outputs": [],
+   "source": "df = load_data(\"churn.csv\")\n!ls data"},
+  {"cell_type": "code", "execution_count": 3, "metadata": {}, "outputs": [],
+   "source": ["%%bash\n", "def not_python():\n"]}
+ ],
+ "metadata": {"kernelspec": {"language": "python", "name": "python3"}},
+ "nbformat": 4,
+ "nbformat_minor": 5
+}`
+
+func TestParseNotebook(t *testing.T) {
+	nb, err := ParseNotebook(testNotebook)
+	if err != nil {
+		t.Fatalf("ParseNotebook failed: %v", err)
+	}
+	if nb.Language != "python" {
+		t.Errorf("expected python kernel, got %q", nb.Language)
+	}
+	if len(nb.Cells) != 4 {
+		t.Fatalf("expected 4 cells, got %d", len(nb.Cells))
+	}
+	if nb.Cells[0].Type != "markdown" || nb.Cells[0].Source != "# Churn model\nLoads data." {
+		t.Errorf("unexpected markdown cell: %+v", nb.Cells[0])
+	}
+	if nb.Cells[2].Source != "df = load_data(\"churn.csv\")\n!ls data" {
+		t.Errorf("expected string-encoded source to be preserved, got %q", nb.Cells[2].Source)
+	}
+}
+
+func TestParseNotebook_Invalid(t *testing.T) {
+	if _, err := ParseNotebook("not json"); err == nil {
+		t.Error("expected error for invalid notebook")
+	}
+}
+
+func TestStripNotebookOutputs(t *testing.T) {
+	stripped, err := StripNotebookOutputs([]byte(testNotebook))
+	if err != nil {
+		t.Fatalf("StripNotebookOutputs failed: %v", err)
+	}
+	if strings.Contains(string(stripped), "done") {
+		t.Error("expected outputs to be stripped")
+	}
+
+	nb, err := ParseNotebook(string(stripped))
+	if err != nil {
+		t.Fatalf("stripped notebook should still parse: %v", err)
+	}
+	if len(nb.Cells) != 4 {
+		t.Errorf("expected cells to be preserved, got %d", len(nb.Cells))
+	}
+}
+
+func TestRegexExtractor_NotebookSymbols(t *testing.T) {
+	extractor := NewRegexExtractor()
+
+	symbols, err := extractor.ExtractSymbols(context.Background(), "analysis.ipynb", testNotebook)
+	if err != nil {
+		t.Fatalf("ExtractSymbols failed: %v", err)
+	}
+
+	var found *Symbol
+	for i := range symbols {
+		if symbols[i].Name == "not_python" {
+			t.Error("cell magic cells should not be parsed as Python")
+		}
+		if symbols[i].Name == "load_data" {
+			found = &symbols[i]
+		}
+	}
+	if found == nil {
+		t.Fatal("expected load_data symbol from code cell")
+	}
+	if found.File != "analysis.ipynb" || found.Cell != 2 || found.Line != 4 {
+		t.Errorf("expected load_data at analysis.ipynb cell 2 line 4, got %s cell %d line %d", found.File, found.Cell, found.Line)
+	}
+	if found.Language != "python" {
+		t.Errorf("expected python language, got %q", found.Language)
+	}
+}
+
+func TestRegexExtractor_NotebookReferences(t *testing.T) {
+	extractor := NewRegexExtractor()
+
+	refs, err := extractor.ExtractReferences(context.Background(), "analysis.ipynb", testNotebook)
+	if err != nil {
+		t.Fatalf("ExtractReferences failed: %v", err)
+	}
+
+	for _, ref := range refs {
+		if ref.SymbolName == "load_data" && ref.Cell == 3 {
+			if ref.File != "analysis.ipynb" || ref.CallerFile != "analysis.ipynb" || ref.Line != 1 {
+				t.Errorf("unexpected reference location: %+v", ref)
+			}
+			return
+		}
+	}
+	t.Error("expected reference to load_data from cell 3")
+}
@@ -4,6 +4,8 @@ package trace
 import (
 	"context"
 	"time"
+
+	"github.com/yoanbernabeu/grepai/git"
 )
 
 // SymbolKind represents the type of symbol.
@@ -21,17 +23,19 @@ const (
 
 // Symbol represents a symbol definition in the codebase.
 type Symbol struct {
-	Name        string     `json:"name"`
-	Kind        SymbolKind `json:"kind"`
-	File        string     `json:"file"`
-	Line        int        `json:"line"`
-	EndLine     int        `json:"end_line,omitempty"`
-	Signature   string     `json:"signature,omitempty"`
-	Receiver    string     `json:"receiver,omitempty"`
-	Package     string     `json:"package,omitempty"`
-	Exported    bool       `json:"exported,omitempty"`
-	Language    string     `json:"language"`
-	FeaturePath string     `json:"feature_path,omitempty"` // RPG semantic hierarchy path (populated when RPG enabled)
+	Name        string         `json:"name"`
+	Kind        SymbolKind     `json:"kind"`
+	File        string         `json:"file"`
+	Line        int            `json:"line"`
+	EndLine     int            `json:"end_line,omitempty"`
+	Signature   string         `json:"signature,omitempty"`
+	Receiver    string         `json:"receiver,omitempty"`
+	Package     string         `json:"package,omitempty"`
+	Exported    bool           `json:"exported,omitempty"`
+	Language    string         `json:"language"`
+	FeaturePath string         `json:"feature_path,omitempty"` // RPG semantic hierarchy path (populated when RPG enabled)
+	Cell        int            `json:"cell,omitempty"`         // 1-based notebook cell number (lines are relative to the cell)
+	Blame       *git.BlameInfo `json:"blame,omitempty"`        // Last commit of the symbol's lines (populated with --blame)
 }
 
 // Reference represents a usage/call of a symbol.
@@ -44,6 +48,7 @@ type Reference struct {
 	CallerName string `json:"caller_name"`
 	CallerFile string `json:"caller_file"`
 	CallerLine int    `json:"caller_line"`
+	Cell       int    `json:"cell,omitempty"` // 1-based notebook cell number (lines are relative to the cell)
 }
 
 // CallEdge represents a caller -> callee relationship.
@@ -91,6 +96,7 @@ type CallSite struct {
 	File    string `json:"file"`
 	Line    int    `json:"line"`
 	Context string `json:"context"`
+	Cell    int    `json:"cell,omitempty"`
 }
 
 // CallGraph represents a multi-level call graph.
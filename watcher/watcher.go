@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/yoanbernabeu/grepai/config"
 	"github.com/yoanbernabeu/grepai/indexer"
 )
 
@@ -20,6 +21,11 @@ const (
 	EventModify
 	EventDelete
 	EventRename
+	// EventIgnoreChange reports a changed .gitignore or .grepaiignore file.
+	// The ignore matcher has already been reloaded when it is delivered.
+	EventIgnoreChange
+	// EventDirConfigChange reports a changed nested .grepai.yaml file.
+	EventDirConfigChange
 )
 
 type FileEvent struct {
@@ -31,6 +37,7 @@ type Watcher struct {
 	root       string
 	watcher    *fsnotify.Watcher
 	ignore     *indexer.IgnoreMatcher
+	fileTypes  *indexer.FileTypes
 	debounceMs int
 	events     chan FileEvent
 	done       chan struct{}
@@ -41,21 +48,37 @@ type Watcher struct {
 	timer     *time.Timer
 }
 
-func NewWatcher(root string, ignore *indexer.IgnoreMatcher, debounceMs int) (*Watcher, error) {
+// Option configures a Watcher.
+type Option func(*Watcher)
+
+// WithFileTypes sets the registry deciding which files produce events.
+// Defaults to indexer.DefaultFileTypes.
+func WithFileTypes(ft *indexer.FileTypes) Option {
+	return func(w *Watcher) {
+		w.fileTypes = ft
+	}
+}
+
+func NewWatcher(root string, ignore *indexer.IgnoreMatcher, debounceMs int, opts ...Option) (*Watcher, error) {
 	fsw, err := fsnotify.NewWatcher()
 	if err != nil {
 		return nil, err
 	}
 
-	return &Watcher{
+	w := &Watcher{
 		root:       root,
 		watcher:    fsw,
 		ignore:     ignore,
+		fileTypes:  indexer.DefaultFileTypes(),
 		debounceMs: debounceMs,
 		events:     make(chan FileEvent, 100),
 		done:       make(chan struct{}),
 		pending:    make(map[string]FileEvent),
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w, nil
 }
 
 func (w *Watcher) Start(ctx context.Context) error {
@@ -135,6 +158,12 @@ func (w *Watcher) handleEvent(event fsnotify.Event) {
 		return
 	}
 
+	// Ignore files and nested settings are hidden files that still matter
+	if name := filepath.Base(relPath); indexer.IsIgnoreFile(name) || name == config.DirConfigFileName {
+		w.handleSettingsEvent(event, relPath)
+		return
+	}
+
 	// Ignore hidden files and ignored paths
 	if strings.HasPrefix(filepath.Base(relPath), ".") {
 		return
@@ -143,9 +172,9 @@ func (w *Watcher) handleEvent(event fsnotify.Event) {
 		return
 	}
 
-	// Check if it's a supported file
-	ext := strings.ToLower(filepath.Ext(event.Name))
-	if !indexer.SupportedExtensions[ext] {
+	// Check if it's a supported file (by extension, name or shebang).
+	// Removed extensionless files cannot be sniffed, so their events pass through.
+	if !w.isSupported(event, relPath) {
 		// Check if it's a directory (for watching new directories)
 		info, err := os.Stat(event.Name)
 		if err != nil || !info.IsDir() {
@@ -181,6 +210,21 @@ func (w *Watcher) handleEvent(event fsnotify.Event) {
 	})
 }
 
+// handleSettingsEvent queues a change of an ignore file or nested .grepai.yaml.
+func (w *Watcher) handleSettingsEvent(event fsnotify.Event, relPath string) {
+	if dir := filepath.Dir(relPath); dir != "." && w.ignore.ShouldIgnore(dir) {
+		return
+	}
+	if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) && !event.Has(fsnotify.Remove) && !event.Has(fsnotify.Rename) {
+		return
+	}
+	evType := EventDirConfigChange
+	if indexer.IsIgnoreFile(filepath.Base(relPath)) {
+		evType = EventIgnoreChange
+	}
+	w.debounceEvent(FileEvent{Type: evType, Path: relPath})
+}
+
 func (w *Watcher) debounceEvent(event FileEvent) {
 	w.pendingMu.Lock()
 	defer w.pendingMu.Unlock()
@@ -210,6 +254,24 @@ func (w *Watcher) flush() {
 	w.pending = make(map[string]FileEvent)
 	w.pendingMu.Unlock()
 
+	// Apply ignore file changes before delivering events, and watch
+	// directories that are no longer ignored.
+	reloaded := false
+	for _, event := range events {
+		if event.Type != EventIgnoreChange {
+			continue
+		}
+		if !reloaded {
+			if err := w.ignore.Reload(); err != nil {
+				log.Printf("Failed to reload ignore files: %v", err)
+			}
+			reloaded = true
+		}
+		if err := w.addRecursive(filepath.Join(w.root, filepath.Dir(event.Path))); err != nil {
+			log.Printf("Failed to watch %s: %v", filepath.Dir(event.Path), err)
+		}
+	}
+
 	for _, event := range events {
 		select {
 		case w.events <- event:
@@ -229,7 +291,26 @@ func (e EventType) String() string {
 		return "DELETE"
 	case EventRename:
 		return "RENAME"
+	case EventIgnoreChange:
+		return "IGNORE"
+	case EventDirConfigChange:
+		return "CONFIG"
 	default:
 		return "UNKNOWN"
 	}
 }
+
+// isSupported reports whether an event concerns a file the index tracks.
+func (w *Watcher) isSupported(event fsnotify.Event, relPath string) bool {
+	if _, ok := w.fileTypes.Match(relPath); ok {
+		return true
+	}
+	if !w.fileTypes.MaybeScript(relPath) {
+		return false
+	}
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		return true
+	}
+	_, ok := w.fileTypes.Detect(event.Name, relPath)
+	return ok
+}